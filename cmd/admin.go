@@ -0,0 +1,317 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"slices"
+	"text/tabwriter"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/auth"
+	"github.com/yanonymousV2/finance-manager-backend/internal/config"
+	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+	"github.com/yanonymousV2/finance-manager-backend/internal/digest"
+	"github.com/yanonymousV2/finance-manager-backend/internal/email"
+	"github.com/yanonymousV2/finance-manager-backend/internal/group"
+	"github.com/yanonymousV2/finance-manager-backend/internal/logging"
+	"github.com/yanonymousV2/finance-manager-backend/internal/push"
+	"github.com/yanonymousV2/finance-manager-backend/internal/scheduler"
+	"github.com/yanonymousV2/finance-manager-backend/internal/softdelete"
+	"github.com/yanonymousV2/finance-manager-backend/internal/storage"
+	"github.com/yanonymousV2/finance-manager-backend/internal/user"
+)
+
+const adminUsage = `Usage: finance-manager admin <command>
+
+Commands:
+  users [SEARCH]                list users, or those whose email contains SEARCH
+  reset-password EMAIL          give the user a new random password and print it
+  purge-deleted [-dry-run] [-older-than 720h]
+                                permanently remove expenses, settlements,
+                                categories and groups deleted longer ago than
+                                -older-than (default 30 days)
+  rebuild-balances [-group ID]  recompute stored group balances from their
+                                history, for one group or every group
+  send-digests                  send the email digests that are due now
+  email-log [RECIPIENT]         list the last 50 emails sent, or sent to
+                                RECIPIENT, and whether they went out
+  jobs                          list scheduled jobs and when they last ran
+  run-job NAME                  run a scheduled job now, unless another
+                                server is running it
+
+Settings are read the same way as by the server.
+`
+
+// adminTask is an admin command ready to run once connected
+type adminTask func(ctx context.Context, cfg *config.Config, database *db.DB) error
+
+// adminCommand runs the admin subcommand and returns the exit code
+func adminCommand(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, adminUsage)
+		return 2
+	}
+	task, err := parseAdminTask(args[0], args[1:])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n\n%s", err, adminUsage)
+		return 2
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	// Logs go to stderr, keeping stdout for the command's output
+	logger, err := logging.New(os.Stderr, cfg.LogLevel, cfg.LogFormat)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	slog.SetDefault(logger)
+
+	ctx := context.Background()
+	database, err := db.New(ctx, cfg.DBURL, poolOptions(cfg))
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	defer database.Close()
+
+	if err := task(ctx, cfg, database); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// parseAdminTask reads the flags and arguments of the named command
+func parseAdminTask(name string, args []string) (adminTask, error) {
+	flags := flag.NewFlagSet("admin "+name, flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	dryRun := flags.Bool("dry-run", false, "")
+	olderThan := flags.Duration("older-than", 30*24*time.Hour, "")
+	groupFlag := flags.String("group", "", "")
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	// Only the flags each command documents
+	var allowed []string
+	var maxArgs, minArgs int
+	switch name {
+	case "users", "email-log":
+		maxArgs = 1
+	case "reset-password":
+		minArgs, maxArgs = 1, 1
+	case "purge-deleted":
+		allowed = []string{"dry-run", "older-than"}
+	case "rebuild-balances":
+		allowed = []string{"group"}
+	case "send-digests", "jobs":
+	case "run-job":
+		minArgs, maxArgs = 1, 1
+	default:
+		return nil, fmt.Errorf("unknown command %q", name)
+	}
+	var unexpected error
+	flags.Visit(func(f *flag.Flag) {
+		if !slices.Contains(allowed, f.Name) {
+			unexpected = fmt.Errorf("%s doesn't take -%s", name, f.Name)
+		}
+	})
+	if unexpected != nil {
+		return nil, unexpected
+	}
+	if flags.NArg() < minArgs || flags.NArg() > maxArgs {
+		return nil, fmt.Errorf("wrong number of arguments for %s", name)
+	}
+
+	switch name {
+	case "users":
+		return listUsers(flags.Arg(0)), nil
+	case "reset-password":
+		return resetPassword(flags.Arg(0)), nil
+	case "purge-deleted":
+		if *olderThan <= 0 {
+			return nil, errors.New("-older-than must be positive")
+		}
+		return purgeDeleted(*olderThan, *dryRun), nil
+	case "rebuild-balances":
+		var groupID *uuid.UUID
+		if *groupFlag != "" {
+			id, err := uuid.Parse(*groupFlag)
+			if err != nil {
+				return nil, fmt.Errorf("invalid -group: %w", err)
+			}
+			groupID = &id
+		}
+		return rebuildBalances(groupID), nil
+	case "email-log":
+		return listEmails(flags.Arg(0)), nil
+	case "jobs":
+		return listJobs, nil
+	case "run-job":
+		return runJob(flags.Arg(0)), nil
+	default:
+		return sendDigests, nil
+	}
+}
+
+func listUsers(search string) adminTask {
+	return func(ctx context.Context, _ *config.Config, database *db.DB) error {
+		users, err := user.List(ctx, database, search)
+		if err != nil {
+			return fmt.Errorf("failed to list users: %w", err)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tEMAIL\tCURRENCY\tCREATED")
+		for _, u := range users {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", u.ID, u.Email, u.DefaultCurrency, u.CreatedAt.Format(time.RFC3339))
+		}
+		return w.Flush()
+	}
+}
+
+func resetPassword(userEmail string) adminTask {
+	return func(ctx context.Context, _ *config.Config, database *db.DB) error {
+		buf := make([]byte, 12)
+		if _, err := rand.Read(buf); err != nil {
+			return err
+		}
+		password := base64.RawURLEncoding.EncodeToString(buf)
+		if err := auth.ResetPassword(ctx, database, userEmail, password); err != nil {
+			return fmt.Errorf("failed to reset password: %w", err)
+		}
+		slog.Info("reset password", "email", userEmail)
+		fmt.Println(password)
+		return nil
+	}
+}
+
+func purgeDeleted(olderThan time.Duration, dryRun bool) adminTask {
+	return func(ctx context.Context, _ *config.Config, database *db.DB) error {
+		cutoff := time.Now().Add(-olderThan)
+		verb := "purged"
+		var results []softdelete.Result
+		var err error
+		if dryRun {
+			verb = "would purge"
+			results, err = softdelete.Count(ctx, database, cutoff)
+		} else {
+			results, err = softdelete.Purge(ctx, database, cutoff)
+		}
+		if err != nil {
+			return err
+		}
+		for _, r := range results {
+			if !dryRun {
+				slog.Info("purged deleted rows", "table", r.Table, "before", cutoff, "rows", r.Rows)
+			}
+			fmt.Printf("%s %d %s deleted before %s\n", verb, r.Rows, r.Table, cutoff.Format(time.RFC3339))
+		}
+		return nil
+	}
+}
+
+func rebuildBalances(groupID *uuid.UUID) adminTask {
+	return func(ctx context.Context, _ *config.Config, database *db.DB) error {
+		corrected, err := group.RebuildBalances(ctx, database, groupID)
+		if err != nil {
+			return fmt.Errorf("failed to rebuild balances: %w", err)
+		}
+		slog.Info("rebuilt group balances", "group_id", groupID, "corrected", corrected)
+		fmt.Printf("corrected %d balances\n", corrected)
+		return nil
+	}
+}
+
+func sendDigests(ctx context.Context, cfg *config.Config, database *db.DB) error {
+	mailer, err := newMailer(cfg, database)
+	if err != nil {
+		return fmt.Errorf("failed to set up email: %w", err)
+	}
+	if err := digest.RunOnce(ctx, database, mailer, cfg.PublicURL); err != nil {
+		return fmt.Errorf("failed to send digests: %w", err)
+	}
+	fmt.Println("sent due digests")
+	return nil
+}
+
+func listEmails(recipient string) adminTask {
+	return func(ctx context.Context, _ *config.Config, database *db.DB) error {
+		attempts, err := email.RecentAttempts(ctx, database, recipient, 50)
+		if err != nil {
+			return fmt.Errorf("failed to list emails: %w", err)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "SENT\tTO\tTEMPLATE\tDRIVER\tSTATUS\tERROR")
+		for _, a := range attempts {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", a.CreatedAt.Format(time.RFC3339), a.Recipient,
+				deref(a.Template), a.Driver, a.Status, deref(a.Error))
+		}
+		return w.Flush()
+	}
+}
+
+func listJobs(ctx context.Context, _ *config.Config, database *db.DB) error {
+	statuses, err := scheduler.Statuses(ctx, database)
+	if err != nil {
+		return fmt.Errorf("failed to list jobs: %w", err)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "NAME\tSCHEDULE\tNEXT RUN\tLAST FINISHED\tRUNNING ON\tLAST ERROR")
+	for _, s := range statuses {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", s.Name, s.Schedule, s.NextRunAt.Format(time.RFC3339),
+			formatTime(s.LastFinishedAt), deref(s.LockedBy), deref(s.LastError))
+	}
+	return w.Flush()
+}
+
+func runJob(name string) adminTask {
+	return func(ctx context.Context, cfg *config.Config, database *db.DB) error {
+		mailer, err := newMailer(cfg, database)
+		if err != nil {
+			return fmt.Errorf("failed to set up email: %w", err)
+		}
+		pusher, err := push.New(cfg, database)
+		if err != nil {
+			return fmt.Errorf("failed to set up push notifications: %w", err)
+		}
+		store, err := storage.New(cfg)
+		if err != nil {
+			return fmt.Errorf("failed to set up storage: %w", err)
+		}
+		s, err := newScheduler(cfg, database, store, mailer, pusher)
+		if err != nil {
+			return err
+		}
+		if err := s.RunNow(ctx, name); err != nil {
+			return err
+		}
+		fmt.Printf("ran %s\n", name)
+		return nil
+	}
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Format(time.RFC3339)
+}
+
+func deref(s *string) string {
+	if s == nil {
+		return "-"
+	}
+	return *s
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseAdminTask(t *testing.T) {
+	for _, args := range [][]string{
+		{"users"},
+		{"users", "example.com"},
+		{"reset-password", "alice@example.com"},
+		{"purge-deleted", "-dry-run", "-older-than", "2160h"},
+		{"rebuild-balances", "-group", "7b0e9c1a-2f3d-4c5b-8a6e-1d2f3a4b5c6d"},
+		{"send-digests"},
+		{"jobs"},
+		{"email-log"},
+		{"email-log", "jane@example.com"},
+		{"run-job", "digests"},
+	} {
+		task, err := parseAdminTask(args[0], args[1:])
+		assert.NoError(t, err, args)
+		assert.NotNil(t, task, args)
+	}
+
+	for _, args := range [][]string{
+		{"vacuum"},
+		{"users", "a", "b"},
+		{"reset-password"},
+		{"purge-deleted", "-older-than", "30d"},
+		{"purge-deleted", "-older-than", "0s"},
+		{"rebuild-balances", "-group", "42"},
+		{"send-digests", "-dry-run"},
+		{"run-job"},
+		{"jobs", "digests"},
+	} {
+		_, err := parseAdminTask(args[0], args[1:])
+		assert.Error(t, err, args)
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/budget"
+	"github.com/yanonymousV2/finance-manager-backend/internal/config"
+	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+	"github.com/yanonymousV2/finance-manager-backend/internal/digest"
+	"github.com/yanonymousV2/finance-manager-backend/internal/email"
+	"github.com/yanonymousV2/finance-manager-backend/internal/fxrate"
+	"github.com/yanonymousV2/finance-manager-backend/internal/notification"
+	"github.com/yanonymousV2/finance-manager-backend/internal/ocr"
+	"github.com/yanonymousV2/finance-manager-backend/internal/plaid"
+	"github.com/yanonymousV2/finance-manager-backend/internal/recurring"
+	"github.com/yanonymousV2/finance-manager-backend/internal/scheduler"
+	"github.com/yanonymousV2/finance-manager-backend/internal/sheets"
+	"github.com/yanonymousV2/finance-manager-backend/internal/softdelete"
+	"github.com/yanonymousV2/finance-manager-backend/internal/storage"
+)
+
+// newScheduler returns a scheduler with the periodic jobs. Schedules are in
+// UTC.
+func newScheduler(cfg *config.Config, database *db.DB, store storage.Storage, mailer email.Sender, pusher notification.Pusher) (*scheduler.Scheduler, error) {
+	s := scheduler.New(database)
+	err := errors.Join(
+		s.Add("digests", "@hourly", 30*time.Minute, func(ctx context.Context) error {
+			return digest.RunOnce(ctx, database, mailer, cfg.PublicURL)
+		}),
+		s.Add("recurring_expenses", "5 0 * * *", 10*time.Minute, func(ctx context.Context) error {
+			_, err := recurring.Materialize(ctx, database, time.Now())
+			return err
+		}),
+		s.Add("budget_alerts", "15 * * * *", 10*time.Minute, func(ctx context.Context) error {
+			return budget.EvaluateAlerts(ctx, database, time.Now())
+		}),
+		s.Add("notifications", "@every 1m", 5*time.Minute, func(ctx context.Context) error {
+			return notification.RunOnce(ctx, database, mailer, pusher)
+		}),
+		s.Add("email_log_cleanup", "45 3 * * *", 10*time.Minute, func(ctx context.Context) error {
+			_, err := email.PurgeLog(ctx, database)
+			return err
+		}),
+	)
+	if client := plaid.New(cfg); client != nil {
+		rates := fxrate.New(cfg, database)
+		err = errors.Join(err, s.Add("plaid_sync", "@every 5m", 15*time.Minute, func(ctx context.Context) error {
+			return plaid.SyncItems(ctx, database, client, rates)
+		}))
+	}
+	if client := sheets.New(cfg); client != nil {
+		err = errors.Join(err, s.Add("google_sheets_export", "@every 5m", 30*time.Minute, func(ctx context.Context) error {
+			return sheets.ExportDue(ctx, database, client)
+		}))
+	}
+	if provider := ocr.New(cfg); provider != nil {
+		err = errors.Join(err, s.Add("receipt_extraction", "@every 1m", 15*time.Minute, func(ctx context.Context) error {
+			return ocr.RunOnce(ctx, database, store, provider)
+		}))
+	}
+	// A retention of 0 keeps deleted rows
+	if cfg.SoftDeleteRetention > 0 {
+		err = errors.Join(err, s.Add("soft_delete_purge", "0 3 * * *", 30*time.Minute, func(ctx context.Context) error {
+			return softdelete.PurgeExpired(ctx, database, cfg.SoftDeleteRetention)
+		}))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s, nil
+}
@@ -2,182 +2,398 @@ package main
 
 import (
 	"context"
+	"fmt"
 	"log"
+	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strconv"
+	"sync"
 	"syscall"
 	"time"
 
-	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/joho/godotenv"
+	"google.golang.org/grpc"
 
 	"github.com/yanonymousV2/finance-manager-backend/internal/auth"
-	"github.com/yanonymousV2/finance-manager-backend/internal/budget"
 	"github.com/yanonymousV2/finance-manager-backend/internal/category"
 	"github.com/yanonymousV2/finance-manager-backend/internal/config"
-	"github.com/yanonymousV2/finance-manager-backend/internal/dashboard"
 	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+	"github.com/yanonymousV2/finance-manager-backend/internal/email"
 	"github.com/yanonymousV2/finance-manager-backend/internal/expense"
+	"github.com/yanonymousV2/finance-manager-backend/internal/fxrate"
 	"github.com/yanonymousV2/finance-manager-backend/internal/group"
-	"github.com/yanonymousV2/finance-manager-backend/internal/middleware"
-	"github.com/yanonymousV2/finance-manager-backend/internal/personalexpense"
-	"github.com/yanonymousV2/finance-manager-backend/internal/settlement"
+	"github.com/yanonymousV2/finance-manager-backend/internal/grpcapi"
+	"github.com/yanonymousV2/finance-manager-backend/internal/health"
+	"github.com/yanonymousV2/finance-manager-backend/internal/logging"
+	"github.com/yanonymousV2/finance-manager-backend/internal/ocr"
+	"github.com/yanonymousV2/finance-manager-backend/internal/plaid"
+	"github.com/yanonymousV2/finance-manager-backend/internal/push"
+	"github.com/yanonymousV2/finance-manager-backend/internal/server"
+	"github.com/yanonymousV2/finance-manager-backend/internal/sheets"
+	"github.com/yanonymousV2/finance-manager-backend/internal/storage"
+	"github.com/yanonymousV2/finance-manager-backend/internal/stream"
+	"github.com/yanonymousV2/finance-manager-backend/internal/webhook"
 )
 
+// migrationsPath holds the migration files, relative to the working directory
+var migrationsPath = filepath.Join("internal", "db", "migrations")
+
 func main() {
 	// Load .env file
-	if err := godotenv.Load(); err != nil {
-		log.Println("No .env file found, using system environment variables")
+	envErr := godotenv.Load()
+
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "migrate":
+			os.Exit(migrateCommand(os.Args[2:]))
+		case "admin":
+			os.Exit(adminCommand(os.Args[2:]))
+		}
 	}
 
-	cfg := config.Load()
+	cfg, err := config.Load()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
 
-	log.Println("==============================================")
-	log.Println("Finance Manager Backend starting...")
-	log.Println("==============================================")
+	logger, err := logging.New(os.Stdout, cfg.LogLevel, cfg.LogFormat)
+	if err != nil {
+		log.Fatal(err)
+	}
+	slog.SetDefault(logger)
+
+	if envErr != nil {
+		slog.Info("no .env file found, using system environment variables")
+	}
+	slog.Info("starting finance manager backend", "env", cfg.Env, "log_level", cfg.LogLevel, "log_format", cfg.LogFormat)
 
 	ctx := context.Background()
 
 	// Connect to DB
-	log.Println("Connecting to database...")
-	database, err := db.New(ctx, cfg.DBURL)
+	poolOptions := poolOptions(cfg)
+	database, err := db.New(ctx, cfg.DBURL, poolOptions)
 	if err != nil {
-		log.Fatal("Failed to connect to database:", err)
-	}
-	defer database.Close()
-	log.Println("✓ Database connection established")
-
-	// Run migrations
-	log.Println("Running database migrations...")
-	migrationsPath := filepath.Join("internal", "db", "migrations")
-	if err := db.RunMigrations(ctx, cfg.DBURL, migrationsPath); err != nil {
-		log.Fatal("Failed to run migrations:", err)
-	}
-	log.Println("✓ Migrations completed successfully")
-	log.Println("[MARKER] About to setup Gin router")
-
-	// Setup Gin
-	log.Println("Setting up Gin router...")
-	r := gin.Default()
-	log.Println("✓ Gin router created")
-
-	// Add request logging middleware
-	log.Println("  → Adding request logging middleware...")
-	r.Use(middleware.RequestLogger())
-	log.Println("  ✓ Request logging middleware added")
-
-	// Add CORS middleware
-	log.Println("  → Adding CORS middleware...")
-	r.Use(middleware.CORS())
-	log.Println("  ✓ CORS middleware added")
-
-	// Health check endpoint
-	log.Println("  → Setting up health check endpoint...")
-	r.GET("/health", func(c *gin.Context) {
-		// Check database connectivity
-		if err := database.Pool.Ping(c.Request.Context()); err != nil {
-			c.JSON(503, gin.H{"status": "unhealthy", "database": "disconnected"})
-			return
+		fatal("failed to connect to database", err)
+	}
+	slog.Info("database connected")
+	if cfg.DBReplicaURL != "" {
+		if err := database.AddReplica(ctx, cfg.DBReplicaURL, poolOptions); err != nil {
+			fatal("invalid DATABASE_REPLICA_URL", err)
 		}
-		c.JSON(200, gin.H{"status": "healthy", "database": "connected"})
-	})
-	log.Println("  ✓ Health check endpoint setup")
-
-	// Create auth service with config
-	log.Println("  → Creating auth service...")
-	authService := &auth.AuthService{
-		DB:        database,
-		JWTSecret: cfg.JWTSecret,
 	}
-	log.Println("  ✓ Auth service created")
 
-	// Auth routes with rate limiting
-	log.Println("  → Setting up auth routes...")
-	authLimited := r.Group("/auth")
-	authLimited.Use(middleware.RateLimiter())
-	{
-		authLimited.POST("/signup", func(c *gin.Context) { auth.Signup(c, authService) })
-		authLimited.POST("/login", func(c *gin.Context) { auth.Login(c, authService) })
+	// Run migrations; otherwise readiness fails until the migrate command has
+	if cfg.MigrateOnStart {
+		if err := db.RunMigrations(ctx, cfg.DBURL, migrationsPath); err != nil {
+			fatal("failed to run migrations", err)
+		}
+	}
+	latestMigration, err := db.LatestMigration(migrationsPath)
+	if err != nil {
+		fatal("failed to find latest migration", err)
 	}
-	log.Println("  ✓ Auth routes setup")
 
-	// Protected routes
-	log.Println("  → Setting up protected routes...")
-	protected := r.Group("/")
-	protected.Use(middleware.JWTAuth(cfg.JWTSecret))
-	{
-		// Groups
-		protected.POST("/groups", func(c *gin.Context) { group.CreateGroup(c, database) })
-		protected.POST("/groups/:id/add-member", func(c *gin.Context) { group.AddMember(c, database) })
-		protected.GET("/groups/:id/balances", func(c *gin.Context) { group.GetBalances(c, database) })
+	// Set up file storage
+	store, err := storage.New(cfg)
+	if err != nil {
+		fatal("failed to set up file storage", err)
+	}
+	slog.Info("file storage ready", "driver", cfg.StorageDriver)
 
-		// Group Expenses
-		protected.POST("/expenses", func(c *gin.Context) { expense.CreateExpense(c, database) })
-		protected.GET("/groups/:id/expenses", func(c *gin.Context) { expense.GetGroupExpenses(c, database) })
+	// Set up outgoing email
+	mailer, err := newMailer(cfg, database)
+	if err != nil {
+		fatal("failed to set up email", err)
+	}
+	slog.Info("email ready", "driver", cfg.EmailDriver)
 
-		// Settlements
-		protected.POST("/settlements", func(c *gin.Context) { settlement.CreateSettlement(c, database) })
+	// Set up push notifications
+	pusher, err := push.New(cfg, database)
+	if err != nil {
+		fatal("failed to set up push notifications", err)
+	}
+	slog.Info("push notifications ready", "providers", pusher.Enabled())
 
-		// Personal Finance - Budget
-		protected.POST("/budget", func(c *gin.Context) { budget.SetMonthlyBudget(c, database) })
-		protected.GET("/budget", func(c *gin.Context) { budget.GetMonthlyBudget(c, database) })
-		protected.GET("/budgets", func(c *gin.Context) { budget.ListBudgets(c, database) })
+	// Exchange rates for amounts paid in a foreign currency
+	rates := fxrate.New(cfg, database)
+	slog.Info("exchange rates ready", "provider", cfg.FXProvider)
 
-		// Personal Finance - Categories
-		protected.POST("/categories", func(c *gin.Context) { category.CreateCategory(c, database) })
-		protected.GET("/categories", func(c *gin.Context) { category.ListCategories(c, database) })
-		protected.PUT("/categories/:id", func(c *gin.Context) { category.UpdateCategory(c, database) })
-		protected.DELETE("/categories/:id", func(c *gin.Context) { category.DeleteCategory(c, database) })
+	defaultCategories, err := category.Defaults(cfg)
+	if err != nil {
+		fatal("invalid DEFAULT_CATEGORIES", err)
+	}
 
-		// Personal Finance - Expenses
-		protected.POST("/personal-expenses", func(c *gin.Context) { personalexpense.CreateExpense(c, database) })
-		protected.GET("/personal-expenses", func(c *gin.Context) { personalexpense.ListExpenses(c, database) })
-		protected.GET("/personal-expenses/:id", func(c *gin.Context) { personalexpense.GetExpense(c, database) })
-		protected.PUT("/personal-expenses/:id", func(c *gin.Context) { personalexpense.UpdateExpense(c, database) })
-		protected.DELETE("/personal-expenses/:id", func(c *gin.Context) { personalexpense.DeleteExpense(c, database) })
+	authService := &auth.AuthService{
+		DB:        database,
+		JWTSecret: cfg.JWTSecret,
+	}
+	if cfg.SeedCategories {
+		authService.OnSignup = func(ctx context.Context, tx pgx.Tx, userID uuid.UUID) error {
+			_, err := category.SeedDefaults(ctx, tx, userID, defaultCategories)
+			return err
+		}
+	}
 
-		// Personal Finance - Dashboard
-		protected.GET("/dashboard/monthly", func(c *gin.Context) { dashboard.GetMonthlyDashboard(c, database) })
+	jobScheduler, err := newScheduler(cfg, database, store, mailer, pusher)
+	if err != nil {
+		fatal("invalid scheduled jobs", err)
 	}
-	log.Println("  ✓ All protected routes setup")
 
-	log.Println("✓ Router setup complete")
+	// Readiness checks; schedulerPoll and webhookInterval are how often the
+	// scheduler checks for due jobs and the webhook job runs
+	const schedulerPoll = 30 * time.Second
+	const webhookInterval = 10 * time.Second
+	schedulerHeartbeat := health.NewHeartbeat(schedulerPoll)
+	webhookHeartbeat := health.NewHeartbeat(webhookInterval)
+	ready := health.NewChecker(2 * time.Second)
+	ready.Add("database", database.Check)
+	ready.Add("migrations", database.CheckMigrations(latestMigration))
+	ready.Add("scheduler", schedulerHeartbeat.Check)
+	ready.Add("webhook_job", webhookHeartbeat.Check)
+	var draining health.Draining
+	ready.Add("accepting_requests", draining.Check)
+
+	// Live group updates; the streams end when the server shuts down
+	streams := stream.NewHub(database)
+
+	// Shared by the REST and gRPC APIs
+	groups := group.NewService(group.NewRepository(database))
+	expenses := expense.NewService(expense.NewRepository(database))
+
+	handler := server.New(server.Deps{
+		DB:                database,
+		Store:             store,
+		Auth:              authService,
+		Groups:            groups,
+		Expenses:          expenses,
+		DefaultCategories: defaultCategories,
+		JWTSecret:         cfg.JWTSecret,
+		Ready:             ready,
+		Streams:           streams,
+		Rates:             rates,
+		Plaid:             plaid.New(cfg),
+		OCR:               ocr.New(cfg),
+		Sheets:            sheets.New(cfg),
+	})
 
 	// Create server with timeouts
 	srv := &http.Server{
-		Addr:           ":" + cfg.Port,
-		Handler:        r,
-		ReadTimeout:    10 * time.Second,
-		WriteTimeout:   10 * time.Second,
-		IdleTimeout:    60 * time.Second,
+		Addr:           ":" + strconv.Itoa(cfg.Port),
+		Handler:        handler,
+		ReadTimeout:    cfg.ReadTimeout,
+		WriteTimeout:   cfg.WriteTimeout,
+		IdleTimeout:    cfg.IdleTimeout,
 		MaxHeaderBytes: 1 << 20, // 1 MB
 	}
+	srv.RegisterOnShutdown(streams.Close)
+
+	// Built-in HTTPS, with plain HTTP redirected to it
+	tlsConfig, redirect, err := server.TLS(server.TLSOptions{
+		CertFile:  cfg.TLSCertFile,
+		KeyFile:   cfg.TLSKeyFile,
+		Domains:   cfg.AutocertDomains,
+		Email:     cfg.AutocertEmail,
+		CacheDir:  cfg.AutocertCacheDir,
+		HTTPSPort: strconv.Itoa(cfg.Port),
+	})
+	if err != nil {
+		fatal("invalid TLS settings", err)
+	}
+	srv.TLSConfig = tlsConfig
+	var redirectSrv *http.Server
+	if redirect != nil {
+		redirectSrv = &http.Server{
+			Addr:              cfg.HTTPRedirectAddr,
+			Handler:           redirect,
+			ReadHeaderTimeout: 10 * time.Second,
+			IdleTimeout:       cfg.IdleTimeout,
+		}
+		go func() {
+			slog.Info("redirecting HTTP to HTTPS", "addr", redirectSrv.Addr)
+			if err := redirectSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				fatal("failed to start HTTP redirect server", err)
+			}
+		}()
+	}
+
+	// Run periodic jobs, such as digests and purging deleted rows, in the
+	// background
+	jobCtx, stopJobs := context.WithCancel(context.Background())
+	var jobs sync.WaitGroup
+	jobs.Add(1)
+	go func() {
+		defer jobs.Done()
+		jobScheduler.Run(jobCtx, schedulerPoll, schedulerHeartbeat)
+	}()
+
+	// Post events to webhook endpoints
+	jobs.Add(1)
+	go func() {
+		defer jobs.Done()
+		webhook.Run(jobCtx, database, webhook.NewClient(cfg.WebhookAllowPrivate), webhookInterval, webhookHeartbeat)
+	}()
+
+	// Listen for group events to stream
+	jobs.Add(1)
+	go func() {
+		defer jobs.Done()
+		streams.Run(jobCtx)
+	}()
 
 	// Start server in a goroutine
 	go func() {
-		log.Println("==============================================")
-		log.Printf("🚀 Server listening on http://localhost:%s", cfg.Port)
-		log.Println("==============================================")
-		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
-			log.Fatal("Failed to start server:", err)
+		slog.Info("server listening", "addr", srv.Addr, "tls", tlsConfig != nil)
+		var err error
+		if tlsConfig != nil {
+			// Certificates come from TLSConfig
+			err = srv.ListenAndServeTLS("", "")
+		} else {
+			err = srv.ListenAndServe()
+		}
+		if err != nil && err != http.ErrServerClosed {
+			fatal("failed to start server", err)
 		}
 	}()
 
+	// The gRPC API for internal services, on its own port
+	var grpcSrv *grpc.Server
+	if cfg.GRPCAddr != "" {
+		lis, err := net.Listen("tcp", cfg.GRPCAddr)
+		if err != nil {
+			fatal("failed to listen for gRPC", err)
+		}
+		grpcSrv = grpcapi.New(grpcapi.Deps{JWTSecret: cfg.JWTSecret, Groups: groups, Expenses: expenses})
+		go func() {
+			slog.Info("gRPC server listening", "addr", lis.Addr().String())
+			if err := grpcSrv.Serve(lis); err != nil {
+				fatal("failed to start gRPC server", err)
+			}
+		}()
+	}
+
+	// Profiles and maintenance tasks on a separate port, kept off the public
+	// one. No write timeout since a CPU profile takes 30 seconds by default.
+	var adminSrv *http.Server
+	if cfg.AdminAddr != "" {
+		adminSrv = &http.Server{
+			Addr:              cfg.AdminAddr,
+			Handler:           server.NewAdmin(database),
+			ReadHeaderTimeout: 10 * time.Second,
+		}
+		go func() {
+			slog.Info("admin server listening", "addr", adminSrv.Addr)
+			if err := adminSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+				fatal("failed to start admin server", err)
+			}
+		}()
+	}
+
 	// Wait for interrupt signal to gracefully shutdown the server
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit
-	log.Println("Shutting down server...")
+	slog.Info("shutting down server", "delay", cfg.ShutdownDelay, "timeout", cfg.ShutdownTimeout)
+
+	// Fail readiness first and give load balancers time to notice
+	draining.Start()
+	time.Sleep(cfg.ShutdownDelay)
 
-	// Graceful shutdown with 5 second timeout
-	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
 	defer cancel()
+	clean := true
 
+	// Stop accepting connections and let in-flight requests, such as
+	// imports and report downloads, finish
+	if redirectSrv != nil {
+		redirectSrv.Close()
+	}
 	if err := srv.Shutdown(ctx); err != nil {
-		log.Fatal("Server forced to shutdown:", err)
+		slog.Error("requests still running at shutdown timeout", "error", err)
+		clean = false
 	}
+	if grpcSrv != nil && !stopGRPC(ctx, grpcSrv) {
+		slog.Error("gRPC calls still running at shutdown timeout")
+		clean = false
+	}
+
+	// Then let the background jobs finish what they're doing
+	stopJobs()
+	jobsDone := make(chan struct{})
+	go func() {
+		jobs.Wait()
+		close(jobsDone)
+	}()
+	select {
+	case <-jobsDone:
+	case <-ctx.Done():
+		slog.Error("background jobs still running at shutdown timeout")
+		clean = false
+	}
+
+	if adminSrv != nil {
+		adminSrv.Close()
+	}
+	if !clean {
+		// Closing the pool would wait for the work still holding connections
+		slog.Warn("server exited before all work finished")
+		os.Exit(1)
+	}
+
+	// Last, once nothing is using it
+	database.Close()
+	slog.Info("server exited gracefully")
+}
+
+// stopGRPC lets in-flight gRPC calls finish, cutting them off when ctx ends.
+// It reports whether they all finished.
+func stopGRPC(ctx context.Context, srv *grpc.Server) bool {
+	stopped := make(chan struct{})
+	go func() {
+		srv.GracefulStop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		return true
+	case <-ctx.Done():
+		srv.Stop()
+		return false
+	}
+}
+
+// poolOptions are the connection pool settings from cfg
+func poolOptions(cfg *config.Config) db.PoolOptions {
+	return db.PoolOptions{
+		MaxConns:          int32(cfg.DBMaxConns),
+		MinConns:          int32(cfg.DBMinConns),
+		MaxConnLifetime:   cfg.DBMaxConnLifetime,
+		MaxConnIdleTime:   cfg.DBMaxConnIdleTime,
+		HealthCheckPeriod: cfg.DBHealthCheckPeriod,
+		StatementTimeout:  cfg.DBStatementTimeout,
+		QueryTimeout:      cfg.DBQueryTimeout,
+		SlowQuery:         cfg.DBSlowQuery,
+	}
+}
+
+// newMailer returns the email sender selected by cfg, recording every
+// attempt in email_log
+func newMailer(cfg *config.Config, database *db.DB) (email.Sender, error) {
+	sender, err := email.New(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return email.Record(sender, database, cfg.EmailDriver), nil
+}
 
-	log.Println("Server exited gracefully")
+// fatal logs err and exits
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
 }
@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/config"
+	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+)
+
+const migrateUsage = `Usage: finance-manager migrate <command>
+
+Commands:
+  up [-dry-run]           apply every pending migration
+  down [-dry-run] [N]     roll back the last N migrations (default 1)
+  status                  show the applied version and pending migrations
+  force VERSION           mark VERSION as applied and clean without running
+                          anything, after fixing a failed migration by hand;
+                          -1 marks none applied
+
+-dry-run prints what would run without changing the database. The
+database is DATABASE_URL, read the same way as by the server.
+`
+
+// migrateCommand runs the migrate subcommand and returns the exit code
+func migrateCommand(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	// Only up and down take flags, so force's -1 isn't one
+	rest := args[1:]
+	var dryRun bool
+	if args[0] == "up" || args[0] == "down" {
+		flags := flag.NewFlagSet("migrate "+args[0], flag.ContinueOnError)
+		flags.SetOutput(io.Discard)
+		flags.BoolVar(&dryRun, "dry-run", false, "")
+		if err := flags.Parse(rest); err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n\n%s", err, migrateUsage)
+			return 2
+		}
+		rest = flags.Args()
+	}
+
+	var run func(*migration) error
+	switch {
+	case args[0] == "up" && len(rest) == 0:
+		run = func(m *migration) error { return m.up(dryRun) }
+	case args[0] == "down" && len(rest) <= 1:
+		steps := 1
+		if len(rest) == 1 {
+			n, err := strconv.Atoi(rest[0])
+			if err != nil || n < 1 {
+				fmt.Fprintf(os.Stderr, "down takes a number of migrations, got %q\n", rest[0])
+				return 2
+			}
+			steps = n
+		}
+		run = func(m *migration) error { return m.down(steps, dryRun) }
+	case args[0] == "status" && len(rest) == 0:
+		run = (*migration).status
+	case args[0] == "force" && len(rest) == 1:
+		version, err := strconv.Atoi(rest[0])
+		if err != nil || version < -1 {
+			fmt.Fprintf(os.Stderr, "force takes a version or -1, got %q\n", rest[0])
+			return 2
+		}
+		run = func(m *migration) error { return m.force(version) }
+	default:
+		fmt.Fprint(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	m, err := newMigration(context.Background())
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	if err := run(m); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
+
+// migration is a connected migrator with the migration files and the
+// version the database is at
+type migration struct {
+	migrator   *db.Migrator
+	migrations []db.Migration
+	version    uint
+	dirty      bool
+}
+
+func newMigration(ctx context.Context) (*migration, error) {
+	dbURL, err := config.DatabaseURL()
+	if err != nil {
+		return nil, err
+	}
+	migrations, err := db.Migrations(migrationsPath)
+	if err != nil {
+		return nil, err
+	}
+	migrator, err := db.NewMigrator(ctx, dbURL, migrationsPath)
+	if err != nil {
+		return nil, err
+	}
+	version, dirty, err := migrator.Version()
+	if err != nil {
+		return nil, err
+	}
+	return &migration{migrator: migrator, migrations: migrations, version: version, dirty: dirty}, nil
+}
+
+// checkClean refuses to migrate from a version that failed part way
+func (m *migration) checkClean() error {
+	if m.dirty {
+		return fmt.Errorf("migration %d failed part way; fix the schema by hand, then run migrate force", m.version)
+	}
+	return nil
+}
+
+func (m *migration) up(dryRun bool) error {
+	if err := m.checkClean(); err != nil {
+		return err
+	}
+	pending := db.Pending(m.migrations, m.version)
+	if len(pending) == 0 {
+		fmt.Printf("no pending migrations, at version %d\n", m.version)
+		return nil
+	}
+	if dryRun {
+		fmt.Println("would apply:")
+		printMigrations(pending)
+		return nil
+	}
+	if err := m.migrator.Up(); err != nil {
+		return err
+	}
+	fmt.Println("applied:")
+	printMigrations(pending)
+	return nil
+}
+
+func (m *migration) down(steps int, dryRun bool) error {
+	if err := m.checkClean(); err != nil {
+		return err
+	}
+	undo := db.RollBack(m.migrations, m.version, steps)
+	if len(undo) < steps {
+		return fmt.Errorf("only %d migrations are applied", len(undo))
+	}
+	if dryRun {
+		fmt.Println("would roll back:")
+		printMigrations(undo)
+		return nil
+	}
+	if err := m.migrator.Down(steps); err != nil {
+		return err
+	}
+	fmt.Println("rolled back:")
+	printMigrations(undo)
+	return nil
+}
+
+func (m *migration) status() error {
+	fmt.Printf("version: %d\n", m.version)
+	fmt.Printf("dirty:   %t\n", m.dirty)
+	pending := db.Pending(m.migrations, m.version)
+	if len(pending) == 0 {
+		fmt.Println("pending: none")
+		return nil
+	}
+	fmt.Println("pending:")
+	printMigrations(pending)
+	return nil
+}
+
+func (m *migration) force(version int) error {
+	if err := m.migrator.Force(version); err != nil {
+		return err
+	}
+	fmt.Printf("forced version %d\n", version)
+	return nil
+}
+
+func printMigrations(migrations []db.Migration) {
+	for _, m := range migrations {
+		fmt.Printf("  %s\n", m)
+	}
+}
@@ -0,0 +1,365 @@
+package account
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+	"github.com/yanonymousV2/finance-manager-backend/internal/db/sqlc"
+	"github.com/yanonymousV2/finance-manager-backend/internal/helpers"
+	"github.com/yanonymousV2/finance-manager-backend/internal/middleware"
+	"github.com/yanonymousV2/finance-manager-backend/internal/validation"
+)
+
+type Account struct {
+	ID             uuid.UUID       `json:"id" db:"id"`
+	UserID         uuid.UUID       `json:"user_id" db:"user_id"`
+	Name           string          `json:"name" db:"name"`
+	Type           string          `json:"type" db:"type"`
+	Currency       string          `json:"currency" db:"currency"`
+	OpeningBalance decimal.Decimal `json:"opening_balance" db:"opening_balance"`
+	Balance        decimal.Decimal `json:"balance"`
+	CreatedAt      time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at" db:"updated_at"`
+}
+
+type CreateAccountRequest struct {
+	Name           string  `json:"name" validate:"required,min=1,max=100"`
+	Type           string  `json:"type" validate:"required,oneof=cash bank card"`
+	Currency       string  `json:"currency,omitempty" validate:"omitempty,iso4217"`
+	OpeningBalance *string `json:"opening_balance,omitempty" validate:"omitempty,numeric"`
+}
+
+type UpdateAccountRequest struct {
+	Name           *string `json:"name,omitempty" validate:"omitempty,min=1,max=100"`
+	Type           *string `json:"type,omitempty" validate:"omitempty,oneof=cash bank card"`
+	OpeningBalance *string `json:"opening_balance,omitempty" validate:"omitempty,numeric"`
+}
+
+// LedgerEntry is a personal expense paid from an account together with the
+// account balance right after it
+type LedgerEntry struct {
+	ExpenseID      uuid.UUID       `json:"expense_id"`
+	Description    *string         `json:"description,omitempty"`
+	Amount         decimal.Decimal `json:"amount"`
+	ExpenseDate    time.Time       `json:"expense_date"`
+	RunningBalance decimal.Decimal `json:"running_balance"`
+}
+
+// accountColumns selects an account together with its current balance: the
+// opening balance less everything spent from it
+const accountColumns = `a.id, a.user_id, a.name, a.type, a.currency, a.opening_balance,
+	a.opening_balance - COALESCE((SELECT SUM(pe.amount) FROM personal_expenses pe WHERE pe.account_id = a.id), 0),
+	a.created_at, a.updated_at`
+
+func scanAccount(row interface{ Scan(...any) error }) (Account, error) {
+	var a Account
+	err := row.Scan(&a.ID, &a.UserID, &a.Name, &a.Type, &a.Currency, &a.OpeningBalance, &a.Balance, &a.CreatedAt, &a.UpdatedAt)
+	return a, err
+}
+
+// CreateAccount creates a new account
+func CreateAccount(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req CreateAccountRequest
+	if !validation.Bind(c, &req) {
+		return
+	}
+
+	openingBalance := decimal.Zero
+	if req.OpeningBalance != nil {
+		var err error
+		if openingBalance, err = decimal.NewFromString(*req.OpeningBalance); err != nil {
+			c.JSON(400, gin.H{"error": "invalid opening balance format"})
+			return
+		}
+	}
+
+	currency := req.Currency
+	if currency == "" {
+		currency = "USD"
+	}
+
+	var exists bool
+	err := db.Pool.QueryRow(c.Request.Context(),
+		"SELECT EXISTS(SELECT 1 FROM accounts WHERE user_id = $1 AND name = $2)",
+		userID, req.Name).Scan(&exists)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to check account"})
+		return
+	}
+	if exists {
+		c.JSON(409, gin.H{"error": "account with this name already exists"})
+		return
+	}
+
+	account, err := scanAccount(db.Pool.QueryRow(c.Request.Context(),
+		`INSERT INTO accounts (user_id, name, type, currency, opening_balance)
+		 VALUES ($1, $2, $3, $4, $5)
+		 RETURNING id, user_id, name, type, currency, opening_balance, opening_balance, created_at, updated_at`,
+		userID, req.Name, req.Type, currency, openingBalance))
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to create account"})
+		return
+	}
+
+	c.JSON(201, account)
+}
+
+// ListAccounts retrieves all accounts of a user with their current balances
+func ListAccounts(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	rows, err := db.Pool.Query(c.Request.Context(),
+		`SELECT `+accountColumns+`
+		 FROM accounts a
+		 WHERE a.user_id = $1
+		 ORDER BY a.name ASC`,
+		userID)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to retrieve accounts"})
+		return
+	}
+	defer rows.Close()
+
+	accounts := []Account{}
+	for rows.Next() {
+		account, err := scanAccount(rows)
+		if err != nil {
+			c.JSON(500, gin.H{"error": "failed to scan account"})
+			return
+		}
+		accounts = append(accounts, account)
+	}
+
+	c.JSON(200, accounts)
+}
+
+// GetAccount retrieves an account with its current balance
+func GetAccount(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	accountID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid account id"})
+		return
+	}
+
+	account, err := scanAccount(db.Pool.QueryRow(c.Request.Context(),
+		`SELECT `+accountColumns+` FROM accounts a WHERE a.id = $1 AND a.user_id = $2`,
+		accountID, userID))
+	if err != nil {
+		c.JSON(404, gin.H{"error": "account not found"})
+		return
+	}
+
+	c.JSON(200, account)
+}
+
+// UpdateAccount updates an existing account
+func UpdateAccount(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	accountID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid account id"})
+		return
+	}
+
+	var req UpdateAccountRequest
+	if !validation.Bind(c, &req) {
+		return
+	}
+
+	var ownerID uuid.UUID
+	err = db.Pool.QueryRow(c.Request.Context(),
+		`SELECT user_id FROM accounts WHERE id = $1`, accountID).Scan(&ownerID)
+	if err != nil {
+		c.JSON(404, gin.H{"error": "account not found"})
+		return
+	}
+	if ownerID != userID {
+		c.JSON(403, gin.H{"error": "not authorized to update this account"})
+		return
+	}
+
+	if req.Name == nil && req.Type == nil && req.OpeningBalance == nil {
+		c.JSON(400, gin.H{"error": "no fields to update"})
+		return
+	}
+
+	params := sqlc.UpdateAccountParams{ID: accountID, Name: req.Name, Type: req.Type}
+	if req.OpeningBalance != nil {
+		openingBalance, err := decimal.NewFromString(*req.OpeningBalance)
+		if err != nil {
+			c.JSON(400, gin.H{"error": "invalid opening balance format"})
+			return
+		}
+		params.OpeningBalance = &openingBalance
+	}
+
+	if err := sqlc.New(db.Pool).UpdateAccount(c.Request.Context(), params); err != nil {
+		c.JSON(500, gin.H{"error": "failed to update account"})
+		return
+	}
+
+	account, err := scanAccount(db.Pool.QueryRow(c.Request.Context(),
+		`SELECT `+accountColumns+` FROM accounts a WHERE a.id = $1`, accountID))
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to get account"})
+		return
+	}
+
+	c.JSON(200, account)
+}
+
+// DeleteAccount deletes an account. Expenses paid from it are kept but no
+// longer reference an account.
+func DeleteAccount(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	accountID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid account id"})
+		return
+	}
+
+	var ownerID uuid.UUID
+	err = db.Pool.QueryRow(c.Request.Context(),
+		`SELECT user_id FROM accounts WHERE id = $1`, accountID).Scan(&ownerID)
+	if err != nil {
+		c.JSON(404, gin.H{"error": "account not found"})
+		return
+	}
+	if ownerID != userID {
+		c.JSON(403, gin.H{"error": "not authorized to delete this account"})
+		return
+	}
+
+	err = db.WithTx(c.Request.Context(), func(tx pgx.Tx) error {
+		// Detach expenses here rather than leaving it to ON DELETE SET NULL so
+		// their updated_at moves and cached expense lists are refreshed
+		_, err := tx.Exec(c.Request.Context(),
+			`UPDATE personal_expenses SET account_id = NULL, updated_at = NOW() WHERE account_id = $1`, accountID)
+		if err != nil {
+			return helpers.NewRequestError(500, "failed to update expenses")
+		}
+
+		_, err = tx.Exec(c.Request.Context(), `DELETE FROM accounts WHERE id = $1`, accountID)
+		if err != nil {
+			return helpers.NewRequestError(500, "failed to delete account")
+		}
+		return nil
+	})
+	if err != nil {
+		helpers.RespondError(c, err, "failed to delete account")
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "account deleted successfully"})
+}
+
+// GetLedger lists the expenses paid from an account, newest first, with the
+// running balance after each one
+func GetLedger(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	accountID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid account id"})
+		return
+	}
+
+	// Parse pagination parameters
+	limit := 50 // default
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
+			limit = l
+		}
+	}
+
+	offset := 0
+	if offsetStr := c.Query("offset"); offsetStr != "" {
+		if o, err := strconv.Atoi(offsetStr); err == nil && o >= 0 {
+			offset = o
+		}
+	}
+
+	var openingBalance decimal.Decimal
+	var totalCount int
+	err = db.Pool.QueryRow(c.Request.Context(),
+		`SELECT a.opening_balance, (SELECT COUNT(*) FROM personal_expenses WHERE account_id = a.id)
+		 FROM accounts a WHERE a.id = $1 AND a.user_id = $2`,
+		accountID, userID).Scan(&openingBalance, &totalCount)
+	if err != nil {
+		c.JSON(404, gin.H{"error": "account not found"})
+		return
+	}
+
+	rows, err := db.Pool.Query(c.Request.Context(),
+		`SELECT id, description, amount, expense_date, running_balance FROM (
+		     SELECT id, description, amount, expense_date, created_at,
+		            $2::numeric - SUM(amount) OVER (ORDER BY expense_date, created_at, id) AS running_balance
+		     FROM personal_expenses
+		     WHERE account_id = $1
+		 ) ledger
+		 ORDER BY expense_date DESC, created_at DESC, id DESC
+		 LIMIT $3 OFFSET $4`,
+		accountID, openingBalance, limit, offset)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to retrieve ledger"})
+		return
+	}
+	defer rows.Close()
+
+	entries := []LedgerEntry{}
+	for rows.Next() {
+		var e LedgerEntry
+		if err := rows.Scan(&e.ExpenseID, &e.Description, &e.Amount, &e.ExpenseDate, &e.RunningBalance); err != nil {
+			c.JSON(500, gin.H{"error": "failed to scan ledger entry"})
+			return
+		}
+		entries = append(entries, e)
+	}
+
+	c.JSON(200, gin.H{
+		"account_id":      accountID,
+		"opening_balance": openingBalance,
+		"entries":         entries,
+		"pagination": gin.H{
+			"limit":  limit,
+			"offset": offset,
+			"total":  totalCount,
+		},
+	})
+}
@@ -1,21 +1,26 @@
 package auth
 
 import (
+	"context"
+	"errors"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/go-playground/validator/v10"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"golang.org/x/crypto/bcrypt"
 
 	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+	"github.com/yanonymousV2/finance-manager-backend/internal/helpers"
 	"github.com/yanonymousV2/finance-manager-backend/internal/user"
+	"github.com/yanonymousV2/finance-manager-backend/internal/validation"
 )
 
 type SignupRequest struct {
 	Email    string `json:"email" validate:"required,email"`
 	Password string `json:"password" validate:"required,min=6"`
+	Currency string `json:"currency,omitempty" validate:"omitempty,iso4217"`
 }
 
 type LoginRequest struct {
@@ -37,19 +42,15 @@ type Claims struct {
 type AuthService struct {
 	DB        *db.DB
 	JWTSecret string
+	// OnSignup, when set, runs inside the signup transaction to set up the
+	// new user's data
+	OnSignup func(ctx context.Context, tx pgx.Tx, userID uuid.UUID) error
 }
 
 func Signup(c *gin.Context, service *AuthService) {
 	db := service.DB
 	var req SignupRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
-		return
-	}
-
-	validate := validator.New()
-	if err := validate.Struct(req); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
+	if !validation.Bind(c, &req) {
 		return
 	}
 
@@ -72,13 +73,30 @@ func Signup(c *gin.Context, service *AuthService) {
 		return
 	}
 
-	// Insert user
+	currency := req.Currency
+	if currency == "" {
+		currency = user.DefaultCurrency
+	}
+
+	// Create the user and their defaults together
 	var u user.User
-	err = db.Pool.QueryRow(c.Request.Context(),
-		"INSERT INTO users (email, password_hash) VALUES ($1, $2) RETURNING id, email, created_at",
-		req.Email, string(hash)).Scan(&u.ID, &u.Email, &u.CreatedAt)
+	err = db.WithTx(c.Request.Context(), func(tx pgx.Tx) error {
+		err := tx.QueryRow(c.Request.Context(),
+			"INSERT INTO users (email, password_hash, default_currency) VALUES ($1, $2, $3) RETURNING id, email, default_currency, created_at",
+			req.Email, string(hash), currency).Scan(&u.ID, &u.Email, &u.DefaultCurrency, &u.CreatedAt)
+		if err != nil {
+			return helpers.NewRequestError(500, "failed to create user")
+		}
+
+		if service.OnSignup != nil {
+			if err := service.OnSignup(c.Request.Context(), tx, u.ID); err != nil {
+				return helpers.NewRequestError(500, "failed to set up user")
+			}
+		}
+		return nil
+	})
 	if err != nil {
-		c.JSON(500, gin.H{"error": "failed to create user"})
+		helpers.RespondError(c, err, "failed to create user")
 		return
 	}
 
@@ -95,22 +113,15 @@ func Signup(c *gin.Context, service *AuthService) {
 func Login(c *gin.Context, service *AuthService) {
 	db := service.DB
 	var req LoginRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
-		return
-	}
-
-	validate := validator.New()
-	if err := validate.Struct(req); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
+	if !validation.Bind(c, &req) {
 		return
 	}
 
 	// Get user
 	var u user.User
 	err := db.Pool.QueryRow(c.Request.Context(),
-		"SELECT id, email, password_hash, created_at FROM users WHERE email = $1", req.Email).Scan(
-		&u.ID, &u.Email, &u.PasswordHash, &u.CreatedAt)
+		"SELECT id, email, password_hash, default_currency, created_at FROM users WHERE email = $1", req.Email).Scan(
+		&u.ID, &u.Email, &u.PasswordHash, &u.DefaultCurrency, &u.CreatedAt)
 	if err != nil {
 		c.JSON(401, gin.H{"error": "invalid credentials"})
 		return
@@ -144,3 +155,43 @@ func generateToken(userID uuid.UUID, email string, jwtSecret string) (string, er
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString([]byte(jwtSecret))
 }
+
+// ErrInvalidToken is returned by ParseToken for a token that's malformed,
+// expired or not signed with the secret
+var ErrInvalidToken = errors.New("invalid token")
+
+// ParseToken returns the claims of a token issued by Login or Signup
+func ParseToken(tokenString, jwtSecret string) (*Claims, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &Claims{}, func(token *jwt.Token) (interface{}, error) {
+		return []byte(jwtSecret), nil
+	})
+	if err != nil || !token.Valid {
+		return nil, ErrInvalidToken
+	}
+	claims, ok := token.Claims.(*Claims)
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+	return claims, nil
+}
+
+// ErrUserNotFound is returned by ResetPassword for an unknown email
+var ErrUserNotFound = errors.New("user not found")
+
+// ResetPassword sets the password of the user with email, for operators
+// helping someone who is locked out
+func ResetPassword(ctx context.Context, db *db.DB, email, password string) error {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+	tag, err := db.Pool.Exec(ctx,
+		"UPDATE users SET password_hash = $2 WHERE email = $1 AND NOT is_placeholder", email, string(hash))
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package budget
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+	"github.com/yanonymousV2/finance-manager-backend/internal/event"
+	"github.com/yanonymousV2/finance-manager-backend/internal/notification"
+)
+
+// AlertThresholds are the percentages of a budget at which the user is
+// alerted, once each per budget
+var AlertThresholds = []int{80, 100}
+
+// crossed returns the thresholds that spent has reached of effective, the
+// budget plus anything carried over. An overspent carryover can leave
+// effective at zero or below, in which case any spending crosses them all.
+func crossed(effective, spent decimal.Decimal) []int {
+	if !spent.IsPositive() {
+		return nil
+	}
+	var thresholds []int
+	for _, t := range AlertThresholds {
+		if spent.Mul(decimal.NewFromInt(100)).GreaterThanOrEqual(effective.Mul(decimal.NewFromInt(int64(t)))) {
+			thresholds = append(thresholds, t)
+		}
+	}
+	return thresholds
+}
+
+// EvaluateAlerts checks every budget covering now that still has alerts to
+// send, and notifies its owner of the highest threshold newly crossed.
+// Crossing 100% also records a budget.exceeded event. Spending is counted
+// in the user's default currency, as on the dashboard.
+func EvaluateAlerts(ctx context.Context, db *db.DB, now time.Time) error {
+	rows, err := db.Pool.Query(ctx,
+		`SELECT `+budgetColumns+`,
+		        (SELECT u.default_currency FROM users u WHERE u.id = monthly_budgets.user_id)
+		 FROM monthly_budgets
+		 WHERE start_date <= $1 AND end_date >= $1
+		   AND (SELECT COUNT(*) FROM budget_alerts a WHERE a.budget_id = monthly_budgets.id) < $2`,
+		day(now), len(AlertThresholds))
+	if err != nil {
+		return fmt.Errorf("failed to find budgets: %w", err)
+	}
+	type pending struct {
+		Budget
+		currency string
+	}
+	budgets, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (pending, error) {
+		var p pending
+		err := row.Scan(&p.ID, &p.UserID, &p.Amount, &p.Period, &p.StartDate, &p.EndDate,
+			&p.Month, &p.Year, &p.Rollover, &p.CreatedAt, &p.UpdatedAt, &p.currency)
+		return p, err
+	})
+	if err != nil {
+		return fmt.Errorf("failed to find budgets: %w", err)
+	}
+
+	sent := 0
+	for _, p := range budgets {
+		// Stop between budgets on shutdown, never half way through one
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		ok, err := evaluate(ctx, db, p.Budget, p.currency)
+		if err != nil {
+			return fmt.Errorf("failed to evaluate budget %s: %w", p.ID, err)
+		}
+		if ok {
+			sent++
+		}
+	}
+	if sent > 0 {
+		slog.Info("sent budget alerts", "count", sent)
+	}
+	return nil
+}
+
+// evaluate alerts the owner of b if it has crossed a threshold they haven't
+// been alerted about, and reports whether it did
+func evaluate(ctx context.Context, db *db.DB, b Budget, currency string) (bool, error) {
+	carried, err := Carryover(ctx, db, b, currency)
+	if err != nil {
+		return false, err
+	}
+	effective := b.Amount.Add(carried)
+
+	var spent decimal.Decimal
+	err = db.Pool.QueryRow(ctx,
+		`SELECT COALESCE(SUM(amount), 0) FROM personal_expenses
+		 WHERE user_id = $1 AND currency = $2 AND expense_date >= $3 AND expense_date < $4`,
+		b.UserID, currency, b.StartDate, b.EndDate.AddDate(0, 0, 1)).Scan(&spent)
+	if err != nil {
+		return false, err
+	}
+	thresholds := crossed(effective, spent)
+	if len(thresholds) == 0 {
+		return false, nil
+	}
+
+	notified := false
+	err = db.WithTx(ctx, func(tx pgx.Tx) error {
+		// Claim the thresholds; ones already alerted, by this server or
+		// another, are skipped
+		rows, err := tx.Query(ctx,
+			`INSERT INTO budget_alerts (budget_id, threshold) SELECT $1, unnest($2::int[])
+			 ON CONFLICT DO NOTHING RETURNING threshold`, b.ID, thresholds)
+		if err != nil {
+			return err
+		}
+		claimed, err := pgx.CollectRows(rows, pgx.RowTo[int])
+		if err != nil || len(claimed) == 0 {
+			return err
+		}
+		highest := claimed[0]
+		for _, t := range claimed {
+			highest = max(highest, t)
+		}
+
+		data := map[string]any{
+			"budget_id":        b.ID,
+			"period":           b.Period,
+			"start_date":       b.StartDate.Format("2006-01-02"),
+			"end_date":         b.EndDate.Format("2006-01-02"),
+			"amount":           b.Amount,
+			"effective_budget": effective,
+			"spent":            spent,
+			"currency":         currency,
+			"threshold":        highest,
+		}
+		if _, err := notification.Create(ctx, tx, b.UserID, nil, notification.TypeBudgetThreshold, data); err != nil {
+			return err
+		}
+		notified = true
+		if highest < 100 {
+			return nil
+		}
+		return event.Record(ctx, tx, event.Event{Type: event.BudgetExceeded, UserID: b.UserID, Data: data})
+	})
+	return notified, err
+}
@@ -0,0 +1,22 @@
+package budget
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCrossed(t *testing.T) {
+	d := decimal.RequireFromString
+	assert.Empty(t, crossed(d("500"), d("0")))
+	assert.Empty(t, crossed(d("500"), d("399.99")))
+	assert.Equal(t, []int{80}, crossed(d("500"), d("400")))
+	assert.Equal(t, []int{80}, crossed(d("500"), d("499.99")))
+	assert.Equal(t, []int{80, 100}, crossed(d("500"), d("500")))
+	assert.Equal(t, []int{80, 100}, crossed(d("500"), d("750")))
+
+	// An overspent carryover leaves nothing to spend
+	assert.Equal(t, []int{80, 100}, crossed(d("-20"), d("1")))
+	assert.Empty(t, crossed(d("-20"), d("0")))
+}
@@ -1,36 +1,110 @@
 package budget
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/shopspring/decimal"
 
 	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+	"github.com/yanonymousV2/finance-manager-backend/internal/helpers"
 	"github.com/yanonymousV2/finance-manager-backend/internal/middleware"
+	"github.com/yanonymousV2/finance-manager-backend/internal/validation"
 )
 
-type MonthlyBudget struct {
+// Budget is a spending budget for a period. StartDate and EndDate are the
+// first and last day it covers; Month and Year are set for monthly budgets
+// and Year for yearly ones.
+type Budget struct {
 	ID        uuid.UUID       `json:"id" db:"id"`
 	UserID    uuid.UUID       `json:"user_id" db:"user_id"`
 	Amount    decimal.Decimal `json:"amount" db:"amount"`
-	Month     int             `json:"month" db:"month"`
-	Year      int             `json:"year" db:"year"`
+	Period    string          `json:"period" db:"period"`
+	StartDate time.Time       `json:"start_date" db:"start_date"`
+	EndDate   time.Time       `json:"end_date" db:"end_date"`
+	Month     *int            `json:"month,omitempty" db:"month"`
+	Year      *int            `json:"year,omitempty" db:"year"`
+	Rollover  bool            `json:"rollover" db:"rollover"`
 	CreatedAt time.Time       `json:"created_at" db:"created_at"`
 	UpdatedAt time.Time       `json:"updated_at" db:"updated_at"`
 }
 
+// budgetColumns is the column list scanBudget expects
+const budgetColumns = "id, user_id, amount, period, start_date, end_date, month, year, rollover, created_at, updated_at"
+
+func scanBudget(row pgx.Row) (Budget, error) {
+	var b Budget
+	err := row.Scan(&b.ID, &b.UserID, &b.Amount, &b.Period, &b.StartDate, &b.EndDate,
+		&b.Month, &b.Year, &b.Rollover, &b.CreatedAt, &b.UpdatedAt)
+	return b, err
+}
+
+// SetBudgetRequest describes the period by month and year (monthly), year
+// (yearly), start_date (weekly) or start_date and end_date (custom). Period
+// defaults to monthly.
 type SetBudgetRequest struct {
-	Amount string `json:"amount" validate:"required,numeric"`
-	Month  int    `json:"month" validate:"required,min=1,max=12"`
-	Year   int    `json:"year" validate:"required,min=2000,max=2100"`
+	Amount    string `json:"amount" validate:"required,numeric"`
+	Period    string `json:"period,omitempty" validate:"omitempty,oneof=weekly monthly yearly custom"`
+	Month     int    `json:"month,omitempty" validate:"omitempty,min=1,max=12"`
+	Year      int    `json:"year,omitempty" validate:"omitempty,min=2000,max=2100"`
+	StartDate string `json:"start_date,omitempty"`
+	EndDate   string `json:"end_date,omitempty"`
+	// Rollover carries what is left (or overspent) into the next period.
+	// Left out, a new budget has none and an existing one keeps its setting.
+	Rollover *bool `json:"rollover,omitempty"`
+}
+
+// Find returns the user's budget of the given period type covering date, or
+// nil when there is none
+func Find(ctx context.Context, db *db.DB, userID uuid.UUID, period string, date time.Time) (*Budget, error) {
+	b, err := scanBudget(db.Pool.QueryRow(ctx,
+		`SELECT `+budgetColumns+`
+		 FROM monthly_budgets
+		 WHERE user_id = $1 AND period = $2 AND start_date <= $3 AND end_date >= $3
+		 ORDER BY start_date DESC
+		 LIMIT 1`,
+		userID, period, day(date)))
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &b, nil
+}
+
+// Between returns the user's budgets of any period type that start or end
+// between from and to (both inclusive), by start date
+func Between(ctx context.Context, db *db.DB, userID uuid.UUID, from, to time.Time) ([]Budget, error) {
+	rows, err := db.Pool.Query(ctx,
+		`SELECT `+budgetColumns+`
+		 FROM monthly_budgets
+		 WHERE user_id = $1 AND ((start_date >= $2 AND start_date <= $3) OR (end_date >= $2 AND end_date <= $3))
+		 ORDER BY start_date, period`,
+		userID, day(from), day(to))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var budgets []Budget
+	for rows.Next() {
+		b, err := scanBudget(rows)
+		if err != nil {
+			return nil, err
+		}
+		budgets = append(budgets, b)
+	}
+	return budgets, rows.Err()
 }
 
-// SetMonthlyBudget sets or updates the budget for a specific month
-func SetMonthlyBudget(c *gin.Context, db *db.DB) {
+// SetBudget sets or updates the budget for a period
+func SetBudget(c *gin.Context, db *db.DB) {
 	userID, ok := middleware.GetUserID(c)
 	if !ok {
 		c.JSON(401, gin.H{"error": "unauthorized"})
@@ -38,14 +112,7 @@ func SetMonthlyBudget(c *gin.Context, db *db.DB) {
 	}
 
 	var req SetBudgetRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
-		return
-	}
-
-	validate := validator.New()
-	if err := validate.Struct(req); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
+	if !validation.Bind(c, &req) {
 		return
 	}
 
@@ -61,17 +128,32 @@ func SetMonthlyBudget(c *gin.Context, db *db.DB) {
 		return
 	}
 
+	start, end, err := periodRange(req)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	period := req.Period
+	if period == "" {
+		period = PeriodMonthly
+	}
+	var month, year *int
+	switch period {
+	case PeriodMonthly:
+		month, year = &req.Month, &req.Year
+	case PeriodYearly:
+		year = &req.Year
+	}
+
 	// Upsert budget
-	var budget MonthlyBudget
-	err = db.Pool.QueryRow(c.Request.Context(),
-		`INSERT INTO monthly_budgets (user_id, amount, month, year, updated_at) 
-		 VALUES ($1, $2, $3, $4, NOW()) 
-		 ON CONFLICT (user_id, month, year) 
-		 DO UPDATE SET amount = $2, updated_at = NOW()
-		 RETURNING id, user_id, amount, month, year, created_at, updated_at`,
-		userID, amount, req.Month, req.Year).Scan(
-		&budget.ID, &budget.UserID, &budget.Amount, &budget.Month, &budget.Year,
-		&budget.CreatedAt, &budget.UpdatedAt)
+	budget, err := scanBudget(db.Pool.QueryRow(c.Request.Context(),
+		`INSERT INTO monthly_budgets (user_id, amount, period, start_date, end_date, month, year, rollover, updated_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, COALESCE($8, FALSE), NOW())
+		 ON CONFLICT (user_id, period, start_date)
+		 DO UPDATE SET amount = $2, end_date = $5, rollover = COALESCE($8, monthly_budgets.rollover), updated_at = NOW()
+		 RETURNING `+budgetColumns,
+		userID, amount, period, start, end, month, year, req.Rollover))
 	if err != nil {
 		c.JSON(500, gin.H{"error": "failed to set budget"})
 		return
@@ -80,55 +162,117 @@ func SetMonthlyBudget(c *gin.Context, db *db.DB) {
 	c.JSON(200, budget)
 }
 
-// GetMonthlyBudget retrieves the budget for a specific month
-func GetMonthlyBudget(c *gin.Context, db *db.DB) {
+// lookupDate returns the day whose budget GetBudget looks up: date if given,
+// else the first day of the requested month (monthly) or year (yearly), else
+// today
+func lookupDate(period, monthStr, yearStr, dateStr string, now time.Time) (time.Time, error) {
+	if dateStr != "" {
+		date, err := time.Parse(dateLayout, dateStr)
+		if err != nil {
+			return time.Time{}, errors.New("invalid date, use YYYY-MM-DD")
+		}
+		return date, nil
+	}
+
+	month, year := int(now.Month()), now.Year()
+	if monthStr != "" {
+		if _, err := fmt.Sscanf(monthStr, "%d", &month); err != nil || month < 1 || month > 12 {
+			return time.Time{}, errors.New("invalid month")
+		}
+	}
+	if yearStr != "" {
+		if _, err := fmt.Sscanf(yearStr, "%d", &year); err != nil || year < 2000 || year > 2100 {
+			return time.Time{}, errors.New("invalid year")
+		}
+	}
+
+	switch period {
+	case PeriodMonthly:
+		return time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC), nil
+	case PeriodYearly:
+		return time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC), nil
+	}
+	return day(now), nil
+}
+
+// GetBudget retrieves the budget of a period type covering a date. Monthly
+// budgets can also be looked up by month and year, which default to the
+// current month.
+func GetBudget(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	period := c.DefaultQuery("period", PeriodMonthly)
+	if period != PeriodWeekly && period != PeriodMonthly && period != PeriodYearly && period != PeriodCustom {
+		c.JSON(400, gin.H{"error": "invalid period"})
+		return
+	}
+
+	date, err := lookupDate(period, c.Query("month"), c.Query("year"), c.Query("date"), time.Now())
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	budget, err := Find(c.Request.Context(), db, userID, period, date)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to retrieve budget"})
+		return
+	}
+	if budget == nil {
+		c.JSON(404, gin.H{"error": "budget not found for this period"})
+		return
+	}
+
+	c.JSON(200, budget)
+}
+
+// DeleteBudget removes the budget of a period type covering a date, looked up
+// the same way as GetBudget
+func DeleteBudget(c *gin.Context, db *db.DB) {
 	userID, ok := middleware.GetUserID(c)
 	if !ok {
 		c.JSON(401, gin.H{"error": "unauthorized"})
 		return
 	}
 
-	monthStr := c.Query("month")
-	yearStr := c.Query("year")
+	period := c.DefaultQuery("period", PeriodMonthly)
+	if period != PeriodWeekly && period != PeriodMonthly && period != PeriodYearly && period != PeriodCustom {
+		c.JSON(400, gin.H{"error": "invalid period"})
+		return
+	}
 
-	if monthStr == "" || yearStr == "" {
-		// Default to current month
-		now := time.Now()
-		if monthStr == "" {
-			monthStr = now.Format("1")
-		}
-		if yearStr == "" {
-			yearStr = now.Format("2006")
-		}
+	date, err := lookupDate(period, c.Query("month"), c.Query("year"), c.Query("date"), time.Now())
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
 	}
 
-	var month, year int
-	if _, err := fmt.Sscanf(monthStr, "%d", &month); err != nil || month < 1 || month > 12 {
-		c.JSON(400, gin.H{"error": "invalid month"})
+	budget, err := Find(c.Request.Context(), db, userID, period, date)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to retrieve budget"})
 		return
 	}
-	if _, err := fmt.Sscanf(yearStr, "%d", &year); err != nil || year < 2000 || year > 2100 {
-		c.JSON(400, gin.H{"error": "invalid year"})
+	if budget == nil {
+		c.JSON(404, gin.H{"error": "budget not found for this period"})
 		return
 	}
 
-	var budget MonthlyBudget
-	err := db.Pool.QueryRow(c.Request.Context(),
-		`SELECT id, user_id, amount, month, year, created_at, updated_at 
-		 FROM monthly_budgets 
-		 WHERE user_id = $1 AND month = $2 AND year = $3`,
-		userID, month, year).Scan(
-		&budget.ID, &budget.UserID, &budget.Amount, &budget.Month, &budget.Year,
-		&budget.CreatedAt, &budget.UpdatedAt)
+	_, err = db.Pool.Exec(c.Request.Context(),
+		`DELETE FROM monthly_budgets WHERE id = $1 AND user_id = $2`,
+		budget.ID, userID)
 	if err != nil {
-		c.JSON(404, gin.H{"error": "budget not found for this month"})
+		c.JSON(500, gin.H{"error": "failed to delete budget"})
 		return
 	}
 
-	c.JSON(200, budget)
+	c.JSON(200, gin.H{"message": "budget deleted successfully"})
 }
 
-// ListBudgets retrieves all budgets for a user
+// ListBudgets retrieves all budgets for a user, optionally of one period type
 func ListBudgets(c *gin.Context, db *db.DB) {
 	userID, ok := middleware.GetUserID(c)
 	if !ok {
@@ -136,23 +280,27 @@ func ListBudgets(c *gin.Context, db *db.DB) {
 		return
 	}
 
-	rows, err := db.Pool.Query(c.Request.Context(),
-		`SELECT id, user_id, amount, month, year, created_at, updated_at 
-		 FROM monthly_budgets 
-		 WHERE user_id = $1 
-		 ORDER BY year DESC, month DESC`,
-		userID)
+	query := `SELECT ` + budgetColumns + `
+		 FROM monthly_budgets
+		 WHERE user_id = $1`
+	args := []interface{}{userID}
+	if period := c.Query("period"); period != "" {
+		query += ` AND period = $2`
+		args = append(args, period)
+	}
+	query += ` ORDER BY start_date DESC, period`
+
+	rows, err := db.Pool.Query(c.Request.Context(), query, args...)
 	if err != nil {
 		c.JSON(500, gin.H{"error": "failed to retrieve budgets"})
 		return
 	}
 	defer rows.Close()
 
-	var budgets []MonthlyBudget
+	var budgets []Budget
 	for rows.Next() {
-		var budget MonthlyBudget
-		if err := rows.Scan(&budget.ID, &budget.UserID, &budget.Amount, &budget.Month,
-			&budget.Year, &budget.CreatedAt, &budget.UpdatedAt); err != nil {
+		budget, err := scanBudget(rows)
+		if err != nil {
 			c.JSON(500, gin.H{"error": "failed to scan budget"})
 			return
 		}
@@ -160,7 +308,17 @@ func ListBudgets(c *gin.Context, db *db.DB) {
 	}
 
 	if budgets == nil {
-		budgets = []MonthlyBudget{}
+		budgets = []Budget{}
+	}
+
+	var lastUpdated *time.Time
+	for i := range budgets {
+		if lastUpdated == nil || budgets[i].UpdatedAt.After(*lastUpdated) {
+			lastUpdated = &budgets[i].UpdatedAt
+		}
+	}
+	if helpers.NotModified(c, helpers.ETag(len(budgets), lastUpdated)) {
+		return
 	}
 
 	c.JSON(200, budgets)
@@ -0,0 +1,85 @@
+package budget
+
+import (
+	"errors"
+	"time"
+)
+
+const (
+	PeriodWeekly  = "weekly"
+	PeriodMonthly = "monthly"
+	PeriodYearly  = "yearly"
+	PeriodCustom  = "custom"
+)
+
+const dateLayout = "2006-01-02"
+
+// day truncates t to midnight UTC of its date
+func day(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// periodRange returns the first and last day of the budget period described
+// by req. Weekly budgets run for seven days from start_date so they can
+// follow a paycheck rather than the calendar week.
+func periodRange(req SetBudgetRequest) (time.Time, time.Time, error) {
+	switch req.Period {
+	case "", PeriodMonthly:
+		if req.Month == 0 || req.Year == 0 {
+			return time.Time{}, time.Time{}, errors.New("month and year are required for monthly budgets")
+		}
+		start := time.Date(req.Year, time.Month(req.Month), 1, 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(0, 1, -1), nil
+	case PeriodYearly:
+		if req.Year == 0 {
+			return time.Time{}, time.Time{}, errors.New("year is required for yearly budgets")
+		}
+		start := time.Date(req.Year, time.January, 1, 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(1, 0, -1), nil
+	case PeriodWeekly:
+		if req.StartDate == "" {
+			return time.Time{}, time.Time{}, errors.New("start_date is required for weekly budgets")
+		}
+		start, err := time.Parse(dateLayout, req.StartDate)
+		if err != nil {
+			return time.Time{}, time.Time{}, errors.New("invalid start_date, use YYYY-MM-DD")
+		}
+		return start, start.AddDate(0, 0, 6), nil
+	case PeriodCustom:
+		if req.StartDate == "" || req.EndDate == "" {
+			return time.Time{}, time.Time{}, errors.New("start_date and end_date are required for custom budgets")
+		}
+		start, err := time.Parse(dateLayout, req.StartDate)
+		if err != nil {
+			return time.Time{}, time.Time{}, errors.New("invalid start_date, use YYYY-MM-DD")
+		}
+		end, err := time.Parse(dateLayout, req.EndDate)
+		if err != nil {
+			return time.Time{}, time.Time{}, errors.New("invalid end_date, use YYYY-MM-DD")
+		}
+		if end.Before(start) {
+			return time.Time{}, time.Time{}, errors.New("end_date cannot be before start_date")
+		}
+		return start, end, nil
+	}
+	return time.Time{}, time.Time{}, errors.New("invalid period")
+}
+
+// CalendarRange returns the first and last day of the calendar week
+// (Monday to Sunday), month or year containing date. Custom periods only
+// exist as budgets, so they have no calendar range.
+func CalendarRange(period string, date time.Time) (time.Time, time.Time, bool) {
+	date = day(date)
+	switch period {
+	case PeriodWeekly:
+		start := date.AddDate(0, 0, -((int(date.Weekday()) + 6) % 7))
+		return start, start.AddDate(0, 0, 6), true
+	case PeriodMonthly:
+		start := time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(0, 1, -1), true
+	case PeriodYearly:
+		start := time.Date(date.Year(), time.January, 1, 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(1, 0, -1), true
+	}
+	return time.Time{}, time.Time{}, false
+}
@@ -0,0 +1,103 @@
+package budget
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func ymd(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestPeriodRange(t *testing.T) {
+	tests := []struct {
+		name      string
+		req       SetBudgetRequest
+		wantStart time.Time
+		wantEnd   time.Time
+		wantErr   bool
+	}{
+		{name: "monthly by default", req: SetBudgetRequest{Month: 2, Year: 2028}, wantStart: ymd(2028, 2, 1), wantEnd: ymd(2028, 2, 29)},
+		{name: "monthly needs year", req: SetBudgetRequest{Period: PeriodMonthly, Month: 2}, wantErr: true},
+		{name: "yearly", req: SetBudgetRequest{Period: PeriodYearly, Year: 2026}, wantStart: ymd(2026, 1, 1), wantEnd: ymd(2026, 12, 31)},
+		{name: "weekly from any day", req: SetBudgetRequest{Period: PeriodWeekly, StartDate: "2026-10-15"}, wantStart: ymd(2026, 10, 15), wantEnd: ymd(2026, 10, 21)},
+		{name: "weekly needs start", req: SetBudgetRequest{Period: PeriodWeekly}, wantErr: true},
+		{name: "custom", req: SetBudgetRequest{Period: PeriodCustom, StartDate: "2026-10-10", EndDate: "2026-10-24"}, wantStart: ymd(2026, 10, 10), wantEnd: ymd(2026, 10, 24)},
+		{name: "custom single day", req: SetBudgetRequest{Period: PeriodCustom, StartDate: "2026-10-10", EndDate: "2026-10-10"}, wantStart: ymd(2026, 10, 10), wantEnd: ymd(2026, 10, 10)},
+		{name: "custom backwards", req: SetBudgetRequest{Period: PeriodCustom, StartDate: "2026-10-10", EndDate: "2026-10-09"}, wantErr: true},
+		{name: "custom bad date", req: SetBudgetRequest{Period: PeriodCustom, StartDate: "10/10/2026", EndDate: "2026-10-24"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end, err := periodRange(tt.req)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.wantStart, start)
+			assert.Equal(t, tt.wantEnd, end)
+		})
+	}
+}
+
+func TestCalendarRange(t *testing.T) {
+	// A Thursday
+	thursday := time.Date(2026, 10, 15, 18, 30, 0, 0, time.UTC)
+
+	start, end, ok := CalendarRange(PeriodWeekly, thursday)
+	assert.True(t, ok)
+	assert.Equal(t, ymd(2026, 10, 12), start)
+	assert.Equal(t, ymd(2026, 10, 18), end)
+
+	start, end, ok = CalendarRange(PeriodWeekly, ymd(2026, 10, 18))
+	assert.True(t, ok)
+	assert.Equal(t, ymd(2026, 10, 12), start, "Sunday belongs to the week before")
+	assert.Equal(t, ymd(2026, 10, 18), end)
+
+	start, end, ok = CalendarRange(PeriodMonthly, thursday)
+	assert.True(t, ok)
+	assert.Equal(t, ymd(2026, 10, 1), start)
+	assert.Equal(t, ymd(2026, 10, 31), end)
+
+	start, end, ok = CalendarRange(PeriodYearly, thursday)
+	assert.True(t, ok)
+	assert.Equal(t, ymd(2026, 1, 1), start)
+	assert.Equal(t, ymd(2026, 12, 31), end)
+
+	_, _, ok = CalendarRange(PeriodCustom, thursday)
+	assert.False(t, ok)
+}
+
+func TestLookupDate(t *testing.T) {
+	now := time.Date(2026, 10, 15, 18, 30, 0, 0, time.UTC)
+
+	got, err := lookupDate(PeriodMonthly, "", "", "", now)
+	require.NoError(t, err)
+	assert.Equal(t, ymd(2026, 10, 1), got)
+
+	got, err = lookupDate(PeriodMonthly, "2", "2026", "", now)
+	require.NoError(t, err)
+	assert.Equal(t, ymd(2026, 2, 1), got)
+
+	got, err = lookupDate(PeriodYearly, "", "2025", "", now)
+	require.NoError(t, err)
+	assert.Equal(t, ymd(2025, 1, 1), got)
+
+	got, err = lookupDate(PeriodWeekly, "", "", "", now)
+	require.NoError(t, err)
+	assert.Equal(t, ymd(2026, 10, 15), got)
+
+	got, err = lookupDate(PeriodCustom, "", "", "2026-09-30", now)
+	require.NoError(t, err)
+	assert.Equal(t, ymd(2026, 9, 30), got)
+
+	_, err = lookupDate(PeriodMonthly, "13", "2026", "", now)
+	assert.Error(t, err)
+	_, err = lookupDate(PeriodWeekly, "", "", "yesterday", now)
+	assert.Error(t, err)
+}
@@ -0,0 +1,76 @@
+package budget
+
+import (
+	"context"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+)
+
+// periodResult is a past period's budget and what was spent against it
+type periodResult struct {
+	Amount   decimal.Decimal
+	Spent    decimal.Decimal
+	Rollover bool
+}
+
+// carryover returns what the periods, oldest first and ending with the
+// previous one, carry into the next. A period without rollover carries
+// nothing, whatever it received itself.
+func carryover(periods []periodResult) decimal.Decimal {
+	carry := decimal.Zero
+	for _, m := range periods {
+		if !m.Rollover {
+			carry = decimal.Zero
+			continue
+		}
+		carry = m.Amount.Add(carry).Sub(m.Spent)
+	}
+	return carry
+}
+
+// Carryover returns the amount carried into b by the unbroken run of
+// rollover budgets of the same period type right before it. Spending is
+// counted in currency, the user's default.
+func Carryover(ctx context.Context, db *db.DB, b Budget, currency string) (decimal.Decimal, error) {
+	rows, err := db.Pool.Query(ctx,
+		`SELECT b.amount, b.start_date, b.end_date, b.rollover,
+		        (SELECT COALESCE(SUM(pe.amount), 0) FROM personal_expenses pe
+		         WHERE pe.user_id = b.user_id AND pe.currency = $4
+		           AND pe.expense_date >= b.start_date AND pe.expense_date < b.end_date + 1)
+		 FROM monthly_budgets b
+		 WHERE b.user_id = $1 AND b.period = $2 AND b.start_date < $3
+		 ORDER BY b.start_date DESC`,
+		b.UserID, b.Period, b.StartDate, currency)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	defer rows.Close()
+
+	// Walk back while each budget ends the day before the next one starts
+	var chain []periodResult
+	expected := b.StartDate.AddDate(0, 0, -1)
+	for rows.Next() {
+		var m periodResult
+		var start, end time.Time
+		if err := rows.Scan(&m.Amount, &start, &end, &m.Rollover, &m.Spent); err != nil {
+			return decimal.Zero, err
+		}
+		if !end.Equal(expected) || !m.Rollover {
+			break
+		}
+		chain = append(chain, m)
+		expected = start.AddDate(0, 0, -1)
+	}
+	if err := rows.Err(); err != nil {
+		return decimal.Zero, err
+	}
+
+	// Oldest first
+	for i, j := 0, len(chain)-1; i < j; i, j = i+1, j-1 {
+		chain[i], chain[j] = chain[j], chain[i]
+	}
+	return carryover(chain), nil
+}
@@ -0,0 +1,45 @@
+package budget
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCarryover(t *testing.T) {
+	d := decimal.RequireFromString
+
+	tests := []struct {
+		name    string
+		periods []periodResult
+		want    string
+	}{
+		{name: "no periods", want: "0"},
+		{name: "unspent", periods: []periodResult{{Amount: d("500"), Spent: d("420"), Rollover: true}}, want: "80"},
+		{name: "overspent", periods: []periodResult{{Amount: d("500"), Spent: d("560"), Rollover: true}}, want: "-60"},
+		{
+			name: "accumulates",
+			periods: []periodResult{
+				{Amount: d("500"), Spent: d("400"), Rollover: true},
+				{Amount: d("500"), Spent: d("550"), Rollover: true},
+			},
+			want: "50",
+		},
+		{
+			name: "period without rollover resets",
+			periods: []periodResult{
+				{Amount: d("500"), Spent: d("100"), Rollover: true},
+				{Amount: d("500"), Spent: d("500"), Rollover: false},
+				{Amount: d("300"), Spent: d("250"), Rollover: true},
+			},
+			want: "50",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, carryover(tt.periods).String())
+		})
+	}
+}
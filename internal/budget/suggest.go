@@ -0,0 +1,280 @@
+package budget
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+	"github.com/yanonymousV2/finance-manager-backend/internal/helpers"
+	"github.com/yanonymousV2/finance-manager-backend/internal/middleware"
+)
+
+const (
+	minSuggestionMonths     = 3
+	maxSuggestionMonths     = 6
+	defaultSuggestionMonths = 6
+)
+
+var errNoHistory = errors.New("no spending history before this month")
+
+type CategorySuggestion struct {
+	CategoryID   uuid.UUID       `json:"category_id"`
+	CategoryName string          `json:"category_name"`
+	Amount       decimal.Decimal `json:"amount"`
+}
+
+// Suggestion is a monthly budget proposed from the months before it
+type Suggestion struct {
+	Month      int                  `json:"month"`
+	Year       int                  `json:"year"`
+	Currency   string               `json:"currency"`
+	MonthsUsed int                  `json:"months_used"`
+	Amount     decimal.Decimal      `json:"amount"`
+	Categories []CategorySuggestion `json:"categories"`
+}
+
+func median(sorted []decimal.Decimal) decimal.Decimal {
+	n := len(sorted)
+	if n == 0 {
+		return decimal.Zero
+	}
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return sorted[n/2-1].Add(sorted[n/2]).Div(decimal.NewFromInt(2))
+}
+
+// suggestAmount returns the median of the monthly values after dropping
+// outliers more than three median absolute deviations from the median,
+// rounded up to a whole unit
+func suggestAmount(values []decimal.Decimal) decimal.Decimal {
+	sorted := append([]decimal.Decimal(nil), values...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].LessThan(sorted[j]) })
+	mid := median(sorted)
+
+	deviations := make([]decimal.Decimal, len(sorted))
+	for i, v := range sorted {
+		deviations[i] = v.Sub(mid).Abs()
+	}
+	sort.Slice(deviations, func(i, j int) bool { return deviations[i].LessThan(deviations[j]) })
+	limit := median(deviations).Mul(decimal.NewFromInt(3))
+
+	if limit.IsPositive() {
+		kept := sorted[:0:0]
+		for _, v := range sorted {
+			if v.Sub(mid).Abs().LessThanOrEqual(limit) {
+				kept = append(kept, v)
+			}
+		}
+		mid = median(kept)
+	}
+	return mid.Ceil()
+}
+
+// suggest builds the budget for month/year from the spending in up to months
+// full months before it, in the user's default currency. Months before the
+// user's first expense are not counted, so a new user's budget is not pulled
+// down by empty months.
+func suggest(ctx context.Context, db *db.DB, userID uuid.UUID, month, year, months int) (Suggestion, error) {
+	s := Suggestion{Month: month, Year: year, Categories: []CategorySuggestion{}}
+
+	currency, err := helpers.GetUserCurrency(ctx, db, userID)
+	if err != nil {
+		return s, err
+	}
+	s.Currency = currency
+
+	end := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+	start := end.AddDate(0, -months, 0)
+
+	var first *time.Time
+	err = db.Pool.QueryRow(ctx,
+		`SELECT MIN(expense_date) FROM personal_expenses WHERE user_id = $1 AND currency = $2`,
+		userID, currency).Scan(&first)
+	if err != nil {
+		return s, err
+	}
+	if first == nil || !first.Before(end) {
+		return s, errNoHistory
+	}
+	if firstMonth := time.Date(first.Year(), first.Month(), 1, 0, 0, 0, 0, time.UTC); firstMonth.After(start) {
+		start = firstMonth
+	}
+	s.MonthsUsed = (end.Year()-start.Year())*12 + int(end.Month()) - int(start.Month())
+
+	rows, err := db.Pool.Query(ctx,
+		`SELECT date_trunc('month', pe.expense_date), ec.id, ec.name, SUM(pe.amount)
+		 FROM personal_expenses pe
+		 LEFT JOIN expense_categories ec ON pe.category_id = ec.id AND NOT ec.archived
+		 WHERE pe.user_id = $1 AND pe.currency = $2 AND pe.expense_date >= $3 AND pe.expense_date < $4
+		 GROUP BY 1, 2, 3`,
+		userID, currency, start, end)
+	if err != nil {
+		return s, err
+	}
+	defer rows.Close()
+
+	totals := make([]decimal.Decimal, s.MonthsUsed)
+	byCategory := map[uuid.UUID][]decimal.Decimal{}
+	names := map[uuid.UUID]string{}
+	for rows.Next() {
+		var bucket time.Time
+		var categoryID *uuid.UUID
+		var name *string
+		var amount decimal.Decimal
+		if err := rows.Scan(&bucket, &categoryID, &name, &amount); err != nil {
+			return s, err
+		}
+		i := (bucket.Year()-start.Year())*12 + int(bucket.Month()) - int(start.Month())
+		if i < 0 || i >= s.MonthsUsed {
+			continue
+		}
+		totals[i] = totals[i].Add(amount)
+		if categoryID == nil {
+			continue
+		}
+		if byCategory[*categoryID] == nil {
+			byCategory[*categoryID] = make([]decimal.Decimal, s.MonthsUsed)
+			names[*categoryID] = *name
+		}
+		byCategory[*categoryID][i] = amount
+	}
+	if err := rows.Err(); err != nil {
+		return s, err
+	}
+
+	s.Amount = suggestAmount(totals)
+	for categoryID, values := range byCategory {
+		if amount := suggestAmount(values); amount.IsPositive() {
+			s.Categories = append(s.Categories, CategorySuggestion{CategoryID: categoryID, CategoryName: names[categoryID], Amount: amount})
+		}
+	}
+	sort.Slice(s.Categories, func(i, j int) bool {
+		if !s.Categories[i].Amount.Equal(s.Categories[j].Amount) {
+			return s.Categories[i].Amount.GreaterThan(s.Categories[j].Amount)
+		}
+		return s.Categories[i].CategoryName < s.Categories[j].CategoryName
+	})
+	return s, nil
+}
+
+// suggestionParams reads month and year (default next month) and the number
+// of months of history to use
+func suggestionParams(c *gin.Context, now time.Time) (month, year, months int, err error) {
+	next := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, 1, 0)
+	month, year, months = int(next.Month()), next.Year(), defaultSuggestionMonths
+
+	if s := c.Query("month"); s != "" {
+		if _, err := fmt.Sscanf(s, "%d", &month); err != nil || month < 1 || month > 12 {
+			return 0, 0, 0, errors.New("invalid month")
+		}
+	}
+	if s := c.Query("year"); s != "" {
+		if _, err := fmt.Sscanf(s, "%d", &year); err != nil || year < 2000 || year > 2100 {
+			return 0, 0, 0, errors.New("invalid year")
+		}
+	}
+	if s := c.Query("months"); s != "" {
+		months, err = strconv.Atoi(s)
+		if err != nil || months < minSuggestionMonths || months > maxSuggestionMonths {
+			return 0, 0, 0, fmt.Errorf("months must be between %d and %d", minSuggestionMonths, maxSuggestionMonths)
+		}
+	}
+	return month, year, months, nil
+}
+
+// GetBudgetSuggestion proposes a monthly budget and per-category limits from
+// the trailing months of spending
+func GetBudgetSuggestion(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	month, year, months, err := suggestionParams(c, time.Now())
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	s, err := suggest(c.Request.Context(), db, userID, month, year, months)
+	if errors.Is(err, errNoHistory) {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to suggest budget"})
+		return
+	}
+
+	c.JSON(200, s)
+}
+
+// AcceptBudgetSuggestion recomputes the suggestion for the same parameters
+// and applies it: the monthly budget is set to the suggested total and each
+// suggested category gets the suggested monthly limit
+func AcceptBudgetSuggestion(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	month, year, months, err := suggestionParams(c, time.Now())
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	s, err := suggest(c.Request.Context(), db, userID, month, year, months)
+	if errors.Is(err, errNoHistory) {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to suggest budget"})
+		return
+	}
+
+	start := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+	var budget Budget
+	err = db.WithTx(c.Request.Context(), func(tx pgx.Tx) error {
+		var err error
+		budget, err = scanBudget(tx.QueryRow(c.Request.Context(),
+			`INSERT INTO monthly_budgets (user_id, amount, period, start_date, end_date, month, year, updated_at)
+			 VALUES ($1, $2, $3, $4, $5, $6, $7, NOW())
+			 ON CONFLICT (user_id, period, start_date)
+			 DO UPDATE SET amount = $2, updated_at = NOW()
+			 RETURNING `+budgetColumns,
+			userID, s.Amount, PeriodMonthly, start, start.AddDate(0, 1, -1), month, year))
+		if err != nil {
+			return helpers.NewRequestError(500, "failed to set budget")
+		}
+
+		batch := &pgx.Batch{}
+		for _, cs := range s.Categories {
+			batch.Queue(`UPDATE expense_categories SET monthly_limit = $1 WHERE id = $2 AND user_id = $3 AND deleted_at IS NULL`,
+				cs.Amount, cs.CategoryID, userID)
+		}
+		if err := tx.SendBatch(c.Request.Context(), batch).Close(); err != nil {
+			return helpers.NewRequestError(500, "failed to set category limits")
+		}
+		return nil
+	})
+	if err != nil {
+		helpers.RespondError(c, err, "failed to apply budget suggestion")
+		return
+	}
+
+	c.JSON(200, gin.H{"budget": budget, "suggestion": s})
+}
@@ -0,0 +1,38 @@
+package budget
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSuggestAmount(t *testing.T) {
+	d := decimal.RequireFromString
+	values := func(ss ...string) []decimal.Decimal {
+		out := make([]decimal.Decimal, len(ss))
+		for i, s := range ss {
+			out[i] = d(s)
+		}
+		return out
+	}
+
+	tests := []struct {
+		name   string
+		values []decimal.Decimal
+		want   string
+	}{
+		{name: "odd count", values: values("300", "100", "200"), want: "200"},
+		{name: "even count", values: values("100", "200", "300", "400"), want: "250"},
+		{name: "rounds up", values: values("120.10", "120.40", "99.99"), want: "121"},
+		{name: "drops outlier", values: values("500", "520", "480", "510", "490", "3000"), want: "500"},
+		{name: "all equal", values: values("80", "80", "80"), want: "80"},
+		{name: "mostly empty months", values: values("0", "0", "0", "45"), want: "0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, suggestAmount(tt.values).String())
+		})
+	}
+}
@@ -0,0 +1,181 @@
+package calendar
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/budget"
+	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+	"github.com/yanonymousV2/finance-manager-backend/internal/middleware"
+	"github.com/yanonymousV2/finance-manager-backend/internal/recurring"
+)
+
+// Event types
+const (
+	TypeRecurringExpense = "recurring_expense"
+	TypeSubscription     = "subscription"
+	TypeBudgetStart      = "budget_period_start"
+	TypeBudgetEnd        = "budget_period_end"
+)
+
+const (
+	defaultDays = 30
+	maxDays     = 365
+)
+
+// Event is something expected on a day: a charge, with its amount, or the
+// start or end of a budget period
+type Event struct {
+	Date               time.Time        `json:"date"`
+	Type               string           `json:"type"`
+	Title              string           `json:"title"`
+	Amount             *decimal.Decimal `json:"amount,omitempty"`
+	Currency           *string          `json:"currency,omitempty"`
+	RecurringExpenseID *uuid.UUID       `json:"recurring_expense_id,omitempty"`
+	SubscriptionID     *uuid.UUID       `json:"subscription_id,omitempty"`
+	BudgetID           *uuid.UUID       `json:"budget_id,omitempty"`
+	Period             *string          `json:"period,omitempty"`
+}
+
+// Upcoming is the events from From to To, and what the expected charges in
+// the user's default currency add up to
+type Upcoming struct {
+	From          time.Time       `json:"from"`
+	To            time.Time       `json:"to"`
+	Currency      string          `json:"currency"`
+	ExpectedTotal decimal.Decimal `json:"expected_total"`
+	Events        []Event         `json:"events"`
+}
+
+func recurringTitle(r recurring.RecurringExpense) string {
+	switch {
+	case r.Description != nil && *r.Description != "":
+		return *r.Description
+	case r.Merchant != nil:
+		return *r.Merchant
+	default:
+		return "Recurring expense"
+	}
+}
+
+// upcoming lists the due dates of active recurring expenses, the expected
+// charges of detected subscriptions and the budget period boundaries from
+// from to to (both inclusive), by date
+func upcoming(expenses []recurring.RecurringExpense, detected recurring.DetectedSubscriptions, budgets []budget.Budget, from, to time.Time) Upcoming {
+	result := Upcoming{From: from, To: to, Currency: detected.Currency, ExpectedTotal: decimal.Zero, Events: []Event{}}
+
+	for i := range expenses {
+		r := &expenses[i]
+		if !r.Active {
+			continue
+		}
+		// Charges before today that haven't been recorded yet are due now
+		first := r.NextDueDate
+		if first.Before(from) {
+			first = from
+		}
+		for _, due := range recurring.DueDates(r.Frequency, r.StartDate, first, to) {
+			result.Events = append(result.Events, Event{
+				Date: due, Type: TypeRecurringExpense, Title: recurringTitle(*r),
+				Amount: &r.Amount, Currency: &r.Currency, RecurringExpenseID: &r.ID,
+			})
+			if r.Currency == result.Currency {
+				result.ExpectedTotal = result.ExpectedTotal.Add(r.Amount)
+			}
+		}
+	}
+
+	for i := range detected.Subscriptions {
+		s := &detected.Subscriptions[i]
+		for _, due := range recurring.DueDates(s.Frequency, s.LastCharged, from, to) {
+			if !due.After(s.LastCharged) {
+				continue
+			}
+			result.Events = append(result.Events, Event{
+				Date: due, Type: TypeSubscription, Title: s.Merchant,
+				Amount: &s.Amount, Currency: &result.Currency, SubscriptionID: &s.ID,
+			})
+			result.ExpectedTotal = result.ExpectedTotal.Add(s.Amount)
+		}
+	}
+
+	for i := range budgets {
+		b := &budgets[i]
+		if !b.StartDate.Before(from) && !b.StartDate.After(to) {
+			result.Events = append(result.Events, Event{
+				Date: b.StartDate, Type: TypeBudgetStart, Title: "New " + b.Period + " budget starts",
+				Amount: &b.Amount, BudgetID: &b.ID, Period: &b.Period,
+			})
+		}
+		if !b.EndDate.Before(from) && !b.EndDate.After(to) {
+			result.Events = append(result.Events, Event{
+				Date: b.EndDate, Type: TypeBudgetEnd, Title: "Last day of " + b.Period + " budget",
+				Amount: &b.Amount, BudgetID: &b.ID, Period: &b.Period,
+			})
+		}
+	}
+
+	// Charges before budget boundaries on the same day
+	order := map[string]int{TypeRecurringExpense: 0, TypeSubscription: 0, TypeBudgetStart: 1, TypeBudgetEnd: 2}
+	sort.SliceStable(result.Events, func(i, j int) bool {
+		a, b := result.Events[i], result.Events[j]
+		if !a.Date.Equal(b.Date) {
+			return a.Date.Before(b.Date)
+		}
+		if order[a.Type] != order[b.Type] {
+			return order[a.Type] < order[b.Type]
+		}
+		return a.Title < b.Title
+	})
+	return result
+}
+
+// GetUpcoming returns what's expected over the next days days (default 30),
+// today included: recurring expenses and detected subscriptions coming due,
+// and budget periods starting or ending
+func GetUpcoming(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	days := defaultDays
+	if daysStr := c.Query("days"); daysStr != "" {
+		parsed, err := strconv.Atoi(daysStr)
+		if err != nil || parsed < 1 || parsed > maxDays {
+			c.JSON(400, gin.H{"error": "days must be between 1 and " + strconv.Itoa(maxDays)})
+			return
+		}
+		days = parsed
+	}
+
+	now := time.Now().UTC()
+	from := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	to := from.AddDate(0, 0, days-1)
+
+	expenses, err := recurring.List(c.Request.Context(), db, userID)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to get recurring expenses"})
+		return
+	}
+
+	detected, err := recurring.Detect(c.Request.Context(), db, userID, now)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to detect subscriptions"})
+		return
+	}
+
+	budgets, err := budget.Between(c.Request.Context(), db, userID, from, to)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to get budgets"})
+		return
+	}
+
+	c.JSON(200, upcoming(expenses, detected, budgets, from, to))
+}
@@ -0,0 +1,73 @@
+package calendar
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/budget"
+	"github.com/yanonymousV2/finance-manager-backend/internal/recurring"
+)
+
+func ymd(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestUpcoming(t *testing.T) {
+	d := decimal.RequireFromString
+	from, to := ymd(2024, 6, 20), ymd(2024, 7, 19)
+	rent, gym := "Rent", "Gym"
+
+	expenses := []recurring.RecurringExpense{
+		// Due on the 1st; June's is past
+		{ID: uuid.New(), Description: &rent, Amount: d("1200"), Currency: "USD", Frequency: recurring.FrequencyMonthly,
+			StartDate: ymd(2024, 1, 1), NextDueDate: ymd(2024, 7, 1), Active: true},
+		// Weekly, one charge still unrecorded from before today
+		{ID: uuid.New(), Merchant: &gym, Amount: d("10"), Currency: "EUR", Frequency: recurring.FrequencyWeekly,
+			StartDate: ymd(2024, 6, 6), NextDueDate: ymd(2024, 6, 13), Active: true},
+		// Paused
+		{ID: uuid.New(), Description: &rent, Amount: d("99"), Currency: "USD", Frequency: recurring.FrequencyMonthly,
+			StartDate: ymd(2024, 1, 5), NextDueDate: ymd(2024, 7, 5), Active: false},
+	}
+	detected := recurring.DetectedSubscriptions{
+		Currency: "USD",
+		Subscriptions: []recurring.Subscription{
+			{ID: uuid.New(), Merchant: "Streamly", Frequency: recurring.FrequencyMonthly, Amount: d("16.99"),
+				LastCharged: ymd(2024, 6, 3).Add(9 * time.Hour)},
+		},
+	}
+	budgets := []budget.Budget{
+		{ID: uuid.New(), Period: budget.PeriodMonthly, Amount: d("2000"), StartDate: ymd(2024, 6, 1), EndDate: ymd(2024, 6, 30)},
+		{ID: uuid.New(), Period: budget.PeriodMonthly, Amount: d("2000"), StartDate: ymd(2024, 7, 1), EndDate: ymd(2024, 7, 31)},
+	}
+
+	result := upcoming(expenses, detected, budgets, from, to)
+
+	type row struct {
+		date  time.Time
+		kind  string
+		title string
+	}
+	var rows []row
+	for _, e := range result.Events {
+		rows = append(rows, row{e.Date, e.Type, e.Title})
+	}
+	require.Equal(t, []row{
+		{ymd(2024, 6, 20), TypeRecurringExpense, "Gym"},
+		{ymd(2024, 6, 27), TypeRecurringExpense, "Gym"},
+		{ymd(2024, 6, 30), TypeBudgetEnd, "Last day of monthly budget"},
+		{ymd(2024, 7, 1), TypeRecurringExpense, "Rent"},
+		{ymd(2024, 7, 1), TypeBudgetStart, "New monthly budget starts"},
+		{ymd(2024, 7, 3), TypeSubscription, "Streamly"},
+		{ymd(2024, 7, 4), TypeRecurringExpense, "Gym"},
+		{ymd(2024, 7, 11), TypeRecurringExpense, "Gym"},
+		{ymd(2024, 7, 18), TypeRecurringExpense, "Gym"},
+	}, rows)
+
+	// Gym is charged in euros, so only rent and the subscription count
+	assert.Equal(t, "1216.99", result.ExpectedTotal.String())
+}
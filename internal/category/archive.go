@@ -0,0 +1,54 @@
+package category
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+	"github.com/yanonymousV2/finance-manager-backend/internal/helpers"
+	"github.com/yanonymousV2/finance-manager-backend/internal/middleware"
+)
+
+// ArchiveCategory hides a category from category lists. Expenses keep it.
+func ArchiveCategory(c *gin.Context, db *db.DB) {
+	setArchived(c, db, true)
+}
+
+// UnarchiveCategory makes an archived category available again
+func UnarchiveCategory(c *gin.Context, db *db.DB) {
+	setArchived(c, db, false)
+}
+
+func setArchived(c *gin.Context, db *db.DB, archived bool) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	categoryID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid category id"})
+		return
+	}
+
+	ownerID, _, err := helpers.GetCategoryOwner(c.Request.Context(), db.Pool, categoryID)
+	if err != nil {
+		c.JSON(404, gin.H{"error": "category not found"})
+		return
+	}
+	if ownerID != userID {
+		c.JSON(403, gin.H{"error": "not authorized to update this category"})
+		return
+	}
+
+	category, err := scanCategory(db.Pool.QueryRow(c.Request.Context(),
+		`UPDATE expense_categories SET archived = $1 WHERE id = $2 AND deleted_at IS NULL RETURNING `+categoryColumns,
+		archived, categoryID))
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to update category"})
+		return
+	}
+
+	c.JSON(200, category)
+}
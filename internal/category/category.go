@@ -1,36 +1,82 @@
 package category
 
 import (
-	"fmt"
+	"errors"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/shopspring/decimal"
 
 	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+	"github.com/yanonymousV2/finance-manager-backend/internal/db/sqlc"
+	"github.com/yanonymousV2/finance-manager-backend/internal/helpers"
 	"github.com/yanonymousV2/finance-manager-backend/internal/middleware"
+	"github.com/yanonymousV2/finance-manager-backend/internal/softdelete"
+	"github.com/yanonymousV2/finance-manager-backend/internal/validation"
 )
 
 type ExpenseCategory struct {
-	ID        uuid.UUID `json:"id" db:"id"`
-	UserID    uuid.UUID `json:"user_id" db:"user_id"`
-	Name      string    `json:"name" db:"name"`
-	Color     *string   `json:"color,omitempty" db:"color"`
-	Icon      *string   `json:"icon,omitempty" db:"icon"`
-	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	ID        uuid.UUID  `json:"id" db:"id"`
+	UserID    uuid.UUID  `json:"user_id" db:"user_id"`
+	ParentID  *uuid.UUID `json:"parent_id,omitempty" db:"parent_id"`
+	Name      string     `json:"name" db:"name"`
+	Color     *string    `json:"color,omitempty" db:"color"`
+	Icon      *string    `json:"icon,omitempty" db:"icon"`
+	Archived  bool       `json:"archived" db:"archived"`
+	Pinned    bool       `json:"pinned" db:"pinned"`
+	SortOrder *int       `json:"sort_order,omitempty" db:"sort_order"`
+	// MonthlyLimit is a soft limit; going over it sends a notification
+	MonthlyLimit *decimal.Decimal `json:"monthly_limit,omitempty" db:"monthly_limit"`
+	CreatedAt    time.Time        `json:"created_at" db:"created_at"`
+	// Children is only filled in tree listings
+	Children []ExpenseCategory `json:"children,omitempty"`
+}
+
+// categoryColumns selects a category in the order scanCategory reads it
+const categoryColumns = "id, user_id, parent_id, name, color, icon, archived, pinned, sort_order, monthly_limit, created_at"
+
+func scanCategory(row interface{ Scan(...any) error }) (ExpenseCategory, error) {
+	var cat ExpenseCategory
+	err := row.Scan(&cat.ID, &cat.UserID, &cat.ParentID, &cat.Name, &cat.Color, &cat.Icon, &cat.Archived, &cat.Pinned, &cat.SortOrder, &cat.MonthlyLimit, &cat.CreatedAt)
+	return cat, err
 }
 
 type CreateCategoryRequest struct {
-	Name  string  `json:"name" validate:"required,min=1,max=100"`
-	Color *string `json:"color,omitempty" validate:"omitempty,len=7"`
-	Icon  *string `json:"icon,omitempty" validate:"omitempty,max=50"`
+	Name     string     `json:"name" validate:"required,min=1,max=100"`
+	ParentID *uuid.UUID `json:"parent_id,omitempty"`
+	Color    *string    `json:"color,omitempty" validate:"omitempty,len=7"`
+	Icon     *string    `json:"icon,omitempty" validate:"omitempty,max=50"`
+	// MonthlyLimit is in the user's default currency
+	MonthlyLimit *string `json:"monthly_limit,omitempty" validate:"omitempty,numeric"`
 }
 
 type UpdateCategoryRequest struct {
-	Name  *string `json:"name,omitempty" validate:"omitempty,min=1,max=100"`
-	Color *string `json:"color,omitempty" validate:"omitempty,len=7"`
-	Icon  *string `json:"icon,omitempty" validate:"omitempty,max=50"`
+	Name     *string    `json:"name,omitempty" validate:"omitempty,min=1,max=100"`
+	ParentID *uuid.UUID `json:"parent_id,omitempty"`
+	// ClearParent moves the category to the top level
+	ClearParent bool    `json:"clear_parent,omitempty"`
+	Color       *string `json:"color,omitempty" validate:"omitempty,len=7"`
+	Icon        *string `json:"icon,omitempty" validate:"omitempty,max=50"`
+	// A monthly limit of 0 removes the limit
+	MonthlyLimit *string `json:"monthly_limit,omitempty" validate:"omitempty,numeric"`
+}
+
+// parseLimit parses a monthly limit; zero means no limit
+func parseLimit(s string) (*decimal.Decimal, error) {
+	limit, err := decimal.NewFromString(s)
+	if err != nil {
+		return nil, errors.New("invalid monthly_limit format")
+	}
+	if limit.IsNegative() {
+		return nil, errors.New("monthly_limit cannot be negative")
+	}
+	if limit.IsZero() {
+		return nil, nil
+	}
+	return &limit, nil
 }
 
 // CreateCategory creates a new expense category
@@ -42,24 +88,32 @@ func CreateCategory(c *gin.Context, db *db.DB) {
 	}
 
 	var req CreateCategoryRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
+	if !validation.Bind(c, &req) {
 		return
 	}
 
-	validate := validator.New()
-	if err := validate.Struct(req); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
-		return
+	if req.ParentID != nil {
+		if status, err := checkParent(c.Request.Context(), db, userID, uuid.Nil, *req.ParentID); err != nil {
+			c.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
 	}
 
-	var category ExpenseCategory
-	err := db.Pool.QueryRow(c.Request.Context(),
-		`INSERT INTO expense_categories (user_id, name, color, icon) 
-		 VALUES ($1, $2, $3, $4) 
-		 RETURNING id, user_id, name, color, icon, created_at`,
-		userID, req.Name, req.Color, req.Icon).Scan(
-		&category.ID, &category.UserID, &category.Name, &category.Color, &category.Icon, &category.CreatedAt)
+	var monthlyLimit *decimal.Decimal
+	if req.MonthlyLimit != nil {
+		limit, err := parseLimit(*req.MonthlyLimit)
+		if err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+		monthlyLimit = limit
+	}
+
+	category, err := scanCategory(db.Pool.QueryRow(c.Request.Context(),
+		`INSERT INTO expense_categories (user_id, parent_id, name, color, icon, monthly_limit) 
+		 VALUES ($1, $2, $3, $4, $5, $6) 
+		 RETURNING `+categoryColumns,
+		userID, req.ParentID, req.Name, req.Color, req.Icon, monthlyLimit))
 	if err != nil {
 		c.JSON(500, gin.H{"error": "failed to create category"})
 		return
@@ -68,7 +122,10 @@ func CreateCategory(c *gin.Context, db *db.DB) {
 	c.JSON(201, category)
 }
 
-// ListCategories retrieves all categories for a user
+// ListCategories retrieves all categories for a user, pinned first and then
+// in the user's order, as a flat list or, with tree=true, as top-level
+// categories with their children nested. Archived categories are left out
+// unless include_archived=true.
 func ListCategories(c *gin.Context, db *db.DB) {
 	userID, ok := middleware.GetUserID(c)
 	if !ok {
@@ -77,11 +134,11 @@ func ListCategories(c *gin.Context, db *db.DB) {
 	}
 
 	rows, err := db.Pool.Query(c.Request.Context(),
-		`SELECT id, user_id, name, color, icon, created_at 
+		`SELECT `+categoryColumns+` 
 		 FROM expense_categories 
-		 WHERE user_id = $1 
-		 ORDER BY name ASC`,
-		userID)
+		 WHERE user_id = $1 AND deleted_at IS NULL AND (NOT archived OR $2) 
+		 ORDER BY pinned DESC, sort_order ASC NULLS LAST, name ASC`,
+		userID, c.Query("include_archived") == "true")
 	if err != nil {
 		c.JSON(500, gin.H{"error": "failed to retrieve categories"})
 		return
@@ -90,8 +147,8 @@ func ListCategories(c *gin.Context, db *db.DB) {
 
 	var categories []ExpenseCategory
 	for rows.Next() {
-		var cat ExpenseCategory
-		if err := rows.Scan(&cat.ID, &cat.UserID, &cat.Name, &cat.Color, &cat.Icon, &cat.CreatedAt); err != nil {
+		cat, err := scanCategory(rows)
+		if err != nil {
 			c.JSON(500, gin.H{"error": "failed to scan category"})
 			return
 		}
@@ -102,10 +159,13 @@ func ListCategories(c *gin.Context, db *db.DB) {
 		categories = []ExpenseCategory{}
 	}
 
+	if c.Query("tree") == "true" {
+		categories = buildTree(categories)
+	}
+
 	c.JSON(200, categories)
 }
 
-// UpdateCategory updates an existing category
 func UpdateCategory(c *gin.Context, db *db.DB) {
 	userID, ok := middleware.GetUserID(c)
 	if !ok {
@@ -121,21 +181,12 @@ func UpdateCategory(c *gin.Context, db *db.DB) {
 	}
 
 	var req UpdateCategoryRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
-		return
-	}
-
-	validate := validator.New()
-	if err := validate.Struct(req); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
+	if !validation.Bind(c, &req) {
 		return
 	}
 
 	// Check if category belongs to user
-	var ownerID uuid.UUID
-	err = db.Pool.QueryRow(c.Request.Context(),
-		`SELECT user_id FROM expense_categories WHERE id = $1`, categoryID).Scan(&ownerID)
+	ownerID, _, err := helpers.GetCategoryOwner(c.Request.Context(), db.Pool, categoryID)
 	if err != nil {
 		c.JSON(404, gin.H{"error": "category not found"})
 		return
@@ -145,48 +196,59 @@ func UpdateCategory(c *gin.Context, db *db.DB) {
 		return
 	}
 
-	// Build update query dynamically
-	query := `UPDATE expense_categories SET `
-	args := []interface{}{}
-	argCount := 1
-
-	if req.Name != nil {
-		query += fmt.Sprintf("name = $%d, ", argCount)
-		args = append(args, *req.Name)
-		argCount++
-	}
-	if req.Color != nil {
-		query += fmt.Sprintf("color = $%d, ", argCount)
-		args = append(args, *req.Color)
-		argCount++
+	if req.ParentID != nil && req.ClearParent {
+		c.JSON(400, gin.H{"error": "parent_id and clear_parent cannot be combined"})
+		return
 	}
-	if req.Icon != nil {
-		query += fmt.Sprintf("icon = $%d, ", argCount)
-		args = append(args, *req.Icon)
-		argCount++
+	if req.ParentID != nil {
+		if status, err := checkParent(c.Request.Context(), db, userID, categoryID, *req.ParentID); err != nil {
+			c.JSON(status, gin.H{"error": err.Error()})
+			return
+		}
 	}
 
-	if argCount == 1 {
+	if req.Name == nil && req.ParentID == nil && req.Color == nil && req.Icon == nil &&
+		req.MonthlyLimit == nil && !req.ClearParent {
 		c.JSON(400, gin.H{"error": "no fields to update"})
 		return
 	}
 
-	// Remove trailing comma and add WHERE clause
-	query = query[:len(query)-2] + fmt.Sprintf(" WHERE id = $%d RETURNING id, user_id, name, color, icon, created_at", argCount)
-	args = append(args, categoryID)
+	var limit *decimal.Decimal
+	if req.MonthlyLimit != nil {
+		limit, err = parseLimit(*req.MonthlyLimit)
+		if err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+	}
 
-	var category ExpenseCategory
-	err = db.Pool.QueryRow(c.Request.Context(), query, args...).Scan(
-		&category.ID, &category.UserID, &category.Name, &category.Color, &category.Icon, &category.CreatedAt)
+	row, err := sqlc.New(db.Pool).UpdateCategory(c.Request.Context(), sqlc.UpdateCategoryParams{
+		ID:           categoryID,
+		Name:         req.Name,
+		ParentID:     req.ParentID,
+		ClearParent:  req.ClearParent,
+		Color:        req.Color,
+		Icon:         req.Icon,
+		SetLimit:     req.MonthlyLimit != nil,
+		MonthlyLimit: limit,
+	})
 	if err != nil {
 		c.JSON(500, gin.H{"error": "failed to update category"})
 		return
 	}
+	category := ExpenseCategory{
+		ID: row.ID, UserID: row.UserID, ParentID: row.ParentID, Name: row.Name, Color: row.Color, Icon: row.Icon,
+		Archived: row.Archived, Pinned: row.Pinned, SortOrder: row.SortOrder, MonthlyLimit: row.MonthlyLimit,
+		CreatedAt: row.CreatedAt,
+	}
 
 	c.JSON(200, category)
 }
 
-// DeleteCategory deletes a category
+// DeleteCategory deletes a category after moving its expenses to the
+// category given by reassign_to, or leaving them uncategorized when
+// reassign_to is "none". Its subcategories move to the top level. It can be
+// restored until it's purged, but its expenses stay where they were moved.
 func DeleteCategory(c *gin.Context, db *db.DB) {
 	userID, ok := middleware.GetUserID(c)
 	if !ok {
@@ -201,10 +263,28 @@ func DeleteCategory(c *gin.Context, db *db.DB) {
 		return
 	}
 
+	// Deleting must say where the expenses go so none are lost by accident
+	var reassignTo *uuid.UUID
+	switch reassignStr := c.Query("reassign_to"); reassignStr {
+	case "":
+		c.JSON(400, gin.H{"error": "reassign_to is required: a category id, or none to leave expenses uncategorized"})
+		return
+	case "none":
+	default:
+		id, err := uuid.Parse(reassignStr)
+		if err != nil {
+			c.JSON(400, gin.H{"error": "invalid reassign_to category id"})
+			return
+		}
+		if id == categoryID {
+			c.JSON(400, gin.H{"error": "cannot reassign expenses to the deleted category"})
+			return
+		}
+		reassignTo = &id
+	}
+
 	// Check if category belongs to user
-	var ownerID uuid.UUID
-	err = db.Pool.QueryRow(c.Request.Context(),
-		`SELECT user_id FROM expense_categories WHERE id = $1`, categoryID).Scan(&ownerID)
+	ownerID, _, err := helpers.GetCategoryOwner(c.Request.Context(), db.Pool, categoryID)
 	if err != nil {
 		c.JSON(404, gin.H{"error": "category not found"})
 		return
@@ -214,12 +294,115 @@ func DeleteCategory(c *gin.Context, db *db.DB) {
 		return
 	}
 
-	_, err = db.Pool.Exec(c.Request.Context(),
-		`DELETE FROM expense_categories WHERE id = $1`, categoryID)
+	if reassignTo != nil {
+		targetOwnerID, _, err := helpers.GetCategoryOwner(c.Request.Context(), db.Pool, *reassignTo)
+		if err != nil {
+			c.JSON(400, gin.H{"error": "invalid reassign_to category"})
+			return
+		}
+		if targetOwnerID != userID {
+			c.JSON(403, gin.H{"error": "reassign_to category does not belong to user"})
+			return
+		}
+	}
+
+	var reassigned int64
+	err = db.WithTx(c.Request.Context(), func(tx pgx.Tx) error {
+		tag, err := tx.Exec(c.Request.Context(),
+			`UPDATE personal_expenses SET category_id = $1, updated_at = NOW() WHERE category_id = $2`,
+			reassignTo, categoryID)
+		if err != nil {
+			return helpers.NewRequestError(500, "failed to reassign expenses")
+		}
+		reassigned = tag.RowsAffected()
+
+		// Merchant defaults and group mirrors follow the expenses
+		_, err = tx.Exec(c.Request.Context(),
+			`UPDATE merchants SET category_id = $1 WHERE category_id = $2`, reassignTo, categoryID)
+		if err != nil {
+			return helpers.NewRequestError(500, "failed to reassign merchants")
+		}
+		_, err = tx.Exec(c.Request.Context(),
+			`UPDATE group_members SET mirror_category_id = $1 WHERE mirror_category_id = $2`, reassignTo, categoryID)
+		if err != nil {
+			return helpers.NewRequestError(500, "failed to reassign group mirrors")
+		}
+
+		// What deleting the row used to do through its foreign keys
+		_, err = tx.Exec(c.Request.Context(),
+			`UPDATE expense_categories SET parent_id = NULL WHERE parent_id = $1`, categoryID)
+		if err != nil {
+			return helpers.NewRequestError(500, "failed to detach subcategories")
+		}
+		_, err = tx.Exec(c.Request.Context(),
+			`UPDATE recurring_expenses SET category_id = NULL WHERE category_id = $1`, categoryID)
+		if err != nil {
+			return helpers.NewRequestError(500, "failed to detach recurring expenses")
+		}
+
+		if _, err := softdelete.Delete(c.Request.Context(), tx, softdelete.Categories, categoryID, userID); err != nil {
+			return helpers.NewRequestError(500, "failed to delete category")
+		}
+		return nil
+	})
+	if err != nil {
+		helpers.RespondError(c, err, "failed to delete category")
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"message":             "category deleted successfully",
+		"reassigned_expenses": reassigned,
+	})
+}
+
+// RestoreCategory undoes DeleteCategory. It fails if a category with the
+// same name has been created since.
+func RestoreCategory(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	categoryID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
-		c.JSON(500, gin.H{"error": "failed to delete category"})
+		c.JSON(400, gin.H{"error": "invalid category id"})
+		return
+	}
+
+	var ownerID uuid.UUID
+	var deletedAt *time.Time
+	err = db.Pool.QueryRow(c.Request.Context(),
+		`SELECT user_id, deleted_at FROM expense_categories WHERE id = $1`, categoryID).Scan(&ownerID, &deletedAt)
+	if err != nil {
+		c.JSON(404, gin.H{"error": "category not found"})
+		return
+	}
+	if ownerID != userID {
+		c.JSON(403, gin.H{"error": "not authorized to restore this category"})
+		return
+	}
+	if deletedAt == nil {
+		c.JSON(400, gin.H{"error": "category is not deleted"})
 		return
 	}
 
-	c.JSON(200, gin.H{"message": "category deleted successfully"})
+	if _, err := softdelete.Restore(c.Request.Context(), db.Pool, softdelete.Categories, categoryID); err != nil {
+		var pgErr *pgconn.PgError
+		if errors.As(err, &pgErr) && pgErr.Code == "23505" { // unique_violation
+			c.JSON(409, gin.H{"error": "a category with this name already exists"})
+			return
+		}
+		c.JSON(500, gin.H{"error": "failed to restore category"})
+		return
+	}
+
+	category, err := scanCategory(db.Pool.QueryRow(c.Request.Context(),
+		`SELECT `+categoryColumns+` FROM expense_categories WHERE id = $1`, categoryID))
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to load category"})
+		return
+	}
+	c.JSON(200, category)
 }
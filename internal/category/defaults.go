@@ -0,0 +1,142 @@
+package category
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/config"
+	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+	"github.com/yanonymousV2/finance-manager-backend/internal/middleware"
+)
+
+// Default is a category every new user starts with
+type Default struct {
+	Name  string
+	Color *string
+	Icon  *string
+}
+
+func builtIn(name, color, icon string) Default {
+	return Default{Name: name, Color: &color, Icon: &icon}
+}
+
+// builtInDefaults is the starter set used unless DEFAULT_CATEGORIES is set
+var builtInDefaults = []Default{
+	builtIn("Food", "#FF7043", "utensils"),
+	builtIn("Groceries", "#66BB6A", "shopping-cart"),
+	builtIn("Transport", "#42A5F5", "car"),
+	builtIn("Rent", "#8D6E63", "home"),
+	builtIn("Utilities", "#FFCA28", "bolt"),
+	builtIn("Entertainment", "#AB47BC", "film"),
+	builtIn("Health", "#EF5350", "heart"),
+	builtIn("Shopping", "#EC407A", "shopping-bag"),
+	builtIn("Travel", "#26C6DA", "plane"),
+	builtIn("Other", "#78909C", "tag"),
+}
+
+var colorPattern = regexp.MustCompile(`^#[0-9A-Fa-f]{6}$`)
+
+// ParseDefaults parses "Name|#RRGGBB|icon" entries separated by ";". Color
+// and icon are optional.
+func ParseDefaults(spec string) ([]Default, error) {
+	var defaults []Default
+	seen := map[string]bool{}
+	for _, entry := range strings.Split(spec, ";") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		fields := strings.Split(entry, "|")
+		if len(fields) > 3 {
+			return nil, fmt.Errorf("category %q has too many fields", entry)
+		}
+
+		d := Default{Name: strings.TrimSpace(fields[0])}
+		if d.Name == "" || len(d.Name) > 100 {
+			return nil, fmt.Errorf("category %q needs a name of 1 to 100 characters", entry)
+		}
+		if seen[strings.ToLower(d.Name)] {
+			return nil, fmt.Errorf("category %q is listed twice", d.Name)
+		}
+		seen[strings.ToLower(d.Name)] = true
+
+		if len(fields) > 1 {
+			if color := strings.TrimSpace(fields[1]); color != "" {
+				if !colorPattern.MatchString(color) {
+					return nil, fmt.Errorf("category %q has invalid color %q", d.Name, color)
+				}
+				d.Color = &color
+			}
+		}
+		if len(fields) > 2 {
+			if icon := strings.TrimSpace(fields[2]); icon != "" {
+				if len(icon) > 50 {
+					return nil, fmt.Errorf("category %q has an icon longer than 50 characters", d.Name)
+				}
+				d.Icon = &icon
+			}
+		}
+		defaults = append(defaults, d)
+	}
+	return defaults, nil
+}
+
+// Defaults returns the default categories configured by cfg
+func Defaults(cfg *config.Config) ([]Default, error) {
+	if cfg.DefaultCategories == "" {
+		return builtInDefaults, nil
+	}
+	return ParseDefaults(cfg.DefaultCategories)
+}
+
+// SeedDefaults creates the default categories the user does not have yet
+// inside tx and returns how many were created. Running it again is harmless.
+func SeedDefaults(ctx context.Context, tx pgx.Tx, userID uuid.UUID, defaults []Default) (int, error) {
+	batch := &pgx.Batch{}
+	created := 0
+	for _, d := range defaults {
+		batch.Queue(
+			`INSERT INTO expense_categories (user_id, name, color, icon)
+			 VALUES ($1, $2, $3, $4)
+			 ON CONFLICT (user_id, name) WHERE deleted_at IS NULL DO NOTHING`,
+			userID, d.Name, d.Color, d.Icon,
+		).Exec(func(tag pgconn.CommandTag) error {
+			created += int(tag.RowsAffected())
+			return nil
+		})
+	}
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		return 0, err
+	}
+	return created, nil
+}
+
+// SeedDefaultCategories adds any missing default categories for the
+// authenticated user, e.g. after deleting some or for accounts created while
+// seeding was off
+func SeedDefaultCategories(c *gin.Context, db *db.DB, defaults []Default) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var created int
+	err := db.WithTx(c.Request.Context(), func(tx pgx.Tx) error {
+		var err error
+		created, err = SeedDefaults(c.Request.Context(), tx, userID, defaults)
+		return err
+	})
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to create default categories"})
+		return
+	}
+
+	c.JSON(200, gin.H{"created": created})
+}
@@ -0,0 +1,38 @@
+package category
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/config"
+)
+
+func TestParseDefaults(t *testing.T) {
+	defaults, err := ParseDefaults("Food|#FF7043|utensils; Rent ;Fun||party;")
+	require.NoError(t, err)
+	require.Len(t, defaults, 3)
+	assert.Equal(t, "Food", defaults[0].Name)
+	assert.Equal(t, "#FF7043", *defaults[0].Color)
+	assert.Equal(t, "utensils", *defaults[0].Icon)
+	assert.Equal(t, "Rent", defaults[1].Name)
+	assert.Nil(t, defaults[1].Color)
+	assert.Nil(t, defaults[2].Color)
+	assert.Equal(t, "party", *defaults[2].Icon)
+
+	for _, spec := range []string{"Food|red", "Food|#FF7043|a|b", "|#FF7043", "Food;food"} {
+		_, err := ParseDefaults(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestDefaults(t *testing.T) {
+	defaults, err := Defaults(&config.Config{})
+	require.NoError(t, err)
+	assert.Equal(t, builtInDefaults, defaults)
+
+	defaults, err = Defaults(&config.Config{DefaultCategories: "Rent"})
+	require.NoError(t, err)
+	assert.Equal(t, []Default{{Name: "Rent"}}, defaults)
+}
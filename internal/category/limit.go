@@ -0,0 +1,45 @@
+package category
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+)
+
+// LimitStatus is a category's spending in a month against its monthly limit
+type LimitStatus struct {
+	CategoryID   uuid.UUID       `json:"category_id"`
+	CategoryName string          `json:"category_name"`
+	Month        int             `json:"month"`
+	Year         int             `json:"year"`
+	MonthlyLimit decimal.Decimal `json:"monthly_limit"`
+	Spent        decimal.Decimal `json:"spent"`
+	Exceeded     bool            `json:"exceeded"`
+}
+
+// CheckLimit returns the category's spending in currency for the month
+// containing date, or nil when the category has no monthly limit
+func CheckLimit(ctx context.Context, db *db.DB, categoryID uuid.UUID, date time.Time, currency string) (*LimitStatus, error) {
+	date = date.UTC()
+	start := time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, time.UTC)
+	status := LimitStatus{CategoryID: categoryID, Month: int(start.Month()), Year: start.Year()}
+
+	var limit *decimal.Decimal
+	err := db.Pool.QueryRow(ctx,
+		`SELECT ec.name, ec.monthly_limit,
+		        (SELECT COALESCE(SUM(amount), 0) FROM personal_expenses
+		         WHERE category_id = ec.id AND currency = $2 AND expense_date >= $3 AND expense_date < $4)
+		 FROM expense_categories ec WHERE ec.id = $1`,
+		categoryID, currency, start, start.AddDate(0, 1, 0)).Scan(&status.CategoryName, &limit, &status.Spent)
+	if err != nil || limit == nil {
+		return nil, err
+	}
+
+	status.MonthlyLimit = *limit
+	status.Exceeded = status.Spent.GreaterThan(*limit)
+	return &status, nil
+}
@@ -0,0 +1,89 @@
+package category
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+	"github.com/yanonymousV2/finance-manager-backend/internal/middleware"
+	"github.com/yanonymousV2/finance-manager-backend/internal/validation"
+)
+
+type ReorderItem struct {
+	ID     uuid.UUID `json:"id" validate:"required"`
+	Pinned bool      `json:"pinned"`
+}
+
+// ReorderCategoriesRequest lists categories in the order they should be
+// shown. Categories left out keep their place after the listed ones.
+type ReorderCategoriesRequest struct {
+	Categories []ReorderItem `json:"categories" validate:"required,min=1,max=500,dive"`
+}
+
+// ReorderCategories sets the display order and pinned flag of the user's
+// categories
+func ReorderCategories(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req ReorderCategoriesRequest
+	if !validation.Bind(c, &req) {
+		return
+	}
+
+	ids := make([]uuid.UUID, 0, len(req.Categories))
+	seen := make(map[uuid.UUID]bool, len(req.Categories))
+	for _, item := range req.Categories {
+		if seen[item.ID] {
+			c.JSON(400, gin.H{"error": "category " + item.ID.String() + " is listed twice"})
+			return
+		}
+		seen[item.ID] = true
+		ids = append(ids, item.ID)
+	}
+
+	var owned int
+	err := db.Pool.QueryRow(c.Request.Context(),
+		`SELECT COUNT(*) FROM expense_categories WHERE id = ANY($1) AND user_id = $2 AND deleted_at IS NULL`,
+		ids, userID).Scan(&owned)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to check categories"})
+		return
+	}
+	if owned != len(ids) {
+		c.JSON(400, gin.H{"error": "all categories must exist and belong to user"})
+		return
+	}
+
+	err = db.WithTx(c.Request.Context(), func(tx pgx.Tx) error {
+		// Listed categories move ahead of the ones left out, which keep their
+		// relative order
+		_, err := tx.Exec(c.Request.Context(),
+			`UPDATE expense_categories ec SET sort_order = $1 + r.n
+			 FROM (SELECT id, ROW_NUMBER() OVER (ORDER BY sort_order, name) - 1 AS n
+			       FROM expense_categories
+			       WHERE user_id = $2 AND deleted_at IS NULL AND sort_order IS NOT NULL AND NOT id = ANY($3)) r
+			 WHERE ec.id = r.id`,
+			len(ids), userID, ids)
+		if err != nil {
+			return err
+		}
+
+		batch := &pgx.Batch{}
+		for i, item := range req.Categories {
+			batch.Queue(`UPDATE expense_categories SET sort_order = $1, pinned = $2 WHERE id = $3`,
+				i, item.Pinned, item.ID)
+		}
+		return tx.SendBatch(c.Request.Context(), batch).Close()
+	})
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to reorder categories"})
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "categories reordered successfully"})
+}
@@ -0,0 +1,78 @@
+package category
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+	"github.com/yanonymousV2/finance-manager-backend/internal/helpers"
+)
+
+// checkParent checks that parentID can become the parent of categoryID: it
+// must belong to the user and must not be the category itself or one of its
+// descendants. categoryID is uuid.Nil for a new category. The returned status
+// is the HTTP status to answer with on error.
+func checkParent(ctx context.Context, db *db.DB, userID, categoryID, parentID uuid.UUID) (int, error) {
+	ownerID, _, err := helpers.GetCategoryOwner(ctx, db.Pool, parentID)
+	if err != nil {
+		return 400, errors.New("invalid parent category")
+	}
+	if ownerID != userID {
+		return 403, errors.New("parent category does not belong to user")
+	}
+	if categoryID == uuid.Nil {
+		return 0, nil
+	}
+
+	// Walk up from the new parent; meeting the category means a cycle
+	var cycle bool
+	err = db.Pool.QueryRow(ctx,
+		`WITH RECURSIVE ancestors AS (
+		     SELECT id, parent_id FROM expense_categories WHERE id = $1
+		     UNION
+		     SELECT ec.id, ec.parent_id FROM expense_categories ec JOIN ancestors a ON ec.id = a.parent_id
+		 )
+		 SELECT EXISTS(SELECT 1 FROM ancestors WHERE id = $2)`,
+		parentID, categoryID).Scan(&cycle)
+	if err != nil {
+		return 500, errors.New("failed to check parent category")
+	}
+	if cycle {
+		return 400, errors.New("a category cannot be nested under itself or its subcategories")
+	}
+	return 0, nil
+}
+
+// buildTree nests categories under their parents, keeping the order of the
+// flat list at every level. Categories whose parent is missing from the list
+// are treated as top-level.
+func buildTree(flat []ExpenseCategory) []ExpenseCategory {
+	present := make(map[uuid.UUID]bool, len(flat))
+	children := make(map[uuid.UUID][]ExpenseCategory)
+	for _, cat := range flat {
+		present[cat.ID] = true
+	}
+	var roots []ExpenseCategory
+	for _, cat := range flat {
+		if cat.ParentID != nil && present[*cat.ParentID] {
+			children[*cat.ParentID] = append(children[*cat.ParentID], cat)
+		} else {
+			roots = append(roots, cat)
+		}
+	}
+
+	var attach func(cats []ExpenseCategory) []ExpenseCategory
+	attach = func(cats []ExpenseCategory) []ExpenseCategory {
+		for i := range cats {
+			cats[i].Children = attach(children[cats[i].ID])
+		}
+		return cats
+	}
+	tree := attach(roots)
+	if tree == nil {
+		tree = []ExpenseCategory{}
+	}
+	return tree
+}
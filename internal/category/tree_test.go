@@ -0,0 +1,31 @@
+package category
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildTree(t *testing.T) {
+	food := ExpenseCategory{ID: uuid.New(), Name: "Food"}
+	dining := ExpenseCategory{ID: uuid.New(), ParentID: &food.ID, Name: "Dining"}
+	coffee := ExpenseCategory{ID: uuid.New(), ParentID: &dining.ID, Name: "Coffee"}
+	groceries := ExpenseCategory{ID: uuid.New(), ParentID: &food.ID, Name: "Groceries"}
+	missing := uuid.New()
+	orphan := ExpenseCategory{ID: uuid.New(), ParentID: &missing, Name: "Orphan"}
+
+	tree := buildTree([]ExpenseCategory{coffee, dining, food, groceries, orphan})
+	require.Len(t, tree, 2)
+	assert.Equal(t, "Food", tree[0].Name)
+	assert.Equal(t, "Orphan", tree[1].Name)
+
+	require.Len(t, tree[0].Children, 2)
+	assert.Equal(t, "Dining", tree[0].Children[0].Name)
+	assert.Equal(t, "Groceries", tree[0].Children[1].Name)
+	require.Len(t, tree[0].Children[0].Children, 1)
+	assert.Equal(t, "Coffee", tree[0].Children[0].Children[0].Name)
+
+	assert.Equal(t, []ExpenseCategory{}, buildTree(nil))
+}
@@ -0,0 +1,253 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const secret = "0123456789abcdef0123456789abcdef"
+
+func env(values map[string]string) func(string) string {
+	return func(key string) string { return values[key] }
+}
+
+func TestLoadDefaults(t *testing.T) {
+	cfg, err := load(env(map[string]string{"JWT_SECRET": secret}))
+	require.NoError(t, err)
+	assert.Equal(t, "development", cfg.Env)
+	assert.Equal(t, 8080, cfg.Port)
+	assert.Equal(t, "text", cfg.LogFormat)
+	assert.Equal(t, "debug", cfg.LogLevel)
+	assert.Equal(t, 15*time.Second, cfg.ShutdownTimeout)
+	assert.Equal(t, 587, cfg.SMTPPort)
+	assert.True(t, cfg.SeedCategories)
+	assert.Equal(t, "http://localhost:8080", cfg.PublicURL)
+	assert.Equal(t, 25, cfg.DBMaxConns)
+	assert.Equal(t, 30*time.Second, cfg.DBStatementTimeout)
+}
+
+func TestLoadReportsEverything(t *testing.T) {
+	_, err := load(env(map[string]string{
+		"PORT":                    "http",
+		"SHUTDOWN_TIMEOUT":        "5",
+		"SEED_DEFAULT_CATEGORIES": "maybe",
+		"STORAGE_DRIVER":          "s3",
+		"EMAIL_DRIVER":            "smtp",
+		"LOG_LEVEL":               "loud",
+		"TLS_CERT_FILE":           "cert.pem",
+		"DB_MAX_CONNS":            "0",
+		"DB_MIN_CONNS":            "30",
+	}))
+	var errs Errors
+	require.ErrorAs(t, err, &errs)
+	assert.Equal(t, Errors{
+		`JWT_SECRET is required`,
+		`PORT must be a port number from 1 to 65535, got "http"`,
+		`DB_MAX_CONNS must be a whole number of at least 1, got "0"`,
+		`LOG_LEVEL must be one of debug, info, warn, error, got "loud"`,
+		`SHUTDOWN_TIMEOUT must be a duration such as 30s or 2m, got "5"`,
+		`SEED_DEFAULT_CATEGORIES must be true or false, got "maybe"`,
+		`DB_MIN_CONNS (30) can't be more than DB_MAX_CONNS (25)`,
+		`S3_BUCKET is required for s3 storage`,
+		`S3_ACCESS_KEY and S3_SECRET_KEY are required for s3 storage`,
+		`SMTP_HOST is required for smtp email`,
+		`TLS_CERT_FILE and TLS_KEY_FILE must be set together`,
+	}, errs)
+	assert.Contains(t, err.Error(), "invalid configuration:\n  - JWT_SECRET is required\n  - PORT")
+}
+
+func TestLoadEmailDrivers(t *testing.T) {
+	_, err := load(env(map[string]string{"JWT_SECRET": secret, "EMAIL_DRIVER": "ses", "SES_ACCESS_KEY": "AKID"}))
+	assert.Equal(t, Errors{"SES_ACCESS_KEY and SES_SECRET_KEY are required for ses email"}, err)
+
+	_, err = load(env(map[string]string{"JWT_SECRET": secret, "EMAIL_DRIVER": "sendgrid"}))
+	assert.Equal(t, Errors{"SENDGRID_API_KEY is required for sendgrid email"}, err)
+
+	cfg, err := load(env(map[string]string{"JWT_SECRET": secret, "EMAIL_DRIVER": "sendgrid", "SENDGRID_API_KEY": "SG.key"}))
+	require.NoError(t, err)
+	assert.Equal(t, "sendgrid", cfg.EmailDriver)
+	assert.Equal(t, "us-east-1", cfg.SESRegion)
+}
+
+func TestLoadAPNs(t *testing.T) {
+	_, err := load(env(map[string]string{"JWT_SECRET": secret, "APNS_KEY_FILE": "AuthKey.p8", "APNS_KEY_ID": "ABC123"}))
+	assert.Equal(t, Errors{"APNS_KEY_ID, APNS_TEAM_ID and APNS_TOPIC are required with APNS_KEY_FILE"}, err)
+
+	cfg, err := load(env(map[string]string{
+		"JWT_SECRET":    secret,
+		"APNS_KEY_FILE": "AuthKey.p8",
+		"APNS_KEY_ID":   "ABC123",
+		"APNS_TEAM_ID":  "TEAM123",
+		"APNS_TOPIC":    "com.example.finance",
+		"APNS_SANDBOX":  "true",
+	}))
+	require.NoError(t, err)
+	assert.True(t, cfg.APNsSandbox)
+}
+
+func TestLoadFXProvider(t *testing.T) {
+	cfg, err := load(env(map[string]string{"JWT_SECRET": secret}))
+	require.NoError(t, err)
+	assert.Equal(t, "ecb", cfg.FXProvider)
+
+	_, err = load(env(map[string]string{"JWT_SECRET": secret, "FX_PROVIDER": "exchangeratehost"}))
+	assert.Equal(t, Errors{"FX_API_KEY is required for the exchangeratehost provider"}, err)
+
+	cfg, err = load(env(map[string]string{"JWT_SECRET": secret, "FX_PROVIDER": "exchangeratehost", "FX_API_KEY": "key"}))
+	require.NoError(t, err)
+	assert.Equal(t, "exchangeratehost", cfg.FXProvider)
+}
+
+func TestLoadPlaid(t *testing.T) {
+	cfg, err := load(env(map[string]string{"JWT_SECRET": secret}))
+	require.NoError(t, err)
+	assert.Equal(t, "sandbox", cfg.PlaidEnv)
+	assert.Equal(t, []string{"US"}, cfg.PlaidCountryCodes)
+
+	_, err = load(env(map[string]string{"JWT_SECRET": secret, "PLAID_CLIENT_ID": "client", "TOKEN_ENCRYPTION_KEY": tokenKey}))
+	assert.Equal(t, Errors{"PLAID_CLIENT_ID and PLAID_SECRET must be set together"}, err)
+
+	cfg, err = load(env(map[string]string{
+		"JWT_SECRET":           secret,
+		"PLAID_CLIENT_ID":      "client",
+		"PLAID_SECRET":         "secret",
+		"PLAID_ENV":            "production",
+		"PLAID_COUNTRY_CODES":  "US,CA",
+		"TOKEN_ENCRYPTION_KEY": tokenKey,
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, "production", cfg.PlaidEnv)
+	assert.Equal(t, []string{"US", "CA"}, cfg.PlaidCountryCodes)
+}
+
+func TestLoadOCR(t *testing.T) {
+	cfg, err := load(env(map[string]string{"JWT_SECRET": secret}))
+	require.NoError(t, err)
+	assert.Equal(t, "none", cfg.OCRProvider)
+
+	_, err = load(env(map[string]string{"JWT_SECRET": secret, "OCR_PROVIDER": "mindee"}))
+	assert.Equal(t, Errors{"OCR_API_KEY is required for the mindee OCR provider"}, err)
+
+	_, err = load(env(map[string]string{"JWT_SECRET": secret, "OCR_PROVIDER": "azure", "OCR_API_KEY": "key"}))
+	assert.Equal(t, Errors{"OCR_ENDPOINT is required for the azure OCR provider"}, err)
+
+	cfg, err = load(env(map[string]string{
+		"JWT_SECRET":   secret,
+		"OCR_PROVIDER": "azure",
+		"OCR_API_KEY":  "key",
+		"OCR_ENDPOINT": "https://receipts.cognitiveservices.azure.com",
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, "azure", cfg.OCRProvider)
+}
+
+func TestLoadProduction(t *testing.T) {
+	_, err := load(env(map[string]string{"APP_ENV": "production", "JWT_SECRET": secret}))
+	assert.Equal(t, Errors{"DATABASE_URL is required in production", "PUBLIC_URL is required in production"}, err)
+
+	cfg, err := load(env(map[string]string{
+		"APP_ENV":      "production",
+		"JWT_SECRET":   secret,
+		"DATABASE_URL": "postgres://db/finance",
+		"PUBLIC_URL":   "https://api.example.com",
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, "json", cfg.LogFormat)
+
+	_, err = load(env(map[string]string{"APP_ENV": "staging"}))
+	assert.Error(t, err)
+}
+
+func TestLoadFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{
+		"JWT_SECRET": "`+secret+`",
+		"PORT": 9000,
+		"SEED_DEFAULT_CATEGORIES": false,
+		"AUTOCERT_DOMAINS": ["a.example.com", "b.example.com"],
+		"LOG_LEVEL": "info",
+		"environments": {
+			"test": {"LOG_LEVEL": "error", "SHUTDOWN_TIMEOUT": "1s"}
+		}
+	}`), 0o600))
+
+	cfg, err := load(env(map[string]string{"CONFIG_FILE": path, "APP_ENV": "test", "PORT": "9100"}))
+	require.NoError(t, err)
+	// Environment variables beat the file, its environment section beats
+	// its top level, and both beat the profile
+	assert.Equal(t, 9100, cfg.Port)
+	assert.Equal(t, "error", cfg.LogLevel)
+	assert.Equal(t, "text", cfg.LogFormat)
+	assert.Equal(t, time.Second, cfg.ShutdownTimeout)
+	assert.False(t, cfg.SeedCategories)
+	assert.Equal(t, []string{"a.example.com", "b.example.com"}, cfg.AutocertDomains)
+
+	cfg, err = load(env(map[string]string{"CONFIG_FILE": path}))
+	require.NoError(t, err)
+	assert.Equal(t, "info", cfg.LogLevel)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"JWT_SECRET": "`+secret+`", "JWT_SECERT": "x"}`), 0o600))
+	_, err = load(env(map[string]string{"CONFIG_FILE": path}))
+	assert.Equal(t, Errors{"unknown setting JWT_SECERT in config file"}, err)
+
+	require.NoError(t, os.WriteFile(path, []byte(`{"environments": {"prod": {}}}`), 0o600))
+	_, err = load(env(map[string]string{"CONFIG_FILE": path}))
+	assert.ErrorContains(t, err, `unknown environment "prod"`)
+
+	_, err = load(env(map[string]string{"CONFIG_FILE": filepath.Join(t.TempDir(), "missing.json")}))
+	assert.ErrorContains(t, err, "CONFIG_FILE")
+}
+
+func TestDatabaseURL(t *testing.T) {
+	// Nothing else is required
+	url, err := databaseURL(env(nil))
+	require.NoError(t, err)
+	assert.Equal(t, defaultDBURL, url)
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	require.NoError(t, os.WriteFile(path, []byte(`{"DATABASE_URL": "postgres://db/finance", "PORT": 9000}`), 0o600))
+	url, err = databaseURL(env(map[string]string{"CONFIG_FILE": path}))
+	require.NoError(t, err)
+	assert.Equal(t, "postgres://db/finance", url)
+
+	_, err = databaseURL(env(map[string]string{"APP_ENV": "production"}))
+	assert.Equal(t, Errors{"DATABASE_URL is required in production"}, err)
+}
+
+func TestLoadGoogle(t *testing.T) {
+	_, err := load(env(map[string]string{"JWT_SECRET": secret, "GOOGLE_CLIENT_SECRET": "secret", "TOKEN_ENCRYPTION_KEY": tokenKey}))
+	assert.Equal(t, Errors{"GOOGLE_CLIENT_ID and GOOGLE_CLIENT_SECRET must be set together"}, err)
+
+	cfg, err := load(env(map[string]string{
+		"JWT_SECRET":           secret,
+		"GOOGLE_CLIENT_ID":     "client",
+		"GOOGLE_CLIENT_SECRET": "secret",
+		"TOKEN_ENCRYPTION_KEY": tokenKey,
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, "client", cfg.GoogleClientID)
+}
+
+// tokenKey is 32 bytes, base64-encoded
+const tokenKey = "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY="
+
+func TestLoadTokenEncryptionKey(t *testing.T) {
+	_, err := load(env(map[string]string{
+		"JWT_SECRET":           secret,
+		"GOOGLE_CLIENT_ID":     "client",
+		"GOOGLE_CLIENT_SECRET": "secret",
+	}))
+	assert.Equal(t, Errors{"TOKEN_ENCRYPTION_KEY is required with PLAID_CLIENT_ID or GOOGLE_CLIENT_ID"}, err)
+
+	_, err = load(env(map[string]string{"JWT_SECRET": secret, "TOKEN_ENCRYPTION_KEY": "c2hvcnQ="}))
+	assert.Equal(t, Errors{"TOKEN_ENCRYPTION_KEY must be 32 random bytes, base64-encoded (openssl rand -base64 32)"}, err)
+
+	cfg, err := load(env(map[string]string{"JWT_SECRET": secret, "TOKEN_ENCRYPTION_KEY": tokenKey}))
+	require.NoError(t, err)
+	assert.Equal(t, []byte("0123456789abcdef0123456789abcdef"), cfg.TokenEncryptionKey)
+}
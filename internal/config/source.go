@@ -0,0 +1,246 @@
+package config
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// profiles are the defaults for each APP_ENV, applied under the config file
+// and environment variables
+var profiles = map[string]map[string]string{
+	"development": {
+		"LOG_FORMAT":                     "text",
+		"LOG_LEVEL":                      "debug",
+		"WEBHOOK_ALLOW_PRIVATE_NETWORKS": "true",
+	},
+	"test": {
+		"LOG_FORMAT":                     "text",
+		"LOG_LEVEL":                      "warn",
+		"WEBHOOK_ALLOW_PRIVATE_NETWORKS": "true",
+	},
+	"production": {
+		"LOG_FORMAT": "json",
+		"LOG_LEVEL":  "info",
+	},
+}
+
+// source looks settings up in environment variables, then the config file,
+// then the profile. It records every problem it meets so they can be
+// reported together.
+type source struct {
+	getenv  func(string) string
+	file    map[string]string
+	profile map[string]string
+	used    map[string]bool
+	errs    []string
+}
+
+func (s *source) errorf(format string, args ...any) {
+	s.errs = append(s.errs, fmt.Sprintf(format, args...))
+}
+
+// lookup returns the setting for key and whether one was given anywhere
+func (s *source) lookup(key string) (string, bool) {
+	s.used[key] = true
+	if v := s.getenv(key); v != "" {
+		return v, true
+	}
+	if v, ok := s.file[key]; ok && v != "" {
+		return v, true
+	}
+	if v, ok := s.profile[key]; ok {
+		return v, true
+	}
+	return "", false
+}
+
+func (s *source) string(key, def string) string {
+	if v, ok := s.lookup(key); ok {
+		return v
+	}
+	return def
+}
+
+func (s *source) required(key string) string {
+	v, ok := s.lookup(key)
+	if !ok {
+		s.errorf("%s is required", key)
+	}
+	return v
+}
+
+func (s *source) oneOf(key, def string, options ...string) string {
+	v := s.string(key, def)
+	if !slices.Contains(options, v) {
+		s.errorf("%s must be one of %s, got %q", key, strings.Join(options, ", "), v)
+	}
+	return v
+}
+
+func (s *source) bool(key string, def bool) bool {
+	v, ok := s.lookup(key)
+	if !ok {
+		return def
+	}
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		s.errorf("%s must be true or false, got %q", key, v)
+		return def
+	}
+	return b
+}
+
+func (s *source) int(key string, def, min int) int {
+	v, ok := s.lookup(key)
+	if !ok {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < min {
+		s.errorf("%s must be a whole number of at least %d, got %q", key, min, v)
+		return def
+	}
+	return n
+}
+
+func (s *source) port(key string, def int) int {
+	v, ok := s.lookup(key)
+	if !ok {
+		return def
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 1 || n > 65535 {
+		s.errorf("%s must be a port number from 1 to 65535, got %q", key, v)
+		return def
+	}
+	return n
+}
+
+func (s *source) duration(key string, def time.Duration) time.Duration {
+	v, ok := s.lookup(key)
+	if !ok {
+		return def
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		s.errorf("%s must be a duration such as 30s or 2m, got %q", key, v)
+		return def
+	}
+	return d
+}
+
+// key reads a base64-encoded key of size bytes, or nil when unset
+func (s *source) key(key string, size int) []byte {
+	v, ok := s.lookup(key)
+	if !ok {
+		return nil
+	}
+	b, err := base64.StdEncoding.DecodeString(v)
+	if err != nil || len(b) != size {
+		s.errorf("%s must be %d random bytes, base64-encoded (openssl rand -base64 %d)", key, size, size)
+		return nil
+	}
+	return b
+}
+
+// list reads a comma-separated list
+func (s *source) list(key string) []string {
+	v, _ := s.lookup(key)
+	var list []string
+	for _, item := range strings.Split(v, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+// unused reports config file keys nothing read, which are usually typos
+func (s *source) unused() {
+	var keys []string
+	for key := range s.file {
+		if !s.used[key] {
+			keys = append(keys, key)
+		}
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		s.errorf("unknown setting %s in config file", key)
+	}
+}
+
+// readFile reads a JSON config file. Keys are the environment variable
+// names; an "environments" object holds overrides for each APP_ENV.
+//
+//	{"PORT": 8080, "environments": {"production": {"LOG_LEVEL": "warn"}}}
+func readFile(path, env string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var file map[string]json.RawMessage
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	values := map[string]string{}
+	if err := flatten(file, values); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if raw, ok := file["environments"]; ok {
+		var environments map[string]map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &environments); err != nil {
+			return nil, fmt.Errorf("%s: environments must map names to settings", path)
+		}
+		for name := range environments {
+			if _, ok := profiles[name]; !ok {
+				return nil, fmt.Errorf("%s: unknown environment %q", path, name)
+			}
+		}
+		if err := flatten(environments[env], values); err != nil {
+			return nil, fmt.Errorf("%s: environments.%s: %w", path, env, err)
+		}
+	}
+	return values, nil
+}
+
+// flatten turns JSON strings, numbers, booleans and string arrays into the
+// strings an environment variable would hold
+func flatten(settings map[string]json.RawMessage, into map[string]string) error {
+	for key, raw := range settings {
+		if key == "environments" {
+			continue
+		}
+		var value any
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return err
+		}
+		switch v := value.(type) {
+		case string:
+			into[key] = v
+		case float64, bool:
+			into[key] = string(raw)
+		case []any:
+			items := make([]string, len(v))
+			for i, item := range v {
+				s, ok := item.(string)
+				if !ok {
+					return fmt.Errorf("%s must be a list of strings", key)
+				}
+				items[i] = s
+			}
+			into[key] = strings.Join(items, ",")
+		case nil:
+		default:
+			return fmt.Errorf("%s must be a string, number, boolean or list", key)
+		}
+	}
+	return nil
+}
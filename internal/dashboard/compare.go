@@ -0,0 +1,132 @@
+package dashboard
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+	"github.com/yanonymousV2/finance-manager-backend/internal/helpers"
+	"github.com/yanonymousV2/finance-manager-backend/internal/middleware"
+)
+
+// Change is spending in one month against the month before. PercentChange
+// is nil when nothing was spent the month before.
+type Change struct {
+	Current       decimal.Decimal  `json:"current"`
+	Previous      decimal.Decimal  `json:"previous"`
+	Difference    decimal.Decimal  `json:"difference"`
+	PercentChange *decimal.Decimal `json:"percent_change"`
+}
+
+type CategoryChange struct {
+	CategoryID   *uuid.UUID `json:"category_id"`
+	CategoryName *string    `json:"category_name"`
+	Change
+}
+
+type MonthComparison struct {
+	Month         int              `json:"month"`
+	Year          int              `json:"year"`
+	PreviousMonth int              `json:"previous_month"`
+	PreviousYear  int              `json:"previous_year"`
+	Currency      string           `json:"currency"`
+	Total         Change           `json:"total"`
+	Categories    []CategoryChange `json:"categories"`
+}
+
+// newChange compares current with previous, with the percentage rounded to
+// one decimal place
+func newChange(current, previous decimal.Decimal) Change {
+	change := Change{Current: current, Previous: previous, Difference: current.Sub(previous)}
+	if previous.IsPositive() {
+		percent := change.Difference.Div(previous).Mul(decimal.NewFromInt(100)).Round(1)
+		change.PercentChange = &percent
+	}
+	return change
+}
+
+// GetMonthComparison compares a month's spending (default the current one)
+// with the month before, in total and per category
+func GetMonthComparison(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	now := time.Now()
+	month := int(now.Month())
+	year := now.Year()
+
+	if monthStr := c.Query("month"); monthStr != "" {
+		if _, err := fmt.Sscanf(monthStr, "%d", &month); err != nil || month < 1 || month > 12 {
+			c.JSON(400, gin.H{"error": "invalid month"})
+			return
+		}
+	}
+	if yearStr := c.Query("year"); yearStr != "" {
+		if _, err := fmt.Sscanf(yearStr, "%d", &year); err != nil || year < 2000 || year > 2100 {
+			c.JSON(400, gin.H{"error": "invalid year"})
+			return
+		}
+	}
+
+	currentStart := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+	previousStart := currentStart.AddDate(0, -1, 0)
+
+	currency, err := helpers.GetUserCurrency(c.Request.Context(), db, userID)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to get default currency"})
+		return
+	}
+
+	rows, err := db.Pool.Query(c.Request.Context(),
+		`SELECT ec.id, ec.name,
+		        COALESCE(SUM(pe.amount) FILTER (WHERE pe.expense_date >= $3), 0),
+		        COALESCE(SUM(pe.amount) FILTER (WHERE pe.expense_date < $3), 0)
+		 FROM personal_expenses pe
+		 LEFT JOIN expense_categories ec ON pe.category_id = ec.id
+		 WHERE pe.user_id = $1 AND pe.currency = $2 AND pe.expense_date >= $4 AND pe.expense_date < $5
+		 GROUP BY ec.id, ec.name`,
+		userID, currency, currentStart, previousStart, currentStart.AddDate(0, 1, 0))
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to compare months"})
+		return
+	}
+	defer rows.Close()
+
+	var currentTotal, previousTotal decimal.Decimal
+	categories := []CategoryChange{}
+	for rows.Next() {
+		var cc CategoryChange
+		var current, previous decimal.Decimal
+		if err := rows.Scan(&cc.CategoryID, &cc.CategoryName, &current, &previous); err != nil {
+			c.JSON(500, gin.H{"error": "failed to scan comparison"})
+			return
+		}
+		cc.Change = newChange(current, previous)
+		currentTotal = currentTotal.Add(current)
+		previousTotal = previousTotal.Add(previous)
+		categories = append(categories, cc)
+	}
+
+	// Biggest swings first, either way
+	sort.Slice(categories, func(i, j int) bool {
+		return categories[i].Difference.Abs().GreaterThan(categories[j].Difference.Abs())
+	})
+
+	c.JSON(200, MonthComparison{
+		Month:         month,
+		Year:          year,
+		PreviousMonth: int(previousStart.Month()),
+		PreviousYear:  previousStart.Year(),
+		Currency:      currency,
+		Total:         newChange(currentTotal, previousTotal),
+		Categories:    categories,
+	})
+}
@@ -0,0 +1,27 @@
+package dashboard
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewChange(t *testing.T) {
+	d := decimal.RequireFromString
+
+	change := newChange(d("264"), d("200"))
+	assert.Equal(t, "64", change.Difference.String())
+	require.NotNil(t, change.PercentChange)
+	assert.Equal(t, "32", change.PercentChange.String())
+
+	change = newChange(d("50"), d("150"))
+	assert.Equal(t, "-100", change.Difference.String())
+	require.NotNil(t, change.PercentChange)
+	assert.Equal(t, "-66.7", change.PercentChange.String())
+
+	change = newChange(d("80"), decimal.Zero)
+	assert.Equal(t, "80", change.Difference.String())
+	assert.Nil(t, change.PercentChange)
+}
@@ -1,6 +1,7 @@
 package dashboard
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -8,162 +9,341 @@ import (
 	"github.com/google/uuid"
 	"github.com/shopspring/decimal"
 
+	"github.com/yanonymousV2/finance-manager-backend/internal/budget"
 	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+	"github.com/yanonymousV2/finance-manager-backend/internal/helpers"
 	"github.com/yanonymousV2/finance-manager-backend/internal/middleware"
 )
 
 type CategorySpending struct {
 	CategoryID   *uuid.UUID      `json:"category_id"`
+	ParentID     *uuid.UUID      `json:"parent_id,omitempty"`
 	CategoryName *string         `json:"category_name"`
 	TotalAmount  decimal.Decimal `json:"total_amount"`
 	ExpenseCount int             `json:"expense_count"`
+	// Source is personal, or group for shares of group expenses, which are
+	// broken down by their free-form category label
+	Source string `json:"source"`
 }
 
-type MonthlyDashboard struct {
-	Month             int                `json:"month"`
-	Year              int                `json:"year"`
+// SourceSpending splits total spending into personal expenses and the user's
+// shares of group expenses
+type SourceSpending struct {
+	Personal          decimal.Decimal `json:"personal"`
+	Group             decimal.Decimal `json:"group"`
+	GroupExpenseCount int             `json:"group_expense_count"`
+}
+
+// Summary is the spending in a period against its budget. Budget is the
+// base amount, CarriedOver what rollover budgets before it add and
+// EffectiveBudget their sum.
+type Summary struct {
+	Currency          string             `json:"currency"`
 	Budget            *decimal.Decimal   `json:"budget"`
+	CarriedOver       *decimal.Decimal   `json:"carried_over"`
+	EffectiveBudget   *decimal.Decimal   `json:"effective_budget"`
 	TotalSpent        decimal.Decimal    `json:"total_spent"`
 	RemainingBudget   *decimal.Decimal   `json:"remaining_budget"`
-	DaysInMonth       int                `json:"days_in_month"`
 	DaysElapsed       int                `json:"days_elapsed"`
 	DaysRemaining     int                `json:"days_remaining"`
 	DailyAverageSpent decimal.Decimal    `json:"daily_average_spent"`
 	ProjectedSpending *decimal.Decimal   `json:"projected_spending"`
 	IsOverBudget      bool               `json:"is_over_budget"`
 	ExpenseCount      int                `json:"expense_count"`
+	BySource          *SourceSpending    `json:"by_source,omitempty"`
 	CategoryBreakdown []CategorySpending `json:"category_breakdown"`
 }
 
-func GetMonthlyDashboard(c *gin.Context, db *db.DB) {
-	userID, ok := middleware.GetUserID(c)
-	if !ok {
-		c.JSON(401, gin.H{"error": "unauthorized"})
-		return
-	}
-
-	monthStr := c.Query("month")
-	yearStr := c.Query("year")
+type MonthlyDashboard struct {
+	Month       int `json:"month"`
+	Year        int `json:"year"`
+	DaysInMonth int `json:"days_in_month"`
+	Summary
+}
 
-	now := time.Now()
-	month := int(now.Month())
-	year := now.Year()
+type PeriodDashboard struct {
+	Period       string    `json:"period"`
+	StartDate    time.Time `json:"start_date"`
+	EndDate      time.Time `json:"end_date"`
+	DaysInPeriod int       `json:"days_in_period"`
+	Summary
+}
 
-	if monthStr != "" {
-		if _, err := fmt.Sscanf(monthStr, "%d", &month); err != nil || month < 1 || month > 12 {
-			c.JSON(400, gin.H{"error": "invalid month"})
-			return
-		}
+// periodDays returns how many days the period from start to end (both
+// inclusive) has and how many of them have begun by now
+func periodDays(start, end, now time.Time) (total, elapsed int) {
+	total = int(end.Sub(start).Hours()/24) + 1
+	switch {
+	case now.Before(start):
+		elapsed = 0
+	case !now.Before(end.AddDate(0, 0, 1)):
+		elapsed = total
+	default:
+		elapsed = int(now.Sub(start).Hours()/24) + 1
 	}
-	if yearStr != "" {
-		if _, err := fmt.Sscanf(yearStr, "%d", &year); err != nil || year < 2000 || year > 2100 {
-			c.JSON(400, gin.H{"error": "invalid year"})
-			return
-		}
+	return total, elapsed
+}
+
+type summaryOptions struct {
+	// Rollup counts subcategory spending towards its top-level category
+	Rollup bool
+	// IncludeGroups adds the user's shares of group expenses
+	IncludeGroups bool
+}
+
+func parseSummaryOptions(c *gin.Context) summaryOptions {
+	return summaryOptions{
+		Rollup:        c.Query("rollup") == "true",
+		IncludeGroups: c.Query("include_groups") == "true",
 	}
+}
 
-	startDate := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
-	endDate := startDate.AddDate(0, 1, 0)
+// summarize computes the spending from start to end (both inclusive) against
+// b, which may be nil
+func summarize(ctx context.Context, db *db.DB, userID uuid.UUID, start, end, now time.Time, b *budget.Budget, opts summaryOptions) (Summary, error) {
+	summary := Summary{}
+	endExclusive := end.AddDate(0, 0, 1)
 
-	var budget *decimal.Decimal
-	var budgetAmount decimal.Decimal
-	err := db.Pool.QueryRow(c.Request.Context(),
-		`SELECT amount FROM monthly_budgets WHERE user_id = $1 AND month = $2 AND year = $3`,
-		userID, month, year).Scan(&budgetAmount)
-	if err == nil {
-		budget = &budgetAmount
+	// Totals are in the user's default currency. Foreign expenses count at
+	// their converted amount; mirrored group shares in another currency
+	// have no rate and are left out.
+	currency, err := helpers.GetUserCurrency(ctx, db, userID)
+	if err != nil {
+		return summary, fmt.Errorf("get default currency: %w", err)
+	}
+	summary.Currency = currency
+
+	// Rollover budgets in the periods before carry their leftover or overspend
+	if b != nil {
+		carried, err := budget.Carryover(ctx, db, *b, currency)
+		if err != nil {
+			return summary, fmt.Errorf("calculate budget carryover: %w", err)
+		}
+		effective := b.Amount.Add(carried)
+		summary.Budget = &b.Amount
+		summary.CarriedOver, summary.EffectiveBudget = &carried, &effective
 	}
 
-	var totalSpent decimal.Decimal
-	var expenseCount int
-	err = db.Pool.QueryRow(c.Request.Context(),
+	err = db.Pool.QueryRow(ctx,
 		`SELECT COALESCE(SUM(amount), 0), COUNT(*) 
 		 FROM personal_expenses 
-		 WHERE user_id = $1 AND expense_date >= $2 AND expense_date < $3`,
-		userID, startDate, endDate).Scan(&totalSpent, &expenseCount)
+		 WHERE user_id = $1 AND expense_date >= $2 AND expense_date < $3 AND currency = $4`,
+		userID, start, endExclusive, currency).Scan(&summary.TotalSpent, &summary.ExpenseCount)
 	if err != nil {
-		c.JSON(500, gin.H{"error": "failed to calculate total spent"})
-		return
+		return summary, fmt.Errorf("calculate total spent: %w", err)
 	}
 
-	rows, err := db.Pool.Query(c.Request.Context(),
-		`SELECT pe.category_id, ec.name, COALESCE(SUM(pe.amount), 0), COUNT(*) 
+	breakdownQuery := `SELECT ec.id, ec.parent_id, ec.name, COALESCE(SUM(pe.amount), 0), COUNT(*) 
 		 FROM personal_expenses pe 
 		 LEFT JOIN expense_categories ec ON pe.category_id = ec.id 
-		 WHERE pe.user_id = $1 AND pe.expense_date >= $2 AND pe.expense_date < $3 
-		 GROUP BY pe.category_id, ec.name 
-		 ORDER BY SUM(pe.amount) DESC`,
-		userID, startDate, endDate)
+		 WHERE pe.user_id = $1 AND pe.expense_date >= $2 AND pe.expense_date < $3 AND pe.currency = $4 
+		 GROUP BY ec.id, ec.parent_id, ec.name 
+		 ORDER BY SUM(pe.amount) DESC`
+	if opts.Rollup {
+		breakdownQuery = `WITH RECURSIVE roots AS (
+		     SELECT id, id AS root_id FROM expense_categories WHERE user_id = $1 AND parent_id IS NULL
+		     UNION ALL
+		     SELECT ec.id, r.root_id FROM expense_categories ec JOIN roots r ON ec.parent_id = r.id
+		 )
+		 SELECT ec.id, ec.parent_id, ec.name, COALESCE(SUM(pe.amount), 0), COUNT(*) 
+		 FROM personal_expenses pe 
+		 LEFT JOIN roots r ON pe.category_id = r.id 
+		 LEFT JOIN expense_categories ec ON r.root_id = ec.id 
+		 WHERE pe.user_id = $1 AND pe.expense_date >= $2 AND pe.expense_date < $3 AND pe.currency = $4 
+		 GROUP BY ec.id, ec.parent_id, ec.name 
+		 ORDER BY SUM(pe.amount) DESC`
+	}
+
+	rows, err := db.Pool.Query(ctx, breakdownQuery, userID, start, endExclusive, currency)
 	if err != nil {
-		c.JSON(500, gin.H{"error": "failed to get category breakdown"})
-		return
+		return summary, fmt.Errorf("get category breakdown: %w", err)
 	}
 	defer rows.Close()
 
-	var categoryBreakdown []CategorySpending
+	summary.CategoryBreakdown = []CategorySpending{}
 	for rows.Next() {
 		var cs CategorySpending
-		if err := rows.Scan(&cs.CategoryID, &cs.CategoryName, &cs.TotalAmount, &cs.ExpenseCount); err != nil {
-			c.JSON(500, gin.H{"error": "failed to scan category breakdown"})
-			return
+		if err := rows.Scan(&cs.CategoryID, &cs.ParentID, &cs.CategoryName, &cs.TotalAmount, &cs.ExpenseCount); err != nil {
+			return summary, fmt.Errorf("scan category breakdown: %w", err)
 		}
-		categoryBreakdown = append(categoryBreakdown, cs)
+		cs.Source = sourcePersonal
+		summary.CategoryBreakdown = append(summary.CategoryBreakdown, cs)
+	}
+	if err := rows.Err(); err != nil {
+		return summary, fmt.Errorf("get category breakdown: %w", err)
 	}
 
-	if categoryBreakdown == nil {
-		categoryBreakdown = []CategorySpending{}
+	if opts.IncludeGroups {
+		if err := addGroupShares(ctx, db, &summary, userID, start, endExclusive); err != nil {
+			return summary, fmt.Errorf("add group shares: %w", err)
+		}
 	}
 
-	daysInMonth := endDate.AddDate(0, 0, -1).Day()
-	var daysElapsed int
-	var daysRemaining int
+	days, elapsed := periodDays(start, end, now)
+	summary.DaysElapsed = elapsed
+	summary.DaysRemaining = days - elapsed
 
-	if year == now.Year() && month == int(now.Month()) {
-		daysElapsed = now.Day()
-		daysRemaining = daysInMonth - daysElapsed
-	} else if startDate.After(now) {
-		daysElapsed = 0
-		daysRemaining = daysInMonth
-	} else {
-		daysElapsed = daysInMonth
-		daysRemaining = 0
+	summary.DailyAverageSpent = decimal.Zero
+	if elapsed > 0 {
+		summary.DailyAverageSpent = summary.TotalSpent.Div(decimal.NewFromInt(int64(elapsed)))
 	}
 
-	dailyAverageSpent := decimal.Zero
-	if daysElapsed > 0 {
-		dailyAverageSpent = totalSpent.Div(decimal.NewFromInt(int64(daysElapsed)))
+	if summary.EffectiveBudget != nil {
+		remaining := summary.EffectiveBudget.Sub(summary.TotalSpent)
+		summary.RemainingBudget = &remaining
+		summary.IsOverBudget = remaining.IsNegative()
+
+		if elapsed > 0 {
+			projected := summary.DailyAverageSpent.Mul(decimal.NewFromInt(int64(days)))
+			summary.ProjectedSpending = &projected
+		}
 	}
 
-	var remainingBudget *decimal.Decimal
-	var projectedSpending *decimal.Decimal
-	var isOverBudget bool
+	return summary, nil
+}
 
-	if budget != nil {
-		remaining := budget.Sub(totalSpent)
-		remainingBudget = &remaining
-		isOverBudget = remaining.IsNegative()
+// parseMonth reads the month and year query parameters, defaulting to the
+// month containing now, and responds with 400 when they are invalid
+func parseMonth(c *gin.Context, now time.Time) (month, year int, ok bool) {
+	month = int(now.Month())
+	year = now.Year()
 
-		if daysElapsed > 0 {
-			projected := dailyAverageSpent.Mul(decimal.NewFromInt(int64(daysInMonth)))
-			projectedSpending = &projected
+	if monthStr := c.Query("month"); monthStr != "" {
+		if _, err := fmt.Sscanf(monthStr, "%d", &month); err != nil || month < 1 || month > 12 {
+			c.JSON(400, gin.H{"error": "invalid month"})
+			return 0, 0, false
 		}
 	}
+	if yearStr := c.Query("year"); yearStr != "" {
+		if _, err := fmt.Sscanf(yearStr, "%d", &year); err != nil || year < 2000 || year > 2100 {
+			c.JSON(400, gin.H{"error": "invalid year"})
+			return 0, 0, false
+		}
+	}
+	return month, year, true
+}
+
+// monthlyDashboard summarizes a calendar month against its monthly budget
+func monthlyDashboard(ctx context.Context, db *db.DB, userID uuid.UUID, month, year int, now time.Time, opts summaryOptions) (MonthlyDashboard, error) {
+	startDate := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+	endDate := startDate.AddDate(0, 1, -1)
 
-	dashboard := MonthlyDashboard{
-		Month:             month,
-		Year:              year,
-		Budget:            budget,
-		TotalSpent:        totalSpent,
-		RemainingBudget:   remainingBudget,
-		DaysInMonth:       daysInMonth,
-		DaysElapsed:       daysElapsed,
-		DaysRemaining:     daysRemaining,
-		DailyAverageSpent: dailyAverageSpent,
-		ProjectedSpending: projectedSpending,
-		IsOverBudget:      isOverBudget,
-		ExpenseCount:      expenseCount,
-		CategoryBreakdown: categoryBreakdown,
+	b, err := budget.Find(ctx, db, userID, budget.PeriodMonthly, startDate)
+	if err != nil {
+		return MonthlyDashboard{}, fmt.Errorf("failed to get budget: %w", err)
+	}
+
+	summary, err := summarize(ctx, db, userID, startDate, endDate, now, b, opts)
+	if err != nil {
+		return MonthlyDashboard{}, err
+	}
+
+	// The forecast replaces the straight-line projection; it only covers
+	// personal expenses, so group shares so far are added as they are
+	if summary.ProjectedSpending != nil {
+		forecast, err := forecastMonth(ctx, db, userID, summary.Currency, month, year, now)
+		if err != nil {
+			return MonthlyDashboard{}, fmt.Errorf("failed to forecast spending: %w", err)
+		}
+		projected := forecast.Projected
+		if summary.BySource != nil {
+			projected = projected.Add(summary.BySource.Group)
+		}
+		summary.ProjectedSpending = &projected
+	}
+
+	days, _ := periodDays(startDate, endDate, now)
+	return MonthlyDashboard{
+		Month:       month,
+		Year:        year,
+		DaysInMonth: days,
+		Summary:     summary,
+	}, nil
+}
+
+// Monthly summarizes a calendar month for the user as GET /dashboard/monthly
+// does, adding their shares of group expenses when includeGroups is set
+func Monthly(ctx context.Context, db *db.DB, userID uuid.UUID, month, year int, includeGroups bool) (MonthlyDashboard, error) {
+	return monthlyDashboard(ctx, db, userID, month, year, time.Now(), summaryOptions{IncludeGroups: includeGroups})
+}
+
+func GetMonthlyDashboard(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	now := time.Now()
+	month, year, ok := parseMonth(c, now)
+	if !ok {
+		return
+	}
+
+	dashboard, err := monthlyDashboard(c.Request.Context(), db, userID, month, year, now, parseSummaryOptions(c))
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to build dashboard"})
+		return
 	}
 
 	c.JSON(200, dashboard)
 }
+
+// GetPeriodDashboard summarizes the budget period of the given type covering
+// a date (default today). The window is that budget's range, or the calendar
+// week, month or year when no budget covers the date.
+func GetPeriodDashboard(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	period := c.DefaultQuery("period", budget.PeriodMonthly)
+	if period != budget.PeriodWeekly && period != budget.PeriodMonthly && period != budget.PeriodYearly && period != budget.PeriodCustom {
+		c.JSON(400, gin.H{"error": "invalid period"})
+		return
+	}
+
+	now := time.Now()
+	date := now
+	if dateStr := c.Query("date"); dateStr != "" {
+		parsed, err := time.Parse("2006-01-02", dateStr)
+		if err != nil {
+			c.JSON(400, gin.H{"error": "invalid date, use YYYY-MM-DD"})
+			return
+		}
+		date = parsed
+	}
+
+	b, err := budget.Find(c.Request.Context(), db, userID, period, date)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to get budget"})
+		return
+	}
+
+	var startDate, endDate time.Time
+	if b != nil {
+		startDate, endDate = b.StartDate, b.EndDate
+	} else if start, end, ok := budget.CalendarRange(period, date); ok {
+		startDate, endDate = start, end
+	} else {
+		c.JSON(404, gin.H{"error": "no custom budget covers this date"})
+		return
+	}
+
+	summary, err := summarize(c.Request.Context(), db, userID, startDate, endDate, now, b, parseSummaryOptions(c))
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to build dashboard"})
+		return
+	}
+
+	days, _ := periodDays(startDate, endDate, now)
+	c.JSON(200, PeriodDashboard{
+		Period:       period,
+		StartDate:    startDate,
+		EndDate:      endDate,
+		DaysInPeriod: days,
+		Summary:      summary,
+	})
+}
@@ -0,0 +1,388 @@
+package dashboard
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+	"github.com/yanonymousV2/finance-manager-backend/internal/helpers"
+	"github.com/yanonymousV2/finance-manager-backend/internal/middleware"
+)
+
+const (
+	// forecastHistoryMonths is how many months before the forecast month
+	// the daily averages are taken from
+	forecastHistoryMonths = 3
+	// forecastZ spreads the bounds to an 80% interval
+	forecastZ          = 1.28
+	forecastConfidence = 0.8
+)
+
+// historyWeights weighs the months before the forecast month, most recent
+// first
+var historyWeights = []float64{3, 2, 1}
+
+// RecurringCharge is a merchant charged once in each of the months before
+// the forecast month. Charged tells whether it has come this month already.
+type RecurringCharge struct {
+	MerchantID   uuid.UUID       `json:"merchant_id"`
+	MerchantName string          `json:"merchant_name"`
+	CategoryID   *uuid.UUID      `json:"category_id"`
+	Amount       decimal.Decimal `json:"amount"`
+	Charged      bool            `json:"charged"`
+}
+
+type CategoryForecast struct {
+	CategoryID        *uuid.UUID      `json:"category_id"`
+	CategoryName      *string         `json:"category_name"`
+	SpentToDate       decimal.Decimal `json:"spent_to_date"`
+	UpcomingRecurring decimal.Decimal `json:"upcoming_recurring"`
+	DailyRate         decimal.Decimal `json:"daily_rate"`
+	Projected         decimal.Decimal `json:"projected"`
+	Low               decimal.Decimal `json:"low"`
+	High              decimal.Decimal `json:"high"`
+
+	// spread is the half width of the bounds before clamping, which totals
+	// combine as independent
+	spread float64
+}
+
+type Forecast struct {
+	Month       int                `json:"month"`
+	Year        int                `json:"year"`
+	Currency    string             `json:"currency"`
+	DaysInMonth int                `json:"days_in_month"`
+	DaysElapsed int                `json:"days_elapsed"`
+	Confidence  float64            `json:"confidence"`
+	SpentToDate decimal.Decimal    `json:"spent_to_date"`
+	Projected   decimal.Decimal    `json:"projected"`
+	Low         decimal.Decimal    `json:"low"`
+	High        decimal.Decimal    `json:"high"`
+	Categories  []CategoryForecast `json:"categories"`
+	Recurring   []RecurringCharge  `json:"recurring"`
+}
+
+// forecastInput is one category's spending; variable amounts leave out
+// recurring charges, which are forecast separately
+type forecastInput struct {
+	// HistoryRates are the daily variable spending rates of the months
+	// before, most recent first
+	HistoryRates     []float64
+	VariableSpent    float64
+	RecurringCharged float64
+	RecurringPending float64
+	DaysInMonth      int
+	DaysElapsed      int
+}
+
+// forecastCategory projects a category's month. The daily rate blends this
+// month's rate with a recency-weighted average of the months before, leaning
+// on this month more as it goes on. The bounds come from how much those
+// daily rates vary.
+func forecastCategory(in forecastInput) CategoryForecast {
+	var history, weights float64
+	for i, rate := range in.HistoryRates {
+		if i >= len(historyWeights) {
+			break
+		}
+		history += rate * historyWeights[i]
+		weights += historyWeights[i]
+	}
+
+	var current float64
+	if in.DaysElapsed > 0 {
+		current = in.VariableSpent / float64(in.DaysElapsed)
+	}
+
+	rate := current
+	rates := append([]float64(nil), in.HistoryRates...)
+	if weights > 0 {
+		history /= weights
+		elapsed := float64(in.DaysElapsed) / float64(in.DaysInMonth)
+		rate = elapsed*current + (1-elapsed)*history
+	}
+	if in.DaysElapsed > 0 {
+		rates = append(rates, current)
+	}
+
+	remaining := float64(in.DaysInMonth - in.DaysElapsed)
+	known := in.VariableSpent + in.RecurringCharged + in.RecurringPending
+	projected := known + rate*remaining
+
+	spread := forecastZ * rateDeviation(rates, rate) * remaining
+	low := math.Max(known, projected-spread)
+
+	return CategoryForecast{
+		SpentToDate:       money(in.VariableSpent + in.RecurringCharged),
+		UpcomingRecurring: money(in.RecurringPending),
+		DailyRate:         money(rate),
+		Projected:         money(projected),
+		Low:               money(low),
+		High:              money(projected + spread),
+		spread:            spread,
+	}
+}
+
+// rateDeviation is the sample standard deviation of rates, or half of rate
+// when there are too few to tell
+func rateDeviation(rates []float64, rate float64) float64 {
+	if len(rates) < 2 {
+		return rate / 2
+	}
+	var mean float64
+	for _, r := range rates {
+		mean += r
+	}
+	mean /= float64(len(rates))
+	var sum float64
+	for _, r := range rates {
+		sum += (r - mean) * (r - mean)
+	}
+	return math.Sqrt(sum / float64(len(rates)-1))
+}
+
+func money(f float64) decimal.Decimal {
+	return decimal.NewFromFloat(f).Round(2)
+}
+
+type forecastKey struct {
+	categoryID uuid.UUID
+	merchantID uuid.UUID
+}
+
+// forecastRow is the spending of one month (0 is the forecast month, 1 the
+// month before, ...) in one category at one merchant
+type forecastRow struct {
+	month        int
+	categoryID   *uuid.UUID
+	categoryName *string
+	merchantID   *uuid.UUID
+	merchantName *string
+	amount       float64
+	count        int
+}
+
+func loadForecastRows(ctx context.Context, db *db.DB, userID uuid.UUID, currency string, start time.Time) ([]forecastRow, error) {
+	historyStart := start.AddDate(0, -forecastHistoryMonths, 0)
+	rows, err := db.Pool.Query(ctx,
+		`SELECT date_trunc('month', pe.expense_date AT TIME ZONE 'UTC'), ec.id, ec.name, m.id, m.name,
+		        SUM(pe.amount)::float8, COUNT(*)
+		 FROM personal_expenses pe
+		 LEFT JOIN expense_categories ec ON pe.category_id = ec.id
+		 LEFT JOIN merchants m ON pe.merchant_id = m.id
+		 WHERE pe.user_id = $1 AND pe.currency = $2 AND pe.expense_date >= $3 AND pe.expense_date < $4
+		 GROUP BY 1, ec.id, ec.name, m.id, m.name`,
+		userID, currency, historyStart, start.AddDate(0, 1, 0))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []forecastRow
+	for rows.Next() {
+		var r forecastRow
+		var bucket time.Time
+		if err := rows.Scan(&bucket, &r.categoryID, &r.categoryName, &r.merchantID, &r.merchantName, &r.amount, &r.count); err != nil {
+			return nil, err
+		}
+		r.month = (start.Year()-bucket.Year())*12 + int(start.Month()) - int(bucket.Month())
+		result = append(result, r)
+	}
+	return result, rows.Err()
+}
+
+// buildForecast turns the month's and the previous months' spending into a
+// forecast
+func buildForecast(rows []forecastRow, daysInMonth, daysElapsed int, historyDays []int) ([]CategoryForecast, []RecurringCharge) {
+	// A merchant charged exactly once in every month before, in the same
+	// category, is taken as recurring
+	seen := map[forecastKey][]forecastRow{}
+	for _, r := range rows {
+		if r.merchantID == nil || r.month == 0 || r.count != 1 {
+			continue
+		}
+		key := forecastKey{merchantID: *r.merchantID}
+		if r.categoryID != nil {
+			key.categoryID = *r.categoryID
+		}
+		seen[key] = append(seen[key], r)
+	}
+	recurring := map[forecastKey]*RecurringCharge{}
+	for key, months := range seen {
+		if len(months) != forecastHistoryMonths {
+			continue
+		}
+		var total float64
+		for _, r := range months {
+			total += r.amount
+		}
+		recurring[key] = &RecurringCharge{
+			MerchantID:   key.merchantID,
+			MerchantName: *months[0].merchantName,
+			CategoryID:   months[0].categoryID,
+			Amount:       money(total / float64(len(months))),
+		}
+	}
+
+	type categoryTotals struct {
+		id        *uuid.UUID
+		name      *string
+		variable  []float64
+		charged   float64
+		pending   float64
+		hasRecent bool
+	}
+	categories := map[uuid.UUID]*categoryTotals{}
+	category := func(id *uuid.UUID, name *string) *categoryTotals {
+		key := uuid.Nil
+		if id != nil {
+			key = *id
+		}
+		if categories[key] == nil {
+			categories[key] = &categoryTotals{id: id, name: name, variable: make([]float64, forecastHistoryMonths+1)}
+		}
+		return categories[key]
+	}
+
+	for _, r := range rows {
+		ct := category(r.categoryID, r.categoryName)
+		key := forecastKey{}
+		if r.merchantID != nil {
+			key.merchantID = *r.merchantID
+		}
+		if r.categoryID != nil {
+			key.categoryID = *r.categoryID
+		}
+		if charge, ok := recurring[key]; ok && r.merchantID != nil {
+			if r.month == 0 {
+				charge.Charged = true
+				ct.charged += r.amount
+			}
+			continue
+		}
+		ct.variable[r.month] += r.amount
+	}
+	// Charges that have not come by the end of the month are not expected
+	for _, charge := range recurring {
+		if !charge.Charged && daysElapsed < daysInMonth {
+			category(charge.CategoryID, nil).pending += charge.Amount.InexactFloat64()
+		}
+	}
+
+	forecasts := []CategoryForecast{}
+	for _, ct := range categories {
+		rates := make([]float64, forecastHistoryMonths)
+		for i := range rates {
+			rates[i] = ct.variable[i+1] / float64(historyDays[i])
+		}
+		f := forecastCategory(forecastInput{
+			HistoryRates:     rates,
+			VariableSpent:    ct.variable[0],
+			RecurringCharged: ct.charged,
+			RecurringPending: ct.pending,
+			DaysInMonth:      daysInMonth,
+			DaysElapsed:      daysElapsed,
+		})
+		f.CategoryID, f.CategoryName = ct.id, ct.name
+		forecasts = append(forecasts, f)
+	}
+	sort.Slice(forecasts, func(i, j int) bool {
+		return forecasts[i].Projected.GreaterThan(forecasts[j].Projected)
+	})
+
+	charges := []RecurringCharge{}
+	for _, charge := range recurring {
+		charges = append(charges, *charge)
+	}
+	sort.Slice(charges, func(i, j int) bool { return charges[i].MerchantName < charges[j].MerchantName })
+	return forecasts, charges
+}
+
+// GetSpendingForecast projects a month's spending (default the current
+// month) per category, with 80% bounds
+func GetSpendingForecast(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	now := time.Now()
+	month := int(now.Month())
+	year := now.Year()
+
+	if monthStr := c.Query("month"); monthStr != "" {
+		if _, err := fmt.Sscanf(monthStr, "%d", &month); err != nil || month < 1 || month > 12 {
+			c.JSON(400, gin.H{"error": "invalid month"})
+			return
+		}
+	}
+	if yearStr := c.Query("year"); yearStr != "" {
+		if _, err := fmt.Sscanf(yearStr, "%d", &year); err != nil || year < 2000 || year > 2100 {
+			c.JSON(400, gin.H{"error": "invalid year"})
+			return
+		}
+	}
+
+	currency, err := helpers.GetUserCurrency(c.Request.Context(), db, userID)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to get default currency"})
+		return
+	}
+
+	forecast, err := forecastMonth(c.Request.Context(), db, userID, currency, month, year, now)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to forecast spending"})
+		return
+	}
+
+	c.JSON(200, forecast)
+}
+
+// forecastMonth forecasts the spending of month/year in currency as of now
+func forecastMonth(ctx context.Context, db *db.DB, userID uuid.UUID, currency string, month, year int, now time.Time) (Forecast, error) {
+	start := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+	daysInMonth, daysElapsed := periodDays(start, start.AddDate(0, 1, -1), now)
+	historyDays := make([]int, forecastHistoryMonths)
+	for i := range historyDays {
+		historyDays[i] = start.AddDate(0, -i, -1).Day()
+	}
+
+	rows, err := loadForecastRows(ctx, db, userID, currency, start)
+	if err != nil {
+		return Forecast{}, err
+	}
+	categories, recurring := buildForecast(rows, daysInMonth, daysElapsed, historyDays)
+
+	forecast := Forecast{
+		Month:       month,
+		Year:        year,
+		Currency:    currency,
+		DaysInMonth: daysInMonth,
+		DaysElapsed: daysElapsed,
+		Confidence:  forecastConfidence,
+		Categories:  categories,
+		Recurring:   recurring,
+	}
+	var spread float64
+	for _, f := range categories {
+		forecast.SpentToDate = forecast.SpentToDate.Add(f.SpentToDate)
+		forecast.Projected = forecast.Projected.Add(f.Projected)
+		spread += f.spread * f.spread
+	}
+	spread = math.Sqrt(spread)
+	known := forecast.SpentToDate
+	for _, f := range categories {
+		known = known.Add(f.UpcomingRecurring)
+	}
+	forecast.Low = decimal.Max(known, forecast.Projected.Sub(money(spread)))
+	forecast.High = forecast.Projected.Add(money(spread))
+	return forecast, nil
+}
@@ -0,0 +1,87 @@
+package dashboard
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestForecastCategory(t *testing.T) {
+	// Halfway through a 30 day month at 10 a day, after months of 10 a day
+	f := forecastCategory(forecastInput{
+		HistoryRates:  []float64{10, 10, 10},
+		VariableSpent: 150,
+		DaysInMonth:   30,
+		DaysElapsed:   15,
+	})
+	assert.Equal(t, "300", f.Projected.String())
+	assert.Equal(t, "10", f.DailyRate.String())
+	assert.Equal(t, "300", f.Low.String())
+	assert.Equal(t, "300", f.High.String())
+
+	// Spending faster than usual: the rate moves between the two
+	f = forecastCategory(forecastInput{
+		HistoryRates:  []float64{10, 10, 10},
+		VariableSpent: 300,
+		DaysInMonth:   30,
+		DaysElapsed:   15,
+	})
+	assert.Equal(t, "15", f.DailyRate.String())
+	assert.Equal(t, "525", f.Projected.String())
+	assert.True(t, f.Low.LessThan(f.Projected))
+	assert.True(t, f.High.GreaterThan(f.Projected))
+	assert.True(t, f.Low.GreaterThanOrEqual(f.SpentToDate))
+
+	// Upcoming recurring charges are added on top
+	f = forecastCategory(forecastInput{
+		HistoryRates:     []float64{0, 0, 0},
+		RecurringPending: 12.99,
+		DaysInMonth:      30,
+		DaysElapsed:      10,
+	})
+	assert.Equal(t, "12.99", f.Projected.String())
+	assert.Equal(t, "12.99", f.UpcomingRecurring.String())
+
+	// A finished month is what was spent
+	f = forecastCategory(forecastInput{
+		HistoryRates:  []float64{50, 5},
+		VariableSpent: 420,
+		DaysInMonth:   28,
+		DaysElapsed:   28,
+	})
+	assert.Equal(t, "420", f.Projected.String())
+	assert.Equal(t, "420", f.Low.String())
+	assert.Equal(t, "420", f.High.String())
+}
+
+func TestBuildForecastRecurring(t *testing.T) {
+	streaming := uuid.New()
+	grocer := uuid.New()
+	name := func(s string) *string { return &s }
+
+	rows := []forecastRow{
+		{month: 1, merchantID: &streaming, merchantName: name("Netflix"), amount: 15.49, count: 1},
+		{month: 2, merchantID: &streaming, merchantName: name("Netflix"), amount: 15.49, count: 1},
+		{month: 3, merchantID: &streaming, merchantName: name("Netflix"), amount: 15.49, count: 1},
+		// Several visits a month is not a subscription
+		{month: 1, merchantID: &grocer, merchantName: name("Grocer"), amount: 300, count: 4},
+		{month: 2, merchantID: &grocer, merchantName: name("Grocer"), amount: 300, count: 4},
+		{month: 3, merchantID: &grocer, merchantName: name("Grocer"), amount: 300, count: 4},
+		{month: 0, merchantID: &grocer, merchantName: name("Grocer"), amount: 100, count: 1},
+	}
+
+	categories, recurring := buildForecast(rows, 30, 10, []int{30, 31, 30})
+	require.Len(t, recurring, 1)
+	assert.Equal(t, "Netflix", recurring[0].MerchantName)
+	assert.False(t, recurring[0].Charged)
+
+	require.Len(t, categories, 1)
+	assert.Equal(t, "15.49", categories[0].UpcomingRecurring.String())
+	assert.Equal(t, "100", categories[0].SpentToDate.String())
+
+	// Once the month is over, a charge that never came is not expected
+	categories, _ = buildForecast(rows, 30, 30, []int{30, 31, 30})
+	assert.True(t, categories[0].UpcomingRecurring.IsZero())
+}
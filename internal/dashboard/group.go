@@ -0,0 +1,224 @@
+package dashboard
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+	"github.com/yanonymousV2/finance-manager-backend/internal/helpers"
+	"github.com/yanonymousV2/finance-manager-backend/internal/middleware"
+)
+
+type MemberSpending struct {
+	UserID   uuid.UUID       `json:"user_id"`
+	Paid     decimal.Decimal `json:"paid"`
+	Consumed decimal.Decimal `json:"consumed"`
+}
+
+type LargestExpense struct {
+	ID          uuid.UUID       `json:"id"`
+	Description string          `json:"description"`
+	TotalAmount decimal.Decimal `json:"total_amount"`
+	PaidBy      uuid.UUID       `json:"paid_by"`
+	ExpenseDate time.Time       `json:"expense_date"`
+}
+
+type MonthlySpending struct {
+	Month        int             `json:"month"`
+	Year         int             `json:"year"`
+	TotalAmount  decimal.Decimal `json:"total_amount"`
+	ExpenseCount int             `json:"expense_count"`
+}
+
+type GroupCategorySpending struct {
+	Category     *string         `json:"category"`
+	TotalAmount  decimal.Decimal `json:"total_amount"`
+	ExpenseCount int             `json:"expense_count"`
+}
+
+type TagSpending struct {
+	Tag          string          `json:"tag"`
+	TotalAmount  decimal.Decimal `json:"total_amount"`
+	ExpenseCount int             `json:"expense_count"`
+}
+
+type GroupDashboard struct {
+	GroupID           uuid.UUID               `json:"group_id"`
+	Currency          string                  `json:"currency"`
+	TotalSpent        decimal.Decimal         `json:"total_spent"`
+	ExpenseCount      int                     `json:"expense_count"`
+	MemberSpending    []MemberSpending        `json:"member_spending"`
+	LargestExpenses   []LargestExpense        `json:"largest_expenses"`
+	MonthlySpending   []MonthlySpending       `json:"monthly_spending"`
+	CategoryBreakdown []GroupCategorySpending `json:"category_breakdown"`
+	TagBreakdown      []TagSpending           `json:"tag_breakdown"`
+}
+
+const largestExpensesLimit = 5
+
+// GetGroupDashboard returns spending analytics for a group
+func GetGroupDashboard(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	groupID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid group id"})
+		return
+	}
+
+	isMember, err := helpers.IsGroupMember(c.Request.Context(), db, groupID, userID)
+	if err != nil || !isMember {
+		c.JSON(403, gin.H{"error": "not a member of the group"})
+		return
+	}
+
+	dashboard, err := Group(c.Request.Context(), db, groupID)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to build dashboard"})
+		return
+	}
+
+	c.JSON(200, dashboard)
+}
+
+// Group computes a group's spending analytics. The caller checks the user
+// may see the group.
+func Group(ctx context.Context, db *db.DB, groupID uuid.UUID) (GroupDashboard, error) {
+	dashboard := GroupDashboard{GroupID: groupID}
+
+	var err error
+	dashboard.Currency, err = helpers.GetGroupCurrency(ctx, db, groupID)
+	if err != nil {
+		return GroupDashboard{}, fmt.Errorf("failed to get group currency: %w", err)
+	}
+
+	err = db.Pool.QueryRow(ctx,
+		`SELECT COALESCE(SUM(total_amount), 0), COUNT(*) FROM expenses WHERE group_id = $1 AND deleted_at IS NULL AND status = 'approved'`,
+		groupID).Scan(&dashboard.TotalSpent, &dashboard.ExpenseCount)
+	if err != nil {
+		return GroupDashboard{}, fmt.Errorf("failed to calculate total spent: %w", err)
+	}
+
+	// Paid and consumed per member, including members with no activity
+	memberRows, err := db.Pool.Query(ctx,
+		`SELECT gm.user_id,
+		        COALESCE((SELECT SUM(ep.amount) FROM expense_payers ep JOIN expenses e ON ep.expense_id = e.id
+		                  WHERE e.group_id = gm.group_id AND ep.user_id = gm.user_id AND e.deleted_at IS NULL AND e.status = 'approved'), 0),
+		        COALESCE((SELECT SUM(es.amount) FROM expense_splits es JOIN expenses e ON es.expense_id = e.id
+		                  WHERE e.group_id = gm.group_id AND es.user_id = gm.user_id AND e.deleted_at IS NULL AND e.status = 'approved'), 0)
+		 FROM group_members gm
+		 WHERE gm.group_id = $1
+		 ORDER BY gm.joined_at`,
+		groupID)
+	if err != nil {
+		return GroupDashboard{}, fmt.Errorf("failed to get member spending: %w", err)
+	}
+	defer memberRows.Close()
+
+	dashboard.MemberSpending = []MemberSpending{}
+	for memberRows.Next() {
+		var ms MemberSpending
+		if err := memberRows.Scan(&ms.UserID, &ms.Paid, &ms.Consumed); err != nil {
+			return GroupDashboard{}, fmt.Errorf("failed to scan member spending: %w", err)
+		}
+		dashboard.MemberSpending = append(dashboard.MemberSpending, ms)
+	}
+
+	largestRows, err := db.Pool.Query(ctx,
+		`SELECT id, description, total_amount, paid_by, expense_date
+		 FROM expenses
+		 WHERE group_id = $1 AND deleted_at IS NULL AND status = 'approved'
+		 ORDER BY total_amount DESC, expense_date DESC
+		 LIMIT $2`,
+		groupID, largestExpensesLimit)
+	if err != nil {
+		return GroupDashboard{}, fmt.Errorf("failed to get largest expenses: %w", err)
+	}
+	defer largestRows.Close()
+
+	dashboard.LargestExpenses = []LargestExpense{}
+	for largestRows.Next() {
+		var le LargestExpense
+		if err := largestRows.Scan(&le.ID, &le.Description, &le.TotalAmount, &le.PaidBy, &le.ExpenseDate); err != nil {
+			return GroupDashboard{}, fmt.Errorf("failed to scan largest expense: %w", err)
+		}
+		dashboard.LargestExpenses = append(dashboard.LargestExpenses, le)
+	}
+
+	monthRows, err := db.Pool.Query(ctx,
+		`SELECT EXTRACT(MONTH FROM expense_date)::int, EXTRACT(YEAR FROM expense_date)::int, SUM(total_amount), COUNT(*)
+		 FROM expenses
+		 WHERE group_id = $1 AND deleted_at IS NULL AND status = 'approved'
+		 GROUP BY 1, 2
+		 ORDER BY 2 DESC, 1 DESC`,
+		groupID)
+	if err != nil {
+		return GroupDashboard{}, fmt.Errorf("failed to get monthly spending: %w", err)
+	}
+	defer monthRows.Close()
+
+	dashboard.MonthlySpending = []MonthlySpending{}
+	for monthRows.Next() {
+		var ms MonthlySpending
+		if err := monthRows.Scan(&ms.Month, &ms.Year, &ms.TotalAmount, &ms.ExpenseCount); err != nil {
+			return GroupDashboard{}, fmt.Errorf("failed to scan monthly spending: %w", err)
+		}
+		dashboard.MonthlySpending = append(dashboard.MonthlySpending, ms)
+	}
+
+	categoryRows, err := db.Pool.Query(ctx,
+		`SELECT category, SUM(total_amount), COUNT(*)
+		 FROM expenses
+		 WHERE group_id = $1 AND deleted_at IS NULL AND status = 'approved'
+		 GROUP BY category
+		 ORDER BY SUM(total_amount) DESC`,
+		groupID)
+	if err != nil {
+		return GroupDashboard{}, fmt.Errorf("failed to get category breakdown: %w", err)
+	}
+	defer categoryRows.Close()
+
+	dashboard.CategoryBreakdown = []GroupCategorySpending{}
+	for categoryRows.Next() {
+		var cs GroupCategorySpending
+		if err := categoryRows.Scan(&cs.Category, &cs.TotalAmount, &cs.ExpenseCount); err != nil {
+			return GroupDashboard{}, fmt.Errorf("failed to scan category breakdown: %w", err)
+		}
+		dashboard.CategoryBreakdown = append(dashboard.CategoryBreakdown, cs)
+	}
+
+	// An expense with several tags counts toward each of them
+	tagRows, err := db.Pool.Query(ctx,
+		`SELECT t.name, SUM(e.total_amount), COUNT(*)
+		 FROM tags t
+		 JOIN expense_tags et ON et.tag_id = t.id
+		 JOIN expenses e ON e.id = et.expense_id
+		 WHERE t.group_id = $1 AND e.deleted_at IS NULL AND e.status = 'approved'
+		 GROUP BY t.name
+		 ORDER BY SUM(e.total_amount) DESC, t.name`,
+		groupID)
+	if err != nil {
+		return GroupDashboard{}, fmt.Errorf("failed to get tag breakdown: %w", err)
+	}
+	defer tagRows.Close()
+
+	dashboard.TagBreakdown = []TagSpending{}
+	for tagRows.Next() {
+		var ts TagSpending
+		if err := tagRows.Scan(&ts.Tag, &ts.TotalAmount, &ts.ExpenseCount); err != nil {
+			return GroupDashboard{}, fmt.Errorf("failed to scan tag breakdown: %w", err)
+		}
+		dashboard.TagBreakdown = append(dashboard.TagBreakdown, ts)
+	}
+
+	return dashboard, nil
+}
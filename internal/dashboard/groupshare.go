@@ -0,0 +1,59 @@
+package dashboard
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+)
+
+const (
+	sourcePersonal = "personal"
+	sourceGroup    = "group"
+)
+
+// addGroupShares adds the user's shares of approved group expenses in the
+// summary's currency to its totals and breakdown. Shares already mirrored
+// into personal expenses are counted there and skipped here.
+func addGroupShares(ctx context.Context, db *db.DB, summary *Summary, userID uuid.UUID, start, endExclusive time.Time) error {
+	rows, err := db.Pool.Query(ctx,
+		`SELECT e.category, SUM(es.amount), COUNT(*)
+		 FROM expense_splits es
+		 JOIN expenses e ON e.id = es.expense_id
+		 JOIN groups g ON g.id = e.group_id AND g.deleted_at IS NULL
+		 WHERE es.user_id = $1 AND es.amount > 0 AND e.deleted_at IS NULL AND e.status = 'approved'
+		   AND e.expense_date >= $2 AND e.expense_date < $3 AND e.currency = $4
+		   AND NOT EXISTS (SELECT 1 FROM personal_expenses pe WHERE pe.user_id = $1 AND pe.group_expense_id = e.id)
+		 GROUP BY e.category`,
+		userID, start, endExclusive, summary.Currency)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	bySource := SourceSpending{Personal: summary.TotalSpent, Group: decimal.Zero}
+	for rows.Next() {
+		cs := CategorySpending{Source: sourceGroup}
+		if err := rows.Scan(&cs.CategoryName, &cs.TotalAmount, &cs.ExpenseCount); err != nil {
+			return err
+		}
+		bySource.Group = bySource.Group.Add(cs.TotalAmount)
+		bySource.GroupExpenseCount += cs.ExpenseCount
+		summary.CategoryBreakdown = append(summary.CategoryBreakdown, cs)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	summary.TotalSpent = summary.TotalSpent.Add(bySource.Group)
+	summary.ExpenseCount += bySource.GroupExpenseCount
+	summary.BySource = &bySource
+	sort.SliceStable(summary.CategoryBreakdown, func(i, j int) bool {
+		return summary.CategoryBreakdown[i].TotalAmount.GreaterThan(summary.CategoryBreakdown[j].TotalAmount)
+	})
+	return nil
+}
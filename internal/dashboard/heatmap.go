@@ -0,0 +1,93 @@
+package dashboard
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+	"github.com/yanonymousV2/finance-manager-backend/internal/helpers"
+	"github.com/yanonymousV2/finance-manager-backend/internal/middleware"
+)
+
+type DaySpending struct {
+	Date         string          `json:"date"`
+	TotalAmount  decimal.Decimal `json:"total_amount"`
+	ExpenseCount int             `json:"expense_count"`
+}
+
+// yearDays returns every day of the year, with nothing spent
+func yearDays(year int) []DaySpending {
+	start := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	var days []DaySpending
+	for d := start; d.Year() == year; d = d.AddDate(0, 0, 1) {
+		days = append(days, DaySpending{Date: d.Format("2006-01-02"), TotalAmount: decimal.Zero})
+	}
+	return days
+}
+
+// GetSpendingHeatmap returns the spending on every day of a year (default the
+// current one), for a contributions-style calendar
+func GetSpendingHeatmap(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	year := time.Now().Year()
+	if yearStr := c.Query("year"); yearStr != "" {
+		if _, err := fmt.Sscanf(yearStr, "%d", &year); err != nil || year < 2000 || year > 2100 {
+			c.JSON(400, gin.H{"error": "invalid year"})
+			return
+		}
+	}
+
+	currency, err := helpers.GetUserCurrency(c.Request.Context(), db, userID)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to get default currency"})
+		return
+	}
+
+	startDate := time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC)
+	rows, err := db.Pool.Query(c.Request.Context(),
+		`SELECT (expense_date AT TIME ZONE 'UTC')::date AS day, SUM(amount), COUNT(*)
+		 FROM personal_expenses
+		 WHERE user_id = $1 AND expense_date >= $2 AND expense_date < $3 AND currency = $4
+		 GROUP BY day`,
+		userID, startDate, startDate.AddDate(1, 0, 0), currency)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to get spending heatmap"})
+		return
+	}
+	defer rows.Close()
+
+	days := yearDays(year)
+	maxAmount := decimal.Zero
+	for rows.Next() {
+		var day time.Time
+		var total decimal.Decimal
+		var count int
+		if err := rows.Scan(&day, &total, &count); err != nil {
+			c.JSON(500, gin.H{"error": "failed to scan spending heatmap"})
+			return
+		}
+		i := day.YearDay() - 1
+		if i < 0 || i >= len(days) {
+			continue
+		}
+		days[i].TotalAmount, days[i].ExpenseCount = total, count
+		if total.GreaterThan(maxAmount) {
+			maxAmount = total
+		}
+	}
+
+	c.JSON(200, gin.H{
+		"year":       year,
+		"currency":   currency,
+		"max_amount": maxAmount,
+		"days":       days,
+	})
+}
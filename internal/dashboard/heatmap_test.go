@@ -0,0 +1,18 @@
+package dashboard
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestYearDays(t *testing.T) {
+	days := yearDays(2026)
+	require.Len(t, days, 365)
+	assert.Equal(t, "2026-01-01", days[0].Date)
+	assert.Equal(t, "2026-12-31", days[364].Date)
+	assert.True(t, days[100].TotalAmount.IsZero())
+
+	assert.Len(t, yearDays(2028), 366)
+}
@@ -0,0 +1,120 @@
+package dashboard
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+	"github.com/yanonymousV2/finance-manager-backend/internal/helpers"
+	"github.com/yanonymousV2/finance-manager-backend/internal/middleware"
+)
+
+// MerchantSpending is what was spent at a merchant in a month. ExpensesURL
+// lists the expenses behind it.
+type MerchantSpending struct {
+	MerchantID   uuid.UUID       `json:"merchant_id"`
+	MerchantName string          `json:"merchant_name"`
+	TotalAmount  decimal.Decimal `json:"total_amount"`
+	VisitCount   int             `json:"visit_count"`
+	LastVisit    time.Time       `json:"last_visit"`
+	ExpensesURL  string          `json:"expenses_url"`
+}
+
+// expensesURL links to the personal expenses at a merchant from start to end
+// (both inclusive)
+func expensesURL(merchantID uuid.UUID, start, end time.Time) string {
+	query := url.Values{}
+	query.Set("merchant_id", merchantID.String())
+	query.Set("start_date", start.Format("2006-01-02"))
+	query.Set("end_date", end.Format("2006-01-02"))
+	return "/personal-expenses?" + query.Encode()
+}
+
+// GetTopMerchants ranks the merchants the user spent the most at in a month
+// (default the current one). Expenses without a normalized merchant are left
+// out.
+func GetTopMerchants(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	now := time.Now()
+	month := int(now.Month())
+	year := now.Year()
+
+	if monthStr := c.Query("month"); monthStr != "" {
+		if _, err := fmt.Sscanf(monthStr, "%d", &month); err != nil || month < 1 || month > 12 {
+			c.JSON(400, gin.H{"error": "invalid month"})
+			return
+		}
+	}
+	if yearStr := c.Query("year"); yearStr != "" {
+		if _, err := fmt.Sscanf(yearStr, "%d", &year); err != nil || year < 2000 || year > 2100 {
+			c.JSON(400, gin.H{"error": "invalid year"})
+			return
+		}
+	}
+
+	limit := 10
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 50 {
+			limit = l
+		}
+	}
+
+	// by=visits ranks by visit count instead of total spend
+	orderBy := "SUM(pe.amount) DESC, COUNT(*) DESC"
+	if c.Query("by") == "visits" {
+		orderBy = "COUNT(*) DESC, SUM(pe.amount) DESC"
+	}
+
+	startDate := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+	endDate := startDate.AddDate(0, 1, 0)
+
+	currency, err := helpers.GetUserCurrency(c.Request.Context(), db, userID)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to get default currency"})
+		return
+	}
+
+	rows, err := db.Pool.Query(c.Request.Context(),
+		`SELECT m.id, m.name, SUM(pe.amount), COUNT(*), MAX(pe.expense_date)
+		 FROM personal_expenses pe
+		 JOIN merchants m ON pe.merchant_id = m.id
+		 WHERE pe.user_id = $1 AND pe.expense_date >= $2 AND pe.expense_date < $3 AND pe.currency = $4
+		 GROUP BY m.id, m.name
+		 ORDER BY `+orderBy+`, m.name
+		 LIMIT $5`,
+		userID, startDate, endDate, currency, limit)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to get top merchants"})
+		return
+	}
+	defer rows.Close()
+
+	merchants := []MerchantSpending{}
+	for rows.Next() {
+		var ms MerchantSpending
+		if err := rows.Scan(&ms.MerchantID, &ms.MerchantName, &ms.TotalAmount, &ms.VisitCount, &ms.LastVisit); err != nil {
+			c.JSON(500, gin.H{"error": "failed to scan top merchants"})
+			return
+		}
+		ms.ExpensesURL = expensesURL(ms.MerchantID, startDate, endDate.AddDate(0, 0, -1))
+		merchants = append(merchants, ms)
+	}
+
+	c.JSON(200, gin.H{
+		"month":     month,
+		"year":      year,
+		"currency":  currency,
+		"merchants": merchants,
+	})
+}
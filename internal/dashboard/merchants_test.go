@@ -0,0 +1,18 @@
+package dashboard
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpensesURL(t *testing.T) {
+	merchantID := uuid.MustParse("d50e8400-e29b-41d4-a716-446655440000")
+	start := time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+
+	assert.Equal(t,
+		"/personal-expenses?end_date=2026-02-28&merchant_id=d50e8400-e29b-41d4-a716-446655440000&start_date=2026-02-01",
+		expensesURL(merchantID, start, start.AddDate(0, 1, -1)))
+}
@@ -0,0 +1,300 @@
+package dashboard
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+	"github.com/yanonymousV2/finance-manager-backend/internal/middleware"
+	"github.com/yanonymousV2/finance-manager-backend/internal/pdf"
+)
+
+// Report layout, in points
+const (
+	reportMargin      = 50.0
+	reportLabelWidth  = 160.0
+	reportRowHeight   = 18.0
+	reportBarHeight   = 10.0
+	reportChartHeight = 140.0
+)
+
+var (
+	reportText   = pdf.Color{R: 33, G: 33, B: 33}
+	reportMuted  = pdf.Color{R: 117, G: 117, B: 117}
+	reportRule   = pdf.Color{R: 224, G: 224, B: 224}
+	reportBar    = pdf.Color{R: 66, G: 133, B: 244}
+	reportOver   = pdf.Color{R: 219, G: 68, B: 55}
+	reportRowAlt = pdf.Color{R: 245, G: 245, B: 245}
+)
+
+// dailySpending returns the personal spending on each of the days from start
+func dailySpending(ctx context.Context, db *db.DB, userID uuid.UUID, currency string, start time.Time, days int) ([]decimal.Decimal, error) {
+	totals := make([]decimal.Decimal, days)
+	for i := range totals {
+		totals[i] = decimal.Zero
+	}
+
+	rows, err := db.Pool.Query(ctx,
+		`SELECT (expense_date AT TIME ZONE 'UTC')::date AS day, SUM(amount)
+		 FROM personal_expenses
+		 WHERE user_id = $1 AND expense_date >= $2 AND expense_date < $3 AND currency = $4
+		 GROUP BY day`,
+		userID, start, start.AddDate(0, 0, days), currency)
+	if err != nil {
+		return nil, fmt.Errorf("get daily spending: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var day time.Time
+		var amount decimal.Decimal
+		if err := rows.Scan(&day, &amount); err != nil {
+			return nil, fmt.Errorf("scan daily spending: %w", err)
+		}
+		if i := int(day.Sub(start).Hours() / 24); i >= 0 && i < days {
+			totals[i] = amount
+		}
+	}
+	return totals, rows.Err()
+}
+
+// reportWriter lays out a report top to bottom, starting a new page when
+// the next block doesn't fit
+type reportWriter struct {
+	doc *pdf.Document
+	y   float64
+}
+
+func (w *reportWriter) need(height float64) {
+	if w.doc.PageCount() == 0 || w.y+height > pdf.PageHeight-reportMargin {
+		w.doc.AddPage()
+		w.y = reportMargin
+	}
+}
+
+func (w *reportWriter) heading(s string) {
+	w.need(40)
+	w.y += 24
+	w.doc.Text(reportMargin, w.y, 13, true, reportText, s)
+	w.y += 8
+	w.doc.Line(reportMargin, w.y, pdf.PageWidth-reportMargin, w.y, 0.5, reportRule)
+	w.y += 6
+}
+
+// row draws a label with values right-aligned at the given x positions
+func (w *reportWriter) row(label string, shade bool, values []string, at []float64) {
+	w.need(reportRowHeight)
+	if shade {
+		w.doc.Rect(reportMargin, w.y, pdf.PageWidth-2*reportMargin, reportRowHeight, reportRowAlt)
+	}
+	baseline := w.y + 12.5
+	w.doc.Text(reportMargin+6, baseline, 10, false, reportText, label)
+	for i, v := range values {
+		w.doc.TextRight(at[i], baseline, 10, false, reportText, v)
+	}
+	w.y += reportRowHeight
+}
+
+func categoryLabel(cs CategorySpending) string {
+	label := "Uncategorized"
+	if cs.CategoryName != nil {
+		label = *cs.CategoryName
+	}
+	suffix := ""
+	if cs.Source == sourceGroup {
+		suffix = " (group)"
+	}
+	// Long names are cut short so they don't run into the bar chart
+	if pdf.TextWidth(label+suffix, 10, false) > reportLabelWidth {
+		runes := []rune(label)
+		for len(runes) > 0 && pdf.TextWidth(string(runes)+"..."+suffix, 10, false) > reportLabelWidth {
+			runes = runes[:len(runes)-1]
+		}
+		label = string(runes) + "..."
+	}
+	return label + suffix
+}
+
+// renderMonthlyReport draws the monthly dashboard as a printable statement:
+// a summary table, spending per category as a table and bar chart, and a
+// chart of personal spending per day
+func renderMonthlyReport(d MonthlyDashboard, daily []decimal.Decimal, generated time.Time) *pdf.Document {
+	w := &reportWriter{doc: pdf.New()}
+	right := pdf.PageWidth - reportMargin
+	money := func(amount decimal.Decimal) string {
+		return amount.StringFixed(2) + " " + d.Currency
+	}
+	month := time.Date(d.Year, time.Month(d.Month), 1, 0, 0, 0, 0, time.UTC)
+
+	w.need(60)
+	w.doc.Text(reportMargin, w.y+18, 20, true, reportText, "Monthly Statement")
+	w.doc.Text(reportMargin, w.y+38, 11, false, reportMuted,
+		month.Format("January 2006")+" - generated "+generated.UTC().Format("2 Jan 2006 15:04 UTC"))
+	w.y += 44
+
+	// Summary
+	w.heading("Summary")
+	type line struct {
+		label string
+		value string
+	}
+	lines := []line{}
+	if d.Budget != nil {
+		lines = append(lines, line{"Budget", money(*d.Budget)})
+		if !d.CarriedOver.IsZero() {
+			lines = append(lines, line{"Carried over", money(*d.CarriedOver)}, line{"Effective budget", money(*d.EffectiveBudget)})
+		}
+	}
+	lines = append(lines, line{"Total spent", money(d.TotalSpent)})
+	if d.BySource != nil {
+		lines = append(lines, line{"  Personal", money(d.BySource.Personal)}, line{"  Group shares", money(d.BySource.Group)})
+	}
+	if d.RemainingBudget != nil {
+		if d.IsOverBudget {
+			lines = append(lines, line{"Over budget by", money(d.RemainingBudget.Neg())})
+		} else {
+			lines = append(lines, line{"Remaining", money(*d.RemainingBudget)})
+		}
+	}
+	lines = append(lines,
+		line{"Expenses", strconv.Itoa(d.ExpenseCount)},
+		line{"Daily average", money(d.DailyAverageSpent)},
+	)
+	if d.ProjectedSpending != nil && d.DaysRemaining > 0 {
+		lines = append(lines, line{"Projected for the month", money(*d.ProjectedSpending)})
+	}
+	for i, l := range lines {
+		w.row(l.label, i%2 == 1, []string{l.value}, []float64{right - 6})
+	}
+
+	// Budget used, as a bar
+	if d.EffectiveBudget != nil && d.EffectiveBudget.IsPositive() {
+		w.need(30)
+		w.y += 12
+		width := right - reportMargin
+		used := d.TotalSpent.Div(*d.EffectiveBudget).InexactFloat64()
+		color := reportBar
+		if used > 1 {
+			used, color = 1, reportOver
+		}
+		w.doc.Rect(reportMargin, w.y, width, reportBarHeight, reportRule)
+		w.doc.Rect(reportMargin, w.y, width*used, reportBarHeight, color)
+		w.y += reportBarHeight + 4
+		w.doc.Text(reportMargin, w.y+9, 9, false, reportMuted,
+			d.TotalSpent.Div(*d.EffectiveBudget).Mul(decimal.NewFromInt(100)).Round(0).String()+"% of budget used")
+		w.y += 12
+	}
+
+	// Spending by category
+	w.heading("Spending by Category")
+	if len(d.CategoryBreakdown) == 0 {
+		w.need(reportRowHeight)
+		w.doc.Text(reportMargin+6, w.y+12.5, 10, false, reportMuted, "No spending this month")
+		w.y += reportRowHeight
+	}
+	largest := decimal.Zero
+	for _, cs := range d.CategoryBreakdown {
+		if cs.TotalAmount.GreaterThan(largest) {
+			largest = cs.TotalAmount
+		}
+	}
+	barStart, barWidth := reportMargin+reportLabelWidth+10, 150.0
+	for i, cs := range d.CategoryBreakdown {
+		share := ""
+		if d.TotalSpent.IsPositive() {
+			share = cs.TotalAmount.Div(d.TotalSpent).Mul(decimal.NewFromInt(100)).Round(1).String() + "%"
+		}
+		w.row(categoryLabel(cs), i%2 == 1,
+			[]string{strconv.Itoa(cs.ExpenseCount), money(cs.TotalAmount), share},
+			[]float64{barStart + barWidth + 35, right - 55, right - 6})
+		if largest.IsPositive() {
+			length := cs.TotalAmount.Div(largest).InexactFloat64() * barWidth
+			w.doc.Rect(barStart, w.y-reportRowHeight+4, length, reportBarHeight, reportBar)
+		}
+	}
+
+	// Daily spending chart
+	if len(daily) > 0 {
+		w.heading("Daily Spending (personal expenses)")
+		w.need(reportChartHeight + 30)
+		top, bottom := w.y+10, w.y+10+reportChartHeight
+		width := right - reportMargin - 40
+		step := width / float64(len(daily))
+		chartLeft := reportMargin + 40
+
+		peak := decimal.Zero
+		for _, amount := range daily {
+			if amount.GreaterThan(peak) {
+				peak = amount
+			}
+		}
+		w.doc.Line(chartLeft, bottom, right, bottom, 0.5, reportMuted)
+		w.doc.TextRight(chartLeft-4, bottom, 8, false, reportMuted, "0")
+		if peak.IsPositive() {
+			w.doc.Line(chartLeft, top, right, top, 0.5, reportRule)
+			w.doc.TextRight(chartLeft-4, top+3, 8, false, reportMuted, peak.StringFixed(2))
+		}
+		for i, amount := range daily {
+			x := chartLeft + float64(i)*step
+			if peak.IsPositive() && amount.IsPositive() {
+				height := amount.Div(peak).InexactFloat64() * reportChartHeight
+				w.doc.Rect(x+step*0.15, bottom-height, step*0.7, height, reportBar)
+			}
+			// Label the first day and every fifth after it
+			if i == 0 || (i+1)%5 == 0 {
+				label := strconv.Itoa(i + 1)
+				w.doc.Text(x+step/2-pdf.TextWidth(label, 8, false)/2, bottom+12, 8, false, reportMuted, label)
+			}
+		}
+		w.y = bottom + 20
+	}
+
+	return w.doc
+}
+
+// GetMonthlyReportPDF renders the monthly dashboard for a month (default the
+// current one) as a PDF statement. It takes the same options as the
+// monthly dashboard.
+func GetMonthlyReportPDF(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	now := time.Now()
+	month, year, ok := parseMonth(c, now)
+	if !ok {
+		return
+	}
+
+	dashboard, err := monthlyDashboard(c.Request.Context(), db, userID, month, year, now, parseSummaryOptions(c))
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to build report"})
+		return
+	}
+
+	start := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+	daily, err := dailySpending(c.Request.Context(), db, userID, dashboard.Currency, start, dashboard.DaysInMonth)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to build report"})
+		return
+	}
+
+	filename := fmt.Sprintf("statement-%d-%02d.pdf", year, month)
+	var buf bytes.Buffer
+	if _, err := renderMonthlyReport(dashboard, daily, now).WriteTo(&buf); err != nil {
+		c.JSON(500, gin.H{"error": "failed to build report"})
+		return
+	}
+
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	c.Data(200, "application/pdf", buf.Bytes())
+}
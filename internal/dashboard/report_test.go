@@ -0,0 +1,59 @@
+package dashboard
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCategoryLabel(t *testing.T) {
+	name := "Groceries"
+	assert.Equal(t, "Groceries", categoryLabel(CategorySpending{CategoryName: &name, Source: sourcePersonal}))
+	assert.Equal(t, "Groceries (group)", categoryLabel(CategorySpending{CategoryName: &name, Source: sourceGroup}))
+	assert.Equal(t, "Uncategorized", categoryLabel(CategorySpending{Source: sourcePersonal}))
+
+	long := strings.Repeat("Subscriptions ", 5)
+	label := categoryLabel(CategorySpending{CategoryName: &long, Source: sourceGroup})
+	assert.True(t, strings.HasSuffix(label, "... (group)"))
+	assert.Less(t, len(label), len(long))
+}
+
+func TestRenderMonthlyReport(t *testing.T) {
+	d := decimal.RequireFromString
+	budget, carried, effective, remaining := d("500"), d("0"), d("500"), d("-20")
+	dashboard := MonthlyDashboard{
+		Month: 2, Year: 2024, DaysInMonth: 29,
+		Summary: Summary{
+			Currency: "USD", Budget: &budget, CarriedOver: &carried, EffectiveBudget: &effective,
+			TotalSpent: d("520"), RemainingBudget: &remaining, IsOverBudget: true,
+			DaysElapsed: 29, DailyAverageSpent: d("17.93"), ExpenseCount: 3,
+		},
+	}
+	for i := 0; i < 40; i++ {
+		name := "Category"
+		dashboard.CategoryBreakdown = append(dashboard.CategoryBreakdown,
+			CategorySpending{CategoryName: &name, TotalAmount: d("8.5"), ExpenseCount: 1, Source: sourcePersonal})
+	}
+	daily := make([]decimal.Decimal, 29)
+	for i := range daily {
+		daily[i] = decimal.NewFromInt(int64(i))
+	}
+
+	doc := renderMonthlyReport(dashboard, daily, time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC))
+	// Forty categories run onto a second page, with the chart after them
+	assert.Equal(t, 2, doc.PageCount())
+
+	var buf bytes.Buffer
+	_, err := doc.WriteTo(&buf)
+	require.NoError(t, err)
+	out := buf.String()
+	assert.Contains(t, out, "(February 2024 - generated 1 Mar 2024 12:00 UTC) Tj")
+	assert.Contains(t, out, "(Over budget by) Tj")
+	assert.Contains(t, out, "(104% of budget used) Tj")
+	assert.Contains(t, out, "(28.00) Tj")
+}
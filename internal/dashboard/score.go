@@ -0,0 +1,257 @@
+package dashboard
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/category"
+	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+	"github.com/yanonymousV2/finance-manager-backend/internal/middleware"
+)
+
+// Score factor weights. Factors that don't apply (no budget or limits, too
+// few days) are left out and the others count for more.
+const (
+	overspendWeight   = 50
+	consistencyWeight = 30
+	volatilityWeight  = 20
+)
+
+// volatilityMinDays is how many days of spending the volatility factor needs
+const volatilityMinDays = 7
+
+// ScoreFactor is one part of the budget score, from 0 to 100
+type ScoreFactor struct {
+	Name   string `json:"name"`
+	Score  int    `json:"score"`
+	Weight int    `json:"weight"`
+	Detail string `json:"detail"`
+}
+
+// BudgetScore rates how well a month's spending kept to the budget and
+// category limits, from 0 to 100. Score and Grade are nil when there is
+// nothing to rate yet.
+type BudgetScore struct {
+	Month               int                    `json:"month"`
+	Year                int                    `json:"year"`
+	Score               *int                   `json:"score"`
+	Grade               *string                `json:"grade"`
+	Factors             []ScoreFactor          `json:"factors"`
+	OverspentCategories []category.LimitStatus `json:"overspent_categories"`
+}
+
+type scoreInput struct {
+	// Budget is the month's effective budget, nil without one
+	Budget *decimal.Decimal
+	Spent  decimal.Decimal
+	Limits []category.LimitStatus
+	// Daily is the spending on each day of the month so far
+	Daily       []decimal.Decimal
+	DaysInMonth int
+}
+
+// limitScore is 100 within the limit, falling to 0 at 50% over it
+func limitScore(spent, limit decimal.Decimal) float64 {
+	over := spent.Div(limit).InexactFloat64() - 1
+	if over <= 0 {
+		return 100
+	}
+	return math.Max(0, 100-200*over)
+}
+
+func grade(score int) string {
+	switch {
+	case score >= 90:
+		return "A"
+	case score >= 80:
+		return "B"
+	case score >= 70:
+		return "C"
+	case score >= 60:
+		return "D"
+	default:
+		return "F"
+	}
+}
+
+// scoreMonth rates spending on three factors: overspending of the budget and
+// category limits, how many days spending stayed on or under the budget's
+// pace, and how much daily spending swings around its average
+func scoreMonth(in scoreInput) (int, []ScoreFactor) {
+	factors := []ScoreFactor{}
+
+	// Overspends: the monthly budget and each category limit score alike
+	total, count, exceeded := 0.0, 0, 0
+	if in.Budget != nil && in.Budget.IsPositive() {
+		total += limitScore(in.Spent, *in.Budget)
+		count++
+		if in.Spent.GreaterThan(*in.Budget) {
+			exceeded++
+		}
+	}
+	for _, l := range in.Limits {
+		total += limitScore(l.Spent, l.MonthlyLimit)
+		count++
+		if l.Exceeded {
+			exceeded++
+		}
+	}
+	if count > 0 {
+		factors = append(factors, ScoreFactor{
+			Name:   "overspend",
+			Score:  int(math.Round(total / float64(count))),
+			Weight: overspendWeight,
+			Detail: fmt.Sprintf("%d of %d limits exceeded", exceeded, count),
+		})
+	}
+
+	// Consistency: days the running total stayed within the budget's pace
+	if in.Budget != nil && in.Budget.IsPositive() && len(in.Daily) > 0 {
+		daily := in.Budget.InexactFloat64() / float64(in.DaysInMonth)
+		running, onPace := 0.0, 0
+		for i, amount := range in.Daily {
+			running += amount.InexactFloat64()
+			if running <= daily*float64(i+1)+0.005 {
+				onPace++
+			}
+		}
+		factors = append(factors, ScoreFactor{
+			Name:   "consistency",
+			Score:  int(math.Round(100 * float64(onPace) / float64(len(in.Daily)))),
+			Weight: consistencyWeight,
+			Detail: fmt.Sprintf("on or under budget pace on %d of %d days", onPace, len(in.Daily)),
+		})
+	}
+
+	// Volatility: the coefficient of variation of daily spending. Up to 1 is
+	// normal and scores full marks; 4 or more scores nothing.
+	if len(in.Daily) >= volatilityMinDays {
+		mean := 0.0
+		for _, amount := range in.Daily {
+			mean += amount.InexactFloat64()
+		}
+		mean /= float64(len(in.Daily))
+		if mean > 0 {
+			variance := 0.0
+			for _, amount := range in.Daily {
+				d := amount.InexactFloat64() - mean
+				variance += d * d
+			}
+			cv := math.Sqrt(variance/float64(len(in.Daily))) / mean
+			factors = append(factors, ScoreFactor{
+				Name:   "volatility",
+				Score:  int(math.Round(100 * math.Min(1, math.Max(0, (4-cv)/3)))),
+				Weight: volatilityWeight,
+				Detail: fmt.Sprintf("daily spending varies by %.1fx its average", cv),
+			})
+		}
+	}
+
+	weighted, weights := 0, 0
+	for _, f := range factors {
+		weighted += f.Score * f.Weight
+		weights += f.Weight
+	}
+	if weights == 0 {
+		return 0, factors
+	}
+	return int(math.Round(float64(weighted) / float64(weights))), factors
+}
+
+// categoryLimits returns the month's spending against every category limit
+// the user has set
+func categoryLimits(ctx context.Context, db *db.DB, userID uuid.UUID, month time.Time, currency string) ([]category.LimitStatus, error) {
+	rows, err := db.Pool.Query(ctx,
+		"SELECT id FROM expense_categories WHERE user_id = $1 AND deleted_at IS NULL AND monthly_limit IS NOT NULL ORDER BY name", userID)
+	if err != nil {
+		return nil, err
+	}
+	var ids []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	limits := []category.LimitStatus{}
+	for _, id := range ids {
+		status, err := category.CheckLimit(ctx, db, id, month, currency)
+		if err != nil {
+			return nil, err
+		}
+		if status != nil {
+			limits = append(limits, *status)
+		}
+	}
+	return limits, nil
+}
+
+// GetBudgetScore rates a month (default the current one) from 0 to 100 on
+// how well spending kept to the budget and category limits
+func GetBudgetScore(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	now := time.Now()
+	month, year, ok := parseMonth(c, now)
+	if !ok {
+		return
+	}
+	start := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+
+	dashboard, err := monthlyDashboard(c.Request.Context(), db, userID, month, year, now, summaryOptions{})
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to build dashboard"})
+		return
+	}
+
+	limits, err := categoryLimits(c.Request.Context(), db, userID, start, dashboard.Currency)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to get category limits"})
+		return
+	}
+
+	daily, err := dailySpending(c.Request.Context(), db, userID, dashboard.Currency, start, dashboard.DaysElapsed)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to get daily spending"})
+		return
+	}
+
+	result := BudgetScore{Month: month, Year: year, OverspentCategories: []category.LimitStatus{}}
+	for _, l := range limits {
+		if l.Exceeded {
+			result.OverspentCategories = append(result.OverspentCategories, l)
+		}
+	}
+
+	score, factors := scoreMonth(scoreInput{
+		Budget:      dashboard.EffectiveBudget,
+		Spent:       dashboard.TotalSpent,
+		Limits:      limits,
+		Daily:       daily,
+		DaysInMonth: dashboard.DaysInMonth,
+	})
+	result.Factors = factors
+	if len(factors) > 0 {
+		g := grade(score)
+		result.Score, result.Grade = &score, &g
+	}
+
+	c.JSON(200, result)
+}
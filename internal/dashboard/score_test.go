@@ -0,0 +1,71 @@
+package dashboard
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/category"
+)
+
+func TestScoreMonth(t *testing.T) {
+	d := decimal.RequireFromString
+	days := func(amounts ...string) []decimal.Decimal {
+		out := make([]decimal.Decimal, len(amounts))
+		for i, a := range amounts {
+			out[i] = d(a)
+		}
+		return out
+	}
+
+	t.Run("steady and within budget", func(t *testing.T) {
+		budget := d("300")
+		score, factors := scoreMonth(scoreInput{
+			Budget: &budget, Spent: d("70"),
+			Daily:       days("10", "10", "10", "10", "10", "10", "10"),
+			DaysInMonth: 30,
+		})
+		assert.Equal(t, 100, score)
+		require.Len(t, factors, 3)
+		assert.Equal(t, "0 of 1 limits exceeded", factors[0].Detail)
+		assert.Equal(t, "on or under budget pace on 7 of 7 days", factors[1].Detail)
+		assert.Equal(t, "daily spending varies by 0.0x its average", factors[2].Detail)
+	})
+
+	t.Run("category overspent", func(t *testing.T) {
+		budget := d("300")
+		score, factors := scoreMonth(scoreInput{
+			Budget: &budget, Spent: d("280"),
+			Limits: []category.LimitStatus{
+				// 25% over scores 50
+				{MonthlyLimit: d("100"), Spent: d("125"), Exceeded: true},
+				{MonthlyLimit: d("50"), Spent: d("20")},
+			},
+			// Spent 280 on the first day, against a pace of 10 a day
+			Daily:       days("280", "0", "0", "0", "0", "0", "0"),
+			DaysInMonth: 30,
+		})
+		require.Len(t, factors, 3)
+		assert.Equal(t, 83, factors[0].Score)
+		assert.Equal(t, "1 of 3 limits exceeded", factors[0].Detail)
+		assert.Equal(t, 0, factors[1].Score)
+		// The coefficient of variation is sqrt(6) ~ 2.45
+		assert.Equal(t, 52, factors[2].Score)
+		assert.Equal(t, 52, score)
+	})
+
+	t.Run("no budget or limits", func(t *testing.T) {
+		score, factors := scoreMonth(scoreInput{Spent: d("30"), Daily: days("10", "20"), DaysInMonth: 31})
+		assert.Equal(t, 0, score)
+		assert.Empty(t, factors)
+	})
+}
+
+func TestGrade(t *testing.T) {
+	assert.Equal(t, "A", grade(90))
+	assert.Equal(t, "B", grade(89))
+	assert.Equal(t, "D", grade(60))
+	assert.Equal(t, "F", grade(59))
+}
@@ -0,0 +1,297 @@
+package dashboard
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+	"github.com/yanonymousV2/finance-manager-backend/internal/helpers"
+	"github.com/yanonymousV2/finance-manager-backend/internal/middleware"
+)
+
+const (
+	defaultTrendMonths = 12
+	maxTrendMonths     = 24
+)
+
+// CategoryTrend is a category's spending per period, aligned with
+// Trends.Periods
+type CategoryTrend struct {
+	CategoryID   *uuid.UUID        `json:"category_id"`
+	CategoryName *string           `json:"category_name"`
+	Total        decimal.Decimal   `json:"total"`
+	Totals       []decimal.Decimal `json:"totals"`
+}
+
+// Trends is spending per period, with every period in the range present
+// (zero when nothing was spent) so it can be charted as is
+type Trends struct {
+	Granularity string            `json:"granularity"`
+	Currency    string            `json:"currency"`
+	Periods     []time.Time       `json:"periods"`
+	Totals      []decimal.Decimal `json:"totals"`
+	Categories  []CategoryTrend   `json:"categories"`
+}
+
+// trendPeriods returns the start of every day, week (from Monday) or month
+// of the months calendar months up to and including the one containing now
+func trendPeriods(granularity string, months int, now time.Time) []time.Time {
+	end := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, 1, 0)
+	start := end.AddDate(0, -months, 0)
+
+	var periods []time.Time
+	switch granularity {
+	case "day":
+		for t := start; t.Before(end); t = t.AddDate(0, 0, 1) {
+			periods = append(periods, t)
+		}
+	case "week":
+		for t := start.AddDate(0, 0, -((int(start.Weekday()) + 6) % 7)); t.Before(end); t = t.AddDate(0, 0, 7) {
+			periods = append(periods, t)
+		}
+	case "month":
+		for t := start; t.Before(end); t = t.AddDate(0, 1, 0) {
+			periods = append(periods, t)
+		}
+	}
+	return periods
+}
+
+// GetSpendingTrends returns spending per day, week or month over the last
+// months calendar months, in total and per category
+func GetSpendingTrends(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	granularity := c.DefaultQuery("granularity", "month")
+	if granularity != "day" && granularity != "week" && granularity != "month" {
+		c.JSON(400, gin.H{"error": "granularity must be one of day, week, month"})
+		return
+	}
+
+	months := defaultTrendMonths
+	if monthsStr := c.Query("months"); monthsStr != "" {
+		parsed, err := strconv.Atoi(monthsStr)
+		if err != nil || parsed < 1 || parsed > maxTrendMonths {
+			c.JSON(400, gin.H{"error": "months must be between 1 and " + strconv.Itoa(maxTrendMonths)})
+			return
+		}
+		months = parsed
+	}
+
+	currency, err := helpers.GetUserCurrency(c.Request.Context(), db, userID)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to get default currency"})
+		return
+	}
+
+	now := time.Now()
+	periods := trendPeriods(granularity, months, now)
+	index := make(map[int64]int, len(periods))
+	for i, p := range periods {
+		index[p.Unix()] = i
+	}
+	end := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, 1, 0)
+
+	rows, err := db.Pool.Query(c.Request.Context(),
+		`SELECT date_trunc($2, pe.expense_date AT TIME ZONE 'UTC') AS bucket, ec.id, ec.name, SUM(pe.amount)
+		 FROM personal_expenses pe
+		 LEFT JOIN expense_categories ec ON pe.category_id = ec.id
+		 WHERE pe.user_id = $1 AND pe.currency = $3 AND pe.expense_date >= $4 AND pe.expense_date < $5
+		 GROUP BY bucket, ec.id, ec.name
+		 ORDER BY ec.name NULLS LAST`,
+		userID, granularity, currency, periods[0], end)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to get spending trends"})
+		return
+	}
+	defer rows.Close()
+
+	zeros := func() []decimal.Decimal {
+		totals := make([]decimal.Decimal, len(periods))
+		for i := range totals {
+			totals[i] = decimal.Zero
+		}
+		return totals
+	}
+
+	trends := Trends{Granularity: granularity, Currency: currency, Periods: periods, Totals: zeros(), Categories: []CategoryTrend{}}
+	// Uncategorized spending is kept under uuid.Nil
+	byCategory := map[uuid.UUID]int{}
+	for rows.Next() {
+		var bucket time.Time
+		var categoryID *uuid.UUID
+		var categoryName *string
+		var amount decimal.Decimal
+		if err := rows.Scan(&bucket, &categoryID, &categoryName, &amount); err != nil {
+			c.JSON(500, gin.H{"error": "failed to scan spending trends"})
+			return
+		}
+		i, ok := index[bucket.Unix()]
+		if !ok {
+			continue
+		}
+
+		key := uuid.Nil
+		if categoryID != nil {
+			key = *categoryID
+		}
+		at, ok := byCategory[key]
+		if !ok {
+			at = len(trends.Categories)
+			byCategory[key] = at
+			trends.Categories = append(trends.Categories, CategoryTrend{
+				CategoryID: categoryID, CategoryName: categoryName, Total: decimal.Zero, Totals: zeros(),
+			})
+		}
+
+		trends.Totals[i] = trends.Totals[i].Add(amount)
+		trends.Categories[at].Totals[i] = trends.Categories[at].Totals[i].Add(amount)
+		trends.Categories[at].Total = trends.Categories[at].Total.Add(amount)
+	}
+
+	c.JSON(200, trends)
+}
+
+// MonthStats is a category's spending in one month
+type MonthStats struct {
+	Month         time.Time       `json:"month"`
+	Total         decimal.Decimal `json:"total"`
+	Count         int             `json:"count"`
+	AverageAmount decimal.Decimal `json:"average_amount"`
+}
+
+// CategoryDetail is one category's spending per month, with every month in
+// the range present
+type CategoryDetail struct {
+	CategoryID   uuid.UUID       `json:"category_id"`
+	CategoryName string          `json:"category_name"`
+	Currency     string          `json:"currency"`
+	Total        decimal.Decimal `json:"total"`
+	Count        int             `json:"count"`
+	// MonthlyAverage is Total spread over every month in the range
+	MonthlyAverage decimal.Decimal `json:"monthly_average"`
+	AverageAmount  decimal.Decimal `json:"average_amount"`
+	Months         []MonthStats    `json:"months"`
+}
+
+// categoryMonths fills in a MonthStats for each of periods from the totals
+// and counts found, keyed by month start, and sums them up
+func categoryMonths(periods []time.Time, totals map[int64]decimal.Decimal, counts map[int64]int) ([]MonthStats, decimal.Decimal, int) {
+	months := make([]MonthStats, len(periods))
+	total, count := decimal.Zero, 0
+	for i, p := range periods {
+		m := MonthStats{Month: p, Total: decimal.Zero, AverageAmount: decimal.Zero}
+		if n := counts[p.Unix()]; n > 0 {
+			m.Total, m.Count = totals[p.Unix()], n
+			m.AverageAmount = m.Total.Div(decimal.NewFromInt(int64(n))).Round(2)
+		}
+		months[i] = m
+		total, count = total.Add(m.Total), count+m.Count
+	}
+	return months, total, count
+}
+
+// GetCategoryTrends returns a category's monthly totals, transaction counts
+// and average transaction size over the last months calendar months. With
+// rollup=true its subcategories' spending is included.
+func GetCategoryTrends(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	categoryID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid category id"})
+		return
+	}
+
+	months := defaultTrendMonths
+	if monthsStr := c.Query("months"); monthsStr != "" {
+		parsed, err := strconv.Atoi(monthsStr)
+		if err != nil || parsed < 1 || parsed > maxTrendMonths {
+			c.JSON(400, gin.H{"error": "months must be between 1 and " + strconv.Itoa(maxTrendMonths)})
+			return
+		}
+		months = parsed
+	}
+
+	detail := CategoryDetail{CategoryID: categoryID}
+	err = db.Pool.QueryRow(c.Request.Context(),
+		`SELECT name FROM expense_categories WHERE id = $1 AND user_id = $2 AND deleted_at IS NULL`,
+		categoryID, userID).Scan(&detail.CategoryName)
+	if err != nil {
+		c.JSON(404, gin.H{"error": "category not found"})
+		return
+	}
+
+	currency, err := helpers.GetUserCurrency(c.Request.Context(), db, userID)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to get default currency"})
+		return
+	}
+	detail.Currency = currency
+
+	now := time.Now()
+	periods := trendPeriods("month", months, now)
+	end := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, 1, 0)
+
+	query := `SELECT date_trunc('month', expense_date AT TIME ZONE 'UTC') AS bucket, SUM(amount), COUNT(*)
+		 FROM personal_expenses
+		 WHERE user_id = $1 AND category_id = $2 AND currency = $3 AND expense_date >= $4 AND expense_date < $5
+		 GROUP BY bucket`
+	if c.Query("rollup") == "true" {
+		query = `WITH RECURSIVE tree AS (
+		     SELECT id FROM expense_categories WHERE id = $2
+		     UNION ALL
+		     SELECT ec.id FROM expense_categories ec JOIN tree t ON ec.parent_id = t.id
+		 )
+		 SELECT date_trunc('month', expense_date AT TIME ZONE 'UTC') AS bucket, SUM(amount), COUNT(*)
+		 FROM personal_expenses
+		 WHERE user_id = $1 AND category_id IN (SELECT id FROM tree) AND currency = $3
+		   AND expense_date >= $4 AND expense_date < $5
+		 GROUP BY bucket`
+	}
+
+	rows, err := db.Pool.Query(c.Request.Context(), query, userID, categoryID, currency, periods[0], end)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to get category trends"})
+		return
+	}
+	defer rows.Close()
+
+	totals := map[int64]decimal.Decimal{}
+	counts := map[int64]int{}
+	for rows.Next() {
+		var bucket time.Time
+		var amount decimal.Decimal
+		var count int
+		if err := rows.Scan(&bucket, &amount, &count); err != nil {
+			c.JSON(500, gin.H{"error": "failed to scan category trends"})
+			return
+		}
+		totals[bucket.Unix()], counts[bucket.Unix()] = amount, count
+	}
+	if err := rows.Err(); err != nil {
+		c.JSON(500, gin.H{"error": "failed to get category trends"})
+		return
+	}
+
+	detail.Months, detail.Total, detail.Count = categoryMonths(periods, totals, counts)
+	detail.MonthlyAverage = detail.Total.Div(decimal.NewFromInt(int64(len(periods)))).Round(2)
+	detail.AverageAmount = decimal.Zero
+	if detail.Count > 0 {
+		detail.AverageAmount = detail.Total.Div(decimal.NewFromInt(int64(detail.Count))).Round(2)
+	}
+
+	c.JSON(200, detail)
+}
@@ -0,0 +1,48 @@
+package dashboard
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTrendPeriods(t *testing.T) {
+	now := time.Date(2026, 10, 15, 18, 30, 0, 0, time.UTC)
+
+	months := trendPeriods("month", 3, now)
+	require.Len(t, months, 3)
+	assert.Equal(t, time.Date(2026, 8, 1, 0, 0, 0, 0, time.UTC), months[0])
+	assert.Equal(t, time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC), months[2])
+
+	days := trendPeriods("day", 1, now)
+	require.Len(t, days, 31)
+	assert.Equal(t, time.Date(2026, 10, 31, 0, 0, 0, 0, time.UTC), days[30])
+
+	// October 2026 starts on a Thursday, so the first week starts in September
+	weeks := trendPeriods("week", 1, now)
+	require.Len(t, weeks, 5)
+	assert.Equal(t, time.Date(2026, 9, 28, 0, 0, 0, 0, time.UTC), weeks[0])
+	assert.Equal(t, time.Monday, weeks[4].Weekday())
+	assert.Equal(t, time.Date(2026, 10, 26, 0, 0, 0, 0, time.UTC), weeks[4])
+}
+
+func TestCategoryMonths(t *testing.T) {
+	periods := trendPeriods("month", 3, time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC))
+	aug, oct := periods[0].Unix(), periods[2].Unix()
+
+	months, total, count := categoryMonths(periods,
+		map[int64]decimal.Decimal{aug: decimal.RequireFromString("100"), oct: decimal.RequireFromString("50")},
+		map[int64]int{aug: 3, oct: 1})
+
+	require.Len(t, months, 3)
+	assert.Equal(t, "33.33", months[0].AverageAmount.String())
+	// Nothing in September
+	assert.True(t, months[1].Total.IsZero())
+	assert.Equal(t, 0, months[1].Count)
+	assert.True(t, months[1].AverageAmount.IsZero())
+	assert.Equal(t, "150", total.String())
+	assert.Equal(t, 4, count)
+}
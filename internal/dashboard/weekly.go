@@ -0,0 +1,223 @@
+package dashboard
+
+import (
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/budget"
+	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+	"github.com/yanonymousV2/finance-manager-backend/internal/helpers"
+	"github.com/yanonymousV2/finance-manager-backend/internal/middleware"
+)
+
+const (
+	weeklyTopCategories = 5
+	weeklyNotableLimit  = 5
+	// An expense is notable when it's at least notableFactor times the
+	// median expense over the notableHistoryDays days before the week
+	notableFactor      = 3
+	notableHistoryDays = 90
+)
+
+// NotableTransaction is an unusually large personal expense
+type NotableTransaction struct {
+	ID           uuid.UUID       `json:"id"`
+	Description  *string         `json:"description"`
+	Merchant     *string         `json:"merchant"`
+	CategoryName *string         `json:"category_name"`
+	Amount       decimal.Decimal `json:"amount"`
+	ExpenseDate  time.Time       `json:"expense_date"`
+}
+
+// WeeklyDashboard is a week's spending so far against the same days of the
+// week before, for a home screen
+type WeeklyDashboard struct {
+	WeekStart   time.Time `json:"week_start"`
+	WeekEnd     time.Time `json:"week_end"`
+	DaysElapsed int       `json:"days_elapsed"`
+	Currency    string    `json:"currency"`
+	// Total compares the week so far with last week up to the same weekday
+	Total         Change               `json:"total"`
+	LastWeekTotal decimal.Decimal      `json:"last_week_total"`
+	TopCategories []CategoryChange     `json:"top_categories"`
+	Notable       []NotableTransaction `json:"notable_transactions"`
+}
+
+// topCategories returns the n categories spent the most on this week
+func topCategories(categories []CategoryChange, n int) []CategoryChange {
+	top := []CategoryChange{}
+	for _, cc := range categories {
+		if cc.Current.IsPositive() {
+			top = append(top, cc)
+		}
+	}
+	sort.SliceStable(top, func(i, j int) bool { return top[i].Current.GreaterThan(top[j].Current) })
+	if len(top) > n {
+		top = top[:n]
+	}
+	return top
+}
+
+// notableTransactions picks the week's expenses that are at least
+// notableFactor times the median of history (sorted), biggest first. Without
+// any history the week's biggest expenses are notable.
+func notableTransactions(week []NotableTransaction, history []decimal.Decimal, n int) []NotableTransaction {
+	threshold := decimal.Zero
+	if len(history) > 0 {
+		mid := len(history) / 2
+		median := history[mid]
+		if len(history)%2 == 0 {
+			median = history[mid-1].Add(history[mid]).Div(decimal.NewFromInt(2))
+		}
+		threshold = median.Mul(decimal.NewFromInt(notableFactor))
+	}
+
+	notable := []NotableTransaction{}
+	for _, t := range week {
+		if t.Amount.IsPositive() && t.Amount.GreaterThanOrEqual(threshold) {
+			notable = append(notable, t)
+		}
+	}
+	sort.SliceStable(notable, func(i, j int) bool { return notable[i].Amount.GreaterThan(notable[j].Amount) })
+	if len(notable) > n {
+		notable = notable[:n]
+	}
+	return notable
+}
+
+// GetWeeklyDashboard summarizes the week (Monday to Sunday) containing a date
+// (default today): spending so far against last week, the top categories and
+// notable transactions
+func GetWeeklyDashboard(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	now := time.Now()
+	date := now
+	if dateStr := c.Query("date"); dateStr != "" {
+		parsed, err := time.Parse("2006-01-02", dateStr)
+		if err != nil {
+			c.JSON(400, gin.H{"error": "invalid date, use YYYY-MM-DD"})
+			return
+		}
+		date = parsed
+	}
+
+	weekStart, weekEnd, _ := budget.CalendarRange(budget.PeriodWeekly, date)
+	_, elapsed := periodDays(weekStart, weekEnd, now)
+	lastWeekStart := weekStart.AddDate(0, 0, -7)
+	// The same days of last week as have passed of this one
+	lastWeekToDate := lastWeekStart.AddDate(0, 0, elapsed)
+
+	currency, err := helpers.GetUserCurrency(c.Request.Context(), db, userID)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to get default currency"})
+		return
+	}
+
+	rows, err := db.Pool.Query(c.Request.Context(),
+		`SELECT ec.id, ec.name,
+		        COALESCE(SUM(pe.amount) FILTER (WHERE pe.expense_date >= $3), 0),
+		        COALESCE(SUM(pe.amount) FILTER (WHERE pe.expense_date < $4), 0),
+		        COALESCE(SUM(pe.amount) FILTER (WHERE pe.expense_date < $3), 0)
+		 FROM personal_expenses pe
+		 LEFT JOIN expense_categories ec ON pe.category_id = ec.id
+		 WHERE pe.user_id = $1 AND pe.currency = $2 AND pe.expense_date >= $5 AND pe.expense_date < $6
+		 GROUP BY ec.id, ec.name`,
+		userID, currency, weekStart, lastWeekToDate, lastWeekStart, weekEnd.AddDate(0, 0, 1))
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to get weekly spending"})
+		return
+	}
+	defer rows.Close()
+
+	var current, previous, lastWeekTotal decimal.Decimal
+	categories := []CategoryChange{}
+	for rows.Next() {
+		var cc CategoryChange
+		var thisWeek, toDate, lastWeek decimal.Decimal
+		if err := rows.Scan(&cc.CategoryID, &cc.CategoryName, &thisWeek, &toDate, &lastWeek); err != nil {
+			c.JSON(500, gin.H{"error": "failed to scan weekly spending"})
+			return
+		}
+		cc.Change = newChange(thisWeek, toDate)
+		current = current.Add(thisWeek)
+		previous = previous.Add(toDate)
+		lastWeekTotal = lastWeekTotal.Add(lastWeek)
+		categories = append(categories, cc)
+	}
+	if err := rows.Err(); err != nil {
+		c.JSON(500, gin.H{"error": "failed to get weekly spending"})
+		return
+	}
+
+	rows, err = db.Pool.Query(c.Request.Context(),
+		`SELECT pe.id, pe.description, pe.merchant, ec.name, pe.amount, pe.expense_date
+		 FROM personal_expenses pe
+		 LEFT JOIN expense_categories ec ON pe.category_id = ec.id
+		 WHERE pe.user_id = $1 AND pe.currency = $2 AND pe.expense_date >= $3 AND pe.expense_date < $4
+		 ORDER BY pe.expense_date`,
+		userID, currency, weekStart, weekEnd.AddDate(0, 0, 1))
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to get weekly expenses"})
+		return
+	}
+	defer rows.Close()
+
+	var week []NotableTransaction
+	for rows.Next() {
+		var t NotableTransaction
+		if err := rows.Scan(&t.ID, &t.Description, &t.Merchant, &t.CategoryName, &t.Amount, &t.ExpenseDate); err != nil {
+			c.JSON(500, gin.H{"error": "failed to scan weekly expenses"})
+			return
+		}
+		week = append(week, t)
+	}
+	if err := rows.Err(); err != nil {
+		c.JSON(500, gin.H{"error": "failed to get weekly expenses"})
+		return
+	}
+
+	rows, err = db.Pool.Query(c.Request.Context(),
+		`SELECT amount FROM personal_expenses
+		 WHERE user_id = $1 AND currency = $2 AND expense_date >= $3 AND expense_date < $4
+		 ORDER BY amount`,
+		userID, currency, weekStart.AddDate(0, 0, -notableHistoryDays), weekStart)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to get spending history"})
+		return
+	}
+	defer rows.Close()
+
+	var history []decimal.Decimal
+	for rows.Next() {
+		var amount decimal.Decimal
+		if err := rows.Scan(&amount); err != nil {
+			c.JSON(500, gin.H{"error": "failed to scan spending history"})
+			return
+		}
+		history = append(history, amount)
+	}
+	if err := rows.Err(); err != nil {
+		c.JSON(500, gin.H{"error": "failed to get spending history"})
+		return
+	}
+
+	c.JSON(200, WeeklyDashboard{
+		WeekStart:     weekStart,
+		WeekEnd:       weekEnd,
+		DaysElapsed:   elapsed,
+		Currency:      currency,
+		Total:         newChange(current, previous),
+		LastWeekTotal: lastWeekTotal,
+		TopCategories: topCategories(categories, weeklyTopCategories),
+		Notable:       notableTransactions(week, history, weeklyNotableLimit),
+	})
+}
@@ -0,0 +1,53 @@
+package dashboard
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTopCategories(t *testing.T) {
+	d := decimal.RequireFromString
+	food, travel, books := "Food", "Travel", "Books"
+	categories := []CategoryChange{
+		{CategoryName: &food, Change: newChange(d("40"), d("60"))},
+		// Only spent on last week
+		{CategoryName: &books, Change: newChange(d("0"), d("25"))},
+		{CategoryName: &travel, Change: newChange(d("120"), d("0"))},
+	}
+
+	top := topCategories(categories, 5)
+	require.Len(t, top, 2)
+	assert.Equal(t, "Travel", *top[0].CategoryName)
+	assert.Equal(t, "Food", *top[1].CategoryName)
+
+	assert.Len(t, topCategories(categories, 1), 1)
+}
+
+func TestNotableTransactions(t *testing.T) {
+	d := decimal.RequireFromString
+	week := []NotableTransaction{
+		{Amount: d("12")},
+		{Amount: d("45")},
+		{Amount: d("300")},
+		{Amount: d("30")},
+	}
+
+	t.Run("against the median", func(t *testing.T) {
+		// Median 10, so 30 and up is notable
+		notable := notableTransactions(week, []decimal.Decimal{d("5"), d("8"), d("12"), d("40")}, 5)
+		require.Len(t, notable, 3)
+		assert.Equal(t, "300", notable[0].Amount.String())
+		assert.Equal(t, "45", notable[1].Amount.String())
+		assert.Equal(t, "30", notable[2].Amount.String())
+	})
+
+	t.Run("no history", func(t *testing.T) {
+		notable := notableTransactions(week, nil, 2)
+		require.Len(t, notable, 2)
+		assert.Equal(t, "300", notable[0].Amount.String())
+		assert.Equal(t, "45", notable[1].Amount.String())
+	})
+}
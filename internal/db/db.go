@@ -3,37 +3,41 @@ package db
 import (
 	"context"
 	"fmt"
-	"log"
+	"strconv"
 	"time"
 
-	"github.com/golang-migrate/migrate/v4"
-	"github.com/golang-migrate/migrate/v4/database/postgres"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
 	"github.com/jackc/pgx/v5/pgxpool"
-	"github.com/jackc/pgx/v5/stdlib"
 )
 
 type DB struct {
 	Pool *pgxpool.Pool
-}
 
-func New(ctx context.Context, dbURL string) (*DB, error) {
-	// Configure connection pool
-	config, err := pgxpool.ParseConfig(dbURL)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse config: %w", err)
-	}
+	// replica serves Reader when configured and healthy
+	replica *replica
+}
 
-	// Set pool settings for better performance
-	config.MaxConns = 25                       // Maximum number of connections
-	config.MinConns = 5                        // Minimum number of connections
-	config.MaxConnLifetime = 1 * time.Hour     // Maximum connection lifetime
-	config.MaxConnIdleTime = 30 * time.Minute  // Maximum idle time
-	config.HealthCheckPeriod = 1 * time.Minute // Health check frequency
+// PoolOptions size the connection pool and bound how long queries run
+type PoolOptions struct {
+	MaxConns          int32
+	MinConns          int32
+	MaxConnLifetime   time.Duration
+	MaxConnIdleTime   time.Duration
+	HealthCheckPeriod time.Duration
+	// StatementTimeout is Postgres's statement_timeout on every connection,
+	// so a runaway query is stopped by the server; zero leaves it unset
+	StatementTimeout time.Duration
+	// QueryTimeout caps each query's context when the caller's deadline
+	// isn't sooner; zero leaves queries to their caller's context
+	QueryTimeout time.Duration
+	// SlowQuery is how long a query runs before it's logged at warn level
+	// even when it succeeds; zero turns this off
+	SlowQuery time.Duration
+}
 
-	pool, err := pgxpool.NewWithConfig(ctx, config)
+func New(ctx context.Context, dbURL string, opts PoolOptions) (*DB, error) {
+	pool, err := newPool(ctx, dbURL, opts, nil)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create pool: %w", err)
+		return nil, err
 	}
 
 	if err := pool.Ping(ctx); err != nil {
@@ -43,51 +47,38 @@ func New(ctx context.Context, dbURL string) (*DB, error) {
 	return &DB{Pool: pool}, nil
 }
 
-func (db *DB) Close() {
-	db.Pool.Close()
-}
-
-func RunMigrations(ctx context.Context, dbURL, migrationsPath string) error {
-	log.Println("  → Creating migration pool connection...")
-	// Create a sql.DB from pgx pool for migrate
-	pool, err := pgxpool.New(ctx, dbURL)
+// newPool configures a connection pool from opts, with extra runtime
+// parameters set on every connection
+func newPool(ctx context.Context, dbURL string, opts PoolOptions, params map[string]string) (*pgxpool.Pool, error) {
+	// Configure connection pool
+	config, err := pgxpool.ParseConfig(dbURL)
 	if err != nil {
-		return fmt.Errorf("failed to create pool for migration: %w", err)
+		return nil, fmt.Errorf("failed to parse config: %w", err)
 	}
-	log.Println("  ✓ Migration pool connected")
 
-	log.Println("  → Pinging database...")
-	if err := pool.Ping(ctx); err != nil {
-		return fmt.Errorf("failed to ping database: %w", err)
+	config.MaxConns = opts.MaxConns
+	config.MinConns = opts.MinConns
+	config.MaxConnLifetime = opts.MaxConnLifetime
+	config.MaxConnIdleTime = opts.MaxConnIdleTime
+	config.HealthCheckPeriod = opts.HealthCheckPeriod
+	if opts.StatementTimeout > 0 {
+		config.ConnConfig.RuntimeParams["statement_timeout"] = strconv.FormatInt(opts.StatementTimeout.Milliseconds(), 10)
 	}
-	log.Println("  ✓ Database ping successful")
-
-	sqlDB := stdlib.OpenDBFromPool(pool)
-
-	log.Println("  → Creating Postgres driver...")
-	driver, err := postgres.WithInstance(sqlDB, &postgres.Config{})
-	if err != nil {
-		return fmt.Errorf("failed to create postgres driver: %w", err)
+	for name, value := range params {
+		config.ConnConfig.RuntimeParams[name] = value
 	}
-	log.Println("  ✓ Postgres driver created")
+	config.ConnConfig.Tracer = queryTracer{timeout: opts.QueryTimeout, slowQuery: opts.SlowQuery}
 
-	log.Println("  → Initializing migration instance...")
-	m, err := migrate.NewWithDatabaseInstance(
-		fmt.Sprintf("file://%s", migrationsPath),
-		"postgres", driver)
+	pool, err := pgxpool.NewWithConfig(ctx, config)
 	if err != nil {
-		return fmt.Errorf("failed to create migrate instance: %w", err)
+		return nil, fmt.Errorf("failed to create pool: %w", err)
 	}
-	log.Println("  ✓ Migration instance initialized")
+	return pool, nil
+}
 
-	log.Println("  → Executing migrations...")
-	if err := m.Up(); err != nil && err != migrate.ErrNoChange {
-		return fmt.Errorf("failed to run migrations: %w", err)
+func (db *DB) Close() {
+	if db.replica != nil {
+		db.replica.close()
 	}
-	log.Println("  ✓ All migrations executed successfully")
-
-	// Note: Not closing the migration pool here to avoid deadlocks with the migrate library
-	// The pool will be garbage collected when the function returns
-
-	return nil
+	db.Pool.Close()
 }
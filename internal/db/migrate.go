@@ -0,0 +1,106 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+	_ "github.com/golang-migrate/migrate/v4/source/file"
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+// Migrator applies the migrations in a directory to a database
+type Migrator struct {
+	m *migrate.Migrate
+}
+
+func NewMigrator(ctx context.Context, dbURL, migrationsPath string) (*Migrator, error) {
+	slog.Debug("creating migration pool")
+	// Create a sql.DB from pgx pool for migrate
+	pool, err := pgxpool.New(ctx, dbURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create pool for migration: %w", err)
+	}
+
+	if err := pool.Ping(ctx); err != nil {
+		return nil, fmt.Errorf("failed to ping database: %w", err)
+	}
+
+	sqlDB := stdlib.OpenDBFromPool(pool)
+
+	driver, err := postgres.WithInstance(sqlDB, &postgres.Config{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create postgres driver: %w", err)
+	}
+
+	m, err := migrate.NewWithDatabaseInstance(
+		fmt.Sprintf("file://%s", migrationsPath),
+		"postgres", driver)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create migrate instance: %w", err)
+	}
+
+	// Note: Not closing the migration pool to avoid deadlocks with the migrate library
+	// The pool will be garbage collected once the Migrator is
+
+	return &Migrator{m: m}, nil
+}
+
+// Version returns the applied version, 0 when no migration has run, and
+// whether the last one failed part way
+func (mg *Migrator) Version() (uint, bool, error) {
+	version, dirty, err := mg.m.Version()
+	if errors.Is(err, migrate.ErrNilVersion) {
+		return 0, false, nil
+	}
+	if err != nil {
+		return 0, false, fmt.Errorf("failed to read schema version: %w", err)
+	}
+	return version, dirty, nil
+}
+
+// Up applies every pending migration
+func (mg *Migrator) Up() error {
+	if err := mg.m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+		return fmt.Errorf("failed to run migrations: %w", err)
+	}
+	return nil
+}
+
+// Down rolls back the last steps migrations
+func (mg *Migrator) Down(steps int) error {
+	if err := mg.m.Steps(-steps); err != nil {
+		return fmt.Errorf("failed to roll back migrations: %w", err)
+	}
+	return nil
+}
+
+// Force records version as applied and clean without running anything, to
+// recover after a migration failed part way and was fixed by hand. -1
+// records that no migration has run.
+func (mg *Migrator) Force(version int) error {
+	if err := mg.m.Force(version); err != nil {
+		return fmt.Errorf("failed to force version %d: %w", version, err)
+	}
+	return nil
+}
+
+func RunMigrations(ctx context.Context, dbURL, migrationsPath string) error {
+	mg, err := NewMigrator(ctx, dbURL, migrationsPath)
+	if err != nil {
+		return err
+	}
+
+	slog.Debug("applying migrations", "path", migrationsPath)
+	if err := mg.Up(); err != nil {
+		return err
+	}
+	if version, dirty, err := mg.Version(); err == nil {
+		slog.Info("migrations applied", "version", version, "dirty", dirty)
+	}
+	return nil
+}
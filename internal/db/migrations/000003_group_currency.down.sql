@@ -0,0 +1,3 @@
+ALTER TABLE settlements DROP COLUMN IF EXISTS currency;
+ALTER TABLE expenses DROP COLUMN IF EXISTS currency;
+ALTER TABLE groups DROP COLUMN IF EXISTS currency;
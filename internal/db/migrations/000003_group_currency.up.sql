@@ -0,0 +1,6 @@
+-- Add default currency to groups
+ALTER TABLE groups ADD COLUMN currency VARCHAR(3) NOT NULL DEFAULT 'USD';
+
+-- Record the currency each expense and settlement was created in
+ALTER TABLE expenses ADD COLUMN currency VARCHAR(3) NOT NULL DEFAULT 'USD';
+ALTER TABLE settlements ADD COLUMN currency VARCHAR(3) NOT NULL DEFAULT 'USD';
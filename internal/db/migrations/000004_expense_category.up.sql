@@ -0,0 +1,2 @@
+-- Free-form category label for group expenses
+ALTER TABLE expenses ADD COLUMN category VARCHAR(100);
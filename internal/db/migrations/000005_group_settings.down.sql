@@ -0,0 +1,2 @@
+ALTER TABLE group_members DROP COLUMN IF EXISTS default_shares;
+DROP TABLE IF EXISTS group_settings;
@@ -0,0 +1,11 @@
+-- Create group_settings table
+CREATE TABLE group_settings (
+    group_id UUID PRIMARY KEY REFERENCES groups(id) ON DELETE CASCADE,
+    default_split_mode VARCHAR(10) NOT NULL DEFAULT 'equal' CHECK (default_split_mode IN ('equal', 'shares')),
+    rounding_rule VARCHAR(20) NOT NULL DEFAULT 'payer' CHECK (rounding_rule IN ('payer', 'largest_remainder')),
+    allow_non_payer_edits BOOLEAN NOT NULL DEFAULT TRUE,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+-- Weight used when splitting expenses by shares
+ALTER TABLE group_members ADD COLUMN default_shares INTEGER NOT NULL DEFAULT 1 CHECK (default_shares > 0);
@@ -0,0 +1,6 @@
+DELETE FROM users WHERE is_placeholder;
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_credentials_check;
+ALTER TABLE users DROP COLUMN IF EXISTS is_placeholder;
+ALTER TABLE users DROP COLUMN IF EXISTS display_name;
+ALTER TABLE users ALTER COLUMN password_hash SET NOT NULL;
+ALTER TABLE users ALTER COLUMN email SET NOT NULL;
@@ -0,0 +1,7 @@
+-- Placeholder users have a display name but no login credentials
+ALTER TABLE users ALTER COLUMN email DROP NOT NULL;
+ALTER TABLE users ALTER COLUMN password_hash DROP NOT NULL;
+ALTER TABLE users ADD COLUMN display_name VARCHAR(100);
+ALTER TABLE users ADD COLUMN is_placeholder BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE users ADD CONSTRAINT users_credentials_check
+    CHECK (is_placeholder OR (email IS NOT NULL AND password_hash IS NOT NULL));
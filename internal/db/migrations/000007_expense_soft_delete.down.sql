@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_expenses_group_id_active;
+ALTER TABLE expenses DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,4 @@
+-- Soft delete for group expenses
+ALTER TABLE expenses ADD COLUMN deleted_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX idx_expenses_group_id_active ON expenses(group_id) WHERE deleted_at IS NULL;
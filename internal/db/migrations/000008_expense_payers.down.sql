@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_expense_payers_user_id;
+DROP TABLE IF EXISTS expense_payers;
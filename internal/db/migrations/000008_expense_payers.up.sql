@@ -0,0 +1,13 @@
+-- Create expense_payers table so an expense can be paid by several members
+CREATE TABLE expense_payers (
+    expense_id UUID NOT NULL REFERENCES expenses(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    amount DECIMAL(10,2) NOT NULL CHECK (amount > 0),
+    PRIMARY KEY (expense_id, user_id)
+);
+
+-- Existing expenses were paid in full by paid_by
+INSERT INTO expense_payers (expense_id, user_id, amount)
+SELECT id, paid_by, total_amount FROM expenses;
+
+CREATE INDEX idx_expense_payers_user_id ON expense_payers(user_id);
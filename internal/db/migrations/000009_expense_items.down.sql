@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_expense_item_assignees_user_id;
+DROP INDEX IF EXISTS idx_expense_items_expense_id;
+DROP TABLE IF EXISTS expense_item_assignees;
+DROP TABLE IF EXISTS expense_items;
@@ -0,0 +1,18 @@
+-- Create expense_items table for itemized bills
+CREATE TABLE expense_items (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    expense_id UUID NOT NULL REFERENCES expenses(id) ON DELETE CASCADE,
+    description VARCHAR(255) NOT NULL,
+    amount DECIMAL(10,2) NOT NULL CHECK (amount >= 0),
+    position INTEGER NOT NULL DEFAULT 0
+);
+
+-- Create expense_item_assignees table linking items to the members who shared them
+CREATE TABLE expense_item_assignees (
+    item_id UUID NOT NULL REFERENCES expense_items(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    PRIMARY KEY (item_id, user_id)
+);
+
+CREATE INDEX idx_expense_items_expense_id ON expense_items(expense_id);
+CREATE INDEX idx_expense_item_assignees_user_id ON expense_item_assignees(user_id);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_expenses_group_expense_date;
+ALTER TABLE expenses DROP COLUMN IF EXISTS expense_date;
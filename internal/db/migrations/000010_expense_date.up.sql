@@ -0,0 +1,7 @@
+-- Date the expense happened, which may differ from when it was recorded
+ALTER TABLE expenses ADD COLUMN expense_date TIMESTAMP WITH TIME ZONE;
+UPDATE expenses SET expense_date = created_at;
+ALTER TABLE expenses ALTER COLUMN expense_date SET NOT NULL;
+ALTER TABLE expenses ALTER COLUMN expense_date SET DEFAULT NOW();
+
+CREATE INDEX idx_expenses_group_expense_date ON expenses(group_id, expense_date DESC);
@@ -0,0 +1,10 @@
+-- Create expense_comments table for discussion on group expenses
+CREATE TABLE expense_comments (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    expense_id UUID NOT NULL REFERENCES expenses(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    body TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_expense_comments_expense_id ON expense_comments(expense_id, created_at);
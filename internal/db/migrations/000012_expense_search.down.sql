@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_expense_splits_user_expense;
+DROP INDEX IF EXISTS idx_expenses_group_total_amount;
+DROP INDEX IF EXISTS idx_expenses_group_category;
+DROP INDEX IF EXISTS idx_expenses_description_trgm;
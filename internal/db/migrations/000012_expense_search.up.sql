@@ -0,0 +1,7 @@
+-- Trigram index backs ILIKE search on expense descriptions
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX idx_expenses_description_trgm ON expenses USING GIN (description gin_trgm_ops);
+CREATE INDEX idx_expenses_group_category ON expenses(group_id, category);
+CREATE INDEX idx_expenses_group_total_amount ON expenses(group_id, total_amount);
+CREATE INDEX idx_expense_splits_user_expense ON expense_splits(user_id, expense_id);
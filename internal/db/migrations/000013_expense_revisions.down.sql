@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_expense_revisions_expense_id;
+DROP TABLE IF EXISTS expense_revisions;
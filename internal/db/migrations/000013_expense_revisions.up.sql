@@ -0,0 +1,12 @@
+-- Create expense_revisions table recording every change to a group expense
+CREATE TABLE expense_revisions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    expense_id UUID NOT NULL REFERENCES expenses(id) ON DELETE CASCADE,
+    edited_by UUID NOT NULL REFERENCES users(id),
+    action VARCHAR(20) NOT NULL CHECK (action IN ('update', 'delete', 'restore')),
+    before JSONB,
+    after JSONB,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_expense_revisions_expense_id ON expense_revisions(expense_id, created_at DESC);
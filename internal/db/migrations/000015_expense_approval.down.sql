@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_expenses_group_pending;
+ALTER TABLE expenses DROP COLUMN IF EXISTS reviewed_at;
+ALTER TABLE expenses DROP COLUMN IF EXISTS reviewed_by;
+ALTER TABLE expenses DROP COLUMN IF EXISTS status;
+ALTER TABLE expenses DROP COLUMN IF EXISTS created_by;
+ALTER TABLE group_settings DROP COLUMN IF EXISTS approval_threshold;
@@ -0,0 +1,12 @@
+-- Expenses above the threshold need another member's approval; NULL disables approval
+ALTER TABLE group_settings ADD COLUMN approval_threshold DECIMAL(10,2) CHECK (approval_threshold >= 0);
+
+-- Track who created an expense and its approval state
+ALTER TABLE expenses ADD COLUMN created_by UUID REFERENCES users(id) ON DELETE SET NULL;
+UPDATE expenses SET created_by = paid_by;
+
+ALTER TABLE expenses ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'approved' CHECK (status IN ('pending', 'approved', 'rejected'));
+ALTER TABLE expenses ADD COLUMN reviewed_by UUID REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE expenses ADD COLUMN reviewed_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX idx_expenses_group_pending ON expenses(group_id) WHERE status = 'pending';
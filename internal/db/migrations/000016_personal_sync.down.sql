@@ -0,0 +1,4 @@
+ALTER TABLE personal_expenses DROP CONSTRAINT IF EXISTS personal_expenses_user_group_expense_key;
+ALTER TABLE personal_expenses DROP COLUMN IF EXISTS group_expense_id;
+ALTER TABLE group_members DROP COLUMN IF EXISTS mirror_category_id;
+ALTER TABLE group_members DROP COLUMN IF EXISTS mirror_to_personal;
@@ -0,0 +1,7 @@
+-- Members can mirror their share of group expenses into their personal ledger
+ALTER TABLE group_members ADD COLUMN mirror_to_personal BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE group_members ADD COLUMN mirror_category_id UUID REFERENCES expense_categories(id) ON DELETE SET NULL;
+
+-- Link mirrored personal expenses to the group expense they came from
+ALTER TABLE personal_expenses ADD COLUMN group_expense_id UUID REFERENCES expenses(id) ON DELETE CASCADE;
+ALTER TABLE personal_expenses ADD CONSTRAINT personal_expenses_user_group_expense_key UNIQUE (user_id, group_expense_id);
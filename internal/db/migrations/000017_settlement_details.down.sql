@@ -0,0 +1,3 @@
+ALTER TABLE settlements DROP COLUMN IF EXISTS external_ref;
+ALTER TABLE settlements DROP COLUMN IF EXISTS note;
+ALTER TABLE settlements DROP COLUMN IF EXISTS method;
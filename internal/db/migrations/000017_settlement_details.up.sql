@@ -0,0 +1,4 @@
+-- Record how a settlement was paid
+ALTER TABLE settlements ADD COLUMN method VARCHAR(20) CHECK (method IN ('cash', 'bank_transfer', 'venmo', 'other'));
+ALTER TABLE settlements ADD COLUMN note TEXT;
+ALTER TABLE settlements ADD COLUMN external_ref VARCHAR(100);
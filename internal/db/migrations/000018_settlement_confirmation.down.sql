@@ -0,0 +1,3 @@
+ALTER TABLE settlements DROP COLUMN IF EXISTS confirmed_at;
+ALTER TABLE settlements DROP COLUMN IF EXISTS status;
+ALTER TABLE group_settings DROP COLUMN IF EXISTS require_settlement_confirmation;
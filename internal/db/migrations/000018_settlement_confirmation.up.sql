@@ -0,0 +1,6 @@
+-- Groups can require the recipient to confirm a settlement before it counts
+ALTER TABLE group_settings ADD COLUMN require_settlement_confirmation BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE settlements ADD COLUMN status VARCHAR(20) NOT NULL DEFAULT 'confirmed' CHECK (status IN ('pending', 'confirmed'));
+ALTER TABLE settlements ADD COLUMN confirmed_at TIMESTAMP WITH TIME ZONE;
+UPDATE settlements SET confirmed_at = created_at;
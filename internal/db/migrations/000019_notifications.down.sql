@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_notifications_user_group_type;
+DROP INDEX IF EXISTS idx_notifications_user_id;
+DROP TABLE IF EXISTS notifications;
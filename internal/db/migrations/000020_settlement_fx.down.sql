@@ -0,0 +1,3 @@
+ALTER TABLE settlements DROP COLUMN IF EXISTS fx_rate;
+ALTER TABLE settlements DROP COLUMN IF EXISTS original_currency;
+ALTER TABLE settlements DROP COLUMN IF EXISTS original_amount;
@@ -0,0 +1,5 @@
+-- Settlements paid in another currency keep the original amount and the rate
+-- used to convert it; amount and currency stay in the group's currency
+ALTER TABLE settlements ADD COLUMN original_amount DECIMAL(10,2);
+ALTER TABLE settlements ADD COLUMN original_currency VARCHAR(3);
+ALTER TABLE settlements ADD COLUMN fx_rate DECIMAL(18,8) CHECK (fx_rate > 0);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_settlement_allocations_expense_id;
+DROP TABLE IF EXISTS settlement_allocations;
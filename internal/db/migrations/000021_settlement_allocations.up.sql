@@ -0,0 +1,9 @@
+-- Create settlement_allocations table recording which expenses a settlement pays off
+CREATE TABLE settlement_allocations (
+    settlement_id UUID NOT NULL REFERENCES settlements(id) ON DELETE CASCADE,
+    expense_id UUID NOT NULL REFERENCES expenses(id) ON DELETE CASCADE,
+    amount DECIMAL(10,2) NOT NULL CHECK (amount > 0),
+    PRIMARY KEY (settlement_id, expense_id)
+);
+
+CREATE INDEX idx_settlement_allocations_expense_id ON settlement_allocations(expense_id);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_personal_expenses_account_id;
+DROP INDEX IF EXISTS idx_accounts_user_id;
+ALTER TABLE personal_expenses DROP COLUMN IF EXISTS account_id;
+DROP TABLE IF EXISTS accounts;
@@ -0,0 +1,18 @@
+-- Create accounts table for the cash, bank and card accounts money is spent from
+CREATE TABLE accounts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    type VARCHAR(10) NOT NULL CHECK (type IN ('cash', 'bank', 'card')),
+    currency VARCHAR(3) NOT NULL DEFAULT 'USD',
+    opening_balance DECIMAL(12,2) NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE(user_id, name)
+);
+
+-- Let each personal expense say which account paid for it
+ALTER TABLE personal_expenses ADD COLUMN account_id UUID REFERENCES accounts(id) ON DELETE SET NULL;
+
+CREATE INDEX idx_accounts_user_id ON accounts(user_id);
+CREATE INDEX idx_personal_expenses_account_id ON personal_expenses(account_id, expense_date);
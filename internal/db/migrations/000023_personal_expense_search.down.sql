@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_personal_expenses_notes_trgm;
+DROP INDEX IF EXISTS idx_personal_expenses_description_trgm;
+DROP INDEX IF EXISTS idx_personal_expenses_search_vector;
+ALTER TABLE personal_expenses DROP COLUMN IF EXISTS search_vector;
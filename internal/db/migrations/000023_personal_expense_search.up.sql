@@ -0,0 +1,14 @@
+-- Full-text vector over the searchable text of personal expenses
+ALTER TABLE personal_expenses ADD COLUMN search_vector tsvector
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector('english', COALESCE(description, '')), 'A') ||
+        setweight(to_tsvector('english', COALESCE(notes, '')), 'B')
+    ) STORED;
+
+CREATE INDEX idx_personal_expenses_search_vector ON personal_expenses USING GIN (search_vector);
+
+-- Trigram indexes back partial-word matches full-text search misses
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX idx_personal_expenses_description_trgm ON personal_expenses USING GIN (description gin_trgm_ops);
+CREATE INDEX idx_personal_expenses_notes_trgm ON personal_expenses USING GIN (notes gin_trgm_ops);
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_receipts_expense_id;
+DROP TABLE IF EXISTS receipts;
@@ -0,0 +1,16 @@
+DROP INDEX IF EXISTS idx_merchant_aliases_merchant_id;
+DROP INDEX IF EXISTS idx_personal_expenses_merchant_id;
+DROP INDEX IF EXISTS idx_personal_expenses_merchant_trgm;
+
+ALTER TABLE personal_expenses DROP COLUMN search_vector;
+ALTER TABLE personal_expenses ADD COLUMN search_vector tsvector
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector('english', COALESCE(description, '')), 'A') ||
+        setweight(to_tsvector('english', COALESCE(notes, '')), 'B')
+    ) STORED;
+CREATE INDEX idx_personal_expenses_search_vector ON personal_expenses USING GIN (search_vector);
+
+ALTER TABLE personal_expenses DROP COLUMN IF EXISTS merchant;
+ALTER TABLE personal_expenses DROP COLUMN IF EXISTS merchant_id;
+DROP TABLE IF EXISTS merchant_aliases;
+DROP TABLE IF EXISTS merchants;
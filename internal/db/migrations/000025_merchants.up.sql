@@ -0,0 +1,36 @@
+-- Create merchants table holding each user's normalized payees
+CREATE TABLE merchants (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    category_id UUID REFERENCES expense_categories(id) ON DELETE SET NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE(user_id, name)
+);
+
+-- Create merchant_aliases table mapping normalized raw payee text to a merchant
+CREATE TABLE merchant_aliases (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    merchant_id UUID NOT NULL REFERENCES merchants(id) ON DELETE CASCADE,
+    alias VARCHAR(100) NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    UNIQUE(user_id, alias)
+);
+
+ALTER TABLE personal_expenses ADD COLUMN merchant_id UUID REFERENCES merchants(id) ON DELETE SET NULL;
+ALTER TABLE personal_expenses ADD COLUMN merchant VARCHAR(100);
+
+-- Rebuild the search vector to cover the merchant name
+ALTER TABLE personal_expenses DROP COLUMN search_vector;
+ALTER TABLE personal_expenses ADD COLUMN search_vector tsvector
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector('english', COALESCE(merchant, '')), 'A') ||
+        setweight(to_tsvector('english', COALESCE(description, '')), 'A') ||
+        setweight(to_tsvector('english', COALESCE(notes, '')), 'B')
+    ) STORED;
+
+CREATE INDEX idx_personal_expenses_search_vector ON personal_expenses USING GIN (search_vector);
+CREATE INDEX idx_personal_expenses_merchant_trgm ON personal_expenses USING GIN (merchant gin_trgm_ops);
+CREATE INDEX idx_personal_expenses_merchant_id ON personal_expenses(merchant_id);
+CREATE INDEX idx_merchant_aliases_merchant_id ON merchant_aliases(merchant_id);
@@ -0,0 +1,5 @@
+ALTER TABLE personal_expenses DROP COLUMN IF EXISTS fx_rate;
+ALTER TABLE personal_expenses DROP COLUMN IF EXISTS original_currency;
+ALTER TABLE personal_expenses DROP COLUMN IF EXISTS original_amount;
+ALTER TABLE personal_expenses DROP COLUMN IF EXISTS currency;
+ALTER TABLE users DROP COLUMN IF EXISTS default_currency;
@@ -0,0 +1,10 @@
+-- Personal expenses are reported in each user's default currency
+ALTER TABLE users ADD COLUMN default_currency VARCHAR(3) NOT NULL DEFAULT 'USD';
+
+-- amount and currency hold the expense in the user's default currency at the
+-- time it was recorded; expenses paid in another currency keep the original
+-- amount and the rate used to convert it
+ALTER TABLE personal_expenses ADD COLUMN currency VARCHAR(3) NOT NULL DEFAULT 'USD';
+ALTER TABLE personal_expenses ADD COLUMN original_amount DECIMAL(12,2) CHECK (original_amount > 0);
+ALTER TABLE personal_expenses ADD COLUMN original_currency VARCHAR(3);
+ALTER TABLE personal_expenses ADD COLUMN fx_rate DECIMAL(18,8) CHECK (fx_rate > 0);
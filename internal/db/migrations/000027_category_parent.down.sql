@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_expense_categories_parent_id;
+
+ALTER TABLE expense_categories DROP CONSTRAINT IF EXISTS expense_categories_parent_not_self;
+ALTER TABLE expense_categories DROP COLUMN IF EXISTS parent_id;
@@ -0,0 +1,6 @@
+-- Categories can be nested under a parent category; deleting a parent moves
+-- its children to the top level
+ALTER TABLE expense_categories ADD COLUMN parent_id UUID REFERENCES expense_categories(id) ON DELETE SET NULL;
+ALTER TABLE expense_categories ADD CONSTRAINT expense_categories_parent_not_self CHECK (parent_id <> id);
+
+CREATE INDEX idx_expense_categories_parent_id ON expense_categories(parent_id);
@@ -0,0 +1 @@
+ALTER TABLE expense_categories DROP COLUMN IF EXISTS archived;
@@ -0,0 +1,3 @@
+-- Archived categories are hidden from category lists but keep labelling the
+-- expenses that use them
+ALTER TABLE expense_categories ADD COLUMN archived BOOLEAN NOT NULL DEFAULT FALSE;
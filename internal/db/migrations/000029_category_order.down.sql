@@ -0,0 +1,2 @@
+ALTER TABLE expense_categories DROP COLUMN IF EXISTS pinned;
+ALTER TABLE expense_categories DROP COLUMN IF EXISTS sort_order;
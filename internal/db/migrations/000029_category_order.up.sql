@@ -0,0 +1,4 @@
+-- User-defined category order; pinned categories come first, then ordered
+-- ones, then the rest by name
+ALTER TABLE expense_categories ADD COLUMN sort_order INTEGER;
+ALTER TABLE expense_categories ADD COLUMN pinned BOOLEAN NOT NULL DEFAULT FALSE;
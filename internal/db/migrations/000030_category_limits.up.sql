@@ -0,0 +1,2 @@
+-- Optional soft monthly spending limit per category
+ALTER TABLE expense_categories ADD COLUMN monthly_limit DECIMAL(12,2) CHECK (monthly_limit > 0);
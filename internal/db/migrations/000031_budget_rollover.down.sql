@@ -0,0 +1 @@
+ALTER TABLE monthly_budgets DROP COLUMN IF EXISTS rollover;
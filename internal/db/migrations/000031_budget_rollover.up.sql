@@ -0,0 +1,2 @@
+-- Whether what is left of the budget (or overspent) carries into the next month
+ALTER TABLE monthly_budgets ADD COLUMN rollover BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS idx_monthly_budgets_period_start;
+
+DELETE FROM monthly_budgets WHERE period <> 'monthly';
+
+ALTER TABLE monthly_budgets
+    DROP CONSTRAINT IF EXISTS monthly_budgets_period_range,
+    ALTER COLUMN month SET NOT NULL,
+    ALTER COLUMN year SET NOT NULL,
+    DROP COLUMN IF EXISTS end_date,
+    DROP COLUMN IF EXISTS start_date,
+    DROP COLUMN IF EXISTS period;
@@ -0,0 +1,21 @@
+-- Budgets cover a period (weekly, monthly, yearly or a custom range) given by
+-- its first and last day. month and year are kept for monthly budgets and
+-- year for yearly ones.
+ALTER TABLE monthly_budgets
+    ADD COLUMN period VARCHAR(10) NOT NULL DEFAULT 'monthly'
+        CHECK (period IN ('weekly', 'monthly', 'yearly', 'custom')),
+    ADD COLUMN start_date DATE,
+    ADD COLUMN end_date DATE;
+
+UPDATE monthly_budgets
+SET start_date = make_date(year, month, 1),
+    end_date = (make_date(year, month, 1) + INTERVAL '1 month' - INTERVAL '1 day')::DATE;
+
+ALTER TABLE monthly_budgets
+    ALTER COLUMN start_date SET NOT NULL,
+    ALTER COLUMN end_date SET NOT NULL,
+    ALTER COLUMN month DROP NOT NULL,
+    ALTER COLUMN year DROP NOT NULL,
+    ADD CONSTRAINT monthly_budgets_period_range CHECK (end_date >= start_date);
+
+CREATE UNIQUE INDEX idx_monthly_budgets_period_start ON monthly_budgets(user_id, period, start_date);
@@ -0,0 +1,10 @@
+-- Scheduled email summaries. last_sent_at is when the last digest went out,
+-- so one goes out per week or month; unsubscribe_token lets the link in the
+-- email turn digests off without logging in.
+CREATE TABLE digest_preferences (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    frequency VARCHAR(10) NOT NULL DEFAULT 'off' CHECK (frequency IN ('off', 'weekly', 'monthly')),
+    unsubscribe_token UUID NOT NULL UNIQUE DEFAULT gen_random_uuid(),
+    last_sent_at TIMESTAMP WITH TIME ZONE,
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
@@ -0,0 +1,10 @@
+-- Insights are computed from expense history on request; this records which
+-- ones a user has dismissed or acknowledged. insight_id is derived from what
+-- the insight is about, so the same finding keeps its id between requests.
+CREATE TABLE insight_states (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    insight_id UUID NOT NULL,
+    status VARCHAR(20) NOT NULL CHECK (status IN ('dismissed', 'acknowledged')),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    PRIMARY KEY (user_id, insight_id)
+);
@@ -0,0 +1,21 @@
+-- Create recurring_expenses table for bills and subscriptions a user expects
+-- to pay on a schedule. Due dates follow start_date, so monthly bills on the
+-- 31st fall on the last day of shorter months without drifting.
+CREATE TABLE recurring_expenses (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    category_id UUID REFERENCES expense_categories(id) ON DELETE SET NULL,
+    merchant_id UUID REFERENCES merchants(id) ON DELETE SET NULL,
+    merchant VARCHAR(100),
+    amount DECIMAL(12,2) NOT NULL CHECK (amount > 0),
+    currency VARCHAR(3) NOT NULL,
+    description VARCHAR(255),
+    frequency VARCHAR(10) NOT NULL CHECK (frequency IN ('weekly', 'monthly', 'yearly')),
+    start_date DATE NOT NULL,
+    next_due_date DATE NOT NULL,
+    active BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE INDEX idx_recurring_expenses_user_id ON recurring_expenses(user_id);
@@ -0,0 +1,10 @@
+DROP TRIGGER IF EXISTS settlements_balance ON settlements;
+DROP TRIGGER IF EXISTS expenses_balance_delete ON expenses;
+DROP TRIGGER IF EXISTS expenses_balance_update ON expenses;
+DROP TRIGGER IF EXISTS expense_splits_balance ON expense_splits;
+DROP TRIGGER IF EXISTS expense_payers_balance ON expense_payers;
+DROP FUNCTION IF EXISTS track_settlement_balance();
+DROP FUNCTION IF EXISTS track_expense_balance();
+DROP FUNCTION IF EXISTS track_expense_share_balance();
+DROP FUNCTION IF EXISTS adjust_group_balance(UUID, UUID, DECIMAL);
+DROP TABLE IF EXISTS group_balances;
@@ -0,0 +1,112 @@
+-- Keep each member's net balance per group up to date, so reading balances
+-- doesn't aggregate a group's whole history. Positive means the member is
+-- owed. Triggers below adjust it on every write that counts towards it.
+CREATE TABLE group_balances (
+    group_id UUID NOT NULL REFERENCES groups(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    balance DECIMAL(14,2) NOT NULL DEFAULT 0,
+    PRIMARY KEY (group_id, user_id)
+);
+
+-- adjust_group_balance adds delta to a member's balance. Rows of a group or
+-- user being deleted are skipped; their balances are deleted with them.
+CREATE FUNCTION adjust_group_balance(g UUID, u UUID, delta DECIMAL) RETURNS VOID AS $$
+    INSERT INTO group_balances (group_id, user_id, balance)
+    SELECT g, u, delta
+    WHERE EXISTS (SELECT 1 FROM groups WHERE id = g) AND EXISTS (SELECT 1 FROM users WHERE id = u)
+    ON CONFLICT (group_id, user_id) DO UPDATE SET balance = group_balances.balance + EXCLUDED.balance
+$$ LANGUAGE sql;
+
+-- Only approved expenses that aren't deleted count. Payers (direction 1)
+-- are owed what they paid and split members (direction -1) owe their share.
+CREATE FUNCTION track_expense_share_balance() RETURNS TRIGGER AS $$
+DECLARE
+    direction DECIMAL := TG_ARGV[0]::DECIMAL;
+    g UUID;
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        SELECT group_id INTO g FROM expenses
+        WHERE id = OLD.expense_id AND deleted_at IS NULL AND status = 'approved';
+        IF FOUND THEN
+            PERFORM adjust_group_balance(g, OLD.user_id, -direction * OLD.amount);
+        END IF;
+    END IF;
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        SELECT group_id INTO g FROM expenses
+        WHERE id = NEW.expense_id AND deleted_at IS NULL AND status = 'approved';
+        IF FOUND THEN
+            PERFORM adjust_group_balance(g, NEW.user_id, direction * NEW.amount);
+        END IF;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER expense_payers_balance AFTER INSERT OR UPDATE OR DELETE ON expense_payers
+    FOR EACH ROW EXECUTE FUNCTION track_expense_share_balance(1);
+CREATE TRIGGER expense_splits_balance AFTER INSERT OR UPDATE OR DELETE ON expense_splits
+    FOR EACH ROW EXECUTE FUNCTION track_expense_share_balance('-1');
+
+-- When an expense stops or starts counting (approval, deletion, restore) its
+-- payers and splits are taken out or put back. Deletes run before the
+-- payers and splits are cascaded away, which then find no expense.
+CREATE FUNCTION track_expense_balance() RETURNS TRIGGER AS $$
+BEGIN
+    IF OLD.deleted_at IS NULL AND OLD.status = 'approved' THEN
+        PERFORM adjust_group_balance(OLD.group_id, user_id, -amount) FROM expense_payers WHERE expense_id = OLD.id;
+        PERFORM adjust_group_balance(OLD.group_id, user_id, amount) FROM expense_splits WHERE expense_id = OLD.id;
+    END IF;
+    IF TG_OP = 'DELETE' THEN
+        RETURN OLD;
+    END IF;
+    IF NEW.deleted_at IS NULL AND NEW.status = 'approved' THEN
+        PERFORM adjust_group_balance(NEW.group_id, user_id, amount) FROM expense_payers WHERE expense_id = NEW.id;
+        PERFORM adjust_group_balance(NEW.group_id, user_id, -amount) FROM expense_splits WHERE expense_id = NEW.id;
+    END IF;
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER expenses_balance_update AFTER UPDATE OF group_id, status, deleted_at ON expenses
+    FOR EACH ROW EXECUTE FUNCTION track_expense_balance();
+CREATE TRIGGER expenses_balance_delete BEFORE DELETE ON expenses
+    FOR EACH ROW EXECUTE FUNCTION track_expense_balance();
+
+-- A confirmed settlement moves the payer up and the recipient down
+CREATE FUNCTION track_settlement_balance() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        IF OLD.status = 'confirmed' THEN
+            PERFORM adjust_group_balance(OLD.group_id, OLD.from_user, -OLD.amount);
+            PERFORM adjust_group_balance(OLD.group_id, OLD.to_user, OLD.amount);
+        END IF;
+    END IF;
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        IF NEW.status = 'confirmed' THEN
+            PERFORM adjust_group_balance(NEW.group_id, NEW.from_user, NEW.amount);
+            PERFORM adjust_group_balance(NEW.group_id, NEW.to_user, -NEW.amount);
+        END IF;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER settlements_balance AFTER INSERT OR UPDATE OR DELETE ON settlements
+    FOR EACH ROW EXECUTE FUNCTION track_settlement_balance();
+
+-- Existing history
+INSERT INTO group_balances (group_id, user_id, balance)
+SELECT group_id, user_id, SUM(amount) FROM (
+    SELECT e.group_id, ep.user_id, ep.amount
+    FROM expense_payers ep JOIN expenses e ON e.id = ep.expense_id
+    WHERE e.deleted_at IS NULL AND e.status = 'approved'
+    UNION ALL
+    SELECT e.group_id, es.user_id, -es.amount
+    FROM expense_splits es JOIN expenses e ON e.id = es.expense_id
+    WHERE e.deleted_at IS NULL AND e.status = 'approved'
+    UNION ALL
+    SELECT group_id, from_user, amount FROM settlements WHERE status = 'confirmed'
+    UNION ALL
+    SELECT group_id, to_user, -amount FROM settlements WHERE status = 'confirmed'
+) entries
+GROUP BY group_id, user_id;
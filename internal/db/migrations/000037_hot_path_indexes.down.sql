@@ -0,0 +1,6 @@
+CREATE INDEX IF NOT EXISTS idx_monthly_budgets_user_id ON monthly_budgets(user_id);
+CREATE INDEX IF NOT EXISTS idx_monthly_budgets_month_year ON monthly_budgets(user_id, month, year);
+CREATE INDEX IF NOT EXISTS idx_personal_expenses_user_date_range ON personal_expenses(user_id, expense_date DESC);
+CREATE INDEX IF NOT EXISTS idx_settlements_group_id ON settlements(group_id);
+DROP INDEX IF EXISTS idx_settlements_group_created_at;
+DROP INDEX IF EXISTS idx_expenses_group_listing;
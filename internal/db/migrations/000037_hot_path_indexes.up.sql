@@ -0,0 +1,18 @@
+-- Indexes matching how the busiest lists are read. Several asked-for
+-- indexes are already covered: expense_splits by its (expense_id, user_id)
+-- key, group_members by its (group_id, user_id) key, monthly_budgets by
+-- UNIQUE(user_id, month, year) and personal_expenses by
+-- idx_personal_expenses_expense_date.
+
+-- Group expense lists skip deleted expenses and sort newest first
+CREATE INDEX idx_expenses_group_listing ON expenses(group_id, expense_date DESC, created_at DESC)
+    WHERE deleted_at IS NULL;
+
+-- Settlement lists sort newest first; this replaces the plain group index
+CREATE INDEX idx_settlements_group_created_at ON settlements(group_id, created_at DESC);
+DROP INDEX IF EXISTS idx_settlements_group_id;
+
+-- Duplicates of the indexes above or of unique constraints
+DROP INDEX IF EXISTS idx_personal_expenses_user_date_range;
+DROP INDEX IF EXISTS idx_monthly_budgets_month_year;
+DROP INDEX IF EXISTS idx_monthly_budgets_user_id;
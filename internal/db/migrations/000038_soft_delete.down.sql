@@ -0,0 +1,38 @@
+-- Rows still soft deleted are removed, as they would have been before
+DELETE FROM settlements WHERE deleted_at IS NOT NULL;
+DELETE FROM expense_categories WHERE deleted_at IS NOT NULL;
+DELETE FROM groups WHERE deleted_at IS NOT NULL;
+
+CREATE OR REPLACE FUNCTION track_settlement_balance() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        IF OLD.status = 'confirmed' THEN
+            PERFORM adjust_group_balance(OLD.group_id, OLD.from_user, -OLD.amount);
+            PERFORM adjust_group_balance(OLD.group_id, OLD.to_user, OLD.amount);
+        END IF;
+    END IF;
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        IF NEW.status = 'confirmed' THEN
+            PERFORM adjust_group_balance(NEW.group_id, NEW.from_user, NEW.amount);
+            PERFORM adjust_group_balance(NEW.group_id, NEW.to_user, -NEW.amount);
+        END IF;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP INDEX IF EXISTS idx_groups_deleted_at;
+DROP INDEX IF EXISTS idx_expense_categories_deleted_at;
+DROP INDEX IF EXISTS idx_settlements_deleted_at;
+DROP INDEX IF EXISTS idx_expenses_deleted_at;
+
+DROP INDEX IF EXISTS idx_expense_categories_user_name;
+ALTER TABLE expense_categories ADD CONSTRAINT expense_categories_user_id_name_key UNIQUE (user_id, name);
+
+ALTER TABLE groups DROP COLUMN IF EXISTS deleted_by;
+ALTER TABLE groups DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE expense_categories DROP COLUMN IF EXISTS deleted_by;
+ALTER TABLE expense_categories DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE settlements DROP COLUMN IF EXISTS deleted_by;
+ALTER TABLE settlements DROP COLUMN IF EXISTS deleted_at;
+ALTER TABLE expenses DROP COLUMN IF EXISTS deleted_by;
@@ -0,0 +1,40 @@
+-- Soft delete for settlements, categories and groups, the way expenses
+-- already work: deleted_at hides a row until it is restored or purged, and
+-- deleted_by records who deleted it.
+ALTER TABLE expenses ADD COLUMN deleted_by UUID REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE settlements ADD COLUMN deleted_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE settlements ADD COLUMN deleted_by UUID REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE expense_categories ADD COLUMN deleted_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE expense_categories ADD COLUMN deleted_by UUID REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE groups ADD COLUMN deleted_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE groups ADD COLUMN deleted_by UUID REFERENCES users(id) ON DELETE SET NULL;
+
+-- A deleted category's name can be used again
+ALTER TABLE expense_categories DROP CONSTRAINT expense_categories_user_id_name_key;
+CREATE UNIQUE INDEX idx_expense_categories_user_name ON expense_categories(user_id, name)
+    WHERE deleted_at IS NULL;
+
+-- The purge job finds rows deleted before its cutoff
+CREATE INDEX idx_expenses_deleted_at ON expenses(deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX idx_settlements_deleted_at ON settlements(deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX idx_expense_categories_deleted_at ON expense_categories(deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX idx_groups_deleted_at ON groups(deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- Deleted settlements stop counting towards balances
+CREATE OR REPLACE FUNCTION track_settlement_balance() RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP IN ('UPDATE', 'DELETE') THEN
+        IF OLD.status = 'confirmed' AND OLD.deleted_at IS NULL THEN
+            PERFORM adjust_group_balance(OLD.group_id, OLD.from_user, -OLD.amount);
+            PERFORM adjust_group_balance(OLD.group_id, OLD.to_user, OLD.amount);
+        END IF;
+    END IF;
+    IF TG_OP IN ('INSERT', 'UPDATE') THEN
+        IF NEW.status = 'confirmed' AND NEW.deleted_at IS NULL THEN
+            PERFORM adjust_group_balance(NEW.group_id, NEW.from_user, NEW.amount);
+            PERFORM adjust_group_balance(NEW.group_id, NEW.to_user, -NEW.amount);
+        END IF;
+    END IF;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
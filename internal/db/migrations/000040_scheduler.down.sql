@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS budget_alerts;
+DROP INDEX IF EXISTS idx_recurring_expenses_auto_record_due;
+ALTER TABLE recurring_expenses DROP COLUMN IF EXISTS auto_record;
+DROP TABLE IF EXISTS scheduled_jobs;
@@ -0,0 +1,29 @@
+-- One row per periodic job. A server runs a job only after claiming it by
+-- setting locked_until, so replicas never run the same job at once, and
+-- next_run_at is shared so a run isn't repeated by each replica.
+CREATE TABLE scheduled_jobs (
+    name VARCHAR(50) PRIMARY KEY,
+    schedule VARCHAR(100) NOT NULL,
+    next_run_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    locked_until TIMESTAMP WITH TIME ZONE,
+    locked_by VARCHAR(255),
+    last_started_at TIMESTAMP WITH TIME ZONE,
+    last_finished_at TIMESTAMP WITH TIME ZONE,
+    last_error TEXT
+);
+
+-- Recurring expenses with auto_record on are recorded as personal expenses
+-- on each due date
+ALTER TABLE recurring_expenses ADD COLUMN auto_record BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX idx_recurring_expenses_auto_record_due ON recurring_expenses(next_due_date)
+    WHERE active AND auto_record;
+
+-- Budget thresholds (percent of the amount) the user has been alerted
+-- about, so each alert is only sent once
+CREATE TABLE budget_alerts (
+    budget_id UUID NOT NULL REFERENCES monthly_budgets(id) ON DELETE CASCADE,
+    threshold INTEGER NOT NULL,
+    sent_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (budget_id, threshold)
+);
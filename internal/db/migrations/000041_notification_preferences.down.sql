@@ -0,0 +1,11 @@
+DROP INDEX IF EXISTS idx_events_unnotified;
+ALTER TABLE events DROP COLUMN IF EXISTS notified_at;
+DROP INDEX IF EXISTS idx_notifications_push_pending;
+DROP INDEX IF EXISTS idx_notifications_email_pending;
+ALTER TABLE notifications
+    DROP COLUMN IF EXISTS pushed_at,
+    DROP COLUMN IF EXISTS emailed_at,
+    DROP COLUMN IF EXISTS push,
+    DROP COLUMN IF EXISTS email,
+    DROP COLUMN IF EXISTS in_app;
+DROP TABLE IF EXISTS notification_preferences;
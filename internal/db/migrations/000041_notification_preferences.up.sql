@@ -0,0 +1,30 @@
+-- Channels a user wants each notification type on. Types without a row use
+-- the defaults: in-app only.
+CREATE TABLE notification_preferences (
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    type VARCHAR(50) NOT NULL,
+    in_app BOOLEAN NOT NULL,
+    email BOOLEAN NOT NULL,
+    push BOOLEAN NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, type)
+);
+
+-- The channels a notification goes out on, copied from the preferences when
+-- it's created, and when it went out on each
+ALTER TABLE notifications
+    ADD COLUMN in_app BOOLEAN NOT NULL DEFAULT TRUE,
+    ADD COLUMN email BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN push BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN emailed_at TIMESTAMP WITH TIME ZONE,
+    ADD COLUMN pushed_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX idx_notifications_email_pending ON notifications(created_at) WHERE email AND emailed_at IS NULL;
+CREATE INDEX idx_notifications_push_pending ON notifications(created_at) WHERE push AND pushed_at IS NULL;
+
+-- Events are turned into notifications separately from webhook dispatch.
+-- Existing events are older than the pipeline and are skipped.
+ALTER TABLE events ADD COLUMN notified_at TIMESTAMP WITH TIME ZONE;
+UPDATE events SET notified_at = NOW();
+
+CREATE INDEX idx_events_unnotified ON events(created_at) WHERE notified_at IS NULL;
@@ -0,0 +1,16 @@
+-- Every attempt to send an email, kept for a while for support and to spot
+-- a failing provider
+CREATE TABLE email_log (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    recipient VARCHAR(255) NOT NULL,
+    subject TEXT NOT NULL,
+    template VARCHAR(50),
+    driver VARCHAR(20) NOT NULL,
+    status VARCHAR(10) NOT NULL CHECK (status IN ('sent', 'failed')),
+    error TEXT,
+    duration_ms INTEGER NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_email_log_created_at ON email_log(created_at);
+CREATE INDEX idx_email_log_recipient ON email_log(recipient, created_at DESC);
@@ -0,0 +1,15 @@
+-- Devices that receive push notifications. A token belongs to one user at a
+-- time: registering it again, e.g. after signing in to another account on
+-- the same phone, moves it. Tokens the provider reports as no longer valid
+-- are deleted.
+CREATE TABLE device_tokens (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    provider VARCHAR(10) NOT NULL CHECK (provider IN ('fcm', 'apns')),
+    token VARCHAR(512) NOT NULL UNIQUE,
+    name VARCHAR(100),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    last_seen_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_device_tokens_user_id ON device_tokens(user_id);
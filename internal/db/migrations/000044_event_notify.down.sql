@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_events_group_id;
+DROP TRIGGER IF EXISTS events_notify ON events;
+DROP FUNCTION IF EXISTS notify_event();
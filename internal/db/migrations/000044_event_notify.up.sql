@@ -0,0 +1,14 @@
+-- Announce new group events on the "events" channel when their transaction
+-- commits, so servers can stream them to connected clients without polling.
+-- The payload is small; listeners read the event itself from the table.
+CREATE FUNCTION notify_event() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('events', json_build_object('id', NEW.id, 'group_id', NEW.group_id)::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER events_notify AFTER INSERT ON events
+    FOR EACH ROW WHEN (NEW.group_id IS NOT NULL) EXECUTE FUNCTION notify_event();
+
+CREATE INDEX idx_events_group_id ON events(group_id, created_at) WHERE group_id IS NOT NULL;
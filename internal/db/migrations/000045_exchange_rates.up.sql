@@ -0,0 +1,15 @@
+-- Daily exchange rates as fetched from the configured provider, one row per
+-- currency against the provider's base. Only days that are over are stored,
+-- so a date's rates never change once fetched and conversions for it are
+-- repeatable; published_on is the provider's last publication on or before
+-- the date, e.g. the Friday for a weekend.
+CREATE TABLE exchange_rates (
+    date DATE NOT NULL,
+    base VARCHAR(3) NOT NULL,
+    currency VARCHAR(3) NOT NULL,
+    rate DECIMAL(18,8) NOT NULL CHECK (rate > 0),
+    published_on DATE NOT NULL,
+    provider VARCHAR(20) NOT NULL,
+    fetched_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (date, currency)
+);
@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_personal_expenses_plaid_transaction_id;
+ALTER TABLE personal_expenses DROP COLUMN IF EXISTS plaid_transaction_id;
+DROP TABLE IF EXISTS plaid_accounts;
+DROP TABLE IF EXISTS plaid_items;
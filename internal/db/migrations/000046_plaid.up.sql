@@ -0,0 +1,41 @@
+-- Bank connections made through Plaid Link. An item is one login at an
+-- institution; its access token is never returned by the API. cursor is
+-- where the last transactions sync stopped, and sync_requested_at is set
+-- when Plaid reports new transactions, for the sync job to pick up.
+CREATE TABLE plaid_items (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    item_id VARCHAR(100) NOT NULL UNIQUE,
+    access_token VARCHAR(200) NOT NULL,
+    institution_name VARCHAR(100),
+    status VARCHAR(20) NOT NULL DEFAULT 'active' CHECK (status IN ('active', 'login_required', 'revoked', 'error')),
+    error_code VARCHAR(100),
+    cursor TEXT,
+    sync_requested_at TIMESTAMP WITH TIME ZONE,
+    last_synced_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_plaid_items_user_id ON plaid_items(user_id);
+
+-- The bank accounts of an item, each linked to an account its transactions
+-- are recorded against
+CREATE TABLE plaid_accounts (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    item_id UUID NOT NULL REFERENCES plaid_items(id) ON DELETE CASCADE,
+    plaid_account_id VARCHAR(100) NOT NULL UNIQUE,
+    account_id UUID REFERENCES accounts(id) ON DELETE SET NULL,
+    name VARCHAR(100) NOT NULL,
+    mask VARCHAR(10),
+    type VARCHAR(20) NOT NULL,
+    subtype VARCHAR(50),
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_plaid_accounts_item_id ON plaid_accounts(item_id);
+
+-- The Plaid transaction an expense was synced from, so it's recorded once
+-- and follows later changes to the transaction
+ALTER TABLE personal_expenses ADD COLUMN plaid_transaction_id VARCHAR(100);
+CREATE UNIQUE INDEX idx_personal_expenses_plaid_transaction_id ON personal_expenses(plaid_transaction_id)
+    WHERE plaid_transaction_id IS NOT NULL;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_personal_expenses_ofx_fitid;
+ALTER TABLE personal_expenses DROP COLUMN IF EXISTS ofx_fitid;
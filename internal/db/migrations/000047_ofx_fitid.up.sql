@@ -0,0 +1,6 @@
+-- The FITID of the OFX statement transaction an expense was imported from.
+-- Banks keep a transaction's FITID stable across downloads within an
+-- account, so importing overlapping statements records each one once.
+ALTER TABLE personal_expenses ADD COLUMN ofx_fitid VARCHAR(255);
+CREATE UNIQUE INDEX idx_personal_expenses_ofx_fitid ON personal_expenses(account_id, ofx_fitid)
+    WHERE ofx_fitid IS NOT NULL;
@@ -0,0 +1,21 @@
+-- What OCR read off a receipt. A row is queued as pending when a receipt is
+-- uploaded with an OCR provider configured; the extraction job claims due
+-- rows by pushing next_attempt_at forward and retries failures with
+-- backoff until attempts runs out.
+CREATE TABLE receipt_extractions (
+    receipt_id UUID PRIMARY KEY REFERENCES receipts(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'succeeded', 'failed')),
+    provider VARCHAR(20) NOT NULL,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    merchant VARCHAR(255),
+    receipt_date DATE,
+    total DECIMAL(12,2),
+    currency VARCHAR(3),
+    line_items JSONB NOT NULL DEFAULT '[]',
+    error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    completed_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX idx_receipt_extractions_due ON receipt_extractions(next_attempt_at) WHERE status = 'pending';
@@ -0,0 +1,12 @@
+-- CSV mappings users saved to reuse on later imports. mapping holds an
+-- ImportMapping: the columns, sign convention and category table.
+CREATE TABLE import_profiles (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name VARCHAR(100) NOT NULL,
+    mapping JSONB NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE DEFAULT NOW()
+);
+
+CREATE UNIQUE INDEX idx_import_profiles_user_name ON import_profiles(user_id, LOWER(name));
@@ -0,0 +1,30 @@
+-- A user's export of their personal or a group's expenses to a Google
+-- Sheet. The row is created as pending when they start connecting, with
+-- oauth_state identifying them when Google redirects back. Exports append
+-- the expenses created after the cursor, (cursor_created_at, cursor_id), so
+-- each is written once; the export job runs the ones whose schedule is due.
+CREATE TABLE google_sheets_integrations (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'active', 'reauth_required')),
+    oauth_state UUID UNIQUE,
+    oauth_state_expires_at TIMESTAMP WITH TIME ZONE,
+    refresh_token TEXT,
+    access_token TEXT,
+    access_token_expires_at TIMESTAMP WITH TIME ZONE,
+    spreadsheet_id VARCHAR(100),
+    sheet_name VARCHAR(100) NOT NULL DEFAULT 'Expenses',
+    source VARCHAR(10) NOT NULL DEFAULT 'personal' CHECK (source IN ('personal', 'group')),
+    group_id UUID REFERENCES groups(id) ON DELETE SET NULL,
+    schedule VARCHAR(10) NOT NULL DEFAULT 'off' CHECK (schedule IN ('off', 'hourly', 'daily', 'weekly')),
+    cursor_created_at TIMESTAMP WITH TIME ZONE,
+    cursor_id UUID,
+    rows_exported INTEGER NOT NULL DEFAULT 0,
+    last_exported_at TIMESTAMP WITH TIME ZONE,
+    next_export_at TIMESTAMP WITH TIME ZONE,
+    last_error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_google_sheets_integrations_due ON google_sheets_integrations(next_export_at)
+    WHERE status = 'active' AND schedule <> 'off';
@@ -0,0 +1,12 @@
+-- Sealed tokens can't be decrypted here: Plaid items with one are deleted
+-- and have to be linked again, and Google integrations go back to needing
+-- the user to connect again
+DELETE FROM plaid_items WHERE get_byte(access_token, 0) = 1;
+ALTER TABLE plaid_items ALTER COLUMN access_token TYPE VARCHAR(200) USING convert_from(access_token, 'UTF8');
+
+UPDATE google_sheets_integrations SET refresh_token = NULL, status = 'reauth_required'
+WHERE get_byte(refresh_token, 0) = 1;
+ALTER TABLE google_sheets_integrations ALTER COLUMN refresh_token TYPE TEXT USING convert_from(refresh_token, 'UTF8');
+
+UPDATE google_sheets_integrations SET access_token = NULL, access_token_expires_at = NULL;
+ALTER TABLE google_sheets_integrations ALTER COLUMN access_token TYPE TEXT USING NULL;
@@ -0,0 +1,11 @@
+-- Plaid and Google tokens are stored encrypted with TOKEN_ENCRYPTION_KEY:
+-- a version byte, the nonce and the AES-GCM ciphertext. Existing tokens
+-- keep their bytes until the sync and export jobs seal them; sealed values
+-- start with the version byte, which plaintext tokens never do.
+ALTER TABLE plaid_items ALTER COLUMN access_token TYPE BYTEA USING convert_to(access_token, 'UTF8');
+
+ALTER TABLE google_sheets_integrations ALTER COLUMN refresh_token TYPE BYTEA USING convert_to(refresh_token, 'UTF8');
+
+-- Access tokens last an hour; the next export gets a new, sealed one
+UPDATE google_sheets_integrations SET access_token = NULL, access_token_expires_at = NULL;
+ALTER TABLE google_sheets_integrations ALTER COLUMN access_token TYPE BYTEA USING NULL;
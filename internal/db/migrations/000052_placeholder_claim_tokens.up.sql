@@ -0,0 +1,3 @@
+-- A placeholder can only be claimed with the token its group's creator
+-- issued for it, so members can't take over each other's placeholders
+ALTER TABLE users ADD COLUMN claim_token UUID UNIQUE;
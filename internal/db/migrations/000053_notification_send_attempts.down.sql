@@ -0,0 +1,5 @@
+ALTER TABLE notifications
+    DROP COLUMN IF EXISTS push_error,
+    DROP COLUMN IF EXISTS push_attempts,
+    DROP COLUMN IF EXISTS email_error,
+    DROP COLUMN IF EXISTS email_attempts;
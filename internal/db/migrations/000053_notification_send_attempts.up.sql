@@ -0,0 +1,8 @@
+-- Failed email and push sends are counted, with the last error, and retried
+-- on later runs until the attempts run out, so one notification that keeps
+-- failing doesn't hold up the rest of its channel
+ALTER TABLE notifications
+    ADD COLUMN email_attempts INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN email_error TEXT,
+    ADD COLUMN push_attempts INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN push_error TEXT;
@@ -0,0 +1,8 @@
+-- name: UpdateAccount :exec
+-- A null argument leaves its column alone
+UPDATE accounts SET
+    name = COALESCE(sqlc.narg('name'), name),
+    type = COALESCE(sqlc.narg('type'), type),
+    opening_balance = COALESCE(sqlc.narg('opening_balance'), opening_balance),
+    updated_at = NOW()
+WHERE id = @id;
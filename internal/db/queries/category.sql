@@ -0,0 +1,11 @@
+-- name: UpdateCategory :one
+-- A null argument leaves its column alone. The parent and limit can be
+-- cleared, so they have their own flags.
+UPDATE expense_categories SET
+    name = COALESCE(sqlc.narg('name'), name),
+    parent_id = CASE WHEN @clear_parent::bool THEN NULL ELSE COALESCE(sqlc.narg('parent_id'), parent_id) END,
+    color = COALESCE(sqlc.narg('color'), color),
+    icon = COALESCE(sqlc.narg('icon'), icon),
+    monthly_limit = CASE WHEN @set_limit::bool THEN sqlc.narg('monthly_limit') ELSE monthly_limit END
+WHERE id = @id AND deleted_at IS NULL
+RETURNING id, user_id, parent_id, name, color, icon, archived, pinned, sort_order, monthly_limit, created_at;
@@ -0,0 +1,52 @@
+-- name: UpdateGroupExpense :one
+-- A null argument leaves its column alone; with none the expense is
+-- returned as it is
+UPDATE expenses SET
+    description = COALESCE(sqlc.narg('description'), description),
+    category = COALESCE(sqlc.narg('category'), category),
+    expense_date = COALESCE(sqlc.narg('expense_date'), expense_date)
+WHERE id = @id
+RETURNING id, group_id, description, total_amount, currency, category, paid_by, expense_date, created_by, status, created_at;
+
+-- name: CountGroupExpenses :one
+-- Takes the filters of ListGroupExpenses; a null filter matches everything
+SELECT COUNT(*) FROM expenses e
+WHERE e.group_id = @group_id AND e.deleted_at IS NULL
+  AND (sqlc.narg('start_date')::timestamptz IS NULL OR e.expense_date >= sqlc.narg('start_date'))
+  AND (sqlc.narg('end_date')::timestamptz IS NULL OR e.expense_date < sqlc.narg('end_date'))
+  AND (sqlc.narg('paid_by')::uuid IS NULL OR EXISTS (
+      SELECT 1 FROM expense_payers ep WHERE ep.expense_id = e.id AND ep.user_id = sqlc.narg('paid_by')))
+  AND (sqlc.narg('participant')::uuid IS NULL OR EXISTS (
+      SELECT 1 FROM expense_splits es WHERE es.expense_id = e.id AND es.user_id = sqlc.narg('participant')))
+  AND (sqlc.narg('min_amount')::numeric IS NULL OR e.total_amount >= sqlc.narg('min_amount'))
+  AND (sqlc.narg('max_amount')::numeric IS NULL OR e.total_amount <= sqlc.narg('max_amount'))
+  AND (sqlc.narg('category')::text IS NULL OR e.category = sqlc.narg('category'))
+  AND (sqlc.narg('status')::text IS NULL OR e.status = sqlc.narg('status'))
+  AND (sqlc.narg('tag')::text IS NULL OR EXISTS (
+      SELECT 1 FROM expense_tags et JOIN tags t ON t.id = et.tag_id
+      WHERE et.expense_id = e.id AND t.name = sqlc.narg('tag')))
+  AND (sqlc.narg('search')::text IS NULL OR e.description ILIKE sqlc.narg('search'));
+
+-- name: ListGroupExpenses :many
+-- Newest first, with each expense's tags. search is an ILIKE pattern.
+SELECT e.id, e.group_id, e.description, e.total_amount, e.currency, e.category, e.paid_by, e.expense_date, e.created_by, e.status, e.created_at,
+    ARRAY(SELECT t.name FROM expense_tags et JOIN tags t ON t.id = et.tag_id
+          WHERE et.expense_id = e.id ORDER BY t.name)::text[] AS tags
+FROM expenses e
+WHERE e.group_id = @group_id AND e.deleted_at IS NULL
+  AND (sqlc.narg('start_date')::timestamptz IS NULL OR e.expense_date >= sqlc.narg('start_date'))
+  AND (sqlc.narg('end_date')::timestamptz IS NULL OR e.expense_date < sqlc.narg('end_date'))
+  AND (sqlc.narg('paid_by')::uuid IS NULL OR EXISTS (
+      SELECT 1 FROM expense_payers ep WHERE ep.expense_id = e.id AND ep.user_id = sqlc.narg('paid_by')))
+  AND (sqlc.narg('participant')::uuid IS NULL OR EXISTS (
+      SELECT 1 FROM expense_splits es WHERE es.expense_id = e.id AND es.user_id = sqlc.narg('participant')))
+  AND (sqlc.narg('min_amount')::numeric IS NULL OR e.total_amount >= sqlc.narg('min_amount'))
+  AND (sqlc.narg('max_amount')::numeric IS NULL OR e.total_amount <= sqlc.narg('max_amount'))
+  AND (sqlc.narg('category')::text IS NULL OR e.category = sqlc.narg('category'))
+  AND (sqlc.narg('status')::text IS NULL OR e.status = sqlc.narg('status'))
+  AND (sqlc.narg('tag')::text IS NULL OR EXISTS (
+      SELECT 1 FROM expense_tags et JOIN tags t ON t.id = et.tag_id
+      WHERE et.expense_id = e.id AND t.name = sqlc.narg('tag')))
+  AND (sqlc.narg('search')::text IS NULL OR e.description ILIKE sqlc.narg('search'))
+ORDER BY e.expense_date DESC, e.created_at DESC
+LIMIT @row_limit::bigint OFFSET @row_offset::bigint;
@@ -0,0 +1,7 @@
+-- name: UpdateMerchant :one
+-- A null argument leaves its column alone
+UPDATE merchants SET
+    name = COALESCE(sqlc.narg('name'), name),
+    category_id = COALESCE(sqlc.narg('category_id'), category_id)
+WHERE id = @id
+RETURNING id, name, category_id, created_at;
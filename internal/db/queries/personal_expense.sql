@@ -0,0 +1,68 @@
+-- name: UpdatePersonalExpense :one
+-- A null argument leaves its column alone. The merchant can be cleared and
+-- the amount columns change together, so they have their own flags.
+UPDATE personal_expenses SET
+    category_id = COALESCE(sqlc.narg('category_id'), category_id),
+    account_id = COALESCE(sqlc.narg('account_id'), account_id),
+    merchant_id = CASE WHEN @set_merchant::bool THEN sqlc.narg('merchant_id') ELSE merchant_id END,
+    merchant = CASE WHEN @set_merchant::bool THEN sqlc.narg('merchant') ELSE merchant END,
+    amount = CASE WHEN @set_amount::bool THEN sqlc.narg('amount') ELSE amount END,
+    currency = CASE WHEN @set_amount::bool THEN sqlc.narg('currency') ELSE currency END,
+    original_amount = CASE WHEN @set_amount::bool THEN sqlc.narg('original_amount') ELSE original_amount END,
+    original_currency = CASE WHEN @set_amount::bool THEN sqlc.narg('original_currency') ELSE original_currency END,
+    fx_rate = CASE WHEN @set_amount::bool THEN sqlc.narg('fx_rate') ELSE fx_rate END,
+    description = COALESCE(sqlc.narg('description'), description),
+    notes = COALESCE(sqlc.narg('notes'), notes),
+    expense_date = COALESCE(sqlc.narg('expense_date'), expense_date),
+    updated_at = NOW()
+WHERE id = @id
+RETURNING id, user_id, category_id, account_id, merchant_id, merchant, amount, currency, description, notes, expense_date,
+    group_expense_id, created_at, updated_at, original_amount, original_currency, fx_rate;
+
+-- name: CountPersonalExpenses :one
+-- Takes the filters of ListPersonalExpenses; a null filter matches
+-- everything. The count and newest change make the list's ETag; with no
+-- expenses the newest change is the epoch.
+SELECT COUNT(*), COALESCE(MAX(updated_at), to_timestamp(0))::timestamptz AS last_updated
+FROM personal_expenses
+WHERE user_id = @user_id
+  AND (sqlc.narg('category_id')::uuid IS NULL OR category_id = sqlc.narg('category_id'))
+  AND (sqlc.narg('account_id')::uuid IS NULL OR account_id = sqlc.narg('account_id'))
+  AND (sqlc.narg('merchant_id')::uuid IS NULL OR merchant_id = sqlc.narg('merchant_id'))
+  AND (sqlc.narg('start_date')::timestamptz IS NULL OR expense_date >= sqlc.narg('start_date'))
+  AND (sqlc.narg('end_date')::timestamptz IS NULL OR expense_date < sqlc.narg('end_date'));
+
+-- name: ListPersonalExpenses :many
+-- sort_column is amount, expense_date or created_at. Ties are broken by
+-- creation time and id, in the same direction, so pages stay stable.
+SELECT id, user_id, category_id, account_id, merchant_id, merchant, amount, currency, description, notes, expense_date,
+    group_expense_id, created_at, updated_at, original_amount, original_currency, fx_rate
+FROM personal_expenses
+WHERE user_id = @user_id
+  AND (sqlc.narg('category_id')::uuid IS NULL OR category_id = sqlc.narg('category_id'))
+  AND (sqlc.narg('account_id')::uuid IS NULL OR account_id = sqlc.narg('account_id'))
+  AND (sqlc.narg('merchant_id')::uuid IS NULL OR merchant_id = sqlc.narg('merchant_id'))
+  AND (sqlc.narg('start_date')::timestamptz IS NULL OR expense_date >= sqlc.narg('start_date'))
+  AND (sqlc.narg('end_date')::timestamptz IS NULL OR expense_date < sqlc.narg('end_date'))
+ORDER BY
+    CASE WHEN @sort_column::text = 'amount' AND NOT @descending::bool THEN amount END ASC,
+    CASE WHEN @sort_column::text = 'amount' AND @descending::bool THEN amount END DESC,
+    CASE WHEN @sort_column::text = 'expense_date' AND NOT @descending::bool THEN expense_date END ASC,
+    CASE WHEN @sort_column::text = 'expense_date' AND @descending::bool THEN expense_date END DESC,
+    CASE WHEN NOT @descending::bool THEN created_at END ASC,
+    CASE WHEN @descending::bool THEN created_at END DESC,
+    CASE WHEN NOT @descending::bool THEN id END ASC,
+    CASE WHEN @descending::bool THEN id END DESC
+LIMIT @row_limit::bigint OFFSET @row_offset::bigint;
+
+-- name: PersonalExpenseStats :many
+-- Spending in currency summed per group_by period (day, week or month); a
+-- null date matches everything
+SELECT date_trunc(@group_by::text, expense_date)::timestamptz AS bucket,
+    SUM(amount)::numeric AS total_amount, COUNT(*) AS expense_count
+FROM personal_expenses
+WHERE user_id = @user_id AND currency = @currency
+  AND (sqlc.narg('start_date')::timestamptz IS NULL OR expense_date >= sqlc.narg('start_date'))
+  AND (sqlc.narg('end_date')::timestamptz IS NULL OR expense_date < sqlc.narg('end_date'))
+GROUP BY bucket
+ORDER BY bucket;
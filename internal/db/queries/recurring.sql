@@ -0,0 +1,13 @@
+-- name: UpdateRecurringExpense :one
+-- A null argument leaves its column alone
+UPDATE recurring_expenses SET
+    category_id = COALESCE(sqlc.narg('category_id'), category_id),
+    amount = COALESCE(sqlc.narg('amount'), amount),
+    description = COALESCE(sqlc.narg('description'), description),
+    active = COALESCE(sqlc.narg('active'), active),
+    auto_record = COALESCE(sqlc.narg('auto_record'), auto_record),
+    next_due_date = COALESCE(sqlc.narg('next_due_date'), next_due_date),
+    updated_at = NOW()
+WHERE id = @id
+RETURNING id, user_id, category_id, merchant_id, merchant, amount, currency, description, frequency,
+    start_date, next_due_date, active, auto_record, created_at, updated_at;
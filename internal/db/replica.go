@@ -0,0 +1,94 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// replicaCheckInterval is how often the replica is pinged, and so about how
+// long reads keep going to it after it fails
+const replicaCheckInterval = 10 * time.Second
+
+// replica is a read-only pool that Reader hands out while it answers pings
+type replica struct {
+	db   *DB
+	up   atomic.Bool
+	stop context.CancelFunc
+	done sync.WaitGroup
+}
+
+// AddReplica connects a read-only pool to a replica at dbURL for Reader.
+// The replica may be down at first; reads use the primary until it answers.
+// Connections are opened read-only, so a write sent there by mistake fails
+// rather than diverging from the primary.
+func (db *DB) AddReplica(ctx context.Context, dbURL string, opts PoolOptions) error {
+	pool, err := newPool(ctx, dbURL, opts, map[string]string{"default_transaction_read_only": "on"})
+	if err != nil {
+		return fmt.Errorf("replica: %w", err)
+	}
+
+	checkCtx, stop := context.WithCancel(context.Background())
+	r := &replica{db: &DB{Pool: pool}, stop: stop}
+	r.check(ctx)
+	if !r.up.Load() {
+		slog.Warn("read replica not answering yet, reading from primary")
+	}
+	r.done.Add(1)
+	go func() {
+		defer r.done.Done()
+		ticker := time.NewTicker(replicaCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-checkCtx.Done():
+				return
+			case <-ticker.C:
+				r.check(checkCtx)
+			}
+		}
+	}()
+	db.replica = r
+	return nil
+}
+
+// check pings the replica and logs when it goes down or comes back
+func (r *replica) check(ctx context.Context) {
+	pingCtx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	err := r.db.Pool.Ping(pingCtx)
+	if ctx.Err() != nil {
+		// Shutting down
+		return
+	}
+
+	up := err == nil
+	if r.up.Swap(up) == up {
+		return
+	}
+	if up {
+		slog.Info("read replica available")
+	} else {
+		slog.Warn("read replica unavailable, reading from primary", "error", err)
+	}
+}
+
+func (r *replica) close() {
+	r.stop()
+	r.done.Wait()
+	r.db.Pool.Close()
+}
+
+// Reader returns the database to use for heavy reads, such as dashboards,
+// reports and exports: the replica when one is configured and answering,
+// otherwise the primary. Replicas lag slightly, so reads that must see a
+// write the same request just made should use the primary.
+func (db *DB) Reader() *DB {
+	if db.replica != nil && db.replica.up.Load() {
+		return db.replica.db
+	}
+	return db
+}
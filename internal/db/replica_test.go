@@ -0,0 +1,25 @@
+package db
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReader(t *testing.T) {
+	primary := &DB{}
+	assert.Same(t, primary, primary.Reader())
+
+	// Nothing listens on port 1, so reads stay on the primary
+	require.NoError(t, primary.AddReplica(context.Background(), "postgres://user@127.0.0.1:1/db", PoolOptions{MaxConns: 1, MaxConnLifetime: time.Hour, MaxConnIdleTime: time.Minute, HealthCheckPeriod: time.Minute}))
+	defer primary.replica.close()
+	assert.Same(t, primary, primary.Reader())
+
+	primary.replica.up.Store(true)
+	assert.Same(t, primary.replica.db, primary.Reader())
+
+	assert.Error(t, primary.AddReplica(context.Background(), "not a url", PoolOptions{}))
+}
@@ -0,0 +1,40 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: account.sql
+
+package sqlc
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+const updateAccount = `-- name: UpdateAccount :exec
+UPDATE accounts SET
+    name = COALESCE($1, name),
+    type = COALESCE($2, type),
+    opening_balance = COALESCE($3, opening_balance),
+    updated_at = NOW()
+WHERE id = $4
+`
+
+type UpdateAccountParams struct {
+	Name           *string
+	Type           *string
+	OpeningBalance *decimal.Decimal
+	ID             uuid.UUID
+}
+
+// A null argument leaves its column alone
+func (q *Queries) UpdateAccount(ctx context.Context, arg UpdateAccountParams) error {
+	_, err := q.db.Exec(ctx, updateAccount,
+		arg.Name,
+		arg.Type,
+		arg.OpeningBalance,
+		arg.ID,
+	)
+	return err
+}
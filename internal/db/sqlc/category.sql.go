@@ -0,0 +1,80 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: category.sql
+
+package sqlc
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+const updateCategory = `-- name: UpdateCategory :one
+UPDATE expense_categories SET
+    name = COALESCE($1, name),
+    parent_id = CASE WHEN $2::bool THEN NULL ELSE COALESCE($3, parent_id) END,
+    color = COALESCE($4, color),
+    icon = COALESCE($5, icon),
+    monthly_limit = CASE WHEN $6::bool THEN $7 ELSE monthly_limit END
+WHERE id = $8 AND deleted_at IS NULL
+RETURNING id, user_id, parent_id, name, color, icon, archived, pinned, sort_order, monthly_limit, created_at
+`
+
+type UpdateCategoryParams struct {
+	Name         *string
+	ClearParent  bool
+	ParentID     *uuid.UUID
+	Color        *string
+	Icon         *string
+	SetLimit     bool
+	MonthlyLimit *decimal.Decimal
+	ID           uuid.UUID
+}
+
+type UpdateCategoryRow struct {
+	ID           uuid.UUID
+	UserID       uuid.UUID
+	ParentID     *uuid.UUID
+	Name         string
+	Color        *string
+	Icon         *string
+	Archived     bool
+	Pinned       bool
+	SortOrder    *int
+	MonthlyLimit *decimal.Decimal
+	CreatedAt    time.Time
+}
+
+// A null argument leaves its column alone. The parent and limit can be
+// cleared, so they have their own flags.
+func (q *Queries) UpdateCategory(ctx context.Context, arg UpdateCategoryParams) (UpdateCategoryRow, error) {
+	row := q.db.QueryRow(ctx, updateCategory,
+		arg.Name,
+		arg.ClearParent,
+		arg.ParentID,
+		arg.Color,
+		arg.Icon,
+		arg.SetLimit,
+		arg.MonthlyLimit,
+		arg.ID,
+	)
+	var i UpdateCategoryRow
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.ParentID,
+		&i.Name,
+		&i.Color,
+		&i.Icon,
+		&i.Archived,
+		&i.Pinned,
+		&i.SortOrder,
+		&i.MonthlyLimit,
+		&i.CreatedAt,
+	)
+	return i, err
+}
@@ -0,0 +1,226 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: expense.sql
+
+package sqlc
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+const countGroupExpenses = `-- name: CountGroupExpenses :one
+SELECT COUNT(*) FROM expenses e
+WHERE e.group_id = $1 AND e.deleted_at IS NULL
+  AND ($2::timestamptz IS NULL OR e.expense_date >= $2)
+  AND ($3::timestamptz IS NULL OR e.expense_date < $3)
+  AND ($4::uuid IS NULL OR EXISTS (
+      SELECT 1 FROM expense_payers ep WHERE ep.expense_id = e.id AND ep.user_id = $4))
+  AND ($5::uuid IS NULL OR EXISTS (
+      SELECT 1 FROM expense_splits es WHERE es.expense_id = e.id AND es.user_id = $5))
+  AND ($6::numeric IS NULL OR e.total_amount >= $6)
+  AND ($7::numeric IS NULL OR e.total_amount <= $7)
+  AND ($8::text IS NULL OR e.category = $8)
+  AND ($9::text IS NULL OR e.status = $9)
+  AND ($10::text IS NULL OR EXISTS (
+      SELECT 1 FROM expense_tags et JOIN tags t ON t.id = et.tag_id
+      WHERE et.expense_id = e.id AND t.name = $10))
+  AND ($11::text IS NULL OR e.description ILIKE $11)
+`
+
+type CountGroupExpensesParams struct {
+	GroupID     uuid.UUID
+	StartDate   *time.Time
+	EndDate     *time.Time
+	PaidBy      *uuid.UUID
+	Participant *uuid.UUID
+	MinAmount   *decimal.Decimal
+	MaxAmount   *decimal.Decimal
+	Category    *string
+	Status      *string
+	Tag         *string
+	Search      *string
+}
+
+// Takes the filters of ListGroupExpenses; a null filter matches everything
+func (q *Queries) CountGroupExpenses(ctx context.Context, arg CountGroupExpensesParams) (int64, error) {
+	row := q.db.QueryRow(ctx, countGroupExpenses,
+		arg.GroupID,
+		arg.StartDate,
+		arg.EndDate,
+		arg.PaidBy,
+		arg.Participant,
+		arg.MinAmount,
+		arg.MaxAmount,
+		arg.Category,
+		arg.Status,
+		arg.Tag,
+		arg.Search,
+	)
+	var count int64
+	err := row.Scan(&count)
+	return count, err
+}
+
+const listGroupExpenses = `-- name: ListGroupExpenses :many
+SELECT e.id, e.group_id, e.description, e.total_amount, e.currency, e.category, e.paid_by, e.expense_date, e.created_by, e.status, e.created_at,
+    ARRAY(SELECT t.name FROM expense_tags et JOIN tags t ON t.id = et.tag_id
+          WHERE et.expense_id = e.id ORDER BY t.name)::text[] AS tags
+FROM expenses e
+WHERE e.group_id = $1 AND e.deleted_at IS NULL
+  AND ($2::timestamptz IS NULL OR e.expense_date >= $2)
+  AND ($3::timestamptz IS NULL OR e.expense_date < $3)
+  AND ($4::uuid IS NULL OR EXISTS (
+      SELECT 1 FROM expense_payers ep WHERE ep.expense_id = e.id AND ep.user_id = $4))
+  AND ($5::uuid IS NULL OR EXISTS (
+      SELECT 1 FROM expense_splits es WHERE es.expense_id = e.id AND es.user_id = $5))
+  AND ($6::numeric IS NULL OR e.total_amount >= $6)
+  AND ($7::numeric IS NULL OR e.total_amount <= $7)
+  AND ($8::text IS NULL OR e.category = $8)
+  AND ($9::text IS NULL OR e.status = $9)
+  AND ($10::text IS NULL OR EXISTS (
+      SELECT 1 FROM expense_tags et JOIN tags t ON t.id = et.tag_id
+      WHERE et.expense_id = e.id AND t.name = $10))
+  AND ($11::text IS NULL OR e.description ILIKE $11)
+ORDER BY e.expense_date DESC, e.created_at DESC
+LIMIT $13::bigint OFFSET $12::bigint
+`
+
+type ListGroupExpensesParams struct {
+	GroupID     uuid.UUID
+	StartDate   *time.Time
+	EndDate     *time.Time
+	PaidBy      *uuid.UUID
+	Participant *uuid.UUID
+	MinAmount   *decimal.Decimal
+	MaxAmount   *decimal.Decimal
+	Category    *string
+	Status      *string
+	Tag         *string
+	Search      *string
+	RowOffset   int64
+	RowLimit    int64
+}
+
+type ListGroupExpensesRow struct {
+	ID          uuid.UUID
+	GroupID     uuid.UUID
+	Description string
+	TotalAmount decimal.Decimal
+	Currency    string
+	Category    *string
+	PaidBy      uuid.UUID
+	ExpenseDate time.Time
+	CreatedBy   *uuid.UUID
+	Status      string
+	CreatedAt   time.Time
+	Tags        []string
+}
+
+// Newest first, with each expense's tags. search is an ILIKE pattern.
+func (q *Queries) ListGroupExpenses(ctx context.Context, arg ListGroupExpensesParams) ([]ListGroupExpensesRow, error) {
+	rows, err := q.db.Query(ctx, listGroupExpenses,
+		arg.GroupID,
+		arg.StartDate,
+		arg.EndDate,
+		arg.PaidBy,
+		arg.Participant,
+		arg.MinAmount,
+		arg.MaxAmount,
+		arg.Category,
+		arg.Status,
+		arg.Tag,
+		arg.Search,
+		arg.RowOffset,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListGroupExpensesRow
+	for rows.Next() {
+		var i ListGroupExpensesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.GroupID,
+			&i.Description,
+			&i.TotalAmount,
+			&i.Currency,
+			&i.Category,
+			&i.PaidBy,
+			&i.ExpenseDate,
+			&i.CreatedBy,
+			&i.Status,
+			&i.CreatedAt,
+			&i.Tags,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updateGroupExpense = `-- name: UpdateGroupExpense :one
+UPDATE expenses SET
+    description = COALESCE($1, description),
+    category = COALESCE($2, category),
+    expense_date = COALESCE($3, expense_date)
+WHERE id = $4
+RETURNING id, group_id, description, total_amount, currency, category, paid_by, expense_date, created_by, status, created_at
+`
+
+type UpdateGroupExpenseParams struct {
+	Description *string
+	Category    *string
+	ExpenseDate *time.Time
+	ID          uuid.UUID
+}
+
+type UpdateGroupExpenseRow struct {
+	ID          uuid.UUID
+	GroupID     uuid.UUID
+	Description string
+	TotalAmount decimal.Decimal
+	Currency    string
+	Category    *string
+	PaidBy      uuid.UUID
+	ExpenseDate time.Time
+	CreatedBy   *uuid.UUID
+	Status      string
+	CreatedAt   time.Time
+}
+
+// A null argument leaves its column alone; with none the expense is
+// returned as it is
+func (q *Queries) UpdateGroupExpense(ctx context.Context, arg UpdateGroupExpenseParams) (UpdateGroupExpenseRow, error) {
+	row := q.db.QueryRow(ctx, updateGroupExpense,
+		arg.Description,
+		arg.Category,
+		arg.ExpenseDate,
+		arg.ID,
+	)
+	var i UpdateGroupExpenseRow
+	err := row.Scan(
+		&i.ID,
+		&i.GroupID,
+		&i.Description,
+		&i.TotalAmount,
+		&i.Currency,
+		&i.Category,
+		&i.PaidBy,
+		&i.ExpenseDate,
+		&i.CreatedBy,
+		&i.Status,
+		&i.CreatedAt,
+	)
+	return i, err
+}
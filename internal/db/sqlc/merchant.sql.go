@@ -0,0 +1,47 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: merchant.sql
+
+package sqlc
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const updateMerchant = `-- name: UpdateMerchant :one
+UPDATE merchants SET
+    name = COALESCE($1, name),
+    category_id = COALESCE($2, category_id)
+WHERE id = $3
+RETURNING id, name, category_id, created_at
+`
+
+type UpdateMerchantParams struct {
+	Name       *string
+	CategoryID *uuid.UUID
+	ID         uuid.UUID
+}
+
+type UpdateMerchantRow struct {
+	ID         uuid.UUID
+	Name       string
+	CategoryID *uuid.UUID
+	CreatedAt  time.Time
+}
+
+// A null argument leaves its column alone
+func (q *Queries) UpdateMerchant(ctx context.Context, arg UpdateMerchantParams) (UpdateMerchantRow, error) {
+	row := q.db.QueryRow(ctx, updateMerchant, arg.Name, arg.CategoryID, arg.ID)
+	var i UpdateMerchantRow
+	err := row.Scan(
+		&i.ID,
+		&i.Name,
+		&i.CategoryID,
+		&i.CreatedAt,
+	)
+	return i, err
+}
@@ -0,0 +1,468 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+
+package sqlc
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+type Account struct {
+	ID             uuid.UUID
+	UserID         uuid.UUID
+	Name           string
+	Type           string
+	Currency       string
+	OpeningBalance decimal.Decimal
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+type BudgetAlert struct {
+	BudgetID  uuid.UUID
+	Threshold int32
+	SentAt    time.Time
+}
+
+type DeviceToken struct {
+	ID         uuid.UUID
+	UserID     uuid.UUID
+	Provider   string
+	Token      string
+	Name       *string
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+}
+
+type DigestPreference struct {
+	UserID           uuid.UUID
+	Frequency        string
+	UnsubscribeToken uuid.UUID
+	LastSentAt       *time.Time
+	UpdatedAt        time.Time
+}
+
+type EmailLog struct {
+	ID         uuid.UUID
+	Recipient  string
+	Subject    string
+	Template   *string
+	Driver     string
+	Status     string
+	Error      *string
+	DurationMs int32
+	CreatedAt  time.Time
+}
+
+type Event struct {
+	ID           uuid.UUID
+	Type         string
+	UserID       uuid.UUID
+	GroupID      *uuid.UUID
+	Data         []byte
+	CreatedAt    time.Time
+	DispatchedAt *time.Time
+	NotifiedAt   *time.Time
+}
+
+type ExchangeRate struct {
+	Date        time.Time
+	Base        string
+	Currency    string
+	Rate        decimal.Decimal
+	PublishedOn time.Time
+	Provider    string
+	FetchedAt   time.Time
+}
+
+type Expense struct {
+	ID          uuid.UUID
+	GroupID     uuid.UUID
+	Description string
+	TotalAmount decimal.Decimal
+	PaidBy      uuid.UUID
+	CreatedAt   time.Time
+	Currency    string
+	Category    *string
+	DeletedAt   *time.Time
+	ExpenseDate time.Time
+	CreatedBy   *uuid.UUID
+	Status      string
+	ReviewedBy  *uuid.UUID
+	ReviewedAt  *time.Time
+	DeletedBy   *uuid.UUID
+}
+
+type ExpenseCategory struct {
+	ID           uuid.UUID
+	UserID       uuid.UUID
+	Name         string
+	Color        *string
+	Icon         *string
+	CreatedAt    time.Time
+	ParentID     *uuid.UUID
+	Archived     bool
+	SortOrder    *int
+	Pinned       bool
+	MonthlyLimit *decimal.Decimal
+	DeletedAt    *time.Time
+	DeletedBy    *uuid.UUID
+}
+
+type ExpenseComment struct {
+	ID        uuid.UUID
+	ExpenseID uuid.UUID
+	UserID    uuid.UUID
+	Body      string
+	CreatedAt time.Time
+}
+
+type ExpenseItem struct {
+	ID          uuid.UUID
+	ExpenseID   uuid.UUID
+	Description string
+	Amount      decimal.Decimal
+	Position    int32
+}
+
+type ExpenseItemAssignee struct {
+	ItemID uuid.UUID
+	UserID uuid.UUID
+}
+
+type ExpensePayer struct {
+	ExpenseID uuid.UUID
+	UserID    uuid.UUID
+	Amount    decimal.Decimal
+}
+
+type ExpenseRevision struct {
+	ID        uuid.UUID
+	ExpenseID uuid.UUID
+	EditedBy  uuid.UUID
+	Action    string
+	Before    []byte
+	After     []byte
+	CreatedAt time.Time
+}
+
+type ExpenseSplit struct {
+	ExpenseID uuid.UUID
+	UserID    uuid.UUID
+	Amount    decimal.Decimal
+}
+
+type ExpenseTag struct {
+	ExpenseID uuid.UUID
+	TagID     uuid.UUID
+}
+
+type GoogleSheetsIntegration struct {
+	UserID               uuid.UUID
+	Status               string
+	OauthState           *uuid.UUID
+	OauthStateExpiresAt  *time.Time
+	RefreshToken         []byte
+	AccessToken          []byte
+	AccessTokenExpiresAt *time.Time
+	SpreadsheetID        *string
+	SheetName            string
+	Source               string
+	GroupID              *uuid.UUID
+	Schedule             string
+	CursorCreatedAt      *time.Time
+	CursorID             *uuid.UUID
+	RowsExported         int32
+	LastExportedAt       *time.Time
+	NextExportAt         *time.Time
+	LastError            *string
+	CreatedAt            time.Time
+	UpdatedAt            time.Time
+}
+
+type Group struct {
+	ID        uuid.UUID
+	Name      string
+	CreatedBy uuid.UUID
+	CreatedAt time.Time
+	Currency  string
+	DeletedAt *time.Time
+	DeletedBy *uuid.UUID
+}
+
+type GroupBalance struct {
+	GroupID uuid.UUID
+	UserID  uuid.UUID
+	Balance decimal.Decimal
+}
+
+type GroupMember struct {
+	GroupID          uuid.UUID
+	UserID           uuid.UUID
+	JoinedAt         *time.Time
+	DefaultShares    int32
+	MirrorToPersonal bool
+	MirrorCategoryID *uuid.UUID
+}
+
+type GroupSetting struct {
+	GroupID                       uuid.UUID
+	DefaultSplitMode              string
+	RoundingRule                  string
+	AllowNonPayerEdits            bool
+	UpdatedAt                     time.Time
+	ApprovalThreshold             *decimal.Decimal
+	RequireSettlementConfirmation bool
+}
+
+type ImportProfile struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	Name      string
+	Mapping   []byte
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+type InsightState struct {
+	UserID    uuid.UUID
+	InsightID uuid.UUID
+	Status    string
+	UpdatedAt time.Time
+}
+
+type Merchant struct {
+	ID         uuid.UUID
+	UserID     uuid.UUID
+	Name       string
+	CategoryID *uuid.UUID
+	CreatedAt  time.Time
+}
+
+type MerchantAlias struct {
+	ID         uuid.UUID
+	UserID     uuid.UUID
+	MerchantID uuid.UUID
+	Alias      string
+	CreatedAt  time.Time
+}
+
+type MonthlyBudget struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	Amount    decimal.Decimal
+	Month     *int32
+	Year      *int32
+	CreatedAt time.Time
+	UpdatedAt time.Time
+	Rollover  bool
+	Period    string
+	StartDate time.Time
+	EndDate   time.Time
+}
+
+type Notification struct {
+	ID            uuid.UUID
+	UserID        uuid.UUID
+	GroupID       *uuid.UUID
+	Type          string
+	Data          []byte
+	ReadAt        *time.Time
+	CreatedAt     time.Time
+	InApp         bool
+	Email         bool
+	Push          bool
+	EmailedAt     *time.Time
+	PushedAt      *time.Time
+	EmailAttempts int32
+	EmailError    *string
+	PushAttempts  int32
+	PushError     *string
+}
+
+type NotificationPreference struct {
+	UserID    uuid.UUID
+	Type      string
+	InApp     bool
+	Email     bool
+	Push      bool
+	UpdatedAt time.Time
+}
+
+type PersonalExpense struct {
+	ID                 uuid.UUID
+	UserID             uuid.UUID
+	CategoryID         *uuid.UUID
+	Amount             decimal.Decimal
+	Description        *string
+	Notes              *string
+	ExpenseDate        time.Time
+	CreatedAt          time.Time
+	UpdatedAt          time.Time
+	GroupExpenseID     *uuid.UUID
+	AccountID          *uuid.UUID
+	MerchantID         *uuid.UUID
+	Merchant           *string
+	SearchVector       interface{}
+	Currency           string
+	OriginalAmount     *decimal.Decimal
+	OriginalCurrency   *string
+	FXRate             *decimal.Decimal
+	PlaidTransactionID *string
+	OfxFitid           *string
+}
+
+type PlaidAccount struct {
+	ID             uuid.UUID
+	ItemID         uuid.UUID
+	PlaidAccountID string
+	AccountID      *uuid.UUID
+	Name           string
+	Mask           *string
+	Type           string
+	Subtype        *string
+	CreatedAt      time.Time
+}
+
+type PlaidItem struct {
+	ID              uuid.UUID
+	UserID          uuid.UUID
+	ItemID          string
+	AccessToken     []byte
+	InstitutionName *string
+	Status          string
+	ErrorCode       *string
+	Cursor          *string
+	SyncRequestedAt *time.Time
+	LastSyncedAt    *time.Time
+	CreatedAt       time.Time
+}
+
+type Receipt struct {
+	ID          uuid.UUID
+	ExpenseID   uuid.UUID
+	UserID      uuid.UUID
+	StorageKey  string
+	Filename    string
+	ContentType string
+	SizeBytes   int64
+	CreatedAt   time.Time
+}
+
+type ReceiptExtraction struct {
+	ReceiptID     uuid.UUID
+	Status        string
+	Provider      string
+	Attempts      int32
+	NextAttemptAt time.Time
+	Merchant      *string
+	ReceiptDate   *time.Time
+	Total         *decimal.Decimal
+	Currency      *string
+	LineItems     []byte
+	Error         *string
+	CreatedAt     time.Time
+	CompletedAt   *time.Time
+}
+
+type RecurringExpense struct {
+	ID          uuid.UUID
+	UserID      uuid.UUID
+	CategoryID  *uuid.UUID
+	MerchantID  *uuid.UUID
+	Merchant    *string
+	Amount      decimal.Decimal
+	Currency    string
+	Description *string
+	Frequency   string
+	StartDate   time.Time
+	NextDueDate time.Time
+	Active      bool
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+	AutoRecord  bool
+}
+
+type ScheduledJob struct {
+	Name           string
+	Schedule       string
+	NextRunAt      time.Time
+	LockedUntil    *time.Time
+	LockedBy       *string
+	LastStartedAt  *time.Time
+	LastFinishedAt *time.Time
+	LastError      *string
+}
+
+type Settlement struct {
+	ID               uuid.UUID
+	GroupID          uuid.UUID
+	FromUser         uuid.UUID
+	ToUser           uuid.UUID
+	Amount           decimal.Decimal
+	CreatedAt        time.Time
+	Currency         string
+	Method           *string
+	Note             *string
+	ExternalRef      *string
+	Status           string
+	ConfirmedAt      *time.Time
+	OriginalAmount   *decimal.Decimal
+	OriginalCurrency *string
+	FXRate           *decimal.Decimal
+	DeletedAt        *time.Time
+	DeletedBy        *uuid.UUID
+}
+
+type SettlementAllocation struct {
+	SettlementID uuid.UUID
+	ExpenseID    uuid.UUID
+	Amount       decimal.Decimal
+}
+
+type Tag struct {
+	ID        uuid.UUID
+	GroupID   uuid.UUID
+	Name      string
+	CreatedAt time.Time
+}
+
+type User struct {
+	ID              uuid.UUID
+	Email           *string
+	PasswordHash    *string
+	CreatedAt       time.Time
+	DisplayName     *string
+	IsPlaceholder   bool
+	DefaultCurrency string
+	ClaimToken      *uuid.UUID
+}
+
+type WebhookDelivery struct {
+	ID             uuid.UUID
+	EndpointID     uuid.UUID
+	EventID        uuid.UUID
+	Status         string
+	Attempts       int32
+	NextAttemptAt  time.Time
+	LastAttemptAt  *time.Time
+	ResponseStatus *int32
+	LastError      *string
+	CreatedAt      time.Time
+}
+
+type WebhookEndpoint struct {
+	ID          uuid.UUID
+	UserID      uuid.UUID
+	Url         string
+	Description *string
+	Secret      string
+	EventTypes  []string
+	Active      bool
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
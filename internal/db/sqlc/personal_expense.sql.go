@@ -0,0 +1,316 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: personal_expense.sql
+
+package sqlc
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+const countPersonalExpenses = `-- name: CountPersonalExpenses :one
+SELECT COUNT(*), COALESCE(MAX(updated_at), to_timestamp(0))::timestamptz AS last_updated
+FROM personal_expenses
+WHERE user_id = $1
+  AND ($2::uuid IS NULL OR category_id = $2)
+  AND ($3::uuid IS NULL OR account_id = $3)
+  AND ($4::uuid IS NULL OR merchant_id = $4)
+  AND ($5::timestamptz IS NULL OR expense_date >= $5)
+  AND ($6::timestamptz IS NULL OR expense_date < $6)
+`
+
+type CountPersonalExpensesParams struct {
+	UserID     uuid.UUID
+	CategoryID *uuid.UUID
+	AccountID  *uuid.UUID
+	MerchantID *uuid.UUID
+	StartDate  *time.Time
+	EndDate    *time.Time
+}
+
+type CountPersonalExpensesRow struct {
+	Count       int64
+	LastUpdated time.Time
+}
+
+// Takes the filters of ListPersonalExpenses; a null filter matches
+// everything. The count and newest change make the list's ETag; with no
+// expenses the newest change is the epoch.
+func (q *Queries) CountPersonalExpenses(ctx context.Context, arg CountPersonalExpensesParams) (CountPersonalExpensesRow, error) {
+	row := q.db.QueryRow(ctx, countPersonalExpenses,
+		arg.UserID,
+		arg.CategoryID,
+		arg.AccountID,
+		arg.MerchantID,
+		arg.StartDate,
+		arg.EndDate,
+	)
+	var i CountPersonalExpensesRow
+	err := row.Scan(&i.Count, &i.LastUpdated)
+	return i, err
+}
+
+const listPersonalExpenses = `-- name: ListPersonalExpenses :many
+SELECT id, user_id, category_id, account_id, merchant_id, merchant, amount, currency, description, notes, expense_date,
+    group_expense_id, created_at, updated_at, original_amount, original_currency, fx_rate
+FROM personal_expenses
+WHERE user_id = $1
+  AND ($2::uuid IS NULL OR category_id = $2)
+  AND ($3::uuid IS NULL OR account_id = $3)
+  AND ($4::uuid IS NULL OR merchant_id = $4)
+  AND ($5::timestamptz IS NULL OR expense_date >= $5)
+  AND ($6::timestamptz IS NULL OR expense_date < $6)
+ORDER BY
+    CASE WHEN $7::text = 'amount' AND NOT $8::bool THEN amount END ASC,
+    CASE WHEN $7::text = 'amount' AND $8::bool THEN amount END DESC,
+    CASE WHEN $7::text = 'expense_date' AND NOT $8::bool THEN expense_date END ASC,
+    CASE WHEN $7::text = 'expense_date' AND $8::bool THEN expense_date END DESC,
+    CASE WHEN NOT $8::bool THEN created_at END ASC,
+    CASE WHEN $8::bool THEN created_at END DESC,
+    CASE WHEN NOT $8::bool THEN id END ASC,
+    CASE WHEN $8::bool THEN id END DESC
+LIMIT $10::bigint OFFSET $9::bigint
+`
+
+type ListPersonalExpensesParams struct {
+	UserID     uuid.UUID
+	CategoryID *uuid.UUID
+	AccountID  *uuid.UUID
+	MerchantID *uuid.UUID
+	StartDate  *time.Time
+	EndDate    *time.Time
+	SortColumn string
+	Descending bool
+	RowOffset  int64
+	RowLimit   int64
+}
+
+type ListPersonalExpensesRow struct {
+	ID               uuid.UUID
+	UserID           uuid.UUID
+	CategoryID       *uuid.UUID
+	AccountID        *uuid.UUID
+	MerchantID       *uuid.UUID
+	Merchant         *string
+	Amount           decimal.Decimal
+	Currency         string
+	Description      *string
+	Notes            *string
+	ExpenseDate      time.Time
+	GroupExpenseID   *uuid.UUID
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+	OriginalAmount   *decimal.Decimal
+	OriginalCurrency *string
+	FXRate           *decimal.Decimal
+}
+
+// sort_column is amount, expense_date or created_at. Ties are broken by
+// creation time and id, in the same direction, so pages stay stable.
+func (q *Queries) ListPersonalExpenses(ctx context.Context, arg ListPersonalExpensesParams) ([]ListPersonalExpensesRow, error) {
+	rows, err := q.db.Query(ctx, listPersonalExpenses,
+		arg.UserID,
+		arg.CategoryID,
+		arg.AccountID,
+		arg.MerchantID,
+		arg.StartDate,
+		arg.EndDate,
+		arg.SortColumn,
+		arg.Descending,
+		arg.RowOffset,
+		arg.RowLimit,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []ListPersonalExpensesRow
+	for rows.Next() {
+		var i ListPersonalExpensesRow
+		if err := rows.Scan(
+			&i.ID,
+			&i.UserID,
+			&i.CategoryID,
+			&i.AccountID,
+			&i.MerchantID,
+			&i.Merchant,
+			&i.Amount,
+			&i.Currency,
+			&i.Description,
+			&i.Notes,
+			&i.ExpenseDate,
+			&i.GroupExpenseID,
+			&i.CreatedAt,
+			&i.UpdatedAt,
+			&i.OriginalAmount,
+			&i.OriginalCurrency,
+			&i.FXRate,
+		); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const personalExpenseStats = `-- name: PersonalExpenseStats :many
+SELECT date_trunc($1::text, expense_date)::timestamptz AS bucket,
+    SUM(amount)::numeric AS total_amount, COUNT(*) AS expense_count
+FROM personal_expenses
+WHERE user_id = $2 AND currency = $3
+  AND ($4::timestamptz IS NULL OR expense_date >= $4)
+  AND ($5::timestamptz IS NULL OR expense_date < $5)
+GROUP BY bucket
+ORDER BY bucket
+`
+
+type PersonalExpenseStatsParams struct {
+	GroupBy   string
+	UserID    uuid.UUID
+	Currency  string
+	StartDate *time.Time
+	EndDate   *time.Time
+}
+
+type PersonalExpenseStatsRow struct {
+	Bucket       time.Time
+	TotalAmount  decimal.Decimal
+	ExpenseCount int64
+}
+
+// Spending in currency summed per group_by period (day, week or month); a
+// null date matches everything
+func (q *Queries) PersonalExpenseStats(ctx context.Context, arg PersonalExpenseStatsParams) ([]PersonalExpenseStatsRow, error) {
+	rows, err := q.db.Query(ctx, personalExpenseStats,
+		arg.GroupBy,
+		arg.UserID,
+		arg.Currency,
+		arg.StartDate,
+		arg.EndDate,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var items []PersonalExpenseStatsRow
+	for rows.Next() {
+		var i PersonalExpenseStatsRow
+		if err := rows.Scan(&i.Bucket, &i.TotalAmount, &i.ExpenseCount); err != nil {
+			return nil, err
+		}
+		items = append(items, i)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return items, nil
+}
+
+const updatePersonalExpense = `-- name: UpdatePersonalExpense :one
+UPDATE personal_expenses SET
+    category_id = COALESCE($1, category_id),
+    account_id = COALESCE($2, account_id),
+    merchant_id = CASE WHEN $3::bool THEN $4 ELSE merchant_id END,
+    merchant = CASE WHEN $3::bool THEN $5 ELSE merchant END,
+    amount = CASE WHEN $6::bool THEN $7 ELSE amount END,
+    currency = CASE WHEN $6::bool THEN $8 ELSE currency END,
+    original_amount = CASE WHEN $6::bool THEN $9 ELSE original_amount END,
+    original_currency = CASE WHEN $6::bool THEN $10 ELSE original_currency END,
+    fx_rate = CASE WHEN $6::bool THEN $11 ELSE fx_rate END,
+    description = COALESCE($12, description),
+    notes = COALESCE($13, notes),
+    expense_date = COALESCE($14, expense_date),
+    updated_at = NOW()
+WHERE id = $15
+RETURNING id, user_id, category_id, account_id, merchant_id, merchant, amount, currency, description, notes, expense_date,
+    group_expense_id, created_at, updated_at, original_amount, original_currency, fx_rate
+`
+
+type UpdatePersonalExpenseParams struct {
+	CategoryID       *uuid.UUID
+	AccountID        *uuid.UUID
+	SetMerchant      bool
+	MerchantID       *uuid.UUID
+	Merchant         *string
+	SetAmount        bool
+	Amount           *decimal.Decimal
+	Currency         *string
+	OriginalAmount   *decimal.Decimal
+	OriginalCurrency *string
+	FXRate           *decimal.Decimal
+	Description      *string
+	Notes            *string
+	ExpenseDate      *time.Time
+	ID               uuid.UUID
+}
+
+type UpdatePersonalExpenseRow struct {
+	ID               uuid.UUID
+	UserID           uuid.UUID
+	CategoryID       *uuid.UUID
+	AccountID        *uuid.UUID
+	MerchantID       *uuid.UUID
+	Merchant         *string
+	Amount           decimal.Decimal
+	Currency         string
+	Description      *string
+	Notes            *string
+	ExpenseDate      time.Time
+	GroupExpenseID   *uuid.UUID
+	CreatedAt        time.Time
+	UpdatedAt        time.Time
+	OriginalAmount   *decimal.Decimal
+	OriginalCurrency *string
+	FXRate           *decimal.Decimal
+}
+
+// A null argument leaves its column alone. The merchant can be cleared and
+// the amount columns change together, so they have their own flags.
+func (q *Queries) UpdatePersonalExpense(ctx context.Context, arg UpdatePersonalExpenseParams) (UpdatePersonalExpenseRow, error) {
+	row := q.db.QueryRow(ctx, updatePersonalExpense,
+		arg.CategoryID,
+		arg.AccountID,
+		arg.SetMerchant,
+		arg.MerchantID,
+		arg.Merchant,
+		arg.SetAmount,
+		arg.Amount,
+		arg.Currency,
+		arg.OriginalAmount,
+		arg.OriginalCurrency,
+		arg.FXRate,
+		arg.Description,
+		arg.Notes,
+		arg.ExpenseDate,
+		arg.ID,
+	)
+	var i UpdatePersonalExpenseRow
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.CategoryID,
+		&i.AccountID,
+		&i.MerchantID,
+		&i.Merchant,
+		&i.Amount,
+		&i.Currency,
+		&i.Description,
+		&i.Notes,
+		&i.ExpenseDate,
+		&i.GroupExpenseID,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+		&i.OriginalAmount,
+		&i.OriginalCurrency,
+		&i.FXRate,
+	)
+	return i, err
+}
@@ -0,0 +1,88 @@
+// Code generated by sqlc. DO NOT EDIT.
+// versions:
+//   sqlc v1.29.0
+// source: recurring.sql
+
+package sqlc
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+const updateRecurringExpense = `-- name: UpdateRecurringExpense :one
+UPDATE recurring_expenses SET
+    category_id = COALESCE($1, category_id),
+    amount = COALESCE($2, amount),
+    description = COALESCE($3, description),
+    active = COALESCE($4, active),
+    auto_record = COALESCE($5, auto_record),
+    next_due_date = COALESCE($6, next_due_date),
+    updated_at = NOW()
+WHERE id = $7
+RETURNING id, user_id, category_id, merchant_id, merchant, amount, currency, description, frequency,
+    start_date, next_due_date, active, auto_record, created_at, updated_at
+`
+
+type UpdateRecurringExpenseParams struct {
+	CategoryID  *uuid.UUID
+	Amount      *decimal.Decimal
+	Description *string
+	Active      *bool
+	AutoRecord  *bool
+	NextDueDate *time.Time
+	ID          uuid.UUID
+}
+
+type UpdateRecurringExpenseRow struct {
+	ID          uuid.UUID
+	UserID      uuid.UUID
+	CategoryID  *uuid.UUID
+	MerchantID  *uuid.UUID
+	Merchant    *string
+	Amount      decimal.Decimal
+	Currency    string
+	Description *string
+	Frequency   string
+	StartDate   time.Time
+	NextDueDate time.Time
+	Active      bool
+	AutoRecord  bool
+	CreatedAt   time.Time
+	UpdatedAt   time.Time
+}
+
+// A null argument leaves its column alone
+func (q *Queries) UpdateRecurringExpense(ctx context.Context, arg UpdateRecurringExpenseParams) (UpdateRecurringExpenseRow, error) {
+	row := q.db.QueryRow(ctx, updateRecurringExpense,
+		arg.CategoryID,
+		arg.Amount,
+		arg.Description,
+		arg.Active,
+		arg.AutoRecord,
+		arg.NextDueDate,
+		arg.ID,
+	)
+	var i UpdateRecurringExpenseRow
+	err := row.Scan(
+		&i.ID,
+		&i.UserID,
+		&i.CategoryID,
+		&i.MerchantID,
+		&i.Merchant,
+		&i.Amount,
+		&i.Currency,
+		&i.Description,
+		&i.Frequency,
+		&i.StartDate,
+		&i.NextDueDate,
+		&i.Active,
+		&i.AutoRecord,
+		&i.CreatedAt,
+		&i.UpdatedAt,
+	)
+	return i, err
+}
@@ -0,0 +1,113 @@
+package db
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Migration is a migration file pair, such as 000012_tags.up.sql and its
+// down file
+type Migration struct {
+	Version uint
+	Name    string
+}
+
+func (m Migration) String() string {
+	return fmt.Sprintf("%06d_%s", m.Version, m.Name)
+}
+
+// Migrations lists the migrations in migrationsPath by version
+func Migrations(migrationsPath string) ([]Migration, error) {
+	entries, err := os.ReadDir(migrationsPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+	var migrations []Migration
+	for _, entry := range entries {
+		base, found := strings.CutSuffix(entry.Name(), ".up.sql")
+		if !found {
+			continue
+		}
+		prefix, name, found := strings.Cut(base, "_")
+		if !found {
+			continue
+		}
+		version, err := strconv.ParseUint(prefix, 10, 64)
+		if err != nil {
+			continue
+		}
+		migrations = append(migrations, Migration{Version: uint(version), Name: name})
+	}
+	slices.SortFunc(migrations, func(a, b Migration) int { return cmp.Compare(a.Version, b.Version) })
+	return migrations, nil
+}
+
+// LatestMigration returns the highest version among the migration files in
+// migrationsPath
+func LatestMigration(migrationsPath string) (uint, error) {
+	migrations, err := Migrations(migrationsPath)
+	if err != nil || len(migrations) == 0 {
+		return 0, err
+	}
+	return migrations[len(migrations)-1].Version, nil
+}
+
+// Pending returns the migrations after version, in the order Up applies them
+func Pending(migrations []Migration, version uint) []Migration {
+	var pending []Migration
+	for _, m := range migrations {
+		if m.Version > version {
+			pending = append(pending, m)
+		}
+	}
+	return pending
+}
+
+// RollBack returns the migrations Down(steps) undoes from version, newest
+// first. There are fewer than steps when fewer are applied.
+func RollBack(migrations []Migration, version uint, steps int) []Migration {
+	var undo []Migration
+	for i := len(migrations) - 1; i >= 0 && len(undo) < steps; i-- {
+		if migrations[i].Version <= version {
+			undo = append(undo, migrations[i])
+		}
+	}
+	return undo
+}
+
+// MigrationStatus is the schema version the database is at
+type MigrationStatus struct {
+	Version  uint `json:"version"`
+	Expected uint `json:"expected"`
+	Dirty    bool `json:"dirty"`
+}
+
+// CheckMigrations returns a readiness check that the database has every
+// migration up to expected applied, and none left half done
+func (db *DB) CheckMigrations(expected uint) func(ctx context.Context) (any, error) {
+	return func(ctx context.Context) (any, error) {
+		status := MigrationStatus{Expected: expected}
+		err := db.Pool.QueryRow(ctx, `SELECT version, dirty FROM schema_migrations LIMIT 1`).
+			Scan(&status.Version, &status.Dirty)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read schema version: %w", err)
+		}
+		switch {
+		case status.Dirty:
+			return status, fmt.Errorf("migration %d failed part way", status.Version)
+		case status.Version < expected:
+			return status, fmt.Errorf("schema is at %d, want %d", status.Version, expected)
+		}
+		return status, nil
+	}
+}
+
+// Check is a readiness check that the database answers
+func (db *DB) Check(ctx context.Context) (any, error) {
+	return nil, db.Pool.Ping(ctx)
+}
@@ -0,0 +1,43 @@
+package db
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLatestMigration(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"000001_init.up.sql", "000001_init.down.sql", "000012_tags.up.sql", "000013_next.down.sql", "README.md"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), nil, 0o644))
+	}
+	latest, err := LatestMigration(dir)
+	require.NoError(t, err)
+	assert.Equal(t, uint(12), latest)
+
+	_, err = LatestMigration(filepath.Join(dir, "missing"))
+	assert.Error(t, err)
+}
+
+func TestMigrationPlans(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"000002_tags.up.sql", "000001_init.up.sql", "000001_init.down.sql", "000003_group_balances.up.sql"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), nil, 0o644))
+	}
+	migrations, err := Migrations(dir)
+	require.NoError(t, err)
+	initial, tags, balances := Migration{1, "init"}, Migration{2, "tags"}, Migration{3, "group_balances"}
+	assert.Equal(t, []Migration{initial, tags, balances}, migrations)
+	assert.Equal(t, "000003_group_balances", balances.String())
+
+	assert.Equal(t, []Migration{initial, tags, balances}, Pending(migrations, 0))
+	assert.Equal(t, []Migration{balances}, Pending(migrations, 2))
+	assert.Empty(t, Pending(migrations, 3))
+
+	assert.Equal(t, []Migration{tags}, RollBack(migrations, 2, 1))
+	assert.Equal(t, []Migration{balances, tags, initial}, RollBack(migrations, 3, 5))
+	assert.Empty(t, RollBack(migrations, 0, 1))
+}
@@ -0,0 +1,111 @@
+package db
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/requestid"
+)
+
+// queryTracer logs failed queries at error level, ones slower than
+// slowQuery at warn and every other one at debug, with the ID of the
+// request that ran them. Arguments are logged by type only since their
+// values hold user data. A zero slowQuery turns the warnings off.
+//
+// It also gives each query a deadline of timeout, when set. pgx runs the
+// query, and reads its rows, with the context TraceQueryStart returns.
+type queryTracer struct {
+	timeout   time.Duration
+	slowQuery time.Duration
+}
+
+type queryStart struct {
+	sql    string
+	args   []any
+	start  time.Time
+	cancel context.CancelFunc
+}
+
+type queryStartKey struct{}
+
+func (t queryTracer) TraceQueryStart(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	q := queryStart{sql: data.SQL, args: data.Args, start: time.Now()}
+	if t.timeout > 0 {
+		ctx, q.cancel = context.WithTimeout(ctx, t.timeout)
+	}
+	return context.WithValue(ctx, queryStartKey{}, q)
+}
+
+func (t queryTracer) TraceQueryEnd(ctx context.Context, _ *pgx.Conn, data pgx.TraceQueryEndData) {
+	q, ok := ctx.Value(queryStartKey{}).(queryStart)
+	if !ok {
+		return
+	}
+	if q.cancel != nil {
+		defer q.cancel()
+	}
+	duration := time.Since(q.start)
+	logger := requestid.Logger(ctx)
+	attrs := []slog.Attr{
+		slog.String("sql", compact(q.sql)),
+		slog.Float64("duration_ms", float64(duration.Microseconds())/1000),
+		slog.Any("args", redact(q.args)),
+	}
+	switch {
+	case data.Err != nil:
+		attrs = append(attrs, slog.String("error", data.Err.Error()))
+		logger.LogAttrs(ctx, slog.LevelError, "query failed", attrs...)
+	case t.slowQuery > 0 && duration >= t.slowQuery:
+		logger.LogAttrs(ctx, slog.LevelWarn, "slow query", attrs...)
+	default:
+		logger.LogAttrs(ctx, slog.LevelDebug, "query", attrs...)
+	}
+}
+
+// compact puts a query on one line
+func compact(sql string) string {
+	return strings.Join(strings.Fields(sql), " ")
+}
+
+// redact describes query arguments without their values: each becomes its
+// type, or null. Named arguments keep their names.
+func redact(args []any) []string {
+	if len(args) == 1 {
+		if named, ok := args[0].(pgx.NamedArgs); ok {
+			names := make([]string, 0, len(named))
+			for name := range named {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			redacted := make([]string, len(names))
+			for i, name := range names {
+				redacted[i] = name + "=" + argType(named[name])
+			}
+			return redacted
+		}
+	}
+
+	redacted := make([]string, len(args))
+	for i, arg := range args {
+		redacted[i] = argType(arg)
+	}
+	return redacted
+}
+
+func argType(arg any) string {
+	if arg == nil {
+		return "null"
+	}
+	v := reflect.ValueOf(arg)
+	if v.Kind() == reflect.Pointer && v.IsNil() {
+		return "null"
+	}
+	return fmt.Sprintf("%T", arg)
+}
@@ -0,0 +1,66 @@
+package db
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/requestid"
+)
+
+func TestRedact(t *testing.T) {
+	var name *string
+	assert.Equal(t, []string{"uuid.UUID", "string", "null", "null"},
+		redact([]any{uuid.New(), "alice@example.com", nil, name}))
+	assert.Equal(t, []string{"id=uuid.UUID", "name=null"},
+		redact([]any{pgx.NamedArgs{"name": name, "id": uuid.New()}}))
+}
+
+func TestQueryTracer(t *testing.T) {
+	var buf bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: slog.LevelWarn})))
+
+	trace := func(tracer queryTracer, sleep time.Duration, err error) map[string]any {
+		buf.Reset()
+		ctx := requestid.With(context.Background(), "req-1")
+		ctx = tracer.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{
+			SQL:  "SELECT *\n\t FROM users WHERE email = $1",
+			Args: []any{"alice@example.com"},
+		})
+		time.Sleep(sleep)
+		tracer.TraceQueryEnd(ctx, nil, pgx.TraceQueryEndData{Err: err})
+		if buf.Len() == 0 {
+			return nil
+		}
+		var entry map[string]any
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+		return entry
+	}
+
+	entry := trace(queryTracer{slowQuery: time.Millisecond}, 2*time.Millisecond, nil)
+	require.NotNil(t, entry)
+	assert.Equal(t, "slow query", entry["msg"])
+	assert.Equal(t, "req-1", entry["request_id"])
+	assert.Equal(t, "SELECT * FROM users WHERE email = $1", entry["sql"])
+	assert.Equal(t, []any{"string"}, entry["args"])
+	assert.NotContains(t, buf.String(), "alice@example.com")
+
+	// Fast queries, and slow ones with the threshold off, stay at debug
+	assert.Nil(t, trace(queryTracer{slowQuery: time.Hour}, 0, nil))
+	assert.Nil(t, trace(queryTracer{}, 2*time.Millisecond, nil))
+
+	entry = trace(queryTracer{}, 0, errors.New("boom"))
+	require.NotNil(t, entry)
+	assert.Equal(t, "query failed", entry["msg"])
+	assert.Equal(t, "boom", entry["error"])
+}
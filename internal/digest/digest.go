@@ -0,0 +1,193 @@
+package digest
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/budget"
+	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+	"github.com/yanonymousV2/finance-manager-backend/internal/email"
+	"github.com/yanonymousV2/finance-manager-backend/internal/group"
+	"github.com/yanonymousV2/finance-manager-backend/internal/helpers"
+)
+
+// Digest frequencies
+const (
+	FrequencyOff     = "off"
+	FrequencyWeekly  = "weekly"
+	FrequencyMonthly = "monthly"
+)
+
+// biggestExpenses is how many expenses a digest lists
+const biggestExpenses = 5
+
+type Expense struct {
+	ID           uuid.UUID       `json:"id"`
+	Description  *string         `json:"description"`
+	Merchant     *string         `json:"merchant"`
+	CategoryName *string         `json:"category_name"`
+	Amount       decimal.Decimal `json:"amount"`
+	ExpenseDate  time.Time       `json:"expense_date"`
+}
+
+// BudgetStatus is spending in the digest period against the budget covering it
+type BudgetStatus struct {
+	Amount    decimal.Decimal `json:"amount"`
+	Spent     decimal.Decimal `json:"spent"`
+	Remaining decimal.Decimal `json:"remaining"`
+	Exceeded  bool            `json:"exceeded"`
+}
+
+// Digest summarizes a user's spending over one week or month. EndDate is the
+// last day covered.
+type Digest struct {
+	Frequency       string              `json:"frequency"`
+	StartDate       time.Time           `json:"start_date"`
+	EndDate         time.Time           `json:"end_date"`
+	Currency        string              `json:"currency"`
+	TotalSpent      decimal.Decimal     `json:"total_spent"`
+	ExpenseCount    int                 `json:"expense_count"`
+	Budget          *BudgetStatus       `json:"budget"`
+	BiggestExpenses []Expense           `json:"biggest_expenses"`
+	GroupBalances   []group.UserBalance `json:"group_balances"`
+}
+
+// window returns the last complete week (Monday to Sunday) or calendar month
+// before now, as a start and an exclusive end
+func window(frequency string, now time.Time) (start, end time.Time) {
+	now = now.UTC()
+	if frequency == FrequencyWeekly {
+		today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+		end = today.AddDate(0, 0, -((int(today.Weekday()) + 6) % 7))
+		return end.AddDate(0, 0, -7), end
+	}
+	end = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return end.AddDate(0, -1, 0), end
+}
+
+// Build collects the digest for the last complete week or month before now
+func Build(ctx context.Context, db *db.DB, userID uuid.UUID, frequency string, now time.Time) (Digest, error) {
+	start, end := window(frequency, now)
+	d := Digest{Frequency: frequency, StartDate: start, EndDate: end.AddDate(0, 0, -1), BiggestExpenses: []Expense{}}
+
+	currency, err := helpers.GetUserCurrency(ctx, db, userID)
+	if err != nil {
+		return d, fmt.Errorf("failed to get default currency: %w", err)
+	}
+	d.Currency = currency
+
+	err = db.Pool.QueryRow(ctx,
+		`SELECT COALESCE(SUM(amount), 0), COUNT(*) FROM personal_expenses
+		 WHERE user_id = $1 AND currency = $2 AND expense_date >= $3 AND expense_date < $4`,
+		userID, currency, start, end).Scan(&d.TotalSpent, &d.ExpenseCount)
+	if err != nil {
+		return d, fmt.Errorf("failed to get total spending: %w", err)
+	}
+
+	rows, err := db.Pool.Query(ctx,
+		`SELECT pe.id, pe.description, COALESCE(m.name, pe.merchant), ec.name, pe.amount, pe.expense_date
+		 FROM personal_expenses pe
+		 LEFT JOIN merchants m ON pe.merchant_id = m.id
+		 LEFT JOIN expense_categories ec ON pe.category_id = ec.id
+		 WHERE pe.user_id = $1 AND pe.currency = $2 AND pe.expense_date >= $3 AND pe.expense_date < $4
+		 ORDER BY pe.amount DESC, pe.expense_date DESC
+		 LIMIT $5`,
+		userID, currency, start, end, biggestExpenses)
+	if err != nil {
+		return d, fmt.Errorf("failed to get biggest expenses: %w", err)
+	}
+	for rows.Next() {
+		var e Expense
+		if err := rows.Scan(&e.ID, &e.Description, &e.Merchant, &e.CategoryName, &e.Amount, &e.ExpenseDate); err != nil {
+			rows.Close()
+			return d, fmt.Errorf("failed to scan expense: %w", err)
+		}
+		d.BiggestExpenses = append(d.BiggestExpenses, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return d, fmt.Errorf("failed to get biggest expenses: %w", err)
+	}
+
+	period := budget.PeriodMonthly
+	if frequency == FrequencyWeekly {
+		period = budget.PeriodWeekly
+	}
+	b, err := budget.Find(ctx, db, userID, period, start)
+	if err != nil {
+		return d, fmt.Errorf("failed to get budget: %w", err)
+	}
+	if b != nil {
+		remaining := b.Amount.Sub(d.TotalSpent)
+		d.Budget = &BudgetStatus{Amount: b.Amount, Spent: d.TotalSpent, Remaining: remaining, Exceeded: remaining.IsNegative()}
+	}
+
+	d.GroupBalances, err = group.UserBalances(ctx, db, userID)
+	if err != nil {
+		return d, err
+	}
+	if d.GroupBalances == nil {
+		d.GroupBalances = []group.UserBalance{}
+	}
+	return d, nil
+}
+
+func expenseLabel(e Expense) string {
+	switch {
+	case e.Description != nil && *e.Description != "":
+		return *e.Description
+	case e.Merchant != nil && *e.Merchant != "":
+		return *e.Merchant
+	case e.CategoryName != nil:
+		return *e.CategoryName
+	default:
+		return "Expense"
+	}
+}
+
+// render formats the digest as an email, without the recipient
+func render(d Digest, unsubscribeURL string) (email.Message, error) {
+	money := func(amount decimal.Decimal, currency string) string {
+		return amount.StringFixed(2) + " " + currency
+	}
+
+	data := email.DigestData{
+		Weekly:         d.Frequency == FrequencyWeekly,
+		Period:         d.StartDate.Format("January 2006"),
+		TotalSpent:     money(d.TotalSpent, d.Currency),
+		ExpenseCount:   d.ExpenseCount,
+		UnsubscribeURL: unsubscribeURL,
+	}
+	if data.Weekly {
+		data.Period = d.StartDate.Format("Jan 2") + " to " + d.EndDate.Format("Jan 2, 2006")
+	}
+	if d.Budget != nil {
+		data.Budget = &email.DigestBudget{
+			Exceeded:   d.Budget.Exceeded,
+			Amount:     money(d.Budget.Amount, d.Currency),
+			Difference: money(d.Budget.Remaining.Abs(), d.Currency),
+		}
+	}
+	for _, e := range d.BiggestExpenses {
+		data.Expenses = append(data.Expenses, email.DigestExpense{
+			Date:   e.ExpenseDate.Format("Jan 2"),
+			Label:  expenseLabel(e),
+			Amount: money(e.Amount, d.Currency),
+		})
+	}
+	for _, g := range d.GroupBalances {
+		status := "settled up"
+		switch {
+		case g.Balance.IsPositive():
+			status = "you are owed " + money(g.Balance, g.Currency)
+		case g.Balance.IsNegative():
+			status = "you owe " + money(g.Balance.Neg(), g.Currency)
+		}
+		data.Groups = append(data.Groups, email.DigestGroup{Name: g.GroupName, Status: status})
+	}
+	return email.Render(email.TemplateDigest, data)
+}
@@ -0,0 +1,102 @@
+package digest
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/group"
+)
+
+func TestWindow(t *testing.T) {
+	// Wednesday
+	now := time.Date(2024, 3, 6, 15, 0, 0, 0, time.UTC)
+
+	start, end := window(FrequencyWeekly, now)
+	assert.Equal(t, time.Date(2024, 2, 26, 0, 0, 0, 0, time.UTC), start)
+	assert.Equal(t, time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC), end)
+
+	// On a Monday the week just ended is the one covered
+	start, end = window(FrequencyWeekly, time.Date(2024, 3, 4, 0, 30, 0, 0, time.UTC))
+	assert.Equal(t, time.Date(2024, 2, 26, 0, 0, 0, 0, time.UTC), start)
+	assert.Equal(t, time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC), end)
+
+	start, end = window(FrequencyMonthly, now)
+	assert.Equal(t, time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC), start)
+	assert.Equal(t, time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), end)
+
+	start, _ = window(FrequencyMonthly, time.Date(2024, 1, 15, 0, 0, 0, 0, time.UTC))
+	assert.Equal(t, time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC), start)
+}
+
+func TestRender(t *testing.T) {
+	d := decimal.RequireFromString
+	groceries := "Groceries"
+	cafe := "Corner Cafe"
+
+	digest := Digest{
+		Frequency:    FrequencyWeekly,
+		StartDate:    time.Date(2024, 2, 26, 0, 0, 0, 0, time.UTC),
+		EndDate:      time.Date(2024, 3, 3, 0, 0, 0, 0, time.UTC),
+		Currency:     "USD",
+		TotalSpent:   d("230.5"),
+		ExpenseCount: 2,
+		Budget:       &BudgetStatus{Amount: d("200"), Spent: d("230.5"), Remaining: d("-30.5"), Exceeded: true},
+		BiggestExpenses: []Expense{
+			{Description: &groceries, Amount: d("200"), ExpenseDate: time.Date(2024, 2, 27, 0, 0, 0, 0, time.UTC)},
+			{Merchant: &cafe, Amount: d("30.5"), ExpenseDate: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)},
+		},
+		GroupBalances: []group.UserBalance{
+			{GroupName: "Flat", Balance: d("-12"), Currency: "EUR"},
+			{GroupName: "Trip", Balance: decimal.Zero, Currency: "USD"},
+		},
+	}
+
+	msg, err := render(digest, "https://example.com/digest/unsubscribe?token=abc")
+	require.NoError(t, err)
+	assert.Equal(t, "Your weekly spending summary: Feb 26 to Mar 3, 2024", msg.Subject)
+	assert.Equal(t,
+		"Total spent: 230.50 USD across 2 expenses\n"+
+			"Budget: 30.50 USD over your 200.00 USD budget\n"+
+			"\nBiggest expenses:\n"+
+			"  Feb 27  Groceries  200.00 USD\n"+
+			"  Mar 1  Corner Cafe  30.50 USD\n"+
+			"\nGroup balances:\n"+
+			"  Flat: you owe 12.00 EUR\n"+
+			"  Trip: settled up\n"+
+			"\nTo stop these emails, open https://example.com/digest/unsubscribe?token=abc\n",
+		msg.Body)
+	assert.Equal(t, "digest", msg.Template)
+	assert.Contains(t, msg.HTML, `<a href="https://example.com/digest/unsubscribe?token=abc">unsubscribe</a>`)
+	assert.Contains(t, msg.HTML, "Corner Cafe")
+
+	digest.Frequency = FrequencyMonthly
+	digest.StartDate = time.Date(2024, 2, 1, 0, 0, 0, 0, time.UTC)
+	msg, err = render(digest, "")
+	require.NoError(t, err)
+	assert.Equal(t, "Your monthly spending summary: February 2024", msg.Subject)
+}
+
+func TestConfirmUnsubscribe(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	router.GET("/digest/unsubscribe", ConfirmUnsubscribe)
+
+	token := uuid.New()
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/digest/unsubscribe?token="+token.String(), nil))
+	require.Equal(t, 200, w.Code)
+	assert.Equal(t, "text/html; charset=utf-8", w.Header().Get("Content-Type"))
+	// Opening the link only shows a form that posts back to it
+	assert.Contains(t, w.Body.String(), `<form method="post" action="unsubscribe?token=`+token.String()+`">`)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/digest/unsubscribe?token=not-a-token", nil))
+	assert.Equal(t, 400, w.Code)
+}
@@ -0,0 +1,172 @@
+package digest
+
+import (
+	"bytes"
+	"errors"
+	"html/template"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+	"github.com/yanonymousV2/finance-manager-backend/internal/middleware"
+	"github.com/yanonymousV2/finance-manager-backend/internal/validation"
+)
+
+type Preferences struct {
+	Frequency  string     `json:"frequency"`
+	LastSentAt *time.Time `json:"last_sent_at,omitempty"`
+}
+
+type UpdatePreferencesRequest struct {
+	Frequency string `json:"frequency" validate:"required,oneof=off weekly monthly"`
+}
+
+// GetPreferences returns how often the authenticated user gets email digests
+func GetPreferences(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	prefs := Preferences{Frequency: FrequencyOff}
+	err := db.Pool.QueryRow(c.Request.Context(),
+		"SELECT frequency, last_sent_at FROM digest_preferences WHERE user_id = $1", userID).
+		Scan(&prefs.Frequency, &prefs.LastSentAt)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(500, gin.H{"error": "failed to get digest preferences"})
+		return
+	}
+
+	c.JSON(200, prefs)
+}
+
+// UpdatePreferences sets how often the authenticated user gets email
+// digests. Changing the frequency starts counting from now, so the first
+// digest covers the first full week or month after it.
+func UpdatePreferences(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req UpdatePreferencesRequest
+	if !validation.Bind(c, &req) {
+		return
+	}
+
+	var prefs Preferences
+	err := db.Pool.QueryRow(c.Request.Context(),
+		`INSERT INTO digest_preferences (user_id, frequency, last_sent_at) VALUES ($1, $2, NOW())
+		 ON CONFLICT (user_id) DO UPDATE SET
+		     frequency = EXCLUDED.frequency,
+		     last_sent_at = CASE WHEN digest_preferences.frequency = EXCLUDED.frequency
+		                         THEN digest_preferences.last_sent_at ELSE NOW() END,
+		     updated_at = NOW()
+		 RETURNING frequency, last_sent_at`,
+		userID, req.Frequency).Scan(&prefs.Frequency, &prefs.LastSentAt)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to update digest preferences"})
+		return
+	}
+
+	c.JSON(200, prefs)
+}
+
+// unsubscribePage asks the reader to confirm, so link scanners and
+// prefetchers that open the link don't unsubscribe anyone. The form posts
+// back to the same link.
+var unsubscribePage = template.Must(template.New("unsubscribe").Parse(`<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><meta name="viewport" content="width=device-width, initial-scale=1"><title>Unsubscribe</title></head>
+<body>
+<h1>Unsubscribe from email summaries?</h1>
+<p>You'll stop getting weekly and monthly summaries. You can turn them back on in the app's settings.</p>
+<form method="post" action="unsubscribe?token={{.}}"><button type="submit">Unsubscribe</button></form>
+</body>
+</html>
+`))
+
+const unsubscribedPage = `<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><meta name="viewport" content="width=device-width, initial-scale=1"><title>Unsubscribed</title></head>
+<body>
+<h1>You're unsubscribed</h1>
+<p>You won't get email summaries any more.</p>
+</body>
+</html>
+`
+
+// ConfirmUnsubscribe shows the page the unsubscribe link in a digest opens.
+// It changes nothing; unsubscribing takes the POST its form sends.
+func ConfirmUnsubscribe(c *gin.Context) {
+	token, err := uuid.Parse(c.Query("token"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid token"})
+		return
+	}
+
+	var page bytes.Buffer
+	if err := unsubscribePage.Execute(&page, token); err != nil {
+		c.JSON(500, gin.H{"error": "failed to render page"})
+		return
+	}
+	c.Data(200, "text/html; charset=utf-8", page.Bytes())
+}
+
+// Unsubscribe turns digests off for the user the token in the email link
+// belongs to. It needs no login and also serves one-click unsubscribe from
+// mail clients (RFC 8058). Browsers posting the confirmation form get a
+// page back, everything else JSON.
+func Unsubscribe(c *gin.Context, db *db.DB) {
+	token, err := uuid.Parse(c.Query("token"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid token"})
+		return
+	}
+
+	tag, err := db.Pool.Exec(c.Request.Context(),
+		"UPDATE digest_preferences SET frequency = 'off', updated_at = NOW() WHERE unsubscribe_token = $1", token)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to unsubscribe"})
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		c.JSON(404, gin.H{"error": "subscription not found"})
+		return
+	}
+
+	if c.NegotiateFormat(gin.MIMEJSON, gin.MIMEHTML) == gin.MIMEHTML {
+		c.Data(200, "text/html; charset=utf-8", []byte(unsubscribedPage))
+		return
+	}
+	c.JSON(200, gin.H{"message": "unsubscribed from email summaries"})
+}
+
+// PreviewDigest returns the digest the authenticated user would get now for
+// the last complete week or month
+func PreviewDigest(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	frequency := c.DefaultQuery("frequency", FrequencyWeekly)
+	if frequency != FrequencyWeekly && frequency != FrequencyMonthly {
+		c.JSON(400, gin.H{"error": "frequency must be weekly or monthly"})
+		return
+	}
+
+	d, err := Build(c.Request.Context(), db, userID, frequency, time.Now())
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to build digest"})
+		return
+	}
+
+	c.JSON(200, d)
+}
@@ -0,0 +1,122 @@
+package digest
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+	"github.com/yanonymousV2/finance-manager-backend/internal/email"
+)
+
+// RunOnce sends the digests that are due now. The scheduler runs it hourly,
+// and it can be run by hand. On cancellation it stops between digests, never
+// half way through one.
+func RunOnce(ctx context.Context, db *db.DB, sender email.Sender, publicURL string) error {
+	return sendDue(ctx, db, sender, publicURL, time.Now())
+}
+
+type recipient struct {
+	userID     uuid.UUID
+	frequency  string
+	token      uuid.UUID
+	email      string
+	lastSentAt *time.Time
+}
+
+func unsubscribeURL(publicURL string, token uuid.UUID) string {
+	return publicURL + "/digest/unsubscribe?token=" + token.String()
+}
+
+// sendDue emails everyone who hasn't had a digest since their last week or
+// month ended
+func sendDue(ctx context.Context, db *db.DB, sender email.Sender, publicURL string, now time.Time) error {
+	_, weekEnd := window(FrequencyWeekly, now)
+	_, monthEnd := window(FrequencyMonthly, now)
+
+	rows, err := db.Pool.Query(ctx,
+		`SELECT dp.user_id, dp.frequency, dp.unsubscribe_token, u.email, dp.last_sent_at
+		 FROM digest_preferences dp
+		 JOIN users u ON u.id = dp.user_id
+		 WHERE u.email IS NOT NULL
+		   AND ((dp.frequency = 'weekly' AND (dp.last_sent_at IS NULL OR dp.last_sent_at < $1))
+		     OR (dp.frequency = 'monthly' AND (dp.last_sent_at IS NULL OR dp.last_sent_at < $2)))`,
+		weekEnd, monthEnd)
+	if err != nil {
+		return fmt.Errorf("failed to get due digests: %w", err)
+	}
+	var due []recipient
+	for rows.Next() {
+		var r recipient
+		if err := rows.Scan(&r.userID, &r.frequency, &r.token, &r.email, &r.lastSentAt); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan due digest: %w", err)
+		}
+		due = append(due, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to get due digests: %w", err)
+	}
+
+	for _, r := range due {
+		// Stop between digests on shutdown, never half way through one
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err := send(context.WithoutCancel(ctx), db, sender, publicURL, r, now); err != nil {
+			slog.Error("failed to send digest", "user_id", r.userID, "error", err)
+		}
+	}
+	return nil
+}
+
+// send claims and sends one digest. The claim only succeeds if last_sent_at
+// is unchanged, so two servers running the job never both send it; if
+// sending fails the claim is released and the next run retries.
+func send(ctx context.Context, db *db.DB, sender email.Sender, publicURL string, r recipient, now time.Time) error {
+	// Match what Postgres stores so the release below finds the claim
+	now = now.Truncate(time.Microsecond)
+	tag, err := db.Pool.Exec(ctx,
+		`UPDATE digest_preferences SET last_sent_at = $2
+		 WHERE user_id = $1 AND frequency = $3 AND last_sent_at IS NOT DISTINCT FROM $4`,
+		r.userID, now, r.frequency, r.lastSentAt)
+	if err != nil {
+		return fmt.Errorf("failed to claim digest: %w", err)
+	}
+	if tag.RowsAffected() == 0 {
+		return nil
+	}
+
+	err = deliver(ctx, db, sender, publicURL, r, now)
+	if err != nil {
+		if _, releaseErr := db.Pool.Exec(ctx,
+			"UPDATE digest_preferences SET last_sent_at = $3 WHERE user_id = $1 AND last_sent_at = $2",
+			r.userID, now, r.lastSentAt); releaseErr != nil {
+			slog.Error("failed to release digest claim", "user_id", r.userID, "error", releaseErr)
+		}
+	}
+	return err
+}
+
+func deliver(ctx context.Context, db *db.DB, sender email.Sender, publicURL string, r recipient, now time.Time) error {
+	d, err := Build(ctx, db, r.userID, r.frequency, now)
+	if err != nil {
+		return err
+	}
+
+	link := unsubscribeURL(publicURL, r.token)
+	msg, err := render(d, link)
+	if err != nil {
+		return err
+	}
+	msg.To = r.email
+	msg.Headers = map[string]string{
+		"List-Unsubscribe":      "<" + link + ">",
+		"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+	}
+	return sender.Send(ctx, msg)
+}
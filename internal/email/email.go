@@ -0,0 +1,53 @@
+package email
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/config"
+)
+
+// Message is an email with a plain text body and, optionally, an HTML
+// alternative. Headers are added to the standard ones, e.g.
+// List-Unsubscribe. Template names the template it was rendered from, if
+// any, for the send log.
+type Message struct {
+	To       string
+	Subject  string
+	Body     string
+	HTML     string
+	Headers  map[string]string
+	Template string
+}
+
+// Sender delivers email
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+// New returns the sender selected by cfg.EmailDriver
+func New(cfg *config.Config) (Sender, error) {
+	switch cfg.EmailDriver {
+	case "log":
+		return Log{}, nil
+	case "smtp":
+		if cfg.SMTPHost == "" {
+			return nil, errors.New("SMTP_HOST is required for smtp email")
+		}
+		return NewSMTP(cfg.SMTPHost, strconv.Itoa(cfg.SMTPPort), cfg.SMTPUsername, cfg.SMTPPassword, cfg.EmailFrom), nil
+	case "ses":
+		if cfg.SESAccessKey == "" || cfg.SESSecretKey == "" {
+			return nil, errors.New("SES_ACCESS_KEY and SES_SECRET_KEY are required for ses email")
+		}
+		return NewSES(cfg.SESEndpoint, cfg.SESRegion, cfg.SESAccessKey, cfg.SESSecretKey, cfg.EmailFrom), nil
+	case "sendgrid":
+		if cfg.SendGridAPIKey == "" {
+			return nil, errors.New("SENDGRID_API_KEY is required for sendgrid email")
+		}
+		return NewSendGrid(cfg.SendGridAPIKey, cfg.EmailFrom), nil
+	default:
+		return nil, fmt.Errorf("unknown email driver %q", cfg.EmailDriver)
+	}
+}
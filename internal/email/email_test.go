@@ -0,0 +1,106 @@
+package email
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRender(t *testing.T) {
+	data := map[string]any{
+		TemplateVerification:  VerificationData{Name: "Jane", URL: "https://example.com/verify?token=abc", Expires: 24 * time.Hour},
+		TemplatePasswordReset: PasswordResetData{URL: "https://example.com/reset?token=abc", Expires: 30 * time.Minute},
+		TemplateInvite:        InviteData{InviterName: "Jane", GroupName: "Flat <3", URL: "https://example.com/join?token=abc", Expires: 7 * 24 * time.Hour},
+		TemplateDigest:        DigestData{Weekly: true, Period: "Feb 26 to Mar 3, 2024", TotalSpent: "0.00 USD"},
+	}
+	for _, name := range Templates {
+		msg, err := Render(name, data[name])
+		require.NoError(t, err, name)
+		assert.NotEmpty(t, msg.Subject, name)
+		assert.NotEmpty(t, msg.Body, name)
+		assert.Contains(t, msg.HTML, "<!DOCTYPE html>", name)
+		assert.Equal(t, name, msg.Template)
+	}
+
+	msg, err := Render(TemplateVerification, data[TemplateVerification])
+	require.NoError(t, err)
+	assert.Equal(t, "Confirm your email address", msg.Subject)
+	assert.Contains(t, msg.Body, "Hi Jane,\n")
+	assert.Contains(t, msg.Body, "\nhttps://example.com/verify?token=abc\n")
+	assert.Contains(t, msg.Body, "expires in 24 hours")
+
+	msg, err = Render(TemplatePasswordReset, data[TemplatePasswordReset])
+	require.NoError(t, err)
+	assert.Contains(t, msg.Body, "Hi there,\n")
+	assert.Contains(t, msg.Body, "expires in 30 minutes")
+
+	// HTML is escaped, text isn't
+	msg, err = Render(TemplateInvite, data[TemplateInvite])
+	require.NoError(t, err)
+	assert.Equal(t, "Jane invited you to Flat <3", msg.Subject)
+	assert.Contains(t, msg.HTML, "Flat &lt;3")
+	assert.Contains(t, msg.Body, "expires in 7 days")
+
+	_, err = Render("welcome", nil)
+	assert.Error(t, err)
+	_, err = Render(TemplateInvite, VerificationData{})
+	assert.Error(t, err)
+}
+
+func TestFormatDuration(t *testing.T) {
+	assert.Equal(t, "1 hour", formatDuration(time.Hour))
+	assert.Equal(t, "24 hours", formatDuration(24*time.Hour))
+	assert.Equal(t, "2 days", formatDuration(48*time.Hour))
+	assert.Equal(t, "90 minutes", formatDuration(90*time.Minute))
+	assert.Equal(t, "1 minute", formatDuration(time.Minute))
+}
+
+func TestSES(t *testing.T) {
+	var got sesRequest
+	status := http.StatusOK
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v2/email/outbound-emails", r.URL.Path)
+		assert.Contains(t, r.Header.Get("Authorization"), "Credential=AKID/")
+		assert.Contains(t, r.Header.Get("Authorization"), "/eu-west-1/ses/aws4_request")
+		body, _ := io.ReadAll(r.Body)
+		assert.NoError(t, json.Unmarshal(body, &got))
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	s := NewSES(srv.URL, "eu-west-1", "AKID", "secret", "no-reply@example.com")
+	msg := Message{To: "jane@example.com", Subject: "Hi", Body: "text", HTML: "<p>html</p>",
+		Headers: map[string]string{"List-Unsubscribe": "<https://example.com/u>"}}
+	require.NoError(t, s.Send(t.Context(), msg))
+	assert.Equal(t, "no-reply@example.com", got.FromEmailAddress)
+	assert.Equal(t, []string{"jane@example.com"}, got.Destination.ToAddresses)
+	assert.Equal(t, "text", got.Content.Simple.Body.Text.Data)
+	assert.Equal(t, "<p>html</p>", got.Content.Simple.Body.HTML.Data)
+	assert.Equal(t, []sesHeader{{Name: "List-Unsubscribe", Value: "<https://example.com/u>"}}, got.Content.Simple.Headers)
+
+	status = http.StatusBadRequest
+	assert.ErrorContains(t, s.Send(t.Context(), msg), "ses: 400")
+}
+
+func TestSendGrid(t *testing.T) {
+	var got sendGridRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer SG.key", r.Header.Get("Authorization"))
+		body, _ := io.ReadAll(r.Body)
+		assert.NoError(t, json.Unmarshal(body, &got))
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	s := NewSendGrid("SG.key", "no-reply@example.com")
+	s.url = srv.URL
+	require.NoError(t, s.Send(t.Context(), Message{To: "jane@example.com", Subject: "Hi", Body: "text", HTML: "<p>html</p>"}))
+	assert.Equal(t, "jane@example.com", got.Personalizations[0].To[0].Email)
+	assert.Equal(t, []sendGridContent{{Type: "text/plain", Value: "text"}, {Type: "text/html", Value: "<p>html</p>"}}, got.Content)
+}
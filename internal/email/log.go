@@ -0,0 +1,16 @@
+package email
+
+import (
+	"context"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/requestid"
+)
+
+// Log writes emails to the server log instead of sending them, for
+// development
+type Log struct{}
+
+func (Log) Send(ctx context.Context, msg Message) error {
+	requestid.Logger(ctx).Info("email", "to", msg.To, "subject", msg.Subject, "template", msg.Template, "body", msg.Body)
+	return nil
+}
@@ -0,0 +1,91 @@
+package email
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+)
+
+// LogRetention is how long send attempts are kept in email_log
+const LogRetention = 90 * 24 * time.Hour
+
+// Recorder wraps a Sender, recording every attempt and its outcome in
+// email_log. Failing to record is logged and doesn't fail the send.
+type Recorder struct {
+	next   Sender
+	db     *db.DB
+	driver string
+}
+
+// Record returns next wrapped in a Recorder. driver is stored with each
+// attempt, e.g. "smtp".
+func Record(next Sender, db *db.DB, driver string) *Recorder {
+	return &Recorder{next: next, db: db, driver: driver}
+}
+
+func (r *Recorder) Send(ctx context.Context, msg Message) error {
+	start := time.Now()
+	err := r.next.Send(ctx, msg)
+
+	status := "sent"
+	var errMessage *string
+	if err != nil {
+		status = "failed"
+		message := err.Error()
+		errMessage = &message
+	}
+	var template *string
+	if msg.Template != "" {
+		template = &msg.Template
+	}
+	_, logErr := r.db.Pool.Exec(context.WithoutCancel(ctx),
+		`INSERT INTO email_log (recipient, subject, template, driver, status, error, duration_ms)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		msg.To, msg.Subject, template, r.driver, status, errMessage, time.Since(start).Milliseconds())
+	if logErr != nil {
+		slog.Error("failed to record email", "to", msg.To, "error", logErr)
+	}
+	return err
+}
+
+// Attempt is a row of email_log
+type Attempt struct {
+	ID         uuid.UUID `json:"id"`
+	Recipient  string    `json:"recipient"`
+	Subject    string    `json:"subject"`
+	Template   *string   `json:"template,omitempty"`
+	Driver     string    `json:"driver"`
+	Status     string    `json:"status"`
+	Error      *string   `json:"error,omitempty"`
+	DurationMS int       `json:"duration_ms"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+// RecentAttempts returns up to limit send attempts, newest first, to
+// recipient or to anyone when it's empty
+func RecentAttempts(ctx context.Context, db *db.DB, recipient string, limit int) ([]Attempt, error) {
+	rows, err := db.Pool.Query(ctx,
+		`SELECT id, recipient, subject, template, driver, status, error, duration_ms, created_at
+		 FROM email_log
+		 WHERE $1 = '' OR recipient = $1
+		 ORDER BY created_at DESC LIMIT $2`, recipient, limit)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByPos[Attempt])
+}
+
+// PurgeLog removes send attempts older than LogRetention and returns how
+// many it removed
+func PurgeLog(ctx context.Context, db *db.DB) (int64, error) {
+	tag, err := db.Pool.Exec(ctx, "DELETE FROM email_log WHERE created_at < $1", time.Now().Add(-LogRetention))
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
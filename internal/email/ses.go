@@ -0,0 +1,114 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/sigv4"
+)
+
+// SES sends email through the Amazon SES v2 API, signing requests with AWS
+// Signature Version 4
+type SES struct {
+	endpoint  string
+	region    string
+	accessKey string
+	secretKey string
+	from      string
+	client    *http.Client
+}
+
+// NewSES returns an SES sender. An empty endpoint means AWS SES in region.
+func NewSES(endpoint, region, accessKey, secretKey, from string) *SES {
+	if region == "" {
+		region = "us-east-1"
+	}
+	if endpoint == "" {
+		endpoint = "https://email." + region + ".amazonaws.com"
+	}
+	return &SES{
+		endpoint:  strings.TrimRight(endpoint, "/"),
+		region:    region,
+		accessKey: accessKey,
+		secretKey: secretKey,
+		from:      from,
+		client:    &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+type sesContent struct {
+	Data    string `json:"Data"`
+	Charset string `json:"Charset"`
+}
+
+type sesHeader struct {
+	Name  string `json:"Name"`
+	Value string `json:"Value"`
+}
+
+// sesRequest is the SendEmail request body for a simple message
+type sesRequest struct {
+	FromEmailAddress string `json:"FromEmailAddress"`
+	Destination      struct {
+		ToAddresses []string `json:"ToAddresses"`
+	} `json:"Destination"`
+	Content struct {
+		Simple struct {
+			Subject sesContent `json:"Subject"`
+			Body    struct {
+				Text *sesContent `json:"Text,omitempty"`
+				HTML *sesContent `json:"Html,omitempty"`
+			} `json:"Body"`
+			Headers []sesHeader `json:"Headers,omitempty"`
+		} `json:"Simple"`
+	} `json:"Content"`
+}
+
+func (s *SES) Send(ctx context.Context, msg Message) error {
+	var body sesRequest
+	body.FromEmailAddress = s.from
+	body.Destination.ToAddresses = []string{msg.To}
+	simple := &body.Content.Simple
+	simple.Subject = sesContent{Data: headerValue(msg.Subject), Charset: "UTF-8"}
+	simple.Body.Text = &sesContent{Data: msg.Body, Charset: "UTF-8"}
+	if msg.HTML != "" {
+		simple.Body.HTML = &sesContent{Data: msg.HTML, Charset: "UTF-8"}
+	}
+	names := make([]string, 0, len(msg.Headers))
+	for name := range msg.Headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		simple.Headers = append(simple.Headers, sesHeader{Name: headerValue(name), Value: headerValue(msg.Headers[name])})
+	}
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint+"/v2/email/outbound-emails", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	sigv4.SignRequest(req, payload, s.accessKey, s.secretKey, s.region, "ses", time.Now())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("ses: %s: %s", resp.Status, detail)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package email
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"mime/quotedprintable"
+	"net"
+	"net/smtp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// SMTP sends email through an SMTP server, authenticating with PLAIN when a
+// username is set
+type SMTP struct {
+	addr string
+	host string
+	auth smtp.Auth
+	from string
+}
+
+func NewSMTP(host, port, username, password, from string) *SMTP {
+	s := &SMTP{addr: net.JoinHostPort(host, port), host: host, from: from}
+	if username != "" {
+		s.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return s
+}
+
+func (s *SMTP) Send(ctx context.Context, msg Message) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	return smtp.SendMail(s.addr, s.auth, s.from, []string{msg.To}, buildMessage(s.from, msg, time.Now()))
+}
+
+// headerValue strips line breaks so values can't inject extra headers
+func headerValue(v string) string {
+	return strings.NewReplacer("\r", "", "\n", " ").Replace(v)
+}
+
+// buildMessage formats msg as an RFC 5322 message with CRLF line endings.
+// A message with HTML is sent as multipart/alternative, text first, with
+// both parts quoted-printable.
+func buildMessage(from string, msg Message, now time.Time) []byte {
+	var b bytes.Buffer
+	header := func(k, v string) {
+		b.WriteString(k + ": " + headerValue(v) + "\r\n")
+	}
+
+	header("From", from)
+	header("To", msg.To)
+	header("Subject", msg.Subject)
+	header("Date", now.Format(time.RFC1123Z))
+	header("MIME-Version", "1.0")
+	var boundary string
+	if msg.HTML != "" {
+		boundary = newBoundary()
+		header("Content-Type", `multipart/alternative; boundary="`+boundary+`"`)
+	} else {
+		header("Content-Type", "text/plain; charset=UTF-8")
+	}
+
+	keys := make([]string, 0, len(msg.Headers))
+	for k := range msg.Headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		header(headerValue(k), msg.Headers[k])
+	}
+
+	b.WriteString("\r\n")
+	if boundary == "" {
+		b.WriteString(crlf(msg.Body))
+		return b.Bytes()
+	}
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain; charset=UTF-8", msg.Body},
+		{"text/html; charset=UTF-8", msg.HTML},
+	} {
+		b.WriteString("--" + boundary + "\r\n")
+		header("Content-Type", part.contentType)
+		header("Content-Transfer-Encoding", "quoted-printable")
+		b.WriteString("\r\n")
+		w := quotedprintable.NewWriter(&b)
+		w.Write([]byte(crlf(part.body)))
+		w.Close()
+		b.WriteString("\r\n")
+	}
+	b.WriteString("--" + boundary + "--\r\n")
+	return b.Bytes()
+}
+
+// crlf converts line endings to CRLF
+func crlf(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "\r\n", "\n"), "\n", "\r\n")
+}
+
+// newBoundary returns a random multipart boundary
+func newBoundary() string {
+	buf := make([]byte, 16)
+	rand.Read(buf)
+	return "fm-" + hex.EncodeToString(buf)
+}
@@ -0,0 +1,64 @@
+package email
+
+import (
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBuildMessage(t *testing.T) {
+	now := time.Date(2024, 3, 4, 9, 30, 0, 0, time.UTC)
+	msg := Message{
+		To:      "jane@example.com",
+		Subject: "Weekly summary\r\nBcc: evil@example.com",
+		Body:    "Line one\nLine two",
+		Headers: map[string]string{"List-Unsubscribe": "<https://example.com/u>"},
+	}
+
+	assert.Equal(t,
+		"From: no-reply@example.com\r\n"+
+			"To: jane@example.com\r\n"+
+			"Subject: Weekly summary Bcc: evil@example.com\r\n"+
+			"Date: Mon, 04 Mar 2024 09:30:00 +0000\r\n"+
+			"MIME-Version: 1.0\r\n"+
+			"Content-Type: text/plain; charset=UTF-8\r\n"+
+			"List-Unsubscribe: <https://example.com/u>\r\n"+
+			"\r\n"+
+			"Line one\r\nLine two",
+		string(buildMessage("no-reply@example.com", msg, now)))
+}
+
+func TestBuildMessageHTML(t *testing.T) {
+	msg := Message{To: "jane@example.com", Subject: "Summary", Body: "Line one\nLine two", HTML: "<p>Caf\u00e9</p>"}
+	parsed, err := mail.ReadMessage(strings.NewReader(string(buildMessage("no-reply@example.com", msg, time.Now()))))
+	require.NoError(t, err)
+
+	mediaType, params, err := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	require.NoError(t, err)
+	assert.Equal(t, "multipart/alternative", mediaType)
+
+	parts := multipart.NewReader(parsed.Body, params["boundary"])
+	var types, bodies []string
+	for {
+		part, err := parts.NextRawPart()
+		if err == io.EOF {
+			break
+		}
+		require.NoError(t, err)
+		assert.Equal(t, "quoted-printable", part.Header.Get("Content-Transfer-Encoding"))
+		body, err := io.ReadAll(quotedprintable.NewReader(part))
+		require.NoError(t, err)
+		types = append(types, part.Header.Get("Content-Type"))
+		bodies = append(bodies, string(body))
+	}
+	assert.Equal(t, []string{"text/plain; charset=UTF-8", "text/html; charset=UTF-8"}, types)
+	assert.Equal(t, []string{"Line one\r\nLine two", "<p>Caf\u00e9</p>"}, bodies)
+}
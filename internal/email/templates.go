@@ -0,0 +1,141 @@
+package email
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+// Templates. Each has a text file defining "subject" and "text" and an HTML
+// file defining "content", shown inside layout.html.
+const (
+	TemplateVerification  = "verification"
+	TemplatePasswordReset = "password_reset"
+	TemplateInvite        = "invite"
+	TemplateDigest        = "digest"
+)
+
+// Templates lists every template
+var Templates = []string{TemplateVerification, TemplatePasswordReset, TemplateInvite, TemplateDigest}
+
+// VerificationData fills in the verification template. Name may be empty.
+type VerificationData struct {
+	Name    string
+	URL     string
+	Expires time.Duration
+}
+
+// PasswordResetData fills in the password_reset template. Name may be
+// empty.
+type PasswordResetData struct {
+	Name    string
+	URL     string
+	Expires time.Duration
+}
+
+// InviteData fills in the invite template
+type InviteData struct {
+	InviterName string
+	GroupName   string
+	URL         string
+	Expires     time.Duration
+}
+
+// DigestData fills in the digest template. Amounts are formatted with
+// their currency; Period is the dates covered, e.g. "Feb 26 to Mar 3, 2024".
+type DigestData struct {
+	Weekly         bool
+	Period         string
+	TotalSpent     string
+	ExpenseCount   int
+	Budget         *DigestBudget
+	Expenses       []DigestExpense
+	Groups         []DigestGroup
+	UnsubscribeURL string
+}
+
+// DigestBudget is the budget line of a digest. Difference is what's left,
+// or how much over when Exceeded.
+type DigestBudget struct {
+	Exceeded   bool
+	Amount     string
+	Difference string
+}
+
+type DigestExpense struct {
+	Date   string
+	Label  string
+	Amount string
+}
+
+// DigestGroup is a group's balance, e.g. Status "you owe 12.00 EUR"
+type DigestGroup struct {
+	Name   string
+	Status string
+}
+
+//go:embed templates
+var templateFiles embed.FS
+
+var (
+	textTemplates = map[string]*texttemplate.Template{}
+	htmlTemplates = map[string]*htmltemplate.Template{}
+)
+
+func init() {
+	funcs := map[string]any{"duration": formatDuration}
+	layout := htmltemplate.Must(htmltemplate.New("layout").Funcs(funcs).ParseFS(templateFiles, "templates/layout.html"))
+	for _, name := range Templates {
+		textTemplates[name] = texttemplate.Must(texttemplate.New(name).Funcs(funcs).ParseFS(templateFiles, "templates/"+name+".txt"))
+		htmlTemplates[name] = htmltemplate.Must(htmltemplate.Must(layout.Clone()).ParseFS(templateFiles, "templates/"+name+".html"))
+	}
+}
+
+// Render fills in the named template with data, which must be the
+// template's data type. The message has Subject, Body, HTML and Template
+// set; the caller adds To and any Headers.
+func Render(name string, data any) (Message, error) {
+	text, ok := textTemplates[name]
+	if !ok {
+		return Message{}, fmt.Errorf("unknown email template %q", name)
+	}
+	var subject, body, html bytes.Buffer
+	if err := text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return Message{}, fmt.Errorf("email template %s: %w", name, err)
+	}
+	if err := text.ExecuteTemplate(&body, "text", data); err != nil {
+		return Message{}, fmt.Errorf("email template %s: %w", name, err)
+	}
+	if err := htmlTemplates[name].ExecuteTemplate(&html, "layout", data); err != nil {
+		return Message{}, fmt.Errorf("email template %s: %w", name, err)
+	}
+	return Message{
+		Subject:  strings.TrimSpace(subject.String()),
+		Body:     body.String(),
+		HTML:     html.String(),
+		Template: name,
+	}, nil
+}
+
+// formatDuration writes d in the largest whole unit, e.g. "24 hours" or
+// "7 days"
+func formatDuration(d time.Duration) string {
+	unit := func(n int64, name string) string {
+		if n == 1 {
+			return "1 " + name
+		}
+		return fmt.Sprintf("%d %ss", n, name)
+	}
+	switch {
+	case d >= 48*time.Hour && d%(24*time.Hour) == 0:
+		return unit(int64(d/(24*time.Hour)), "day")
+	case d >= time.Hour && d%time.Hour == 0:
+		return unit(int64(d/time.Hour), "hour")
+	default:
+		return unit(int64(d.Round(time.Minute)/time.Minute), "minute")
+	}
+}
@@ -0,0 +1,77 @@
+// Package event records domain events in the events table, the outbox the
+// webhook dispatcher reads. Events are written in the same transaction as
+// the change they describe, so one is never lost or sent for a change that
+// was rolled back.
+package event
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Event types
+const (
+	ExpenseCreated      = "expense.created"
+	ExpenseUpdated      = "expense.updated"
+	ExpenseDeleted      = "expense.deleted"
+	ExpenseRestored     = "expense.restored"
+	ExpenseApproved     = "expense.approved"
+	ExpenseRejected     = "expense.rejected"
+	SettlementCreated   = "settlement.created"
+	SettlementConfirmed = "settlement.confirmed"
+	SettlementDeleted   = "settlement.deleted"
+	SettlementRestored  = "settlement.restored"
+	MemberAdded         = "group.member_added"
+	BudgetExceeded      = "budget.exceeded"
+)
+
+// Types lists every event type, for validating webhook subscriptions
+var Types = []string{
+	ExpenseCreated, ExpenseUpdated, ExpenseDeleted, ExpenseRestored, ExpenseApproved, ExpenseRejected,
+	SettlementCreated, SettlementConfirmed, SettlementDeleted, SettlementRestored,
+	MemberAdded, BudgetExceeded,
+}
+
+// Deleted is the data of the *.deleted events
+type Deleted struct {
+	ID      uuid.UUID `json:"id"`
+	GroupID uuid.UUID `json:"group_id"`
+}
+
+// Member is the data of group.member_added
+type Member struct {
+	GroupID       uuid.UUID `json:"group_id"`
+	UserID        uuid.UUID `json:"user_id"`
+	IsPlaceholder bool      `json:"is_placeholder"`
+}
+
+// Event is something that happened. UserID is who caused it; events with a
+// GroupID are sent to every member of the group. Data is stored as JSON and
+// is usually what the API returns for the changed object.
+type Event struct {
+	Type    string
+	UserID  uuid.UUID
+	GroupID *uuid.UUID
+	Data    any
+}
+
+const insertSQL = "INSERT INTO events (type, user_id, group_id, data) VALUES ($1, $2, $3, $4)"
+
+// Execer is satisfied by both the pool and a transaction
+type Execer interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+// Record writes e. Pass the transaction making the change.
+func Record(ctx context.Context, q Execer, e Event) error {
+	_, err := q.Exec(ctx, insertSQL, e.Type, e.UserID, e.GroupID, e.Data)
+	return err
+}
+
+// Queue queues writing e on batch
+func Queue(batch *pgx.Batch, e Event) {
+	batch.Queue(insertSQL, e.Type, e.UserID, e.GroupID, e.Data)
+}
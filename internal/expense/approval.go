@@ -0,0 +1,110 @@
+package expense
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+	"github.com/yanonymousV2/finance-manager-backend/internal/event"
+	"github.com/yanonymousV2/finance-manager-backend/internal/helpers"
+	"github.com/yanonymousV2/finance-manager-backend/internal/middleware"
+	"github.com/yanonymousV2/finance-manager-backend/internal/personalexpense"
+)
+
+// Expense statuses; only approved expenses count toward balances
+const (
+	statusPending  = "pending"
+	statusApproved = "approved"
+	statusRejected = "rejected"
+)
+
+// ApproveExpense approves a pending expense so it counts toward balances
+func ApproveExpense(c *gin.Context, db *db.DB) {
+	reviewExpense(c, db, statusApproved)
+}
+
+// RejectExpense rejects a pending expense; it stays excluded from balances
+func RejectExpense(c *gin.Context, db *db.DB) {
+	reviewExpense(c, db, statusRejected)
+}
+
+// reviewExpense moves a pending expense to status. Reviewers must be group
+// members other than the member who created the expense.
+func reviewExpense(c *gin.Context, db *db.DB, status string) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	expenseID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid expense id"})
+		return
+	}
+
+	var groupID uuid.UUID
+	var createdBy *uuid.UUID
+	var current string
+	err = db.Pool.QueryRow(c.Request.Context(),
+		"SELECT group_id, created_by, status FROM expenses WHERE id = $1 AND deleted_at IS NULL",
+		expenseID).Scan(&groupID, &createdBy, &current)
+	if err != nil {
+		c.JSON(404, gin.H{"error": "expense not found"})
+		return
+	}
+
+	isMember, err := helpers.IsGroupMember(c.Request.Context(), db, groupID, userID)
+	if err != nil || !isMember {
+		c.JSON(403, gin.H{"error": "not a member of the group"})
+		return
+	}
+
+	if current != statusPending {
+		c.JSON(400, gin.H{"error": "expense is not pending approval"})
+		return
+	}
+
+	if createdBy != nil && *createdBy == userID {
+		c.JSON(403, gin.H{"error": "expense must be reviewed by another member"})
+		return
+	}
+
+	err = db.WithTx(c.Request.Context(), func(tx pgx.Tx) error {
+		// Guard on the status so concurrent reviews cannot both succeed
+		tag, err := tx.Exec(c.Request.Context(),
+			`UPDATE expenses SET status = $1, reviewed_by = $2, reviewed_at = NOW()
+			 WHERE id = $3 AND status = 'pending'`,
+			status, userID, expenseID)
+		if err != nil {
+			return helpers.NewRequestError(500, "failed to review expense")
+		}
+		if tag.RowsAffected() == 0 {
+			return helpers.NewRequestError(409, "expense was already reviewed")
+		}
+
+		if err := personalexpense.SyncGroupExpense(c.Request.Context(), tx, expenseID); err != nil {
+			return helpers.NewRequestError(500, "failed to sync personal expenses")
+		}
+
+		exp, err := loadEventExpense(c.Request.Context(), tx, expenseID)
+		if err != nil {
+			return helpers.NewRequestError(500, "failed to load expense")
+		}
+		eventType := event.ExpenseApproved
+		if status == statusRejected {
+			eventType = event.ExpenseRejected
+		}
+		if err := event.Record(c.Request.Context(), tx, event.Event{Type: eventType, UserID: userID, GroupID: &groupID, Data: exp}); err != nil {
+			return helpers.NewRequestError(500, "failed to record event")
+		}
+		return nil
+	})
+	if err != nil {
+		helpers.RespondError(c, err, "failed to review expense")
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "expense " + status})
+}
@@ -0,0 +1,52 @@
+package expense
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/helpers"
+	"github.com/yanonymousV2/finance-manager-backend/internal/middleware"
+	"github.com/yanonymousV2/finance-manager-backend/internal/validation"
+)
+
+// BulkCreateExpenseRequest holds at most 500 expenses
+type BulkCreateExpenseRequest struct {
+	Expenses []CreateExpenseRequest `json:"expenses" validate:"required,min=1,max=500,dive"`
+}
+
+// BulkCreateExpenses creates many expenses in one transaction, e.g. when
+// importing an existing ledger. Either all expenses are created or none.
+func BulkCreateExpenses(c *gin.Context, service *Service) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req BulkCreateExpenseRequest
+	if !validation.Bind(c, &req) {
+		return
+	}
+
+	// A bad entry is reported with its index
+	expenses, err := service.CreateMany(c.Request.Context(), userID, req.Expenses)
+	if err != nil {
+		var indexErr *IndexError
+		var reqErr *helpers.RequestError
+		switch {
+		case errors.As(err, &indexErr) && errors.As(err, &reqErr):
+			c.JSON(reqErr.Status, gin.H{"error": reqErr.Message, "index": indexErr.Index})
+		case errors.As(err, &indexErr):
+			c.JSON(500, gin.H{"error": "failed to create expenses", "index": indexErr.Index})
+		default:
+			c.JSON(500, gin.H{"error": "failed to create expenses"})
+		}
+		return
+	}
+
+	c.JSON(201, gin.H{
+		"expenses": expenses,
+		"count":    len(expenses),
+	})
+}
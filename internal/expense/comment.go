@@ -0,0 +1,164 @@
+package expense
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+	"github.com/yanonymousV2/finance-manager-backend/internal/helpers"
+	"github.com/yanonymousV2/finance-manager-backend/internal/middleware"
+	"github.com/yanonymousV2/finance-manager-backend/internal/validation"
+)
+
+type Comment struct {
+	ID         uuid.UUID `json:"id" db:"id"`
+	ExpenseID  uuid.UUID `json:"expense_id" db:"expense_id"`
+	UserID     uuid.UUID `json:"user_id" db:"user_id"`
+	AuthorName string    `json:"author_name"`
+	Body       string    `json:"body" db:"body"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+}
+
+type CreateCommentRequest struct {
+	Body string `json:"body" validate:"required,min=1,max=2000"`
+}
+
+// authorizeExpenseView checks that the expense exists and the user belongs to
+// its group
+func authorizeExpenseView(c *gin.Context, db *db.DB, expenseID, userID uuid.UUID) bool {
+	var groupID uuid.UUID
+	err := db.Pool.QueryRow(c.Request.Context(),
+		"SELECT group_id FROM expenses WHERE id = $1 AND deleted_at IS NULL",
+		expenseID).Scan(&groupID)
+	if err != nil {
+		c.JSON(404, gin.H{"error": "expense not found"})
+		return false
+	}
+
+	isMember, err := helpers.IsGroupMember(c.Request.Context(), db, groupID, userID)
+	if err != nil || !isMember {
+		c.JSON(403, gin.H{"error": "not a member of the group"})
+		return false
+	}
+
+	return true
+}
+
+// CreateComment adds a comment to a group expense
+func CreateComment(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	expenseID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid expense id"})
+		return
+	}
+
+	var req CreateCommentRequest
+	if !validation.Bind(c, &req) {
+		return
+	}
+
+	if !authorizeExpenseView(c, db, expenseID, userID) {
+		return
+	}
+
+	comment := Comment{ExpenseID: expenseID, UserID: userID}
+	err = db.Pool.QueryRow(c.Request.Context(),
+		`WITH inserted AS (
+		     INSERT INTO expense_comments (expense_id, user_id, body) VALUES ($1, $2, $3)
+		     RETURNING id, user_id, body, created_at
+		 )
+		 SELECT i.id, COALESCE(u.display_name, u.email), i.body, i.created_at
+		 FROM inserted i JOIN users u ON u.id = i.user_id`,
+		expenseID, userID, req.Body).Scan(&comment.ID, &comment.AuthorName, &comment.Body, &comment.CreatedAt)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to create comment"})
+		return
+	}
+
+	c.JSON(201, comment)
+}
+
+// GetComments returns the comments on a group expense, oldest first
+func GetComments(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	expenseID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid expense id"})
+		return
+	}
+
+	if !authorizeExpenseView(c, db, expenseID, userID) {
+		return
+	}
+
+	// Parse pagination parameters
+	limit := 50 // default
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
+			limit = l
+		}
+	}
+
+	offset := 0
+	if offsetStr := c.Query("offset"); offsetStr != "" {
+		if o, err := strconv.Atoi(offsetStr); err == nil && o >= 0 {
+			offset = o
+		}
+	}
+
+	var totalCount int
+	err = db.Pool.QueryRow(c.Request.Context(),
+		"SELECT COUNT(*) FROM expense_comments WHERE expense_id = $1", expenseID).Scan(&totalCount)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to get total count"})
+		return
+	}
+
+	rows, err := db.Pool.Query(c.Request.Context(),
+		`SELECT ec.id, ec.expense_id, ec.user_id, COALESCE(u.display_name, u.email), ec.body, ec.created_at
+		 FROM expense_comments ec
+		 JOIN users u ON u.id = ec.user_id
+		 WHERE ec.expense_id = $1
+		 ORDER BY ec.created_at, ec.id
+		 LIMIT $2 OFFSET $3`,
+		expenseID, limit, offset)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to get comments"})
+		return
+	}
+	defer rows.Close()
+
+	comments := []Comment{}
+	for rows.Next() {
+		var comment Comment
+		if err := rows.Scan(&comment.ID, &comment.ExpenseID, &comment.UserID, &comment.AuthorName,
+			&comment.Body, &comment.CreatedAt); err != nil {
+			c.JSON(500, gin.H{"error": "failed to scan comment"})
+			return
+		}
+		comments = append(comments, comment)
+	}
+
+	c.JSON(200, gin.H{
+		"comments": comments,
+		"pagination": gin.H{
+			"limit":  limit,
+			"offset": offset,
+			"total":  totalCount,
+		},
+	})
+}
@@ -0,0 +1,105 @@
+package expense
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/event"
+	"github.com/yanonymousV2/finance-manager-backend/internal/personalexpense"
+)
+
+// preparedExpense is a validated expense with its payers, items and splits
+// resolved, ready to be inserted
+type preparedExpense struct {
+	GroupID     uuid.UUID
+	Description string
+	TotalAmount decimal.Decimal
+	Currency    string
+	Category    *string
+	ExpenseDate *time.Time
+	PaidBy      uuid.UUID
+	Payers      []splitAmount
+	Items       []itemShare
+	Splits      []splitAmount
+	Tags        []string
+	CreatedBy   uuid.UUID
+	Status      string
+}
+
+// queueExpense queues the inserts for a prepared expense and its tags on
+// batch, followed by the personal ledger sync. IDs are
+// generated up front so dependent rows need no round trip; exp is filled in
+// once the batch has been sent.
+func queueExpense(batch *pgx.Batch, p *preparedExpense, exp *Expense) {
+	exp.ID = uuid.New()
+
+	batch.Queue(
+		`INSERT INTO expenses (id, group_id, description, total_amount, currency, category, paid_by, expense_date, created_by, status)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, COALESCE($8, NOW()), $9, $10)
+		 RETURNING group_id, description, total_amount, currency, category, paid_by, expense_date, created_by, status, created_at`,
+		exp.ID, p.GroupID, p.Description, p.TotalAmount, p.Currency, p.Category, p.PaidBy, p.ExpenseDate, p.CreatedBy, p.Status,
+	).QueryRow(func(row pgx.Row) error {
+		return row.Scan(&exp.GroupID, &exp.Description, &exp.TotalAmount, &exp.Currency, &exp.Category,
+			&exp.PaidBy, &exp.ExpenseDate, &exp.CreatedBy, &exp.Status, &exp.CreatedAt)
+	})
+
+	exp.Payers = make([]ExpensePayer, len(p.Payers))
+	for i, payer := range p.Payers {
+		exp.Payers[i] = ExpensePayer{ExpenseID: exp.ID, UserID: payer.UserID, Amount: payer.Amount}
+	}
+	queueShares(batch, "expense_payers", exp.ID, p.Payers)
+
+	for i, item := range p.Items {
+		itemID := uuid.New()
+		batch.Queue("INSERT INTO expense_items (id, expense_id, description, amount, position) VALUES ($1, $2, $3, $4, $5)",
+			itemID, exp.ID, item.Description, item.Amount, i)
+		if len(item.UserIDs) > 0 {
+			batch.Queue("INSERT INTO expense_item_assignees (item_id, user_id) SELECT $1, unnest($2::uuid[])",
+				itemID, item.UserIDs)
+		}
+		exp.Items = append(exp.Items, ExpenseItem{ID: itemID, Description: item.Description, Amount: item.Amount, UserIDs: item.UserIDs})
+	}
+
+	exp.Splits = make([]ExpenseSplit, len(p.Splits))
+	for i, split := range p.Splits {
+		exp.Splits[i] = ExpenseSplit{ExpenseID: exp.ID, UserID: split.UserID, Amount: split.Amount}
+	}
+	queueShares(batch, "expense_splits", exp.ID, p.Splits)
+
+	queueTags(batch, p.GroupID, exp.ID, p.Tags)
+	exp.Tags = p.Tags
+
+	personalexpense.QueueGroupExpenseSync(batch, exp.ID)
+}
+
+// recordCreated writes an expense.created event for each of the expenses
+// userID just inserted in tx
+func recordCreated(ctx context.Context, tx pgx.Tx, userID uuid.UUID, expenses ...Expense) error {
+	batch := &pgx.Batch{}
+	for _, exp := range expenses {
+		event.Queue(batch, event.Event{Type: event.ExpenseCreated, UserID: userID, GroupID: &exp.GroupID, Data: exp})
+	}
+	return tx.SendBatch(ctx, batch).Close()
+}
+
+// queueShares inserts all of an expense's payer or split rows with one
+// statement, so large groups don't add a statement per member. Amounts go
+// as text to keep their exact decimal value.
+func queueShares(batch *pgx.Batch, table string, expenseID uuid.UUID, shares []splitAmount) {
+	if len(shares) == 0 {
+		return
+	}
+	userIDs := make([]uuid.UUID, len(shares))
+	amounts := make([]string, len(shares))
+	for i, share := range shares {
+		userIDs[i] = share.UserID
+		amounts[i] = share.Amount.String()
+	}
+	batch.Queue(`INSERT INTO `+table+` (expense_id, user_id, amount)
+		 SELECT $1, user_id, amount FROM unnest($2::uuid[], $3::numeric[]) AS s(user_id, amount)`,
+		expenseID, userIDs, amounts)
+}
@@ -2,16 +2,23 @@ package expense
 
 import (
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/shopspring/decimal"
 
 	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+	"github.com/yanonymousV2/finance-manager-backend/internal/db/sqlc"
+	"github.com/yanonymousV2/finance-manager-backend/internal/event"
+	"github.com/yanonymousV2/finance-manager-backend/internal/group"
 	"github.com/yanonymousV2/finance-manager-backend/internal/helpers"
 	"github.com/yanonymousV2/finance-manager-backend/internal/middleware"
+	"github.com/yanonymousV2/finance-manager-backend/internal/personalexpense"
+	"github.com/yanonymousV2/finance-manager-backend/internal/softdelete"
+	"github.com/yanonymousV2/finance-manager-backend/internal/validation"
 )
 
 type Expense struct {
@@ -19,9 +26,33 @@ type Expense struct {
 	GroupID     uuid.UUID       `json:"group_id" db:"group_id"`
 	Description string          `json:"description" db:"description"`
 	TotalAmount decimal.Decimal `json:"total_amount" db:"total_amount"`
+	Currency    string          `json:"currency" db:"currency"`
+	Category    *string         `json:"category,omitempty" db:"category"`
 	PaidBy      uuid.UUID       `json:"paid_by" db:"paid_by"`
+	PaidByName  *string         `json:"paid_by_name,omitempty"`
+	ExpenseDate time.Time       `json:"expense_date" db:"expense_date"`
+	CreatedBy   *uuid.UUID      `json:"created_by,omitempty" db:"created_by"`
+	Status      string          `json:"status" db:"status"`
+	ReviewedBy  *uuid.UUID      `json:"reviewed_by,omitempty" db:"reviewed_by"`
+	ReviewedAt  *time.Time      `json:"reviewed_at,omitempty" db:"reviewed_at"`
 	CreatedAt   time.Time       `json:"created_at" db:"created_at"`
+	Payers      []ExpensePayer  `json:"payers,omitempty"`
+	Items       []ExpenseItem   `json:"items,omitempty"`
 	Splits      []ExpenseSplit  `json:"splits,omitempty"`
+	Tags        []string        `json:"tags"`
+}
+
+type ExpenseItem struct {
+	ID          uuid.UUID       `json:"id" db:"id"`
+	Description string          `json:"description" db:"description"`
+	Amount      decimal.Decimal `json:"amount" db:"amount"`
+	UserIDs     []uuid.UUID     `json:"user_ids"`
+}
+
+type ExpensePayer struct {
+	ExpenseID uuid.UUID       `json:"expense_id" db:"expense_id"`
+	UserID    uuid.UUID       `json:"user_id" db:"user_id"`
+	Amount    decimal.Decimal `json:"amount" db:"amount"`
 }
 
 type ExpenseSplit struct {
@@ -31,10 +62,36 @@ type ExpenseSplit struct {
 }
 
 type CreateExpenseRequest struct {
-	GroupID     uuid.UUID                   `json:"group_id" validate:"required"`
-	Description string                      `json:"description" validate:"required"`
-	TotalAmount string                      `json:"total_amount" validate:"required,numeric"`
-	Splits      []CreateExpenseSplitRequest `json:"splits" validate:"required,min=1,dive"`
+	GroupID      uuid.UUID                   `json:"group_id" validate:"required"`
+	Description  string                      `json:"description" validate:"required"`
+	TotalAmount  string                      `json:"total_amount" validate:"required,numeric"`
+	Currency     string                      `json:"currency,omitempty" validate:"omitempty,iso4217"`
+	Category     *string                     `json:"category,omitempty" validate:"omitempty,max=100"`
+	ExpenseDate  *time.Time                  `json:"expense_date,omitempty"`
+	SplitMode    string                      `json:"split_mode,omitempty" validate:"omitempty,oneof=exact equal itemized"`
+	Participants []uuid.UUID                 `json:"participants,omitempty"`
+	Payers       []CreateExpensePayerRequest `json:"payers,omitempty" validate:"omitempty,dive"`
+	Items        []CreateExpenseItemRequest  `json:"items,omitempty" validate:"omitempty,dive"`
+	Splits       []CreateExpenseSplitRequest `json:"splits,omitempty" validate:"omitempty,dive"`
+	Tags         []string                    `json:"tags,omitempty" validate:"omitempty,max=20,dive,max=50"`
+}
+
+type UpdateExpenseRequest struct {
+	Description *string    `json:"description,omitempty" validate:"omitempty,min=1"`
+	Category    *string    `json:"category,omitempty" validate:"omitempty,max=100"`
+	ExpenseDate *time.Time `json:"expense_date,omitempty"`
+	Tags        []string   `json:"tags,omitempty" validate:"omitempty,max=20,dive,max=50"`
+}
+
+type CreateExpensePayerRequest struct {
+	UserID uuid.UUID `json:"user_id" validate:"required"`
+	Amount string    `json:"amount" validate:"required,numeric"`
+}
+
+type CreateExpenseItemRequest struct {
+	Description string      `json:"description" validate:"required,max=255"`
+	Amount      string      `json:"amount" validate:"required,numeric"`
+	UserIDs     []uuid.UUID `json:"user_ids" validate:"required,min=1"`
 }
 
 type CreateExpenseSplitRequest struct {
@@ -42,7 +99,7 @@ type CreateExpenseSplitRequest struct {
 	Amount string    `json:"amount" validate:"required,numeric"`
 }
 
-func CreateExpense(c *gin.Context, db *db.DB) {
+func CreateExpense(c *gin.Context, service *Service) {
 	userID, ok := middleware.GetUserID(c)
 	if !ok {
 		c.JSON(401, gin.H{"error": "unauthorized"})
@@ -50,31 +107,33 @@ func CreateExpense(c *gin.Context, db *db.DB) {
 	}
 
 	var req CreateExpenseRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
+	if !validation.Bind(c, &req) {
 		return
 	}
 
-	validate := validator.New()
-	if err := validate.Struct(req); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
+	exp, err := service.Create(c.Request.Context(), userID, req)
+	if err != nil {
+		helpers.RespondError(c, err, "failed to create expense")
 		return
 	}
 
-	// Parse total amount
-	totalAmount, err := decimal.NewFromString(req.TotalAmount)
-	if err != nil {
-		c.JSON(400, gin.H{"error": "invalid total amount format"})
+	c.JSON(201, exp)
+}
+
+func GetGroupExpenses(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
 		return
 	}
 
-	if totalAmount.LessThanOrEqual(decimal.Zero) {
-		c.JSON(400, gin.H{"error": "total amount must be greater than 0"})
+	groupIDStr := c.Param("id")
+	groupID, err := uuid.Parse(groupIDStr)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid group id"})
 		return
 	}
 
-	groupID := req.GroupID
-
 	// Check if user is member of group
 	isMember, err := helpers.IsGroupMember(c.Request.Context(), db, groupID, userID)
 	if err != nil || !isMember {
@@ -82,171 +141,477 @@ func CreateExpense(c *gin.Context, db *db.DB) {
 		return
 	}
 
-	// Validate splits: all users are members, sum == total
-	splitSum := decimal.Zero
-	userIDs := make(map[uuid.UUID]bool)
-	parsedSplits := make([]struct {
-		UserID uuid.UUID
-		Amount decimal.Decimal
-	}, len(req.Splits))
+	// Parse pagination parameters
+	limit := 50 // default
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
+			limit = l
+		}
+	}
+
+	offset := 0
+	if offsetStr := c.Query("offset"); offsetStr != "" {
+		if o, err := strconv.Atoi(offsetStr); err == nil && o >= 0 {
+			offset = o
+		}
+	}
+
+	// Filters are shared between the page query and the count query
+	filter := sqlc.CountGroupExpensesParams{GroupID: groupID}
+
+	if startDate, err := time.Parse("2006-01-02", c.Query("start_date")); err == nil {
+		filter.StartDate = &startDate
+	}
+
+	if endDate, err := time.Parse("2006-01-02", c.Query("end_date")); err == nil {
+		endDate = endDate.Add(24 * time.Hour)
+		filter.EndDate = &endDate
+	}
 
-	for i, split := range req.Splits {
-		if userIDs[split.UserID] {
-			c.JSON(400, gin.H{"error": "duplicate user in splits"})
+	if paidByStr := c.Query("paid_by"); paidByStr != "" {
+		paidBy, err := uuid.Parse(paidByStr)
+		if err != nil {
+			c.JSON(400, gin.H{"error": "invalid paid_by"})
 			return
 		}
-		userIDs[split.UserID] = true
+		filter.PaidBy = &paidBy
+	}
 
-		// Parse split amount
-		amount, err := decimal.NewFromString(split.Amount)
+	if participantStr := c.Query("participant"); participantStr != "" {
+		participant, err := uuid.Parse(participantStr)
 		if err != nil {
-			c.JSON(400, gin.H{"error": "invalid split amount format"})
+			c.JSON(400, gin.H{"error": "invalid participant"})
 			return
 		}
+		filter.Participant = &participant
+	}
 
-		if amount.LessThan(decimal.Zero) {
-			c.JSON(400, gin.H{"error": "split amount cannot be negative"})
+	if minAmountStr := c.Query("min_amount"); minAmountStr != "" {
+		minAmount, err := decimal.NewFromString(minAmountStr)
+		if err != nil {
+			c.JSON(400, gin.H{"error": "invalid min_amount"})
 			return
 		}
+		filter.MinAmount = &minAmount
+	}
 
-		parsedSplits[i].UserID = split.UserID
-		parsedSplits[i].Amount = amount
-		splitSum = splitSum.Add(amount)
+	if maxAmountStr := c.Query("max_amount"); maxAmountStr != "" {
+		maxAmount, err := decimal.NewFromString(maxAmountStr)
+		if err != nil {
+			c.JSON(400, gin.H{"error": "invalid max_amount"})
+			return
+		}
+		filter.MaxAmount = &maxAmount
 	}
 
-	if !splitSum.Equal(totalAmount) {
-		c.JSON(400, gin.H{"error": "splits sum does not match total amount"})
-		return
+	if category := c.Query("category"); category != "" {
+		filter.Category = &category
 	}
 
-	// Check all users are members
-	for uid := range userIDs {
-		isMember, err = helpers.IsGroupMember(c.Request.Context(), db, groupID, uid)
-		if err != nil || !isMember {
-			c.JSON(400, gin.H{"error": "all split users must be group members"})
+	if status := c.Query("status"); status != "" {
+		if status != statusPending && status != statusApproved && status != statusRejected {
+			c.JSON(400, gin.H{"error": "invalid status"})
 			return
 		}
+		filter.Status = &status
+	}
+
+	if tag := c.Query("tag"); tag != "" {
+		filter.Tag = &tag
+	}
+
+	if search := strings.TrimSpace(c.Query("q")); search != "" {
+		pattern := "%" + helpers.EscapeLike(search) + "%"
+		filter.Search = &pattern
+	}
+
+	queries := sqlc.New(db.Pool)
+
+	// Get total count for pagination metadata
+	totalCount, err := queries.CountGroupExpenses(c.Request.Context(), filter)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to get total count"})
+		return
+	}
+
+	// Get expenses with pagination
+	rows, err := queries.ListGroupExpenses(c.Request.Context(), sqlc.ListGroupExpensesParams{
+		GroupID:     filter.GroupID,
+		StartDate:   filter.StartDate,
+		EndDate:     filter.EndDate,
+		PaidBy:      filter.PaidBy,
+		Participant: filter.Participant,
+		MinAmount:   filter.MinAmount,
+		MaxAmount:   filter.MaxAmount,
+		Category:    filter.Category,
+		Status:      filter.Status,
+		Tag:         filter.Tag,
+		Search:      filter.Search,
+		RowLimit:    int64(limit),
+		RowOffset:   int64(offset),
+	})
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to get expenses"})
+		return
+	}
+
+	var expenses []Expense
+	for _, row := range rows {
+		expenses = append(expenses, Expense{
+			ID: row.ID, GroupID: row.GroupID, Description: row.Description, TotalAmount: row.TotalAmount,
+			Currency: row.Currency, Category: row.Category, PaidBy: row.PaidBy, ExpenseDate: row.ExpenseDate,
+			CreatedBy: row.CreatedBy, Status: row.Status, CreatedAt: row.CreatedAt, Tags: row.Tags,
+		})
+	}
+
+	c.JSON(200, gin.H{
+		"expenses": expenses,
+		"pagination": gin.H{
+			"limit":  limit,
+			"offset": offset,
+			"total":  totalCount,
+		},
+	})
+}
+
+// GetExpense returns a single group expense with its splits
+func GetExpense(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
 	}
 
-	// Start transaction
-	tx, err := db.Pool.Begin(c.Request.Context())
+	expenseID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
-		c.JSON(500, gin.H{"error": "failed to start transaction"})
+		c.JSON(400, gin.H{"error": "invalid expense id"})
 		return
 	}
-	defer tx.Rollback(c.Request.Context())
 
-	// Insert expense
 	var exp Expense
-	err = tx.QueryRow(c.Request.Context(),
-		"INSERT INTO expenses (group_id, description, total_amount, paid_by) VALUES ($1, $2, $3, $4) RETURNING id, group_id, description, total_amount, paid_by, created_at",
-		groupID, req.Description, totalAmount, userID).Scan(&exp.ID, &exp.GroupID, &exp.Description, &exp.TotalAmount, &exp.PaidBy, &exp.CreatedAt)
+	err = db.Pool.QueryRow(c.Request.Context(),
+		`SELECT e.id, e.group_id, e.description, e.total_amount, e.currency, e.category, e.paid_by,
+		        COALESCE(u.display_name, u.email), e.expense_date, e.created_by, e.status, e.reviewed_by, e.reviewed_at,
+		        e.created_at
+		 FROM expenses e
+		 JOIN users u ON u.id = e.paid_by
+		 WHERE e.id = $1 AND e.deleted_at IS NULL`,
+		expenseID).Scan(&exp.ID, &exp.GroupID, &exp.Description, &exp.TotalAmount, &exp.Currency, &exp.Category,
+		&exp.PaidBy, &exp.PaidByName, &exp.ExpenseDate, &exp.CreatedBy, &exp.Status, &exp.ReviewedBy, &exp.ReviewedAt,
+		&exp.CreatedAt)
 	if err != nil {
-		c.JSON(500, gin.H{"error": "failed to create expense"})
+		c.JSON(404, gin.H{"error": "expense not found"})
 		return
 	}
 
-	// Insert splits
-	for _, split := range parsedSplits {
-		_, err = tx.Exec(c.Request.Context(),
-			"INSERT INTO expense_splits (expense_id, user_id, amount) VALUES ($1, $2, $3)",
-			exp.ID, split.UserID, split.Amount)
-		if err != nil {
-			c.JSON(500, gin.H{"error": "failed to create expense split"})
+	// Check if user is member of group
+	isMember, err := helpers.IsGroupMember(c.Request.Context(), db, exp.GroupID, userID)
+	if err != nil || !isMember {
+		c.JSON(403, gin.H{"error": "not a member of the group"})
+		return
+	}
+
+	payerRows, err := db.Pool.Query(c.Request.Context(),
+		"SELECT expense_id, user_id, amount FROM expense_payers WHERE expense_id = $1 ORDER BY amount DESC, user_id",
+		expenseID)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to get expense payers"})
+		return
+	}
+	defer payerRows.Close()
+
+	exp.Payers = []ExpensePayer{}
+	for payerRows.Next() {
+		var payer ExpensePayer
+		if err := payerRows.Scan(&payer.ExpenseID, &payer.UserID, &payer.Amount); err != nil {
+			c.JSON(500, gin.H{"error": "failed to scan payer"})
 			return
 		}
+		exp.Payers = append(exp.Payers, payer)
 	}
 
-	// Commit
-	if err := tx.Commit(c.Request.Context()); err != nil {
-		c.JSON(500, gin.H{"error": "failed to commit transaction"})
+	itemRows, err := db.Pool.Query(c.Request.Context(),
+		`SELECT ei.id, ei.description, ei.amount, COALESCE(array_agg(eia.user_id) FILTER (WHERE eia.user_id IS NOT NULL), '{}')
+		 FROM expense_items ei
+		 LEFT JOIN expense_item_assignees eia ON eia.item_id = ei.id
+		 WHERE ei.expense_id = $1
+		 GROUP BY ei.id
+		 ORDER BY ei.position`,
+		expenseID)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to get expense items"})
 		return
 	}
+	defer itemRows.Close()
 
-	// Load splits for response
-	exp.Splits = make([]ExpenseSplit, len(parsedSplits))
-	for i, split := range parsedSplits {
-		exp.Splits[i] = ExpenseSplit{
-			ExpenseID: exp.ID,
-			UserID:    split.UserID,
-			Amount:    split.Amount,
+	for itemRows.Next() {
+		var item ExpenseItem
+		if err := itemRows.Scan(&item.ID, &item.Description, &item.Amount, &item.UserIDs); err != nil {
+			c.JSON(500, gin.H{"error": "failed to scan item"})
+			return
 		}
+		exp.Items = append(exp.Items, item)
 	}
 
-	c.JSON(201, exp)
+	rows, err := db.Pool.Query(c.Request.Context(),
+		"SELECT expense_id, user_id, amount FROM expense_splits WHERE expense_id = $1 ORDER BY amount DESC, user_id",
+		expenseID)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to get expense splits"})
+		return
+	}
+	defer rows.Close()
+
+	exp.Splits = []ExpenseSplit{}
+	for rows.Next() {
+		var split ExpenseSplit
+		if err := rows.Scan(&split.ExpenseID, &split.UserID, &split.Amount); err != nil {
+			c.JSON(500, gin.H{"error": "failed to scan split"})
+			return
+		}
+		exp.Splits = append(exp.Splits, split)
+	}
+
+	exp.Tags, err = loadTags(c.Request.Context(), db.Pool, expenseID)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to get expense tags"})
+		return
+	}
+
+	c.JSON(200, exp)
 }
 
-func GetGroupExpenses(c *gin.Context, db *db.DB) {
+// UpdateExpense updates the description, category or date of a group expense
+func UpdateExpense(c *gin.Context, db *db.DB) {
 	userID, ok := middleware.GetUserID(c)
 	if !ok {
 		c.JSON(401, gin.H{"error": "unauthorized"})
 		return
 	}
 
-	groupIDStr := c.Param("id")
-	groupID, err := uuid.Parse(groupIDStr)
+	expenseID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
-		c.JSON(400, gin.H{"error": "invalid group id"})
+		c.JSON(400, gin.H{"error": "invalid expense id"})
 		return
 	}
 
-	// Check if user is member of group
+	var req UpdateExpenseRequest
+	if !validation.Bind(c, &req) {
+		return
+	}
+
+	_, deletedAt, ok := authorizeExpenseEdit(c, db, expenseID, userID)
+	if !ok {
+		return
+	}
+	if deletedAt != nil {
+		c.JSON(404, gin.H{"error": "expense not found"})
+		return
+	}
+
+	if req.Description == nil && req.Category == nil && req.ExpenseDate == nil && req.Tags == nil {
+		c.JSON(400, gin.H{"error": "no fields to update"})
+		return
+	}
+
+	var exp Expense
+	err = db.WithTx(c.Request.Context(), func(tx pgx.Tx) error {
+		before, err := snapshotExpense(c.Request.Context(), tx, expenseID)
+		if err != nil {
+			return helpers.NewRequestError(500, "failed to load expense")
+		}
+
+		// When only tags change the expense is read back as is
+		row, err := sqlc.New(tx).UpdateGroupExpense(c.Request.Context(), sqlc.UpdateGroupExpenseParams{
+			ID:          expenseID,
+			Description: req.Description,
+			Category:    req.Category,
+			ExpenseDate: req.ExpenseDate,
+		})
+		if err != nil {
+			return helpers.NewRequestError(500, "failed to update expense")
+		}
+		exp = Expense{
+			ID: row.ID, GroupID: row.GroupID, Description: row.Description, TotalAmount: row.TotalAmount,
+			Currency: row.Currency, Category: row.Category, PaidBy: row.PaidBy, ExpenseDate: row.ExpenseDate,
+			CreatedBy: row.CreatedBy, Status: row.Status, CreatedAt: row.CreatedAt,
+		}
+
+		if req.Tags != nil {
+			if err := replaceTags(c.Request.Context(), tx, exp.GroupID, expenseID, normalizeTags(req.Tags)); err != nil {
+				return helpers.NewRequestError(500, "failed to update expense tags")
+			}
+		}
+
+		if err := personalexpense.SyncGroupExpense(c.Request.Context(), tx, expenseID); err != nil {
+			return helpers.NewRequestError(500, "failed to sync personal expenses")
+		}
+
+		after, err := snapshotExpense(c.Request.Context(), tx, expenseID)
+		if err != nil {
+			return helpers.NewRequestError(500, "failed to load expense")
+		}
+		exp.Tags = after.Tags
+
+		if err := recordRevision(c.Request.Context(), tx, expenseID, userID, revisionUpdate, before, after); err != nil {
+			return helpers.NewRequestError(500, "failed to record revision")
+		}
+
+		if err := event.Record(c.Request.Context(), tx, event.Event{Type: event.ExpenseUpdated, UserID: userID, GroupID: &exp.GroupID, Data: exp}); err != nil {
+			return helpers.NewRequestError(500, "failed to record event")
+		}
+		return nil
+	})
+	if err != nil {
+		helpers.RespondError(c, err, "failed to update expense")
+		return
+	}
+
+	c.JSON(200, exp)
+}
+
+// authorizeExpenseEdit loads the group and payers of an expense and checks
+// that the user may modify it under the group's settings
+func authorizeExpenseEdit(c *gin.Context, db *db.DB, expenseID, userID uuid.UUID) (groupID uuid.UUID, deletedAt *time.Time, ok bool) {
+	var isPayer bool
+	err := db.Pool.QueryRow(c.Request.Context(),
+		`SELECT group_id, deleted_at,
+		        EXISTS(SELECT 1 FROM expense_payers WHERE expense_id = expenses.id AND user_id = $2)
+		 FROM expenses WHERE id = $1`,
+		expenseID, userID).Scan(&groupID, &deletedAt, &isPayer)
+	if err != nil {
+		c.JSON(404, gin.H{"error": "expense not found"})
+		return uuid.Nil, nil, false
+	}
+
 	isMember, err := helpers.IsGroupMember(c.Request.Context(), db, groupID, userID)
 	if err != nil || !isMember {
 		c.JSON(403, gin.H{"error": "not a member of the group"})
-		return
+		return uuid.Nil, nil, false
 	}
 
-	// Parse pagination parameters
-	limit := 50 // default
-	if limitStr := c.Query("limit"); limitStr != "" {
-		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
-			limit = l
-		}
+	settings, err := group.LoadSettings(c.Request.Context(), db, groupID)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to get group settings"})
+		return uuid.Nil, nil, false
+	}
+	if !settings.AllowNonPayerEdits && !isPayer {
+		c.JSON(403, gin.H{"error": "only the payer can modify this expense"})
+		return uuid.Nil, nil, false
 	}
 
-	offset := 0
-	if offsetStr := c.Query("offset"); offsetStr != "" {
-		if o, err := strconv.Atoi(offsetStr); err == nil && o >= 0 {
-			offset = o
-		}
+	return groupID, deletedAt, true
+}
+
+// DeleteExpense soft deletes a group expense so it can be restored later
+func DeleteExpense(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
 	}
 
-	// Get expenses with pagination
-	rows, err := db.Pool.Query(c.Request.Context(),
-		"SELECT id, group_id, description, total_amount, paid_by, created_at FROM expenses WHERE group_id = $1 ORDER BY created_at DESC LIMIT $2 OFFSET $3",
-		groupID, limit, offset)
+	expenseID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
-		c.JSON(500, gin.H{"error": "failed to get expenses"})
+		c.JSON(400, gin.H{"error": "invalid expense id"})
 		return
 	}
-	defer rows.Close()
 
-	var expenses []Expense
-	for rows.Next() {
-		var exp Expense
-		if err := rows.Scan(&exp.ID, &exp.GroupID, &exp.Description, &exp.TotalAmount, &exp.PaidBy, &exp.CreatedAt); err != nil {
-			c.JSON(500, gin.H{"error": "failed to scan expense"})
-			return
+	groupID, deletedAt, ok := authorizeExpenseEdit(c, db, expenseID, userID)
+	if !ok {
+		return
+	}
+	if deletedAt != nil {
+		c.JSON(404, gin.H{"error": "expense not found"})
+		return
+	}
+
+	err = db.WithTx(c.Request.Context(), func(tx pgx.Tx) error {
+		if _, err := softdelete.Delete(c.Request.Context(), tx, softdelete.Expenses, expenseID, userID); err != nil {
+			return helpers.NewRequestError(500, "failed to delete expense")
+		}
+
+		if err := personalexpense.SyncGroupExpense(c.Request.Context(), tx, expenseID); err != nil {
+			return helpers.NewRequestError(500, "failed to sync personal expenses")
+		}
+
+		snapshot, err := snapshotExpense(c.Request.Context(), tx, expenseID)
+		if err != nil {
+			return helpers.NewRequestError(500, "failed to load expense")
+		}
+
+		if err := recordRevision(c.Request.Context(), tx, expenseID, userID, revisionDelete, snapshot, nil); err != nil {
+			return helpers.NewRequestError(500, "failed to record revision")
+		}
+
+		deleted := event.Deleted{ID: expenseID, GroupID: groupID}
+		if err := event.Record(c.Request.Context(), tx, event.Event{Type: event.ExpenseDeleted, UserID: userID, GroupID: &groupID, Data: deleted}); err != nil {
+			return helpers.NewRequestError(500, "failed to record event")
 		}
-		expenses = append(expenses, exp)
+		return nil
+	})
+	if err != nil {
+		helpers.RespondError(c, err, "failed to delete expense")
+		return
 	}
 
-	// Get total count for pagination metadata
-	var totalCount int
-	err = db.Pool.QueryRow(c.Request.Context(),
-		"SELECT COUNT(*) FROM expenses WHERE group_id = $1", groupID).Scan(&totalCount)
+	c.JSON(200, gin.H{"message": "expense deleted successfully"})
+}
+
+// RestoreExpense undoes a soft delete
+func RestoreExpense(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	expenseID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
-		c.JSON(500, gin.H{"error": "failed to get total count"})
+		c.JSON(400, gin.H{"error": "invalid expense id"})
 		return
 	}
 
-	c.JSON(200, gin.H{
-		"expenses": expenses,
-		"pagination": gin.H{
-			"limit":  limit,
-			"offset": offset,
-			"total":  totalCount,
-		},
+	_, deletedAt, ok := authorizeExpenseEdit(c, db, expenseID, userID)
+	if !ok {
+		return
+	}
+	if deletedAt == nil {
+		c.JSON(400, gin.H{"error": "expense is not deleted"})
+		return
+	}
+
+	err = db.WithTx(c.Request.Context(), func(tx pgx.Tx) error {
+		if _, err := softdelete.Restore(c.Request.Context(), tx, softdelete.Expenses, expenseID); err != nil {
+			return helpers.NewRequestError(500, "failed to restore expense")
+		}
+
+		if err := personalexpense.SyncGroupExpense(c.Request.Context(), tx, expenseID); err != nil {
+			return helpers.NewRequestError(500, "failed to sync personal expenses")
+		}
+
+		snapshot, err := snapshotExpense(c.Request.Context(), tx, expenseID)
+		if err != nil {
+			return helpers.NewRequestError(500, "failed to load expense")
+		}
+
+		if err := recordRevision(c.Request.Context(), tx, expenseID, userID, revisionRestore, nil, snapshot); err != nil {
+			return helpers.NewRequestError(500, "failed to record revision")
+		}
+
+		exp, err := loadEventExpense(c.Request.Context(), tx, expenseID)
+		if err != nil {
+			return helpers.NewRequestError(500, "failed to load expense")
+		}
+		if err := event.Record(c.Request.Context(), tx, event.Event{Type: event.ExpenseRestored, UserID: userID, GroupID: &exp.GroupID, Data: exp}); err != nil {
+			return helpers.NewRequestError(500, "failed to record event")
+		}
+		return nil
 	})
+	if err != nil {
+		helpers.RespondError(c, err, "failed to restore expense")
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "expense restored successfully"})
 }
@@ -88,6 +88,29 @@ func TestCreateExpense_Validation(t *testing.T) {
 			expectedStatus: 201,
 			expectError:    false,
 		},
+		{
+			name: "equal split mode",
+			requestBody: CreateExpenseRequest{
+				GroupID:      groupID,
+				Description:  "Test Expense",
+				TotalAmount:  "100.00",
+				SplitMode:    "equal",
+				Participants: []uuid.UUID{userID, userID2},
+			},
+			expectedStatus: 201,
+			expectError:    false,
+		},
+		{
+			name: "equal split mode without participants",
+			requestBody: CreateExpenseRequest{
+				GroupID:     groupID,
+				Description: "Test Expense",
+				TotalAmount: "100.00",
+				SplitMode:   "equal",
+			},
+			expectedStatus: 400,
+			expectError:    true,
+		},
 		{
 			name: "splits sum mismatch",
 			requestBody: CreateExpenseRequest{
@@ -158,7 +181,7 @@ func TestCreateExpense_Validation(t *testing.T) {
 			c.Request = httptest.NewRequest("POST", "/expenses", bytes.NewBuffer(body))
 			c.Request.Header.Set("Content-Type", "application/json")
 
-			CreateExpense(c, testDB)
+			CreateExpense(c, NewService(NewRepository(testDB)))
 
 			assert.Equal(t, tt.expectedStatus, w.Code)
 
@@ -0,0 +1,61 @@
+package expense
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+	"github.com/yanonymousV2/finance-manager-backend/internal/group"
+	"github.com/yanonymousV2/finance-manager-backend/internal/helpers"
+)
+
+// Repository is the storage Service works against
+type Repository interface {
+	IsMember(ctx context.Context, groupID, userID uuid.UUID) (bool, error)
+	Currency(ctx context.Context, groupID uuid.UUID) (string, error)
+	Settings(ctx context.Context, groupID uuid.UUID) (group.Settings, error)
+	// Create inserts the expenses with their payers, items, splits and tags
+	// in one transaction and records an expense.created event for each
+	Create(ctx context.Context, userID uuid.UUID, expenses ...*preparedExpense) ([]Expense, error)
+}
+
+// pgRepository is the Postgres Repository
+type pgRepository struct {
+	db *db.DB
+}
+
+func NewRepository(db *db.DB) Repository {
+	return &pgRepository{db: db}
+}
+
+func (r *pgRepository) IsMember(ctx context.Context, groupID, userID uuid.UUID) (bool, error) {
+	return helpers.IsGroupMember(ctx, r.db, groupID, userID)
+}
+
+func (r *pgRepository) Currency(ctx context.Context, groupID uuid.UUID) (string, error) {
+	return helpers.GetGroupCurrency(ctx, r.db, groupID)
+}
+
+func (r *pgRepository) Settings(ctx context.Context, groupID uuid.UUID) (group.Settings, error) {
+	return group.LoadSettings(ctx, r.db, groupID)
+}
+
+func (r *pgRepository) Create(ctx context.Context, userID uuid.UUID, prepared ...*preparedExpense) ([]Expense, error) {
+	expenses := make([]Expense, len(prepared))
+	err := r.db.WithTx(ctx, func(tx pgx.Tx) error {
+		batch := &pgx.Batch{}
+		for i, p := range prepared {
+			queueExpense(batch, p, &expenses[i])
+		}
+		if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+			return err
+		}
+		return recordCreated(ctx, tx, userID, expenses...)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return expenses, nil
+}
@@ -0,0 +1,178 @@
+package expense
+
+import (
+	"context"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+	"github.com/yanonymousV2/finance-manager-backend/internal/helpers"
+	"github.com/yanonymousV2/finance-manager-backend/internal/middleware"
+)
+
+// Revision actions recorded in expense_revisions
+const (
+	revisionUpdate  = "update"
+	revisionDelete  = "delete"
+	revisionRestore = "restore"
+)
+
+// ExpenseSnapshot is the state of an expense before or after a revision
+type ExpenseSnapshot struct {
+	Description string          `json:"description"`
+	TotalAmount decimal.Decimal `json:"total_amount"`
+	Category    *string         `json:"category,omitempty"`
+	ExpenseDate time.Time       `json:"expense_date"`
+	Splits      []ExpenseSplit  `json:"splits"`
+	Tags        []string        `json:"tags"`
+}
+
+type Revision struct {
+	ID         uuid.UUID        `json:"id" db:"id"`
+	ExpenseID  uuid.UUID        `json:"expense_id" db:"expense_id"`
+	EditedBy   uuid.UUID        `json:"edited_by" db:"edited_by"`
+	EditorName string           `json:"editor_name"`
+	Action     string           `json:"action" db:"action"`
+	Before     *ExpenseSnapshot `json:"before" db:"before"`
+	After      *ExpenseSnapshot `json:"after" db:"after"`
+	CreatedAt  time.Time        `json:"created_at" db:"created_at"`
+}
+
+// loadEventExpense reads an expense with its splits and tags in tx, as the
+// data of the events that change whether it counts toward balances
+func loadEventExpense(ctx context.Context, tx pgx.Tx, expenseID uuid.UUID) (Expense, error) {
+	var exp Expense
+	err := tx.QueryRow(ctx,
+		`SELECT id, group_id, description, total_amount, currency, category, paid_by, expense_date,
+		        created_by, status, reviewed_by, reviewed_at, created_at
+		 FROM expenses WHERE id = $1`,
+		expenseID).Scan(&exp.ID, &exp.GroupID, &exp.Description, &exp.TotalAmount, &exp.Currency, &exp.Category,
+		&exp.PaidBy, &exp.ExpenseDate, &exp.CreatedBy, &exp.Status, &exp.ReviewedBy, &exp.ReviewedAt, &exp.CreatedAt)
+	if err != nil {
+		return Expense{}, err
+	}
+
+	rows, err := tx.Query(ctx,
+		"SELECT expense_id, user_id, amount FROM expense_splits WHERE expense_id = $1 ORDER BY amount DESC, user_id",
+		expenseID)
+	if err != nil {
+		return Expense{}, err
+	}
+	exp.Splits, err = pgx.CollectRows(rows, pgx.RowToStructByPos[ExpenseSplit])
+	if err != nil {
+		return Expense{}, err
+	}
+
+	exp.Tags, err = loadTags(ctx, tx, expenseID)
+	if err != nil {
+		return Expense{}, err
+	}
+	return exp, nil
+}
+
+// snapshotExpense reads the current state of an expense inside tx
+func snapshotExpense(ctx context.Context, tx pgx.Tx, expenseID uuid.UUID) (*ExpenseSnapshot, error) {
+	var s ExpenseSnapshot
+	err := tx.QueryRow(ctx,
+		"SELECT description, total_amount, category, expense_date FROM expenses WHERE id = $1",
+		expenseID).Scan(&s.Description, &s.TotalAmount, &s.Category, &s.ExpenseDate)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(ctx,
+		"SELECT expense_id, user_id, amount FROM expense_splits WHERE expense_id = $1 ORDER BY user_id",
+		expenseID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	s.Splits = []ExpenseSplit{}
+	for rows.Next() {
+		var split ExpenseSplit
+		if err := rows.Scan(&split.ExpenseID, &split.UserID, &split.Amount); err != nil {
+			return nil, err
+		}
+		s.Splits = append(s.Splits, split)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	s.Tags, err = loadTags(ctx, tx, expenseID)
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+// recordRevision stores a revision of an expense inside tx
+func recordRevision(ctx context.Context, tx pgx.Tx, expenseID, userID uuid.UUID, action string, before, after *ExpenseSnapshot) error {
+	_, err := tx.Exec(ctx,
+		`INSERT INTO expense_revisions (expense_id, edited_by, action, before, after)
+		 VALUES ($1, $2, $3, $4, $5)`,
+		expenseID, userID, action, before, after)
+	return err
+}
+
+// GetExpenseHistory returns the revisions of a group expense, newest first
+func GetExpenseHistory(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	expenseID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid expense id"})
+		return
+	}
+
+	// History stays visible for deleted expenses so deletions can be traced
+	var groupID uuid.UUID
+	err = db.Pool.QueryRow(c.Request.Context(),
+		"SELECT group_id FROM expenses WHERE id = $1", expenseID).Scan(&groupID)
+	if err != nil {
+		c.JSON(404, gin.H{"error": "expense not found"})
+		return
+	}
+
+	isMember, err := helpers.IsGroupMember(c.Request.Context(), db, groupID, userID)
+	if err != nil || !isMember {
+		c.JSON(403, gin.H{"error": "not a member of the group"})
+		return
+	}
+
+	rows, err := db.Pool.Query(c.Request.Context(),
+		`SELECT er.id, er.expense_id, er.edited_by, COALESCE(u.display_name, u.email, ''), er.action,
+		        er.before, er.after, er.created_at
+		 FROM expense_revisions er
+		 LEFT JOIN users u ON u.id = er.edited_by
+		 WHERE er.expense_id = $1
+		 ORDER BY er.created_at DESC, er.id`,
+		expenseID)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to get expense history"})
+		return
+	}
+	defer rows.Close()
+
+	revisions := []Revision{}
+	for rows.Next() {
+		var r Revision
+		if err := rows.Scan(&r.ID, &r.ExpenseID, &r.EditedBy, &r.EditorName, &r.Action,
+			&r.Before, &r.After, &r.CreatedAt); err != nil {
+			c.JSON(500, gin.H{"error": "failed to scan revision"})
+			return
+		}
+		revisions = append(revisions, r)
+	}
+
+	c.JSON(200, revisions)
+}
@@ -0,0 +1,273 @@
+package expense
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/group"
+	"github.com/yanonymousV2/finance-manager-backend/internal/helpers"
+)
+
+// Service holds the rules for creating expenses, independent of HTTP and of
+// the storage behind Repository. Requests that break a rule fail with a
+// *helpers.RequestError.
+type Service struct {
+	repo Repository
+}
+
+func NewService(repo Repository) *Service {
+	return &Service{repo: repo}
+}
+
+// IndexError is an error for one expense of a CreateMany request
+type IndexError struct {
+	Index int
+	Err   error
+}
+
+func (e *IndexError) Error() string {
+	return fmt.Sprintf("expense %d: %v", e.Index, e.Err)
+}
+
+func (e *IndexError) Unwrap() error {
+	return e.Err
+}
+
+// Create creates an expense on behalf of userID
+func (s *Service) Create(ctx context.Context, userID uuid.UUID, req CreateExpenseRequest) (Expense, error) {
+	p, err := s.prepare(ctx, userID, req)
+	if err != nil {
+		return Expense{}, err
+	}
+
+	expenses, err := s.repo.Create(ctx, userID, p)
+	if err != nil {
+		return Expense{}, fmt.Errorf("create expense: %w", err)
+	}
+	return expenses[0], nil
+}
+
+// CreateMany creates all the expenses or none of them. Every request is
+// validated before anything is stored; a bad one fails with an *IndexError.
+func (s *Service) CreateMany(ctx context.Context, userID uuid.UUID, reqs []CreateExpenseRequest) ([]Expense, error) {
+	prepared := make([]*preparedExpense, len(reqs))
+	for i, req := range reqs {
+		p, err := s.prepare(ctx, userID, req)
+		if err != nil {
+			return nil, &IndexError{Index: i, Err: err}
+		}
+		prepared[i] = p
+	}
+
+	expenses, err := s.repo.Create(ctx, userID, prepared...)
+	if err != nil {
+		return nil, fmt.Errorf("create expenses: %w", err)
+	}
+	return expenses, nil
+}
+
+// prepare validates a create request on behalf of userID and computes its
+// payers and splits. Errors are *helpers.RequestError.
+func (s *Service) prepare(ctx context.Context, userID uuid.UUID, req CreateExpenseRequest) (*preparedExpense, error) {
+	// Parse total amount
+	totalAmount, err := decimal.NewFromString(req.TotalAmount)
+	if err != nil {
+		return nil, helpers.NewRequestError(400, "invalid total amount format")
+	}
+
+	if totalAmount.LessThanOrEqual(decimal.Zero) {
+		return nil, helpers.NewRequestError(400, "total amount must be greater than 0")
+	}
+
+	groupID := req.GroupID
+
+	// Check if user is member of group
+	isMember, err := s.repo.IsMember(ctx, groupID, userID)
+	if err != nil || !isMember {
+		return nil, helpers.NewRequestError(403, "not a member of the group")
+	}
+
+	// Expenses must be recorded in the group's currency
+	currency, err := s.repo.Currency(ctx, groupID)
+	if err != nil {
+		return nil, helpers.NewRequestError(500, "failed to get group currency")
+	}
+	if req.Currency != "" && req.Currency != currency {
+		return nil, helpers.NewRequestError(400, "currency does not match group currency")
+	}
+
+	settings, err := s.repo.Settings(ctx, groupID)
+	if err != nil {
+		return nil, helpers.NewRequestError(500, "failed to get group settings")
+	}
+
+	// Validate payers: defaults to the current user paying the full amount.
+	// The first payer is recorded as paid_by.
+	parsedPayers := []splitAmount{{UserID: userID, Amount: totalAmount}}
+	if len(req.Payers) > 0 {
+		parsedPayers = make([]splitAmount, len(req.Payers))
+		payerSum := decimal.Zero
+		payerIDs := make(map[uuid.UUID]bool)
+		for i, payer := range req.Payers {
+			if payerIDs[payer.UserID] {
+				return nil, helpers.NewRequestError(400, "duplicate user in payers")
+			}
+			payerIDs[payer.UserID] = true
+
+			amount, err := decimal.NewFromString(payer.Amount)
+			if err != nil {
+				return nil, helpers.NewRequestError(400, "invalid payer amount format")
+			}
+			if amount.LessThanOrEqual(decimal.Zero) {
+				return nil, helpers.NewRequestError(400, "payer amount must be greater than 0")
+			}
+
+			isMember, err := s.repo.IsMember(ctx, groupID, payer.UserID)
+			if err != nil || !isMember {
+				return nil, helpers.NewRequestError(400, "all payers must be group members")
+			}
+
+			parsedPayers[i] = splitAmount{UserID: payer.UserID, Amount: amount}
+			payerSum = payerSum.Add(amount)
+		}
+		if !payerSum.Equal(totalAmount) {
+			return nil, helpers.NewRequestError(400, "payers sum does not match total amount")
+		}
+	}
+	paidBy := parsedPayers[0].UserID
+
+	// Validate splits: all users are members, sum == total
+	splitSum := decimal.Zero
+	userIDs := make(map[uuid.UUID]bool)
+	parsedSplits := make([]splitAmount, len(req.Splits))
+
+	for i, split := range req.Splits {
+		if userIDs[split.UserID] {
+			return nil, helpers.NewRequestError(400, "duplicate user in splits")
+		}
+		userIDs[split.UserID] = true
+
+		// Parse split amount
+		amount, err := decimal.NewFromString(split.Amount)
+		if err != nil {
+			return nil, helpers.NewRequestError(400, "invalid split amount format")
+		}
+
+		if amount.LessThan(decimal.Zero) {
+			return nil, helpers.NewRequestError(400, "split amount cannot be negative")
+		}
+
+		parsedSplits[i].UserID = split.UserID
+		parsedSplits[i].Amount = amount
+		splitSum = splitSum.Add(amount)
+	}
+
+	// Equal splits only take a participant list; the server computes amounts
+	if req.SplitMode == group.SplitModeEqual {
+		if len(req.Splits) > 0 {
+			return nil, helpers.NewRequestError(400, "splits cannot be combined with equal split mode")
+		}
+		if len(req.Participants) == 0 {
+			return nil, helpers.NewRequestError(400, "participants are required for equal split mode")
+		}
+		for _, uid := range req.Participants {
+			if userIDs[uid] {
+				return nil, helpers.NewRequestError(400, "duplicate user in participants")
+			}
+			userIDs[uid] = true
+		}
+	}
+
+	// Itemized splits are derived from line items plus tax and tip
+	var parsedItems []itemShare
+	if req.SplitMode == splitModeItemized {
+		if len(req.Splits) > 0 {
+			return nil, helpers.NewRequestError(400, "splits cannot be combined with itemized split mode")
+		}
+		if len(req.Items) == 0 {
+			return nil, helpers.NewRequestError(400, "items are required for itemized split mode")
+		}
+		itemsSum := decimal.Zero
+		for _, item := range req.Items {
+			amount, err := decimal.NewFromString(item.Amount)
+			if err != nil || amount.IsNegative() || !amount.Equal(amount.Round(2)) {
+				return nil, helpers.NewRequestError(400, "invalid item amount")
+			}
+			assignees := make(map[uuid.UUID]bool)
+			for _, uid := range item.UserIDs {
+				if assignees[uid] {
+					return nil, helpers.NewRequestError(400, "duplicate user in item")
+				}
+				assignees[uid] = true
+				userIDs[uid] = true
+			}
+			parsedItems = append(parsedItems, itemShare{Description: item.Description, Amount: amount, UserIDs: item.UserIDs})
+			itemsSum = itemsSum.Add(amount)
+		}
+		if itemsSum.GreaterThan(totalAmount) {
+			return nil, helpers.NewRequestError(400, "items sum exceeds total amount")
+		}
+	} else if len(req.Items) > 0 {
+		return nil, helpers.NewRequestError(400, "items require itemized split mode")
+	}
+
+	if req.SplitMode == splitModeExact && len(req.Splits) == 0 {
+		return nil, helpers.NewRequestError(400, "splits are required for exact split mode")
+	}
+
+	// Server-computed splits: equal shares between participants, or the
+	// group's default split mode when no splits are given
+	if len(req.Splits) == 0 {
+		if !totalAmount.Equal(totalAmount.Round(2)) {
+			return nil, helpers.NewRequestError(400, "total amount cannot have more than 2 decimal places")
+		}
+		switch req.SplitMode {
+		case group.SplitModeEqual:
+			parsedSplits = equalSplits(settings, totalAmount, paidBy, req.Participants)
+		case splitModeItemized:
+			parsedSplits = itemizedSplits(totalAmount, parsedItems, paidBy, settings.RoundingRule)
+		default:
+			parsedSplits = defaultSplits(settings, totalAmount, paidBy)
+		}
+		for _, split := range parsedSplits {
+			splitSum = splitSum.Add(split.Amount)
+		}
+	}
+
+	if !splitSum.Equal(totalAmount) {
+		return nil, helpers.NewRequestError(400, "splits sum does not match total amount")
+	}
+
+	// Check all users are members
+	for uid := range userIDs {
+		isMember, err = s.repo.IsMember(ctx, groupID, uid)
+		if err != nil || !isMember {
+			return nil, helpers.NewRequestError(400, "all split users must be group members")
+		}
+	}
+
+	// Expenses above the group's approval threshold wait for another member
+	status := statusApproved
+	if settings.ApprovalThreshold != nil && totalAmount.GreaterThan(*settings.ApprovalThreshold) {
+		status = statusPending
+	}
+
+	return &preparedExpense{
+		GroupID:     groupID,
+		Description: req.Description,
+		TotalAmount: totalAmount,
+		Currency:    currency,
+		Category:    req.Category,
+		ExpenseDate: req.ExpenseDate,
+		PaidBy:      paidBy,
+		Payers:      parsedPayers,
+		Items:       parsedItems,
+		Splits:      parsedSplits,
+		Tags:        normalizeTags(req.Tags),
+		CreatedBy:   userID,
+		Status:      status,
+	}, nil
+}
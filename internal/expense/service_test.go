@@ -0,0 +1,279 @@
+package expense
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/group"
+	"github.com/yanonymousV2/finance-manager-backend/internal/helpers"
+)
+
+// memoryRepository is an in-memory Repository
+type memoryRepository struct {
+	members  map[uuid.UUID][]uuid.UUID
+	currency map[uuid.UUID]string
+	settings map[uuid.UUID]group.Settings
+	expenses []Expense
+}
+
+func newMemoryRepository() *memoryRepository {
+	return &memoryRepository{
+		members:  map[uuid.UUID][]uuid.UUID{},
+		currency: map[uuid.UUID]string{},
+		settings: map[uuid.UUID]group.Settings{},
+	}
+}
+
+// addGroup adds a group in USD with equal default splits between members
+func (r *memoryRepository) addGroup(members ...uuid.UUID) uuid.UUID {
+	groupID := uuid.New()
+	r.members[groupID] = members
+	r.currency[groupID] = "USD"
+	shares := make([]group.MemberShare, len(members))
+	for i, uid := range members {
+		shares[i] = group.MemberShare{UserID: uid, Shares: 1}
+	}
+	r.settings[groupID] = group.Settings{
+		GroupID:          groupID,
+		DefaultSplitMode: group.SplitModeEqual,
+		RoundingRule:     group.RoundingPayer,
+		MemberShares:     shares,
+	}
+	return groupID
+}
+
+func (r *memoryRepository) IsMember(_ context.Context, groupID, userID uuid.UUID) (bool, error) {
+	for _, id := range r.members[groupID] {
+		if id == userID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *memoryRepository) Currency(_ context.Context, groupID uuid.UUID) (string, error) {
+	return r.currency[groupID], nil
+}
+
+func (r *memoryRepository) Settings(_ context.Context, groupID uuid.UUID) (group.Settings, error) {
+	return r.settings[groupID], nil
+}
+
+func (r *memoryRepository) Create(_ context.Context, _ uuid.UUID, prepared ...*preparedExpense) ([]Expense, error) {
+	expenses := make([]Expense, len(prepared))
+	for i, p := range prepared {
+		exp := Expense{ID: uuid.New(), GroupID: p.GroupID, Description: p.Description, TotalAmount: p.TotalAmount,
+			Currency: p.Currency, PaidBy: p.PaidBy, CreatedBy: &p.CreatedBy, Status: p.Status, Tags: p.Tags}
+		for _, split := range p.Splits {
+			exp.Splits = append(exp.Splits, ExpenseSplit{ExpenseID: exp.ID, UserID: split.UserID, Amount: split.Amount})
+		}
+		for _, payer := range p.Payers {
+			exp.Payers = append(exp.Payers, ExpensePayer{ExpenseID: exp.ID, UserID: payer.UserID, Amount: payer.Amount})
+		}
+		expenses[i] = exp
+	}
+	r.expenses = append(r.expenses, expenses...)
+	return expenses, nil
+}
+
+// splitsOf returns an expense's splits by user, as strings
+func splitsOf(exp Expense) map[uuid.UUID]string {
+	splits := make(map[uuid.UUID]string, len(exp.Splits))
+	for _, s := range exp.Splits {
+		splits[s.UserID] = s.Amount.String()
+	}
+	return splits
+}
+
+// requestError asserts err is a *helpers.RequestError with status and message
+func requestError(t *testing.T, err error, status int, message string) {
+	t.Helper()
+	var reqErr *helpers.RequestError
+	require.ErrorAs(t, err, &reqErr)
+	assert.Equal(t, status, reqErr.Status)
+	assert.Equal(t, message, reqErr.Message)
+}
+
+func TestServiceCreateDefaultSplits(t *testing.T) {
+	repo := newMemoryRepository()
+	userA, userB, userC := uuid.New(), uuid.New(), uuid.New()
+	groupID := repo.addGroup(userA, userB, userC)
+
+	exp, err := NewService(repo).Create(context.Background(), userB, CreateExpenseRequest{
+		GroupID: groupID, Description: "Dinner", TotalAmount: "100.00",
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, "USD", exp.Currency)
+	assert.Equal(t, userB, exp.PaidBy, "the creator pays unless payers are given")
+	assert.Equal(t, statusApproved, exp.Status)
+	assert.Equal(t, map[uuid.UUID]string{userA: "33.33", userB: "33.34", userC: "33.33"}, splitsOf(exp),
+		"the leftover cent goes to the payer")
+}
+
+func TestServiceCreateEqualSplits(t *testing.T) {
+	repo := newMemoryRepository()
+	userA, userB, userC := uuid.New(), uuid.New(), uuid.New()
+	groupID := repo.addGroup(userA, userB, userC)
+
+	exp, err := NewService(repo).Create(context.Background(), userA, CreateExpenseRequest{
+		GroupID: groupID, Description: "Taxi", TotalAmount: "30", SplitMode: group.SplitModeEqual,
+		Participants: []uuid.UUID{userA, userC},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[uuid.UUID]string{userA: "15", userC: "15"}, splitsOf(exp))
+}
+
+func TestServiceCreateItemizedSplits(t *testing.T) {
+	repo := newMemoryRepository()
+	userA, userB := uuid.New(), uuid.New()
+	groupID := repo.addGroup(userA, userB)
+
+	exp, err := NewService(repo).Create(context.Background(), userA, CreateExpenseRequest{
+		GroupID: groupID, Description: "Lunch", TotalAmount: "33", SplitMode: splitModeItemized,
+		Items: []CreateExpenseItemRequest{
+			{Description: "Pasta", Amount: "20", UserIDs: []uuid.UUID{userA}},
+			{Description: "Salad", Amount: "10", UserIDs: []uuid.UUID{userB}},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[uuid.UUID]string{userA: "22", userB: "11"}, splitsOf(exp), "tax is spread by item subtotal")
+}
+
+func TestServiceCreateApprovalThreshold(t *testing.T) {
+	repo := newMemoryRepository()
+	userA, userB := uuid.New(), uuid.New()
+	groupID := repo.addGroup(userA, userB)
+	settings := repo.settings[groupID]
+	threshold := decimal.NewFromInt(50)
+	settings.ApprovalThreshold = &threshold
+	repo.settings[groupID] = settings
+	service := NewService(repo)
+
+	exp, err := service.Create(context.Background(), userA, CreateExpenseRequest{
+		GroupID: groupID, Description: "Groceries", TotalAmount: "50",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, statusApproved, exp.Status)
+
+	exp, err = service.Create(context.Background(), userA, CreateExpenseRequest{
+		GroupID: groupID, Description: "Hotel", TotalAmount: "50.01",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, statusPending, exp.Status)
+}
+
+func TestServiceCreateRules(t *testing.T) {
+	repo := newMemoryRepository()
+	userA, userB, outsider := uuid.New(), uuid.New(), uuid.New()
+	groupID := repo.addGroup(userA, userB)
+
+	tests := []struct {
+		name    string
+		userID  uuid.UUID
+		req     CreateExpenseRequest
+		status  int
+		message string
+	}{
+		{
+			name:    "not a member",
+			userID:  outsider,
+			req:     CreateExpenseRequest{GroupID: groupID, Description: "x", TotalAmount: "10"},
+			status:  403,
+			message: "not a member of the group",
+		},
+		{
+			name:    "zero total",
+			userID:  userA,
+			req:     CreateExpenseRequest{GroupID: groupID, Description: "x", TotalAmount: "0"},
+			status:  400,
+			message: "total amount must be greater than 0",
+		},
+		{
+			name:    "other currency",
+			userID:  userA,
+			req:     CreateExpenseRequest{GroupID: groupID, Description: "x", TotalAmount: "10", Currency: "EUR"},
+			status:  400,
+			message: "currency does not match group currency",
+		},
+		{
+			name:   "payers short of total",
+			userID: userA,
+			req: CreateExpenseRequest{GroupID: groupID, Description: "x", TotalAmount: "10",
+				Payers: []CreateExpensePayerRequest{{UserID: userA, Amount: "4"}, {UserID: userB, Amount: "5"}}},
+			status:  400,
+			message: "payers sum does not match total amount",
+		},
+		{
+			name:   "splits short of total",
+			userID: userA,
+			req: CreateExpenseRequest{GroupID: groupID, Description: "x", TotalAmount: "10",
+				Splits: []CreateExpenseSplitRequest{{UserID: userA, Amount: "5"}, {UserID: userB, Amount: "4"}}},
+			status:  400,
+			message: "splits sum does not match total amount",
+		},
+		{
+			name:   "split with a non-member",
+			userID: userA,
+			req: CreateExpenseRequest{GroupID: groupID, Description: "x", TotalAmount: "10",
+				Splits: []CreateExpenseSplitRequest{{UserID: userA, Amount: "5"}, {UserID: outsider, Amount: "5"}}},
+			status:  400,
+			message: "all split users must be group members",
+		},
+		{
+			name:   "items without itemized mode",
+			userID: userA,
+			req: CreateExpenseRequest{GroupID: groupID, Description: "x", TotalAmount: "10",
+				Items: []CreateExpenseItemRequest{{Description: "a", Amount: "10", UserIDs: []uuid.UUID{userA}}}},
+			status:  400,
+			message: "items require itemized split mode",
+		},
+		{
+			name:    "fractional cents without splits",
+			userID:  userA,
+			req:     CreateExpenseRequest{GroupID: groupID, Description: "x", TotalAmount: "10.001"},
+			status:  400,
+			message: "total amount cannot have more than 2 decimal places",
+		},
+	}
+
+	service := NewService(repo)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.Create(context.Background(), tt.userID, tt.req)
+			requestError(t, err, tt.status, tt.message)
+		})
+	}
+	assert.Empty(t, repo.expenses)
+}
+
+func TestServiceCreateMany(t *testing.T) {
+	repo := newMemoryRepository()
+	userA, userB := uuid.New(), uuid.New()
+	groupID := repo.addGroup(userA, userB)
+	service := NewService(repo)
+
+	_, err := service.CreateMany(context.Background(), userA, []CreateExpenseRequest{
+		{GroupID: groupID, Description: "Rent", TotalAmount: "1000"},
+		{GroupID: groupID, Description: "Power", TotalAmount: "-5"},
+	})
+	var indexErr *IndexError
+	require.True(t, errors.As(err, &indexErr))
+	assert.Equal(t, 1, indexErr.Index)
+	requestError(t, err, 400, "total amount must be greater than 0")
+	assert.Empty(t, repo.expenses, "nothing is stored when one expense is invalid")
+
+	expenses, err := service.CreateMany(context.Background(), userA, []CreateExpenseRequest{
+		{GroupID: groupID, Description: "Rent", TotalAmount: "1000"},
+		{GroupID: groupID, Description: "Power", TotalAmount: "80"},
+	})
+	require.NoError(t, err)
+	assert.Len(t, expenses, 2)
+	assert.Len(t, repo.expenses, 2)
+}
@@ -0,0 +1,162 @@
+package expense
+
+import (
+	"sort"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/group"
+)
+
+// Split modes handled only at expense creation; equal and shares live in
+// the group package because they double as group defaults
+const (
+	splitModeExact    = "exact"
+	splitModeItemized = "itemized"
+)
+
+type splitAmount struct {
+	UserID uuid.UUID
+	Amount decimal.Decimal
+}
+
+type splitWeight struct {
+	UserID uuid.UUID
+	Weight int64
+}
+
+// allocateSplits divides total between participants in proportion to their
+// weights, working in cents so the parts always add up to the total. Cents
+// left over after flooring go to the payer, or to the participants with the
+// largest fractional remainders when rounding is largest_remainder.
+func allocateSplits(total decimal.Decimal, weights []splitWeight, payer uuid.UUID, rounding string) []splitAmount {
+	if len(weights) == 0 {
+		return nil
+	}
+
+	cents := total.Shift(2).IntPart()
+	var totalWeight int64
+	for _, w := range weights {
+		totalWeight += w.Weight
+	}
+
+	base := make([]int64, len(weights))
+	remainders := make([]int64, len(weights))
+	leftover := cents
+	for i, w := range weights {
+		base[i] = cents * w.Weight / totalWeight
+		remainders[i] = cents * w.Weight % totalWeight
+		leftover -= base[i]
+	}
+
+	if leftover > 0 {
+		switch rounding {
+		case group.RoundingLargestRemainder:
+			order := make([]int, len(weights))
+			for i := range order {
+				order[i] = i
+			}
+			sort.SliceStable(order, func(a, b int) bool {
+				return remainders[order[a]] > remainders[order[b]]
+			})
+			for i := int64(0); i < leftover; i++ {
+				base[order[i%int64(len(order))]]++
+			}
+		default:
+			// Remainder goes to the payer, or the first participant if the
+			// payer is not part of the split
+			target := 0
+			for i, w := range weights {
+				if w.UserID == payer {
+					target = i
+					break
+				}
+			}
+			base[target] += leftover
+		}
+	}
+
+	splits := make([]splitAmount, len(weights))
+	for i, w := range weights {
+		splits[i] = splitAmount{UserID: w.UserID, Amount: decimal.New(base[i], -2)}
+	}
+	return splits
+}
+
+// defaultSplits splits total between all group members according to the
+// group's default split mode and rounding rule
+func defaultSplits(settings group.Settings, total decimal.Decimal, payer uuid.UUID) []splitAmount {
+	weights := make([]splitWeight, len(settings.MemberShares))
+	for i, ms := range settings.MemberShares {
+		weights[i] = splitWeight{UserID: ms.UserID, Weight: 1}
+		if settings.DefaultSplitMode == group.SplitModeShares {
+			weights[i].Weight = int64(ms.Shares)
+		}
+	}
+
+	return allocateSplits(total, weights, payer, settings.RoundingRule)
+}
+
+// equalSplits splits total evenly between participants using the group's
+// rounding rule for leftover cents
+func equalSplits(settings group.Settings, total decimal.Decimal, payer uuid.UUID, participants []uuid.UUID) []splitAmount {
+	weights := make([]splitWeight, len(participants))
+	for i, uid := range participants {
+		weights[i] = splitWeight{UserID: uid, Weight: 1}
+	}
+
+	return allocateSplits(total, weights, payer, settings.RoundingRule)
+}
+
+type itemShare struct {
+	Description string
+	Amount      decimal.Decimal
+	UserIDs     []uuid.UUID
+}
+
+// itemizedSplits splits each item evenly between its assignees, then spreads
+// whatever the total adds on top of the items (tax, tip) in proportion to
+// each member's item subtotal
+func itemizedSplits(total decimal.Decimal, items []itemShare, payer uuid.UUID, rounding string) []splitAmount {
+	var order []uuid.UUID
+	subtotals := make(map[uuid.UUID]decimal.Decimal)
+	itemsSum := decimal.Zero
+
+	for _, item := range items {
+		weights := make([]splitWeight, len(item.UserIDs))
+		for i, uid := range item.UserIDs {
+			weights[i] = splitWeight{UserID: uid, Weight: 1}
+		}
+		for _, share := range allocateSplits(item.Amount, weights, payer, rounding) {
+			if _, ok := subtotals[share.UserID]; !ok {
+				order = append(order, share.UserID)
+			}
+			subtotals[share.UserID] = subtotals[share.UserID].Add(share.Amount)
+		}
+		itemsSum = itemsSum.Add(item.Amount)
+	}
+
+	extra := total.Sub(itemsSum)
+	if extra.IsPositive() {
+		weights := make([]splitWeight, 0, len(order))
+		for _, uid := range order {
+			weights = append(weights, splitWeight{UserID: uid, Weight: subtotals[uid].Shift(2).IntPart()})
+		}
+		// Fall back to an even spread when every item is free
+		if itemsSum.IsZero() {
+			for i := range weights {
+				weights[i].Weight = 1
+			}
+		}
+		for _, share := range allocateSplits(extra, weights, payer, rounding) {
+			subtotals[share.UserID] = subtotals[share.UserID].Add(share.Amount)
+		}
+	}
+
+	splits := make([]splitAmount, len(order))
+	for i, uid := range order {
+		splits[i] = splitAmount{UserID: uid, Amount: subtotals[uid]}
+	}
+	return splits
+}
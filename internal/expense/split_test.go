@@ -0,0 +1,104 @@
+package expense
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/group"
+)
+
+func TestAllocateSplits(t *testing.T) {
+	userA := uuid.New()
+	userB := uuid.New()
+	userC := uuid.New()
+
+	tests := []struct {
+		name     string
+		total    string
+		weights  []splitWeight
+		payer    uuid.UUID
+		rounding string
+		expected []string
+	}{
+		{
+			name:     "equal split without remainder",
+			total:    "90.00",
+			weights:  []splitWeight{{userA, 1}, {userB, 1}, {userC, 1}},
+			payer:    userA,
+			rounding: group.RoundingPayer,
+			expected: []string{"30", "30", "30"},
+		},
+		{
+			name:     "remainder goes to payer",
+			total:    "100.00",
+			weights:  []splitWeight{{userA, 1}, {userB, 1}, {userC, 1}},
+			payer:    userB,
+			rounding: group.RoundingPayer,
+			expected: []string{"33.33", "33.34", "33.33"},
+		},
+		{
+			name:     "remainder goes to first participant when payer is not split",
+			total:    "100.00",
+			weights:  []splitWeight{{userB, 1}, {userC, 1}, {userA, 1}},
+			payer:    uuid.New(),
+			rounding: group.RoundingPayer,
+			expected: []string{"33.34", "33.33", "33.33"},
+		},
+		{
+			name:     "weighted shares",
+			total:    "100.00",
+			weights:  []splitWeight{{userA, 2}, {userB, 1}},
+			payer:    userA,
+			rounding: group.RoundingPayer,
+			expected: []string{"66.67", "33.33"},
+		},
+		{
+			name:     "largest remainder",
+			total:    "0.05",
+			weights:  []splitWeight{{userA, 1}, {userB, 1}, {userC, 1}},
+			payer:    userA,
+			rounding: group.RoundingLargestRemainder,
+			expected: []string{"0.02", "0.02", "0.01"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			total := decimal.RequireFromString(tt.total)
+			splits := allocateSplits(total, tt.weights, tt.payer, tt.rounding)
+
+			sum := decimal.Zero
+			for i, split := range splits {
+				assert.Equal(t, tt.weights[i].UserID, split.UserID)
+				assert.True(t, decimal.RequireFromString(tt.expected[i]).Equal(split.Amount),
+					"split %d: expected %s, got %s", i, tt.expected[i], split.Amount)
+				sum = sum.Add(split.Amount)
+			}
+			assert.True(t, sum.Equal(total))
+		})
+	}
+}
+
+func TestItemizedSplits(t *testing.T) {
+	userA := uuid.New()
+	userB := uuid.New()
+
+	items := []itemShare{
+		{Description: "Pasta", Amount: decimal.RequireFromString("20.00"), UserIDs: []uuid.UUID{userA}},
+		{Description: "Steak", Amount: decimal.RequireFromString("40.00"), UserIDs: []uuid.UUID{userB}},
+		{Description: "Wine", Amount: decimal.RequireFromString("30.00"), UserIDs: []uuid.UUID{userA, userB}},
+	}
+
+	// 90.00 of items plus 18.00 tax and tip, shared 35:55
+	total := decimal.RequireFromString("108.00")
+	splits := itemizedSplits(total, items, userA, group.RoundingPayer)
+
+	assert.Len(t, splits, 2)
+	assert.Equal(t, userA, splits[0].UserID)
+	assert.True(t, decimal.RequireFromString("42.00").Equal(splits[0].Amount), "got %s", splits[0].Amount)
+	assert.Equal(t, userB, splits[1].UserID)
+	assert.True(t, decimal.RequireFromString("66.00").Equal(splits[1].Amount), "got %s", splits[1].Amount)
+}
@@ -0,0 +1,65 @@
+package expense
+
+import (
+	"context"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+)
+
+// Tags are created per group on first use
+const (
+	insertTagSQL = `INSERT INTO tags (group_id, name) VALUES ($1, $2) ON CONFLICT (group_id, name) DO NOTHING`
+	linkTagSQL   = `INSERT INTO expense_tags (expense_id, tag_id)
+		 SELECT $1, id FROM tags WHERE group_id = $2 AND name = $3
+		 ON CONFLICT DO NOTHING`
+)
+
+// normalizeTags trims tags and drops blanks and duplicates, keeping order
+func normalizeTags(tags []string) []string {
+	seen := make(map[string]bool)
+	normalized := []string{}
+	for _, tag := range tags {
+		tag = strings.TrimSpace(tag)
+		if tag == "" || seen[tag] {
+			continue
+		}
+		seen[tag] = true
+		normalized = append(normalized, tag)
+	}
+	return normalized
+}
+
+// queueTags queues the inserts attaching tags to an expense on batch
+func queueTags(batch *pgx.Batch, groupID, expenseID uuid.UUID, tags []string) {
+	for _, tag := range tags {
+		batch.Queue(insertTagSQL, groupID, tag)
+		batch.Queue(linkTagSQL, expenseID, groupID, tag)
+	}
+}
+
+// replaceTags swaps the tags of an expense for the given set inside tx
+func replaceTags(ctx context.Context, tx pgx.Tx, groupID, expenseID uuid.UUID, tags []string) error {
+	batch := &pgx.Batch{}
+	batch.Queue("DELETE FROM expense_tags WHERE expense_id = $1", expenseID)
+	queueTags(batch, groupID, expenseID, tags)
+	return tx.SendBatch(ctx, batch).Close()
+}
+
+// querier is satisfied by both the pool and a transaction
+type querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+// loadTags returns the tag names of an expense in alphabetical order
+func loadTags(ctx context.Context, q querier, expenseID uuid.UUID) ([]string, error) {
+	rows, err := q.Query(ctx,
+		`SELECT t.name FROM expense_tags et JOIN tags t ON t.id = et.tag_id
+		 WHERE et.expense_id = $1 ORDER BY t.name`,
+		expenseID)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowTo[string])
+}
@@ -0,0 +1,138 @@
+package fxrate
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	ecbURL = "https://www.ecb.europa.eu/stats/eurofxref"
+	// ecbRecentDays is how far back the 90-day file is used from, leaving a
+	// margin for the days it's behind; older dates need the full history
+	ecbRecentDays = 85
+	// ecbFileTTL is how long a downloaded file is reused. The ECB publishes
+	// once each working day, around 16:00 CET.
+	ecbFileTTL = time.Hour
+)
+
+// ECB gets the euro foreign exchange reference rates the European Central
+// Bank publishes each working day. It needs no API key, but only has rates
+// for about 30 currencies.
+type ECB struct {
+	url    string
+	client *http.Client
+	now    func() time.Time
+
+	mu    sync.Mutex
+	files map[string]ecbFile
+}
+
+type ecbFile struct {
+	days    []ecbDay
+	fetched time.Time
+}
+
+// ecbDay is one day's rates in an ECB rates file
+type ecbDay struct {
+	Time  string `xml:"time,attr"`
+	Rates []struct {
+		Currency string `xml:"currency,attr"`
+		Rate     string `xml:"rate,attr"`
+	} `xml:"Cube"`
+}
+
+func NewECB() *ECB {
+	return &ECB{
+		url:    ecbURL,
+		client: &http.Client{Timeout: 30 * time.Second},
+		now:    time.Now,
+		files:  make(map[string]ecbFile),
+	}
+}
+
+func (e *ECB) Name() string {
+	return "ecb"
+}
+
+// Fetch returns the rates of the last working day on or before date
+func (e *ECB) Fetch(ctx context.Context, date time.Time) (Rates, error) {
+	name := "eurofxref-hist.xml"
+	if date.After(e.now().AddDate(0, 0, -ecbRecentDays)) {
+		name = "eurofxref-hist-90d.xml"
+	}
+	days, err := e.file(ctx, name)
+	if err != nil {
+		return Rates{}, err
+	}
+
+	// Days are newest first
+	want := date.Format(time.DateOnly)
+	for _, d := range days {
+		if d.Time > want {
+			continue
+		}
+		published, err := time.Parse(time.DateOnly, d.Time)
+		if err != nil {
+			return Rates{}, fmt.Errorf("invalid ECB date %q", d.Time)
+		}
+		rates := Rates{Base: "EUR", PublishedOn: published, Rates: make(map[string]decimal.Decimal, len(d.Rates))}
+		for _, r := range d.Rates {
+			rate, err := decimal.NewFromString(r.Rate)
+			if err != nil || !rate.IsPositive() {
+				// Discontinued currencies appear as N/A in older files
+				continue
+			}
+			rates.Rates[r.Currency] = rate
+		}
+		return rates, nil
+	}
+	return Rates{}, ErrNoRates
+}
+
+// file returns the days in an ECB rates file, downloading it again once
+// it's ecbFileTTL old
+func (e *ECB) file(ctx context.Context, name string) ([]ecbDay, error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if f, ok := e.files[name]; ok && e.now().Sub(f.fetched) < ecbFileTTL {
+		return f.days, nil
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, e.url+"/"+name, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("ECB returned %s", resp.Status)
+	}
+	days, err := parseECB(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	e.files[name] = ecbFile{days: days, fetched: e.now()}
+	return days, nil
+}
+
+// parseECB reads the days of an ECB rates file, an envelope with a Cube of
+// dated Cubes of rates
+func parseECB(r io.Reader) ([]ecbDay, error) {
+	var envelope struct {
+		Days []ecbDay `xml:"Cube>Cube"`
+	}
+	if err := xml.NewDecoder(r).Decode(&envelope); err != nil {
+		return nil, fmt.Errorf("invalid ECB rates file: %w", err)
+	}
+	return envelope.Days, nil
+}
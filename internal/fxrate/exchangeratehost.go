@@ -0,0 +1,101 @@
+package fxrate
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+const exchangeRateHostURL = "https://api.exchangerate.host"
+
+// ExchangeRateHost gets rates from exchangerate.host, which has every day's
+// rates for about 170 currencies against USD. It needs an access key.
+type ExchangeRateHost struct {
+	url    string
+	apiKey string
+	client *http.Client
+	now    func() time.Time
+}
+
+func NewExchangeRateHost(apiKey string) *ExchangeRateHost {
+	return &ExchangeRateHost{
+		url:    exchangeRateHostURL,
+		apiKey: apiKey,
+		client: &http.Client{Timeout: 30 * time.Second},
+		now:    time.Now,
+	}
+}
+
+func (e *ExchangeRateHost) Name() string {
+	return "exchangeratehost"
+}
+
+// exchangeRateHostResponse is the body of the live and historical
+// endpoints. Quotes are keyed by the source and the currency, e.g. USDEUR.
+type exchangeRateHostResponse struct {
+	Success bool                       `json:"success"`
+	Source  string                     `json:"source"`
+	Quotes  map[string]decimal.Decimal `json:"quotes"`
+	Error   *struct {
+		Code int    `json:"code"`
+		Type string `json:"type"`
+		Info string `json:"info"`
+	} `json:"error"`
+}
+
+// Fetch returns date's rates; today's are the latest, since the historical
+// endpoint only has days that are over
+func (e *ExchangeRateHost) Fetch(ctx context.Context, date time.Time) (Rates, error) {
+	query := url.Values{"access_key": {e.apiKey}}
+	endpoint := "/live"
+	if date.Format(time.DateOnly) != e.now().UTC().Format(time.DateOnly) {
+		endpoint = "/historical"
+		query.Set("date", date.Format(time.DateOnly))
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, e.url+endpoint+"?"+query.Encode(), nil)
+	if err != nil {
+		return Rates{}, err
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		// The error includes the URL, and with it the access key
+		var urlErr *url.Error
+		if errors.As(err, &urlErr) {
+			err = urlErr.Err
+		}
+		return Rates{}, err
+	}
+	defer resp.Body.Close()
+
+	var body exchangeRateHostResponse
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return Rates{}, fmt.Errorf("exchangerate.host returned %s", resp.Status)
+	}
+	if !body.Success {
+		if body.Error == nil {
+			return Rates{}, fmt.Errorf("exchangerate.host returned %s", resp.Status)
+		}
+		if body.Error.Code == 302 {
+			// invalid_date: before its first rates
+			return Rates{}, ErrNoRates
+		}
+		return Rates{}, fmt.Errorf("exchangerate.host: %s (%d %s)", body.Error.Info, body.Error.Code, body.Error.Type)
+	}
+
+	rates := Rates{Base: body.Source, PublishedOn: date, Rates: make(map[string]decimal.Decimal, len(body.Quotes))}
+	for pair, rate := range body.Quotes {
+		currency, ok := strings.CutPrefix(pair, body.Source)
+		if !ok || currency == "" || currency == body.Source || !rate.IsPositive() {
+			continue
+		}
+		rates.Rates[currency] = rate
+	}
+	return rates, nil
+}
@@ -0,0 +1,237 @@
+// Package fxrate provides daily exchange rates for converting amounts paid
+// in a foreign currency. Rates come from a provider, the European Central
+// Bank or exchangerate.host, and a day's rates are stored once the day is
+// over, so converting at a past date always gives the same result.
+package fxrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/config"
+	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+)
+
+const (
+	// todayTTL is how long today's rates are reused before they're fetched
+	// again; they aren't final until the day is over
+	todayTTL = time.Hour
+	// places is how many decimal places rates are rounded to, as stored
+	places = 8
+)
+
+var (
+	// ErrUnsupportedCurrency is returned for a currency the provider has no
+	// rate for
+	ErrUnsupportedCurrency = errors.New("unsupported currency")
+	// ErrNoRates is returned for a date before the provider's first rates
+	ErrNoRates = errors.New("no exchange rates for the date")
+	// ErrFutureDate is returned by Rates for a date after today
+	ErrFutureDate = errors.New("date is in the future")
+)
+
+// Rates are what one unit of Base buys in each other currency on Date.
+// PublishedOn is when the provider published them, which is before Date
+// when it doesn't publish every day.
+type Rates struct {
+	Base        string
+	Date        time.Time
+	PublishedOn time.Time
+	Provider    string
+	Rates       map[string]decimal.Decimal
+}
+
+// rebase returns the rates for one unit of base, worked out from the rates
+// against r.Base
+func (r Rates) rebase(base string) (Rates, error) {
+	if base == r.Base {
+		return r, nil
+	}
+	from, ok := r.Rates[base]
+	if !ok {
+		return Rates{}, fmt.Errorf("%w: %s", ErrUnsupportedCurrency, base)
+	}
+	rebased := r
+	rebased.Base = base
+	rebased.Rates = make(map[string]decimal.Decimal, len(r.Rates))
+	rebased.Rates[r.Base] = decimal.NewFromInt(1).Div(from).Round(places)
+	for currency, rate := range r.Rates {
+		if currency != base {
+			rebased.Rates[currency] = rate.Div(from).Round(places)
+		}
+	}
+	return rebased, nil
+}
+
+// Provider fetches a date's rates: the last ones published on or before it,
+// against the provider's own base currency
+type Provider interface {
+	Name() string
+	Fetch(ctx context.Context, date time.Time) (Rates, error)
+}
+
+// Service looks up rates, fetching each past day's from the provider once
+// and keeping them in the exchange_rates table
+type Service struct {
+	db       *db.DB
+	provider Provider
+	now      func() time.Time
+
+	mu           sync.Mutex
+	today        Rates
+	todayFetched time.Time
+}
+
+func NewService(db *db.DB, provider Provider) *Service {
+	return &Service{db: db, provider: provider, now: time.Now}
+}
+
+// New returns a service using the provider cfg names
+func New(cfg *config.Config, db *db.DB) *Service {
+	var provider Provider
+	switch cfg.FXProvider {
+	case "exchangeratehost":
+		provider = NewExchangeRateHost(cfg.FXAPIKey)
+	default:
+		provider = NewECB()
+	}
+	return NewService(db, provider)
+}
+
+// day is the UTC midnight of t's calendar day, in t's own time zone
+func day(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// Rates returns what one unit of base buys in each other currency on date
+func (s *Service) Rates(ctx context.Context, base string, date time.Time) (Rates, error) {
+	date = day(date)
+	today := day(s.now().UTC())
+	if date.After(today) {
+		return Rates{}, ErrFutureDate
+	}
+
+	var rates Rates
+	var err error
+	if date.Equal(today) {
+		rates, err = s.fetchToday(ctx, today)
+	} else {
+		rates, err = s.past(ctx, date)
+	}
+	if err != nil {
+		return Rates{}, err
+	}
+	rates.Date = date
+	return rates.rebase(base)
+}
+
+// Rate returns what one unit of from is worth in to on date. A date after
+// today, e.g. of an expense entered ahead of time, gets today's rate.
+func (s *Service) Rate(ctx context.Context, from, to string, date time.Time) (decimal.Decimal, error) {
+	if from == to {
+		return decimal.NewFromInt(1), nil
+	}
+	if today := s.now().UTC(); day(date).After(day(today)) {
+		date = today
+	}
+	rates, err := s.Rates(ctx, from, date)
+	if err != nil {
+		return decimal.Decimal{}, err
+	}
+	rate, ok := rates.Rates[to]
+	if !ok {
+		return decimal.Decimal{}, fmt.Errorf("%w: %s", ErrUnsupportedCurrency, to)
+	}
+	return rate, nil
+}
+
+// fetchToday returns today's rates, fetching them again once they're
+// todayTTL old
+func (s *Service) fetchToday(ctx context.Context, today time.Time) (Rates, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.today.Date.Equal(today) && s.now().Sub(s.todayFetched) < todayTTL {
+		return s.today, nil
+	}
+	rates, err := s.fetch(ctx, today)
+	if err != nil {
+		return Rates{}, err
+	}
+	s.today, s.todayFetched = rates, s.now()
+	return rates, nil
+}
+
+// past returns a past date's stored rates, fetching and storing them the
+// first time they're asked for
+func (s *Service) past(ctx context.Context, date time.Time) (Rates, error) {
+	rates, found, err := s.load(ctx, date)
+	if err != nil || found {
+		return rates, err
+	}
+	rates, err = s.fetch(ctx, date)
+	if err != nil {
+		return Rates{}, err
+	}
+	if err := s.store(ctx, rates); err != nil {
+		return Rates{}, fmt.Errorf("failed to store exchange rates: %w", err)
+	}
+	return rates, nil
+}
+
+func (s *Service) fetch(ctx context.Context, date time.Time) (Rates, error) {
+	rates, err := s.provider.Fetch(ctx, date)
+	if err != nil {
+		return Rates{}, fmt.Errorf("failed to fetch exchange rates for %s from %s: %w", date.Format(time.DateOnly), s.provider.Name(), err)
+	}
+	rates.Date = date
+	rates.Provider = s.provider.Name()
+	for currency, rate := range rates.Rates {
+		rates.Rates[currency] = rate.Round(places)
+	}
+	return rates, nil
+}
+
+func (s *Service) load(ctx context.Context, date time.Time) (Rates, bool, error) {
+	rows, err := s.db.Pool.Query(ctx,
+		"SELECT base, currency, rate, published_on, provider FROM exchange_rates WHERE date = $1", date)
+	if err != nil {
+		return Rates{}, false, err
+	}
+	defer rows.Close()
+
+	rates := Rates{Date: date, Rates: make(map[string]decimal.Decimal)}
+	for rows.Next() {
+		var currency string
+		var rate decimal.Decimal
+		if err := rows.Scan(&rates.Base, &currency, &rate, &rates.PublishedOn, &rates.Provider); err != nil {
+			return Rates{}, false, err
+		}
+		rates.Rates[currency] = rate
+	}
+	if err := rows.Err(); err != nil {
+		return Rates{}, false, err
+	}
+	return rates, len(rates.Rates) > 0, nil
+}
+
+// store keeps a past date's rates. Rates stored meanwhile by another
+// request are left as they are.
+func (s *Service) store(ctx context.Context, rates Rates) error {
+	currencies := make([]string, 0, len(rates.Rates))
+	values := make([]string, 0, len(rates.Rates))
+	for currency, rate := range rates.Rates {
+		currencies = append(currencies, currency)
+		values = append(values, rate.String())
+	}
+	_, err := s.db.Pool.Exec(ctx,
+		`INSERT INTO exchange_rates (date, base, currency, rate, published_on, provider)
+		 SELECT $1, $2, currency, rate, $5, $6 FROM unnest($3::text[], $4::numeric[]) AS r(currency, rate)
+		 ON CONFLICT (date, currency) DO NOTHING`,
+		rates.Date, rates.Base, currencies, values, rates.PublishedOn, rates.Provider)
+	return err
+}
@@ -0,0 +1,180 @@
+package fxrate
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func date(s string) time.Time {
+	t, _ := time.Parse(time.DateOnly, s)
+	return t
+}
+
+func rateMap(pairs ...string) map[string]decimal.Decimal {
+	m := make(map[string]decimal.Decimal)
+	for i := 0; i < len(pairs); i += 2 {
+		m[pairs[i]] = decimal.RequireFromString(pairs[i+1])
+	}
+	return m
+}
+
+func TestRebase(t *testing.T) {
+	eur := Rates{Base: "EUR", Rates: rateMap("USD", "1.0838", "GBP", "0.85543", "JPY", "162.45")}
+
+	usd, err := eur.rebase("USD")
+	require.NoError(t, err)
+	assert.Equal(t, "USD", usd.Base)
+	assert.Equal(t, "0.92267946", usd.Rates["EUR"].String())
+	assert.Equal(t, "0.78928769", usd.Rates["GBP"].String())
+	assert.Equal(t, "149.88927846", usd.Rates["JPY"].String())
+	assert.NotContains(t, usd.Rates, "USD")
+	assert.Equal(t, "1.0838", eur.Rates["USD"].String(), "the original is unchanged")
+
+	same, err := eur.rebase("EUR")
+	require.NoError(t, err)
+	assert.Equal(t, eur, same)
+
+	_, err = eur.rebase("XYZ")
+	assert.ErrorIs(t, err, ErrUnsupportedCurrency)
+}
+
+const ecbRates = `<?xml version="1.0" encoding="UTF-8"?>
+<gesmes:Envelope xmlns:gesmes="http://www.gesmes.org/xml/2002-08-01" xmlns="http://www.ecb.int/vocabulary/2002-08-01/eurofxref">
+	<gesmes:subject>Reference rates</gesmes:subject>
+	<Cube>
+		<Cube time="2024-03-04"><Cube currency="USD" rate="1.0845"/><Cube currency="GBP" rate="0.85623"/></Cube>
+		<Cube time="2024-03-01"><Cube currency="USD" rate="1.0838"/><Cube currency="GBP" rate="0.85543"/><Cube currency="CYP" rate="N/A"/></Cube>
+	</Cube>
+</gesmes:Envelope>`
+
+func TestECB(t *testing.T) {
+	requests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		assert.Equal(t, "/eurofxref-hist-90d.xml", r.URL.Path)
+		w.Write([]byte(ecbRates))
+	}))
+	defer srv.Close()
+
+	ecb := NewECB()
+	ecb.url = srv.URL
+	ecb.now = func() time.Time { return date("2024-03-05") }
+
+	// A Sunday gets the Friday's rates
+	rates, err := ecb.Fetch(t.Context(), date("2024-03-03"))
+	require.NoError(t, err)
+	assert.Equal(t, "EUR", rates.Base)
+	assert.Equal(t, date("2024-03-01"), rates.PublishedOn)
+	assert.Equal(t, rateMap("USD", "1.0838", "GBP", "0.85543"), rates.Rates)
+
+	rates, err = ecb.Fetch(t.Context(), date("2024-03-04"))
+	require.NoError(t, err)
+	assert.Equal(t, date("2024-03-04"), rates.PublishedOn)
+
+	_, err = ecb.Fetch(t.Context(), date("2024-02-29"))
+	assert.ErrorIs(t, err, ErrNoRates)
+	assert.Equal(t, 1, requests, "the file is reused")
+
+	_, err = parseECB(strings.NewReader("not xml"))
+	assert.Error(t, err)
+}
+
+func TestExchangeRateHost(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "secret", r.URL.Query().Get("access_key"))
+		switch {
+		case r.URL.Path == "/live":
+			w.Write([]byte(`{"success":true,"source":"USD","quotes":{"USDEUR":0.9213,"USDGBP":0.7901}}`))
+		case r.URL.Query().Get("date") == "2024-03-01":
+			w.Write([]byte(`{"success":true,"historical":true,"date":"2024-03-01","source":"USD","quotes":{"USDEUR":0.922679,"USDUSD":1}}`))
+		case r.URL.Query().Get("date") == "1990-01-01":
+			w.Write([]byte(`{"success":false,"error":{"code":302,"type":"invalid_date","info":"You have entered an invalid date."}}`))
+		default:
+			w.Write([]byte(`{"success":false,"error":{"code":104,"type":"usage_limit_reached","info":"Your monthly usage limit has been reached."}}`))
+		}
+	}))
+	defer srv.Close()
+
+	host := NewExchangeRateHost("secret")
+	host.url = srv.URL
+	host.now = func() time.Time { return date("2024-03-05").Add(10 * time.Hour) }
+
+	rates, err := host.Fetch(t.Context(), date("2024-03-05"))
+	require.NoError(t, err)
+	assert.Equal(t, "USD", rates.Base)
+	assert.Equal(t, rateMap("EUR", "0.9213", "GBP", "0.7901"), rates.Rates)
+
+	rates, err = host.Fetch(t.Context(), date("2024-03-01"))
+	require.NoError(t, err)
+	assert.Equal(t, date("2024-03-01"), rates.PublishedOn)
+	assert.Equal(t, rateMap("EUR", "0.922679"), rates.Rates)
+
+	_, err = host.Fetch(t.Context(), date("1990-01-01"))
+	assert.ErrorIs(t, err, ErrNoRates)
+	_, err = host.Fetch(t.Context(), date("2024-02-01"))
+	assert.ErrorContains(t, err, "usage limit")
+}
+
+// fakeProvider counts its fetches
+type fakeProvider struct {
+	rates   Rates
+	fetches int
+}
+
+func (p *fakeProvider) Name() string { return "fake" }
+
+func (p *fakeProvider) Fetch(ctx context.Context, date time.Time) (Rates, error) {
+	p.fetches++
+	rates := p.rates
+	rates.Rates = make(map[string]decimal.Decimal)
+	for currency, rate := range p.rates.Rates {
+		rates.Rates[currency] = rate
+	}
+	return rates, nil
+}
+
+func TestServiceToday(t *testing.T) {
+	provider := &fakeProvider{rates: Rates{Base: "EUR", PublishedOn: date("2024-03-04"), Rates: rateMap("USD", "1.0845", "GBP", "0.85623")}}
+	// Only past dates use the database
+	s := NewService(nil, provider)
+	now := date("2024-03-04").Add(15 * time.Hour)
+	s.now = func() time.Time { return now }
+
+	rates, err := s.Rates(t.Context(), "USD", now)
+	require.NoError(t, err)
+	assert.Equal(t, "USD", rates.Base)
+	assert.Equal(t, date("2024-03-04"), rates.Date)
+	assert.Equal(t, "fake", rates.Provider)
+	assert.Equal(t, "0.92208391", rates.Rates["EUR"].String())
+
+	rate, err := s.Rate(t.Context(), "GBP", "EUR", now)
+	require.NoError(t, err)
+	assert.Equal(t, "1.16791049", rate.String())
+	assert.Equal(t, 1, provider.fetches, "today's rates are reused")
+
+	// Future dates get today's rate
+	rate, err = s.Rate(t.Context(), "EUR", "USD", now.AddDate(0, 1, 0))
+	require.NoError(t, err)
+	assert.Equal(t, "1.0845", rate.String())
+	_, err = s.Rates(t.Context(), "EUR", now.AddDate(0, 0, 1))
+	assert.ErrorIs(t, err, ErrFutureDate)
+
+	rate, err = s.Rate(t.Context(), "CHF", "CHF", now)
+	require.NoError(t, err)
+	assert.Equal(t, "1", rate.String())
+	_, err = s.Rate(t.Context(), "EUR", "XYZ", now)
+	assert.ErrorIs(t, err, ErrUnsupportedCurrency)
+
+	now = now.Add(todayTTL)
+	_, err = s.Rates(t.Context(), "EUR", now)
+	require.NoError(t, err)
+	assert.Equal(t, 2, provider.fetches, "fetched again once stale")
+}
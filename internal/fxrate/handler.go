@@ -0,0 +1,96 @@
+package fxrate
+
+import (
+	"errors"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+	"github.com/yanonymousV2/finance-manager-backend/internal/helpers"
+	"github.com/yanonymousV2/finance-manager-backend/internal/middleware"
+)
+
+// RatesResponse is what one unit of Base buys in each other currency on
+// Date, as published by Provider on PublishedOn
+type RatesResponse struct {
+	Base        string                     `json:"base"`
+	Date        string                     `json:"date"`
+	PublishedOn string                     `json:"published_on"`
+	Provider    string                     `json:"provider"`
+	Rates       map[string]decimal.Decimal `json:"rates"`
+}
+
+// GetRates returns the exchange rates from base, the user's default
+// currency unless given, on date, today unless given
+func GetRates(c *gin.Context, db *db.DB, rates *Service) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if rates == nil {
+		c.JSON(503, gin.H{"error": "exchange rates are unavailable"})
+		return
+	}
+
+	base := strings.ToUpper(c.Query("base"))
+	if base == "" {
+		var err error
+		base, err = helpers.GetUserCurrency(c.Request.Context(), db, userID)
+		if err != nil {
+			c.JSON(500, gin.H{"error": "failed to get default currency"})
+			return
+		}
+	}
+
+	date := time.Now().UTC()
+	if dateStr := c.Query("date"); dateStr != "" {
+		var err error
+		date, err = time.Parse("2006-01-02", dateStr)
+		if err != nil {
+			c.JSON(400, gin.H{"error": "invalid date, expected YYYY-MM-DD"})
+			return
+		}
+	}
+
+	r, err := rates.Rates(c.Request.Context(), base, date)
+	switch {
+	case errors.Is(err, ErrFutureDate):
+		c.JSON(400, gin.H{"error": "date can't be in the future"})
+		return
+	case errors.Is(err, ErrUnsupportedCurrency):
+		c.JSON(400, gin.H{"error": "unsupported base currency"})
+		return
+	case errors.Is(err, ErrNoRates):
+		c.JSON(404, gin.H{"error": "no exchange rates for the date"})
+		return
+	case err != nil:
+		slog.Error("failed to get exchange rates", "error", err)
+		c.JSON(502, gin.H{"error": "failed to get exchange rates"})
+		return
+	}
+
+	c.JSON(200, RatesResponse{
+		Base:        r.Base,
+		Date:        r.Date.Format(time.DateOnly),
+		PublishedOn: r.PublishedOn.Format(time.DateOnly),
+		Provider:    r.Provider,
+		Rates:       r.Rates,
+	})
+}
+
+// RespondRateError responds to a failed Rate lookup for converting a
+// client's amount, which they can retry with a rate of their own
+func RespondRateError(c *gin.Context, err error) {
+	if errors.Is(err, ErrUnsupportedCurrency) || errors.Is(err, ErrNoRates) {
+		c.JSON(400, gin.H{"error": "no exchange rate available for the currency; send fx_rate"})
+		return
+	}
+	slog.Error("failed to get exchange rate", "error", err)
+	c.JSON(502, gin.H{"error": "failed to get exchange rate; send fx_rate"})
+}
@@ -0,0 +1,85 @@
+package graphql
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// batchWait is how long a loader collects keys before fetching them. gqlgen
+// resolves the fields of a list's elements concurrently, so by then every
+// sibling has asked for its key.
+const batchWait = 2 * time.Millisecond
+
+// loader batches the keys asked for within batchWait of each other into one
+// fetch, and remembers what it fetched for the rest of the request
+type loader[K comparable, V any] struct {
+	// fetch returns the values of keys; a key it leaves out gets the zero
+	// value
+	fetch func(ctx context.Context, keys []K) (map[K]V, error)
+
+	mu    sync.Mutex
+	cache map[K]V
+	batch *batch[K, V]
+}
+
+type batch[K comparable, V any] struct {
+	keys   []K
+	seen   map[K]bool
+	done   chan struct{}
+	values map[K]V
+	err    error
+}
+
+func newLoader[K comparable, V any](fetch func(ctx context.Context, keys []K) (map[K]V, error)) *loader[K, V] {
+	return &loader[K, V]{fetch: fetch, cache: map[K]V{}}
+}
+
+// Load returns key's value, waiting for the batch it joins to be fetched
+func (l *loader[K, V]) Load(ctx context.Context, key K) (V, error) {
+	l.mu.Lock()
+	if v, ok := l.cache[key]; ok {
+		l.mu.Unlock()
+		return v, nil
+	}
+	b := l.batch
+	if b == nil {
+		b = &batch[K, V]{seen: map[K]bool{}, done: make(chan struct{})}
+		l.batch = b
+		time.AfterFunc(batchWait, func() { l.run(ctx, b) })
+	}
+	if !b.seen[key] {
+		b.seen[key] = true
+		b.keys = append(b.keys, key)
+	}
+	l.mu.Unlock()
+
+	select {
+	case <-b.done:
+	case <-ctx.Done():
+		var zero V
+		return zero, ctx.Err()
+	}
+	if b.err != nil {
+		var zero V
+		return zero, b.err
+	}
+	return b.values[key], nil
+}
+
+// run fetches b, letting the next Load start a new batch
+func (l *loader[K, V]) run(ctx context.Context, b *batch[K, V]) {
+	l.mu.Lock()
+	l.batch = nil
+	l.mu.Unlock()
+
+	b.values, b.err = l.fetch(ctx, b.keys)
+	if b.err == nil {
+		l.mu.Lock()
+		for _, key := range b.keys {
+			l.cache[key] = b.values[key]
+		}
+		l.mu.Unlock()
+	}
+	close(b.done)
+}
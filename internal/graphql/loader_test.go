@@ -0,0 +1,94 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoaderBatches(t *testing.T) {
+	var mu sync.Mutex
+	var fetches [][]int
+	l := newLoader(func(_ context.Context, keys []int) (map[int]string, error) {
+		mu.Lock()
+		fetches = append(fetches, keys)
+		mu.Unlock()
+		values := map[int]string{}
+		for _, k := range keys {
+			if k != 3 {
+				values[k] = string(rune('a' + k))
+			}
+		}
+		return values, nil
+	})
+
+	// Siblings resolved together share one fetch, with repeated keys
+	// fetched once
+	keys := []int{0, 1, 2, 1, 3}
+	got := make([]string, len(keys))
+	var wg sync.WaitGroup
+	for i, k := range keys {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			v, err := l.Load(context.Background(), k)
+			assert.NoError(t, err)
+			got[i] = v
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, []string{"a", "b", "c", "b", ""}, got, "a key the fetch leaves out gets the zero value")
+	require.Len(t, fetches, 1)
+	assert.ElementsMatch(t, []int{0, 1, 2, 3}, fetches[0])
+
+	// Later loads of fetched keys come from the cache
+	v, err := l.Load(context.Background(), 2)
+	require.NoError(t, err)
+	assert.Equal(t, "c", v)
+	assert.Len(t, fetches, 1)
+
+	_, err = l.Load(context.Background(), 4)
+	require.NoError(t, err)
+	assert.Len(t, fetches, 2)
+}
+
+func TestLoaderError(t *testing.T) {
+	calls := 0
+	l := newLoader(func(context.Context, []int) (map[int]string, error) {
+		calls++
+		return nil, errors.New("connection reset")
+	})
+
+	_, err := l.Load(context.Background(), 1)
+	assert.EqualError(t, err, "connection reset")
+
+	// Failures aren't cached
+	_, err = l.Load(context.Background(), 1)
+	assert.Error(t, err)
+	assert.Equal(t, 2, calls)
+}
+
+func TestUnmarshalDecimal(t *testing.T) {
+	tests := []struct {
+		in   any
+		want string
+	}{
+		{"12.50", "12.5"},
+		{int64(12), "12"},
+		{12.25, "12.25"},
+	}
+	for _, tt := range tests {
+		d, err := UnmarshalDecimal(tt.in)
+		require.NoError(t, err)
+		assert.Equal(t, tt.want, d.String())
+	}
+
+	_, err := UnmarshalDecimal(true)
+	assert.Error(t, err)
+	_, err = UnmarshalDecimal("twelve")
+	assert.Error(t, err)
+}
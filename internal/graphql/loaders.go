@@ -0,0 +1,217 @@
+package graphql
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+	"github.com/yanonymousV2/finance-manager-backend/internal/expense"
+	"github.com/yanonymousV2/finance-manager-backend/internal/group"
+)
+
+// loaders are one request's dataloaders. Each field that loads per parent
+// costs one query for all the parents resolved together.
+type loaders struct {
+	users    *loader[uuid.UUID, *User]
+	members  *loader[uuid.UUID, []*User]
+	expenses *loader[expensePage, []*expense.Expense]
+	splits   *loader[uuid.UUID, []*expense.ExpenseSplit]
+	balances *loader[uuid.UUID, []*group.Balance]
+}
+
+func newLoaders(db *db.DB) *loaders {
+	return &loaders{
+		users:    newLoader(withDB(db, loadUsers)),
+		members:  newLoader(withDB(db, loadMembers)),
+		expenses: newLoader(withDB(db, loadExpenses)),
+		splits:   newLoader(withDB(db, loadSplits)),
+		balances: newLoader(withDB(db, loadBalances)),
+	}
+}
+
+// withDB binds a batch query to db
+func withDB[K comparable, V any](db *db.DB, load func(context.Context, *db.DB, []K) (map[K]V, error)) func(context.Context, []K) (map[K]V, error) {
+	return func(ctx context.Context, keys []K) (map[K]V, error) {
+		return load(ctx, db, keys)
+	}
+}
+
+type loadersKey struct{}
+
+func withLoaders(ctx context.Context, l *loaders) context.Context {
+	return context.WithValue(ctx, loadersKey{}, l)
+}
+
+func loadersFrom(ctx context.Context) *loaders {
+	return ctx.Value(loadersKey{}).(*loaders)
+}
+
+const userColumns = "u.id, u.email, u.display_name, u.default_currency, u.is_placeholder"
+
+func scanUser(row pgx.Row, extra ...any) (*User, error) {
+	u := &User{}
+	err := row.Scan(append(extra, &u.ID, &u.Email, &u.DisplayName, &u.DefaultCurrency, &u.IsPlaceholder)...)
+	return u, err
+}
+
+func loadUsers(ctx context.Context, db *db.DB, ids []uuid.UUID) (map[uuid.UUID]*User, error) {
+	rows, err := db.Pool.Query(ctx, `SELECT `+userColumns+` FROM users u WHERE u.id = ANY($1)`, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get users: %w", err)
+	}
+	defer rows.Close()
+
+	users := make(map[uuid.UUID]*User, len(ids))
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users[u.ID] = u
+	}
+	return users, rows.Err()
+}
+
+// loadMembers returns the members of each group in the order they joined
+func loadMembers(ctx context.Context, db *db.DB, groupIDs []uuid.UUID) (map[uuid.UUID][]*User, error) {
+	rows, err := db.Pool.Query(ctx,
+		`SELECT gm.group_id, `+userColumns+` FROM group_members gm JOIN users u ON u.id = gm.user_id
+		 WHERE gm.group_id = ANY($1) ORDER BY gm.joined_at, u.id`, groupIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get members: %w", err)
+	}
+	defer rows.Close()
+
+	members := make(map[uuid.UUID][]*User, len(groupIDs))
+	for rows.Next() {
+		var groupID uuid.UUID
+		u, err := scanUser(rows, &groupID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan member: %w", err)
+		}
+		members[groupID] = append(members[groupID], u)
+	}
+	return members, rows.Err()
+}
+
+// expensePage is one group's Group.expenses arguments
+type expensePage struct {
+	GroupID  uuid.UUID
+	Limit    int
+	Offset   int
+	Category string
+	Tag      string
+}
+
+// pageExpensesSQL numbers each group's undeleted expenses newest first, as
+// GET /groups/:id/expenses orders them, and keeps one page of each. Empty
+// category and tag filters match everything.
+const pageExpensesSQL = `SELECT id, group_id, description, total_amount, currency, category, paid_by, expense_date, created_by, status, created_at,
+	ARRAY(SELECT t.name FROM expense_tags et JOIN tags t ON t.id = et.tag_id WHERE et.expense_id = page.id ORDER BY t.name)
+FROM (
+	SELECT e.*, ROW_NUMBER() OVER (PARTITION BY e.group_id ORDER BY e.expense_date DESC, e.created_at DESC) AS n
+	FROM expenses e
+	WHERE e.group_id = ANY(@group_ids) AND e.deleted_at IS NULL
+		AND (@category::text = '' OR e.category = @category)
+		AND (@tag::text = '' OR EXISTS (SELECT 1 FROM expense_tags et JOIN tags t ON t.id = et.tag_id
+		                          WHERE et.expense_id = e.id AND t.name = @tag))
+) page
+WHERE n > @offset::int AND n <= @offset::int + @limit::int
+ORDER BY group_id, n`
+
+// loadExpenses runs one query per distinct set of arguments, which is one
+// in practice since sibling groups ask for the same page
+func loadExpenses(ctx context.Context, db *db.DB, pages []expensePage) (map[expensePage][]*expense.Expense, error) {
+	type args struct {
+		limit, offset int
+		category, tag string
+	}
+	groupIDs := map[args][]uuid.UUID{}
+	var order []args
+	for _, p := range pages {
+		a := args{p.Limit, p.Offset, p.Category, p.Tag}
+		if _, ok := groupIDs[a]; !ok {
+			order = append(order, a)
+		}
+		groupIDs[a] = append(groupIDs[a], p.GroupID)
+	}
+
+	expenses := make(map[expensePage][]*expense.Expense, len(pages))
+	for _, a := range order {
+		rows, err := db.Pool.Query(ctx, pageExpensesSQL, pgx.NamedArgs{
+			"group_ids": groupIDs[a],
+			"limit":     a.limit,
+			"offset":    a.offset,
+			"category":  a.category,
+			"tag":       a.tag,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get expenses: %w", err)
+		}
+		for rows.Next() {
+			exp := &expense.Expense{}
+			if err := rows.Scan(&exp.ID, &exp.GroupID, &exp.Description, &exp.TotalAmount, &exp.Currency, &exp.Category,
+				&exp.PaidBy, &exp.ExpenseDate, &exp.CreatedBy, &exp.Status, &exp.CreatedAt, &exp.Tags); err != nil {
+				rows.Close()
+				return nil, fmt.Errorf("failed to scan expense: %w", err)
+			}
+			page := expensePage{GroupID: exp.GroupID, Limit: a.limit, Offset: a.offset, Category: a.category, Tag: a.tag}
+			expenses[page] = append(expenses[page], exp)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, fmt.Errorf("failed to get expenses: %w", err)
+		}
+	}
+	return expenses, nil
+}
+
+// loadSplits returns each expense's splits, largest first as GET
+// /expenses/:id lists them
+func loadSplits(ctx context.Context, db *db.DB, expenseIDs []uuid.UUID) (map[uuid.UUID][]*expense.ExpenseSplit, error) {
+	rows, err := db.Pool.Query(ctx,
+		"SELECT expense_id, user_id, amount FROM expense_splits WHERE expense_id = ANY($1) ORDER BY expense_id, amount DESC, user_id",
+		expenseIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get splits: %w", err)
+	}
+	defer rows.Close()
+
+	splits := make(map[uuid.UUID][]*expense.ExpenseSplit, len(expenseIDs))
+	for rows.Next() {
+		s := &expense.ExpenseSplit{}
+		if err := rows.Scan(&s.ExpenseID, &s.UserID, &s.Amount); err != nil {
+			return nil, fmt.Errorf("failed to scan split: %w", err)
+		}
+		splits[s.ExpenseID] = append(splits[s.ExpenseID], s)
+	}
+	return splits, rows.Err()
+}
+
+// loadBalances returns every member's net balance in each group from
+// group_balances, as group.Service.Balances does for one group
+func loadBalances(ctx context.Context, db *db.DB, groupIDs []uuid.UUID) (map[uuid.UUID][]*group.Balance, error) {
+	rows, err := db.Pool.Query(ctx,
+		`SELECT gm.group_id, gm.user_id, COALESCE(gb.balance, 0), g.currency FROM group_members gm
+		 JOIN groups g ON g.id = gm.group_id
+		 LEFT JOIN group_balances gb ON gb.group_id = gm.group_id AND gb.user_id = gm.user_id
+		 WHERE gm.group_id = ANY($1) ORDER BY gm.group_id, gm.user_id`, groupIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get balances: %w", err)
+	}
+	defer rows.Close()
+
+	balances := make(map[uuid.UUID][]*group.Balance, len(groupIDs))
+	for rows.Next() {
+		var groupID uuid.UUID
+		b := &group.Balance{}
+		if err := rows.Scan(&groupID, &b.UserID, &b.Amount, &b.Currency); err != nil {
+			return nil, fmt.Errorf("failed to scan balance: %w", err)
+		}
+		balances[groupID] = append(balances[groupID], b)
+	}
+	return balances, rows.Err()
+}
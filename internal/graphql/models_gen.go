@@ -0,0 +1,18 @@
+// Code generated by github.com/99designs/gqlgen, DO NOT EDIT.
+
+package graphql
+
+import (
+	"github.com/google/uuid"
+)
+
+type Query struct {
+}
+
+type User struct {
+	ID              uuid.UUID `json:"id"`
+	Email           *string   `json:"email,omitempty"`
+	DisplayName     *string   `json:"displayName,omitempty"`
+	DefaultCurrency string    `json:"defaultCurrency"`
+	IsPlaceholder   bool      `json:"isPlaceholder"`
+}
@@ -0,0 +1,50 @@
+package graphql
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+	"github.com/yanonymousV2/finance-manager-backend/internal/group"
+	"github.com/yanonymousV2/finance-manager-backend/internal/requestid"
+)
+
+// Resolver resolves the schema's fields. Membership is checked where a
+// query first reaches a group, in Query.group and Query.groups; the fields
+// below a group trust it.
+type Resolver struct {
+	db     *db.DB
+	groups *group.Service
+}
+
+// errNotMember is worded as the REST API's 403
+var errNotMember = errors.New("not a member of the group")
+
+type userIDKey struct{}
+
+// userID is the caller, as authenticated by middleware.JWTAuth
+func userID(ctx context.Context) uuid.UUID {
+	id, _ := ctx.Value(userIDKey{}).(uuid.UUID)
+	return id
+}
+
+// internalError logs err and gives the client only message, as handlers
+// answer a 500
+func internalError(ctx context.Context, err error, message string) error {
+	requestid.Logger(ctx).Error(message, "error", err)
+	return errors.New(message)
+}
+
+// user loads a user through the request's dataloader
+func (r *Resolver) user(ctx context.Context, id uuid.UUID) (*User, error) {
+	u, err := loadersFrom(ctx).users.Load(ctx, id)
+	if err != nil {
+		return nil, internalError(ctx, err, "failed to get user")
+	}
+	if u == nil {
+		return nil, internalError(ctx, errors.New("no such user "+id.String()), "failed to get user")
+	}
+	return u, nil
+}
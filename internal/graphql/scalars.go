@@ -0,0 +1,28 @@
+package graphql
+
+import (
+	"fmt"
+
+	"github.com/99designs/gqlgen/graphql"
+	"github.com/shopspring/decimal"
+)
+
+// MarshalDecimal writes an amount as a string, as the REST API does, so it
+// keeps its precision
+func MarshalDecimal(d decimal.Decimal) graphql.Marshaler {
+	return graphql.MarshalString(d.String())
+}
+
+// UnmarshalDecimal reads an amount from a string or a number
+func UnmarshalDecimal(v any) (decimal.Decimal, error) {
+	switch v := v.(type) {
+	case string:
+		return decimal.NewFromString(v)
+	case int64:
+		return decimal.NewFromInt(v), nil
+	case float64:
+		return decimal.NewFromFloat(v), nil
+	default:
+		return decimal.Decimal{}, fmt.Errorf("%T is not a decimal", v)
+	}
+}
@@ -0,0 +1,234 @@
+package graphql
+
+// This file will be automatically regenerated based on the schema, any resolver implementations
+// will be copied through when generating and any unknown code will be moved to the end.
+// Code generated by github.com/99designs/gqlgen
+
+import (
+	"context"
+	"errors"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/yanonymousV2/finance-manager-backend/internal/dashboard"
+	"github.com/yanonymousV2/finance-manager-backend/internal/expense"
+	"github.com/yanonymousV2/finance-manager-backend/internal/group"
+	"github.com/yanonymousV2/finance-manager-backend/internal/helpers"
+)
+
+// User is the resolver for the user field.
+func (r *balanceResolver) User(ctx context.Context, obj *group.Balance) (*User, error) {
+	return r.user(ctx, obj.UserID)
+}
+
+// PaidBy is the resolver for the paidBy field.
+func (r *expenseResolver) PaidBy(ctx context.Context, obj *expense.Expense) (*User, error) {
+	return r.user(ctx, obj.PaidBy)
+}
+
+// Splits is the resolver for the splits field.
+func (r *expenseResolver) Splits(ctx context.Context, obj *expense.Expense) ([]*expense.ExpenseSplit, error) {
+	splits, err := loadersFrom(ctx).splits.Load(ctx, obj.ID)
+	if err != nil {
+		return nil, internalError(ctx, err, "failed to get splits")
+	}
+	return splits, nil
+}
+
+// User is the resolver for the user field.
+func (r *expenseSplitResolver) User(ctx context.Context, obj *expense.ExpenseSplit) (*User, error) {
+	return r.user(ctx, obj.UserID)
+}
+
+// CreatedBy is the resolver for the createdBy field.
+func (r *groupResolver) CreatedBy(ctx context.Context, obj *group.Group) (*User, error) {
+	return r.user(ctx, obj.CreatedBy)
+}
+
+// Members is the resolver for the members field.
+func (r *groupResolver) Members(ctx context.Context, obj *group.Group) ([]*User, error) {
+	members, err := loadersFrom(ctx).members.Load(ctx, obj.ID)
+	if err != nil {
+		return nil, internalError(ctx, err, "failed to get members")
+	}
+	return members, nil
+}
+
+// Expenses is the resolver for the expenses field.
+func (r *groupResolver) Expenses(ctx context.Context, obj *group.Group, limit *int, offset *int, category *string, tag *string) ([]*expense.Expense, error) {
+	page := expensePage{GroupID: obj.ID, Limit: 20}
+	if limit != nil {
+		page.Limit = *limit
+	}
+	if offset != nil {
+		page.Offset = *offset
+	}
+	if category != nil {
+		page.Category = *category
+	}
+	if tag != nil {
+		page.Tag = *tag
+	}
+	if page.Limit < 1 || page.Limit > 100 {
+		return nil, errors.New("limit must be between 1 and 100")
+	}
+	if page.Offset < 0 {
+		return nil, errors.New("offset must not be negative")
+	}
+
+	expenses, err := loadersFrom(ctx).expenses.Load(ctx, page)
+	if err != nil {
+		return nil, internalError(ctx, err, "failed to get expenses")
+	}
+	return expenses, nil
+}
+
+// Balances is the resolver for the balances field.
+func (r *groupResolver) Balances(ctx context.Context, obj *group.Group) ([]*group.Balance, error) {
+	balances, err := loadersFrom(ctx).balances.Load(ctx, obj.ID)
+	if err != nil {
+		return nil, internalError(ctx, err, "failed to calculate balances")
+	}
+	return balances, nil
+}
+
+// PairwiseBalances is the resolver for the pairwiseBalances field.
+func (r *groupResolver) PairwiseBalances(ctx context.Context, obj *group.Group) ([]*group.PairwiseBalance, error) {
+	balances, err := r.groups.PairwiseBalances(ctx, userID(ctx), obj.ID)
+	if err != nil {
+		return nil, internalError(ctx, err, "failed to get pairwise balances")
+	}
+	pairs := make([]*group.PairwiseBalance, len(balances))
+	for i := range balances {
+		pairs[i] = &balances[i]
+	}
+	return pairs, nil
+}
+
+// Dashboard is the resolver for the dashboard field.
+func (r *groupResolver) Dashboard(ctx context.Context, obj *group.Group) (*dashboard.GroupDashboard, error) {
+	d, err := dashboard.Group(ctx, r.db, obj.ID)
+	if err != nil {
+		return nil, internalError(ctx, err, "failed to build dashboard")
+	}
+	return &d, nil
+}
+
+// PaidBy is the resolver for the paidBy field.
+func (r *largestExpenseResolver) PaidBy(ctx context.Context, obj *dashboard.LargestExpense) (*User, error) {
+	return r.user(ctx, obj.PaidBy)
+}
+
+// User is the resolver for the user field.
+func (r *memberSpendingResolver) User(ctx context.Context, obj *dashboard.MemberSpending) (*User, error) {
+	return r.user(ctx, obj.UserID)
+}
+
+// From is the resolver for the from field.
+func (r *pairwiseBalanceResolver) From(ctx context.Context, obj *group.PairwiseBalance) (*User, error) {
+	return r.user(ctx, obj.FromUser)
+}
+
+// To is the resolver for the to field.
+func (r *pairwiseBalanceResolver) To(ctx context.Context, obj *group.PairwiseBalance) (*User, error) {
+	return r.user(ctx, obj.ToUser)
+}
+
+// Me is the resolver for the me field.
+func (r *queryResolver) Me(ctx context.Context) (*User, error) {
+	return r.user(ctx, userID(ctx))
+}
+
+// Groups is the resolver for the groups field.
+func (r *queryResolver) Groups(ctx context.Context) ([]*group.Group, error) {
+	rows, err := r.db.Pool.Query(ctx,
+		`SELECT g.id, g.name, g.currency, g.created_by, g.created_at FROM groups g
+		 JOIN group_members gm ON gm.group_id = g.id
+		 WHERE gm.user_id = $1 AND g.deleted_at IS NULL ORDER BY g.name, g.id`, userID(ctx))
+	if err != nil {
+		return nil, internalError(ctx, err, "failed to get groups")
+	}
+	defer rows.Close()
+
+	groups := []*group.Group{}
+	for rows.Next() {
+		g := &group.Group{}
+		if err := rows.Scan(&g.ID, &g.Name, &g.Currency, &g.CreatedBy, &g.CreatedAt); err != nil {
+			return nil, internalError(ctx, err, "failed to scan group")
+		}
+		groups = append(groups, g)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, internalError(ctx, err, "failed to get groups")
+	}
+	return groups, nil
+}
+
+// Group is the resolver for the group field.
+func (r *queryResolver) Group(ctx context.Context, id uuid.UUID) (*group.Group, error) {
+	isMember, err := helpers.IsGroupMember(ctx, r.db, id, userID(ctx))
+	if err != nil || !isMember {
+		return nil, errNotMember
+	}
+
+	g := &group.Group{}
+	err = r.db.Pool.QueryRow(ctx,
+		"SELECT id, name, currency, created_by, created_at FROM groups WHERE id = $1 AND deleted_at IS NULL",
+		id).Scan(&g.ID, &g.Name, &g.Currency, &g.CreatedBy, &g.CreatedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, internalError(ctx, err, "failed to get group")
+	}
+	return g, nil
+}
+
+// Dashboard is the resolver for the dashboard field.
+func (r *queryResolver) Dashboard(ctx context.Context, year int, month int, includeGroups *bool) (*dashboard.MonthlyDashboard, error) {
+	if month < 1 || month > 12 {
+		return nil, errors.New("invalid month")
+	}
+	if year < 2000 || year > 2100 {
+		return nil, errors.New("invalid year")
+	}
+
+	d, err := dashboard.Monthly(ctx, r.db, userID(ctx), month, year, includeGroups != nil && *includeGroups)
+	if err != nil {
+		return nil, internalError(ctx, err, "failed to build dashboard")
+	}
+	return &d, nil
+}
+
+// Balance returns BalanceResolver implementation.
+func (r *Resolver) Balance() BalanceResolver { return &balanceResolver{r} }
+
+// Expense returns ExpenseResolver implementation.
+func (r *Resolver) Expense() ExpenseResolver { return &expenseResolver{r} }
+
+// ExpenseSplit returns ExpenseSplitResolver implementation.
+func (r *Resolver) ExpenseSplit() ExpenseSplitResolver { return &expenseSplitResolver{r} }
+
+// Group returns GroupResolver implementation.
+func (r *Resolver) Group() GroupResolver { return &groupResolver{r} }
+
+// LargestExpense returns LargestExpenseResolver implementation.
+func (r *Resolver) LargestExpense() LargestExpenseResolver { return &largestExpenseResolver{r} }
+
+// MemberSpending returns MemberSpendingResolver implementation.
+func (r *Resolver) MemberSpending() MemberSpendingResolver { return &memberSpendingResolver{r} }
+
+// PairwiseBalance returns PairwiseBalanceResolver implementation.
+func (r *Resolver) PairwiseBalance() PairwiseBalanceResolver { return &pairwiseBalanceResolver{r} }
+
+// Query returns QueryResolver implementation.
+func (r *Resolver) Query() QueryResolver { return &queryResolver{r} }
+
+type balanceResolver struct{ *Resolver }
+type expenseResolver struct{ *Resolver }
+type expenseSplitResolver struct{ *Resolver }
+type groupResolver struct{ *Resolver }
+type largestExpenseResolver struct{ *Resolver }
+type memberSpendingResolver struct{ *Resolver }
+type pairwiseBalanceResolver struct{ *Resolver }
+type queryResolver struct{ *Resolver }
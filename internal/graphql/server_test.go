@@ -0,0 +1,46 @@
+package graphql
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+)
+
+// query posts a GraphQL query as userID, or anonymously when nil
+func query(t *testing.T, userID *uuid.UUID, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	handle := Handler(nil, nil)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("POST", "/graphql", strings.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+	if userID != nil {
+		c.Set("user_id", *userID)
+	}
+	handle(c)
+	return w
+}
+
+func TestHandler(t *testing.T) {
+	w := query(t, nil, `{"query": "{ __typename }"}`)
+	assert.Equal(t, 401, w.Code)
+
+	userID := uuid.New()
+	w = query(t, &userID, `{"query": "{ __typename }"}`)
+	assert.Equal(t, 200, w.Code)
+	assert.JSONEq(t, `{"data": {"__typename": "Query"}}`, w.Body.String())
+
+	// Arguments are checked before anything is loaded
+	w = query(t, &userID, `{"query": "{ dashboard(year: 2026, month: 13) { totalSpent } }"}`)
+	assert.Equal(t, 200, w.Code)
+	assert.Contains(t, w.Body.String(), `"message":"invalid month"`)
+
+	w = query(t, &userID, `{"query": "{ groups { nope } }"}`)
+	assert.Equal(t, 422, w.Code)
+	assert.Contains(t, w.Body.String(), "Cannot query field")
+}
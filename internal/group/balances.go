@@ -0,0 +1,448 @@
+package group
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+	"github.com/yanonymousV2/finance-manager-backend/internal/helpers"
+	"github.com/yanonymousV2/finance-manager-backend/internal/middleware"
+)
+
+// Transfer is a suggested payment from one member to another
+type Transfer struct {
+	FromUser uuid.UUID       `json:"from_user"`
+	ToUser   uuid.UUID       `json:"to_user"`
+	Amount   decimal.Decimal `json:"amount"`
+	Currency string          `json:"currency"`
+}
+
+// computeBalances returns the net balance of every group member, kept in
+// group_balances by triggers on expenses, splits and settlements. Positive
+// means the member is owed.
+func computeBalances(ctx context.Context, db *db.DB, groupID uuid.UUID) (map[uuid.UUID]decimal.Decimal, error) {
+	rows, err := db.Pool.Query(ctx,
+		`SELECT gm.user_id, COALESCE(gb.balance, 0) FROM group_members gm
+		 LEFT JOIN group_balances gb ON gb.group_id = gm.group_id AND gb.user_id = gm.user_id
+		 WHERE gm.group_id = $1`, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get balances: %w", err)
+	}
+	defer rows.Close()
+
+	members := make(map[uuid.UUID]decimal.Decimal)
+	for rows.Next() {
+		var uid uuid.UUID
+		var balance decimal.Decimal
+		if err := rows.Scan(&uid, &balance); err != nil {
+			return nil, fmt.Errorf("failed to scan balance: %w", err)
+		}
+		members[uid] = balance
+	}
+	return members, rows.Err()
+}
+
+// expectedBalancesSQL derives balances from the full history: payers of
+// approved, undeleted expenses are owed what they paid, split members owe
+// their share, and confirmed, undeleted settlements move the payer up and
+// the recipient down. $1 limits it to one group, or all when null.
+const expectedBalancesSQL = `SELECT group_id, user_id, SUM(amount) AS balance FROM (
+	SELECT e.group_id, ep.user_id, ep.amount
+	FROM expense_payers ep JOIN expenses e ON e.id = ep.expense_id
+	WHERE e.deleted_at IS NULL AND e.status = 'approved'
+	UNION ALL
+	SELECT e.group_id, es.user_id, -es.amount
+	FROM expense_splits es JOIN expenses e ON e.id = es.expense_id
+	WHERE e.deleted_at IS NULL AND e.status = 'approved'
+	UNION ALL
+	SELECT group_id, from_user, amount FROM settlements WHERE status = 'confirmed' AND deleted_at IS NULL
+	UNION ALL
+	SELECT group_id, to_user, -amount FROM settlements WHERE status = 'confirmed' AND deleted_at IS NULL
+) entries
+WHERE $1::uuid IS NULL OR group_id = $1
+GROUP BY group_id, user_id`
+
+// RebuildBalances recomputes group_balances from the history of groupID, or
+// of every group when nil, and returns how many balances were wrong. The
+// triggers keep the table right; this is for reconciling after manual
+// fixes or a bug. Balance writes wait until it's done.
+func RebuildBalances(ctx context.Context, db *db.DB, groupID *uuid.UUID) (int, error) {
+	var corrected int
+	err := db.WithTx(ctx, func(tx pgx.Tx) error {
+		// Conflicts with the trigger writes, so none land between the
+		// comparison and the rewrite
+		if _, err := tx.Exec(ctx, `LOCK TABLE group_balances IN SHARE ROW EXCLUSIVE MODE`); err != nil {
+			return err
+		}
+
+		err := tx.QueryRow(ctx,
+			`SELECT COUNT(*) FROM (`+expectedBalancesSQL+`) expected
+			 FULL JOIN (SELECT * FROM group_balances WHERE $1::uuid IS NULL OR group_id = $1) current
+			   USING (group_id, user_id)
+			 WHERE COALESCE(expected.balance, 0) <> COALESCE(current.balance, 0)`, groupID).Scan(&corrected)
+		if err != nil {
+			return err
+		}
+
+		if _, err := tx.Exec(ctx,
+			`DELETE FROM group_balances WHERE $1::uuid IS NULL OR group_id = $1`, groupID); err != nil {
+			return err
+		}
+		_, err = tx.Exec(ctx,
+			`INSERT INTO group_balances (group_id, user_id, balance) `+expectedBalancesSQL, groupID)
+		return err
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to rebuild balances: %w", err)
+	}
+	return corrected, nil
+}
+
+// SimplifyDebts returns a small set of transfers that settles all balances.
+// It greedily matches the largest debtor with the largest creditor, which
+// needs at most n-1 transfers for n members with a non-zero balance.
+func SimplifyDebts(balances map[uuid.UUID]decimal.Decimal) []Transfer {
+	type entry struct {
+		userID uuid.UUID
+		amount decimal.Decimal
+	}
+
+	var creditors, debtors []entry
+	for uid, amt := range balances {
+		if amt.IsPositive() {
+			creditors = append(creditors, entry{uid, amt})
+		} else if amt.IsNegative() {
+			debtors = append(debtors, entry{uid, amt.Neg()})
+		}
+	}
+
+	// Sort largest first, breaking ties by user ID so results are stable
+	byAmount := func(entries []entry) func(i, j int) bool {
+		return func(i, j int) bool {
+			if cmp := entries[i].amount.Cmp(entries[j].amount); cmp != 0 {
+				return cmp > 0
+			}
+			return entries[i].userID.String() < entries[j].userID.String()
+		}
+	}
+	sort.Slice(creditors, byAmount(creditors))
+	sort.Slice(debtors, byAmount(debtors))
+
+	transfers := []Transfer{}
+	i, j := 0, 0
+	for i < len(debtors) && j < len(creditors) {
+		amount := decimal.Min(debtors[i].amount, creditors[j].amount)
+		transfers = append(transfers, Transfer{
+			FromUser: debtors[i].userID,
+			ToUser:   creditors[j].userID,
+			Amount:   amount,
+		})
+
+		debtors[i].amount = debtors[i].amount.Sub(amount)
+		creditors[j].amount = creditors[j].amount.Sub(amount)
+		if debtors[i].amount.IsZero() {
+			i++
+		}
+		if creditors[j].amount.IsZero() {
+			j++
+		}
+	}
+
+	return transfers
+}
+
+// GetSettleSuggestions returns the transfers needed to zero all group
+// balances once pending settlements are confirmed
+func GetSettleSuggestions(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	groupID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid group id"})
+		return
+	}
+
+	isMember, err := helpers.IsGroupMember(c.Request.Context(), db, groupID, userID)
+	if err != nil || !isMember {
+		c.JSON(403, gin.H{"error": "not a member of the group"})
+		return
+	}
+
+	currency, err := helpers.GetGroupCurrency(c.Request.Context(), db, groupID)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to get group currency"})
+		return
+	}
+
+	balances, err := settleBalances(c.Request.Context(), db.Pool, groupID)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to calculate balances"})
+		return
+	}
+
+	transfers := SimplifyDebts(balances)
+	for i := range transfers {
+		transfers[i].Currency = currency
+	}
+
+	c.JSON(200, transfers)
+}
+
+// UserBalance is a user's net balance in one of their groups. Positive means
+// they are owed.
+type UserBalance struct {
+	GroupID   uuid.UUID       `json:"group_id"`
+	GroupName string          `json:"group_name"`
+	Balance   decimal.Decimal `json:"balance"`
+	Currency  string          `json:"currency"`
+}
+
+// UserBalances returns the user's net balance in every group they belong to
+func UserBalances(ctx context.Context, db *db.DB, userID uuid.UUID) ([]UserBalance, error) {
+	rows, err := db.Pool.Query(ctx,
+		`SELECT g.id, g.name, g.currency, COALESCE(gb.balance, 0) FROM groups g
+		 JOIN group_members gm ON gm.group_id = g.id
+		 LEFT JOIN group_balances gb ON gb.group_id = g.id AND gb.user_id = gm.user_id
+		 WHERE gm.user_id = $1 AND g.deleted_at IS NULL ORDER BY g.name`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get groups: %w", err)
+	}
+	defer rows.Close()
+
+	var balances []UserBalance
+	for rows.Next() {
+		var b UserBalance
+		if err := rows.Scan(&b.GroupID, &b.GroupName, &b.Currency, &b.Balance); err != nil {
+			return nil, fmt.Errorf("failed to scan group: %w", err)
+		}
+		balances = append(balances, b)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get groups: %w", err)
+	}
+	return balances, nil
+}
+
+// PairwiseBalance is the net amount one member owes another
+type PairwiseBalance struct {
+	FromUser uuid.UUID       `json:"from_user"`
+	ToUser   uuid.UUID       `json:"to_user"`
+	Amount   decimal.Decimal `json:"amount"`
+	Currency string          `json:"currency"`
+}
+
+type userPair struct {
+	a, b uuid.UUID
+}
+
+// netPairwise collapses directed debts into one net amount per pair of
+// members, dropping pairs that cancel out.
+func netPairwise(debts []PairwiseBalance) []PairwiseBalance {
+	net := make(map[userPair]decimal.Decimal)
+	var order []userPair
+	for _, d := range debts {
+		// Key on the ordered pair so A->B and B->A land in the same bucket
+		pair, amt := userPair{d.FromUser, d.ToUser}, d.Amount
+		if d.FromUser.String() > d.ToUser.String() {
+			pair, amt = userPair{d.ToUser, d.FromUser}, d.Amount.Neg()
+		}
+		if _, ok := net[pair]; !ok {
+			order = append(order, pair)
+		}
+		net[pair] = net[pair].Add(amt)
+	}
+
+	result := []PairwiseBalance{}
+	for _, pair := range order {
+		amt := net[pair]
+		switch {
+		case amt.IsPositive():
+			result = append(result, PairwiseBalance{FromUser: pair.a, ToUser: pair.b, Amount: amt})
+		case amt.IsNegative():
+			result = append(result, PairwiseBalance{FromUser: pair.b, ToUser: pair.a, Amount: amt.Neg()})
+		}
+	}
+	return result
+}
+
+// pairwiseDebtsSQL returns directed debts between members. Each split owes
+// the payers in proportion to what they paid; a settlement from A to B is
+// treated as B owing A.
+const pairwiseDebtsSQL = `SELECT es.user_id, ep.user_id, ROUND(SUM(es.amount * ep.amount / e.total_amount), 2)
+	 FROM expense_splits es
+	 JOIN expenses e ON es.expense_id = e.id
+	 JOIN expense_payers ep ON ep.expense_id = e.id
+	 WHERE e.group_id = $1 AND es.user_id <> ep.user_id AND e.deleted_at IS NULL AND e.status = 'approved'
+	 GROUP BY es.user_id, ep.user_id
+	 UNION ALL
+	 SELECT to_user, from_user, SUM(amount)
+	 FROM settlements
+	 WHERE group_id = $1 AND status = 'confirmed' AND deleted_at IS NULL
+	 GROUP BY to_user, from_user`
+
+// pairwiseDebts returns the directed debts of a group, before netting
+func pairwiseDebts(ctx context.Context, db *db.DB, groupID uuid.UUID) ([]PairwiseBalance, error) {
+	rows, err := db.Pool.Query(ctx, pairwiseDebtsSQL, groupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var debts []PairwiseBalance
+	for rows.Next() {
+		var d PairwiseBalance
+		if err := rows.Scan(&d.FromUser, &d.ToUser, &d.Amount); err != nil {
+			return nil, err
+		}
+		debts = append(debts, d)
+	}
+	return debts, rows.Err()
+}
+
+// GetPairwiseBalances returns who owes whom within a group
+func GetPairwiseBalances(c *gin.Context, service *Service) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	groupID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid group id"})
+		return
+	}
+
+	balances, err := service.PairwiseBalances(c.Request.Context(), userID, groupID)
+	if err != nil {
+		respondError(c, err, "failed to get pairwise balances")
+		return
+	}
+
+	c.JSON(200, balances)
+}
+
+// BalancePoint is a snapshot of member balances at the start of a period
+type BalancePoint struct {
+	PeriodStart time.Time `json:"period_start"`
+	Balances    []Balance `json:"balances"`
+}
+
+var validHistoryIntervals = map[string]bool{
+	"day":   true,
+	"week":  true,
+	"month": true,
+}
+
+// GetBalanceHistory returns each member's running balance bucketed by interval
+func GetBalanceHistory(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	groupID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid group id"})
+		return
+	}
+
+	interval := c.DefaultQuery("interval", "week")
+	if !validHistoryIntervals[interval] {
+		c.JSON(400, gin.H{"error": "interval must be one of day, week, month"})
+		return
+	}
+
+	isMember, err := helpers.IsGroupMember(c.Request.Context(), db, groupID, userID)
+	if err != nil || !isMember {
+		c.JSON(403, gin.H{"error": "not a member of the group"})
+		return
+	}
+
+	currency, err := helpers.GetGroupCurrency(c.Request.Context(), db, groupID)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to get group currency"})
+		return
+	}
+
+	// Bucket every balance movement, then take a running sum per member
+	rows, err := db.Pool.Query(c.Request.Context(),
+		`WITH deltas AS (
+		     SELECT date_trunc($2, e.expense_date) AS bucket, ep.user_id, ep.amount AS delta
+		     FROM expense_payers ep JOIN expenses e ON ep.expense_id = e.id
+		     WHERE e.group_id = $1 AND e.deleted_at IS NULL AND e.status = 'approved'
+		     UNION ALL
+		     SELECT date_trunc($2, e.expense_date), es.user_id, -es.amount
+		     FROM expense_splits es JOIN expenses e ON es.expense_id = e.id
+		     WHERE e.group_id = $1 AND e.deleted_at IS NULL AND e.status = 'approved'
+		     UNION ALL
+		     SELECT date_trunc($2, confirmed_at), from_user, amount
+		     FROM settlements WHERE group_id = $1 AND status = 'confirmed' AND deleted_at IS NULL
+		     UNION ALL
+		     SELECT date_trunc($2, confirmed_at), to_user, -amount
+		     FROM settlements WHERE group_id = $1 AND status = 'confirmed' AND deleted_at IS NULL
+		 )
+		 SELECT bucket, user_id, SUM(SUM(delta)) OVER (PARTITION BY user_id ORDER BY bucket)
+		 FROM deltas
+		 GROUP BY bucket, user_id
+		 ORDER BY bucket, user_id`,
+		groupID, interval)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to get balance history"})
+		return
+	}
+	defer rows.Close()
+
+	// Carry balances forward so every point includes members without activity
+	running := make(map[uuid.UUID]decimal.Decimal)
+	var seen []uuid.UUID
+	points := []BalancePoint{}
+	snapshot := func(bucket time.Time) {
+		point := BalancePoint{PeriodStart: bucket}
+		for _, id := range seen {
+			point.Balances = append(point.Balances, Balance{UserID: id, Amount: running[id], Currency: currency})
+		}
+		points = append(points, point)
+	}
+
+	var current time.Time
+	for rows.Next() {
+		var bucket time.Time
+		var uid uuid.UUID
+		var amt decimal.Decimal
+		if err := rows.Scan(&bucket, &uid, &amt); err != nil {
+			c.JSON(500, gin.H{"error": "failed to scan balance history"})
+			return
+		}
+		if !current.IsZero() && !bucket.Equal(current) {
+			snapshot(current)
+		}
+		current = bucket
+
+		if _, ok := running[uid]; !ok {
+			seen = append(seen, uid)
+		}
+		running[uid] = amt
+	}
+	if !current.IsZero() {
+		snapshot(current)
+	}
+
+	c.JSON(200, gin.H{
+		"interval": interval,
+		"currency": currency,
+		"points":   points,
+	})
+}
@@ -0,0 +1,118 @@
+package group
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSimplifyDebts(t *testing.T) {
+	userA := uuid.New()
+	userB := uuid.New()
+	userC := uuid.New()
+	userD := uuid.New()
+
+	tests := []struct {
+		name          string
+		balances      map[uuid.UUID]decimal.Decimal
+		expectedCount int
+	}{
+		{
+			name: "all settled",
+			balances: map[uuid.UUID]decimal.Decimal{
+				userA: decimal.Zero,
+				userB: decimal.Zero,
+			},
+			expectedCount: 0,
+		},
+		{
+			name: "one debtor one creditor",
+			balances: map[uuid.UUID]decimal.Decimal{
+				userA: decimal.NewFromInt(50),
+				userB: decimal.NewFromInt(-50),
+			},
+			expectedCount: 1,
+		},
+		{
+			name: "chain collapses to direct transfers",
+			balances: map[uuid.UUID]decimal.Decimal{
+				userA: decimal.NewFromInt(60),
+				userB: decimal.NewFromInt(-20),
+				userC: decimal.NewFromInt(-40),
+			},
+			expectedCount: 2,
+		},
+		{
+			name: "two creditors two debtors",
+			balances: map[uuid.UUID]decimal.Decimal{
+				userA: decimal.RequireFromString("33.33"),
+				userB: decimal.RequireFromString("16.67"),
+				userC: decimal.RequireFromString("-25.00"),
+				userD: decimal.RequireFromString("-25.00"),
+			},
+			expectedCount: 3,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			transfers := SimplifyDebts(tt.balances)
+			assert.Len(t, transfers, tt.expectedCount)
+
+			// Applying the transfers must zero every balance
+			remaining := make(map[uuid.UUID]decimal.Decimal)
+			for uid, amt := range tt.balances {
+				remaining[uid] = amt
+			}
+			for _, tr := range transfers {
+				assert.True(t, tr.Amount.IsPositive())
+				remaining[tr.FromUser] = remaining[tr.FromUser].Add(tr.Amount)
+				remaining[tr.ToUser] = remaining[tr.ToUser].Sub(tr.Amount)
+			}
+			for uid, amt := range remaining {
+				assert.True(t, amt.IsZero(), "balance for %s not settled: %s", uid, amt)
+			}
+		})
+	}
+}
+
+func TestNetPairwise(t *testing.T) {
+	userA := uuid.New()
+	userB := uuid.New()
+	userC := uuid.New()
+
+	debts := []PairwiseBalance{
+		{FromUser: userB, ToUser: userA, Amount: decimal.NewFromInt(30)},
+		{FromUser: userA, ToUser: userB, Amount: decimal.NewFromInt(10)},
+		{FromUser: userC, ToUser: userA, Amount: decimal.NewFromInt(15)},
+		{FromUser: userA, ToUser: userC, Amount: decimal.NewFromInt(15)},
+	}
+
+	result := netPairwise(debts)
+	assert.Len(t, result, 1)
+	assert.Equal(t, userB, result[0].FromUser)
+	assert.Equal(t, userA, result[0].ToUser)
+	assert.True(t, result[0].Amount.Equal(decimal.NewFromInt(20)))
+}
+
+func TestSettlesExactly(t *testing.T) {
+	userA := uuid.New()
+	userB := uuid.New()
+	userC := uuid.New()
+
+	balances := map[uuid.UUID]decimal.Decimal{
+		userA: decimal.NewFromInt(60),
+		userB: decimal.NewFromInt(-20),
+		userC: decimal.NewFromInt(-40),
+	}
+
+	assert.True(t, settlesExactly(balances, SimplifyDebts(balances)))
+
+	stale := []Transfer{
+		{FromUser: userB, ToUser: userA, Amount: decimal.NewFromInt(20)},
+		{FromUser: userC, ToUser: userA, Amount: decimal.NewFromInt(30)},
+	}
+	assert.False(t, settlesExactly(balances, stale))
+}
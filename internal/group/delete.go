@@ -0,0 +1,76 @@
+package group
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+	"github.com/yanonymousV2/finance-manager-backend/internal/middleware"
+	"github.com/yanonymousV2/finance-manager-backend/internal/softdelete"
+)
+
+// DeleteGroup deletes a group for every member. Its expenses, settlements
+// and balances are kept, so restoring it brings everything back, until it's
+// purged. Personal expenses mirrored from it stay. Only its creator can
+// delete it.
+func DeleteGroup(c *gin.Context, db *db.DB) {
+	setDeleted(c, db, true)
+}
+
+// RestoreGroup undoes DeleteGroup
+func RestoreGroup(c *gin.Context, db *db.DB) {
+	setDeleted(c, db, false)
+}
+
+func setDeleted(c *gin.Context, db *db.DB, deleted bool) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	groupID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid group id"})
+		return
+	}
+
+	// Members can't see a deleted group, so only the creator learns it exists
+	var createdBy uuid.UUID
+	var deletedAt *time.Time
+	err = db.Pool.QueryRow(c.Request.Context(),
+		"SELECT created_by, deleted_at FROM groups WHERE id = $1", groupID).Scan(&createdBy, &deletedAt)
+	if err != nil || createdBy != userID && deletedAt != nil {
+		c.JSON(404, gin.H{"error": "group not found"})
+		return
+	}
+	if createdBy != userID {
+		c.JSON(403, gin.H{"error": "only the group's creator can delete or restore it"})
+		return
+	}
+
+	if deleted {
+		if deletedAt != nil {
+			c.JSON(404, gin.H{"error": "group not found"})
+			return
+		}
+		if _, err := softdelete.Delete(c.Request.Context(), db.Pool, softdelete.Groups, groupID, userID); err != nil {
+			c.JSON(500, gin.H{"error": "failed to delete group"})
+			return
+		}
+		c.JSON(200, gin.H{"message": "group deleted successfully"})
+		return
+	}
+
+	if deletedAt == nil {
+		c.JSON(400, gin.H{"error": "group is not deleted"})
+		return
+	}
+	if _, err := softdelete.Restore(c.Request.Context(), db.Pool, softdelete.Groups, groupID); err != nil {
+		c.JSON(500, gin.H{"error": "failed to restore group"})
+		return
+	}
+	c.JSON(200, gin.H{"message": "group restored successfully"})
+}
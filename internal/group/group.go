@@ -1,27 +1,28 @@
 package group
 
 import (
+	"errors"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
 	"github.com/shopspring/decimal"
 
-	"github.com/yanonymousV2/finance-manager-backend/internal/db"
-	"github.com/yanonymousV2/finance-manager-backend/internal/helpers"
 	"github.com/yanonymousV2/finance-manager-backend/internal/middleware"
+	"github.com/yanonymousV2/finance-manager-backend/internal/validation"
 )
 
 type Group struct {
 	ID        uuid.UUID `json:"id" db:"id"`
 	Name      string    `json:"name" db:"name"`
+	Currency  string    `json:"currency" db:"currency"`
 	CreatedBy uuid.UUID `json:"created_by" db:"created_by"`
 	CreatedAt time.Time `json:"created_at" db:"created_at"`
 }
 
 type CreateGroupRequest struct {
-	Name string `json:"name" validate:"required,min=1"`
+	Name     string `json:"name" validate:"required,min=1"`
+	Currency string `json:"currency,omitempty" validate:"omitempty,iso4217"`
 }
 
 type AddMemberRequest struct {
@@ -29,237 +30,92 @@ type AddMemberRequest struct {
 }
 
 type Balance struct {
-	UserID uuid.UUID       `json:"user_id"`
-	Amount decimal.Decimal `json:"amount"`
+	UserID   uuid.UUID       `json:"user_id"`
+	Amount   decimal.Decimal `json:"amount"`
+	Currency string          `json:"currency"`
 }
 
-func CreateGroup(c *gin.Context, db *db.DB) {
-	userID, ok := middleware.GetUserID(c)
-	if !ok {
-		c.JSON(401, gin.H{"error": "unauthorized"})
-		return
-	}
-
-	var req CreateGroupRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
-		return
-	}
+// DefaultCurrency is used when a group is created without an explicit currency
+const DefaultCurrency = "USD"
 
-	validate := validator.New()
-	if err := validate.Struct(req); err != nil {
+// respondError reports a Service error: rule violations with their own
+// status and message, anything else as a 500 with message
+func respondError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, ErrNotMember):
+		c.JSON(403, gin.H{"error": err.Error()})
+	case errors.Is(err, ErrUserNotFound), errors.Is(err, ErrPlaceholder), errors.Is(err, ErrAlreadyMember):
 		c.JSON(400, gin.H{"error": err.Error()})
-		return
+	default:
+		c.JSON(500, gin.H{"error": message})
 	}
+}
 
-	// Start transaction to create group and add creator as member
-	tx, err := db.Pool.Begin(c.Request.Context())
-	if err != nil {
-		c.JSON(500, gin.H{"error": "failed to start transaction"})
+func CreateGroup(c *gin.Context, service *Service) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
 		return
 	}
-	defer tx.Rollback(c.Request.Context())
 
-	var g Group
-	err = tx.QueryRow(c.Request.Context(),
-		"INSERT INTO groups (name, created_by) VALUES ($1, $2) RETURNING id, name, created_by, created_at",
-		req.Name, userID).Scan(&g.ID, &g.Name, &g.CreatedBy, &g.CreatedAt)
-	if err != nil {
-		c.JSON(500, gin.H{"error": "failed to create group"})
+	var req CreateGroupRequest
+	if !validation.Bind(c, &req) {
 		return
 	}
 
-	// Add creator as member
-	_, err = tx.Exec(c.Request.Context(),
-		"INSERT INTO group_members (group_id, user_id) VALUES ($1, $2)",
-		g.ID, userID)
+	g, err := service.Create(c.Request.Context(), userID, req)
 	if err != nil {
-		c.JSON(500, gin.H{"error": "failed to add creator as member"})
-		return
-	}
-
-	if err := tx.Commit(c.Request.Context()); err != nil {
-		c.JSON(500, gin.H{"error": "failed to commit transaction"})
+		respondError(c, err, "failed to create group")
 		return
 	}
 
 	c.JSON(201, g)
 }
 
-func AddMember(c *gin.Context, db *db.DB) {
+func AddMember(c *gin.Context, service *Service) {
 	userID, ok := middleware.GetUserID(c)
 	if !ok {
 		c.JSON(401, gin.H{"error": "unauthorized"})
 		return
 	}
 
-	groupIDStr := c.Param("id")
-	groupID, err := uuid.Parse(groupIDStr)
+	groupID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
 		c.JSON(400, gin.H{"error": "invalid group id"})
 		return
 	}
 
-	// Check if user is member of group
-	var isMember bool
-	err = db.Pool.QueryRow(c.Request.Context(),
-		"SELECT EXISTS(SELECT 1 FROM group_members WHERE group_id = $1 AND user_id = $2)", groupID, userID).Scan(&isMember)
-	if err != nil || !isMember {
-		c.JSON(403, gin.H{"error": "not a member of the group"})
-		return
-	}
-
 	var req AddMemberRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
-		return
-	}
-
-	validate := validator.New()
-	if err := validate.Struct(req); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
-		return
-	}
-
-	// Check if user exists
-	exists, err := helpers.UserExists(c.Request.Context(), db, req.UserID)
-	if err != nil || !exists {
-		c.JSON(400, gin.H{"error": "user does not exist"})
+	if !validation.Bind(c, &req) {
 		return
 	}
 
-	// Check if already member
-	exists, err = helpers.IsGroupMember(c.Request.Context(), db, groupID, req.UserID)
-	if err != nil {
-		c.JSON(500, gin.H{"error": "database error"})
-		return
-	}
-	if exists {
-		c.JSON(400, gin.H{"error": "user already in group"})
-		return
-	}
-
-	// Add member
-	_, err = db.Pool.Exec(c.Request.Context(),
-		"INSERT INTO group_members (group_id, user_id) VALUES ($1, $2)", groupID, req.UserID)
-	if err != nil {
-		c.JSON(500, gin.H{"error": "failed to add member"})
+	if err := service.AddMember(c.Request.Context(), userID, groupID, req.UserID); err != nil {
+		respondError(c, err, "failed to add member")
 		return
 	}
 
 	c.JSON(200, gin.H{"message": "member added"})
 }
 
-func GetBalances(c *gin.Context, db *db.DB) {
+func GetBalances(c *gin.Context, service *Service) {
 	userID, ok := middleware.GetUserID(c)
 	if !ok {
 		c.JSON(401, gin.H{"error": "unauthorized"})
 		return
 	}
 
-	groupIDStr := c.Param("id")
-	groupID, err := uuid.Parse(groupIDStr)
+	groupID, err := uuid.Parse(c.Param("id"))
 	if err != nil {
 		c.JSON(400, gin.H{"error": "invalid group id"})
 		return
 	}
 
-	// Check if user is member of group
-	isMember, err := helpers.IsGroupMember(c.Request.Context(), db, groupID, userID)
-	if err != nil || !isMember {
-		c.JSON(403, gin.H{"error": "not a member of the group"})
-		return
-	}
-
-	// Get all members
-	rows, err := db.Pool.Query(c.Request.Context(),
-		"SELECT user_id FROM group_members WHERE group_id = $1", groupID)
-	if err != nil {
-		c.JSON(500, gin.H{"error": "failed to get members"})
-		return
-	}
-	defer rows.Close()
-
-	members := make(map[uuid.UUID]decimal.Decimal)
-	for rows.Next() {
-		var uid uuid.UUID
-		if err := rows.Scan(&uid); err != nil {
-			c.JSON(500, gin.H{"error": "failed to scan member"})
-			return
-		}
-		members[uid] = decimal.Zero
-	}
-
-	// Add from expenses: paid_by gets +total, split users get -amount
-	expRows, err := db.Pool.Query(c.Request.Context(),
-		"SELECT paid_by, total_amount FROM expenses WHERE group_id = $1", groupID)
-	if err != nil {
-		c.JSON(500, gin.H{"error": "failed to get expenses"})
-		return
-	}
-	defer expRows.Close()
-
-	for expRows.Next() {
-		var paidBy uuid.UUID
-		var total decimal.Decimal
-		if err := expRows.Scan(&paidBy, &total); err != nil {
-			c.JSON(500, gin.H{"error": "failed to scan expense"})
-			return
-		}
-		if bal, ok := members[paidBy]; ok {
-			members[paidBy] = bal.Add(total)
-		}
-	}
-
-	splitRows, err := db.Pool.Query(c.Request.Context(),
-		"SELECT es.user_id, es.amount FROM expense_splits es JOIN expenses e ON es.expense_id = e.id WHERE e.group_id = $1", groupID)
-	if err != nil {
-		c.JSON(500, gin.H{"error": "failed to get expense splits"})
-		return
-	}
-	defer splitRows.Close()
-
-	for splitRows.Next() {
-		var uid uuid.UUID
-		var amt decimal.Decimal
-		if err := splitRows.Scan(&uid, &amt); err != nil {
-			c.JSON(500, gin.H{"error": "failed to scan split"})
-			return
-		}
-		if bal, ok := members[uid]; ok {
-			members[uid] = bal.Sub(amt)
-		}
-	}
-
-	// Subtract settlements: from_user -amount, to_user +amount
-	settRows, err := db.Pool.Query(c.Request.Context(),
-		"SELECT from_user, to_user, amount FROM settlements WHERE group_id = $1", groupID)
+	balances, err := service.Balances(c.Request.Context(), userID, groupID)
 	if err != nil {
-		c.JSON(500, gin.H{"error": "failed to get settlements"})
+		respondError(c, err, "failed to calculate balances")
 		return
 	}
-	defer settRows.Close()
-
-	for settRows.Next() {
-		var from, to uuid.UUID
-		var amt decimal.Decimal
-		if err := settRows.Scan(&from, &to, &amt); err != nil {
-			c.JSON(500, gin.H{"error": "failed to scan settlement"})
-			return
-		}
-		if bal, ok := members[from]; ok {
-			members[from] = bal.Sub(amt)
-		}
-		if bal, ok := members[to]; ok {
-			members[to] = bal.Add(amt)
-		}
-	}
-
-	// Convert to slice
-	var balances []Balance
-	for uid, amt := range members {
-		balances = append(balances, Balance{UserID: uid, Amount: amt})
-	}
 
 	c.JSON(200, balances)
 }
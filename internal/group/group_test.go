@@ -64,6 +64,11 @@ func createExpense(t *testing.T, testDB *db.DB, groupID, paidBy uuid.UUID, total
 		expenseID, groupID, "Test Expense", total, paidBy)
 	require.NoError(t, err)
 
+	_, err = testDB.Pool.Exec(context.Background(),
+		"INSERT INTO expense_payers (expense_id, user_id, amount) VALUES ($1, $2, $3)",
+		expenseID, paidBy, total)
+	require.NoError(t, err)
+
 	for userID, amount := range splits {
 		_, err := testDB.Pool.Exec(context.Background(),
 			"INSERT INTO expense_splits (expense_id, user_id, amount) VALUES ($1, $2, $3)",
@@ -117,7 +122,8 @@ func TestBalanceCalculation(t *testing.T) {
 			},
 		},
 		{
-			name: "settlement reduces balance",
+			// Paying what you owe moves the payer up and the recipient down
+			name: "settlement moves payer up and recipient down",
 			setup: func() {
 				createExpense(t, testDB, groupID, userA, decimal.NewFromFloat(60),
 					map[uuid.UUID]decimal.Decimal{
@@ -128,9 +134,9 @@ func TestBalanceCalculation(t *testing.T) {
 				createSettlement(t, testDB, groupID, userB, userA, decimal.NewFromFloat(20))
 			},
 			expected: map[uuid.UUID]decimal.Decimal{
-				userA: decimal.NewFromFloat(60).Sub(decimal.NewFromFloat(20)),                              // 40
-				userB: decimal.NewFromFloat(0).Sub(decimal.NewFromFloat(20)).Add(decimal.NewFromFloat(20)), // 0
-				userC: decimal.NewFromFloat(0).Sub(decimal.NewFromFloat(20)),                               // -20
+				userA: decimal.NewFromFloat(60).Sub(decimal.NewFromFloat(20)).Sub(decimal.NewFromFloat(20)), // 60 - 20 - 20 = 20
+				userB: decimal.NewFromFloat(0).Sub(decimal.NewFromFloat(20)).Add(decimal.NewFromFloat(20)),  // -20 + 20 = 0
+				userC: decimal.NewFromFloat(0).Sub(decimal.NewFromFloat(20)),                                // -20
 			},
 		},
 		{
@@ -154,9 +160,9 @@ func TestBalanceCalculation(t *testing.T) {
 				createSettlement(t, testDB, groupID, userC, userA, decimal.NewFromFloat(10))
 			},
 			expected: map[uuid.UUID]decimal.Decimal{
-				userA: decimal.NewFromFloat(90).Sub(decimal.NewFromFloat(30)).Sub(decimal.NewFromFloat(20)).Add(decimal.NewFromFloat(10)), // 90 - 30 - 20 + 10 = 50
+				userA: decimal.NewFromFloat(90).Sub(decimal.NewFromFloat(30)).Sub(decimal.NewFromFloat(20)).Sub(decimal.NewFromFloat(10)), // 90 - 30 - 20 - 10 = 30
 				userB: decimal.NewFromFloat(60).Sub(decimal.NewFromFloat(20)).Sub(decimal.NewFromFloat(30)),                               // 60 - 20 - 30 = 10
-				userC: decimal.NewFromFloat(0).Sub(decimal.NewFromFloat(30)).Sub(decimal.NewFromFloat(20)).Sub(decimal.NewFromFloat(10)),  // -30 -20 -10 = -60
+				userC: decimal.NewFromFloat(0).Sub(decimal.NewFromFloat(30)).Sub(decimal.NewFromFloat(20)).Add(decimal.NewFromFloat(10)),  // -30 - 20 + 10 = -40
 			},
 		},
 	}
@@ -164,59 +170,13 @@ func TestBalanceCalculation(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			// Clean up for each test
-			_, err := testDB.Pool.Exec(context.Background(), "TRUNCATE expenses, expense_splits, settlements CASCADE")
+			_, err := testDB.Pool.Exec(context.Background(), "TRUNCATE expenses, expense_splits, settlements, group_balances CASCADE")
 			require.NoError(t, err)
 
 			tt.setup()
 
-			// Calculate balances by simulating the logic
-			balances := make(map[uuid.UUID]decimal.Decimal)
-			balances[userA] = decimal.Zero
-			balances[userB] = decimal.Zero
-			balances[userC] = decimal.Zero
-
-			// Add from expenses
-			expRows, err := testDB.Pool.Query(context.Background(),
-				"SELECT paid_by, total_amount FROM expenses WHERE group_id = $1", groupID)
+			balances, err := computeBalances(context.Background(), testDB, groupID)
 			require.NoError(t, err)
-			defer expRows.Close()
-
-			for expRows.Next() {
-				var paidBy uuid.UUID
-				var total decimal.Decimal
-				err := expRows.Scan(&paidBy, &total)
-				require.NoError(t, err)
-				balances[paidBy] = balances[paidBy].Add(total)
-			}
-
-			// Subtract splits
-			splitRows, err := testDB.Pool.Query(context.Background(),
-				"SELECT es.user_id, es.amount FROM expense_splits es JOIN expenses e ON es.expense_id = e.id WHERE e.group_id = $1", groupID)
-			require.NoError(t, err)
-			defer splitRows.Close()
-
-			for splitRows.Next() {
-				var uid uuid.UUID
-				var amt decimal.Decimal
-				err := splitRows.Scan(&uid, &amt)
-				require.NoError(t, err)
-				balances[uid] = balances[uid].Sub(amt)
-			}
-
-			// Adjust settlements
-			settRows, err := testDB.Pool.Query(context.Background(),
-				"SELECT from_user, to_user, amount FROM settlements WHERE group_id = $1", groupID)
-			require.NoError(t, err)
-			defer settRows.Close()
-
-			for settRows.Next() {
-				var from, to uuid.UUID
-				var amt decimal.Decimal
-				err := settRows.Scan(&from, &to, &amt)
-				require.NoError(t, err)
-				balances[from] = balances[from].Sub(amt)
-				balances[to] = balances[to].Add(amt)
-			}
 
 			// Check expectations (allowing small decimal differences)
 			for userID, expectedBalance := range tt.expected {
@@ -0,0 +1,269 @@
+package group
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+	"github.com/yanonymousV2/finance-manager-backend/internal/event"
+	"github.com/yanonymousV2/finance-manager-backend/internal/helpers"
+	"github.com/yanonymousV2/finance-manager-backend/internal/middleware"
+	"github.com/yanonymousV2/finance-manager-backend/internal/personalexpense"
+	"github.com/yanonymousV2/finance-manager-backend/internal/validation"
+)
+
+// Placeholder is a group member without an account, identified by name only
+type Placeholder struct {
+	ID          uuid.UUID `json:"id" db:"id"`
+	GroupID     uuid.UUID `json:"group_id"`
+	DisplayName string    `json:"display_name" db:"display_name"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+}
+
+type AddPlaceholderRequest struct {
+	DisplayName string `json:"display_name" validate:"required,min=1,max=100"`
+}
+
+// AddPlaceholderMember adds a member by name who can take part in splits
+// before signing up
+func AddPlaceholderMember(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	groupID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid group id"})
+		return
+	}
+
+	isMember, err := helpers.IsGroupMember(c.Request.Context(), db, groupID, userID)
+	if err != nil || !isMember {
+		c.JSON(403, gin.H{"error": "not a member of the group"})
+		return
+	}
+
+	var req AddPlaceholderRequest
+	if !validation.Bind(c, &req) {
+		return
+	}
+
+	p := Placeholder{GroupID: groupID}
+	err = db.WithTx(c.Request.Context(), func(tx pgx.Tx) error {
+		err := tx.QueryRow(c.Request.Context(),
+			"INSERT INTO users (display_name, is_placeholder) VALUES ($1, TRUE) RETURNING id, display_name, created_at",
+			req.DisplayName).Scan(&p.ID, &p.DisplayName, &p.CreatedAt)
+		if err != nil {
+			return helpers.NewRequestError(500, "failed to create placeholder")
+		}
+
+		_, err = tx.Exec(c.Request.Context(),
+			"INSERT INTO group_members (group_id, user_id) VALUES ($1, $2)", groupID, p.ID)
+		if err != nil {
+			return helpers.NewRequestError(500, "failed to add placeholder")
+		}
+
+		err = event.Record(c.Request.Context(), tx, event.Event{
+			Type: event.MemberAdded, UserID: userID, GroupID: &groupID,
+			Data: event.Member{GroupID: groupID, UserID: p.ID, IsPlaceholder: true},
+		})
+		if err != nil {
+			return helpers.NewRequestError(500, "failed to record event")
+		}
+		return nil
+	})
+	if err != nil {
+		helpers.RespondError(c, err, "failed to add placeholder")
+		return
+	}
+
+	c.JSON(201, p)
+}
+
+// IssueClaimToken gives a placeholder a new claim token, replacing any
+// earlier one. Only the group's creator can issue tokens, and hands the
+// token to the person the placeholder stands for.
+func IssueClaimToken(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	groupID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid group id"})
+		return
+	}
+
+	placeholderID, err := uuid.Parse(c.Param("placeholderId"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid placeholder id"})
+		return
+	}
+
+	isMember, err := helpers.IsGroupMember(c.Request.Context(), db, groupID, userID)
+	if err != nil || !isMember {
+		c.JSON(403, gin.H{"error": "not a member of the group"})
+		return
+	}
+
+	var createdBy uuid.UUID
+	err = db.Pool.QueryRow(c.Request.Context(),
+		"SELECT created_by FROM groups WHERE id = $1", groupID).Scan(&createdBy)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to get group"})
+		return
+	}
+	if createdBy != userID {
+		c.JSON(403, gin.H{"error": "only the group's creator can issue claim tokens"})
+		return
+	}
+
+	isMember, err = helpers.IsGroupMember(c.Request.Context(), db, groupID, placeholderID)
+	if err != nil || !isMember {
+		c.JSON(404, gin.H{"error": "placeholder not found in group"})
+		return
+	}
+
+	var token uuid.UUID
+	err = db.Pool.QueryRow(c.Request.Context(),
+		"UPDATE users SET claim_token = gen_random_uuid() WHERE id = $1 AND is_placeholder RETURNING claim_token",
+		placeholderID).Scan(&token)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(400, gin.H{"error": "member is not a placeholder"})
+		return
+	}
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to issue claim token"})
+		return
+	}
+
+	c.JSON(201, gin.H{"claim_token": token})
+}
+
+type ClaimPlaceholderRequest struct {
+	ClaimToken uuid.UUID `json:"claim_token" validate:"required"`
+}
+
+// ClaimPlaceholder merges a placeholder into the authenticated user, moving
+// its expenses, splits and settlements over. The user must already have been
+// added to the group and have the placeholder's claim token.
+func ClaimPlaceholder(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	groupID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid group id"})
+		return
+	}
+
+	placeholderID, err := uuid.Parse(c.Param("placeholderId"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid placeholder id"})
+		return
+	}
+
+	var req ClaimPlaceholderRequest
+	if !validation.Bind(c, &req) {
+		return
+	}
+
+	isMember, err := helpers.IsGroupMember(c.Request.Context(), db, groupID, userID)
+	if err != nil || !isMember {
+		c.JSON(403, gin.H{"error": "not a member of the group"})
+		return
+	}
+
+	isMember, err = helpers.IsGroupMember(c.Request.Context(), db, groupID, placeholderID)
+	if err != nil || !isMember {
+		c.JSON(404, gin.H{"error": "placeholder not found in group"})
+		return
+	}
+
+	isPlaceholder, err := helpers.IsPlaceholder(c.Request.Context(), db, placeholderID)
+	if err != nil || !isPlaceholder {
+		c.JSON(400, gin.H{"error": "member is not a placeholder"})
+		return
+	}
+
+	err = db.WithTx(c.Request.Context(), func(tx pgx.Tx) error {
+		// Clearing the token first means it can only be used once, even by
+		// concurrent claims
+		tag, err := tx.Exec(c.Request.Context(),
+			"UPDATE users SET claim_token = NULL WHERE id = $1 AND is_placeholder AND claim_token = $2",
+			placeholderID, req.ClaimToken)
+		if err != nil {
+			return helpers.NewRequestError(500, "failed to merge placeholder")
+		}
+		if tag.RowsAffected() == 0 {
+			return helpers.NewRequestError(403, "invalid claim token")
+		}
+
+		if err := mergePlaceholder(c.Request.Context(), tx, placeholderID, userID); err != nil {
+			return helpers.NewRequestError(500, "failed to merge placeholder")
+		}
+
+		// The claimed shares now belong to the user and may need mirroring
+		if err := personalexpense.SyncMemberExpenses(c.Request.Context(), tx, groupID, userID); err != nil {
+			return helpers.NewRequestError(500, "failed to sync personal expenses")
+		}
+		return nil
+	})
+	if err != nil {
+		helpers.RespondError(c, err, "failed to merge placeholder")
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "placeholder claimed"})
+}
+
+// mergePlaceholderSQL moves a placeholder's ($1) history to a user ($2).
+// Order matters: shared splits, item assignments and mutual settlements are
+// folded in before the remaining rows are reassigned, so no key or check is
+// violated, and every row is moved before the placeholder's cascading delete.
+var mergePlaceholderSQL = []string{
+	`UPDATE expense_splits es SET amount = es.amount + p.amount
+	 FROM expense_splits p
+	 WHERE p.expense_id = es.expense_id AND p.user_id = $1 AND es.user_id = $2`,
+	`DELETE FROM expense_splits
+	 WHERE user_id = $1 AND expense_id IN (SELECT expense_id FROM expense_splits WHERE user_id = $2)`,
+	`UPDATE expense_splits SET user_id = $2 WHERE user_id = $1`,
+	`UPDATE expense_payers ep SET amount = ep.amount + p.amount
+	 FROM expense_payers p
+	 WHERE p.expense_id = ep.expense_id AND p.user_id = $1 AND ep.user_id = $2`,
+	`DELETE FROM expense_payers
+	 WHERE user_id = $1 AND expense_id IN (SELECT expense_id FROM expense_payers WHERE user_id = $2)`,
+	`UPDATE expense_payers SET user_id = $2 WHERE user_id = $1`,
+	`UPDATE expenses SET paid_by = $2 WHERE paid_by = $1`,
+	`DELETE FROM expense_item_assignees
+	 WHERE user_id = $1 AND item_id IN (SELECT item_id FROM expense_item_assignees WHERE user_id = $2)`,
+	`UPDATE expense_item_assignees SET user_id = $2 WHERE user_id = $1`,
+	`DELETE FROM settlements
+	 WHERE (from_user = $1 AND to_user = $2) OR (from_user = $2 AND to_user = $1)`,
+	`UPDATE settlements SET from_user = $2 WHERE from_user = $1`,
+	`UPDATE settlements SET to_user = $2 WHERE to_user = $1`,
+	`DELETE FROM users WHERE id = $1 AND is_placeholder`,
+}
+
+// mergePlaceholder moves everything placeholderID was part of to userID and
+// deletes the placeholder
+func mergePlaceholder(ctx context.Context, tx pgx.Tx, placeholderID, userID uuid.UUID) error {
+	for _, stmt := range mergePlaceholderSQL {
+		if _, err := tx.Exec(ctx, stmt, placeholderID, userID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package group
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMergePlaceholderItemAssignees(t *testing.T) {
+	testDB := setupBalanceTestDB(t)
+	defer testDB.Close()
+	ctx := context.Background()
+
+	user := createBalanceTestUser(t, testDB, "claimer@example.com")
+	groupID := createBalanceTestGroup(t, testDB, user)
+	var placeholder uuid.UUID
+	err := testDB.Pool.QueryRow(ctx,
+		"INSERT INTO users (display_name, is_placeholder) VALUES ('Sam', TRUE) RETURNING id").Scan(&placeholder)
+	require.NoError(t, err)
+	addGroupMember(t, testDB, groupID, placeholder)
+
+	expenseID := createExpense(t, testDB, groupID, user, decimal.NewFromInt(30), map[uuid.UUID]decimal.Decimal{
+		user:        decimal.NewFromInt(10),
+		placeholder: decimal.NewFromInt(20),
+	})
+	// Only the placeholder had the wine; both had the pizza
+	item := func(description string, assignees ...uuid.UUID) uuid.UUID {
+		var id uuid.UUID
+		err := testDB.Pool.QueryRow(ctx,
+			"INSERT INTO expense_items (expense_id, description, amount) VALUES ($1, $2, 10) RETURNING id",
+			expenseID, description).Scan(&id)
+		require.NoError(t, err)
+		for _, a := range assignees {
+			_, err := testDB.Pool.Exec(ctx,
+				"INSERT INTO expense_item_assignees (item_id, user_id) VALUES ($1, $2)", id, a)
+			require.NoError(t, err)
+		}
+		return id
+	}
+	wine := item("Wine", placeholder)
+	pizza := item("Pizza", user, placeholder)
+
+	err = testDB.WithTx(ctx, func(tx pgx.Tx) error {
+		return mergePlaceholder(ctx, tx, placeholder, user)
+	})
+	require.NoError(t, err)
+
+	assignees := func(itemID uuid.UUID) []uuid.UUID {
+		rows, err := testDB.Pool.Query(ctx, "SELECT user_id FROM expense_item_assignees WHERE item_id = $1", itemID)
+		require.NoError(t, err)
+		ids, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+		require.NoError(t, err)
+		return ids
+	}
+	assert.Equal(t, []uuid.UUID{user}, assignees(wine), "the placeholder's own items move to the user")
+	assert.Equal(t, []uuid.UUID{user}, assignees(pizza), "shared items keep one assignment")
+
+	var remaining int
+	err = testDB.Pool.QueryRow(ctx, "SELECT COUNT(*) FROM users WHERE id = $1", placeholder).Scan(&remaining)
+	require.NoError(t, err)
+	assert.Zero(t, remaining)
+}
+
+// postPlaceholder sends body to handler as userID on the placeholder's path
+func postPlaceholder(t *testing.T, handler gin.HandlerFunc, userID, groupID, placeholderID uuid.UUID, action string, body any) *httptest.ResponseRecorder {
+	t.Helper()
+	router := gin.New()
+	router.POST("/groups/:id/placeholders/:placeholderId/"+action, func(c *gin.Context) {
+		c.Set("user_id", userID)
+		handler(c)
+	})
+
+	raw, err := json.Marshal(body)
+	require.NoError(t, err)
+	w := httptest.NewRecorder()
+	path := "/groups/" + groupID.String() + "/placeholders/" + placeholderID.String() + "/" + action
+	router.ServeHTTP(w, httptest.NewRequest("POST", path, bytes.NewReader(raw)))
+	return w
+}
+
+func TestClaimPlaceholder_RequiresClaimToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	testDB := setupBalanceTestDB(t)
+	defer testDB.Close()
+	ctx := context.Background()
+
+	creator := createBalanceTestUser(t, testDB, "creator@example.com")
+	member := createBalanceTestUser(t, testDB, "member@example.com")
+	sam := createBalanceTestUser(t, testDB, "sam@example.com")
+	groupID := createBalanceTestGroup(t, testDB, creator)
+	addGroupMember(t, testDB, groupID, member)
+	addGroupMember(t, testDB, groupID, sam)
+
+	var placeholder uuid.UUID
+	err := testDB.Pool.QueryRow(ctx,
+		"INSERT INTO users (display_name, is_placeholder) VALUES ('Sam', TRUE) RETURNING id").Scan(&placeholder)
+	require.NoError(t, err)
+	addGroupMember(t, testDB, groupID, placeholder)
+
+	issue := func(userID uuid.UUID) *httptest.ResponseRecorder {
+		return postPlaceholder(t, func(c *gin.Context) { IssueClaimToken(c, testDB) },
+			userID, groupID, placeholder, "claim-token", nil)
+	}
+	claim := func(userID, token uuid.UUID) *httptest.ResponseRecorder {
+		return postPlaceholder(t, func(c *gin.Context) { ClaimPlaceholder(c, testDB) },
+			userID, groupID, placeholder, "claim", gin.H{"claim_token": token})
+	}
+	tokenFrom := func(w *httptest.ResponseRecorder) uuid.UUID {
+		require.Equal(t, 201, w.Code, w.Body.String())
+		var resp struct {
+			ClaimToken uuid.UUID `json:"claim_token"`
+		}
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+		return resp.ClaimToken
+	}
+
+	// Nothing can be claimed before the creator issues a token
+	assert.Equal(t, 403, claim(member, uuid.New()).Code)
+
+	assert.Equal(t, 403, issue(member).Code, "only the creator issues tokens")
+
+	stale := tokenFrom(issue(creator))
+	token := tokenFrom(issue(creator))
+	assert.Equal(t, 403, claim(sam, stale).Code, "a new token replaces the old one")
+	assert.Equal(t, 403, claim(member, uuid.New()).Code)
+
+	w := claim(sam, token)
+	require.Equal(t, 200, w.Code, w.Body.String())
+	var exists bool
+	err = testDB.Pool.QueryRow(ctx, "SELECT EXISTS(SELECT 1 FROM users WHERE id = $1)", placeholder).Scan(&exists)
+	require.NoError(t, err)
+	assert.False(t, exists, "the placeholder is merged into the claimer")
+
+	assert.Equal(t, 404, claim(member, token).Code, "the placeholder is gone once claimed")
+}
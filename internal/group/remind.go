@@ -0,0 +1,116 @@
+package group
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+	"github.com/yanonymousV2/finance-manager-backend/internal/helpers"
+	"github.com/yanonymousV2/finance-manager-backend/internal/middleware"
+	"github.com/yanonymousV2/finance-manager-backend/internal/notification"
+)
+
+// reminderInterval is how often a debtor can be reminded within a group
+const reminderInterval = 24 * time.Hour
+
+// RemindDebtor notifies a member of what they owe the group
+func RemindDebtor(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	groupID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid group id"})
+		return
+	}
+
+	debtorID, err := uuid.Parse(c.Param("userId"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	isMember, err := helpers.IsGroupMember(c.Request.Context(), db, groupID, userID)
+	if err != nil || !isMember {
+		c.JSON(403, gin.H{"error": "not a member of the group"})
+		return
+	}
+
+	if debtorID == userID {
+		c.JSON(400, gin.H{"error": "cannot remind yourself"})
+		return
+	}
+
+	isMember, err = helpers.IsGroupMember(c.Request.Context(), db, groupID, debtorID)
+	if err != nil || !isMember {
+		c.JSON(404, gin.H{"error": "user is not a member of the group"})
+		return
+	}
+
+	// Placeholders have no account to notify
+	isPlaceholder, err := helpers.IsPlaceholder(c.Request.Context(), db, debtorID)
+	if err != nil || isPlaceholder {
+		c.JSON(400, gin.H{"error": "cannot remind a placeholder member"})
+		return
+	}
+
+	balances, err := computeBalances(c.Request.Context(), db, groupID)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to calculate balances"})
+		return
+	}
+	owed := balances[debtorID].Neg()
+	if !owed.IsPositive() {
+		c.JSON(400, gin.H{"error": "user does not owe anything"})
+		return
+	}
+
+	currency, err := helpers.GetGroupCurrency(c.Request.Context(), db, groupID)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to get group currency"})
+		return
+	}
+
+	var n notification.Notification
+	err = db.WithTx(c.Request.Context(), func(tx pgx.Tx) error {
+		// Reminders to one debtor in one group take turns, so two at once
+		// can't both pass the check
+		_, err := tx.Exec(c.Request.Context(), "SELECT pg_advisory_xact_lock(hashtextextended($1, 0))",
+			"settlement_reminder:"+debtorID.String()+":"+groupID.String())
+		if err != nil {
+			return helpers.NewRequestError(500, "failed to check reminders")
+		}
+
+		sent, err := notification.SentSince(c.Request.Context(), tx, debtorID, groupID,
+			notification.TypeSettlementReminder, time.Now().Add(-reminderInterval))
+		if err != nil {
+			return helpers.NewRequestError(500, "failed to check reminders")
+		}
+		if sent {
+			return helpers.NewRequestError(429, "user was already reminded in the last 24 hours")
+		}
+
+		n, err = notification.Create(c.Request.Context(), tx, debtorID, &groupID, notification.TypeSettlementReminder,
+			map[string]any{
+				"amount":      owed.StringFixed(2),
+				"currency":    currency,
+				"reminded_by": userID,
+			})
+		if err != nil {
+			return helpers.NewRequestError(500, "failed to send reminder")
+		}
+		return nil
+	})
+	if err != nil {
+		helpers.RespondError(c, err, "failed to send reminder")
+		return
+	}
+
+	c.JSON(201, n)
+}
@@ -0,0 +1,98 @@
+package group
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+	"github.com/yanonymousV2/finance-manager-backend/internal/event"
+	"github.com/yanonymousV2/finance-manager-backend/internal/helpers"
+)
+
+// Repository is the storage Service works against
+type Repository interface {
+	// Create inserts a group and makes its creator the first member
+	Create(ctx context.Context, name, currency string, createdBy uuid.UUID) (Group, error)
+	// AddMember adds userID to the group on behalf of addedBy
+	AddMember(ctx context.Context, groupID, userID, addedBy uuid.UUID) error
+	IsMember(ctx context.Context, groupID, userID uuid.UUID) (bool, error)
+	UserExists(ctx context.Context, userID uuid.UUID) (bool, error)
+	IsPlaceholder(ctx context.Context, userID uuid.UUID) (bool, error)
+	Currency(ctx context.Context, groupID uuid.UUID) (string, error)
+	// Balances returns every member's net balance; positive means owed
+	Balances(ctx context.Context, groupID uuid.UUID) (map[uuid.UUID]decimal.Decimal, error)
+	// Debts returns what each member owes each other member, in both
+	// directions, before netting
+	Debts(ctx context.Context, groupID uuid.UUID) ([]PairwiseBalance, error)
+}
+
+// pgRepository is the Postgres Repository
+type pgRepository struct {
+	db *db.DB
+}
+
+func NewRepository(db *db.DB) Repository {
+	return &pgRepository{db: db}
+}
+
+func (r *pgRepository) Create(ctx context.Context, name, currency string, createdBy uuid.UUID) (Group, error) {
+	var g Group
+	err := r.db.WithTx(ctx, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx,
+			"INSERT INTO groups (name, currency, created_by) VALUES ($1, $2, $3) RETURNING id, name, currency, created_by, created_at",
+			name, currency, createdBy).Scan(&g.ID, &g.Name, &g.Currency, &g.CreatedBy, &g.CreatedAt)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.Exec(ctx,
+			"INSERT INTO group_members (group_id, user_id) VALUES ($1, $2)",
+			g.ID, createdBy)
+		return err
+	})
+	if err != nil {
+		return Group{}, err
+	}
+	return g, nil
+}
+
+func (r *pgRepository) AddMember(ctx context.Context, groupID, userID, addedBy uuid.UUID) error {
+	return r.db.WithTx(ctx, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx,
+			"INSERT INTO group_members (group_id, user_id) VALUES ($1, $2)", groupID, userID)
+		if err != nil {
+			return err
+		}
+		return event.Record(ctx, tx, event.Event{
+			Type: event.MemberAdded, UserID: addedBy, GroupID: &groupID,
+			Data: event.Member{GroupID: groupID, UserID: userID},
+		})
+	})
+}
+
+func (r *pgRepository) IsMember(ctx context.Context, groupID, userID uuid.UUID) (bool, error) {
+	return helpers.IsGroupMember(ctx, r.db, groupID, userID)
+}
+
+func (r *pgRepository) UserExists(ctx context.Context, userID uuid.UUID) (bool, error) {
+	return helpers.UserExists(ctx, r.db, userID)
+}
+
+func (r *pgRepository) IsPlaceholder(ctx context.Context, userID uuid.UUID) (bool, error) {
+	return helpers.IsPlaceholder(ctx, r.db, userID)
+}
+
+func (r *pgRepository) Currency(ctx context.Context, groupID uuid.UUID) (string, error) {
+	return helpers.GetGroupCurrency(ctx, r.db, groupID)
+}
+
+func (r *pgRepository) Balances(ctx context.Context, groupID uuid.UUID) (map[uuid.UUID]decimal.Decimal, error) {
+	return computeBalances(ctx, r.db, groupID)
+}
+
+func (r *pgRepository) Debts(ctx context.Context, groupID uuid.UUID) ([]PairwiseBalance, error) {
+	return pairwiseDebts(ctx, r.db, groupID)
+}
@@ -0,0 +1,127 @@
+package group
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/google/uuid"
+)
+
+// Errors Service returns for requests that break a rule rather than fail
+var (
+	ErrNotMember     = errors.New("not a member of the group")
+	ErrUserNotFound  = errors.New("user does not exist")
+	ErrPlaceholder   = errors.New("cannot add a placeholder member from another group")
+	ErrAlreadyMember = errors.New("user already in group")
+)
+
+// Service holds the group rules, independent of HTTP and of the storage
+// behind Repository
+type Service struct {
+	repo Repository
+}
+
+func NewService(repo Repository) *Service {
+	return &Service{repo: repo}
+}
+
+// Create creates a group with userID as its first member, in DefaultCurrency
+// unless another is given
+func (s *Service) Create(ctx context.Context, userID uuid.UUID, req CreateGroupRequest) (Group, error) {
+	currency := req.Currency
+	if currency == "" {
+		currency = DefaultCurrency
+	}
+
+	g, err := s.repo.Create(ctx, req.Name, currency, userID)
+	if err != nil {
+		return Group{}, fmt.Errorf("create group: %w", err)
+	}
+	return g, nil
+}
+
+// AddMember adds memberID to the group on behalf of userID, who must be a
+// member. Placeholders can't be added; they belong to the group they were
+// created in.
+func (s *Service) AddMember(ctx context.Context, userID, groupID, memberID uuid.UUID) error {
+	isMember, err := s.repo.IsMember(ctx, groupID, userID)
+	if err != nil || !isMember {
+		return ErrNotMember
+	}
+
+	exists, err := s.repo.UserExists(ctx, memberID)
+	if err != nil || !exists {
+		return ErrUserNotFound
+	}
+
+	isPlaceholder, err := s.repo.IsPlaceholder(ctx, memberID)
+	if err != nil || isPlaceholder {
+		return ErrPlaceholder
+	}
+
+	exists, err = s.repo.IsMember(ctx, groupID, memberID)
+	if err != nil {
+		return fmt.Errorf("check membership: %w", err)
+	}
+	if exists {
+		return ErrAlreadyMember
+	}
+
+	if err := s.repo.AddMember(ctx, groupID, memberID, userID); err != nil {
+		return fmt.Errorf("add member: %w", err)
+	}
+	return nil
+}
+
+// Balances returns every member's net balance in the group's currency, by
+// user ID, for userID who must be a member
+func (s *Service) Balances(ctx context.Context, userID, groupID uuid.UUID) ([]Balance, error) {
+	isMember, err := s.repo.IsMember(ctx, groupID, userID)
+	if err != nil || !isMember {
+		return nil, ErrNotMember
+	}
+
+	currency, err := s.repo.Currency(ctx, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("get group currency: %w", err)
+	}
+
+	members, err := s.repo.Balances(ctx, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("calculate balances: %w", err)
+	}
+
+	balances := make([]Balance, 0, len(members))
+	for uid, amt := range members {
+		balances = append(balances, Balance{UserID: uid, Amount: amt, Currency: currency})
+	}
+	sort.Slice(balances, func(i, j int) bool { return balances[i].UserID.String() < balances[j].UserID.String() })
+	return balances, nil
+}
+
+// PairwiseBalances returns who owes whom in the group's currency, one net
+// amount per pair of members, for userID who must be a member
+func (s *Service) PairwiseBalances(ctx context.Context, userID, groupID uuid.UUID) ([]PairwiseBalance, error) {
+	isMember, err := s.repo.IsMember(ctx, groupID, userID)
+	if err != nil || !isMember {
+		return nil, ErrNotMember
+	}
+
+	currency, err := s.repo.Currency(ctx, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("get group currency: %w", err)
+	}
+
+	debts, err := s.repo.Debts(ctx, groupID)
+	if err != nil {
+		return nil, fmt.Errorf("get pairwise balances: %w", err)
+	}
+
+	balances := netPairwise(debts)
+	for i := range balances {
+		balances[i].Currency = currency
+	}
+	return balances, nil
+}
@@ -0,0 +1,173 @@
+package group
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// memoryRepository is an in-memory Repository
+type memoryRepository struct {
+	groups       map[uuid.UUID]Group
+	members      map[uuid.UUID][]uuid.UUID
+	users        map[uuid.UUID]bool // user ID to whether it's a placeholder
+	balances     map[uuid.UUID]map[uuid.UUID]decimal.Decimal
+	debts        map[uuid.UUID][]PairwiseBalance
+	addMemberErr error
+}
+
+func newMemoryRepository() *memoryRepository {
+	return &memoryRepository{
+		groups:   map[uuid.UUID]Group{},
+		members:  map[uuid.UUID][]uuid.UUID{},
+		users:    map[uuid.UUID]bool{},
+		balances: map[uuid.UUID]map[uuid.UUID]decimal.Decimal{},
+		debts:    map[uuid.UUID][]PairwiseBalance{},
+	}
+}
+
+func (r *memoryRepository) Create(_ context.Context, name, currency string, createdBy uuid.UUID) (Group, error) {
+	g := Group{ID: uuid.New(), Name: name, Currency: currency, CreatedBy: createdBy, CreatedAt: time.Now()}
+	r.groups[g.ID] = g
+	r.members[g.ID] = []uuid.UUID{createdBy}
+	return g, nil
+}
+
+func (r *memoryRepository) AddMember(_ context.Context, groupID, userID, _ uuid.UUID) error {
+	if r.addMemberErr != nil {
+		return r.addMemberErr
+	}
+	r.members[groupID] = append(r.members[groupID], userID)
+	return nil
+}
+
+func (r *memoryRepository) IsMember(_ context.Context, groupID, userID uuid.UUID) (bool, error) {
+	for _, id := range r.members[groupID] {
+		if id == userID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *memoryRepository) UserExists(_ context.Context, userID uuid.UUID) (bool, error) {
+	_, ok := r.users[userID]
+	return ok, nil
+}
+
+func (r *memoryRepository) IsPlaceholder(_ context.Context, userID uuid.UUID) (bool, error) {
+	return r.users[userID], nil
+}
+
+func (r *memoryRepository) Currency(_ context.Context, groupID uuid.UUID) (string, error) {
+	return r.groups[groupID].Currency, nil
+}
+
+func (r *memoryRepository) Balances(_ context.Context, groupID uuid.UUID) (map[uuid.UUID]decimal.Decimal, error) {
+	return r.balances[groupID], nil
+}
+
+func (r *memoryRepository) Debts(_ context.Context, groupID uuid.UUID) ([]PairwiseBalance, error) {
+	return r.debts[groupID], nil
+}
+
+func TestServiceCreate(t *testing.T) {
+	repo := newMemoryRepository()
+	service := NewService(repo)
+	userID := uuid.New()
+
+	g, err := service.Create(context.Background(), userID, CreateGroupRequest{Name: "Trip"})
+	require.NoError(t, err)
+	assert.Equal(t, DefaultCurrency, g.Currency)
+	assert.Equal(t, []uuid.UUID{userID}, repo.members[g.ID])
+
+	g, err = service.Create(context.Background(), userID, CreateGroupRequest{Name: "Flat", Currency: "EUR"})
+	require.NoError(t, err)
+	assert.Equal(t, "EUR", g.Currency)
+}
+
+func TestServiceAddMember(t *testing.T) {
+	ctx := context.Background()
+	repo := newMemoryRepository()
+	service := NewService(repo)
+
+	owner, friend, placeholder, outsider := uuid.New(), uuid.New(), uuid.New(), uuid.New()
+	repo.users[owner], repo.users[friend], repo.users[placeholder], repo.users[outsider] = false, false, true, false
+	g, err := service.Create(ctx, owner, CreateGroupRequest{Name: "Trip"})
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, service.AddMember(ctx, outsider, g.ID, friend), ErrNotMember)
+	assert.ErrorIs(t, service.AddMember(ctx, owner, g.ID, uuid.New()), ErrUserNotFound)
+	assert.ErrorIs(t, service.AddMember(ctx, owner, g.ID, placeholder), ErrPlaceholder)
+
+	require.NoError(t, service.AddMember(ctx, owner, g.ID, friend))
+	assert.Equal(t, []uuid.UUID{owner, friend}, repo.members[g.ID])
+	assert.ErrorIs(t, service.AddMember(ctx, friend, g.ID, friend), ErrAlreadyMember)
+
+	// Storage failures aren't mistaken for a broken rule
+	repo.addMemberErr = errors.New("connection lost")
+	err = service.AddMember(ctx, owner, g.ID, outsider)
+	require.Error(t, err)
+	assert.NotErrorIs(t, err, ErrNotMember)
+}
+
+func TestServiceBalances(t *testing.T) {
+	ctx := context.Background()
+	repo := newMemoryRepository()
+	service := NewService(repo)
+
+	owner, friend := uuid.New(), uuid.New()
+	repo.users[owner], repo.users[friend] = false, false
+	g, err := service.Create(ctx, owner, CreateGroupRequest{Name: "Trip", Currency: "EUR"})
+	require.NoError(t, err)
+	require.NoError(t, service.AddMember(ctx, owner, g.ID, friend))
+	repo.balances[g.ID] = map[uuid.UUID]decimal.Decimal{
+		owner:  decimal.NewFromInt(25),
+		friend: decimal.NewFromInt(-25),
+	}
+
+	_, err = service.Balances(ctx, uuid.New(), g.ID)
+	assert.ErrorIs(t, err, ErrNotMember)
+
+	balances, err := service.Balances(ctx, friend, g.ID)
+	require.NoError(t, err)
+	require.Len(t, balances, 2)
+	for _, b := range balances {
+		assert.Equal(t, "EUR", b.Currency)
+		assert.True(t, b.Amount.Equal(repo.balances[g.ID][b.UserID]))
+	}
+	assert.Less(t, balances[0].UserID.String(), balances[1].UserID.String())
+}
+
+func TestServicePairwiseBalances(t *testing.T) {
+	ctx := context.Background()
+	repo := newMemoryRepository()
+	service := NewService(repo)
+
+	owner, friend := uuid.New(), uuid.New()
+	repo.users[owner], repo.users[friend] = false, false
+	g, err := service.Create(ctx, owner, CreateGroupRequest{Name: "Trip", Currency: "EUR"})
+	require.NoError(t, err)
+	require.NoError(t, service.AddMember(ctx, owner, g.ID, friend))
+	repo.debts[g.ID] = []PairwiseBalance{
+		{FromUser: friend, ToUser: owner, Amount: decimal.NewFromInt(30)},
+		{FromUser: owner, ToUser: friend, Amount: decimal.NewFromInt(10)},
+	}
+
+	_, err = service.PairwiseBalances(ctx, uuid.New(), g.ID)
+	assert.ErrorIs(t, err, ErrNotMember)
+
+	balances, err := service.PairwiseBalances(ctx, owner, g.ID)
+	require.NoError(t, err)
+	require.Len(t, balances, 1)
+	assert.Equal(t, friend, balances[0].FromUser)
+	assert.Equal(t, owner, balances[0].ToUser)
+	assert.Equal(t, "20", balances[0].Amount.String())
+	assert.Equal(t, "EUR", balances[0].Currency)
+}
@@ -0,0 +1,224 @@
+package group
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+	"github.com/yanonymousV2/finance-manager-backend/internal/helpers"
+	"github.com/yanonymousV2/finance-manager-backend/internal/middleware"
+	"github.com/yanonymousV2/finance-manager-backend/internal/validation"
+)
+
+// Split modes used when an expense is created without explicit splits
+const (
+	SplitModeEqual  = "equal"
+	SplitModeShares = "shares"
+)
+
+// Rounding rules decide who absorbs leftover cents after splitting
+const (
+	RoundingPayer            = "payer"
+	RoundingLargestRemainder = "largest_remainder"
+)
+
+type Settings struct {
+	GroupID                       uuid.UUID        `json:"group_id" db:"group_id"`
+	DefaultSplitMode              string           `json:"default_split_mode" db:"default_split_mode"`
+	RoundingRule                  string           `json:"rounding_rule" db:"rounding_rule"`
+	AllowNonPayerEdits            bool             `json:"allow_non_payer_edits" db:"allow_non_payer_edits"`
+	ApprovalThreshold             *decimal.Decimal `json:"approval_threshold" db:"approval_threshold"`
+	RequireSettlementConfirmation bool             `json:"require_settlement_confirmation" db:"require_settlement_confirmation"`
+	MemberShares                  []MemberShare    `json:"member_shares"`
+	UpdatedAt                     *time.Time       `json:"updated_at,omitempty" db:"updated_at"`
+}
+
+type MemberShare struct {
+	UserID uuid.UUID `json:"user_id" validate:"required"`
+	Shares int       `json:"shares" validate:"required,min=1"`
+}
+
+type UpdateSettingsRequest struct {
+	DefaultSplitMode              *string       `json:"default_split_mode,omitempty" validate:"omitempty,oneof=equal shares"`
+	RoundingRule                  *string       `json:"rounding_rule,omitempty" validate:"omitempty,oneof=payer largest_remainder"`
+	AllowNonPayerEdits            *bool         `json:"allow_non_payer_edits,omitempty"`
+	ApprovalThreshold             *string       `json:"approval_threshold,omitempty" validate:"omitempty,numeric"`
+	RequireSettlementConfirmation *bool         `json:"require_settlement_confirmation,omitempty"`
+	MemberShares                  []MemberShare `json:"member_shares,omitempty" validate:"omitempty,dive"`
+}
+
+// LoadSettings returns the settings of a group, falling back to defaults
+// when none have been saved yet
+func LoadSettings(ctx context.Context, db *db.DB, groupID uuid.UUID) (Settings, error) {
+	s := Settings{
+		GroupID:            groupID,
+		DefaultSplitMode:   SplitModeEqual,
+		RoundingRule:       RoundingPayer,
+		AllowNonPayerEdits: true,
+	}
+
+	err := db.Pool.QueryRow(ctx,
+		`SELECT default_split_mode, rounding_rule, allow_non_payer_edits, approval_threshold,
+		        require_settlement_confirmation, updated_at
+		 FROM group_settings WHERE group_id = $1`,
+		groupID).Scan(&s.DefaultSplitMode, &s.RoundingRule, &s.AllowNonPayerEdits, &s.ApprovalThreshold,
+		&s.RequireSettlementConfirmation, &s.UpdatedAt)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return s, err
+	}
+
+	s.MemberShares, err = LoadMemberShares(ctx, db, groupID)
+	return s, err
+}
+
+// LoadMemberShares returns the default share weight of every group member
+func LoadMemberShares(ctx context.Context, db *db.DB, groupID uuid.UUID) ([]MemberShare, error) {
+	rows, err := db.Pool.Query(ctx,
+		"SELECT user_id, default_shares FROM group_members WHERE group_id = $1 ORDER BY joined_at, user_id",
+		groupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	shares := []MemberShare{}
+	for rows.Next() {
+		var ms MemberShare
+		if err := rows.Scan(&ms.UserID, &ms.Shares); err != nil {
+			return nil, err
+		}
+		shares = append(shares, ms)
+	}
+	return shares, rows.Err()
+}
+
+// GetGroupSettings returns the settings of a group
+func GetGroupSettings(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	groupID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid group id"})
+		return
+	}
+
+	isMember, err := helpers.IsGroupMember(c.Request.Context(), db, groupID, userID)
+	if err != nil || !isMember {
+		c.JSON(403, gin.H{"error": "not a member of the group"})
+		return
+	}
+
+	settings, err := LoadSettings(c.Request.Context(), db, groupID)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to get group settings"})
+		return
+	}
+
+	c.JSON(200, settings)
+}
+
+// UpdateGroupSettings updates the settings of a group
+func UpdateGroupSettings(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	groupID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid group id"})
+		return
+	}
+
+	isMember, err := helpers.IsGroupMember(c.Request.Context(), db, groupID, userID)
+	if err != nil || !isMember {
+		c.JSON(403, gin.H{"error": "not a member of the group"})
+		return
+	}
+
+	var req UpdateSettingsRequest
+	if !validation.Bind(c, &req) {
+		return
+	}
+
+	current, err := LoadSettings(c.Request.Context(), db, groupID)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to get group settings"})
+		return
+	}
+	if req.DefaultSplitMode != nil {
+		current.DefaultSplitMode = *req.DefaultSplitMode
+	}
+	if req.RoundingRule != nil {
+		current.RoundingRule = *req.RoundingRule
+	}
+	if req.AllowNonPayerEdits != nil {
+		current.AllowNonPayerEdits = *req.AllowNonPayerEdits
+	}
+	if req.RequireSettlementConfirmation != nil {
+		current.RequireSettlementConfirmation = *req.RequireSettlementConfirmation
+	}
+	// An empty threshold turns approval off
+	if req.ApprovalThreshold != nil {
+		current.ApprovalThreshold = nil
+		if *req.ApprovalThreshold != "" {
+			threshold, err := decimal.NewFromString(*req.ApprovalThreshold)
+			if err != nil || threshold.IsNegative() {
+				c.JSON(400, gin.H{"error": "invalid approval threshold"})
+				return
+			}
+			current.ApprovalThreshold = &threshold
+		}
+	}
+
+	err = db.WithTx(c.Request.Context(), func(tx pgx.Tx) error {
+		_, err := tx.Exec(c.Request.Context(),
+			`INSERT INTO group_settings (group_id, default_split_mode, rounding_rule, allow_non_payer_edits, approval_threshold,
+			                             require_settlement_confirmation, updated_at)
+			 VALUES ($1, $2, $3, $4, $5, $6, NOW())
+			 ON CONFLICT (group_id)
+			 DO UPDATE SET default_split_mode = $2, rounding_rule = $3, allow_non_payer_edits = $4,
+			               approval_threshold = $5, require_settlement_confirmation = $6, updated_at = NOW()`,
+			groupID, current.DefaultSplitMode, current.RoundingRule, current.AllowNonPayerEdits, current.ApprovalThreshold,
+			current.RequireSettlementConfirmation)
+		if err != nil {
+			return helpers.NewRequestError(500, "failed to update group settings")
+		}
+
+		for _, ms := range req.MemberShares {
+			tag, err := tx.Exec(c.Request.Context(),
+				"UPDATE group_members SET default_shares = $1 WHERE group_id = $2 AND user_id = $3",
+				ms.Shares, groupID, ms.UserID)
+			if err != nil {
+				return helpers.NewRequestError(500, "failed to update member shares")
+			}
+			if tag.RowsAffected() == 0 {
+				return helpers.NewRequestError(400, "member shares must reference group members")
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		helpers.RespondError(c, err, "failed to update group settings")
+		return
+	}
+
+	settings, err := LoadSettings(c.Request.Context(), db, groupID)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to get group settings"})
+		return
+	}
+
+	c.JSON(200, settings)
+}
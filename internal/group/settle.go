@@ -0,0 +1,225 @@
+package group
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+	"github.com/yanonymousV2/finance-manager-backend/internal/event"
+	"github.com/yanonymousV2/finance-manager-backend/internal/helpers"
+	"github.com/yanonymousV2/finance-manager-backend/internal/middleware"
+	"github.com/yanonymousV2/finance-manager-backend/internal/settlement"
+	"github.com/yanonymousV2/finance-manager-backend/internal/validation"
+)
+
+type SettleAllTransfer struct {
+	FromUser uuid.UUID       `json:"from_user" validate:"required"`
+	ToUser   uuid.UUID       `json:"to_user" validate:"required"`
+	Amount   decimal.Decimal `json:"amount" validate:"required"`
+}
+
+type SettleAllRequest struct {
+	Transfers []SettleAllTransfer `json:"transfers,omitempty" validate:"omitempty,dive"`
+}
+
+// querier is satisfied by both the pool and a transaction
+type querier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+// settleBalancesSQL returns every member's balance as it will be once the
+// group's pending settlements are confirmed, so settling up doesn't pay
+// them a second time
+const settleBalancesSQL = `SELECT gm.user_id, COALESCE(gb.balance, 0) + COALESCE(p.amount, 0)
+	 FROM group_members gm
+	 LEFT JOIN group_balances gb ON gb.group_id = gm.group_id AND gb.user_id = gm.user_id
+	 LEFT JOIN (
+	     SELECT user_id, SUM(amount) AS amount FROM (
+	         SELECT from_user AS user_id, amount FROM settlements
+	         WHERE group_id = $1 AND status = 'pending' AND deleted_at IS NULL
+	         UNION ALL
+	         SELECT to_user, -amount FROM settlements
+	         WHERE group_id = $1 AND status = 'pending' AND deleted_at IS NULL
+	     ) pending GROUP BY user_id
+	 ) p ON p.user_id = gm.user_id
+	 WHERE gm.group_id = $1`
+
+// settleBalances returns the balances settling up starts from
+func settleBalances(ctx context.Context, q querier, groupID uuid.UUID) (map[uuid.UUID]decimal.Decimal, error) {
+	rows, err := q.Query(ctx, settleBalancesSQL, groupID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	balances := make(map[uuid.UUID]decimal.Decimal)
+	for rows.Next() {
+		var uid uuid.UUID
+		var balance decimal.Decimal
+		if err := rows.Scan(&uid, &balance); err != nil {
+			return nil, err
+		}
+		balances[uid] = balance
+	}
+	return balances, rows.Err()
+}
+
+// lockSettleBalances locks the group and its balances in tx and returns
+// settleBalances. Concurrent settle-alls wait for the group, and expenses
+// and settlements being written wait for, or are waited on by, the balance
+// rows their triggers update.
+func lockSettleBalances(ctx context.Context, tx pgx.Tx, groupID uuid.UUID) (map[uuid.UUID]decimal.Decimal, error) {
+	if _, err := tx.Exec(ctx, "SELECT id FROM groups WHERE id = $1 FOR UPDATE", groupID); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(ctx,
+		"SELECT user_id FROM group_balances WHERE group_id = $1 ORDER BY user_id FOR UPDATE", groupID); err != nil {
+		return nil, err
+	}
+	return settleBalances(ctx, tx, groupID)
+}
+
+// settlesExactly reports whether recording transfers would bring every
+// balance to zero. A settlement raises the payer's balance and lowers the
+// recipient's.
+func settlesExactly(balances map[uuid.UUID]decimal.Decimal, transfers []Transfer) bool {
+	remaining := make(map[uuid.UUID]decimal.Decimal, len(balances))
+	for uid, bal := range balances {
+		remaining[uid] = bal
+	}
+	for _, t := range transfers {
+		remaining[t.FromUser] = remaining[t.FromUser].Add(t.Amount)
+		remaining[t.ToUser] = remaining[t.ToUser].Sub(t.Amount)
+	}
+	for _, bal := range remaining {
+		if !bal.IsZero() {
+			return false
+		}
+	}
+	return true
+}
+
+// SettleAll records a set of settlements that zeroes every balance in the
+// group in one transaction, counting pending settlements as paid. Without a
+// transfer plan in the body the simplified plan from GetSettleSuggestions is
+// used.
+func SettleAll(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	groupID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid group id"})
+		return
+	}
+
+	isMember, err := helpers.IsGroupMember(c.Request.Context(), db, groupID, userID)
+	if err != nil || !isMember {
+		c.JSON(403, gin.H{"error": "not a member of the group"})
+		return
+	}
+
+	// The body is optional
+	var req SettleAllRequest
+	if c.Request.ContentLength > 0 {
+		if !validation.Bind(c, &req) {
+			return
+		}
+	}
+
+	currency, err := helpers.GetGroupCurrency(c.Request.Context(), db, groupID)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to get group currency"})
+		return
+	}
+
+	for _, t := range req.Transfers {
+		if t.FromUser == t.ToUser {
+			c.JSON(400, gin.H{"error": "cannot settle to self"})
+			return
+		}
+		if !t.Amount.IsPositive() {
+			c.JSON(400, gin.H{"error": "transfer amount must be greater than 0"})
+			return
+		}
+	}
+
+	requireConfirmation, err := helpers.RequiresSettlementConfirmation(c.Request.Context(), db, groupID)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to get group settings"})
+		return
+	}
+
+	var settlements []settlement.Settlement
+	err = db.WithTx(c.Request.Context(), func(tx pgx.Tx) error {
+		// The plan is checked against balances nothing else can change
+		// until the settlements are in
+		balances, err := lockSettleBalances(c.Request.Context(), tx, groupID)
+		if err != nil {
+			return helpers.NewRequestError(500, "failed to calculate balances")
+		}
+
+		var transfers []Transfer
+		if len(req.Transfers) == 0 {
+			transfers = SimplifyDebts(balances)
+		} else {
+			for _, t := range req.Transfers {
+				_, fromMember := balances[t.FromUser]
+				_, toMember := balances[t.ToUser]
+				if !fromMember || !toMember {
+					return helpers.NewRequestError(400, "transfers must be between group members")
+				}
+				transfers = append(transfers, Transfer{FromUser: t.FromUser, ToUser: t.ToUser, Amount: t.Amount})
+			}
+			// Reject stale plans, e.g. when an expense was added after the
+			// suggestions were fetched
+			if !settlesExactly(balances, transfers) {
+				return helpers.NewRequestError(409, "transfers do not settle the group's current balances")
+			}
+		}
+
+		settlements = make([]settlement.Settlement, len(transfers))
+		if len(transfers) == 0 {
+			return nil
+		}
+
+		batch := &pgx.Batch{}
+		for i, t := range transfers {
+			s := &settlements[i]
+			batch.Queue(settlement.InsertSQL,
+				groupID, t.FromUser, t.ToUser, t.Amount, currency, nil, nil, nil,
+				settlement.InitialStatus(requireConfirmation, userID, t.ToUser), nil, nil, nil,
+			).QueryRow(func(row pgx.Row) (err error) {
+				*s, err = settlement.Scan(row)
+				return err
+			})
+		}
+		if err := tx.SendBatch(c.Request.Context(), batch).Close(); err != nil {
+			return err
+		}
+
+		events := &pgx.Batch{}
+		for _, s := range settlements {
+			event.Queue(events, event.Event{Type: event.SettlementCreated, UserID: userID, GroupID: &groupID, Data: s})
+		}
+		return tx.SendBatch(c.Request.Context(), events).Close()
+	})
+	if err != nil {
+		helpers.RespondError(c, err, "failed to create settlements")
+		return
+	}
+
+	if len(settlements) == 0 {
+		c.JSON(200, gin.H{"settlements": settlements})
+		return
+	}
+
+	c.JSON(201, gin.H{"settlements": settlements})
+}
@@ -0,0 +1,61 @@
+package group
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/settlement"
+)
+
+func TestSettleAll_CountsPendingSettlements(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	testDB := setupBalanceTestDB(t)
+	defer testDB.Close()
+	ctx := context.Background()
+
+	userA := createBalanceTestUser(t, testDB, "a@example.com")
+	userB := createBalanceTestUser(t, testDB, "b@example.com")
+	groupID := createBalanceTestGroup(t, testDB, userA)
+	addGroupMember(t, testDB, groupID, userB)
+
+	// B owes A 50 and has already sent 20 that A hasn't confirmed yet
+	createExpense(t, testDB, groupID, userA, decimal.NewFromInt(100), map[uuid.UUID]decimal.Decimal{
+		userA: decimal.NewFromInt(50),
+		userB: decimal.NewFromInt(50),
+	})
+	_, err := testDB.Pool.Exec(ctx,
+		"INSERT INTO settlements (group_id, from_user, to_user, amount, status) VALUES ($1, $2, $3, 20, 'pending')",
+		groupID, userB, userA)
+	require.NoError(t, err)
+
+	balances, err := settleBalances(ctx, testDB.Pool, groupID)
+	require.NoError(t, err)
+	assert.True(t, decimal.NewFromInt(30).Equal(balances[userA]), "A is owed %s", balances[userA])
+	assert.True(t, decimal.NewFromInt(-30).Equal(balances[userB]), "B owes %s", balances[userB])
+
+	router := gin.New()
+	router.POST("/groups/:id/settle-all", func(c *gin.Context) {
+		c.Set("user_id", userA)
+		SettleAll(c, testDB)
+	})
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("POST", "/groups/"+groupID.String()+"/settle-all", nil))
+	require.Equal(t, 201, w.Code, w.Body.String())
+
+	var resp struct {
+		Settlements []settlement.Settlement `json:"settlements"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	require.Len(t, resp.Settlements, 1)
+	assert.Equal(t, userB, resp.Settlements[0].FromUser)
+	assert.Equal(t, userA, resp.Settlements[0].ToUser)
+	assert.True(t, decimal.NewFromInt(30).Equal(resp.Settlements[0].Amount), "settled %s", resp.Settlements[0].Amount)
+}
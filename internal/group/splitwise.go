@@ -0,0 +1,702 @@
+package group
+
+import (
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+	"github.com/yanonymousV2/finance-manager-backend/internal/helpers"
+	"github.com/yanonymousV2/finance-manager-backend/internal/middleware"
+	"github.com/yanonymousV2/finance-manager-backend/internal/validation"
+)
+
+const (
+	// maxSplitwiseSize caps an uploaded Splitwise export
+	maxSplitwiseSize = 10 << 20
+	// maxSplitwiseEntries caps the expenses and payments of one export
+	maxSplitwiseEntries = 10000
+	// splitwiseBatchSize is how many entries are sent to the database at once
+	splitwiseBatchSize = 500
+	// maxSplitwiseIssues caps the entries listed as skipped or inferred
+	maxSplitwiseIssues = 100
+)
+
+// SplitwiseImportOptions describe how an export is imported. Name defaults
+// to the group's name in a JSON export and is required for CSV; Currency
+// defaults to the one most entries are in. Me names the Splitwise member
+// who is the importing user, who is otherwise matched by email in a JSON
+// export.
+type SplitwiseImportOptions struct {
+	Name     string `json:"name,omitempty" validate:"omitempty,max=255"`
+	Currency string `json:"currency,omitempty" validate:"omitempty,iso4217"`
+	Me       string `json:"me,omitempty" validate:"omitempty,max=200"`
+	DryRun   bool   `json:"dry_run,omitempty"`
+}
+
+// SplitwiseMember is how a Splitwise member was mapped: to the importing
+// user ("you") or to a new placeholder. UserID is left out on a dry run
+// for placeholders, which aren't created.
+type SplitwiseMember struct {
+	Name    string     `json:"name"`
+	Mapping string     `json:"mapping"`
+	UserID  *uuid.UUID `json:"user_id,omitempty"`
+}
+
+// SplitwiseIssue is an entry of the export, by its CSV line or its
+// position in the JSON expenses, that was skipped or needs checking
+type SplitwiseIssue struct {
+	Row         int    `json:"row"`
+	Description string `json:"description,omitempty"`
+	Reason      string `json:"reason"`
+}
+
+type SplitwiseImportReport struct {
+	DryRun      bool              `json:"dry_run"`
+	Group       *Group            `json:"group,omitempty"`
+	Name        string            `json:"name"`
+	Currency    string            `json:"currency"`
+	Members     []SplitwiseMember `json:"members"`
+	Expenses    int               `json:"expenses"`
+	Settlements int               `json:"settlements"`
+	Skipped     []SplitwiseIssue  `json:"skipped"`
+	// Inferred lists CSV entries with several members ahead: the export only
+	// has each member's net balance, so who paid is inferred, keeping every
+	// balance as it was in Splitwise
+	Inferred []SplitwiseIssue `json:"inferred"`
+}
+
+func (r *SplitwiseImportReport) skip(e swEntry, reason string) {
+	if len(r.Skipped) < maxSplitwiseIssues {
+		r.Skipped = append(r.Skipped, SplitwiseIssue{Row: e.Row, Description: e.Description, Reason: reason})
+	}
+}
+
+// swMember is a person in a Splitwise export
+type swMember struct {
+	Name  string
+	Email string
+}
+
+// swEntry is an expense or payment of a Splitwise export. Paid and Owed
+// hold each member's share by their index in the export's members.
+type swEntry struct {
+	Row         int
+	Date        time.Time
+	Description string
+	Category    string
+	Cost        decimal.Decimal
+	Currency    string
+	Payment     bool
+	Paid        []decimal.Decimal
+	Owed        []decimal.Decimal
+	Inferred    bool
+}
+
+// swExport is a Splitwise group as read from an export. Skipped lists
+// entries that couldn't be read.
+type swExport struct {
+	Name    string
+	Members []swMember
+	Entries []swEntry
+	Skipped []SplitwiseIssue
+}
+
+func (x *swExport) skip(row int, description, reason string) {
+	x.Skipped = append(x.Skipped, SplitwiseIssue{Row: row, Description: description, Reason: reason})
+}
+
+// parseSplitwiseAmount parses an amount and checks it has at most two
+// decimal places
+func parseSplitwiseAmount(s string) (decimal.Decimal, error) {
+	amount, err := decimal.NewFromString(strings.TrimSpace(s))
+	if err != nil || !amount.Equal(amount.Round(2)) {
+		return decimal.Decimal{}, fmt.Errorf("invalid amount %q", s)
+	}
+	return amount, nil
+}
+
+// sharesFromNets turns each member's net balance for an entry, what they
+// paid less what they owe, into what each paid and owes. The members ahead
+// paid their net; the rest of the cost is taken to have been paid, and
+// owed, by whoever is furthest ahead, which keeps every balance as it was.
+// inferred reports whether several members were ahead, so who paid is a
+// guess.
+func sharesFromNets(cost decimal.Decimal, nets []decimal.Decimal) (paid, owed []decimal.Decimal, inferred bool, err error) {
+	sum, ahead := decimal.Zero, decimal.Zero
+	top, aheadCount := -1, 0
+	for i, net := range nets {
+		sum = sum.Add(net)
+		if net.IsPositive() {
+			ahead = ahead.Add(net)
+			aheadCount++
+			if top < 0 || net.GreaterThan(nets[top]) {
+				top = i
+			}
+		}
+	}
+	if !sum.IsZero() {
+		return nil, nil, false, errors.New("member balances don't add up to zero")
+	}
+	if top < 0 {
+		return nil, nil, false, errors.New("no member paid")
+	}
+	if ahead.GreaterThan(cost) {
+		return nil, nil, false, errors.New("member balances exceed the cost")
+	}
+
+	paid = make([]decimal.Decimal, len(nets))
+	owed = make([]decimal.Decimal, len(nets))
+	for i, net := range nets {
+		if net.IsPositive() {
+			paid[i] = net
+		} else {
+			owed[i] = net.Neg()
+		}
+	}
+	rest := cost.Sub(ahead)
+	paid[top] = paid[top].Add(rest)
+	owed[top] = owed[top].Add(rest)
+	return paid, owed, aheadCount > 1, nil
+}
+
+// parseSplitwiseCSV reads the spreadsheet Splitwise exports for a group:
+// Date, Description, Category, Cost and Currency columns followed by a
+// column per member with their net balance for the entry, and a closing
+// "Total balance" row. Payments have the Payment category.
+func parseSplitwiseCSV(r io.Reader) (swExport, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+
+	header, err := reader.Read()
+	if err != nil {
+		return swExport{}, errors.New("failed to read CSV header")
+	}
+	want := []string{"date", "description", "category", "cost", "currency"}
+	if len(header) < len(want)+2 {
+		return swExport{}, errors.New("not a Splitwise export: expected Date, Description, Category, Cost and Currency columns followed by members")
+	}
+	for i, name := range want {
+		if strings.ToLower(strings.TrimSpace(strings.TrimPrefix(header[i], "\uFEFF"))) != name {
+			return swExport{}, errors.New("not a Splitwise export: expected Date, Description, Category, Cost and Currency columns followed by members")
+		}
+	}
+
+	var x swExport
+	for _, name := range header[len(want):] {
+		x.Members = append(x.Members, swMember{Name: strings.TrimSpace(name)})
+	}
+
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return swExport{}, fmt.Errorf("failed to read CSV: %w", err)
+		}
+		line, _ := reader.FieldPos(0)
+		field := func(i int) string {
+			if i >= len(record) {
+				return ""
+			}
+			return strings.TrimSpace(record[i])
+		}
+		description := field(1)
+		if strings.EqualFold(description, "Total balance") {
+			break
+		}
+		if strings.Join(record, "") == "" {
+			continue
+		}
+		if len(x.Entries)+len(x.Skipped) >= maxSplitwiseEntries {
+			return swExport{}, fmt.Errorf("export has more than %d entries", maxSplitwiseEntries)
+		}
+
+		e := swEntry{Row: line, Description: description, Category: field(2), Currency: strings.ToUpper(field(4))}
+		e.Payment = strings.EqualFold(e.Category, "Payment")
+		if e.Date, err = time.Parse(time.DateOnly, field(0)); err != nil {
+			x.skip(line, description, fmt.Sprintf("invalid date %q", field(0)))
+			continue
+		}
+		if e.Cost, err = parseSplitwiseAmount(field(3)); err != nil || !e.Cost.IsPositive() {
+			x.skip(line, description, fmt.Sprintf("invalid cost %q", field(3)))
+			continue
+		}
+		nets := make([]decimal.Decimal, len(x.Members))
+		for i := range x.Members {
+			if field(len(want)+i) == "" {
+				continue
+			}
+			if nets[i], err = parseSplitwiseAmount(field(len(want) + i)); err != nil {
+				break
+			}
+		}
+		if err != nil {
+			x.skip(line, description, err.Error())
+			continue
+		}
+		if e.Paid, e.Owed, e.Inferred, err = sharesFromNets(e.Cost, nets); err != nil {
+			x.skip(line, description, err.Error())
+			continue
+		}
+		x.Entries = append(x.Entries, e)
+	}
+	return x, nil
+}
+
+// swJSONUser is a member as the Splitwise API returns them
+type swJSONUser struct {
+	ID        int64   `json:"id"`
+	FirstName string  `json:"first_name"`
+	LastName  *string `json:"last_name"`
+	Email     *string `json:"email"`
+}
+
+func (u swJSONUser) name() string {
+	if u.LastName == nil || *u.LastName == "" {
+		return u.FirstName
+	}
+	return u.FirstName + " " + *u.LastName
+}
+
+// swJSONExport is a JSON export: a group and its expenses, as the Splitwise
+// API's get_group and get_expenses return them
+type swJSONExport struct {
+	Group *struct {
+		Name    string       `json:"name"`
+		Members []swJSONUser `json:"members"`
+	} `json:"group"`
+	Expenses []struct {
+		Description string  `json:"description"`
+		Payment     bool    `json:"payment"`
+		Cost        string  `json:"cost"`
+		Currency    string  `json:"currency_code"`
+		Date        string  `json:"date"`
+		DeletedAt   *string `json:"deleted_at"`
+		Category    *struct {
+			Name string `json:"name"`
+		} `json:"category"`
+		Users []struct {
+			User      swJSONUser `json:"user"`
+			PaidShare string     `json:"paid_share"`
+			OwedShare string     `json:"owed_share"`
+		} `json:"users"`
+	} `json:"expenses"`
+}
+
+// parseSplitwiseJSON reads a JSON export, which has what each member paid
+// and owes. Deleted expenses are left out.
+func parseSplitwiseJSON(data []byte) (swExport, error) {
+	var doc swJSONExport
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return swExport{}, errors.New("invalid JSON: " + err.Error())
+	}
+	if len(doc.Expenses) > maxSplitwiseEntries {
+		return swExport{}, fmt.Errorf("export has more than %d entries", maxSplitwiseEntries)
+	}
+
+	var x swExport
+	members := map[int64]int{}
+	member := func(u swJSONUser) int {
+		i, ok := members[u.ID]
+		if !ok {
+			i = len(x.Members)
+			members[u.ID] = i
+			m := swMember{Name: strings.TrimSpace(u.name())}
+			if u.Email != nil {
+				m.Email = *u.Email
+			}
+			x.Members = append(x.Members, m)
+		}
+		return i
+	}
+	if doc.Group != nil {
+		x.Name = doc.Group.Name
+		for _, u := range doc.Group.Members {
+			member(u)
+		}
+	}
+
+	for n, exp := range doc.Expenses {
+		row := n + 1
+		if exp.DeletedAt != nil {
+			continue
+		}
+		e := swEntry{Row: row, Description: strings.TrimSpace(exp.Description), Payment: exp.Payment,
+			Currency: strings.ToUpper(exp.Currency)}
+		if exp.Category != nil {
+			e.Category = exp.Category.Name
+		}
+		date, err := time.Parse(time.RFC3339, exp.Date)
+		if err != nil {
+			x.skip(row, e.Description, fmt.Sprintf("invalid date %q", exp.Date))
+			continue
+		}
+		e.Date = date
+		if e.Cost, err = parseSplitwiseAmount(exp.Cost); err != nil || !e.Cost.IsPositive() {
+			x.skip(row, e.Description, fmt.Sprintf("invalid cost %q", exp.Cost))
+			continue
+		}
+
+		shares := map[int][2]decimal.Decimal{}
+		paidSum, owedSum := decimal.Zero, decimal.Zero
+		for _, u := range exp.Users {
+			paid, errPaid := parseSplitwiseAmount(u.PaidShare)
+			owed, errOwed := parseSplitwiseAmount(u.OwedShare)
+			if err = errors.Join(errPaid, errOwed); err != nil {
+				break
+			}
+			if paid.IsNegative() || owed.IsNegative() {
+				err = errors.New("negative share")
+				break
+			}
+			i := member(u.User)
+			shares[i] = [2]decimal.Decimal{shares[i][0].Add(paid), shares[i][1].Add(owed)}
+			paidSum, owedSum = paidSum.Add(paid), owedSum.Add(owed)
+		}
+		if err != nil {
+			x.skip(row, e.Description, err.Error())
+			continue
+		}
+		if !paidSum.Equal(e.Cost) || !owedSum.Equal(e.Cost) {
+			x.skip(row, e.Description, "shares don't add up to the cost")
+			continue
+		}
+		x.Entries = append(x.Entries, e)
+		entry := &x.Entries[len(x.Entries)-1]
+		entry.Paid, entry.Owed = make([]decimal.Decimal, len(x.Members)), make([]decimal.Decimal, len(x.Members))
+		for i, s := range shares {
+			entry.Paid[i], entry.Owed[i] = s[0], s[1]
+		}
+	}
+
+	// Members first seen in a later expense widen the share lists
+	for i := range x.Entries {
+		for len(x.Entries[i].Paid) < len(x.Members) {
+			x.Entries[i].Paid = append(x.Entries[i].Paid, decimal.Zero)
+			x.Entries[i].Owed = append(x.Entries[i].Owed, decimal.Zero)
+		}
+	}
+	return x, nil
+}
+
+// parseSplitwise reads a CSV or JSON export, telling them apart by content
+func parseSplitwise(data []byte) (swExport, error) {
+	data = bytes.TrimPrefix(data, []byte("\uFEFF"))
+	if trimmed := bytes.TrimSpace(data); len(trimmed) > 0 && trimmed[0] == '{' {
+		return parseSplitwiseJSON(trimmed)
+	}
+	return parseSplitwiseCSV(bytes.NewReader(data))
+}
+
+// swShare is a member's part of an entry, by member index
+type swShare struct {
+	Member int
+	Amount decimal.Decimal
+}
+
+// shares lists the positive amounts, largest first so the first is the
+// main payer
+func shares(amounts []decimal.Decimal) []swShare {
+	var list []swShare
+	for i, amount := range amounts {
+		if amount.IsPositive() {
+			list = append(list, swShare{Member: i, Amount: amount})
+		}
+	}
+	for i := 1; i < len(list); i++ {
+		for j := i; j > 0 && list[j].Amount.GreaterThan(list[j-1].Amount); j-- {
+			list[j], list[j-1] = list[j-1], list[j]
+		}
+	}
+	return list
+}
+
+// swPlan is what importing an export does, worked out without touching
+// the database
+type swPlan struct {
+	Name     string
+	Currency string
+	// Me is the member index of the importing user, or -1
+	Me          int
+	Members     []swMember
+	Entries     []swEntry
+	Expenses    int
+	Settlements int
+}
+
+// planSplitwise picks the group's name, currency and the importing user's
+// member, and which entries can be imported, filling in the report
+func planSplitwise(x swExport, opts SplitwiseImportOptions, email string, report *SplitwiseImportReport) (swPlan, error) {
+	p := swPlan{Name: strings.TrimSpace(opts.Name), Currency: opts.Currency, Me: -1, Members: x.Members}
+	if p.Name == "" {
+		p.Name = strings.TrimSpace(x.Name)
+	}
+	if p.Name == "" {
+		return p, errors.New("name is required for a CSV export")
+	}
+	if len([]rune(p.Name)) > 255 {
+		p.Name = string([]rune(p.Name)[:255])
+	}
+
+	for i, m := range x.Members {
+		if (opts.Me != "" && strings.EqualFold(m.Name, strings.TrimSpace(opts.Me))) ||
+			(opts.Me == "" && email != "" && strings.EqualFold(m.Email, email)) {
+			p.Me = i
+			break
+		}
+	}
+	if opts.Me != "" && p.Me < 0 {
+		return p, fmt.Errorf("member %q is not in the export", opts.Me)
+	}
+
+	// The currency most entries are in, the first seen on a tie
+	if p.Currency == "" {
+		counts := map[string]int{}
+		for _, e := range x.Entries {
+			counts[e.Currency]++
+			if counts[e.Currency] > counts[p.Currency] {
+				p.Currency = e.Currency
+			}
+		}
+		if p.Currency == "" {
+			p.Currency = DefaultCurrency
+		}
+	}
+
+	for _, issue := range x.Skipped {
+		if len(report.Skipped) < maxSplitwiseIssues {
+			report.Skipped = append(report.Skipped, issue)
+		}
+	}
+	for _, e := range x.Entries {
+		if e.Currency != p.Currency {
+			report.skip(e, fmt.Sprintf("in %s, not the group's %s", e.Currency, p.Currency))
+			continue
+		}
+		if e.Payment {
+			if len(shares(e.Paid)) != 1 || len(shares(e.Owed)) != 1 || shares(e.Paid)[0].Member == shares(e.Owed)[0].Member {
+				report.skip(e, "a payment must be from one member to another")
+				continue
+			}
+			p.Settlements++
+		} else {
+			p.Expenses++
+			if e.Inferred && len(report.Inferred) < maxSplitwiseIssues {
+				report.Inferred = append(report.Inferred, SplitwiseIssue{Row: e.Row, Description: e.Description,
+					Reason: "several members are ahead; the one furthest ahead is taken to have paid the rest"})
+			}
+		}
+		p.Entries = append(p.Entries, e)
+	}
+
+	report.Name, report.Currency = p.Name, p.Currency
+	report.Expenses, report.Settlements = p.Expenses, p.Settlements
+	for i, m := range x.Members {
+		mapping := "placeholder"
+		if i == p.Me {
+			mapping = "you"
+		}
+		report.Members = append(report.Members, SplitwiseMember{Name: m.Name, Mapping: mapping})
+	}
+	return p, nil
+}
+
+// ImportSplitwise creates a group from a Splitwise export, CSV or JSON,
+// with a placeholder for every member but the importing user, and their
+// expenses and payments as expenses and settlements. The multipart body
+// holds an "options" field with SplitwiseImportOptions followed by a
+// "file" part. A dry run reports what would be imported without creating
+// anything.
+func ImportSplitwise(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
+		c.JSON(400, gin.H{"error": "expected a multipart/form-data body"})
+		return
+	}
+
+	var opts SplitwiseImportOptions
+	var data []byte
+	for data == nil {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			c.JSON(400, gin.H{"error": "missing file"})
+			return
+		}
+		if err != nil {
+			c.JSON(400, gin.H{"error": "invalid multipart body"})
+			return
+		}
+
+		switch part.FormName() {
+		case "options":
+			if err := json.NewDecoder(part).Decode(&opts); err != nil {
+				c.JSON(400, gin.H{"error": "invalid options: " + err.Error()})
+				return
+			}
+			if err := validation.Struct(&opts); err != nil {
+				validation.Respond(c, err)
+				return
+			}
+		case "file":
+			if data, err = io.ReadAll(io.LimitReader(part, maxSplitwiseSize+1)); err != nil {
+				c.JSON(400, gin.H{"error": "failed to read file"})
+				return
+			}
+			if len(data) > maxSplitwiseSize {
+				c.JSON(400, gin.H{"error": fmt.Sprintf("file is larger than %d MB", maxSplitwiseSize>>20)})
+				return
+			}
+		}
+	}
+
+	ctx := c.Request.Context()
+	export, err := parseSplitwise(data)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	var email string
+	if err := db.Pool.QueryRow(ctx, "SELECT COALESCE(email, '') FROM users WHERE id = $1", userID).Scan(&email); err != nil {
+		c.JSON(500, gin.H{"error": "failed to get user"})
+		return
+	}
+
+	report := SplitwiseImportReport{DryRun: opts.DryRun, Skipped: []SplitwiseIssue{}, Inferred: []SplitwiseIssue{}}
+	plan, err := planSplitwise(export, opts, email, &report)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	if opts.DryRun {
+		c.JSON(200, report)
+		return
+	}
+
+	var g Group
+	err = db.WithTx(ctx, func(tx pgx.Tx) error {
+		var err error
+		g, err = importSplitwise(ctx, tx, userID, plan, report.Members)
+		return err
+	})
+	if err != nil {
+		helpers.RespondError(c, err, "failed to import group")
+		return
+	}
+
+	report.Group = &g
+	c.JSON(201, report)
+}
+
+// importSplitwise creates the planned group in tx, filling in the user ID
+// of each member
+func importSplitwise(ctx context.Context, tx pgx.Tx, userID uuid.UUID, p swPlan, members []SplitwiseMember) (Group, error) {
+	var g Group
+	err := tx.QueryRow(ctx,
+		"INSERT INTO groups (name, currency, created_by) VALUES ($1, $2, $3) RETURNING id, name, currency, created_by, created_at",
+		p.Name, p.Currency, userID).Scan(&g.ID, &g.Name, &g.Currency, &g.CreatedBy, &g.CreatedAt)
+	if err != nil {
+		return g, helpers.NewRequestError(500, "failed to create group")
+	}
+	if _, err := tx.Exec(ctx, "INSERT INTO group_members (group_id, user_id) VALUES ($1, $2)", g.ID, userID); err != nil {
+		return g, helpers.NewRequestError(500, "failed to add member")
+	}
+
+	userIDs := make([]uuid.UUID, len(p.Members))
+	for i, m := range p.Members {
+		if i == p.Me {
+			userIDs[i] = userID
+		} else {
+			name := m.Name
+			if name == "" {
+				name = fmt.Sprintf("Splitwise member %d", i+1)
+			}
+			if runes := []rune(name); len(runes) > 100 {
+				name = string(runes[:100])
+			}
+			err := tx.QueryRow(ctx,
+				"INSERT INTO users (display_name, is_placeholder) VALUES ($1, TRUE) RETURNING id", name).Scan(&userIDs[i])
+			if err != nil {
+				return g, helpers.NewRequestError(500, "failed to create placeholder")
+			}
+			if _, err := tx.Exec(ctx, "INSERT INTO group_members (group_id, user_id) VALUES ($1, $2)", g.ID, userIDs[i]); err != nil {
+				return g, helpers.NewRequestError(500, "failed to add placeholder")
+			}
+		}
+		members[i].UserID = &userIDs[i]
+	}
+
+	// Imported history records no events, so members aren't notified of
+	// every old expense
+	for start := 0; start < len(p.Entries); start += splitwiseBatchSize {
+		batch := &pgx.Batch{}
+		for _, e := range p.Entries[start:min(start+splitwiseBatchSize, len(p.Entries))] {
+			queueSplitwiseEntry(batch, g.ID, userID, p.Currency, userIDs, e)
+		}
+		if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+			return g, err
+		}
+	}
+	return g, nil
+}
+
+// queueSplitwiseEntry queues the inserts for an expense with its payers and
+// splits, or for a payment's settlement
+func queueSplitwiseEntry(batch *pgx.Batch, groupID, userID uuid.UUID, currency string, userIDs []uuid.UUID, e swEntry) {
+	paid, owed := shares(e.Paid), shares(e.Owed)
+	if e.Payment {
+		batch.Queue(
+			`INSERT INTO settlements (group_id, from_user, to_user, amount, currency, note, status, confirmed_at, created_at)
+			 VALUES ($1, $2, $3, $4, $5, $6, 'confirmed', $7, $7)`,
+			groupID, userIDs[paid[0].Member], userIDs[owed[0].Member], e.Cost, currency, "Imported from Splitwise", e.Date)
+		return
+	}
+
+	description := e.Description
+	if description == "" {
+		description = "Splitwise expense"
+	}
+	var category *string
+	if e.Category != "" {
+		name := e.Category
+		if runes := []rune(name); len(runes) > 100 {
+			name = string(runes[:100])
+		}
+		category = &name
+	}
+
+	expenseID := uuid.New()
+	batch.Queue(
+		`INSERT INTO expenses (id, group_id, description, total_amount, currency, category, paid_by, expense_date, created_by, status)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, 'approved')`,
+		expenseID, groupID, description, e.Cost, currency, category, userIDs[paid[0].Member], e.Date, userID)
+	for table, list := range map[string][]swShare{"expense_payers": paid, "expense_splits": owed} {
+		ids := make([]uuid.UUID, len(list))
+		amounts := make([]string, len(list))
+		for i, s := range list {
+			ids[i], amounts[i] = userIDs[s.Member], s.Amount.String()
+		}
+		batch.Queue(`INSERT INTO `+table+` (expense_id, user_id, amount)
+			 SELECT $1, user_id, amount FROM unnest($2::uuid[], $3::numeric[]) AS s(user_id, amount)`,
+			expenseID, ids, amounts)
+	}
+}
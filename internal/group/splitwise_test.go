@@ -0,0 +1,176 @@
+package group
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func decs(values ...string) []decimal.Decimal {
+	list := make([]decimal.Decimal, len(values))
+	for i, v := range values {
+		list[i] = decimal.RequireFromString(v)
+	}
+	return list
+}
+
+func assertDecs(t *testing.T, want []string, got []decimal.Decimal) {
+	t.Helper()
+	require.Len(t, got, len(want))
+	for i := range want {
+		assert.True(t, decimal.RequireFromString(want[i]).Equal(got[i]), "%d: want %s, got %s", i, want[i], got[i])
+	}
+}
+
+func TestSharesFromNets(t *testing.T) {
+	// Alice paid 30 split three ways
+	paid, owed, inferred, err := sharesFromNets(decimal.NewFromInt(30), decs("20", "-10", "-10"))
+	require.NoError(t, err)
+	assert.False(t, inferred)
+	assertDecs(t, []string{"30", "0", "0"}, paid)
+	assertDecs(t, []string{"10", "10", "10"}, owed)
+
+	// Two members ahead: the rest goes to whoever is furthest ahead
+	paid, owed, inferred, err = sharesFromNets(decimal.NewFromInt(60), decs("30", "10", "-40"))
+	require.NoError(t, err)
+	assert.True(t, inferred)
+	assertDecs(t, []string{"50", "10", "0"}, paid)
+	assertDecs(t, []string{"20", "0", "40"}, owed)
+
+	_, _, _, err = sharesFromNets(decimal.NewFromInt(30), decs("20", "-5"))
+	assert.EqualError(t, err, "member balances don't add up to zero")
+	_, _, _, err = sharesFromNets(decimal.NewFromInt(10), decs("20", "-20"))
+	assert.EqualError(t, err, "member balances exceed the cost")
+	_, _, _, err = sharesFromNets(decimal.NewFromInt(10), decs("0", "0"))
+	assert.EqualError(t, err, "no member paid")
+}
+
+const splitwiseCSV = "\uFEFFDate,Description,Category,Cost,Currency,Alice Smith,Bob Jones,Carol\n" +
+	"\n" +
+	"2025-01-05,Groceries,Groceries,30.00,USD,20.00,-10.00,-10.00\n" +
+	"2025-01-06,Hotel,Hotel,90.00,EUR,-30.00,60.00,-30.00\n" +
+	"2025-01-07,Settle up,Payment,10.00,USD,-10.00,10.00,0.00\n" +
+	"2025-01-08,Broken,General,10.00,USD,10.00,-5.00,0.00\n" +
+	"bad date,Taxi,Transportation,5.00,USD,5.00,-5.00,0.00\n" +
+	"\n" +
+	"2025-01-09,Total balance, , ,USD,10.00,0.00,-10.00\n"
+
+func TestParseSplitwiseCSV(t *testing.T) {
+	x, err := parseSplitwise([]byte(splitwiseCSV))
+	require.NoError(t, err)
+	assert.Equal(t, []swMember{{Name: "Alice Smith"}, {Name: "Bob Jones"}, {Name: "Carol"}}, x.Members)
+
+	require.Len(t, x.Entries, 3)
+	e := x.Entries[0]
+	assert.Equal(t, 3, e.Row)
+	assert.Equal(t, "Groceries", e.Description)
+	assert.Equal(t, time.Date(2025, 1, 5, 0, 0, 0, 0, time.UTC), e.Date)
+	assert.Equal(t, "USD", e.Currency)
+	assert.False(t, e.Payment)
+	assertDecs(t, []string{"30", "0", "0"}, e.Paid)
+	assertDecs(t, []string{"10", "10", "10"}, e.Owed)
+	assert.True(t, x.Entries[2].Payment)
+
+	assert.Equal(t, []SplitwiseIssue{
+		{Row: 6, Description: "Broken", Reason: "member balances don't add up to zero"},
+		{Row: 7, Description: "Taxi", Reason: `invalid date "bad date"`},
+	}, x.Skipped)
+
+	_, err = parseSplitwise([]byte("Date,Amount,Payee\n2025-01-05,10,Shop\n"))
+	assert.ErrorContains(t, err, "not a Splitwise export")
+}
+
+const splitwiseJSON = `{
+	"group": {"name": "Trip", "members": [
+		{"id": 1, "first_name": "Alice", "last_name": "Smith", "email": "alice@example.com"},
+		{"id": 2, "first_name": "Bob", "last_name": null, "email": "bob@example.com"}
+	]},
+	"expenses": [
+		{"description": "Dinner", "payment": false, "cost": "45.0", "currency_code": "USD",
+		 "date": "2025-01-05T19:00:00Z", "deleted_at": null, "category": {"name": "Dining out"},
+		 "users": [
+			{"user": {"id": 1, "first_name": "Alice", "last_name": "Smith"}, "paid_share": "45.0", "owed_share": "15.0"},
+			{"user": {"id": 2, "first_name": "Bob"}, "paid_share": "0.0", "owed_share": "15.0"},
+			{"user": {"id": 3, "first_name": "Dan"}, "paid_share": "0.0", "owed_share": "15.0"}
+		 ]},
+		{"description": "Deleted", "payment": false, "cost": "5.0", "currency_code": "USD",
+		 "date": "2025-01-06T10:00:00Z", "deleted_at": "2025-01-07T10:00:00Z", "users": []},
+		{"description": "Payment", "payment": true, "cost": "15.0", "currency_code": "USD",
+		 "date": "2025-01-08T10:00:00Z", "deleted_at": null,
+		 "users": [
+			{"user": {"id": 2, "first_name": "Bob"}, "paid_share": "15.0", "owed_share": "0.0"},
+			{"user": {"id": 1, "first_name": "Alice", "last_name": "Smith"}, "paid_share": "0.0", "owed_share": "15.0"}
+		 ]},
+		{"description": "Uneven", "payment": false, "cost": "10.0", "currency_code": "USD",
+		 "date": "2025-01-09T10:00:00Z", "deleted_at": null,
+		 "users": [{"user": {"id": 1, "first_name": "Alice"}, "paid_share": "10.0", "owed_share": "5.0"}]}
+	]
+}`
+
+func TestParseSplitwiseJSON(t *testing.T) {
+	x, err := parseSplitwise([]byte(splitwiseJSON))
+	require.NoError(t, err)
+	assert.Equal(t, "Trip", x.Name)
+	assert.Equal(t, []swMember{
+		{Name: "Alice Smith", Email: "alice@example.com"},
+		{Name: "Bob", Email: "bob@example.com"},
+		{Name: "Dan"},
+	}, x.Members)
+
+	require.Len(t, x.Entries, 2)
+	e := x.Entries[0]
+	assert.Equal(t, 1, e.Row)
+	assert.Equal(t, "Dining out", e.Category)
+	assertDecs(t, []string{"45", "0", "0"}, e.Paid)
+	assertDecs(t, []string{"15", "15", "15"}, e.Owed)
+	e = x.Entries[1]
+	assert.True(t, e.Payment)
+	assertDecs(t, []string{"0", "15", "0"}, e.Paid)
+
+	assert.Equal(t, []SplitwiseIssue{{Row: 4, Description: "Uneven", Reason: "shares don't add up to the cost"}}, x.Skipped)
+}
+
+func TestPlanSplitwise(t *testing.T) {
+	x, err := parseSplitwise([]byte(splitwiseCSV))
+	require.NoError(t, err)
+
+	report := SplitwiseImportReport{}
+	_, err = planSplitwise(x, SplitwiseImportOptions{}, "", &report)
+	assert.EqualError(t, err, "name is required for a CSV export")
+	_, err = planSplitwise(x, SplitwiseImportOptions{Name: "Flat", Me: "Dan"}, "", &report)
+	assert.EqualError(t, err, `member "Dan" is not in the export`)
+
+	report = SplitwiseImportReport{}
+	p, err := planSplitwise(x, SplitwiseImportOptions{Name: "Flat", Me: "bob jones"}, "", &report)
+	require.NoError(t, err)
+	assert.Equal(t, 1, p.Me)
+	assert.Equal(t, "USD", p.Currency, "most entries are in USD")
+	assert.Equal(t, 1, p.Expenses)
+	assert.Equal(t, 1, p.Settlements)
+	assert.Equal(t, []SplitwiseMember{
+		{Name: "Alice Smith", Mapping: "placeholder"},
+		{Name: "Bob Jones", Mapping: "you"},
+		{Name: "Carol", Mapping: "placeholder"},
+	}, report.Members)
+	require.Len(t, report.Skipped, 3)
+	assert.Equal(t, SplitwiseIssue{Row: 4, Description: "Hotel", Reason: "in EUR, not the group's USD"}, report.Skipped[2])
+
+	// The importing user is matched by email in a JSON export
+	x, err = parseSplitwise([]byte(splitwiseJSON))
+	require.NoError(t, err)
+	report = SplitwiseImportReport{}
+	p, err = planSplitwise(x, SplitwiseImportOptions{}, "BOB@example.com", &report)
+	require.NoError(t, err)
+	assert.Equal(t, "Trip", p.Name)
+	assert.Equal(t, 1, p.Me)
+}
+
+func TestShares(t *testing.T) {
+	assert.Equal(t, []swShare{
+		{Member: 2, Amount: decimal.NewFromInt(30)},
+		{Member: 0, Amount: decimal.NewFromInt(10)},
+	}, shares(decs("10", "0", "30")))
+}
@@ -0,0 +1,123 @@
+package group
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+	"github.com/yanonymousV2/finance-manager-backend/internal/helpers"
+	"github.com/yanonymousV2/finance-manager-backend/internal/middleware"
+	"github.com/yanonymousV2/finance-manager-backend/internal/settlement"
+)
+
+type MemberStats struct {
+	UserID              uuid.UUID               `json:"user_id"`
+	Currency            string                  `json:"currency"`
+	TotalPaid           decimal.Decimal         `json:"total_paid"`
+	TotalConsumed       decimal.Decimal         `json:"total_consumed"`
+	ExpensesPaid        int                     `json:"expenses_paid"`
+	ExpensesShared      int                     `json:"expenses_shared"`
+	SettlementsSent     decimal.Decimal         `json:"settlements_sent"`
+	SettlementsReceived decimal.Decimal         `json:"settlements_received"`
+	Balance             decimal.Decimal         `json:"balance"`
+	Settlements         []settlement.Settlement `json:"settlements"`
+}
+
+// GetMemberStats returns how much a member paid versus consumed in a group
+func GetMemberStats(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	groupID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid group id"})
+		return
+	}
+
+	memberID, err := uuid.Parse(c.Param("userId"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	isMember, err := helpers.IsGroupMember(c.Request.Context(), db, groupID, userID)
+	if err != nil || !isMember {
+		c.JSON(403, gin.H{"error": "not a member of the group"})
+		return
+	}
+
+	isMember, err = helpers.IsGroupMember(c.Request.Context(), db, groupID, memberID)
+	if err != nil || !isMember {
+		c.JSON(404, gin.H{"error": "member not found in group"})
+		return
+	}
+
+	stats := MemberStats{UserID: memberID}
+
+	stats.Currency, err = helpers.GetGroupCurrency(c.Request.Context(), db, groupID)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to get group currency"})
+		return
+	}
+
+	err = db.Pool.QueryRow(c.Request.Context(),
+		`SELECT COALESCE(SUM(ep.amount), 0), COUNT(*)
+		 FROM expense_payers ep JOIN expenses e ON ep.expense_id = e.id
+		 WHERE e.group_id = $1 AND ep.user_id = $2 AND e.deleted_at IS NULL AND e.status = 'approved'`,
+		groupID, memberID).Scan(&stats.TotalPaid, &stats.ExpensesPaid)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to get paid expenses"})
+		return
+	}
+
+	err = db.Pool.QueryRow(c.Request.Context(),
+		`SELECT COALESCE(SUM(es.amount), 0), COUNT(*)
+		 FROM expense_splits es JOIN expenses e ON es.expense_id = e.id
+		 WHERE e.group_id = $1 AND es.user_id = $2 AND e.deleted_at IS NULL AND e.status = 'approved'`,
+		groupID, memberID).Scan(&stats.TotalConsumed, &stats.ExpensesShared)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to get expense shares"})
+		return
+	}
+
+	rows, err := db.Pool.Query(c.Request.Context(),
+		`SELECT `+settlement.Columns+`
+		 FROM settlements
+		 WHERE group_id = $1 AND (from_user = $2 OR to_user = $2) AND deleted_at IS NULL
+		 ORDER BY created_at DESC`,
+		groupID, memberID)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to get settlements"})
+		return
+	}
+	defer rows.Close()
+
+	stats.Settlements = []settlement.Settlement{}
+	for rows.Next() {
+		s, err := settlement.Scan(rows)
+		if err != nil {
+			c.JSON(500, gin.H{"error": "failed to scan settlement"})
+			return
+		}
+		// Pending settlements are listed but do not count yet
+		if s.Status != settlement.StatusConfirmed {
+			stats.Settlements = append(stats.Settlements, s)
+			continue
+		}
+		if s.FromUser == memberID {
+			stats.SettlementsSent = stats.SettlementsSent.Add(s.Amount)
+		} else {
+			stats.SettlementsReceived = stats.SettlementsReceived.Add(s.Amount)
+		}
+		stats.Settlements = append(stats.Settlements, s)
+	}
+
+	// Positive means the member is owed money, matching GetBalances
+	stats.Balance = stats.TotalPaid.Sub(stats.TotalConsumed).Add(stats.SettlementsSent).Sub(stats.SettlementsReceived)
+
+	c.JSON(200, stats)
+}
@@ -0,0 +1,868 @@
+// gRPC API for internal services that shouldn't go through HTTP/JSON,
+// served on GRPC_ADDR by internal/grpcapi. The services wrap the same code
+// the REST handlers use, and calls carry the same JWT in the
+// "authorization" metadata as "Bearer <token>".
+//
+// Regenerate internal/grpcapi/financev1 after changing this file:
+//
+//   protoc -I proto --go_out=. --go_opt=module=github.com/yanonymousV2/finance-manager-backend \
+//     --go-grpc_out=. --go-grpc_opt=module=github.com/yanonymousV2/finance-manager-backend \
+//     finance/v1/finance.proto
+
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.9
+// 	protoc        (unknown)
+// source: finance/v1/finance.proto
+
+package financev1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type ValidateTokenRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Token         string                 `protobuf:"bytes,1,opt,name=token,proto3" json:"token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ValidateTokenRequest) Reset() {
+	*x = ValidateTokenRequest{}
+	mi := &file_finance_v1_finance_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ValidateTokenRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ValidateTokenRequest) ProtoMessage() {}
+
+func (x *ValidateTokenRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_finance_v1_finance_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ValidateTokenRequest.ProtoReflect.Descriptor instead.
+func (*ValidateTokenRequest) Descriptor() ([]byte, []int) {
+	return file_finance_v1_finance_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *ValidateTokenRequest) GetToken() string {
+	if x != nil {
+		return x.Token
+	}
+	return ""
+}
+
+type ValidateTokenResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Email         string                 `protobuf:"bytes,2,opt,name=email,proto3" json:"email,omitempty"`
+	ExpiresAt     *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=expires_at,json=expiresAt,proto3" json:"expires_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ValidateTokenResponse) Reset() {
+	*x = ValidateTokenResponse{}
+	mi := &file_finance_v1_finance_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ValidateTokenResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ValidateTokenResponse) ProtoMessage() {}
+
+func (x *ValidateTokenResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_finance_v1_finance_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ValidateTokenResponse.ProtoReflect.Descriptor instead.
+func (*ValidateTokenResponse) Descriptor() ([]byte, []int) {
+	return file_finance_v1_finance_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *ValidateTokenResponse) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *ValidateTokenResponse) GetEmail() string {
+	if x != nil {
+		return x.Email
+	}
+	return ""
+}
+
+func (x *ValidateTokenResponse) GetExpiresAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ExpiresAt
+	}
+	return nil
+}
+
+type CreateExpenseRequest struct {
+	state       protoimpl.MessageState `protogen:"open.v1"`
+	GroupId     string                 `protobuf:"bytes,1,opt,name=group_id,json=groupId,proto3" json:"group_id,omitempty"`
+	Description string                 `protobuf:"bytes,2,opt,name=description,proto3" json:"description,omitempty"`
+	// Decimal string, e.g. "12.50"
+	TotalAmount string `protobuf:"bytes,3,opt,name=total_amount,json=totalAmount,proto3" json:"total_amount,omitempty"`
+	// ISO 4217; the group's currency when empty
+	Currency    string                 `protobuf:"bytes,4,opt,name=currency,proto3" json:"currency,omitempty"`
+	Category    *string                `protobuf:"bytes,5,opt,name=category,proto3,oneof" json:"category,omitempty"`
+	ExpenseDate *timestamppb.Timestamp `protobuf:"bytes,6,opt,name=expense_date,json=expenseDate,proto3,oneof" json:"expense_date,omitempty"`
+	// exact, equal or itemized; equal when empty
+	SplitMode     string   `protobuf:"bytes,7,opt,name=split_mode,json=splitMode,proto3" json:"split_mode,omitempty"`
+	Participants  []string `protobuf:"bytes,8,rep,name=participants,proto3" json:"participants,omitempty"`
+	Payers        []*Share `protobuf:"bytes,9,rep,name=payers,proto3" json:"payers,omitempty"`
+	Splits        []*Share `protobuf:"bytes,10,rep,name=splits,proto3" json:"splits,omitempty"`
+	Tags          []string `protobuf:"bytes,11,rep,name=tags,proto3" json:"tags,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CreateExpenseRequest) Reset() {
+	*x = CreateExpenseRequest{}
+	mi := &file_finance_v1_finance_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CreateExpenseRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CreateExpenseRequest) ProtoMessage() {}
+
+func (x *CreateExpenseRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_finance_v1_finance_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CreateExpenseRequest.ProtoReflect.Descriptor instead.
+func (*CreateExpenseRequest) Descriptor() ([]byte, []int) {
+	return file_finance_v1_finance_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *CreateExpenseRequest) GetGroupId() string {
+	if x != nil {
+		return x.GroupId
+	}
+	return ""
+}
+
+func (x *CreateExpenseRequest) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *CreateExpenseRequest) GetTotalAmount() string {
+	if x != nil {
+		return x.TotalAmount
+	}
+	return ""
+}
+
+func (x *CreateExpenseRequest) GetCurrency() string {
+	if x != nil {
+		return x.Currency
+	}
+	return ""
+}
+
+func (x *CreateExpenseRequest) GetCategory() string {
+	if x != nil && x.Category != nil {
+		return *x.Category
+	}
+	return ""
+}
+
+func (x *CreateExpenseRequest) GetExpenseDate() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ExpenseDate
+	}
+	return nil
+}
+
+func (x *CreateExpenseRequest) GetSplitMode() string {
+	if x != nil {
+		return x.SplitMode
+	}
+	return ""
+}
+
+func (x *CreateExpenseRequest) GetParticipants() []string {
+	if x != nil {
+		return x.Participants
+	}
+	return nil
+}
+
+func (x *CreateExpenseRequest) GetPayers() []*Share {
+	if x != nil {
+		return x.Payers
+	}
+	return nil
+}
+
+func (x *CreateExpenseRequest) GetSplits() []*Share {
+	if x != nil {
+		return x.Splits
+	}
+	return nil
+}
+
+func (x *CreateExpenseRequest) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+type Share struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	UserId        string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	Amount        string                 `protobuf:"bytes,2,opt,name=amount,proto3" json:"amount,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Share) Reset() {
+	*x = Share{}
+	mi := &file_finance_v1_finance_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Share) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Share) ProtoMessage() {}
+
+func (x *Share) ProtoReflect() protoreflect.Message {
+	mi := &file_finance_v1_finance_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Share.ProtoReflect.Descriptor instead.
+func (*Share) Descriptor() ([]byte, []int) {
+	return file_finance_v1_finance_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Share) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *Share) GetAmount() string {
+	if x != nil {
+		return x.Amount
+	}
+	return ""
+}
+
+type Expense struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	GroupId       string                 `protobuf:"bytes,2,opt,name=group_id,json=groupId,proto3" json:"group_id,omitempty"`
+	Description   string                 `protobuf:"bytes,3,opt,name=description,proto3" json:"description,omitempty"`
+	TotalAmount   string                 `protobuf:"bytes,4,opt,name=total_amount,json=totalAmount,proto3" json:"total_amount,omitempty"`
+	Currency      string                 `protobuf:"bytes,5,opt,name=currency,proto3" json:"currency,omitempty"`
+	Category      *string                `protobuf:"bytes,6,opt,name=category,proto3,oneof" json:"category,omitempty"`
+	PaidBy        string                 `protobuf:"bytes,7,opt,name=paid_by,json=paidBy,proto3" json:"paid_by,omitempty"`
+	ExpenseDate   *timestamppb.Timestamp `protobuf:"bytes,8,opt,name=expense_date,json=expenseDate,proto3" json:"expense_date,omitempty"`
+	Status        string                 `protobuf:"bytes,9,opt,name=status,proto3" json:"status,omitempty"`
+	Payers        []*Share               `protobuf:"bytes,10,rep,name=payers,proto3" json:"payers,omitempty"`
+	Splits        []*Share               `protobuf:"bytes,11,rep,name=splits,proto3" json:"splits,omitempty"`
+	Tags          []string               `protobuf:"bytes,12,rep,name=tags,proto3" json:"tags,omitempty"`
+	CreatedAt     *timestamppb.Timestamp `protobuf:"bytes,13,opt,name=created_at,json=createdAt,proto3" json:"created_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Expense) Reset() {
+	*x = Expense{}
+	mi := &file_finance_v1_finance_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Expense) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Expense) ProtoMessage() {}
+
+func (x *Expense) ProtoReflect() protoreflect.Message {
+	mi := &file_finance_v1_finance_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Expense.ProtoReflect.Descriptor instead.
+func (*Expense) Descriptor() ([]byte, []int) {
+	return file_finance_v1_finance_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *Expense) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Expense) GetGroupId() string {
+	if x != nil {
+		return x.GroupId
+	}
+	return ""
+}
+
+func (x *Expense) GetDescription() string {
+	if x != nil {
+		return x.Description
+	}
+	return ""
+}
+
+func (x *Expense) GetTotalAmount() string {
+	if x != nil {
+		return x.TotalAmount
+	}
+	return ""
+}
+
+func (x *Expense) GetCurrency() string {
+	if x != nil {
+		return x.Currency
+	}
+	return ""
+}
+
+func (x *Expense) GetCategory() string {
+	if x != nil && x.Category != nil {
+		return *x.Category
+	}
+	return ""
+}
+
+func (x *Expense) GetPaidBy() string {
+	if x != nil {
+		return x.PaidBy
+	}
+	return ""
+}
+
+func (x *Expense) GetExpenseDate() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ExpenseDate
+	}
+	return nil
+}
+
+func (x *Expense) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *Expense) GetPayers() []*Share {
+	if x != nil {
+		return x.Payers
+	}
+	return nil
+}
+
+func (x *Expense) GetSplits() []*Share {
+	if x != nil {
+		return x.Splits
+	}
+	return nil
+}
+
+func (x *Expense) GetTags() []string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *Expense) GetCreatedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.CreatedAt
+	}
+	return nil
+}
+
+type GetBalancesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	GroupId       string                 `protobuf:"bytes,1,opt,name=group_id,json=groupId,proto3" json:"group_id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetBalancesRequest) Reset() {
+	*x = GetBalancesRequest{}
+	mi := &file_finance_v1_finance_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetBalancesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetBalancesRequest) ProtoMessage() {}
+
+func (x *GetBalancesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_finance_v1_finance_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetBalancesRequest.ProtoReflect.Descriptor instead.
+func (*GetBalancesRequest) Descriptor() ([]byte, []int) {
+	return file_finance_v1_finance_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *GetBalancesRequest) GetGroupId() string {
+	if x != nil {
+		return x.GroupId
+	}
+	return ""
+}
+
+type Balance struct {
+	state  protoimpl.MessageState `protogen:"open.v1"`
+	UserId string                 `protobuf:"bytes,1,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	// Positive when the member is owed
+	Amount        string `protobuf:"bytes,2,opt,name=amount,proto3" json:"amount,omitempty"`
+	Currency      string `protobuf:"bytes,3,opt,name=currency,proto3" json:"currency,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Balance) Reset() {
+	*x = Balance{}
+	mi := &file_finance_v1_finance_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Balance) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Balance) ProtoMessage() {}
+
+func (x *Balance) ProtoReflect() protoreflect.Message {
+	mi := &file_finance_v1_finance_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Balance.ProtoReflect.Descriptor instead.
+func (*Balance) Descriptor() ([]byte, []int) {
+	return file_finance_v1_finance_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *Balance) GetUserId() string {
+	if x != nil {
+		return x.UserId
+	}
+	return ""
+}
+
+func (x *Balance) GetAmount() string {
+	if x != nil {
+		return x.Amount
+	}
+	return ""
+}
+
+func (x *Balance) GetCurrency() string {
+	if x != nil {
+		return x.Currency
+	}
+	return ""
+}
+
+type GetBalancesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Balances      []*Balance             `protobuf:"bytes,1,rep,name=balances,proto3" json:"balances,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetBalancesResponse) Reset() {
+	*x = GetBalancesResponse{}
+	mi := &file_finance_v1_finance_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetBalancesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetBalancesResponse) ProtoMessage() {}
+
+func (x *GetBalancesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_finance_v1_finance_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetBalancesResponse.ProtoReflect.Descriptor instead.
+func (*GetBalancesResponse) Descriptor() ([]byte, []int) {
+	return file_finance_v1_finance_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *GetBalancesResponse) GetBalances() []*Balance {
+	if x != nil {
+		return x.Balances
+	}
+	return nil
+}
+
+type PairwiseBalance struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	FromUser      string                 `protobuf:"bytes,1,opt,name=from_user,json=fromUser,proto3" json:"from_user,omitempty"`
+	ToUser        string                 `protobuf:"bytes,2,opt,name=to_user,json=toUser,proto3" json:"to_user,omitempty"`
+	Amount        string                 `protobuf:"bytes,3,opt,name=amount,proto3" json:"amount,omitempty"`
+	Currency      string                 `protobuf:"bytes,4,opt,name=currency,proto3" json:"currency,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *PairwiseBalance) Reset() {
+	*x = PairwiseBalance{}
+	mi := &file_finance_v1_finance_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *PairwiseBalance) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*PairwiseBalance) ProtoMessage() {}
+
+func (x *PairwiseBalance) ProtoReflect() protoreflect.Message {
+	mi := &file_finance_v1_finance_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use PairwiseBalance.ProtoReflect.Descriptor instead.
+func (*PairwiseBalance) Descriptor() ([]byte, []int) {
+	return file_finance_v1_finance_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *PairwiseBalance) GetFromUser() string {
+	if x != nil {
+		return x.FromUser
+	}
+	return ""
+}
+
+func (x *PairwiseBalance) GetToUser() string {
+	if x != nil {
+		return x.ToUser
+	}
+	return ""
+}
+
+func (x *PairwiseBalance) GetAmount() string {
+	if x != nil {
+		return x.Amount
+	}
+	return ""
+}
+
+func (x *PairwiseBalance) GetCurrency() string {
+	if x != nil {
+		return x.Currency
+	}
+	return ""
+}
+
+type GetPairwiseBalancesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Balances      []*PairwiseBalance     `protobuf:"bytes,1,rep,name=balances,proto3" json:"balances,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetPairwiseBalancesResponse) Reset() {
+	*x = GetPairwiseBalancesResponse{}
+	mi := &file_finance_v1_finance_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetPairwiseBalancesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetPairwiseBalancesResponse) ProtoMessage() {}
+
+func (x *GetPairwiseBalancesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_finance_v1_finance_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetPairwiseBalancesResponse.ProtoReflect.Descriptor instead.
+func (*GetPairwiseBalancesResponse) Descriptor() ([]byte, []int) {
+	return file_finance_v1_finance_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *GetPairwiseBalancesResponse) GetBalances() []*PairwiseBalance {
+	if x != nil {
+		return x.Balances
+	}
+	return nil
+}
+
+var File_finance_v1_finance_proto protoreflect.FileDescriptor
+
+const file_finance_v1_finance_proto_rawDesc = "" +
+	"\n" +
+	"\x18finance/v1/finance.proto\x12\n" +
+	"finance.v1\x1a\x1fgoogle/protobuf/timestamp.proto\",\n" +
+	"\x14ValidateTokenRequest\x12\x14\n" +
+	"\x05token\x18\x01 \x01(\tR\x05token\"\x81\x01\n" +
+	"\x15ValidateTokenResponse\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x14\n" +
+	"\x05email\x18\x02 \x01(\tR\x05email\x129\n" +
+	"\n" +
+	"expires_at\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\texpiresAt\"\xc2\x03\n" +
+	"\x14CreateExpenseRequest\x12\x19\n" +
+	"\bgroup_id\x18\x01 \x01(\tR\agroupId\x12 \n" +
+	"\vdescription\x18\x02 \x01(\tR\vdescription\x12!\n" +
+	"\ftotal_amount\x18\x03 \x01(\tR\vtotalAmount\x12\x1a\n" +
+	"\bcurrency\x18\x04 \x01(\tR\bcurrency\x12\x1f\n" +
+	"\bcategory\x18\x05 \x01(\tH\x00R\bcategory\x88\x01\x01\x12B\n" +
+	"\fexpense_date\x18\x06 \x01(\v2\x1a.google.protobuf.TimestampH\x01R\vexpenseDate\x88\x01\x01\x12\x1d\n" +
+	"\n" +
+	"split_mode\x18\a \x01(\tR\tsplitMode\x12\"\n" +
+	"\fparticipants\x18\b \x03(\tR\fparticipants\x12)\n" +
+	"\x06payers\x18\t \x03(\v2\x11.finance.v1.ShareR\x06payers\x12)\n" +
+	"\x06splits\x18\n" +
+	" \x03(\v2\x11.finance.v1.ShareR\x06splits\x12\x12\n" +
+	"\x04tags\x18\v \x03(\tR\x04tagsB\v\n" +
+	"\t_categoryB\x0f\n" +
+	"\r_expense_date\"8\n" +
+	"\x05Share\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x16\n" +
+	"\x06amount\x18\x02 \x01(\tR\x06amount\"\xd8\x03\n" +
+	"\aExpense\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x19\n" +
+	"\bgroup_id\x18\x02 \x01(\tR\agroupId\x12 \n" +
+	"\vdescription\x18\x03 \x01(\tR\vdescription\x12!\n" +
+	"\ftotal_amount\x18\x04 \x01(\tR\vtotalAmount\x12\x1a\n" +
+	"\bcurrency\x18\x05 \x01(\tR\bcurrency\x12\x1f\n" +
+	"\bcategory\x18\x06 \x01(\tH\x00R\bcategory\x88\x01\x01\x12\x17\n" +
+	"\apaid_by\x18\a \x01(\tR\x06paidBy\x12=\n" +
+	"\fexpense_date\x18\b \x01(\v2\x1a.google.protobuf.TimestampR\vexpenseDate\x12\x16\n" +
+	"\x06status\x18\t \x01(\tR\x06status\x12)\n" +
+	"\x06payers\x18\n" +
+	" \x03(\v2\x11.finance.v1.ShareR\x06payers\x12)\n" +
+	"\x06splits\x18\v \x03(\v2\x11.finance.v1.ShareR\x06splits\x12\x12\n" +
+	"\x04tags\x18\f \x03(\tR\x04tags\x129\n" +
+	"\n" +
+	"created_at\x18\r \x01(\v2\x1a.google.protobuf.TimestampR\tcreatedAtB\v\n" +
+	"\t_category\"/\n" +
+	"\x12GetBalancesRequest\x12\x19\n" +
+	"\bgroup_id\x18\x01 \x01(\tR\agroupId\"V\n" +
+	"\aBalance\x12\x17\n" +
+	"\auser_id\x18\x01 \x01(\tR\x06userId\x12\x16\n" +
+	"\x06amount\x18\x02 \x01(\tR\x06amount\x12\x1a\n" +
+	"\bcurrency\x18\x03 \x01(\tR\bcurrency\"F\n" +
+	"\x13GetBalancesResponse\x12/\n" +
+	"\bbalances\x18\x01 \x03(\v2\x13.finance.v1.BalanceR\bbalances\"{\n" +
+	"\x0fPairwiseBalance\x12\x1b\n" +
+	"\tfrom_user\x18\x01 \x01(\tR\bfromUser\x12\x17\n" +
+	"\ato_user\x18\x02 \x01(\tR\x06toUser\x12\x16\n" +
+	"\x06amount\x18\x03 \x01(\tR\x06amount\x12\x1a\n" +
+	"\bcurrency\x18\x04 \x01(\tR\bcurrency\"V\n" +
+	"\x1bGetPairwiseBalancesResponse\x127\n" +
+	"\bbalances\x18\x01 \x03(\v2\x1b.finance.v1.PairwiseBalanceR\bbalances2c\n" +
+	"\vAuthService\x12T\n" +
+	"\rValidateToken\x12 .finance.v1.ValidateTokenRequest\x1a!.finance.v1.ValidateTokenResponse2X\n" +
+	"\x0eExpenseService\x12F\n" +
+	"\rCreateExpense\x12 .finance.v1.CreateExpenseRequest\x1a\x13.finance.v1.Expense2\xc0\x01\n" +
+	"\x0eBalanceService\x12N\n" +
+	"\vGetBalances\x12\x1e.finance.v1.GetBalancesRequest\x1a\x1f.finance.v1.GetBalancesResponse\x12^\n" +
+	"\x13GetPairwiseBalances\x12\x1e.finance.v1.GetBalancesRequest\x1a'.finance.v1.GetPairwiseBalancesResponseBLZJgithub.com/yanonymousV2/finance-manager-backend/internal/grpcapi/financev1b\x06proto3"
+
+var (
+	file_finance_v1_finance_proto_rawDescOnce sync.Once
+	file_finance_v1_finance_proto_rawDescData []byte
+)
+
+func file_finance_v1_finance_proto_rawDescGZIP() []byte {
+	file_finance_v1_finance_proto_rawDescOnce.Do(func() {
+		file_finance_v1_finance_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_finance_v1_finance_proto_rawDesc), len(file_finance_v1_finance_proto_rawDesc)))
+	})
+	return file_finance_v1_finance_proto_rawDescData
+}
+
+var file_finance_v1_finance_proto_msgTypes = make([]protoimpl.MessageInfo, 10)
+var file_finance_v1_finance_proto_goTypes = []any{
+	(*ValidateTokenRequest)(nil),        // 0: finance.v1.ValidateTokenRequest
+	(*ValidateTokenResponse)(nil),       // 1: finance.v1.ValidateTokenResponse
+	(*CreateExpenseRequest)(nil),        // 2: finance.v1.CreateExpenseRequest
+	(*Share)(nil),                       // 3: finance.v1.Share
+	(*Expense)(nil),                     // 4: finance.v1.Expense
+	(*GetBalancesRequest)(nil),          // 5: finance.v1.GetBalancesRequest
+	(*Balance)(nil),                     // 6: finance.v1.Balance
+	(*GetBalancesResponse)(nil),         // 7: finance.v1.GetBalancesResponse
+	(*PairwiseBalance)(nil),             // 8: finance.v1.PairwiseBalance
+	(*GetPairwiseBalancesResponse)(nil), // 9: finance.v1.GetPairwiseBalancesResponse
+	(*timestamppb.Timestamp)(nil),       // 10: google.protobuf.Timestamp
+}
+var file_finance_v1_finance_proto_depIdxs = []int32{
+	10, // 0: finance.v1.ValidateTokenResponse.expires_at:type_name -> google.protobuf.Timestamp
+	10, // 1: finance.v1.CreateExpenseRequest.expense_date:type_name -> google.protobuf.Timestamp
+	3,  // 2: finance.v1.CreateExpenseRequest.payers:type_name -> finance.v1.Share
+	3,  // 3: finance.v1.CreateExpenseRequest.splits:type_name -> finance.v1.Share
+	10, // 4: finance.v1.Expense.expense_date:type_name -> google.protobuf.Timestamp
+	3,  // 5: finance.v1.Expense.payers:type_name -> finance.v1.Share
+	3,  // 6: finance.v1.Expense.splits:type_name -> finance.v1.Share
+	10, // 7: finance.v1.Expense.created_at:type_name -> google.protobuf.Timestamp
+	6,  // 8: finance.v1.GetBalancesResponse.balances:type_name -> finance.v1.Balance
+	8,  // 9: finance.v1.GetPairwiseBalancesResponse.balances:type_name -> finance.v1.PairwiseBalance
+	0,  // 10: finance.v1.AuthService.ValidateToken:input_type -> finance.v1.ValidateTokenRequest
+	2,  // 11: finance.v1.ExpenseService.CreateExpense:input_type -> finance.v1.CreateExpenseRequest
+	5,  // 12: finance.v1.BalanceService.GetBalances:input_type -> finance.v1.GetBalancesRequest
+	5,  // 13: finance.v1.BalanceService.GetPairwiseBalances:input_type -> finance.v1.GetBalancesRequest
+	1,  // 14: finance.v1.AuthService.ValidateToken:output_type -> finance.v1.ValidateTokenResponse
+	4,  // 15: finance.v1.ExpenseService.CreateExpense:output_type -> finance.v1.Expense
+	7,  // 16: finance.v1.BalanceService.GetBalances:output_type -> finance.v1.GetBalancesResponse
+	9,  // 17: finance.v1.BalanceService.GetPairwiseBalances:output_type -> finance.v1.GetPairwiseBalancesResponse
+	14, // [14:18] is the sub-list for method output_type
+	10, // [10:14] is the sub-list for method input_type
+	10, // [10:10] is the sub-list for extension type_name
+	10, // [10:10] is the sub-list for extension extendee
+	0,  // [0:10] is the sub-list for field type_name
+}
+
+func init() { file_finance_v1_finance_proto_init() }
+func file_finance_v1_finance_proto_init() {
+	if File_finance_v1_finance_proto != nil {
+		return
+	}
+	file_finance_v1_finance_proto_msgTypes[2].OneofWrappers = []any{}
+	file_finance_v1_finance_proto_msgTypes[4].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_finance_v1_finance_proto_rawDesc), len(file_finance_v1_finance_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   10,
+			NumExtensions: 0,
+			NumServices:   3,
+		},
+		GoTypes:           file_finance_v1_finance_proto_goTypes,
+		DependencyIndexes: file_finance_v1_finance_proto_depIdxs,
+		MessageInfos:      file_finance_v1_finance_proto_msgTypes,
+	}.Build()
+	File_finance_v1_finance_proto = out.File
+	file_finance_v1_finance_proto_goTypes = nil
+	file_finance_v1_finance_proto_depIdxs = nil
+}
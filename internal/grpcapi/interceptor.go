@@ -0,0 +1,101 @@
+package grpcapi
+
+import (
+	"context"
+	"expvar"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/auth"
+	"github.com/yanonymousV2/finance-manager-backend/internal/grpcapi/financev1"
+	"github.com/yanonymousV2/finance-manager-backend/internal/requestid"
+)
+
+// Call counts by method and status code, and the total time spent per
+// method, served on the admin API's /debug/vars
+var (
+	calls     = expvar.NewMap("grpc_calls")
+	latencyMS = expvar.NewMap("grpc_latency_ms")
+)
+
+type userIDKey struct{}
+
+// requestIDInterceptor gives every call a request ID, reusing the caller's
+// x-request-id metadata when it looks sane, and sends it back in the
+// response header
+func requestIDInterceptor(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	var id string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if ids := md.Get(requestid.Header); len(ids) > 0 && requestid.Valid(ids[0]) {
+			id = ids[0]
+		}
+	}
+	if id == "" {
+		id = uuid.NewString()
+	}
+	grpc.SetHeader(ctx, metadata.Pairs(requestid.Header, id))
+	return handler(requestid.With(ctx, id), req)
+}
+
+// metricsInterceptor counts and logs each call once it's done, at error
+// level for server faults and warn for the caller's
+func metricsInterceptor(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	elapsed := float64(time.Since(start).Microseconds()) / 1000
+
+	code := status.Code(err)
+	calls.Add(info.FullMethod+" "+code.String(), 1)
+	latencyMS.AddFloat(info.FullMethod, elapsed)
+
+	attrs := []slog.Attr{
+		slog.String("method", info.FullMethod),
+		slog.String("code", code.String()),
+		slog.Float64("latency_ms", elapsed),
+	}
+
+	level := slog.LevelInfo
+	switch code {
+	case codes.OK:
+	case codes.Internal, codes.Unknown, codes.Unavailable, codes.DataLoss:
+		level = slog.LevelError
+	default:
+		level = slog.LevelWarn
+	}
+	requestid.Logger(ctx).LogAttrs(ctx, level, "grpc call", attrs...)
+	return resp, err
+}
+
+// authInterceptor checks the bearer token in the "authorization" metadata,
+// as middleware.JWTAuth does for HTTP, and puts the caller's user ID on the
+// context. AuthService calls validate tokens themselves and are let through.
+func authInterceptor(jwtSecret string) grpc.UnaryServerInterceptor {
+	authPrefix := "/" + financev1.AuthService_ServiceDesc.ServiceName + "/"
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if strings.HasPrefix(info.FullMethod, authPrefix) {
+			return handler(ctx, req)
+		}
+
+		md, _ := metadata.FromIncomingContext(ctx)
+		values := md.Get("authorization")
+		if len(values) == 0 {
+			return nil, status.Error(codes.Unauthenticated, "authorization metadata required")
+		}
+		token, ok := strings.CutPrefix(values[0], "Bearer ")
+		if !ok {
+			return nil, status.Error(codes.Unauthenticated, "bearer token required")
+		}
+		claims, err := auth.ParseToken(token, jwtSecret)
+		if err != nil {
+			return nil, status.Error(codes.Unauthenticated, "invalid token")
+		}
+		return handler(context.WithValue(ctx, userIDKey{}, claims.UserID), req)
+	}
+}
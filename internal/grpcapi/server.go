@@ -0,0 +1,112 @@
+// Package grpcapi serves the gRPC API in proto/finance/v1 for internal
+// services. It wraps the same services the REST handlers use, so both APIs
+// share one set of rules.
+package grpcapi
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/google/uuid"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/expense"
+	"github.com/yanonymousV2/finance-manager-backend/internal/group"
+	"github.com/yanonymousV2/finance-manager-backend/internal/grpcapi/financev1"
+	"github.com/yanonymousV2/finance-manager-backend/internal/helpers"
+	"github.com/yanonymousV2/finance-manager-backend/internal/validation"
+)
+
+// Deps is everything the services need
+type Deps struct {
+	JWTSecret string
+	Groups    *group.Service
+	Expenses  *expense.Service
+}
+
+// New returns a server with every service registered. Calls get a request
+// ID, are counted and logged, and must carry a valid token unless they're
+// to AuthService.
+func New(deps Deps) *grpc.Server {
+	srv := grpc.NewServer(grpc.ChainUnaryInterceptor(
+		requestIDInterceptor,
+		metricsInterceptor,
+		authInterceptor(deps.JWTSecret),
+	))
+	financev1.RegisterAuthServiceServer(srv, &authServer{jwtSecret: deps.JWTSecret})
+	financev1.RegisterExpenseServiceServer(srv, &expenseServer{expenses: deps.Expenses})
+	financev1.RegisterBalanceServiceServer(srv, &balanceServer{groups: deps.Groups})
+	return srv
+}
+
+// parseID parses a UUID field of a request
+func parseID(field, s string) (uuid.UUID, error) {
+	id, err := uuid.Parse(s)
+	if err != nil {
+		return uuid.Nil, status.Errorf(codes.InvalidArgument, "invalid %s", field)
+	}
+	return id, nil
+}
+
+// validate checks req against its validate tags, as validation.Bind does
+// for JSON bodies
+func validate(req any) error {
+	err := validation.Struct(req)
+	if err == nil {
+		return nil
+	}
+	fields := validation.Fields(err)
+	if len(fields) == 0 {
+		return status.Error(codes.InvalidArgument, "invalid request")
+	}
+	problems := make([]string, len(fields))
+	for i, f := range fields {
+		problems[i] = f.Field + " " + f.Message
+	}
+	return status.Error(codes.InvalidArgument, strings.Join(problems, "; "))
+}
+
+// statusError turns an error from a service into a gRPC status. message
+// stands in for errors that aren't the caller's fault.
+func statusError(err error, message string) error {
+	var reqErr *helpers.RequestError
+	switch {
+	case errors.As(err, &reqErr):
+		return status.Error(httpCode(reqErr.Status), reqErr.Message)
+	case errors.Is(err, group.ErrNotMember):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, group.ErrUserNotFound), errors.Is(err, group.ErrPlaceholder), errors.Is(err, group.ErrAlreadyMember):
+		return status.Error(codes.InvalidArgument, err.Error())
+	default:
+		return status.Error(codes.Internal, message)
+	}
+}
+
+// httpCode maps the HTTP status of a RequestError to a gRPC code
+func httpCode(httpStatus int) codes.Code {
+	switch httpStatus {
+	case 400:
+		return codes.InvalidArgument
+	case 401:
+		return codes.Unauthenticated
+	case 403:
+		return codes.PermissionDenied
+	case 404:
+		return codes.NotFound
+	case 409:
+		return codes.AlreadyExists
+	case 429:
+		return codes.ResourceExhausted
+	default:
+		return codes.Internal
+	}
+}
+
+// userID is the caller authenticated by authInterceptor
+func userID(ctx context.Context) uuid.UUID {
+	id, _ := ctx.Value(userIDKey{}).(uuid.UUID)
+	return id
+}
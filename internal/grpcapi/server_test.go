@@ -0,0 +1,211 @@
+package grpcapi
+
+import (
+	"context"
+	"expvar"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/auth"
+	"github.com/yanonymousV2/finance-manager-backend/internal/group"
+	"github.com/yanonymousV2/finance-manager-backend/internal/grpcapi/financev1"
+	"github.com/yanonymousV2/finance-manager-backend/internal/requestid"
+)
+
+const testSecret = "test-secret"
+
+// groupRepository keeps group members and balances in memory
+type groupRepository struct {
+	members  map[uuid.UUID][]uuid.UUID
+	balances map[uuid.UUID]map[uuid.UUID]decimal.Decimal
+	debts    map[uuid.UUID][]group.PairwiseBalance
+}
+
+func (r *groupRepository) Create(context.Context, string, string, uuid.UUID) (group.Group, error) {
+	return group.Group{}, nil
+}
+
+func (r *groupRepository) AddMember(context.Context, uuid.UUID, uuid.UUID, uuid.UUID) error {
+	return nil
+}
+
+func (r *groupRepository) IsMember(_ context.Context, groupID, userID uuid.UUID) (bool, error) {
+	for _, id := range r.members[groupID] {
+		if id == userID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *groupRepository) UserExists(context.Context, uuid.UUID) (bool, error) {
+	return true, nil
+}
+
+func (r *groupRepository) IsPlaceholder(context.Context, uuid.UUID) (bool, error) {
+	return false, nil
+}
+
+func (r *groupRepository) Currency(context.Context, uuid.UUID) (string, error) {
+	return "USD", nil
+}
+
+func (r *groupRepository) Balances(_ context.Context, groupID uuid.UUID) (map[uuid.UUID]decimal.Decimal, error) {
+	return r.balances[groupID], nil
+}
+
+func (r *groupRepository) Debts(_ context.Context, groupID uuid.UUID) ([]group.PairwiseBalance, error) {
+	return r.debts[groupID], nil
+}
+
+// dial serves deps over an in-memory listener and returns a client
+// connection to it
+func dial(t *testing.T, deps Deps) *grpc.ClientConn {
+	t.Helper()
+	lis := bufconn.Listen(1 << 20)
+	srv := New(deps)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func token(t *testing.T, userID uuid.UUID) string {
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, auth.Claims{
+		UserID: userID,
+		Email:  "test@example.com",
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	}).SignedString([]byte(testSecret))
+	require.NoError(t, err)
+	return signed
+}
+
+// withToken adds userID's bearer token to the outgoing metadata
+func withToken(t *testing.T, userID uuid.UUID) context.Context {
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token(t, userID))
+}
+
+func TestValidateToken(t *testing.T) {
+	client := financev1.NewAuthServiceClient(dial(t, Deps{JWTSecret: testSecret}))
+	userID := uuid.New()
+
+	// No bearer token needed to validate one
+	resp, err := client.ValidateToken(context.Background(), &financev1.ValidateTokenRequest{Token: token(t, userID)})
+	require.NoError(t, err)
+	assert.Equal(t, userID.String(), resp.GetUserId())
+	assert.Equal(t, "test@example.com", resp.GetEmail())
+	assert.WithinDuration(t, time.Now().Add(time.Hour), resp.GetExpiresAt().AsTime(), time.Minute)
+
+	_, err = client.ValidateToken(context.Background(), &financev1.ValidateTokenRequest{Token: "nope"})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestAuthInterceptor(t *testing.T) {
+	repo := &groupRepository{}
+	client := financev1.NewBalanceServiceClient(dial(t, Deps{JWTSecret: testSecret, Groups: group.NewService(repo)}))
+	req := &financev1.GetBalancesRequest{GroupId: uuid.NewString()}
+
+	tests := []struct {
+		name string
+		ctx  context.Context
+	}{
+		{"no metadata", context.Background()},
+		{"not a bearer token", metadata.AppendToOutgoingContext(context.Background(), "authorization", "Basic abc")},
+		{"invalid token", metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer nope")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := client.GetBalances(tt.ctx, req)
+			assert.Equal(t, codes.Unauthenticated, status.Code(err))
+		})
+	}
+}
+
+func TestGetBalances(t *testing.T) {
+	userA, userB, outsider := uuid.New(), uuid.New(), uuid.New()
+	groupID := uuid.New()
+	repo := &groupRepository{
+		members: map[uuid.UUID][]uuid.UUID{groupID: {userA, userB}},
+		balances: map[uuid.UUID]map[uuid.UUID]decimal.Decimal{groupID: {
+			userA: decimal.NewFromInt(25),
+			userB: decimal.NewFromInt(-25),
+		}},
+		debts: map[uuid.UUID][]group.PairwiseBalance{groupID: {
+			{FromUser: userB, ToUser: userA, Amount: decimal.NewFromInt(40)},
+			{FromUser: userA, ToUser: userB, Amount: decimal.NewFromInt(15)},
+		}},
+	}
+	client := financev1.NewBalanceServiceClient(dial(t, Deps{JWTSecret: testSecret, Groups: group.NewService(repo)}))
+
+	var header metadata.MD
+	resp, err := client.GetBalances(withToken(t, userA), &financev1.GetBalancesRequest{GroupId: groupID.String()},
+		grpc.Header(&header))
+	require.NoError(t, err)
+	amounts := map[string]string{}
+	for _, b := range resp.GetBalances() {
+		assert.Equal(t, "USD", b.GetCurrency())
+		amounts[b.GetUserId()] = b.GetAmount()
+	}
+	assert.Equal(t, map[string]string{userA.String(): "25", userB.String(): "-25"}, amounts)
+	assert.NotEmpty(t, header.Get(requestid.Header), "every call gets a request ID")
+
+	pairwise, err := client.GetPairwiseBalances(withToken(t, userB), &financev1.GetBalancesRequest{GroupId: groupID.String()})
+	require.NoError(t, err)
+	require.Len(t, pairwise.GetBalances(), 1)
+	assert.Equal(t, userB.String(), pairwise.GetBalances()[0].GetFromUser())
+	assert.Equal(t, userA.String(), pairwise.GetBalances()[0].GetToUser())
+	assert.Equal(t, "25", pairwise.GetBalances()[0].GetAmount())
+
+	_, err = client.GetBalances(withToken(t, outsider), &financev1.GetBalancesRequest{GroupId: groupID.String()})
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	_, err = client.GetBalances(withToken(t, userA), &financev1.GetBalancesRequest{GroupId: "nope"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestRequestIDInterceptor(t *testing.T) {
+	client := financev1.NewAuthServiceClient(dial(t, Deps{JWTSecret: testSecret}))
+
+	var header metadata.MD
+	ctx := metadata.AppendToOutgoingContext(context.Background(), requestid.Header, "caller-id-1")
+	_, err := client.ValidateToken(ctx, &financev1.ValidateTokenRequest{Token: token(t, uuid.New())}, grpc.Header(&header))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"caller-id-1"}, header.Get(requestid.Header))
+}
+
+func TestMetricsInterceptor(t *testing.T) {
+	client := financev1.NewAuthServiceClient(dial(t, Deps{JWTSecret: testSecret}))
+	key := financev1.AuthService_ValidateToken_FullMethodName + " " + codes.Unauthenticated.String()
+	before := int64(0)
+	if v := calls.Get(key); v != nil {
+		before = v.(*expvar.Int).Value()
+	}
+
+	_, err := client.ValidateToken(context.Background(), &financev1.ValidateTokenRequest{Token: "nope"})
+	require.Error(t, err)
+
+	v := calls.Get(key)
+	require.NotNil(t, v)
+	assert.Equal(t, before+1, v.(*expvar.Int).Value())
+	assert.NotNil(t, latencyMS.Get(financev1.AuthService_ValidateToken_FullMethodName))
+}
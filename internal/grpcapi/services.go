@@ -0,0 +1,155 @@
+package grpcapi
+
+import (
+	"context"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/auth"
+	"github.com/yanonymousV2/finance-manager-backend/internal/expense"
+	"github.com/yanonymousV2/finance-manager-backend/internal/group"
+	"github.com/yanonymousV2/finance-manager-backend/internal/grpcapi/financev1"
+)
+
+type authServer struct {
+	financev1.UnimplementedAuthServiceServer
+	jwtSecret string
+}
+
+func (s *authServer) ValidateToken(_ context.Context, req *financev1.ValidateTokenRequest) (*financev1.ValidateTokenResponse, error) {
+	claims, err := auth.ParseToken(req.GetToken(), s.jwtSecret)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "invalid token")
+	}
+
+	resp := &financev1.ValidateTokenResponse{UserId: claims.UserID.String(), Email: claims.Email}
+	if claims.ExpiresAt != nil {
+		resp.ExpiresAt = timestamppb.New(claims.ExpiresAt.Time)
+	}
+	return resp, nil
+}
+
+type expenseServer struct {
+	financev1.UnimplementedExpenseServiceServer
+	expenses *expense.Service
+}
+
+func (s *expenseServer) CreateExpense(ctx context.Context, req *financev1.CreateExpenseRequest) (*financev1.Expense, error) {
+	groupID, err := parseID("group_id", req.GetGroupId())
+	if err != nil {
+		return nil, err
+	}
+
+	create := expense.CreateExpenseRequest{
+		GroupID:     groupID,
+		Description: req.GetDescription(),
+		TotalAmount: req.GetTotalAmount(),
+		Currency:    req.GetCurrency(),
+		Category:    req.Category,
+		SplitMode:   req.GetSplitMode(),
+		Tags:        req.GetTags(),
+	}
+	if req.ExpenseDate != nil {
+		date := req.ExpenseDate.AsTime()
+		create.ExpenseDate = &date
+	}
+	for _, p := range req.GetParticipants() {
+		id, err := parseID("participants", p)
+		if err != nil {
+			return nil, err
+		}
+		create.Participants = append(create.Participants, id)
+	}
+	for _, p := range req.GetPayers() {
+		id, err := parseID("payers.user_id", p.GetUserId())
+		if err != nil {
+			return nil, err
+		}
+		create.Payers = append(create.Payers, expense.CreateExpensePayerRequest{UserID: id, Amount: p.GetAmount()})
+	}
+	for _, sp := range req.GetSplits() {
+		id, err := parseID("splits.user_id", sp.GetUserId())
+		if err != nil {
+			return nil, err
+		}
+		create.Splits = append(create.Splits, expense.CreateExpenseSplitRequest{UserID: id, Amount: sp.GetAmount()})
+	}
+	if err := validate(create); err != nil {
+		return nil, err
+	}
+
+	exp, err := s.expenses.Create(ctx, userID(ctx), create)
+	if err != nil {
+		return nil, statusError(err, "failed to create expense")
+	}
+	return expenseMessage(exp), nil
+}
+
+// expenseMessage converts a created expense to its protobuf form
+func expenseMessage(exp expense.Expense) *financev1.Expense {
+	msg := &financev1.Expense{
+		Id:          exp.ID.String(),
+		GroupId:     exp.GroupID.String(),
+		Description: exp.Description,
+		TotalAmount: exp.TotalAmount.String(),
+		Currency:    exp.Currency,
+		Category:    exp.Category,
+		PaidBy:      exp.PaidBy.String(),
+		ExpenseDate: timestamppb.New(exp.ExpenseDate),
+		Status:      exp.Status,
+		Tags:        exp.Tags,
+		CreatedAt:   timestamppb.New(exp.CreatedAt),
+	}
+	for _, p := range exp.Payers {
+		msg.Payers = append(msg.Payers, &financev1.Share{UserId: p.UserID.String(), Amount: p.Amount.String()})
+	}
+	for _, sp := range exp.Splits {
+		msg.Splits = append(msg.Splits, &financev1.Share{UserId: sp.UserID.String(), Amount: sp.Amount.String()})
+	}
+	return msg
+}
+
+type balanceServer struct {
+	financev1.UnimplementedBalanceServiceServer
+	groups *group.Service
+}
+
+func (s *balanceServer) GetBalances(ctx context.Context, req *financev1.GetBalancesRequest) (*financev1.GetBalancesResponse, error) {
+	groupID, err := parseID("group_id", req.GetGroupId())
+	if err != nil {
+		return nil, err
+	}
+
+	balances, err := s.groups.Balances(ctx, userID(ctx), groupID)
+	if err != nil {
+		return nil, statusError(err, "failed to calculate balances")
+	}
+
+	resp := &financev1.GetBalancesResponse{Balances: make([]*financev1.Balance, len(balances))}
+	for i, b := range balances {
+		resp.Balances[i] = &financev1.Balance{UserId: b.UserID.String(), Amount: b.Amount.String(), Currency: b.Currency}
+	}
+	return resp, nil
+}
+
+func (s *balanceServer) GetPairwiseBalances(ctx context.Context, req *financev1.GetBalancesRequest) (*financev1.GetPairwiseBalancesResponse, error) {
+	groupID, err := parseID("group_id", req.GetGroupId())
+	if err != nil {
+		return nil, err
+	}
+
+	balances, err := s.groups.PairwiseBalances(ctx, userID(ctx), groupID)
+	if err != nil {
+		return nil, statusError(err, "failed to get pairwise balances")
+	}
+
+	resp := &financev1.GetPairwiseBalancesResponse{Balances: make([]*financev1.PairwiseBalance, len(balances))}
+	for i, b := range balances {
+		resp.Balances[i] = &financev1.PairwiseBalance{
+			FromUser: b.FromUser.String(), ToUser: b.ToUser.String(), Amount: b.Amount.String(), Currency: b.Currency,
+		}
+	}
+	return resp, nil
+}
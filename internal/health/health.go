@@ -0,0 +1,164 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Check reports whether one dependency is usable. detail, if not nil, is
+// included in the readiness response either way.
+type Check func(ctx context.Context) (detail any, err error)
+
+// Result is the outcome of one check
+type Result struct {
+	Status    string  `json:"status"`
+	Error     string  `json:"error,omitempty"`
+	LatencyMS float64 `json:"latency_ms"`
+	Detail    any     `json:"detail,omitempty"`
+}
+
+const (
+	StatusOK      = "ok"
+	StatusFailing = "failing"
+)
+
+// Checker runs named checks for the readiness probe
+type Checker struct {
+	timeout time.Duration
+	names   []string
+	checks  map[string]Check
+}
+
+// NewChecker returns a Checker that gives each check at most timeout
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{timeout: timeout, checks: map[string]Check{}}
+}
+
+// Add registers check under name
+func (c *Checker) Add(name string, check Check) {
+	if _, ok := c.checks[name]; !ok {
+		c.names = append(c.names, name)
+	}
+	c.checks[name] = check
+}
+
+// Run runs every check at once and reports whether all passed
+func (c *Checker) Run(ctx context.Context) (bool, map[string]Result) {
+	results := make([]Result, len(c.names))
+	var wg sync.WaitGroup
+	for i, name := range c.names {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			results[i] = c.run(ctx, check)
+		}(i, c.checks[name])
+	}
+	wg.Wait()
+
+	ready := true
+	byName := make(map[string]Result, len(c.names))
+	for i, name := range c.names {
+		byName[name] = results[i]
+		if results[i].Status != StatusOK {
+			ready = false
+		}
+	}
+	return ready, byName
+}
+
+func (c *Checker) run(ctx context.Context, check Check) Result {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	detail, err := check(ctx)
+	result := Result{
+		Status:    StatusOK,
+		LatencyMS: float64(time.Since(start).Microseconds()) / 1000,
+		Detail:    detail,
+	}
+	if err != nil {
+		result.Status = StatusFailing
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// Heartbeat tracks a background worker that runs every interval. It counts
+// as stalled when it hasn't finished a run for two intervals.
+type Heartbeat struct {
+	interval time.Duration
+	now      func() time.Time
+
+	mu      sync.Mutex
+	started time.Time
+	last    time.Time
+	lastErr error
+}
+
+// NewHeartbeat returns a Heartbeat for a worker starting now
+func NewHeartbeat(interval time.Duration) *Heartbeat {
+	h := &Heartbeat{interval: interval, now: time.Now}
+	h.started = h.now()
+	return h
+}
+
+// Beat records a finished run and its error, if any
+func (h *Heartbeat) Beat(err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.last = h.now()
+	h.lastErr = err
+}
+
+// HeartbeatDetail is what a Heartbeat reports to the readiness probe
+type HeartbeatDetail struct {
+	LastRun   *time.Time `json:"last_run"`
+	LastError string     `json:"last_error,omitempty"`
+}
+
+// Check fails when the worker has stalled. A run that ended in an error
+// doesn't fail it, since the worker is still going and the cause (usually
+// the database) has its own check.
+func (h *Heartbeat) Check(context.Context) (any, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var detail HeartbeatDetail
+	since := h.started
+	if !h.last.IsZero() {
+		last := h.last
+		detail.LastRun = &last
+		since = last
+	}
+	if h.lastErr != nil {
+		detail.LastError = h.lastErr.Error()
+	}
+
+	if stalled := h.now().Sub(since); stalled > 2*h.interval {
+		return detail, fmt.Errorf("no run finished in %v", stalled.Round(time.Second))
+	}
+	return detail, nil
+}
+
+// Draining fails readiness once shutdown has begun, so load balancers stop
+// sending requests while the ones in flight finish
+type Draining struct {
+	started atomic.Bool
+}
+
+// Start marks the server as shutting down
+func (d *Draining) Start() {
+	d.started.Store(true)
+}
+
+func (d *Draining) Check(context.Context) (any, error) {
+	if d.started.Load() {
+		return nil, errors.New("shutting down")
+	}
+	return nil, nil
+}
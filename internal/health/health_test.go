@@ -0,0 +1,80 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChecker(t *testing.T) {
+	checker := NewChecker(50 * time.Millisecond)
+	checker.Add("database", func(context.Context) (any, error) { return nil, nil })
+	checker.Add("migrations", func(context.Context) (any, error) { return map[string]int{"version": 35}, nil })
+
+	ready, results := checker.Run(context.Background())
+	assert.True(t, ready)
+	assert.Equal(t, StatusOK, results["database"].Status)
+	assert.Equal(t, map[string]int{"version": 35}, results["migrations"].Detail)
+
+	// A check that hangs is cut off by the timeout
+	checker.Add("slow", func(ctx context.Context) (any, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	})
+	checker.Add("broken", func(context.Context) (any, error) { return nil, errors.New("connection refused") })
+
+	ready, results = checker.Run(context.Background())
+	assert.False(t, ready)
+	assert.Len(t, results, 4)
+	assert.Equal(t, StatusOK, results["database"].Status)
+	assert.Equal(t, Result{Status: StatusFailing, Error: "context deadline exceeded"}, withoutLatency(results["slow"]))
+	assert.Equal(t, Result{Status: StatusFailing, Error: "connection refused"}, withoutLatency(results["broken"]))
+}
+
+func withoutLatency(r Result) Result {
+	r.LatencyMS = 0
+	return r
+}
+
+func TestHeartbeat(t *testing.T) {
+	now := time.Date(2026, 3, 14, 9, 0, 0, 0, time.UTC)
+	h := &Heartbeat{interval: time.Hour, now: func() time.Time { return now }}
+	h.started = now
+
+	// Not stalled while the first run is still due
+	now = now.Add(90 * time.Minute)
+	detail, err := h.Check(context.Background())
+	require.NoError(t, err)
+	assert.Nil(t, detail.(HeartbeatDetail).LastRun)
+
+	// A failed run still counts as a beat
+	h.Beat(errors.New("smtp: timeout"))
+	lastRun := now
+	now = now.Add(119 * time.Minute)
+	detail, err = h.Check(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, HeartbeatDetail{LastRun: &lastRun, LastError: "smtp: timeout"}, detail)
+
+	now = now.Add(2 * time.Minute)
+	_, err = h.Check(context.Background())
+	assert.EqualError(t, err, "no run finished in 2h1m0s")
+
+	h.Beat(nil)
+	detail, err = h.Check(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, detail.(HeartbeatDetail).LastError)
+}
+
+func TestDraining(t *testing.T) {
+	var d Draining
+	_, err := d.Check(context.Background())
+	assert.NoError(t, err)
+
+	d.Start()
+	_, err = d.Check(context.Background())
+	assert.EqualError(t, err, "shutting down")
+}
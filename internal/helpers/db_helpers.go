@@ -4,14 +4,23 @@ import (
 	"context"
 
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
 	"github.com/yanonymousV2/finance-manager-backend/internal/db"
 )
 
-// IsGroupMember checks if a user is a member of a group
+// Querier is satisfied by both the pool and a transaction
+type Querier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// IsGroupMember checks if a user is a member of a group. Nobody is a member
+// of a deleted group, so every membership check hides it.
 func IsGroupMember(ctx context.Context, db *db.DB, groupID, userID uuid.UUID) (bool, error) {
 	var isMember bool
 	err := db.Pool.QueryRow(ctx,
-		"SELECT EXISTS(SELECT 1 FROM group_members WHERE group_id = $1 AND user_id = $2)",
+		`SELECT EXISTS(SELECT 1 FROM group_members gm JOIN groups g ON g.id = gm.group_id
+		 WHERE gm.group_id = $1 AND gm.user_id = $2 AND g.deleted_at IS NULL)`,
 		groupID, userID).Scan(&isMember)
 	return isMember, err
 }
@@ -24,3 +33,49 @@ func UserExists(ctx context.Context, db *db.DB, userID uuid.UUID) (bool, error)
 		userID).Scan(&exists)
 	return exists, err
 }
+
+// GetGroupCurrency returns the default currency of a group
+func GetGroupCurrency(ctx context.Context, db *db.DB, groupID uuid.UUID) (string, error) {
+	var currency string
+	err := db.Pool.QueryRow(ctx,
+		"SELECT currency FROM groups WHERE id = $1 AND deleted_at IS NULL",
+		groupID).Scan(&currency)
+	return currency, err
+}
+
+// GetUserCurrency returns the default currency of a user
+func GetUserCurrency(ctx context.Context, db *db.DB, userID uuid.UUID) (string, error) {
+	var currency string
+	err := db.Pool.QueryRow(ctx,
+		"SELECT default_currency FROM users WHERE id = $1",
+		userID).Scan(&currency)
+	return currency, err
+}
+
+// IsPlaceholder checks if a user is a placeholder without login credentials
+func IsPlaceholder(ctx context.Context, db *db.DB, userID uuid.UUID) (bool, error) {
+	var isPlaceholder bool
+	err := db.Pool.QueryRow(ctx,
+		"SELECT is_placeholder FROM users WHERE id = $1",
+		userID).Scan(&isPlaceholder)
+	return isPlaceholder, err
+}
+
+// RequiresSettlementConfirmation checks if a group's settlements must be
+// confirmed by their recipient before they count
+func RequiresSettlementConfirmation(ctx context.Context, db *db.DB, groupID uuid.UUID) (bool, error) {
+	var required bool
+	err := db.Pool.QueryRow(ctx,
+		"SELECT EXISTS(SELECT 1 FROM group_settings WHERE group_id = $1 AND require_settlement_confirmation)",
+		groupID).Scan(&required)
+	return required, err
+}
+
+// GetCategoryOwner returns who owns a category and whether it's archived.
+// Deleted categories aren't found.
+func GetCategoryOwner(ctx context.Context, q Querier, categoryID uuid.UUID) (ownerID uuid.UUID, archived bool, err error) {
+	err = q.QueryRow(ctx,
+		"SELECT user_id, archived FROM expense_categories WHERE id = $1 AND deleted_at IS NULL",
+		categoryID).Scan(&ownerID, &archived)
+	return ownerID, archived, err
+}
@@ -0,0 +1,33 @@
+package helpers
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RequestError is an error with the response it should produce, for code
+// that fails away from the handler, such as inside a transaction
+type RequestError struct {
+	Status  int
+	Message string
+}
+
+func (e *RequestError) Error() string {
+	return e.Message
+}
+
+func NewRequestError(status int, message string) error {
+	return &RequestError{Status: status, Message: message}
+}
+
+// RespondError writes err's response if it's a RequestError, otherwise a
+// 500 with message
+func RespondError(c *gin.Context, err error, message string) {
+	var reqErr *RequestError
+	if errors.As(err, &reqErr) {
+		c.JSON(reqErr.Status, gin.H{"error": reqErr.Message})
+		return
+	}
+	c.JSON(500, gin.H{"error": message})
+}
@@ -0,0 +1,34 @@
+package helpers
+
+import (
+	"errors"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRespondError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	respond := func(err error) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		RespondError(c, err, "failed to save")
+		return w
+	}
+
+	w := respond(NewRequestError(409, "already reviewed"))
+	assert.Equal(t, 409, w.Code)
+	assert.JSONEq(t, `{"error": "already reviewed"}`, w.Body.String())
+
+	w = respond(fmt.Errorf("batch: %w", NewRequestError(400, "bad allocation")))
+	assert.Equal(t, 400, w.Code)
+	assert.JSONEq(t, `{"error": "bad allocation"}`, w.Body.String())
+
+	w = respond(errors.New("connection reset"))
+	assert.Equal(t, 500, w.Code)
+	assert.JSONEq(t, `{"error": "failed to save"}`, w.Body.String())
+}
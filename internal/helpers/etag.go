@@ -0,0 +1,37 @@
+package helpers
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ETag is a weak validator for a set of rows built from how many there are
+// and when the newest one changed. Deleting a row changes the count and
+// adding or editing one moves lastUpdated, so either changes the tag.
+func ETag(count int, lastUpdated *time.Time) string {
+	var nanos int64
+	if lastUpdated != nil {
+		nanos = lastUpdated.UnixNano()
+	}
+	return fmt.Sprintf(`W/"%d-%x"`, count, nanos)
+}
+
+// NotModified sets etag on the response and, when the request's
+// If-None-Match already has it, responds 304 and returns true
+func NotModified(c *gin.Context, etag string) bool {
+	c.Header("ETag", etag)
+	// Clients may keep the response but must check it's current before use
+	c.Header("Cache-Control", "private, no-cache")
+
+	for _, candidate := range strings.Split(c.GetHeader("If-None-Match"), ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == strings.TrimPrefix(etag, "W/") {
+			c.Status(304)
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,54 @@
+package helpers
+
+import (
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestETag(t *testing.T) {
+	now := time.Date(2026, 3, 14, 9, 30, 0, 0, time.UTC)
+	later := now.Add(time.Microsecond)
+
+	assert.Equal(t, `W/"0-0"`, ETag(0, nil))
+	assert.NotEqual(t, ETag(3, &now), ETag(2, &now))
+	assert.NotEqual(t, ETag(3, &now), ETag(3, &later))
+	assert.Equal(t, ETag(3, &now), ETag(3, &now))
+}
+
+func TestNotModified(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	etag := `W/"3-abc"`
+
+	check := func(ifNoneMatch string) (bool, *httptest.ResponseRecorder) {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest("GET", "/", nil)
+		if ifNoneMatch != "" {
+			c.Request.Header.Set("If-None-Match", ifNoneMatch)
+		}
+		matched := NotModified(c, etag)
+		c.Writer.WriteHeaderNow()
+		return matched, w
+	}
+
+	matched, w := check("")
+	assert.False(t, matched)
+	assert.Equal(t, etag, w.Header().Get("ETag"))
+	assert.Equal(t, "private, no-cache", w.Header().Get("Cache-Control"))
+
+	matched, w = check(etag)
+	assert.True(t, matched)
+	assert.Equal(t, 304, w.Code)
+
+	// Weak comparison ignores the W/ prefix, and lists are searched
+	matched, _ = check(`"other", "3-abc"`)
+	assert.True(t, matched)
+	matched, _ = check("*")
+	assert.True(t, matched)
+	matched, _ = check(`W/"4-abc"`)
+	assert.False(t, matched)
+}
@@ -0,0 +1,8 @@
+package helpers
+
+import "strings"
+
+// EscapeLike escapes the wildcard characters of a LIKE pattern
+func EscapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
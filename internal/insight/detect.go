@@ -0,0 +1,300 @@
+package insight
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// Insight types
+const (
+	TypeCategorySpike = "category_spike"
+	TypeNewRecurring  = "new_recurring_charge"
+	TypeDuplicate     = "possible_duplicate"
+)
+
+const (
+	// A category spikes when this month's spending reaches spikeFactor times
+	// its average over the spikeMonths full months before
+	spikeFactor = 2
+	spikeMonths = 3
+	// historyMonths is how far back expenses are looked at; a recurring
+	// charge is new when its merchant didn't charge anything before last
+	// month in this window
+	historyMonths = 6
+	// A new recurring charge repeats last month's amount within
+	// recurringTolerance and is at least largeChargeFactor times the
+	// user's median expense
+	recurringTolerance = 0.10
+	largeChargeFactor  = 3
+	// Duplicates are same-amount charges at the same place within
+	// duplicateWindow of each other, in the last duplicateDays days
+	duplicateWindow = 48 * time.Hour
+	duplicateDays   = 30
+)
+
+// expense is what detection needs of a personal expense. MerchantKey
+// identifies the merchant (its id, or the raw payee text) and is empty when
+// there is none; Mirrored marks shares of group expenses.
+type expense struct {
+	ID           uuid.UUID
+	Amount       decimal.Decimal
+	Date         time.Time
+	CategoryID   *uuid.UUID
+	CategoryName *string
+	MerchantKey  string
+	Merchant     string
+	Description  string
+	Mirrored     bool
+}
+
+// Insight is something unusual in a user's spending. ID is derived from what
+// it is about, so it stays the same between requests.
+type Insight struct {
+	ID      uuid.UUID       `json:"id"`
+	Type    string          `json:"type"`
+	Message string          `json:"message"`
+	Amount  decimal.Decimal `json:"amount"`
+	Data    map[string]any  `json:"data"`
+	Status  string          `json:"status"`
+}
+
+func newInsight(kind, fingerprint, message string, amount decimal.Decimal, data map[string]any) Insight {
+	return Insight{
+		ID:      uuid.NewSHA1(uuid.NameSpaceOID, []byte(kind+":"+fingerprint)),
+		Type:    kind,
+		Message: message,
+		Amount:  amount,
+		Data:    data,
+		Status:  StatusNew,
+	}
+}
+
+func monthStart(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// detect finds insights in expenses, which cover historyMonths months before
+// the one containing now, in currency
+func detect(expenses []expense, currency string, now time.Time) []Insight {
+	insights := categorySpikes(expenses, currency, now)
+	insights = append(insights, newRecurringCharges(expenses, currency, now)...)
+	insights = append(insights, duplicates(expenses, currency, now)...)
+	return insights
+}
+
+// categorySpikes flags categories whose spending this month is at least
+// spikeFactor times their average over the spikeMonths months before
+func categorySpikes(expenses []expense, currency string, now time.Time) []Insight {
+	current := monthStart(now)
+	trailingStart := current.AddDate(0, -spikeMonths, 0)
+
+	type totals struct {
+		id       *uuid.UUID
+		name     string
+		current  decimal.Decimal
+		trailing decimal.Decimal
+	}
+	// Uncategorized spending is kept under uuid.Nil
+	byCategory := map[uuid.UUID]*totals{}
+	for _, e := range expenses {
+		if e.Date.Before(trailingStart) {
+			continue
+		}
+		key := uuid.Nil
+		if e.CategoryID != nil {
+			key = *e.CategoryID
+		}
+		t, ok := byCategory[key]
+		if !ok {
+			t = &totals{id: e.CategoryID, name: "Uncategorized"}
+			if e.CategoryName != nil {
+				t.name = *e.CategoryName
+			}
+			byCategory[key] = t
+		}
+		if e.Date.Before(current) {
+			t.trailing = t.trailing.Add(e.Amount)
+		} else {
+			t.current = t.current.Add(e.Amount)
+		}
+	}
+
+	var spikes []*totals
+	for _, t := range byCategory {
+		average := t.trailing.Div(decimal.NewFromInt(spikeMonths))
+		if average.IsPositive() && t.current.GreaterThanOrEqual(average.Mul(decimal.NewFromInt(spikeFactor))) {
+			spikes = append(spikes, t)
+		}
+	}
+	sort.Slice(spikes, func(i, j int) bool {
+		if !spikes[i].current.Equal(spikes[j].current) {
+			return spikes[i].current.GreaterThan(spikes[j].current)
+		}
+		return spikes[i].name < spikes[j].name
+	})
+
+	month := current.Format("2006-01")
+	insights := []Insight{}
+	for _, t := range spikes {
+		average := t.trailing.Div(decimal.NewFromInt(spikeMonths)).Round(2)
+		ratio := t.current.Div(average).Round(1)
+		key := uuid.Nil
+		if t.id != nil {
+			key = *t.id
+		}
+		insights = append(insights, newInsight(TypeCategorySpike, key.String()+":"+month,
+			fmt.Sprintf("%s spending this month is %sx its %d-month average (%s vs %s %s)",
+				t.name, ratio, spikeMonths, t.current.StringFixed(2), average.StringFixed(2), currency),
+			t.current,
+			map[string]any{
+				"category_id":      t.id,
+				"category_name":    t.name,
+				"month":            month,
+				"trailing_average": average,
+				"ratio":            ratio,
+			}))
+	}
+	return insights
+}
+
+// median returns the middle value of amounts, which must be sorted
+func median(amounts []decimal.Decimal) decimal.Decimal {
+	if len(amounts) == 0 {
+		return decimal.Zero
+	}
+	mid := len(amounts) / 2
+	if len(amounts)%2 == 1 {
+		return amounts[mid]
+	}
+	return amounts[mid-1].Add(amounts[mid]).Div(decimal.NewFromInt(2))
+}
+
+// newRecurringCharges flags merchants that charged a similar, large amount
+// last month and this month without having charged anything before
+func newRecurringCharges(expenses []expense, currency string, now time.Time) []Insight {
+	current := monthStart(now)
+	previous := current.AddDate(0, -1, 0)
+
+	var amounts []decimal.Decimal
+	byMerchant := map[string][]expense{}
+	for _, e := range expenses {
+		if e.Mirrored {
+			continue
+		}
+		amounts = append(amounts, e.Amount)
+		if e.MerchantKey != "" {
+			byMerchant[e.MerchantKey] = append(byMerchant[e.MerchantKey], e)
+		}
+	}
+	sort.Slice(amounts, func(i, j int) bool { return amounts[i].LessThan(amounts[j]) })
+	large := median(amounts).Mul(decimal.NewFromInt(largeChargeFactor))
+	if !large.IsPositive() {
+		return []Insight{}
+	}
+
+	keys := make([]string, 0, len(byMerchant))
+	for key := range byMerchant {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	insights := []Insight{}
+	for _, key := range keys {
+		// The latest charge in each of the two months, and none before
+		var last, this *expense
+		seenBefore := false
+		for i, e := range byMerchant[key] {
+			switch {
+			case e.Date.Before(previous):
+				seenBefore = true
+			case e.Date.Before(current):
+				if last == nil || e.Date.After(last.Date) {
+					last = &byMerchant[key][i]
+				}
+			default:
+				if this == nil || e.Date.After(this.Date) {
+					this = &byMerchant[key][i]
+				}
+			}
+		}
+		if seenBefore || last == nil || this == nil || this.Amount.LessThan(large) {
+			continue
+		}
+		difference := this.Amount.Sub(last.Amount).Abs().Div(last.Amount)
+		if difference.GreaterThan(decimal.NewFromFloat(recurringTolerance)) {
+			continue
+		}
+
+		insights = append(insights, newInsight(TypeNewRecurring, key+":"+previous.Format("2006-01"),
+			fmt.Sprintf("New recurring charge from %s: %s %s, also charged last month",
+				this.Merchant, this.Amount.StringFixed(2), currency),
+			this.Amount,
+			map[string]any{
+				"merchant":      this.Merchant,
+				"first_charged": last.Date,
+				"expense_ids":   []uuid.UUID{last.ID, this.ID},
+			}))
+	}
+	return insights
+}
+
+// duplicates flags pairs of recent expenses with the same amount at the same
+// merchant (or with the same description) close together in time
+func duplicates(expenses []expense, currency string, now time.Time) []Insight {
+	since := now.AddDate(0, 0, -duplicateDays)
+
+	groups := map[string][]expense{}
+	for _, e := range expenses {
+		if e.Mirrored || e.Date.Before(since) {
+			continue
+		}
+		identity := e.MerchantKey
+		if identity == "" {
+			description := strings.ToLower(strings.TrimSpace(e.Description))
+			if description == "" {
+				continue
+			}
+			identity = "description:" + description
+		}
+		key := identity + "|" + e.Amount.String()
+		groups[key] = append(groups[key], e)
+	}
+
+	keys := make([]string, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	insights := []Insight{}
+	for _, key := range keys {
+		group := groups[key]
+		sort.Slice(group, func(i, j int) bool { return group[i].Date.Before(group[j].Date) })
+		for i := 1; i < len(group); i++ {
+			a, b := group[i-1], group[i]
+			if b.Date.Sub(a.Date) > duplicateWindow {
+				continue
+			}
+			label := a.Merchant
+			if label == "" {
+				label = a.Description
+			}
+			ids := []uuid.UUID{a.ID, b.ID}
+			fingerprint := a.ID.String() + ":" + b.ID.String()
+			if b.ID.String() < a.ID.String() {
+				fingerprint = b.ID.String() + ":" + a.ID.String()
+			}
+			insights = append(insights, newInsight(TypeDuplicate, fingerprint,
+				fmt.Sprintf("Possible duplicate: two %s %s charges for %s on %s and %s",
+					a.Amount.StringFixed(2), currency, label, a.Date.Format("Jan 2"), b.Date.Format("Jan 2")),
+				a.Amount,
+				map[string]any{"expense_ids": ids}))
+		}
+	}
+	return insights
+}
@@ -0,0 +1,104 @@
+package insight
+
+import (
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func ymd(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 12, 0, 0, 0, time.UTC)
+}
+
+func TestCategorySpikes(t *testing.T) {
+	d := decimal.RequireFromString
+	now := ymd(2024, 4, 20)
+	food, travel := uuid.New(), uuid.New()
+	foodName, travelName := "Food", "Travel"
+
+	expenses := []expense{
+		// Food averaged 100 over January to March and is at 250 in April
+		{Amount: d("150"), Date: ymd(2024, 1, 10), CategoryID: &food, CategoryName: &foodName},
+		{Amount: d("150"), Date: ymd(2024, 3, 10), CategoryID: &food, CategoryName: &foodName},
+		{Amount: d("250"), Date: ymd(2024, 4, 2), CategoryID: &food, CategoryName: &foodName},
+		// Travel is up, but not twice its average
+		{Amount: d("300"), Date: ymd(2024, 2, 10), CategoryID: &travel, CategoryName: &travelName},
+		{Amount: d("150"), Date: ymd(2024, 4, 5), CategoryID: &travel, CategoryName: &travelName},
+		// Older than the trailing months, so ignored
+		{Amount: d("900"), Date: ymd(2023, 12, 10), CategoryID: &travel, CategoryName: &travelName},
+		// Nothing uncategorized before, so no average to compare with
+		{Amount: d("80"), Date: ymd(2024, 4, 3)},
+	}
+
+	insights := categorySpikes(expenses, "USD", now)
+	require.Len(t, insights, 1)
+	assert.Equal(t, TypeCategorySpike, insights[0].Type)
+	assert.Equal(t, "Food spending this month is 2.5x its 3-month average (250.00 vs 100.00 USD)", insights[0].Message)
+	assert.Equal(t, "2024-04", insights[0].Data["month"])
+
+	// The same finding keeps its id
+	assert.Equal(t, insights[0].ID, categorySpikes(expenses, "USD", ymd(2024, 4, 28))[0].ID)
+}
+
+func TestNewRecurringCharges(t *testing.T) {
+	d := decimal.RequireFromString
+	now := ymd(2024, 4, 20)
+
+	expenses := []expense{
+		// New gym membership, a little dearer this month
+		{ID: uuid.New(), Amount: d("50"), Date: ymd(2024, 3, 15), MerchantKey: "gym", Merchant: "City Gym"},
+		{ID: uuid.New(), Amount: d("52"), Date: ymd(2024, 4, 15), MerchantKey: "gym", Merchant: "City Gym"},
+		// Long-standing streaming service
+		{Amount: d("40"), Date: ymd(2024, 2, 5), MerchantKey: "stream", Merchant: "Streamly"},
+		{Amount: d("40"), Date: ymd(2024, 3, 5), MerchantKey: "stream", Merchant: "Streamly"},
+		{Amount: d("40"), Date: ymd(2024, 4, 5), MerchantKey: "stream", Merchant: "Streamly"},
+		// New but too small to matter
+		{Amount: d("12"), Date: ymd(2024, 3, 8), MerchantKey: "app", Merchant: "App"},
+		{Amount: d("12"), Date: ymd(2024, 4, 8), MerchantKey: "app", Merchant: "App"},
+		// New and large, but the amount changed a lot
+		{Amount: d("100"), Date: ymd(2024, 3, 9), MerchantKey: "shop", Merchant: "Shop"},
+		{Amount: d("200"), Date: ymd(2024, 4, 9), MerchantKey: "shop", Merchant: "Shop"},
+	}
+	// Small everyday spending sets the median at 10
+	for day := 1; day <= 20; day++ {
+		expenses = append(expenses, expense{Amount: d("10"), Date: ymd(2024, 3, day)})
+	}
+
+	insights := newRecurringCharges(expenses, "USD", now)
+	require.Len(t, insights, 1)
+	assert.Equal(t, TypeNewRecurring, insights[0].Type)
+	assert.Equal(t, "New recurring charge from City Gym: 52.00 USD, also charged last month", insights[0].Message)
+	assert.Equal(t, []uuid.UUID{expenses[0].ID, expenses[1].ID}, insights[0].Data["expense_ids"])
+}
+
+func TestDuplicates(t *testing.T) {
+	d := decimal.RequireFromString
+	now := ymd(2024, 4, 20)
+	a, b := uuid.New(), uuid.New()
+
+	expenses := []expense{
+		{ID: a, Amount: d("42.50"), Date: ymd(2024, 4, 3), MerchantKey: "fuel", Merchant: "Shell"},
+		{ID: b, Amount: d("42.5"), Date: ymd(2024, 4, 4), MerchantKey: "fuel", Merchant: "Shell"},
+		// Same place and amount, but a week later
+		{ID: uuid.New(), Amount: d("42.50"), Date: ymd(2024, 4, 11), MerchantKey: "fuel", Merchant: "Shell"},
+		// Same description, different amount
+		{ID: uuid.New(), Amount: d("5"), Date: ymd(2024, 4, 10), Description: "Coffee"},
+		{ID: uuid.New(), Amount: d("6"), Date: ymd(2024, 4, 10), Description: "coffee"},
+		// Group shares are created automatically and never count
+		{ID: uuid.New(), Amount: d("30"), Date: ymd(2024, 4, 12), Description: "Dinner", Mirrored: true},
+		{ID: uuid.New(), Amount: d("30"), Date: ymd(2024, 4, 12), Description: "Dinner", Mirrored: true},
+		// Too long ago
+		{ID: uuid.New(), Amount: d("9"), Date: ymd(2024, 3, 1), Description: "Parking"},
+		{ID: uuid.New(), Amount: d("9"), Date: ymd(2024, 3, 1), Description: "Parking"},
+	}
+
+	insights := duplicates(expenses, "USD", now)
+	require.Len(t, insights, 1)
+	assert.Equal(t, TypeDuplicate, insights[0].Type)
+	assert.Equal(t, "Possible duplicate: two 42.50 USD charges for Shell on Apr 3 and Apr 4", insights[0].Message)
+	assert.Equal(t, []uuid.UUID{a, b}, insights[0].Data["expense_ids"])
+}
@@ -0,0 +1,170 @@
+package insight
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+	"github.com/yanonymousV2/finance-manager-backend/internal/helpers"
+	"github.com/yanonymousV2/finance-manager-backend/internal/middleware"
+)
+
+// Insight statuses. New insights have no stored state.
+const (
+	StatusNew          = "new"
+	StatusAcknowledged = "acknowledged"
+	StatusDismissed    = "dismissed"
+)
+
+// current computes the user's insights now, with the status each was given
+func current(ctx context.Context, db *db.DB, userID uuid.UUID, now time.Time) (string, []Insight, error) {
+	currency, err := helpers.GetUserCurrency(ctx, db, userID)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to get default currency: %w", err)
+	}
+
+	rows, err := db.Pool.Query(ctx,
+		`SELECT pe.id, pe.amount, pe.expense_date, pe.category_id, ec.name,
+		        COALESCE(pe.merchant_id::text, LOWER(TRIM(pe.merchant)), ''), COALESCE(m.name, pe.merchant, ''),
+		        COALESCE(pe.description, ''), pe.group_expense_id IS NOT NULL
+		 FROM personal_expenses pe
+		 LEFT JOIN expense_categories ec ON pe.category_id = ec.id
+		 LEFT JOIN merchants m ON pe.merchant_id = m.id
+		 WHERE pe.user_id = $1 AND pe.currency = $2 AND pe.expense_date >= $3`,
+		userID, currency, monthStart(now).AddDate(0, -historyMonths, 0))
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to get expenses: %w", err)
+	}
+	var expenses []expense
+	for rows.Next() {
+		var e expense
+		if err := rows.Scan(&e.ID, &e.Amount, &e.Date, &e.CategoryID, &e.CategoryName,
+			&e.MerchantKey, &e.Merchant, &e.Description, &e.Mirrored); err != nil {
+			rows.Close()
+			return "", nil, fmt.Errorf("failed to scan expense: %w", err)
+		}
+		expenses = append(expenses, e)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return "", nil, fmt.Errorf("failed to get expenses: %w", err)
+	}
+
+	insights := detect(expenses, currency, now)
+	if len(insights) == 0 {
+		return currency, insights, nil
+	}
+
+	ids := make([]uuid.UUID, len(insights))
+	for i, insight := range insights {
+		ids[i] = insight.ID
+	}
+	stateRows, err := db.Pool.Query(ctx,
+		"SELECT insight_id, status FROM insight_states WHERE user_id = $1 AND insight_id = ANY($2)",
+		userID, ids)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to get insight states: %w", err)
+	}
+	defer stateRows.Close()
+
+	states := map[uuid.UUID]string{}
+	for stateRows.Next() {
+		var id uuid.UUID
+		var status string
+		if err := stateRows.Scan(&id, &status); err != nil {
+			return "", nil, fmt.Errorf("failed to scan insight state: %w", err)
+		}
+		states[id] = status
+	}
+	for i := range insights {
+		if status, ok := states[insights[i].ID]; ok {
+			insights[i].Status = status
+		}
+	}
+	return currency, insights, stateRows.Err()
+}
+
+// ListInsights returns unusual activity in the authenticated user's recent
+// spending: categories well above their average, new large recurring
+// charges and possible duplicates. Dismissed insights are left out unless
+// include_dismissed=true.
+func ListInsights(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	currency, insights, err := current(c.Request.Context(), db, userID, time.Now())
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to get insights"})
+		return
+	}
+
+	includeDismissed := c.Query("include_dismissed") == "true"
+	result := []Insight{}
+	for _, insight := range insights {
+		if insight.Status != StatusDismissed || includeDismissed {
+			result = append(result, insight)
+		}
+	}
+
+	c.JSON(200, gin.H{"currency": currency, "insights": result})
+}
+
+// setStatus records the status of one of the user's current insights
+func setStatus(c *gin.Context, db *db.DB, status string) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	insightID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid insight id"})
+		return
+	}
+
+	_, insights, err := current(c.Request.Context(), db, userID, time.Now())
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to get insights"})
+		return
+	}
+	var found *Insight
+	for i := range insights {
+		if insights[i].ID == insightID {
+			found = &insights[i]
+		}
+	}
+	if found == nil {
+		c.JSON(404, gin.H{"error": "insight not found"})
+		return
+	}
+
+	_, err = db.Pool.Exec(c.Request.Context(),
+		`INSERT INTO insight_states (user_id, insight_id, status) VALUES ($1, $2, $3)
+		 ON CONFLICT (user_id, insight_id) DO UPDATE SET status = EXCLUDED.status, updated_at = NOW()`,
+		userID, insightID, status)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to update insight"})
+		return
+	}
+
+	found.Status = status
+	c.JSON(200, found)
+}
+
+// DismissInsight hides an insight from the list
+func DismissInsight(c *gin.Context, db *db.DB) {
+	setStatus(c, db, StatusDismissed)
+}
+
+// AcknowledgeInsight marks an insight as seen; it stays in the list
+func AcknowledgeInsight(c *gin.Context, db *db.DB) {
+	setStatus(c, db, StatusAcknowledged)
+}
@@ -0,0 +1,35 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	var buf bytes.Buffer
+	logger, err := New(&buf, "warn", "json")
+	require.NoError(t, err)
+	logger.Info("hidden")
+	logger.Warn("shown", "user_id", "abc")
+
+	var line map[string]any
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &line))
+	assert.Equal(t, "WARN", line["level"])
+	assert.Equal(t, "shown", line["msg"])
+	assert.Equal(t, "abc", line["user_id"])
+
+	buf.Reset()
+	logger, err = New(&buf, "DEBUG", "text")
+	require.NoError(t, err)
+	logger.Debug("query", "sql", "SELECT 1")
+	assert.Contains(t, buf.String(), `level=DEBUG msg=query sql="SELECT 1"`)
+
+	_, err = New(&buf, "loud", "json")
+	assert.Error(t, err)
+	_, err = New(&buf, "info", "xml")
+	assert.Error(t, err)
+}
@@ -0,0 +1,244 @@
+package merchant
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+	"github.com/yanonymousV2/finance-manager-backend/internal/db/sqlc"
+	"github.com/yanonymousV2/finance-manager-backend/internal/helpers"
+	"github.com/yanonymousV2/finance-manager-backend/internal/middleware"
+	"github.com/yanonymousV2/finance-manager-backend/internal/validation"
+)
+
+type Merchant struct {
+	ID         uuid.UUID  `json:"id" db:"id"`
+	Name       string     `json:"name" db:"name"`
+	CategoryID *uuid.UUID `json:"category_id,omitempty" db:"category_id"`
+	Aliases    []Alias    `json:"aliases"`
+	CreatedAt  time.Time  `json:"created_at" db:"created_at"`
+}
+
+type Alias struct {
+	ID    uuid.UUID `json:"id" db:"id"`
+	Alias string    `json:"alias" db:"alias"`
+}
+
+type UpdateMerchantRequest struct {
+	Name       *string    `json:"name,omitempty" validate:"omitempty,min=1,max=100"`
+	CategoryID *uuid.UUID `json:"category_id,omitempty"`
+}
+
+type CreateAliasRequest struct {
+	Alias string `json:"alias" validate:"required,min=1,max=100"`
+}
+
+// ListMerchants retrieves the user's merchants with their aliases
+func ListMerchants(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	rows, err := db.Pool.Query(c.Request.Context(),
+		`SELECT m.id, m.name, m.category_id, m.created_at, a.id, a.alias
+		 FROM merchants m
+		 LEFT JOIN merchant_aliases a ON a.merchant_id = m.id
+		 WHERE m.user_id = $1
+		 ORDER BY m.name ASC, a.alias ASC`,
+		userID)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to retrieve merchants"})
+		return
+	}
+	defer rows.Close()
+
+	merchants := []Merchant{}
+	for rows.Next() {
+		var m Merchant
+		var aliasID *uuid.UUID
+		var alias *string
+		if err := rows.Scan(&m.ID, &m.Name, &m.CategoryID, &m.CreatedAt, &aliasID, &alias); err != nil {
+			c.JSON(500, gin.H{"error": "failed to scan merchant"})
+			return
+		}
+		if n := len(merchants); n == 0 || merchants[n-1].ID != m.ID {
+			m.Aliases = []Alias{}
+			merchants = append(merchants, m)
+		}
+		if aliasID != nil {
+			last := &merchants[len(merchants)-1]
+			last.Aliases = append(last.Aliases, Alias{ID: *aliasID, Alias: *alias})
+		}
+	}
+
+	c.JSON(200, merchants)
+}
+
+// UpdateMerchant renames a merchant or sets the category its new expenses
+// default to
+func UpdateMerchant(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	merchantID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid merchant id"})
+		return
+	}
+
+	var req UpdateMerchantRequest
+	if !validation.Bind(c, &req) {
+		return
+	}
+
+	var ownerID uuid.UUID
+	err = db.Pool.QueryRow(c.Request.Context(),
+		`SELECT user_id FROM merchants WHERE id = $1`, merchantID).Scan(&ownerID)
+	if err != nil {
+		c.JSON(404, gin.H{"error": "merchant not found"})
+		return
+	}
+	if ownerID != userID {
+		c.JSON(403, gin.H{"error": "not authorized to update this merchant"})
+		return
+	}
+
+	if req.CategoryID != nil {
+		categoryOwnerID, _, err := helpers.GetCategoryOwner(c.Request.Context(), db.Pool, *req.CategoryID)
+		if err != nil {
+			c.JSON(400, gin.H{"error": "invalid category"})
+			return
+		}
+		if categoryOwnerID != userID {
+			c.JSON(403, gin.H{"error": "category does not belong to user"})
+			return
+		}
+	}
+
+	if req.Name == nil && req.CategoryID == nil {
+		c.JSON(400, gin.H{"error": "no fields to update"})
+		return
+	}
+
+	var m Merchant
+	err = db.WithTx(c.Request.Context(), func(tx pgx.Tx) error {
+		row, err := sqlc.New(tx).UpdateMerchant(c.Request.Context(), sqlc.UpdateMerchantParams{
+			ID:         merchantID,
+			Name:       req.Name,
+			CategoryID: req.CategoryID,
+		})
+		if err != nil {
+			return helpers.NewRequestError(409, "merchant with this name already exists")
+		}
+		m = Merchant{ID: row.ID, Name: row.Name, CategoryID: row.CategoryID, CreatedAt: row.CreatedAt}
+
+		// Expenses keep a copy of the name for search
+		if req.Name != nil {
+			_, err = tx.Exec(c.Request.Context(),
+				`UPDATE personal_expenses SET merchant = $1, updated_at = NOW() WHERE merchant_id = $2`, m.Name, merchantID)
+			if err != nil {
+				return helpers.NewRequestError(500, "failed to update expenses")
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		helpers.RespondError(c, err, "failed to update merchant")
+		return
+	}
+
+	m.Aliases = []Alias{}
+	c.JSON(200, m)
+}
+
+// CreateAlias maps payee text to a merchant, so future expenses whose
+// merchant starts with it are normalized to that merchant
+func CreateAlias(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	merchantID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid merchant id"})
+		return
+	}
+
+	var req CreateAliasRequest
+	if !validation.Bind(c, &req) {
+		return
+	}
+
+	key := Key(req.Alias)
+	if key == "" {
+		c.JSON(400, gin.H{"error": "alias must contain letters"})
+		return
+	}
+
+	var ownerID uuid.UUID
+	err = db.Pool.QueryRow(c.Request.Context(),
+		`SELECT user_id FROM merchants WHERE id = $1`, merchantID).Scan(&ownerID)
+	if err != nil || ownerID != userID {
+		c.JSON(404, gin.H{"error": "merchant not found"})
+		return
+	}
+
+	// An alias belongs to one merchant; adding it again moves it here
+	var alias Alias
+	err = db.Pool.QueryRow(c.Request.Context(),
+		`INSERT INTO merchant_aliases (user_id, merchant_id, alias) VALUES ($1, $2, $3)
+		 ON CONFLICT (user_id, alias) DO UPDATE SET merchant_id = EXCLUDED.merchant_id
+		 RETURNING id, alias`,
+		userID, merchantID, key).Scan(&alias.ID, &alias.Alias)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to create alias"})
+		return
+	}
+
+	c.JSON(201, alias)
+}
+
+// DeleteAlias removes an alias from a merchant
+func DeleteAlias(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	merchantID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid merchant id"})
+		return
+	}
+
+	aliasID, err := uuid.Parse(c.Param("aliasId"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid alias id"})
+		return
+	}
+
+	tag, err := db.Pool.Exec(c.Request.Context(),
+		`DELETE FROM merchant_aliases WHERE id = $1 AND merchant_id = $2 AND user_id = $3`,
+		aliasID, merchantID, userID)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to delete alias"})
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		c.JSON(404, gin.H{"error": "alias not found"})
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "alias deleted successfully"})
+}
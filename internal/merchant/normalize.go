@@ -0,0 +1,155 @@
+package merchant
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"unicode"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Querier is satisfied by both the connection pool and a transaction
+type Querier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+// processorPrefixes are card processor tags that precede the real payee on
+// statements, e.g. "SQ *BLUE BOTTLE"
+var processorPrefixes = map[string]bool{"sq": true, "tst": true, "sp": true, "pp": true, "paypal": true}
+
+// builtinAliases maps the keys of common statement descriptors to merchant
+// names, so new users get sensible merchants before defining aliases
+var builtinAliases = map[string]string{
+	"amzn":           "Amazon",
+	"amzn mktp":      "Amazon",
+	"amazon":         "Amazon",
+	"amazon com":     "Amazon",
+	"apple com bill": "Apple",
+	"uber":           "Uber",
+	"uber eats":      "Uber Eats",
+	"ubereats":       "Uber Eats",
+	"lyft":           "Lyft",
+	"netflix":        "Netflix",
+	"netflix com":    "Netflix",
+	"spotify":        "Spotify",
+	"starbucks":      "Starbucks",
+	"walmart":        "Walmart",
+	"wal mart":       "Walmart",
+	"wm supercenter": "Walmart",
+	"target":         "Target",
+	"costco":         "Costco",
+	"costco whse":    "Costco",
+}
+
+// Key reduces raw payee text to the form aliases are matched on: lower case
+// words without punctuation, store numbers, reference codes or processor
+// prefixes. "AMZN Mktp US*2K4AB1" becomes "amzn mktp us".
+func Key(raw string) string {
+	words := strings.FieldsFunc(strings.ToLower(raw), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	kept := make([]string, 0, len(words))
+	for _, w := range words {
+		if strings.IndexFunc(w, unicode.IsDigit) >= 0 {
+			continue
+		}
+		kept = append(kept, w)
+	}
+	if len(kept) > 1 && processorPrefixes[kept[0]] {
+		kept = kept[1:]
+	}
+
+	key := []rune(strings.Join(kept, " "))
+	if len(key) > 100 {
+		key = key[:100]
+	}
+	return strings.TrimSpace(string(key))
+}
+
+// matchesAlias reports whether key starts with the words of alias
+func matchesAlias(key, alias string) bool {
+	return key == alias || strings.HasPrefix(key, alias+" ")
+}
+
+// builtinMatch returns the builtin merchant name with the longest alias
+// matching key
+func builtinMatch(key string) (alias, name string) {
+	for a, n := range builtinAliases {
+		if matchesAlias(key, a) && len(a) > len(alias) {
+			alias, name = a, n
+		}
+	}
+	return alias, name
+}
+
+// DisplayName turns a key into a merchant name, capitalizing each word
+func DisplayName(key string) string {
+	words := strings.Fields(key)
+	for i, w := range words {
+		runes := []rune(w)
+		runes[0] = unicode.ToUpper(runes[0])
+		words[i] = string(runes)
+	}
+	return strings.Join(words, " ")
+}
+
+// Resolved is the merchant raw payee text was normalized to
+type Resolved struct {
+	ID         uuid.UUID
+	Name       string
+	CategoryID *uuid.UUID
+}
+
+// Resolve normalizes raw payee text to one of the user's merchants, trying
+// their aliases first, then the builtin aliases, and otherwise creating a
+// merchant named after the text. It returns nil when nothing usable is left
+// of the text.
+func Resolve(ctx context.Context, q Querier, userID uuid.UUID, raw string) (*Resolved, error) {
+	key := Key(raw)
+	if key == "" {
+		return nil, nil
+	}
+
+	var m Resolved
+	err := q.QueryRow(ctx,
+		`SELECT m.id, m.name, m.category_id
+		 FROM merchant_aliases a JOIN merchants m ON m.id = a.merchant_id
+		 WHERE a.user_id = $1 AND ($2 = a.alias OR $2 LIKE a.alias || ' %')
+		 ORDER BY LENGTH(a.alias) DESC
+		 LIMIT 1`,
+		userID, key).Scan(&m.ID, &m.Name, &m.CategoryID)
+	if err == nil {
+		return &m, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return nil, err
+	}
+
+	alias, name := builtinMatch(key)
+	if name == "" {
+		alias, name = key, DisplayName(key)
+	}
+
+	err = q.QueryRow(ctx,
+		`INSERT INTO merchants (user_id, name) VALUES ($1, $2)
+		 ON CONFLICT (user_id, name) DO UPDATE SET name = EXCLUDED.name
+		 RETURNING id, name, category_id`,
+		userID, name).Scan(&m.ID, &m.Name, &m.CategoryID)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = q.Exec(ctx,
+		`INSERT INTO merchant_aliases (user_id, merchant_id, alias) VALUES ($1, $2, $3)
+		 ON CONFLICT (user_id, alias) DO NOTHING`,
+		userID, m.ID, alias)
+	if err != nil {
+		return nil, err
+	}
+	return &m, nil
+}
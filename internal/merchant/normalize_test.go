@@ -0,0 +1,44 @@
+package merchant
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestKey(t *testing.T) {
+	tests := []struct {
+		raw  string
+		want string
+	}{
+		{raw: "AMZN Mktp US*2K4AB1", want: "amzn mktp us"},
+		{raw: "SQ *BLUE BOTTLE COFFEE #123", want: "blue bottle coffee"},
+		{raw: "  Starbucks   Store 04521 ", want: "starbucks store"},
+		{raw: "UBER   *EATS", want: "uber eats"},
+		{raw: "SQ", want: "sq"},
+		{raw: "#12345", want: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.raw, func(t *testing.T) {
+			assert.Equal(t, tt.want, Key(tt.raw))
+		})
+	}
+}
+
+func TestBuiltinMatch(t *testing.T) {
+	alias, name := builtinMatch(Key("AMZN Mktp US*2K4AB1"))
+	assert.Equal(t, "amzn mktp", alias)
+	assert.Equal(t, "Amazon", name)
+
+	_, name = builtinMatch(Key("UBER *EATS 8005928996"))
+	assert.Equal(t, "Uber Eats", name)
+
+	_, name = builtinMatch(Key("Targeted Ads LLC"))
+	assert.Empty(t, name)
+}
+
+func TestDisplayName(t *testing.T) {
+	assert.Equal(t, "Blue Bottle Coffee", DisplayName("blue bottle coffee"))
+	assert.Equal(t, "Café Ñandú", DisplayName("café ñandú"))
+}
@@ -1,34 +1,50 @@
 package middleware
 
 import (
-	"log"
+	"log/slog"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/requestid"
 )
 
-// RequestLogger logs HTTP requests with timing information
+// RequestLogger logs each request once it's done. Server errors log at
+// error level and client errors at warn, so a quieter level still shows
+// what went wrong.
 func RequestLogger() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		start := time.Now()
-		path := c.Request.URL.Path
-		method := c.Request.Method
 
 		// Process request
 		c.Next()
 
-		// Log after request is complete
-		duration := time.Since(start)
-		statusCode := c.Writer.Status()
-		clientIP := c.ClientIP()
+		status := c.Writer.Status()
+		attrs := []slog.Attr{
+			slog.String("method", c.Request.Method),
+			slog.String("route", c.FullPath()),
+			slog.String("path", c.Request.URL.Path),
+			slog.Int("status", status),
+			slog.Float64("latency_ms", float64(time.Since(start).Microseconds())/1000),
+			slog.String("ip", c.ClientIP()),
+		}
+		if userID, ok := c.Get("user_id"); ok {
+			if id, ok := userID.(uuid.UUID); ok {
+				attrs = append(attrs, slog.String("user_id", id.String()))
+			}
+		}
+		if len(c.Errors) > 0 {
+			attrs = append(attrs, slog.String("errors", c.Errors.String()))
+		}
 
-		log.Printf("[%s] %s %s | Status: %d | Duration: %v | IP: %s",
-			method,
-			path,
-			c.Request.Proto,
-			statusCode,
-			duration,
-			clientIP,
-		)
+		level := slog.LevelInfo
+		switch {
+		case status >= 500:
+			level = slog.LevelError
+		case status >= 400:
+			level = slog.LevelWarn
+		}
+		requestid.Logger(c.Request.Context()).LogAttrs(c.Request.Context(), level, "request", attrs...)
 	}
 }
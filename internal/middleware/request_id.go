@@ -0,0 +1,72 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/requestid"
+)
+
+// RequestID gives every request an ID, reusing the caller's X-Request-ID
+// when it looks sane. The ID is echoed in the response header, stored on
+// the request context and added to JSON error responses.
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(requestid.Header)
+		if !requestid.Valid(id) {
+			id = uuid.NewString()
+		}
+
+		c.Set("request_id", id)
+		c.Request = c.Request.WithContext(requestid.With(c.Request.Context(), id))
+		c.Header(requestid.Header, id)
+		c.Writer = &requestIDWriter{ResponseWriter: c.Writer, id: id}
+		c.Next()
+	}
+}
+
+// requestIDWriter adds "request_id" to JSON error bodies of the form
+// {"error": ...}. Gin renders JSON in a single Write, so each call holds a
+// whole body.
+type requestIDWriter struct {
+	gin.ResponseWriter
+	id string
+}
+
+func (w *requestIDWriter) Write(data []byte) (int, error) {
+	if w.Status() < 400 || !strings.HasPrefix(w.Header().Get("Content-Type"), "application/json") {
+		return w.ResponseWriter.Write(data)
+	}
+
+	var body map[string]json.RawMessage
+	if err := json.Unmarshal(data, &body); err != nil {
+		return w.ResponseWriter.Write(data)
+	}
+	if _, ok := body["error"]; !ok {
+		return w.ResponseWriter.Write(data)
+	}
+	if _, ok := body["request_id"]; ok {
+		return w.ResponseWriter.Write(data)
+	}
+	body["request_id"], _ = json.Marshal(w.id)
+	tagged, err := json.Marshal(body)
+	if err != nil {
+		return w.ResponseWriter.Write(data)
+	}
+	if bytes.HasSuffix(data, []byte("\n")) {
+		tagged = append(tagged, '\n')
+	}
+	if _, err := w.ResponseWriter.Write(tagged); err != nil {
+		return 0, err
+	}
+	// Callers check the count against what they passed in
+	return len(data), nil
+}
+
+func (w *requestIDWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
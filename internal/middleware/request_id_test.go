@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/requestid"
+)
+
+func TestRequestID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(RequestID())
+	var seen string
+	r.GET("/ok", func(c *gin.Context) {
+		seen = requestid.From(c.Request.Context())
+		c.JSON(200, gin.H{"error": "not an error"})
+	})
+	r.GET("/fail", func(c *gin.Context) { c.JSON(400, gin.H{"error": "bad"}) })
+
+	serve := func(path, id string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("GET", path, nil)
+		if id != "" {
+			req.Header.Set(requestid.Header, id)
+		}
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := serve("/ok", "abc-123")
+	assert.Equal(t, "abc-123", w.Header().Get(requestid.Header))
+	assert.Equal(t, "abc-123", seen)
+	assert.NotContains(t, w.Body.String(), "request_id")
+
+	// Unusable incoming IDs are replaced
+	w = serve("/ok", "bad id\n"+strings.Repeat("x", 200))
+	assert.Len(t, w.Header().Get(requestid.Header), 36)
+	assert.Equal(t, w.Header().Get(requestid.Header), seen)
+
+	w = serve("/fail", "abc-123")
+	var body map[string]string
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, map[string]string{"error": "bad", "request_id": "abc-123"}, body)
+}
@@ -0,0 +1,153 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+	"github.com/yanonymousV2/finance-manager-backend/internal/email"
+	"github.com/yanonymousV2/finance-manager-backend/internal/push"
+)
+
+const (
+	// sendBatchSize is how many notifications are emailed or pushed at a time
+	sendBatchSize = 100
+	// sendWindow is how long after it's created a notification is still
+	// emailed or pushed; older ones are left when a channel has been failing
+	sendWindow = 24 * time.Hour
+	// maxSendAttempts is how many times sending a notification on a channel
+	// is tried before it's given up on
+	maxSendAttempts = 5
+)
+
+// Pusher sends push notifications to a user's devices; push.Sender is the
+// real one
+type Pusher interface {
+	Push(ctx context.Context, userID uuid.UUID, msg push.Message) error
+}
+
+// render returns the title and text of a notification for email and push
+func render(n Notification) (title, body string) {
+	d := n.Data
+	switch n.Type {
+	case TypeSettlementReminder:
+		return "Payment reminder", fmt.Sprintf("You owe %v %v in one of your groups.", d["amount"], d["currency"])
+	case TypeCategoryLimit:
+		return fmt.Sprintf("%v is over its monthly limit", d["category_name"]),
+			fmt.Sprintf("You've spent %v %v of your %v limit this month.", d["spent"], d["currency"], d["monthly_limit"])
+	case TypeBudgetThreshold:
+		return fmt.Sprintf("You've used %v%% of your budget", d["threshold"]),
+			fmt.Sprintf("You've spent %v %v of your %v %v %v budget for %v to %v.",
+				d["spent"], d["currency"], d["effective_budget"], d["currency"], d["period"], d["start_date"], d["end_date"])
+	case TypeExpenseAdded:
+		return fmt.Sprintf("New expense: %v", d["description"]),
+			fmt.Sprintf("%v %v was added to one of your groups.", d["total_amount"], d["currency"])
+	case TypeSettlementReceived:
+		return "Payment recorded", fmt.Sprintf("A payment of %v %v was recorded between you and another member.", d["amount"], d["currency"])
+	}
+	return "New notification", "You have a new notification."
+}
+
+// RunOnce turns new events into notifications, then emails and pushes the
+// notifications that are due on those channels. The scheduler runs it every
+// minute.
+func RunOnce(ctx context.Context, db *db.DB, sender email.Sender, pusher Pusher) error {
+	if err := FromEvents(ctx, db); err != nil {
+		return err
+	}
+	if err := sendPending(ctx, db, "email", "emailed_at", func(ctx context.Context, p pending) error {
+		if p.email == nil {
+			return nil
+		}
+		title, body := render(p.Notification)
+		return sender.Send(ctx, email.Message{To: *p.email, Subject: title, Body: body})
+	}); err != nil {
+		return err
+	}
+	return sendPending(ctx, db, "push", "pushed_at", func(ctx context.Context, p pending) error {
+		return pusher.Push(ctx, p.UserID, pushMessage(p.Notification))
+	})
+}
+
+// pushMessage is the push notification for n. Its data lets the app open
+// the notification.
+func pushMessage(n Notification) push.Message {
+	title, body := render(n)
+	data := map[string]string{"notification_id": n.ID.String(), "type": n.Type}
+	if n.GroupID != nil {
+		data["group_id"] = n.GroupID.String()
+	}
+	return push.Message{Title: title, Body: body, Data: data}
+}
+
+// pending is a notification waiting to go out on a channel, with the
+// recipient's email address, which placeholders don't have
+type pending struct {
+	Notification
+	email *string
+}
+
+// sendPending sends the recent notifications due on a channel, marking each
+// sent as it goes. channel and sentColumn are the notifications columns
+// that flag the channel and record when it went out; failures are counted
+// in <channel>_attempts with the error in <channel>_error, and the
+// notification is retried on later runs until maxSendAttempts. Only
+// failing to read or update notifications stops it.
+func sendPending(ctx context.Context, db *db.DB, channel, sentColumn string, send func(context.Context, pending) error) error {
+	attemptsColumn, errorColumn := channel+"_attempts", channel+"_error"
+
+	// Each run goes through the due notifications once, in order, so a
+	// failed one isn't retried straight away
+	var afterTime time.Time
+	var afterID uuid.UUID
+	for {
+		// Stop between batches on shutdown
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		rows, err := db.Pool.Query(ctx,
+			`SELECT n.id, n.user_id, n.group_id, n.type, n.data, n.created_at, u.email
+			 FROM notifications n JOIN users u ON u.id = n.user_id
+			 WHERE n.`+channel+` AND n.`+sentColumn+` IS NULL AND n.created_at > $1
+			   AND n.`+attemptsColumn+` < $2 AND (n.created_at, n.id) > ($3, $4)
+			 ORDER BY n.created_at, n.id LIMIT $5`,
+			time.Now().Add(-sendWindow), maxSendAttempts, afterTime, afterID, sendBatchSize)
+		if err != nil {
+			return fmt.Errorf("failed to find %s notifications: %w", channel, err)
+		}
+		due, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (pending, error) {
+			var p pending
+			err := row.Scan(&p.ID, &p.UserID, &p.GroupID, &p.Type, &p.Data, &p.CreatedAt, &p.email)
+			return p, err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to find %s notifications: %w", channel, err)
+		}
+
+		for _, p := range due {
+			afterTime, afterID = p.CreatedAt, p.ID
+			if sendErr := send(ctx, p); sendErr != nil {
+				slog.Error("failed to send notification", "channel", channel, "notification_id", p.ID, "error", sendErr)
+				if _, err := db.Pool.Exec(ctx,
+					`UPDATE notifications SET `+attemptsColumn+` = `+attemptsColumn+` + 1, `+errorColumn+` = $2 WHERE id = $1`,
+					p.ID, sendErr.Error()); err != nil {
+					return fmt.Errorf("failed to record %s notification %s failure: %w", channel, p.ID, err)
+				}
+				continue
+			}
+			if _, err := db.Pool.Exec(ctx,
+				`UPDATE notifications SET `+sentColumn+` = NOW() WHERE id = $1`, p.ID); err != nil {
+				return fmt.Errorf("failed to mark %s notification %s sent: %w", channel, p.ID, err)
+			}
+		}
+		if len(due) < sendBatchSize {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,164 @@
+package notification
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+	"github.com/yanonymousV2/finance-manager-backend/internal/helpers"
+	"github.com/yanonymousV2/finance-manager-backend/internal/middleware"
+)
+
+// Notification types
+const (
+	TypeSettlementReminder = "settlement_reminder"
+	TypeCategoryLimit      = "category_limit_exceeded"
+	TypeBudgetThreshold    = "budget_threshold"
+	TypeExpenseAdded       = "expense_added"
+	TypeSettlementReceived = "settlement_received"
+)
+
+// Types lists every notification type, for validating preferences
+var Types = []string{TypeSettlementReminder, TypeCategoryLimit, TypeBudgetThreshold, TypeExpenseAdded, TypeSettlementReceived}
+
+// Channels are the ways a notification reaches its recipient: the in-app
+// list, email and push
+type Channels struct {
+	InApp bool `json:"in_app"`
+	Email bool `json:"email"`
+	Push  bool `json:"push"`
+}
+
+// DefaultChannels applies to types the user hasn't set preferences for
+var DefaultChannels = Channels{InApp: true}
+
+type Notification struct {
+	ID        uuid.UUID      `json:"id" db:"id"`
+	UserID    uuid.UUID      `json:"user_id" db:"user_id"`
+	GroupID   *uuid.UUID     `json:"group_id,omitempty" db:"group_id"`
+	Type      string         `json:"type" db:"type"`
+	Data      map[string]any `json:"data" db:"data"`
+	Channels  Channels       `json:"channels"`
+	ReadAt    *time.Time     `json:"read_at,omitempty" db:"read_at"`
+	CreatedAt time.Time      `json:"created_at" db:"created_at"`
+}
+
+// Create stores a notification for a user, going out on the channels their
+// preferences for its type turn on. It's stored even with every channel
+// off, which keeps rate limits such as SentSince working; it just isn't
+// listed or sent. Email and push are sent afterwards by RunOnce.
+func Create(ctx context.Context, q helpers.Querier, userID uuid.UUID, groupID *uuid.UUID, kind string, data map[string]any) (Notification, error) {
+	n := Notification{UserID: userID, GroupID: groupID, Type: kind, Data: data}
+	err := q.QueryRow(ctx,
+		`INSERT INTO notifications (user_id, group_id, type, data, in_app, email, push)
+		 SELECT $1, $2, $3, $4, COALESCE(p.in_app, $5), COALESCE(p.email, $6), COALESCE(p.push, $7)
+		 FROM (SELECT 1) AS one
+		 LEFT JOIN notification_preferences p ON p.user_id = $1 AND p.type = $3
+		 RETURNING id, in_app, email, push, created_at`,
+		userID, groupID, kind, data, DefaultChannels.InApp, DefaultChannels.Email, DefaultChannels.Push).
+		Scan(&n.ID, &n.Channels.InApp, &n.Channels.Email, &n.Channels.Push, &n.CreatedAt)
+	return n, err
+}
+
+// SentSince reports whether a user has received a notification of the given
+// type for a group since the given time
+func SentSince(ctx context.Context, q helpers.Querier, userID, groupID uuid.UUID, kind string, since time.Time) (bool, error) {
+	var sent bool
+	err := q.QueryRow(ctx,
+		`SELECT EXISTS(SELECT 1 FROM notifications
+		               WHERE user_id = $1 AND group_id = $2 AND type = $3 AND created_at >= $4)`,
+		userID, groupID, kind, since).Scan(&sent)
+	return sent, err
+}
+
+// ListNotifications returns the authenticated user's in-app notifications,
+// newest first
+func ListNotifications(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	// Parse pagination parameters
+	limit := 50 // default
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
+			limit = l
+		}
+	}
+
+	offset := 0
+	if offsetStr := c.Query("offset"); offsetStr != "" {
+		if o, err := strconv.Atoi(offsetStr); err == nil && o >= 0 {
+			offset = o
+		}
+	}
+
+	unreadOnly := c.Query("unread") == "true"
+
+	rows, err := db.Pool.Query(c.Request.Context(),
+		`SELECT id, user_id, group_id, type, data, in_app, email, push, read_at, created_at
+		 FROM notifications
+		 WHERE user_id = $1 AND in_app AND (NOT $2 OR read_at IS NULL)
+		 ORDER BY created_at DESC, id
+		 LIMIT $3 OFFSET $4`,
+		userID, unreadOnly, limit, offset)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to get notifications"})
+		return
+	}
+	defer rows.Close()
+
+	notifications := []Notification{}
+	for rows.Next() {
+		var n Notification
+		if err := rows.Scan(&n.ID, &n.UserID, &n.GroupID, &n.Type, &n.Data,
+			&n.Channels.InApp, &n.Channels.Email, &n.Channels.Push, &n.ReadAt, &n.CreatedAt); err != nil {
+			c.JSON(500, gin.H{"error": "failed to scan notification"})
+			return
+		}
+		notifications = append(notifications, n)
+	}
+
+	c.JSON(200, gin.H{
+		"notifications": notifications,
+		"pagination": gin.H{
+			"limit":  limit,
+			"offset": offset,
+		},
+	})
+}
+
+// MarkRead marks one of the authenticated user's notifications as read
+func MarkRead(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	notificationID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid notification id"})
+		return
+	}
+
+	tag, err := db.Pool.Exec(c.Request.Context(),
+		"UPDATE notifications SET read_at = COALESCE(read_at, NOW()) WHERE id = $1 AND user_id = $2",
+		notificationID, userID)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to update notification"})
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		c.JSON(404, gin.H{"error": "notification not found"})
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "notification marked as read"})
+}
@@ -0,0 +1,72 @@
+package notification
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/event"
+)
+
+func TestFromEvent(t *testing.T) {
+	alice, bob := uuid.New(), uuid.New()
+	groupID := uuid.New()
+
+	expense, _ := json.Marshal(map[string]any{
+		"id": uuid.New(), "description": "Dinner", "total_amount": "60.00", "currency": "EUR", "paid_by": alice,
+	})
+	kind, data, recipient, ok, err := fromEvent(pendingEvent{Type: event.ExpenseCreated, UserID: alice, GroupID: &groupID, Data: expense})
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, TypeExpenseAdded, kind)
+	assert.Nil(t, recipient, "goes to the group's other members")
+	assert.Equal(t, "Dinner", data["description"])
+	assert.True(t, decimal.RequireFromString("60").Equal(data["total_amount"].(decimal.Decimal)))
+	assert.Equal(t, alice, data["added_by"])
+
+	// Whoever records a settlement, the other party is notified
+	settlement, _ := json.Marshal(map[string]any{
+		"id": uuid.New(), "from_user": bob, "to_user": alice, "amount": "25.00", "currency": "EUR",
+	})
+	kind, _, recipient, ok, err = fromEvent(pendingEvent{Type: event.SettlementCreated, UserID: bob, GroupID: &groupID, Data: settlement})
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, TypeSettlementReceived, kind)
+	assert.Equal(t, alice, *recipient)
+	_, _, recipient, _, err = fromEvent(pendingEvent{Type: event.SettlementCreated, UserID: alice, GroupID: &groupID, Data: settlement})
+	require.NoError(t, err)
+	assert.Equal(t, bob, *recipient)
+
+	_, _, _, ok, err = fromEvent(pendingEvent{Type: event.BudgetExceeded, UserID: alice, Data: json.RawMessage(`{}`)})
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	_, _, _, _, err = fromEvent(pendingEvent{Type: event.ExpenseCreated, UserID: alice, Data: json.RawMessage(`[]`)})
+	assert.Error(t, err)
+}
+
+func TestRender(t *testing.T) {
+	for _, kind := range Types {
+		title, body := render(Notification{Type: kind, Data: map[string]any{}})
+		assert.NotEmpty(t, title, kind)
+		assert.NotEqual(t, "You have a new notification.", body, kind)
+	}
+
+	title, body := render(Notification{Type: TypeSettlementReminder, Data: map[string]any{"amount": "12.50", "currency": "USD"}})
+	assert.Equal(t, "Payment reminder", title)
+	assert.Equal(t, "You owe 12.50 USD in one of your groups.", body)
+}
+
+func TestPushMessage(t *testing.T) {
+	id, groupID := uuid.New(), uuid.New()
+	msg := pushMessage(Notification{ID: id, GroupID: &groupID, Type: TypeSettlementReminder, Data: map[string]any{"amount": "12.50", "currency": "USD"}})
+	assert.Equal(t, "Payment reminder", msg.Title)
+	assert.Equal(t, map[string]string{"notification_id": id.String(), "type": TypeSettlementReminder, "group_id": groupID.String()}, msg.Data)
+
+	msg = pushMessage(Notification{ID: id, Type: TypeBudgetThreshold, Data: map[string]any{}})
+	assert.NotContains(t, msg.Data, "group_id")
+}
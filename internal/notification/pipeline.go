@@ -0,0 +1,152 @@
+package notification
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+	"github.com/yanonymousV2/finance-manager-backend/internal/event"
+)
+
+// eventBatchSize is how many events are turned into notifications at a time
+const eventBatchSize = 100
+
+// pendingEvent is an event not yet turned into notifications
+type pendingEvent struct {
+	ID      uuid.UUID
+	Type    string
+	UserID  uuid.UUID
+	GroupID *uuid.UUID
+	Data    json.RawMessage
+}
+
+// fromEvent returns the notification type and data for an event, and who
+// gets it besides the group's other members: for a settlement, the other
+// party. ok is false for events that don't notify anyone, such as
+// budget.exceeded, whose notification is created along with it.
+func fromEvent(e pendingEvent) (kind string, data map[string]any, recipient *uuid.UUID, ok bool, err error) {
+	switch e.Type {
+	case event.ExpenseCreated:
+		var x struct {
+			ID          uuid.UUID       `json:"id"`
+			Description string          `json:"description"`
+			TotalAmount decimal.Decimal `json:"total_amount"`
+			Currency    string          `json:"currency"`
+			PaidBy      uuid.UUID       `json:"paid_by"`
+		}
+		if err := json.Unmarshal(e.Data, &x); err != nil {
+			return "", nil, nil, false, err
+		}
+		return TypeExpenseAdded, map[string]any{
+			"expense_id":   x.ID,
+			"description":  x.Description,
+			"total_amount": x.TotalAmount,
+			"currency":     x.Currency,
+			"paid_by":      x.PaidBy,
+			"added_by":     e.UserID,
+		}, nil, true, nil
+	case event.SettlementCreated:
+		var x struct {
+			ID       uuid.UUID       `json:"id"`
+			FromUser uuid.UUID       `json:"from_user"`
+			ToUser   uuid.UUID       `json:"to_user"`
+			Amount   decimal.Decimal `json:"amount"`
+			Currency string          `json:"currency"`
+		}
+		if err := json.Unmarshal(e.Data, &x); err != nil {
+			return "", nil, nil, false, err
+		}
+		other := x.ToUser
+		if other == e.UserID {
+			other = x.FromUser
+		}
+		return TypeSettlementReceived, map[string]any{
+			"settlement_id": x.ID,
+			"from_user":     x.FromUser,
+			"to_user":       x.ToUser,
+			"amount":        x.Amount,
+			"currency":      x.Currency,
+			"recorded_by":   e.UserID,
+		}, &other, true, nil
+	}
+	return "", nil, nil, false, nil
+}
+
+// FromEvents turns events recorded since the last run into notifications.
+// A new group expense notifies the group's other members, and a settlement
+// the other party to it. Each batch is turned into notifications and
+// marked in one transaction; SKIP LOCKED lets several servers run it at
+// once.
+func FromEvents(ctx context.Context, db *db.DB) error {
+	for {
+		var n int
+		err := db.WithTx(ctx, func(tx pgx.Tx) error {
+			rows, err := tx.Query(ctx,
+				`SELECT id, type, user_id, group_id, data FROM events
+				 WHERE notified_at IS NULL
+				 ORDER BY created_at LIMIT $1
+				 FOR UPDATE SKIP LOCKED`, eventBatchSize)
+			if err != nil {
+				return err
+			}
+			events, err := pgx.CollectRows(rows, pgx.RowToStructByPos[pendingEvent])
+			if err != nil {
+				return err
+			}
+			n = len(events)
+
+			ids := make([]uuid.UUID, 0, len(events))
+			for _, e := range events {
+				if err := notifyEvent(ctx, tx, e); err != nil {
+					return fmt.Errorf("event %s: %w", e.ID, err)
+				}
+				ids = append(ids, e.ID)
+			}
+			_, err = tx.Exec(ctx, "UPDATE events SET notified_at = NOW() WHERE id = ANY($1)", ids)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create notifications from events: %w", err)
+		}
+		if n < eventBatchSize {
+			return nil
+		}
+	}
+}
+
+// notifyEvent creates the notifications for one event
+func notifyEvent(ctx context.Context, tx pgx.Tx, e pendingEvent) error {
+	kind, data, recipient, ok, err := fromEvent(e)
+	if err != nil || !ok {
+		return err
+	}
+
+	var recipients []uuid.UUID
+	if recipient != nil {
+		recipients = []uuid.UUID{*recipient}
+	} else if e.GroupID != nil {
+		rows, err := tx.Query(ctx,
+			`SELECT gm.user_id FROM group_members gm
+			 JOIN users u ON u.id = gm.user_id
+			 WHERE gm.group_id = $1 AND gm.user_id <> $2 AND NOT u.is_placeholder`,
+			e.GroupID, e.UserID)
+		if err != nil {
+			return err
+		}
+		if recipients, err = pgx.CollectRows(rows, pgx.RowTo[uuid.UUID]); err != nil {
+			return err
+		}
+	}
+
+	for _, userID := range recipients {
+		if _, err := Create(ctx, tx, userID, e.GroupID, kind, data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
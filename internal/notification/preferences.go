@@ -0,0 +1,113 @@
+package notification
+
+import (
+	"slices"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+	"github.com/yanonymousV2/finance-manager-backend/internal/middleware"
+	"github.com/yanonymousV2/finance-manager-backend/internal/validation"
+)
+
+// Preference is the channels a user gets one notification type on
+type Preference struct {
+	Type string `json:"type"`
+	Channels
+}
+
+// UpdatePreferenceRequest changes the channels given and keeps the others
+type UpdatePreferenceRequest struct {
+	InApp *bool `json:"in_app,omitempty"`
+	Email *bool `json:"email,omitempty"`
+	Push  *bool `json:"push,omitempty"`
+}
+
+// GetPreferences returns the authenticated user's channels for every
+// notification type, with defaults for types they haven't set
+func GetPreferences(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	rows, err := db.Pool.Query(c.Request.Context(),
+		"SELECT type, in_app, email, push FROM notification_preferences WHERE user_id = $1", userID)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to get notification preferences"})
+		return
+	}
+	defer rows.Close()
+
+	set := make(map[string]Channels)
+	for rows.Next() {
+		var kind string
+		var ch Channels
+		if err := rows.Scan(&kind, &ch.InApp, &ch.Email, &ch.Push); err != nil {
+			c.JSON(500, gin.H{"error": "failed to scan notification preference"})
+			return
+		}
+		set[kind] = ch
+	}
+	if err := rows.Err(); err != nil {
+		c.JSON(500, gin.H{"error": "failed to get notification preferences"})
+		return
+	}
+
+	prefs := make([]Preference, 0, len(Types))
+	for _, kind := range Types {
+		ch, ok := set[kind]
+		if !ok {
+			ch = DefaultChannels
+		}
+		prefs = append(prefs, Preference{Type: kind, Channels: ch})
+	}
+
+	c.JSON(200, prefs)
+}
+
+// UpdatePreference sets the channels the authenticated user gets a
+// notification type on. Notifications already created keep their channels.
+func UpdatePreference(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	kind := c.Param("type")
+	if !slices.Contains(Types, kind) {
+		c.JSON(404, gin.H{"error": "unknown notification type"})
+		return
+	}
+
+	var req UpdatePreferenceRequest
+	if !validation.Bind(c, &req) {
+		return
+	}
+	if req.InApp == nil && req.Email == nil && req.Push == nil {
+		c.JSON(400, gin.H{"error": "no fields to update"})
+		return
+	}
+
+	pref := Preference{Type: kind}
+	err := db.Pool.QueryRow(c.Request.Context(),
+		`INSERT INTO notification_preferences (user_id, type, in_app, email, push)
+		 VALUES ($1, $2, COALESCE($3, $6), COALESCE($4, $7), COALESCE($5, $8))
+		 ON CONFLICT (user_id, type) DO UPDATE SET
+		     in_app = COALESCE($3, notification_preferences.in_app),
+		     email = COALESCE($4, notification_preferences.email),
+		     push = COALESCE($5, notification_preferences.push),
+		     updated_at = NOW()
+		 RETURNING in_app, email, push`,
+		userID, kind, req.InApp, req.Email, req.Push,
+		DefaultChannels.InApp, DefaultChannels.Email, DefaultChannels.Push).
+		Scan(&pref.InApp, &pref.Email, &pref.Push)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to update notification preference"})
+		return
+	}
+
+	c.JSON(200, pref)
+}
@@ -0,0 +1,193 @@
+package ocr
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+const (
+	azureAnalyzePath = "/documentintelligence/documentModels/prebuilt-receipt:analyze?api-version=2024-11-30"
+	// azurePollInterval is how often an analysis is checked on while it runs
+	azurePollInterval = time.Second
+)
+
+// Azure reads receipts with the prebuilt receipt model of Azure AI Document
+// Intelligence. An analysis runs asynchronously: the receipt is submitted,
+// then its result is polled until it's done.
+type Azure struct {
+	endpoint     string
+	apiKey       string
+	client       *http.Client
+	pollInterval time.Duration
+}
+
+func NewAzure(endpoint, apiKey string) *Azure {
+	return &Azure{
+		endpoint:     strings.TrimRight(endpoint, "/"),
+		apiKey:       apiKey,
+		client:       &http.Client{Timeout: 60 * time.Second},
+		pollInterval: azurePollInterval,
+	}
+}
+
+func (a *Azure) Name() string {
+	return "azure"
+}
+
+// azureField is a field of an analyzed document; which value is set
+// depends on its type
+type azureField struct {
+	ValueString   *string          `json:"valueString"`
+	ValueDate     *string          `json:"valueDate"`
+	ValueNumber   *decimal.Decimal `json:"valueNumber"`
+	ValueCurrency *struct {
+		Amount       decimal.Decimal `json:"amount"`
+		CurrencyCode *string         `json:"currencyCode"`
+	} `json:"valueCurrency"`
+	ValueArray  []azureField          `json:"valueArray"`
+	ValueObject map[string]azureField `json:"valueObject"`
+}
+
+// amount is a currency or number field's amount
+func (f *azureField) amount() *decimal.Decimal {
+	if f == nil {
+		return nil
+	}
+	if f.ValueCurrency != nil {
+		return &f.ValueCurrency.Amount
+	}
+	return f.ValueNumber
+}
+
+type azureResult struct {
+	Status string `json:"status"`
+	Error  *struct {
+		Code    string `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+	AnalyzeResult struct {
+		Documents []struct {
+			Fields map[string]azureField `json:"fields"`
+		} `json:"documents"`
+	} `json:"analyzeResult"`
+}
+
+// do sends a request with the key and decodes the response into result,
+// returning the response for its headers
+func (a *Azure) do(req *http.Request, result *azureResult) (*http.Response, error) {
+	req.Header.Set("Ocp-Apim-Subscription-Key", a.apiKey)
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 10<<20))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, result); err != nil {
+			return nil, fmt.Errorf("azure returned %s", resp.Status)
+		}
+	}
+	if resp.StatusCode >= 300 {
+		if result.Error != nil {
+			return nil, fmt.Errorf("azure: %s (%s)", result.Error.Message, result.Error.Code)
+		}
+		return nil, fmt.Errorf("azure returned %s", resp.Status)
+	}
+	return resp, nil
+}
+
+func (a *Azure) Extract(ctx context.Context, file []byte, contentType string) (Extraction, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.endpoint+azureAnalyzePath, bytes.NewReader(file))
+	if err != nil {
+		return Extraction{}, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	resp, err := a.do(req, &azureResult{})
+	if err != nil {
+		return Extraction{}, err
+	}
+	operation := resp.Header.Get("Operation-Location")
+	// The key is sent along when polling, so only to the endpoint
+	if !strings.HasPrefix(operation, a.endpoint+"/") {
+		return Extraction{}, errors.New("azure returned no operation to poll")
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return Extraction{}, ctx.Err()
+		case <-time.After(a.pollInterval):
+		}
+
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, operation, nil)
+		if err != nil {
+			return Extraction{}, err
+		}
+		var result azureResult
+		if _, err := a.do(req, &result); err != nil {
+			return Extraction{}, err
+		}
+		switch result.Status {
+		case "succeeded":
+			return azureExtraction(result), nil
+		case "failed", "canceled":
+			if result.Error != nil {
+				return Extraction{}, fmt.Errorf("azure: %s (%s)", result.Error.Message, result.Error.Code)
+			}
+			return Extraction{}, fmt.Errorf("azure analysis %s", result.Status)
+		}
+	}
+}
+
+// azureExtraction reads the fields of the first receipt in a result
+func azureExtraction(result azureResult) Extraction {
+	e := Extraction{LineItems: []LineItem{}}
+	if len(result.AnalyzeResult.Documents) == 0 {
+		return e
+	}
+	fields := result.AnalyzeResult.Documents[0].Fields
+
+	if f, ok := fields["MerchantName"]; ok {
+		e.Merchant = f.ValueString
+	}
+	if f, ok := fields["TransactionDate"]; ok && f.ValueDate != nil {
+		if date, err := time.Parse(time.DateOnly, *f.ValueDate); err == nil {
+			e.Date = &date
+		}
+	}
+	if f, ok := fields["Total"]; ok {
+		e.Total = f.amount()
+		if f.ValueCurrency != nil {
+			e.Currency = f.ValueCurrency.CurrencyCode
+		}
+	}
+	for _, item := range fields["Items"].ValueArray {
+		line := LineItem{}
+		if f, ok := item.ValueObject["Description"]; ok && f.ValueString != nil {
+			line.Description = *f.ValueString
+		}
+		if f, ok := item.ValueObject["Quantity"]; ok {
+			line.Quantity = f.ValueNumber
+		}
+		if f, ok := item.ValueObject["Price"]; ok {
+			line.UnitPrice = f.amount()
+		}
+		if f, ok := item.ValueObject["TotalPrice"]; ok {
+			line.Total = f.amount()
+		}
+		e.LineItems = append(e.LineItems, line)
+	}
+	return e
+}
@@ -0,0 +1,127 @@
+package ocr
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+	"github.com/yanonymousV2/finance-manager-backend/internal/middleware"
+)
+
+// Draft pre-fills the receipt's expense from its extraction. It has the
+// fields of an expense update, so it can be sent back as one once the user
+// has checked it.
+type Draft struct {
+	Amount      *string    `json:"amount,omitempty"`
+	Currency    *string    `json:"currency,omitempty"`
+	Merchant    *string    `json:"merchant,omitempty"`
+	Notes       *string    `json:"notes,omitempty"`
+	ExpenseDate *time.Time `json:"expense_date,omitempty"`
+}
+
+// ExtractionResponse is a receipt's extraction. Extraction and Draft are
+// only set once it has succeeded; Error is the last attempt's.
+type ExtractionResponse struct {
+	ReceiptID   uuid.UUID   `json:"receipt_id"`
+	ExpenseID   uuid.UUID   `json:"expense_id"`
+	Status      string      `json:"status"`
+	Provider    string      `json:"provider"`
+	Attempts    int         `json:"attempts"`
+	Error       *string     `json:"error,omitempty"`
+	Extraction  *Extraction `json:"extraction,omitempty"`
+	Draft       *Draft      `json:"draft,omitempty"`
+	CreatedAt   time.Time   `json:"created_at"`
+	CompletedAt *time.Time  `json:"completed_at,omitempty"`
+}
+
+// draft turns an extraction into an expense draft. The line items go in
+// the notes, one per line.
+func draft(e Extraction) Draft {
+	d := Draft{Merchant: e.Merchant, ExpenseDate: e.Date}
+	if e.Total != nil {
+		amount := e.Total.StringFixed(2)
+		d.Amount = &amount
+		// A currency only describes an amount
+		d.Currency = e.Currency
+	}
+
+	var notes strings.Builder
+	for _, item := range e.LineItems {
+		if notes.Len() > 0 {
+			notes.WriteByte('\n')
+		}
+		if item.Quantity != nil && !item.Quantity.Equal(decimal.NewFromInt(1)) {
+			fmt.Fprintf(&notes, "%s × ", item.Quantity)
+		}
+		notes.WriteString(item.Description)
+		if item.Total != nil {
+			fmt.Fprintf(&notes, " %s", item.Total.StringFixed(2))
+		}
+	}
+	if notes.Len() > 0 {
+		s := notes.String()
+		d.Notes = &s
+	}
+	return d
+}
+
+// GetExtraction returns what was read off a receipt, with a draft of its
+// expense once reading it has succeeded
+func GetExtraction(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	receiptID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid attachment id"})
+		return
+	}
+
+	resp := ExtractionResponse{ReceiptID: receiptID}
+	var status *string
+	var e Extraction
+	var lineItems []byte
+	err = db.Pool.QueryRow(c.Request.Context(),
+		`SELECT r.expense_id, x.status, COALESCE(x.provider, ''), COALESCE(x.attempts, 0), x.error,
+		        x.merchant, x.receipt_date, x.total, x.currency, x.line_items, COALESCE(x.created_at, r.created_at), x.completed_at
+		 FROM receipts r LEFT JOIN receipt_extractions x ON x.receipt_id = r.id
+		 WHERE r.id = $1 AND r.user_id = $2`,
+		receiptID, userID).Scan(&resp.ExpenseID, &status, &resp.Provider, &resp.Attempts, &resp.Error,
+		&e.Merchant, &e.Date, &e.Total, &e.Currency, &lineItems, &resp.CreatedAt, &resp.CompletedAt)
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(404, gin.H{"error": "attachment not found"})
+		return
+	}
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to get extraction"})
+		return
+	}
+	// Receipts uploaded while no provider was configured aren't read
+	if status == nil {
+		c.JSON(404, gin.H{"error": "attachment has no extraction"})
+		return
+	}
+	resp.Status = *status
+
+	if resp.Status == StatusSucceeded {
+		if err := json.Unmarshal(lineItems, &e.LineItems); err != nil {
+			c.JSON(500, gin.H{"error": "failed to read extraction"})
+			return
+		}
+		d := draft(e)
+		resp.Extraction, resp.Draft = &e, &d
+	}
+
+	c.JSON(200, resp)
+}
@@ -0,0 +1,170 @@
+package ocr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+	"github.com/yanonymousV2/finance-manager-backend/internal/storage"
+)
+
+// Extraction statuses
+const (
+	StatusPending   = "pending"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+const (
+	// maxAttempts is how many times a receipt is sent to the provider
+	// before its extraction is failed
+	maxAttempts = 3
+	// batchSize is how many receipts are claimed at a time
+	batchSize = 10
+	// extractTimeout bounds one attempt. A claimed receipt isn't picked up
+	// by another server for twice as long.
+	extractTimeout = 2 * time.Minute
+	// maxFileSize caps the file read for an extraction; uploads are
+	// smaller
+	maxFileSize = 20 << 20
+)
+
+// backoff is how long to wait after the given failed attempt: 5 minutes,
+// then 25
+func backoff(attempt int) time.Duration {
+	d := 5 * time.Minute
+	for range attempt - 1 {
+		d *= 5
+	}
+	return d
+}
+
+// Enqueue queues a receipt for extraction by provider
+func Enqueue(ctx context.Context, db *db.DB, receiptID uuid.UUID, provider Provider) error {
+	_, err := db.Pool.Exec(ctx,
+		`INSERT INTO receipt_extractions (receipt_id, provider) VALUES ($1, $2)
+		 ON CONFLICT (receipt_id) DO NOTHING`,
+		receiptID, provider.Name())
+	return err
+}
+
+// claimSQL takes a batch of due extractions, counting the attempt and
+// pushing next_attempt_at past the attempt's timeout so no other server
+// takes them meanwhile
+const claimSQL = `
+	UPDATE receipt_extractions e
+	SET attempts = e.attempts + 1, next_attempt_at = NOW() + $2::interval
+	FROM receipts r
+	WHERE e.receipt_id IN (
+		SELECT receipt_id FROM receipt_extractions
+		WHERE status = 'pending' AND next_attempt_at <= NOW()
+		ORDER BY next_attempt_at LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	) AND r.id = e.receipt_id
+	RETURNING e.receipt_id, e.attempts, r.storage_key, r.content_type`
+
+// claimed is a receipt claimed for an extraction attempt
+type claimed struct {
+	receiptID   uuid.UUID
+	attempt     int
+	storageKey  string
+	contentType string
+}
+
+// RunOnce reads every receipt due for extraction with provider
+func RunOnce(ctx context.Context, db *db.DB, store storage.Storage, provider Provider) error {
+	for {
+		// Stop between batches on shutdown
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		rows, err := db.Pool.Query(ctx, claimSQL, batchSize, 2*extractTimeout)
+		if err != nil {
+			return fmt.Errorf("failed to claim receipts: %w", err)
+		}
+		var due []claimed
+		for rows.Next() {
+			var c claimed
+			if err := rows.Scan(&c.receiptID, &c.attempt, &c.storageKey, &c.contentType); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan receipt: %w", err)
+			}
+			due = append(due, c)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("failed to claim receipts: %w", err)
+		}
+
+		for _, c := range due {
+			extraction, err := extract(ctx, store, provider, c)
+			if err := record(context.WithoutCancel(ctx), db, provider, c, extraction, err); err != nil {
+				slog.Error("failed to record receipt extraction", "receipt_id", c.receiptID, "error", err)
+			}
+		}
+		if len(due) < batchSize {
+			return nil
+		}
+	}
+}
+
+// errMissingFile fails an extraction whose file is gone without retrying
+var errMissingFile = errors.New("receipt file is missing")
+
+func extract(ctx context.Context, store storage.Storage, provider Provider, c claimed) (Extraction, error) {
+	ctx, cancel := context.WithTimeout(ctx, extractTimeout)
+	defer cancel()
+
+	f, err := store.Get(ctx, c.storageKey)
+	if errors.Is(err, storage.ErrNotFound) {
+		return Extraction{}, errMissingFile
+	}
+	if err != nil {
+		return Extraction{}, fmt.Errorf("failed to read receipt: %w", err)
+	}
+	file, err := io.ReadAll(io.LimitReader(f, maxFileSize))
+	f.Close()
+	if err != nil {
+		return Extraction{}, fmt.Errorf("failed to read receipt: %w", err)
+	}
+
+	extraction, err := provider.Extract(ctx, file, c.contentType)
+	if err != nil {
+		return Extraction{}, err
+	}
+	extraction.normalize(time.Now())
+	return extraction, nil
+}
+
+// record stores an attempt's extraction, or its error: the extraction is
+// retried after a backoff until it runs out of attempts
+func record(ctx context.Context, db *db.DB, provider Provider, c claimed, e Extraction, extractErr error) error {
+	if extractErr == nil {
+		_, err := db.Pool.Exec(ctx,
+			`UPDATE receipt_extractions
+			 SET status = 'succeeded', provider = $2, merchant = $3, receipt_date = $4, total = $5, currency = $6,
+			     line_items = $7, error = NULL, completed_at = NOW()
+			 WHERE receipt_id = $1`,
+			c.receiptID, provider.Name(), e.Merchant, e.Date, e.Total, e.Currency, e.LineItems)
+		return err
+	}
+
+	slog.Warn("receipt extraction failed", "receipt_id", c.receiptID, "attempt", c.attempt, "error", extractErr)
+	if c.attempt >= maxAttempts || errors.Is(extractErr, errMissingFile) {
+		_, err := db.Pool.Exec(ctx,
+			`UPDATE receipt_extractions SET status = 'failed', error = $2, completed_at = NOW() WHERE receipt_id = $1`,
+			c.receiptID, extractErr.Error())
+		return err
+	}
+	_, err := db.Pool.Exec(ctx,
+		`UPDATE receipt_extractions SET error = $2, next_attempt_at = NOW() + $3::interval WHERE receipt_id = $1`,
+		c.receiptID, extractErr.Error(), backoff(c.attempt))
+	return err
+}
@@ -0,0 +1,126 @@
+package ocr
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+const mindeeURL = "https://api.mindee.net/v1/products/mindee/expense_receipts/v5/predict"
+
+// Mindee reads receipts with Mindee's receipt API, which answers in one
+// request
+type Mindee struct {
+	url    string
+	apiKey string
+	client *http.Client
+}
+
+func NewMindee(apiKey string) *Mindee {
+	return &Mindee{
+		url:    mindeeURL,
+		apiKey: apiKey,
+		client: &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+func (m *Mindee) Name() string {
+	return "mindee"
+}
+
+// mindeeField is a value Mindee read, with null when it found none
+type mindeeField[T any] struct {
+	Value *T `json:"value"`
+}
+
+type mindeeResponse struct {
+	APIRequest struct {
+		Error struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	} `json:"api_request"`
+	Document struct {
+		Inference struct {
+			Prediction struct {
+				SupplierName mindeeField[string]          `json:"supplier_name"`
+				Date         mindeeField[string]          `json:"date"`
+				TotalAmount  mindeeField[decimal.Decimal] `json:"total_amount"`
+				Locale       struct {
+					Currency *string `json:"currency"`
+				} `json:"locale"`
+				LineItems []struct {
+					Description *string          `json:"description"`
+					Quantity    *decimal.Decimal `json:"quantity"`
+					UnitPrice   *decimal.Decimal `json:"unit_price"`
+					TotalAmount *decimal.Decimal `json:"total_amount"`
+				} `json:"line_items"`
+			} `json:"prediction"`
+		} `json:"inference"`
+	} `json:"document"`
+}
+
+func (m *Mindee) Extract(ctx context.Context, file []byte, contentType string) (Extraction, error) {
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("document", "receipt")
+	if err != nil {
+		return Extraction{}, err
+	}
+	part.Write(file)
+	if err := form.Close(); err != nil {
+		return Extraction{}, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.url, &body)
+	if err != nil {
+		return Extraction{}, err
+	}
+	req.Header.Set("Authorization", "Token "+m.apiKey)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+
+	resp, err := m.client.Do(req)
+	if err != nil {
+		return Extraction{}, err
+	}
+	defer resp.Body.Close()
+
+	var result mindeeResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, 10<<20)).Decode(&result); err != nil {
+		return Extraction{}, fmt.Errorf("mindee returned %s", resp.Status)
+	}
+	if resp.StatusCode >= 300 {
+		if apiErr := result.APIRequest.Error; apiErr.Message != "" {
+			return Extraction{}, fmt.Errorf("mindee: %s (%s)", apiErr.Message, apiErr.Code)
+		}
+		return Extraction{}, fmt.Errorf("mindee returned %s", resp.Status)
+	}
+
+	prediction := result.Document.Inference.Prediction
+	e := Extraction{
+		Merchant:  prediction.SupplierName.Value,
+		Total:     prediction.TotalAmount.Value,
+		Currency:  prediction.Locale.Currency,
+		LineItems: []LineItem{},
+	}
+	if prediction.Date.Value != nil {
+		if date, err := time.Parse(time.DateOnly, *prediction.Date.Value); err == nil {
+			e.Date = &date
+		}
+	}
+	for _, item := range prediction.LineItems {
+		line := LineItem{Quantity: item.Quantity, UnitPrice: item.UnitPrice, Total: item.TotalAmount}
+		if item.Description != nil {
+			line.Description = *item.Description
+		}
+		e.LineItems = append(e.LineItems, line)
+	}
+	return e, nil
+}
@@ -0,0 +1,109 @@
+// Package ocr reads receipts. A receipt uploaded to a personal expense is
+// queued for extraction when a provider is configured; a background job
+// sends it to the provider, Mindee or Azure AI Document Intelligence, and
+// stores the merchant, date, total and line items it read, which the API
+// offers as a draft of the expense.
+package ocr
+
+import (
+	"context"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/config"
+)
+
+// LineItem is one line of a receipt. Any amount the provider couldn't
+// read is nil.
+type LineItem struct {
+	Description string           `json:"description"`
+	Quantity    *decimal.Decimal `json:"quantity,omitempty"`
+	UnitPrice   *decimal.Decimal `json:"unit_price,omitempty"`
+	Total       *decimal.Decimal `json:"total,omitempty"`
+}
+
+// Extraction is what was read off a receipt. Any field the provider
+// couldn't read is nil.
+type Extraction struct {
+	Merchant  *string          `json:"merchant,omitempty"`
+	Date      *time.Time       `json:"date,omitempty"`
+	Total     *decimal.Decimal `json:"total,omitempty"`
+	Currency  *string          `json:"currency,omitempty"`
+	LineItems []LineItem       `json:"line_items"`
+}
+
+// Provider reads a receipt image or PDF
+type Provider interface {
+	// Name identifies the provider in stored extractions
+	Name() string
+	Extract(ctx context.Context, file []byte, contentType string) (Extraction, error)
+}
+
+// New returns the provider cfg names, or nil when receipts aren't read
+func New(cfg *config.Config) Provider {
+	switch cfg.OCRProvider {
+	case "mindee":
+		return NewMindee(cfg.OCRAPIKey)
+	case "azure":
+		return NewAzure(cfg.OCREndpoint, cfg.OCRAPIKey)
+	default:
+		return nil
+	}
+}
+
+// truncate cuts s to at most n runes
+func truncate(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n])
+}
+
+// normalize drops what a provider read that can't be stored or used in an
+// expense: blank text, amounts that aren't positive, currencies that aren't
+// ISO codes and dates after tomorrow, which are misreads
+func (e *Extraction) normalize(now time.Time) {
+	if e.Merchant != nil {
+		merchant := truncate(strings.TrimSpace(*e.Merchant), 255)
+		e.Merchant = &merchant
+		if merchant == "" {
+			e.Merchant = nil
+		}
+	}
+	if e.Date != nil && e.Date.After(now.AddDate(0, 0, 1)) {
+		e.Date = nil
+	}
+	positive := func(d *decimal.Decimal) *decimal.Decimal {
+		if d == nil || !d.IsPositive() {
+			return nil
+		}
+		rounded := d.Round(2)
+		return &rounded
+	}
+	e.Total = positive(e.Total)
+	if e.Currency != nil {
+		currency := strings.ToUpper(strings.TrimSpace(*e.Currency))
+		e.Currency = &currency
+		if len(currency) != 3 {
+			e.Currency = nil
+		}
+	}
+
+	items := make([]LineItem, 0, len(e.LineItems))
+	for _, item := range e.LineItems {
+		item.Description = truncate(strings.TrimSpace(item.Description), 255)
+		if item.Description == "" && item.Total == nil {
+			continue
+		}
+		if item.Quantity != nil && !item.Quantity.IsPositive() {
+			item.Quantity = nil
+		}
+		item.UnitPrice = positive(item.UnitPrice)
+		item.Total = positive(item.Total)
+		items = append(items, item)
+	}
+	e.LineItems = items
+}
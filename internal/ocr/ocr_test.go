@@ -0,0 +1,191 @@
+package ocr
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func dec(s string) *decimal.Decimal {
+	d := decimal.RequireFromString(s)
+	return &d
+}
+
+func str(s string) *string {
+	return &s
+}
+
+func TestMindee(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Token key", r.Header.Get("Authorization"))
+		file, _, err := r.FormFile("document")
+		require.NoError(t, err)
+		data, _ := io.ReadAll(file)
+		assert.Equal(t, "receipt image", string(data))
+
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"document": {"inference": {"prediction": {
+			"supplier_name": {"value": "Blue Bottle Coffee"},
+			"date": {"value": "2025-01-20"},
+			"total_amount": {"value": 9.5},
+			"locale": {"currency": "USD"},
+			"line_items": [
+				{"description": "Latte", "quantity": 2, "unit_price": 4.5, "total_amount": 9},
+				{"description": "Tip", "quantity": null, "unit_price": null, "total_amount": 0.5}
+			]
+		}}}}`))
+	}))
+	defer srv.Close()
+
+	m := NewMindee("key")
+	m.url = srv.URL
+	e, err := m.Extract(t.Context(), []byte("receipt image"), "image/jpeg")
+	require.NoError(t, err)
+	assert.Equal(t, "Blue Bottle Coffee", *e.Merchant)
+	assert.Equal(t, "2025-01-20", e.Date.Format(time.DateOnly))
+	assert.True(t, e.Total.Equal(decimal.RequireFromString("9.5")))
+	assert.Equal(t, "USD", *e.Currency)
+	require.Len(t, e.LineItems, 2)
+	assert.True(t, e.LineItems[0].Quantity.Equal(decimal.NewFromInt(2)))
+	assert.Nil(t, e.LineItems[1].Quantity)
+}
+
+func TestMindeeError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"api_request": {"error": {"code": "Unauthorized", "message": "Authorization required"}, "status_code": 401}}`))
+	}))
+	defer srv.Close()
+
+	m := NewMindee("key")
+	m.url = srv.URL
+	_, err := m.Extract(t.Context(), []byte("receipt image"), "image/jpeg")
+	assert.EqualError(t, err, "mindee: Authorization required (Unauthorized)")
+}
+
+func TestAzure(t *testing.T) {
+	polls := 0
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "key", r.Header.Get("Ocp-Apim-Subscription-Key"))
+		if r.Method == http.MethodPost {
+			assert.Equal(t, "/documentintelligence/documentModels/prebuilt-receipt:analyze", r.URL.Path)
+			assert.Equal(t, "image/png", r.Header.Get("Content-Type"))
+			w.Header().Set("Operation-Location", srv.URL+"/documentintelligence/documentModels/prebuilt-receipt/analyzeResults/1")
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+
+		polls++
+		if polls == 1 {
+			w.Write([]byte(`{"status": "running"}`))
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"status": "succeeded",
+			"analyzeResult": map[string]any{"documents": []any{map[string]any{"fields": map[string]any{
+				"MerchantName":    map[string]any{"type": "string", "valueString": "Contoso"},
+				"TransactionDate": map[string]any{"type": "date", "valueDate": "2025-01-20"},
+				"Total":           map[string]any{"type": "currency", "valueCurrency": map[string]any{"amount": 14.5, "currencyCode": "EUR"}},
+				"Items": map[string]any{"type": "array", "valueArray": []any{
+					map[string]any{"type": "object", "valueObject": map[string]any{
+						"Description": map[string]any{"valueString": "Sandwich"},
+						"Quantity":    map[string]any{"valueNumber": 1},
+						"TotalPrice":  map[string]any{"valueCurrency": map[string]any{"amount": 14.5}},
+					}},
+				}},
+			}}}},
+		})
+	}))
+	defer srv.Close()
+
+	a := NewAzure(srv.URL+"/", "key")
+	a.pollInterval = time.Millisecond
+	e, err := a.Extract(t.Context(), []byte("receipt image"), "image/png")
+	require.NoError(t, err)
+	assert.Equal(t, 2, polls)
+	assert.Equal(t, "Contoso", *e.Merchant)
+	assert.Equal(t, "2025-01-20", e.Date.Format(time.DateOnly))
+	assert.True(t, e.Total.Equal(decimal.RequireFromString("14.5")))
+	assert.Equal(t, "EUR", *e.Currency)
+	require.Len(t, e.LineItems, 1)
+	assert.Equal(t, "Sandwich", e.LineItems[0].Description)
+	assert.True(t, e.LineItems[0].Total.Equal(decimal.RequireFromString("14.5")))
+}
+
+func TestAzureOperationElsewhere(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Operation-Location", "https://attacker.example/results/1")
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	_, err := NewAzure(srv.URL, "key").Extract(t.Context(), []byte("receipt image"), "image/png")
+	assert.Error(t, err, "the key isn't sent to another host")
+}
+
+func TestNormalize(t *testing.T) {
+	now := time.Date(2025, 1, 20, 12, 0, 0, 0, time.UTC)
+	future := now.AddDate(0, 1, 0)
+	e := Extraction{
+		Merchant: str("  "),
+		Date:     &future,
+		Total:    dec("12.345"),
+		Currency: str("usd"),
+		LineItems: []LineItem{
+			{Description: " Latte ", Quantity: dec("0"), Total: dec("4.5")},
+			{Description: "Discount", Total: dec("-1")},
+			{Description: " "},
+		},
+	}
+	e.normalize(now)
+	assert.Nil(t, e.Merchant)
+	assert.Nil(t, e.Date)
+	assert.Equal(t, "12.35", e.Total.String())
+	assert.Equal(t, "USD", *e.Currency)
+	assert.Equal(t, []LineItem{
+		{Description: "Latte", Total: dec("4.50")},
+		{Description: "Discount"},
+	}, e.LineItems)
+
+	e = Extraction{Total: dec("0"), Currency: str("dollars")}
+	e.normalize(now)
+	assert.Nil(t, e.Total)
+	assert.Nil(t, e.Currency)
+}
+
+func TestDraft(t *testing.T) {
+	date := time.Date(2025, 1, 20, 0, 0, 0, 0, time.UTC)
+	d := draft(Extraction{
+		Merchant: str("Blue Bottle Coffee"),
+		Date:     &date,
+		Total:    dec("9.5"),
+		Currency: str("USD"),
+		LineItems: []LineItem{
+			{Description: "Latte", Quantity: dec("2"), Total: dec("9")},
+			{Description: "Tip", Quantity: dec("1"), Total: dec("0.5")},
+		},
+	})
+	assert.Equal(t, "9.50", *d.Amount)
+	assert.Equal(t, "USD", *d.Currency)
+	assert.Equal(t, "Blue Bottle Coffee", *d.Merchant)
+	assert.Equal(t, date, *d.ExpenseDate)
+	assert.Equal(t, "2 × Latte 9.00\nTip 0.50", *d.Notes)
+
+	d = draft(Extraction{Currency: str("USD"), LineItems: []LineItem{}})
+	assert.Nil(t, d.Amount)
+	assert.Nil(t, d.Currency, "a currency without an amount")
+	assert.Nil(t, d.Notes)
+}
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, 5*time.Minute, backoff(1))
+	assert.Equal(t, 25*time.Minute, backoff(2))
+}
@@ -0,0 +1,253 @@
+package openapi
+
+import (
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+)
+
+// Operation describes one route. The document is built from a table of them
+// and the Go types of their request and response bodies.
+type Operation struct {
+	Method string
+	// Path is in gin syntax, with :param and *param segments
+	Path    string
+	Tag     string
+	Summary string
+	// Public routes work without a bearer token
+	Public bool
+	// Request is a value of the JSON body's type, nil without a body
+	Request any
+	// Response is a value of the success body's type, nil for a free-form
+	// object
+	Response any
+	// Status is the success status, 200 by default
+	Status int
+	// ContentType is the success body's type, application/json by default.
+	// Other types are documented as binary.
+	ContentType string
+}
+
+// Document is an OpenAPI document, ready to be encoded as JSON
+type Document map[string]any
+
+var (
+	timeType    = reflect.TypeOf(time.Time{})
+	decimalType = reflect.TypeOf(decimal.Decimal{})
+	uuidType    = reflect.TypeOf(uuid.UUID{})
+)
+
+// Path converts a gin path to OpenAPI syntax and returns its parameter names
+func Path(ginPath string) (string, []string) {
+	segments := strings.Split(ginPath, "/")
+	var params []string
+	for i, s := range segments {
+		if strings.HasPrefix(s, ":") || strings.HasPrefix(s, "*") {
+			params = append(params, s[1:])
+			segments[i] = "{" + s[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/"), params
+}
+
+// schemas collects the named types the document refers to
+type schemas map[string]any
+
+// name is how a struct type appears under components, qualified by its
+// package since several packages share type names
+func name(t reflect.Type) string {
+	pkg := t.PkgPath()
+	if i := strings.LastIndex(pkg, "/"); i >= 0 {
+		pkg = pkg[i+1:]
+	}
+	return pkg + "." + t.Name()
+}
+
+// schema returns the schema of t, adding the structs it refers to to s
+func (s schemas) schema(t reflect.Type) map[string]any {
+	switch t {
+	case timeType:
+		return map[string]any{"type": "string", "format": "date-time"}
+	case decimalType:
+		return map[string]any{"type": "string", "format": "decimal"}
+	case uuidType:
+		return map[string]any{"type": "string", "format": "uuid"}
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		inner := s.schema(t.Elem())
+		if _, ref := inner["$ref"]; ref {
+			return inner
+		}
+		inner["nullable"] = true
+		return inner
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}
+	case reflect.String:
+		return map[string]any{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "format": "byte"}
+		}
+		return map[string]any{"type": "array", "items": s.schema(t.Elem())}
+	case reflect.Map:
+		return map[string]any{"type": "object", "additionalProperties": s.schema(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return s.object(t)
+		}
+		key := name(t)
+		if _, ok := s[key]; !ok {
+			// Claimed before the fields so self-referencing types end
+			s[key] = nil
+			s[key] = s.object(t)
+		}
+		return map[string]any{"$ref": "#/components/schemas/" + key}
+	default:
+		// Interfaces can hold anything
+		return map[string]any{}
+	}
+}
+
+// object returns the schema of a struct's JSON fields. Embedded structs'
+// fields are merged in, as encoding/json does.
+func (s schemas) object(t reflect.Type) map[string]any {
+	properties := map[string]any{}
+	var required []string
+	s.fields(t, properties, &required)
+
+	object := map[string]any{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		object["required"] = required
+	}
+	return object
+}
+
+func (s schemas) fields(t reflect.Type, properties map[string]any, required *[]string) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		fieldName, _, _ := strings.Cut(tag, ",")
+		if f.Anonymous && fieldName == "" && f.Type.Kind() == reflect.Struct {
+			s.fields(f.Type, properties, required)
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if fieldName == "" {
+			fieldName = f.Name
+		}
+		properties[fieldName] = s.schema(f.Type)
+		if strings.Contains(f.Tag.Get("validate"), "required") {
+			*required = append(*required, fieldName)
+		}
+	}
+}
+
+// Build returns the document for ops
+func Build(title, version string, ops []Operation) Document {
+	s := schemas{}
+	s["Error"] = map[string]any{
+		"type":       "object",
+		"properties": map[string]any{"error": map[string]any{"type": "string"}},
+	}
+
+	paths := map[string]any{}
+	for _, op := range ops {
+		path, params := Path(op.Path)
+		item, ok := paths[path].(map[string]any)
+		if !ok {
+			item = map[string]any{}
+			paths[path] = item
+		}
+
+		operation := map[string]any{
+			"summary": op.Summary,
+			"tags":    []string{op.Tag},
+		}
+		if len(params) > 0 {
+			parameters := make([]any, len(params))
+			for i, p := range params {
+				parameters[i] = map[string]any{
+					"name": p, "in": "path", "required": true,
+					"schema": map[string]any{"type": "string"},
+				}
+			}
+			operation["parameters"] = parameters
+		}
+		if !op.Public {
+			operation["security"] = []any{map[string]any{"bearerAuth": []string{}}}
+		}
+		if op.Request != nil {
+			operation["requestBody"] = map[string]any{
+				"required": true,
+				"content": map[string]any{
+					"application/json": map[string]any{"schema": s.schema(reflect.TypeOf(op.Request))},
+				},
+			}
+		}
+
+		status := op.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		contentType := op.ContentType
+		var body map[string]any
+		switch {
+		case contentType != "" && contentType != "application/json":
+			body = map[string]any{"type": "string", "format": "binary"}
+		case op.Response != nil:
+			contentType = "application/json"
+			body = s.schema(reflect.TypeOf(op.Response))
+		default:
+			contentType = "application/json"
+			body = map[string]any{"type": "object"}
+		}
+		success := map[string]any{
+			"description": http.StatusText(status),
+			"content":     map[string]any{contentType: map[string]any{"schema": body}},
+		}
+		// Redirects have no body to describe
+		if status >= 300 && status < 400 {
+			delete(success, "content")
+		}
+		operation["responses"] = map[string]any{
+			strconv.Itoa(status): success,
+			"default": map[string]any{
+				"description": "Error",
+				"content": map[string]any{
+					"application/json": map[string]any{"schema": map[string]any{"$ref": "#/components/schemas/Error"}},
+				},
+			},
+		}
+
+		item[strings.ToLower(op.Method)] = operation
+	}
+
+	return Document{
+		"openapi": "3.0.3",
+		"info":    map[string]any{"title": title, "version": version},
+		"paths":   paths,
+		"components": map[string]any{
+			"schemas": map[string]any(s),
+			"securitySchemes": map[string]any{
+				"bearerAuth": map[string]any{"type": "http", "scheme": "bearer", "bearerFormat": "JWT"},
+			},
+		},
+	}
+}
@@ -0,0 +1,83 @@
+package openapi
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type base struct {
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+type node struct {
+	base
+	Name     string          `json:"name" validate:"required,min=1"`
+	Amount   decimal.Decimal `json:"amount"`
+	Note     *string         `json:"note,omitempty"`
+	Children []node          `json:"children,omitempty"`
+	Secret   string          `json:"-"`
+	hidden   int
+}
+
+func TestPath(t *testing.T) {
+	path, params := Path("/groups/:id/members/:userId/stats")
+	assert.Equal(t, "/groups/{id}/members/{userId}/stats", path)
+	assert.Equal(t, []string{"id", "userId"}, params)
+
+	path, params = Path("/files/*key")
+	assert.Equal(t, "/files/{key}", path)
+	assert.Equal(t, []string{"key"}, params)
+}
+
+func TestBuild(t *testing.T) {
+	doc := Build("Test", "1.0.0", []Operation{
+		{Method: "POST", Path: "/nodes", Tag: "nodes", Summary: "Create node", Request: node{}, Response: node{}, Status: 201},
+		{Method: "GET", Path: "/nodes/:id/report.pdf", Tag: "nodes", Summary: "Report", ContentType: "application/pdf"},
+		{Method: "GET", Path: "/health", Tag: "health", Summary: "Health", Public: true},
+	})
+
+	// Round-trip through JSON to look at it as clients will
+	data, err := json.Marshal(doc)
+	require.NoError(t, err)
+	var spec map[string]any
+	require.NoError(t, json.Unmarshal(data, &spec))
+
+	schemas := spec["components"].(map[string]any)["schemas"].(map[string]any)
+	require.Contains(t, schemas, "openapi.node")
+	object := schemas["openapi.node"].(map[string]any)
+	properties := object["properties"].(map[string]any)
+	assert.ElementsMatch(t, []string{"id", "created_at", "name", "amount", "note", "children"}, keys(properties))
+	assert.Equal(t, map[string]any{"type": "string", "format": "uuid"}, properties["id"])
+	assert.Equal(t, map[string]any{"type": "string", "format": "decimal"}, properties["amount"])
+	assert.Equal(t, map[string]any{"type": "string", "nullable": true}, properties["note"])
+	assert.Equal(t, map[string]any{"type": "array", "items": map[string]any{"$ref": "#/components/schemas/openapi.node"}}, properties["children"])
+	assert.Equal(t, []any{"name"}, object["required"])
+
+	paths := spec["paths"].(map[string]any)
+	create := paths["/nodes"].(map[string]any)["post"].(map[string]any)
+	assert.Contains(t, create["responses"], "201")
+	assert.Contains(t, create, "security")
+
+	report := paths["/nodes/{id}/report.pdf"].(map[string]any)["get"].(map[string]any)
+	content := report["responses"].(map[string]any)["200"].(map[string]any)["content"].(map[string]any)
+	assert.Contains(t, content, "application/pdf")
+	assert.Len(t, report["parameters"], 1)
+
+	health := paths["/health"].(map[string]any)["get"].(map[string]any)
+	assert.NotContains(t, health, "security")
+}
+
+func keys(m map[string]any) []string {
+	out := make([]string, 0, len(m))
+	for k := range m {
+		out = append(out, k)
+	}
+	return out
+}
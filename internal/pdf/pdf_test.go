@@ -0,0 +1,54 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteTo(t *testing.T) {
+	doc := New()
+	doc.AddPage()
+	doc.Text(50, 50, 12, true, Color{}, "Total (USD): 12.50")
+	doc.AddPage()
+	doc.Rect(50, 100, 200, 10, Color{R: 255})
+
+	var buf bytes.Buffer
+	_, err := doc.WriteTo(&buf)
+	require.NoError(t, err)
+	out := buf.Bytes()
+
+	assert.True(t, bytes.HasPrefix(out, []byte("%PDF-1.4\n")))
+	assert.True(t, bytes.HasSuffix(out, []byte("%%EOF\n")))
+	assert.Contains(t, string(out), "/Kids [5 0 R 7 0 R] /Count 2")
+	assert.Contains(t, string(out), `(Total \(USD\): 12.50) Tj`)
+
+	// Every object starts at the offset the cross-reference table gives
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(out, -1)
+	require.Len(t, entries, 8)
+	for i, entry := range entries {
+		offset, err := strconv.Atoi(string(entry[1]))
+		require.NoError(t, err)
+		assert.True(t, bytes.HasPrefix(out[offset:], []byte(fmt.Sprintf("%d 0 obj", i+1))), "object %d", i+1)
+	}
+
+	match := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(out)
+	require.NotNil(t, match)
+	xref, err := strconv.Atoi(string(match[1]))
+	require.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(out[xref:], []byte("xref\n0 9\n")))
+}
+
+func TestEncode(t *testing.T) {
+	assert.Equal(t, []byte{'C', 'a', 'f', 0xE9, ' ', 0x80, '5', ' ', 0x97, ' ', '?'}, encode("Café €5 — 日"))
+}
+
+func TestTextWidth(t *testing.T) {
+	assert.InDelta(t, 5.56*4, TextWidth("1234", 10, false), 1e-9)
+	assert.InDelta(t, 7.22+6.11, TextWidth("Ab", 10, true), 1e-9)
+}
@@ -0,0 +1,70 @@
+package personalexpense
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/fxrate"
+)
+
+// conversion is an expense amount in the user's default currency together
+// with the original amount, currency and rate when it was paid in another one
+type conversion struct {
+	Amount           decimal.Decimal
+	Currency         string
+	OriginalAmount   *decimal.Decimal
+	OriginalCurrency *string
+	FXRate           *decimal.Decimal
+}
+
+// convertAmount converts amount, given in currency, into defaultCurrency at
+// fxRate, rounded to cents. An empty currency means defaultCurrency, which
+// takes no rate.
+func convertAmount(amount decimal.Decimal, currency, defaultCurrency string, fxRate *string) (conversion, error) {
+	if currency == "" || currency == defaultCurrency {
+		if fxRate != nil {
+			return conversion{}, errors.New("fx_rate is only allowed for a foreign currency")
+		}
+		return conversion{Amount: amount, Currency: defaultCurrency}, nil
+	}
+
+	if fxRate == nil {
+		return conversion{}, errors.New("fx_rate is required when currency differs from default currency")
+	}
+	rate, err := decimal.NewFromString(*fxRate)
+	if err != nil {
+		return conversion{}, errors.New("invalid fx_rate format")
+	}
+	if !rate.IsPositive() {
+		return conversion{}, errors.New("fx_rate must be greater than 0")
+	}
+
+	converted := amount.Mul(rate).Round(2)
+	if !converted.IsPositive() {
+		return conversion{}, errors.New("converted amount must be greater than 0")
+	}
+	return conversion{
+		Amount:           converted,
+		Currency:         defaultCurrency,
+		OriginalAmount:   &amount,
+		OriginalCurrency: &currency,
+		FXRate:           &rate,
+	}, nil
+}
+
+// lookupRate returns fxRate, or when it's missing for a foreign currency,
+// the rate on the expense's date from rates
+func lookupRate(ctx context.Context, rates *fxrate.Service, currency, defaultCurrency string, date time.Time, fxRate *string) (*string, error) {
+	if fxRate != nil || rates == nil || currency == "" || currency == defaultCurrency {
+		return fxRate, nil
+	}
+	rate, err := rates.Rate(ctx, currency, defaultCurrency, date)
+	if err != nil {
+		return nil, err
+	}
+	s := rate.String()
+	return &s, nil
+}
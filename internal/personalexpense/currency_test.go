@@ -0,0 +1,46 @@
+package personalexpense
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConvertAmount(t *testing.T) {
+	rate := func(s string) *string { return &s }
+	amount := decimal.RequireFromString("10.00")
+
+	conv, err := convertAmount(amount, "", "USD", nil)
+	require.NoError(t, err)
+	assert.True(t, conv.Amount.Equal(amount))
+	assert.Equal(t, "USD", conv.Currency)
+	assert.Nil(t, conv.OriginalAmount)
+
+	conv, err = convertAmount(amount, "EUR", "USD", rate("1.0856"))
+	require.NoError(t, err)
+	assert.Equal(t, "10.86", conv.Amount.StringFixed(2))
+	assert.Equal(t, "USD", conv.Currency)
+	require.NotNil(t, conv.OriginalCurrency)
+	assert.Equal(t, "EUR", *conv.OriginalCurrency)
+	assert.True(t, conv.OriginalAmount.Equal(amount))
+
+	tests := []struct {
+		name     string
+		currency string
+		fxRate   *string
+	}{
+		{name: "missing rate", currency: "EUR"},
+		{name: "rate for default currency", currency: "USD", fxRate: rate("1.2")},
+		{name: "invalid rate", currency: "EUR", fxRate: rate("abc")},
+		{name: "zero rate", currency: "EUR", fxRate: rate("0")},
+		{name: "rounds to zero", currency: "EUR", fxRate: rate("0.0001")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := convertAmount(amount, tt.currency, "USD", tt.fxRate)
+			assert.Error(t, err)
+		})
+	}
+}
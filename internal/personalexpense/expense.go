@@ -1,48 +1,88 @@
 package personalexpense
 
 import (
-	"fmt"
 	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/shopspring/decimal"
 
 	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+	"github.com/yanonymousV2/finance-manager-backend/internal/db/sqlc"
+	"github.com/yanonymousV2/finance-manager-backend/internal/fxrate"
+	"github.com/yanonymousV2/finance-manager-backend/internal/helpers"
+	"github.com/yanonymousV2/finance-manager-backend/internal/merchant"
 	"github.com/yanonymousV2/finance-manager-backend/internal/middleware"
+	"github.com/yanonymousV2/finance-manager-backend/internal/storage"
+	"github.com/yanonymousV2/finance-manager-backend/internal/validation"
 )
 
 type PersonalExpense struct {
-	ID          uuid.UUID       `json:"id" db:"id"`
-	UserID      uuid.UUID       `json:"user_id" db:"user_id"`
-	CategoryID  *uuid.UUID      `json:"category_id,omitempty" db:"category_id"`
-	Amount      decimal.Decimal `json:"amount" db:"amount"`
-	Description *string         `json:"description,omitempty" db:"description"`
-	Notes       *string         `json:"notes,omitempty" db:"notes"`
-	ExpenseDate time.Time       `json:"expense_date" db:"expense_date"`
-	CreatedAt   time.Time       `json:"created_at" db:"created_at"`
-	UpdatedAt   time.Time       `json:"updated_at" db:"updated_at"`
+	ID             uuid.UUID       `json:"id" db:"id"`
+	UserID         uuid.UUID       `json:"user_id" db:"user_id"`
+	CategoryID     *uuid.UUID      `json:"category_id,omitempty" db:"category_id"`
+	AccountID      *uuid.UUID      `json:"account_id,omitempty" db:"account_id"`
+	MerchantID     *uuid.UUID      `json:"merchant_id,omitempty" db:"merchant_id"`
+	Merchant       *string         `json:"merchant,omitempty" db:"merchant"`
+	Amount         decimal.Decimal `json:"amount" db:"amount"`
+	Currency       string          `json:"currency" db:"currency"`
+	Description    *string         `json:"description,omitempty" db:"description"`
+	Notes          *string         `json:"notes,omitempty" db:"notes"`
+	ExpenseDate    time.Time       `json:"expense_date" db:"expense_date"`
+	GroupExpenseID *uuid.UUID      `json:"group_expense_id,omitempty" db:"group_expense_id"`
+	CreatedAt      time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at" db:"updated_at"`
+
+	// Set when the expense was paid in a currency other than the user's
+	OriginalAmount   *decimal.Decimal `json:"original_amount,omitempty" db:"original_amount"`
+	OriginalCurrency *string          `json:"original_currency,omitempty" db:"original_currency"`
+	FXRate           *decimal.Decimal `json:"fx_rate,omitempty" db:"fx_rate"`
+}
+
+// expenseColumns selects a personal expense in the order scanExpense reads it
+const expenseColumns = "id, user_id, category_id, account_id, merchant_id, merchant, amount, currency, description, notes, expense_date, " +
+	"group_expense_id, created_at, updated_at, original_amount, original_currency, fx_rate"
+
+func scanExpense(row interface{ Scan(...any) error }) (PersonalExpense, error) {
+	var e PersonalExpense
+	err := row.Scan(&e.ID, &e.UserID, &e.CategoryID, &e.AccountID, &e.MerchantID, &e.Merchant, &e.Amount, &e.Currency,
+		&e.Description, &e.Notes, &e.ExpenseDate, &e.GroupExpenseID, &e.CreatedAt, &e.UpdatedAt,
+		&e.OriginalAmount, &e.OriginalCurrency, &e.FXRate)
+	return e, err
 }
 
 type CreateExpenseRequest struct {
-	CategoryID  *uuid.UUID `json:"category_id,omitempty"`
-	Amount      string     `json:"amount" validate:"required,numeric"`
-	Description *string    `json:"description,omitempty" validate:"omitempty,max=255"`
-	Notes       *string    `json:"notes,omitempty"`
-	ExpenseDate time.Time  `json:"expense_date" validate:"required"`
+	CategoryID *uuid.UUID `json:"category_id,omitempty"`
+	AccountID  *uuid.UUID `json:"account_id,omitempty"`
+	Amount     string     `json:"amount" validate:"required,numeric"`
+	Currency   string     `json:"currency,omitempty" validate:"omitempty,iso4217"`
+	// FXRate converts one unit of Currency into the user's default currency.
+	// When Currency differs from it and FXRate is left out, the exchange
+	// rate on ExpenseDate is used.
+	FXRate      *string   `json:"fx_rate,omitempty" validate:"omitempty,numeric"`
+	Description *string   `json:"description,omitempty" validate:"omitempty,max=255"`
+	Merchant    *string   `json:"merchant,omitempty" validate:"omitempty,max=255"`
+	Notes       *string   `json:"notes,omitempty"`
+	ExpenseDate time.Time `json:"expense_date" validate:"required"`
 }
 
 type UpdateExpenseRequest struct {
-	CategoryID  *uuid.UUID `json:"category_id,omitempty"`
-	Amount      *string    `json:"amount,omitempty" validate:"omitempty,numeric"`
+	CategoryID *uuid.UUID `json:"category_id,omitempty"`
+	AccountID  *uuid.UUID `json:"account_id,omitempty"`
+	Amount     *string    `json:"amount,omitempty" validate:"omitempty,numeric"`
+	// Currency and FXRate describe Amount and can only be sent with it; a
+	// missing FXRate is looked up as for a new expense
+	Currency    *string    `json:"currency,omitempty" validate:"omitempty,iso4217"`
+	FXRate      *string    `json:"fx_rate,omitempty" validate:"omitempty,numeric"`
 	Description *string    `json:"description,omitempty" validate:"omitempty,max=255"`
+	Merchant    *string    `json:"merchant,omitempty" validate:"omitempty,max=255"`
 	Notes       *string    `json:"notes,omitempty"`
 	ExpenseDate *time.Time `json:"expense_date,omitempty"`
 }
 
-func CreateExpense(c *gin.Context, db *db.DB) {
+func CreateExpense(c *gin.Context, db *db.DB, rates *fxrate.Service) {
 	userID, ok := middleware.GetUserID(c)
 	if !ok {
 		c.JSON(401, gin.H{"error": "unauthorized"})
@@ -50,14 +90,7 @@ func CreateExpense(c *gin.Context, db *db.DB) {
 	}
 
 	var req CreateExpenseRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
-		return
-	}
-
-	validate := validator.New()
-	if err := validate.Struct(req); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
+	if !validation.Bind(c, &req) {
 		return
 	}
 
@@ -73,10 +106,26 @@ func CreateExpense(c *gin.Context, db *db.DB) {
 		return
 	}
 
+	// Amounts are kept in the user's default currency; expenses paid in
+	// another currency are converted at the given rate and the original is kept
+	defaultCurrency, err := helpers.GetUserCurrency(c.Request.Context(), db, userID)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to get default currency"})
+		return
+	}
+	fxRate, err := lookupRate(c.Request.Context(), rates, req.Currency, defaultCurrency, req.ExpenseDate, req.FXRate)
+	if err != nil {
+		fxrate.RespondRateError(c, err)
+		return
+	}
+	conv, err := convertAmount(amount, req.Currency, defaultCurrency, fxRate)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
 	if req.CategoryID != nil {
-		var ownerID uuid.UUID
-		err := db.Pool.QueryRow(c.Request.Context(),
-			`SELECT user_id FROM expense_categories WHERE id = $1`, req.CategoryID).Scan(&ownerID)
+		ownerID, archived, err := helpers.GetCategoryOwner(c.Request.Context(), db.Pool, *req.CategoryID)
 		if err != nil {
 			c.JSON(400, gin.H{"error": "invalid category"})
 			return
@@ -85,22 +134,60 @@ func CreateExpense(c *gin.Context, db *db.DB) {
 			c.JSON(403, gin.H{"error": "category does not belong to user"})
 			return
 		}
+		if archived {
+			c.JSON(400, gin.H{"error": "category is archived"})
+			return
+		}
 	}
 
-	var expense PersonalExpense
-	err = db.Pool.QueryRow(c.Request.Context(),
-		`INSERT INTO personal_expenses (user_id, category_id, amount, description, notes, expense_date, updated_at) 
-		 VALUES ($1, $2, $3, $4, $5, $6, NOW()) 
-		 RETURNING id, user_id, category_id, amount, description, notes, expense_date, created_at, updated_at`,
-		userID, req.CategoryID, amount, req.Description, req.Notes, req.ExpenseDate).Scan(
-		&expense.ID, &expense.UserID, &expense.CategoryID, &expense.Amount, &expense.Description,
-		&expense.Notes, &expense.ExpenseDate, &expense.CreatedAt, &expense.UpdatedAt)
+	if req.AccountID != nil {
+		var ownerID uuid.UUID
+		err := db.Pool.QueryRow(c.Request.Context(),
+			`SELECT user_id FROM accounts WHERE id = $1`, req.AccountID).Scan(&ownerID)
+		if err != nil {
+			c.JSON(400, gin.H{"error": "invalid account"})
+			return
+		}
+		if ownerID != userID {
+			c.JSON(403, gin.H{"error": "account does not belong to user"})
+			return
+		}
+	}
+
+	// Normalize the merchant; its default category applies when none is given
+	categoryID := req.CategoryID
+	var merchantID *uuid.UUID
+	var merchantName *string
+	if req.Merchant != nil {
+		m, err := merchant.Resolve(c.Request.Context(), db.Pool, userID, *req.Merchant)
+		if err != nil {
+			c.JSON(500, gin.H{"error": "failed to resolve merchant"})
+			return
+		}
+		if m != nil {
+			merchantID, merchantName = &m.ID, &m.Name
+			if categoryID == nil {
+				categoryID = m.CategoryID
+			}
+		}
+	}
+
+	expense, err := scanExpense(db.Pool.QueryRow(c.Request.Context(),
+		`INSERT INTO personal_expenses (user_id, category_id, account_id, merchant_id, merchant, amount, currency, description, notes, expense_date,
+		                                original_amount, original_currency, fx_rate, updated_at) 
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, NOW()) 
+		 RETURNING `+expenseColumns,
+		userID, categoryID, req.AccountID, merchantID, merchantName, conv.Amount, conv.Currency, req.Description, req.Notes, req.ExpenseDate,
+		conv.OriginalAmount, conv.OriginalCurrency, conv.FXRate))
 	if err != nil {
 		c.JSON(500, gin.H{"error": "failed to create expense"})
 		return
 	}
 
-	c.JSON(201, expense)
+	c.JSON(201, ExpenseResponse{
+		PersonalExpense: expense,
+		CategoryLimit:   checkCategoryLimit(c.Request.Context(), db, expense),
+	})
 }
 
 func ListExpenses(c *gin.Context, db *db.DB) {
@@ -124,70 +211,63 @@ func ListExpenses(c *gin.Context, db *db.DB) {
 		}
 	}
 
-	query := `SELECT id, user_id, category_id, amount, description, notes, expense_date, created_at, updated_at 
-		      FROM personal_expenses 
-		      WHERE user_id = $1`
-	countQuery := `SELECT COUNT(*) FROM personal_expenses WHERE user_id = $1`
-	args := []interface{}{userID}
-	argCount := 2
-
-	if categoryIDStr := c.Query("category_id"); categoryIDStr != "" {
-		if categoryID, err := uuid.Parse(categoryIDStr); err == nil {
-			query += fmt.Sprintf(" AND category_id = $%d", argCount)
-			countQuery += fmt.Sprintf(" AND category_id = $%d", argCount)
-			args = append(args, categoryID)
-			argCount++
-		}
+	sortColumn, descending, err := parseSort(c.DefaultQuery("sort", "expense_date"), c.DefaultQuery("order", "desc"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
 	}
 
-	if startDateStr := c.Query("start_date"); startDateStr != "" {
-		if startDate, err := time.Parse("2006-01-02", startDateStr); err == nil {
-			query += fmt.Sprintf(" AND expense_date >= $%d", argCount)
-			countQuery += fmt.Sprintf(" AND expense_date >= $%d", argCount)
-			args = append(args, startDate)
-			argCount++
-		}
+	// Filters that don't parse are ignored
+	filter := sqlc.CountPersonalExpensesParams{UserID: userID}
+	if categoryID, err := uuid.Parse(c.Query("category_id")); err == nil {
+		filter.CategoryID = &categoryID
 	}
-
-	if endDateStr := c.Query("end_date"); endDateStr != "" {
-		if endDate, err := time.Parse("2006-01-02", endDateStr); err == nil {
-			endDate = endDate.Add(24 * time.Hour)
-			query += fmt.Sprintf(" AND expense_date < $%d", argCount)
-			countQuery += fmt.Sprintf(" AND expense_date < $%d", argCount)
-			args = append(args, endDate)
-			argCount++
-		}
+	if accountID, err := uuid.Parse(c.Query("account_id")); err == nil {
+		filter.AccountID = &accountID
+	}
+	if merchantID, err := uuid.Parse(c.Query("merchant_id")); err == nil {
+		filter.MerchantID = &merchantID
+	}
+	if startDate, err := time.Parse("2006-01-02", c.Query("start_date")); err == nil {
+		filter.StartDate = &startDate
+	}
+	if endDate, err := time.Parse("2006-01-02", c.Query("end_date")); err == nil {
+		endDate = endDate.Add(24 * time.Hour)
+		filter.EndDate = &endDate
 	}
 
-	var totalCount int
-	if err := db.Pool.QueryRow(c.Request.Context(), countQuery, args...).Scan(&totalCount); err != nil {
+	queries := sqlc.New(db.Pool)
+	// The count and newest change also make the list's ETag
+	count, err := queries.CountPersonalExpenses(c.Request.Context(), filter)
+	if err != nil {
 		c.JSON(500, gin.H{"error": "failed to get total count"})
 		return
 	}
+	totalCount := int(count.Count)
+	if helpers.NotModified(c, helpers.ETag(totalCount, &count.LastUpdated)) {
+		return
+	}
 
-	query += fmt.Sprintf(" ORDER BY expense_date DESC, created_at DESC LIMIT $%d OFFSET $%d", argCount, argCount+1)
-	args = append(args, limit, offset)
-
-	rows, err := db.Pool.Query(c.Request.Context(), query, args...)
+	rows, err := queries.ListPersonalExpenses(c.Request.Context(), sqlc.ListPersonalExpensesParams{
+		UserID:     filter.UserID,
+		CategoryID: filter.CategoryID,
+		AccountID:  filter.AccountID,
+		MerchantID: filter.MerchantID,
+		StartDate:  filter.StartDate,
+		EndDate:    filter.EndDate,
+		SortColumn: sortColumn,
+		Descending: descending,
+		RowLimit:   int64(limit),
+		RowOffset:  int64(offset),
+	})
 	if err != nil {
 		c.JSON(500, gin.H{"error": "failed to retrieve expenses"})
 		return
 	}
-	defer rows.Close()
-
-	var expenses []PersonalExpense
-	for rows.Next() {
-		var exp PersonalExpense
-		if err := rows.Scan(&exp.ID, &exp.UserID, &exp.CategoryID, &exp.Amount, &exp.Description,
-			&exp.Notes, &exp.ExpenseDate, &exp.CreatedAt, &exp.UpdatedAt); err != nil {
-			c.JSON(500, gin.H{"error": "failed to scan expense"})
-			return
-		}
-		expenses = append(expenses, exp)
-	}
 
-	if expenses == nil {
-		expenses = []PersonalExpense{}
+	expenses := make([]PersonalExpense, len(rows))
+	for i, row := range rows {
+		expenses[i] = PersonalExpense(row)
 	}
 
 	c.JSON(200, gin.H{
@@ -214,22 +294,23 @@ func GetExpense(c *gin.Context, db *db.DB) {
 		return
 	}
 
-	var expense PersonalExpense
-	err = db.Pool.QueryRow(c.Request.Context(),
-		`SELECT id, user_id, category_id, amount, description, notes, expense_date, created_at, updated_at 
+	expense, err := scanExpense(db.Pool.QueryRow(c.Request.Context(),
+		`SELECT `+expenseColumns+` 
 		 FROM personal_expenses 
 		 WHERE id = $1 AND user_id = $2`,
-		expenseID, userID).Scan(&expense.ID, &expense.UserID, &expense.CategoryID, &expense.Amount,
-		&expense.Description, &expense.Notes, &expense.ExpenseDate, &expense.CreatedAt, &expense.UpdatedAt)
+		expenseID, userID))
 	if err != nil {
 		c.JSON(404, gin.H{"error": "expense not found"})
 		return
 	}
+	if helpers.NotModified(c, helpers.ETag(1, &expense.UpdatedAt)) {
+		return
+	}
 
 	c.JSON(200, expense)
 }
 
-func UpdateExpense(c *gin.Context, db *db.DB) {
+func UpdateExpense(c *gin.Context, db *db.DB, rates *fxrate.Service) {
 	userID, ok := middleware.GetUserID(c)
 	if !ok {
 		c.JSON(401, gin.H{"error": "unauthorized"})
@@ -244,19 +325,17 @@ func UpdateExpense(c *gin.Context, db *db.DB) {
 	}
 
 	var req UpdateExpenseRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
+	if !validation.Bind(c, &req) {
 		return
 	}
 
-	validate := validator.New()
-	if err := validate.Struct(req); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
+	if req.Amount == nil && (req.Currency != nil || req.FXRate != nil) {
+		c.JSON(400, gin.H{"error": "currency and fx_rate can only be changed together with amount"})
 		return
 	}
 
-	// Parse amount if provided
-	var parsedAmount *decimal.Decimal
+	// Parse and convert amount if provided
+	var conv *conversion
 	if req.Amount != nil {
 		amount, err := decimal.NewFromString(*req.Amount)
 		if err != nil {
@@ -267,7 +346,47 @@ func UpdateExpense(c *gin.Context, db *db.DB) {
 			c.JSON(400, gin.H{"error": "amount must be greater than 0"})
 			return
 		}
-		parsedAmount = &amount
+		defaultCurrency, err := helpers.GetUserCurrency(c.Request.Context(), db, userID)
+		if err != nil {
+			c.JSON(500, gin.H{"error": "failed to get default currency"})
+			return
+		}
+		currency := ""
+		if req.Currency != nil {
+			currency = *req.Currency
+		}
+		// Without a rate, use the one on the expense's date
+		fxRate := req.FXRate
+		if fxRate == nil && rates != nil && currency != "" && currency != defaultCurrency {
+			date := req.ExpenseDate
+			if date == nil {
+				var existing time.Time
+				err := db.Pool.QueryRow(c.Request.Context(),
+					`SELECT expense_date FROM personal_expenses WHERE id = $1 AND user_id = $2`, expenseID, userID).Scan(&existing)
+				if err != nil {
+					c.JSON(404, gin.H{"error": "expense not found"})
+					return
+				}
+				date = &existing
+			}
+			fxRate, err = lookupRate(c.Request.Context(), rates, currency, defaultCurrency, *date, nil)
+			if err != nil {
+				fxrate.RespondRateError(c, err)
+				return
+			}
+		}
+		converted, err := convertAmount(amount, currency, defaultCurrency, fxRate)
+		if err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+		conv = &converted
+	}
+
+	if req.CategoryID == nil && req.AccountID == nil && req.Amount == nil && req.Description == nil &&
+		req.Merchant == nil && req.Notes == nil && req.ExpenseDate == nil {
+		c.JSON(400, gin.H{"error": "no fields to update"})
+		return
 	}
 
 	var ownerID uuid.UUID
@@ -283,9 +402,7 @@ func UpdateExpense(c *gin.Context, db *db.DB) {
 	}
 
 	if req.CategoryID != nil {
-		var categoryOwnerID uuid.UUID
-		err := db.Pool.QueryRow(c.Request.Context(),
-			`SELECT user_id FROM expense_categories WHERE id = $1`, req.CategoryID).Scan(&categoryOwnerID)
+		categoryOwnerID, archived, err := helpers.GetCategoryOwner(c.Request.Context(), db.Pool, *req.CategoryID)
 		if err != nil {
 			c.JSON(400, gin.H{"error": "invalid category"})
 			return
@@ -294,59 +411,77 @@ func UpdateExpense(c *gin.Context, db *db.DB) {
 			c.JSON(403, gin.H{"error": "category does not belong to user"})
 			return
 		}
+		if archived {
+			c.JSON(400, gin.H{"error": "category is archived"})
+			return
+		}
 	}
 
-	query := `UPDATE personal_expenses SET updated_at = NOW()`
-	args := []interface{}{}
-	argCount := 1
-
-	if req.CategoryID != nil {
-		query += fmt.Sprintf(", category_id = $%d", argCount)
-		args = append(args, req.CategoryID)
-		argCount++
-	}
-	if parsedAmount != nil {
-		query += fmt.Sprintf(", amount = $%d", argCount)
-		args = append(args, parsedAmount)
-		argCount++
-	}
-	if req.Description != nil {
-		query += fmt.Sprintf(", description = $%d", argCount)
-		args = append(args, req.Description)
-		argCount++
-	}
-	if req.Notes != nil {
-		query += fmt.Sprintf(", notes = $%d", argCount)
-		args = append(args, req.Notes)
-		argCount++
-	}
-	if req.ExpenseDate != nil {
-		query += fmt.Sprintf(", expense_date = $%d", argCount)
-		args = append(args, req.ExpenseDate)
-		argCount++
+	if req.AccountID != nil {
+		var accountOwnerID uuid.UUID
+		err := db.Pool.QueryRow(c.Request.Context(),
+			`SELECT user_id FROM accounts WHERE id = $1`, req.AccountID).Scan(&accountOwnerID)
+		if err != nil {
+			c.JSON(400, gin.H{"error": "invalid account"})
+			return
+		}
+		if accountOwnerID != userID {
+			c.JSON(403, gin.H{"error": "account does not belong to user"})
+			return
+		}
 	}
 
-	if argCount == 1 {
-		c.JSON(400, gin.H{"error": "no fields to update"})
-		return
+	// An empty merchant clears it
+	var merchantID *uuid.UUID
+	var merchantName *string
+	if req.Merchant != nil {
+		m, err := merchant.Resolve(c.Request.Context(), db.Pool, userID, *req.Merchant)
+		if err != nil {
+			c.JSON(500, gin.H{"error": "failed to resolve merchant"})
+			return
+		}
+		if m != nil {
+			merchantID, merchantName = &m.ID, &m.Name
+		}
 	}
 
-	query += fmt.Sprintf(" WHERE id = $%d RETURNING id, user_id, category_id, amount, description, notes, expense_date, created_at, updated_at", argCount)
-	args = append(args, expenseID)
-
-	var expense PersonalExpense
-	err = db.Pool.QueryRow(c.Request.Context(), query, args...).Scan(
-		&expense.ID, &expense.UserID, &expense.CategoryID, &expense.Amount, &expense.Description,
-		&expense.Notes, &expense.ExpenseDate, &expense.CreatedAt, &expense.UpdatedAt)
+	// The amount columns are only read when set_amount is true
+	amount := conversion{}
+	if conv != nil {
+		amount = *conv
+	}
+
+	row, err := sqlc.New(db.Pool).UpdatePersonalExpense(c.Request.Context(), sqlc.UpdatePersonalExpenseParams{
+		ID:               expenseID,
+		CategoryID:       req.CategoryID,
+		AccountID:        req.AccountID,
+		SetMerchant:      req.Merchant != nil,
+		MerchantID:       merchantID,
+		Merchant:         merchantName,
+		SetAmount:        conv != nil,
+		Amount:           &amount.Amount,
+		Currency:         &amount.Currency,
+		OriginalAmount:   amount.OriginalAmount,
+		OriginalCurrency: amount.OriginalCurrency,
+		FXRate:           amount.FXRate,
+		Description:      req.Description,
+		Notes:            req.Notes,
+		ExpenseDate:      req.ExpenseDate,
+	})
 	if err != nil {
 		c.JSON(500, gin.H{"error": "failed to update expense"})
 		return
 	}
+	expense := PersonalExpense(row)
 
-	c.JSON(200, expense)
+	c.JSON(200, ExpenseResponse{
+		PersonalExpense: expense,
+		CategoryLimit:   checkCategoryLimit(c.Request.Context(), db, expense),
+	})
 }
 
-func DeleteExpense(c *gin.Context, db *db.DB) {
+// DeleteExpense deletes a personal expense together with its receipts
+func DeleteExpense(c *gin.Context, db *db.DB, store storage.Storage) {
 	userID, ok := middleware.GetUserID(c)
 	if !ok {
 		c.JSON(401, gin.H{"error": "unauthorized"})
@@ -372,12 +507,32 @@ func DeleteExpense(c *gin.Context, db *db.DB) {
 		return
 	}
 
-	_, err = db.Pool.Exec(c.Request.Context(),
-		`DELETE FROM personal_expenses WHERE id = $1`, expenseID)
+	var receiptKeys []string
+	err = db.WithTx(c.Request.Context(), func(tx pgx.Tx) error {
+		rows, err := tx.Query(c.Request.Context(),
+			`DELETE FROM receipts WHERE expense_id = $1 RETURNING storage_key`, expenseID)
+		if err != nil {
+			return helpers.NewRequestError(500, "failed to delete receipts")
+		}
+		receiptKeys, err = pgx.CollectRows(rows, pgx.RowTo[string])
+		if err != nil {
+			return helpers.NewRequestError(500, "failed to delete receipts")
+		}
+
+		_, err = tx.Exec(c.Request.Context(),
+			`DELETE FROM personal_expenses WHERE id = $1`, expenseID)
+		if err != nil {
+			return helpers.NewRequestError(500, "failed to delete expense")
+		}
+		return nil
+	})
 	if err != nil {
-		c.JSON(500, gin.H{"error": "failed to delete expense"})
+		helpers.RespondError(c, err, "failed to delete expense")
 		return
 	}
 
+	// Files go only once the rows referencing them are gone
+	DeleteReceiptFiles(c.Request.Context(), store, receiptKeys)
+
 	c.JSON(200, gin.H{"message": "expense deleted successfully"})
 }
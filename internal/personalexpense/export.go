@@ -0,0 +1,177 @@
+package personalexpense
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+	"github.com/yanonymousV2/finance-manager-backend/internal/middleware"
+	"github.com/yanonymousV2/finance-manager-backend/internal/requestid"
+)
+
+// exportFlushEvery is how many rows are written between flushes of the
+// response
+const exportFlushEvery = 500
+
+// ExportRow is a personal expense with its category, merchant and account
+// resolved to names
+type ExportRow struct {
+	ID               uuid.UUID        `json:"id"`
+	ExpenseDate      time.Time        `json:"expense_date"`
+	Amount           decimal.Decimal  `json:"amount"`
+	Currency         string           `json:"currency"`
+	OriginalAmount   *decimal.Decimal `json:"original_amount,omitempty"`
+	OriginalCurrency *string          `json:"original_currency,omitempty"`
+	FXRate           *decimal.Decimal `json:"fx_rate,omitempty"`
+	Category         *string          `json:"category,omitempty"`
+	Merchant         *string          `json:"merchant,omitempty"`
+	Account          *string          `json:"account,omitempty"`
+	Description      *string          `json:"description,omitempty"`
+	Notes            *string          `json:"notes,omitempty"`
+}
+
+// exportHeader names the CSV columns written by csvRecord. The date, amount,
+// description, category and merchant columns can be imported again as is.
+var exportHeader = []string{"date", "amount", "currency", "original_amount", "original_currency", "fx_rate",
+	"category", "merchant", "account", "description", "notes"}
+
+// csvRecord formats an exported expense as a CSV record
+func csvRecord(r ExportRow) []string {
+	str := func(s *string) string {
+		if s == nil {
+			return ""
+		}
+		return *s
+	}
+	dec := func(d *decimal.Decimal) string {
+		if d == nil {
+			return ""
+		}
+		return d.String()
+	}
+	return []string{
+		r.ExpenseDate.Format("2006-01-02"), r.Amount.StringFixed(2), r.Currency,
+		dec(r.OriginalAmount), str(r.OriginalCurrency), dec(r.FXRate),
+		str(r.Category), str(r.Merchant), str(r.Account), str(r.Description), str(r.Notes),
+	}
+}
+
+// exportExpensesSQL lists a user's expenses oldest first; a null date
+// matches everything. It isn't a sqlc query because sqlc reads every row
+// before returning, and the export writes them as they arrive.
+const exportExpensesSQL = `SELECT pe.id, pe.expense_date, pe.amount, pe.currency, pe.original_amount, pe.original_currency, pe.fx_rate,
+	ec.name, pe.merchant, a.name, pe.description, pe.notes
+FROM personal_expenses pe
+LEFT JOIN expense_categories ec ON pe.category_id = ec.id
+LEFT JOIN accounts a ON pe.account_id = a.id
+WHERE pe.user_id = @user_id
+	AND (@start_date::timestamptz IS NULL OR pe.expense_date >= @start_date)
+	AND (@end_date::timestamptz IS NULL OR pe.expense_date < @end_date)
+ORDER BY pe.expense_date, pe.created_at, pe.id`
+
+// ExportExpenses streams the authenticated user's personal expenses as CSV or
+// JSON, oldest first
+func ExportExpenses(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	format := c.DefaultQuery("format", "csv")
+	if format != "csv" && format != "json" {
+		c.JSON(400, gin.H{"error": "format must be csv or json"})
+		return
+	}
+
+	args := pgx.NamedArgs{"user_id": userID, "start_date": nil, "end_date": nil}
+	if startDateStr := c.Query("start_date"); startDateStr != "" {
+		startDate, err := time.Parse("2006-01-02", startDateStr)
+		if err != nil {
+			c.JSON(400, gin.H{"error": "invalid start_date, expected YYYY-MM-DD"})
+			return
+		}
+		args["start_date"] = startDate
+	}
+
+	if endDateStr := c.Query("end_date"); endDateStr != "" {
+		endDate, err := time.Parse("2006-01-02", endDateStr)
+		if err != nil {
+			c.JSON(400, gin.H{"error": "invalid end_date, expected YYYY-MM-DD"})
+			return
+		}
+		args["end_date"] = endDate.Add(24 * time.Hour)
+	}
+
+	rows, err := db.Pool.Query(c.Request.Context(), exportExpensesSQL, args)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to export expenses"})
+		return
+	}
+	defer rows.Close()
+
+	filename := fmt.Sprintf("expenses-%s.%s", time.Now().Format("2006-01-02"), format)
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, filename))
+	if format == "csv" {
+		c.Header("Content-Type", "text/csv; charset=utf-8")
+	} else {
+		c.Header("Content-Type", "application/json; charset=utf-8")
+	}
+	c.Status(200)
+
+	// Rows are written as they are read, so errors past this point can only
+	// cut the response short
+	w := csv.NewWriter(c.Writer)
+	if format == "csv" {
+		w.Write(exportHeader)
+	} else {
+		c.Writer.WriteString("[")
+	}
+
+	n := 0
+	for rows.Next() {
+		var r ExportRow
+		if err := rows.Scan(&r.ID, &r.ExpenseDate, &r.Amount, &r.Currency, &r.OriginalAmount, &r.OriginalCurrency, &r.FXRate,
+			&r.Category, &r.Merchant, &r.Account, &r.Description, &r.Notes); err != nil {
+			requestid.Logger(c.Request.Context()).Error("failed to export expenses", "user_id", userID, "error", err)
+			return
+		}
+
+		if format == "csv" {
+			w.Write(csvRecord(r))
+		} else {
+			data, err := json.Marshal(r)
+			if err != nil {
+				requestid.Logger(c.Request.Context()).Error("failed to export expenses", "user_id", userID, "error", err)
+				return
+			}
+			if n > 0 {
+				c.Writer.WriteString(",")
+			}
+			c.Writer.Write(data)
+		}
+
+		n++
+		if n%exportFlushEvery == 0 {
+			w.Flush()
+			c.Writer.Flush()
+		}
+	}
+	if err := rows.Err(); err != nil {
+		requestid.Logger(c.Request.Context()).Error("failed to export expenses", "user_id", userID, "error", err)
+		return
+	}
+
+	if format == "csv" {
+		w.Flush()
+	} else {
+		c.Writer.WriteString("]")
+	}
+}
@@ -0,0 +1,29 @@
+package personalexpense
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCSVRecord(t *testing.T) {
+	category, description := "Food", "Lunch, with team"
+	row := ExportRow{
+		ExpenseDate: time.Date(2026, 2, 14, 10, 30, 0, 0, time.UTC),
+		Amount:      decimal.RequireFromString("12.5"),
+		Currency:    "USD",
+		Category:    &category,
+		Description: &description,
+	}
+
+	record := csvRecord(row)
+	assert.Len(t, record, len(exportHeader))
+	assert.Equal(t, []string{"2026-02-14", "12.50", "USD", "", "", "", "Food", "", "", "Lunch, with team", ""}, record)
+
+	original, currency, rate := decimal.RequireFromString("10"), "EUR", decimal.RequireFromString("1.25")
+	row.OriginalAmount, row.OriginalCurrency, row.FXRate = &original, &currency, &rate
+	record = csvRecord(row)
+	assert.Equal(t, []string{"10", "EUR", "1.25"}, record[3:6])
+}
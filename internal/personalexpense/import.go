@@ -0,0 +1,536 @@
+package personalexpense
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+	"github.com/yanonymousV2/finance-manager-backend/internal/helpers"
+	"github.com/yanonymousV2/finance-manager-backend/internal/merchant"
+	"github.com/yanonymousV2/finance-manager-backend/internal/middleware"
+	"github.com/yanonymousV2/finance-manager-backend/internal/validation"
+)
+
+const (
+	// maxImportRows caps the data rows read from one CSV file
+	maxImportRows = 10000
+	// importBatchSize is how many rows are sent to the database at once
+	importBatchSize = 500
+	// maxImportErrors caps the row errors listed in an import report
+	maxImportErrors = 100
+)
+
+// A row is a duplicate when the user already has an expense on the same day
+// with the same amount and description, including rows imported earlier in
+// the same file. Imported amounts are taken to be in the user's default
+// currency.
+const importExpenseSQL = `INSERT INTO personal_expenses (user_id, category_id, amount, currency, description, expense_date, merchant_id, merchant, updated_at)
+	 SELECT $1, $2, $3, (SELECT default_currency FROM users WHERE id = $1), $4, $5, $6, $7, NOW()
+	 WHERE NOT EXISTS (
+	     SELECT 1 FROM personal_expenses
+	     WHERE user_id = $1 AND expense_date = $5 AND amount = $3
+	       AND LOWER(COALESCE(description, '')) = LOWER(COALESCE($4::text, '')))
+	 RETURNING id`
+
+// Sign conventions of an ImportMapping
+const (
+	SignPositive = "positive"
+	SignNegative = "negative"
+	SignSplit    = "split"
+	SignType     = "type"
+)
+
+// ImportMapping names the CSV header of each field. Date is required, and
+// so is Amount unless the amount is split into Outflow and Inflow columns.
+// DateFormat is a Go time layout defaulting to 2006-01-02.
+//
+// Sign says which rows are expenses: rows with a positive amount (the
+// default), a negative one, an outflow ("split"), or whose Type column is
+// one of DebitTypes ("type", defaulting to "debit"). Other rows, like income
+// and refunds, are skipped. Categories renames the file's categories,
+// matched case-insensitively; renaming one to "" leaves its rows
+// uncategorized.
+type ImportMapping struct {
+	Date        string            `json:"date" validate:"required,max=100"`
+	Amount      string            `json:"amount,omitempty" validate:"required_unless=Sign split,max=100"`
+	Outflow     string            `json:"outflow,omitempty" validate:"required_if=Sign split,max=100"`
+	Inflow      string            `json:"inflow,omitempty" validate:"max=100"`
+	Type        string            `json:"type,omitempty" validate:"required_if=Sign type,max=100"`
+	Description string            `json:"description,omitempty" validate:"max=100"`
+	Category    string            `json:"category,omitempty" validate:"max=100"`
+	Merchant    string            `json:"merchant,omitempty" validate:"max=100"`
+	DateFormat  string            `json:"date_format,omitempty" validate:"max=50"`
+	Sign        string            `json:"sign,omitempty" validate:"omitempty,oneof=positive negative split type"`
+	DebitTypes  []string          `json:"debit_types,omitempty" validate:"omitempty,max=20,dive,required,max=50"`
+	Categories  map[string]string `json:"categories,omitempty" validate:"omitempty,max=500,dive,keys,required,max=100,endkeys,max=100"`
+}
+
+// withDefaults fills in the defaults and lowercases the category table's
+// keys for lookups
+func (m ImportMapping) withDefaults() ImportMapping {
+	if m.DateFormat == "" {
+		m.DateFormat = "2006-01-02"
+	}
+	if m.Sign == "" {
+		m.Sign = SignPositive
+	}
+	if m.Sign == SignType && len(m.DebitTypes) == 0 {
+		m.DebitTypes = []string{"debit"}
+	}
+	categories := make(map[string]string, len(m.Categories))
+	for from, to := range m.Categories {
+		categories[strings.ToLower(strings.TrimSpace(from))] = strings.TrimSpace(to)
+	}
+	m.Categories = categories
+	return m
+}
+
+type ImportRowError struct {
+	Row   int    `json:"row"`
+	Error string `json:"error"`
+}
+
+// ImportReport counts what an import did. Skipped rows aren't expenses
+// under the mapping's sign convention.
+type ImportReport struct {
+	Imported          int              `json:"imported"`
+	Duplicates        int              `json:"duplicates"`
+	Skipped           int              `json:"skipped"`
+	Failed            int              `json:"failed"`
+	CategoriesCreated []string         `json:"categories_created"`
+	DuplicateRows     []int            `json:"duplicate_rows"`
+	Errors            []ImportRowError `json:"errors"`
+}
+
+// importRow is a parsed CSV row ready to be inserted
+type importRow struct {
+	Line        int
+	ExpenseDate time.Time
+	Amount      decimal.Decimal
+	Description *string
+	Category    string
+	Merchant    string
+
+	CategoryID   *uuid.UUID
+	MerchantID   *uuid.UUID
+	MerchantName *string
+}
+
+// columnIndexes resolves the mapped headers to column positions. Headers are
+// matched case-insensitively; unmapped optional fields get -1.
+type columnIndexes struct {
+	date, amount, outflow, inflow, typ, description, category, merchant int
+}
+
+func resolveColumns(header []string, m ImportMapping) (columnIndexes, error) {
+	positions := make(map[string]int, len(header))
+	for i, h := range header {
+		positions[strings.ToLower(strings.TrimSpace(h))] = i
+	}
+	find := func(name string, required bool) (int, error) {
+		if name == "" && !required {
+			return -1, nil
+		}
+		i, ok := positions[strings.ToLower(strings.TrimSpace(name))]
+		if !ok {
+			return -1, fmt.Errorf("column %q not found in header", name)
+		}
+		return i, nil
+	}
+
+	var cols columnIndexes
+	var err error
+	if cols.date, err = find(m.Date, true); err != nil {
+		return cols, err
+	}
+	if cols.amount, err = find(m.Amount, m.Sign != SignSplit); err != nil {
+		return cols, err
+	}
+	if cols.outflow, err = find(m.Outflow, m.Sign == SignSplit); err != nil {
+		return cols, err
+	}
+	if cols.inflow, err = find(m.Inflow, false); err != nil {
+		return cols, err
+	}
+	if cols.typ, err = find(m.Type, m.Sign == SignType); err != nil {
+		return cols, err
+	}
+	if cols.description, err = find(m.Description, false); err != nil {
+		return cols, err
+	}
+	if cols.category, err = find(m.Category, false); err != nil {
+		return cols, err
+	}
+	if cols.merchant, err = find(m.Merchant, false); err != nil {
+		return cols, err
+	}
+	return cols, nil
+}
+
+// errNotExpense skips a row that isn't an expense under the mapping's sign
+// convention
+var errNotExpense = errors.New("not an expense")
+
+// parseAmount parses a CSV amount, tolerating thousands separators and a
+// currency symbol after the sign, as in -$1,200.00
+func parseAmount(raw string) (decimal.Decimal, error) {
+	digits, negative := strings.CutPrefix(strings.ReplaceAll(raw, ",", ""), "-")
+	digits = strings.TrimLeft(digits, "$€£")
+	amount, err := decimal.NewFromString(digits)
+	if err != nil || strings.HasPrefix(digits, "-") {
+		return decimal.Decimal{}, fmt.Errorf("invalid amount %q", raw)
+	}
+	if negative {
+		amount = amount.Neg()
+	}
+	return amount, nil
+}
+
+// parseOptionalAmount is parseAmount with an empty field being zero, as in
+// the unused one of an outflow and inflow pair
+func parseOptionalAmount(raw string) (decimal.Decimal, error) {
+	if raw == "" {
+		return decimal.Zero, nil
+	}
+	return parseAmount(raw)
+}
+
+// parseImportRow turns a CSV record into an importRow. m must have its
+// defaults filled in; a row that isn't an expense returns errNotExpense.
+func parseImportRow(record []string, cols columnIndexes, m ImportMapping) (importRow, error) {
+	field := func(i int) string {
+		if i < 0 || i >= len(record) {
+			return ""
+		}
+		return strings.TrimSpace(record[i])
+	}
+
+	var row importRow
+	date, err := time.Parse(m.DateFormat, field(cols.date))
+	if err != nil {
+		return row, fmt.Errorf("invalid date %q", field(cols.date))
+	}
+	row.ExpenseDate = date
+
+	var amount decimal.Decimal
+	switch m.Sign {
+	case SignSplit:
+		if amount, err = parseOptionalAmount(field(cols.outflow)); err != nil {
+			return row, err
+		}
+		if amount.IsZero() {
+			inflow, err := parseOptionalAmount(field(cols.inflow))
+			if err != nil {
+				return row, err
+			}
+			if inflow.IsPositive() {
+				return row, errNotExpense
+			}
+		}
+	default:
+		if amount, err = parseAmount(field(cols.amount)); err != nil {
+			return row, err
+		}
+		if m.Sign == SignNegative {
+			if amount.IsPositive() {
+				return row, errNotExpense
+			}
+			amount = amount.Neg()
+		}
+		if m.Sign == SignType && !slices.ContainsFunc(m.DebitTypes, func(t string) bool {
+			return strings.EqualFold(t, field(cols.typ))
+		}) {
+			return row, errNotExpense
+		}
+	}
+	if !amount.IsPositive() {
+		return row, errors.New("amount must be greater than 0")
+	}
+	row.Amount = amount.Round(2)
+
+	if description := field(cols.description); description != "" {
+		if len(description) > 255 {
+			return row, errors.New("description is longer than 255 characters")
+		}
+		row.Description = &description
+	}
+
+	row.Category = field(cols.category)
+	if renamed, ok := m.Categories[strings.ToLower(row.Category)]; ok {
+		row.Category = renamed
+	}
+	if len(row.Category) > 100 {
+		return row, errors.New("category is longer than 100 characters")
+	}
+	row.Merchant = field(cols.merchant)
+	return row, nil
+}
+
+// categoryResolver matches category names to the user's categories,
+// creating the ones that do not exist yet
+type categoryResolver struct {
+	userID  uuid.UUID
+	byName  map[string]uuid.UUID
+	created []string
+}
+
+func newCategoryResolver(ctx context.Context, tx pgx.Tx, userID uuid.UUID) (*categoryResolver, error) {
+	rows, err := tx.Query(ctx, "SELECT id, name FROM expense_categories WHERE user_id = $1 AND deleted_at IS NULL", userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	r := &categoryResolver{userID: userID, byName: map[string]uuid.UUID{}, created: []string{}}
+	for rows.Next() {
+		var id uuid.UUID
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return nil, err
+		}
+		r.byName[strings.ToLower(name)] = id
+	}
+	return r, rows.Err()
+}
+
+func (r *categoryResolver) resolve(ctx context.Context, tx pgx.Tx, name string) (*uuid.UUID, error) {
+	if name == "" {
+		return nil, nil
+	}
+	if id, ok := r.byName[strings.ToLower(name)]; ok {
+		return &id, nil
+	}
+
+	var id uuid.UUID
+	err := tx.QueryRow(ctx,
+		`INSERT INTO expense_categories (user_id, name) VALUES ($1, $2)
+		 ON CONFLICT (user_id, name) WHERE deleted_at IS NULL DO UPDATE SET name = EXCLUDED.name
+		 RETURNING id`,
+		r.userID, name).Scan(&id)
+	if err != nil {
+		return nil, err
+	}
+	r.byName[strings.ToLower(name)] = id
+	r.created = append(r.created, name)
+	return &id, nil
+}
+
+func (r *ImportReport) addError(line int, err error) {
+	r.Failed++
+	if len(r.Errors) < maxImportErrors {
+		r.Errors = append(r.Errors, ImportRowError{Row: line, Error: err.Error()})
+	}
+}
+
+// flushImport inserts a batch of rows and records which were duplicates
+func flushImport(ctx context.Context, tx pgx.Tx, userID uuid.UUID, rows []importRow, report *ImportReport) error {
+	batch := &pgx.Batch{}
+	for _, row := range rows {
+		line := row.Line
+		batch.Queue(importExpenseSQL,
+			userID, row.CategoryID, row.Amount, row.Description, row.ExpenseDate, row.MerchantID, row.MerchantName,
+		).QueryRow(func(r pgx.Row) error {
+			var id uuid.UUID
+			if err := r.Scan(&id); err != nil {
+				if errors.Is(err, pgx.ErrNoRows) {
+					report.Duplicates++
+					report.DuplicateRows = append(report.DuplicateRows, line)
+					return nil
+				}
+				return err
+			}
+			report.Imported++
+			return nil
+		})
+	}
+	return tx.SendBatch(ctx, batch).Close()
+}
+
+// ImportExpenses imports personal expenses from a CSV upload. The multipart
+// body holds either a "mapping" field with an ImportMapping or a "profile"
+// field naming a saved or built-in profile, followed by a "file" part,
+// which is parsed as it streams in. Invalid rows and duplicates are
+// skipped and listed in the report; everything else is imported in one
+// transaction.
+func ImportExpenses(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
+		c.JSON(400, gin.H{"error": "expected a multipart/form-data body"})
+		return
+	}
+
+	var mapping *ImportMapping
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			c.JSON(400, gin.H{"error": "missing file"})
+			return
+		}
+		if err != nil {
+			c.JSON(400, gin.H{"error": "invalid multipart body"})
+			return
+		}
+
+		if (part.FormName() == "mapping" || part.FormName() == "profile") && mapping != nil {
+			c.JSON(400, gin.H{"error": "send either a mapping or a profile"})
+			return
+		}
+
+		switch part.FormName() {
+		case "profile":
+			name, err := io.ReadAll(io.LimitReader(part, 200))
+			if err != nil {
+				c.JSON(400, gin.H{"error": "invalid multipart body"})
+				return
+			}
+			profile, err := findImportProfile(c.Request.Context(), db, userID, strings.TrimSpace(string(name)))
+			if errors.Is(err, errUnknownProfile) {
+				c.JSON(400, gin.H{"error": fmt.Sprintf("unknown import profile %q", strings.TrimSpace(string(name)))})
+				return
+			}
+			if err != nil {
+				c.JSON(500, gin.H{"error": "failed to get import profile"})
+				return
+			}
+			mapping = &profile.Mapping
+		case "mapping":
+			mapping = &ImportMapping{}
+			if err := json.NewDecoder(part).Decode(mapping); err != nil {
+				c.JSON(400, gin.H{"error": "invalid mapping: " + err.Error()})
+				return
+			}
+			if err := validation.Struct(mapping); err != nil {
+				validation.Respond(c, err)
+				return
+			}
+		case "file":
+			if mapping == nil {
+				c.JSON(400, gin.H{"error": "mapping or profile must be sent before file"})
+				return
+			}
+			importCSV(c, db, userID, *mapping, part)
+			return
+		}
+	}
+}
+
+func importCSV(c *gin.Context, db *db.DB, userID uuid.UUID, mapping ImportMapping, file io.Reader) {
+	ctx := c.Request.Context()
+	mapping = mapping.withDefaults()
+
+	csvReader := csv.NewReader(file)
+	csvReader.FieldsPerRecord = -1
+	csvReader.ReuseRecord = true
+
+	header, err := csvReader.Read()
+	if err != nil {
+		c.JSON(400, gin.H{"error": "failed to read CSV header"})
+		return
+	}
+	cols, err := resolveColumns(header, mapping)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	var resolver *categoryResolver
+	report := ImportReport{DuplicateRows: []int{}, Errors: []ImportRowError{}}
+	err = db.WithTx(ctx, func(tx pgx.Tx) error {
+		var err error
+		resolver, err = newCategoryResolver(ctx, tx, userID)
+		if err != nil {
+			return helpers.NewRequestError(500, "failed to load categories")
+		}
+
+		pending := make([]importRow, 0, importBatchSize)
+		merchants := map[string]*merchant.Resolved{}
+
+		// Line 1 is the header
+		for line := 2; ; line++ {
+			record, err := csvReader.Read()
+			if err == io.EOF {
+				break
+			}
+			if line-1 > maxImportRows {
+				return helpers.NewRequestError(400, fmt.Sprintf("file has more than %d rows", maxImportRows))
+			}
+			if err != nil {
+				var parseErr *csv.ParseError
+				if !errors.As(err, &parseErr) {
+					return helpers.NewRequestError(400, "failed to read CSV")
+				}
+				report.addError(line, err)
+				continue
+			}
+
+			row, err := parseImportRow(record, cols, mapping)
+			if errors.Is(err, errNotExpense) {
+				report.Skipped++
+				continue
+			}
+			if err != nil {
+				report.addError(line, err)
+				continue
+			}
+			row.Line = line
+
+			row.CategoryID, err = resolver.resolve(ctx, tx, row.Category)
+			if err != nil {
+				return helpers.NewRequestError(500, "failed to create category")
+			}
+
+			// Normalize each distinct merchant once per file
+			if key := merchant.Key(row.Merchant); key != "" {
+				m, seen := merchants[key]
+				if !seen {
+					if m, err = merchant.Resolve(ctx, tx, userID, row.Merchant); err != nil {
+						return helpers.NewRequestError(500, "failed to resolve merchant")
+					}
+					merchants[key] = m
+				}
+				row.MerchantID, row.MerchantName = &m.ID, &m.Name
+				if row.CategoryID == nil {
+					row.CategoryID = m.CategoryID
+				}
+			}
+
+			pending = append(pending, row)
+			if len(pending) == importBatchSize {
+				if err := flushImport(ctx, tx, userID, pending, &report); err != nil {
+					return err
+				}
+				pending = pending[:0]
+			}
+		}
+
+		if len(pending) > 0 {
+			return flushImport(ctx, tx, userID, pending, &report)
+		}
+		return nil
+	})
+	if err != nil {
+		helpers.RespondError(c, err, "failed to import expenses")
+		return
+	}
+
+	report.CategoriesCreated = resolver.created
+	c.JSON(200, report)
+}
@@ -0,0 +1,196 @@
+package personalexpense
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/validation"
+)
+
+func TestResolveColumns(t *testing.T) {
+	header := []string{"Date", " Amount ", "Memo", "Category"}
+
+	cols, err := resolveColumns(header, ImportMapping{Date: "date", Amount: "AMOUNT", Description: "Memo"})
+	require.NoError(t, err)
+	assert.Equal(t, columnIndexes{date: 0, amount: 1, outflow: -1, inflow: -1, typ: -1, description: 2, category: -1, merchant: -1}, cols)
+
+	_, err = resolveColumns(header, ImportMapping{Date: "Date", Amount: "Total"})
+	assert.Error(t, err)
+}
+
+func TestParseImportRow(t *testing.T) {
+	cols := columnIndexes{date: 0, amount: 1, outflow: -1, inflow: -1, typ: -1, description: 2, category: 3, merchant: -1}
+
+	tests := []struct {
+		name    string
+		record  []string
+		amount  string
+		wantErr bool
+	}{
+		{name: "plain", record: []string{"2025-01-20", "12.50", "Lunch", "Food"}, amount: "12.5"},
+		{name: "thousands separator and symbol", record: []string{"2025-01-20", "$1,200.00", "Rent", ""}, amount: "1200"},
+		{name: "invalid date", record: []string{"20/01/2025", "12.50", "Lunch", "Food"}, wantErr: true},
+		{name: "zero amount", record: []string{"2025-01-20", "0", "Lunch", "Food"}, wantErr: true},
+		{name: "negative amount", record: []string{"2025-01-20", "-3", "Lunch", "Food"}, wantErr: true},
+		{name: "short record", record: []string{"2025-01-20"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			row, err := parseImportRow(tt.record, cols, ImportMapping{}.withDefaults())
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.amount, row.Amount.String())
+		})
+	}
+}
+
+func TestParseImportRowSign(t *testing.T) {
+	tests := []struct {
+		name    string
+		mapping ImportMapping
+		header  []string
+		record  []string
+		amount  string
+		err     error
+	}{
+		{
+			name:    "negative expense",
+			mapping: ImportMapping{Date: "Date", Amount: "Amount", Sign: SignNegative},
+			header:  []string{"Date", "Amount"},
+			record:  []string{"2025-01-20", "-$1,200.00"},
+			amount:  "1200",
+		},
+		{
+			name:    "negative convention skips income",
+			mapping: ImportMapping{Date: "Date", Amount: "Amount", Sign: SignNegative},
+			header:  []string{"Date", "Amount"},
+			record:  []string{"2025-01-20", "25.00"},
+			err:     errNotExpense,
+		},
+		{
+			name:    "outflow",
+			mapping: ImportMapping{Date: "Date", Sign: SignSplit, Outflow: "Outflow", Inflow: "Inflow"},
+			header:  []string{"Date", "Outflow", "Inflow"},
+			record:  []string{"2025-01-20", "$12.50", "$0.00"},
+			amount:  "12.5",
+		},
+		{
+			name:    "inflow is skipped",
+			mapping: ImportMapping{Date: "Date", Sign: SignSplit, Outflow: "Outflow", Inflow: "Inflow"},
+			header:  []string{"Date", "Outflow", "Inflow"},
+			record:  []string{"2025-01-20", "", "100"},
+			err:     errNotExpense,
+		},
+		{
+			name:    "debit type",
+			mapping: ImportMapping{Date: "Date", Amount: "Amount", Sign: SignType, Type: "Type"},
+			header:  []string{"Date", "Amount", "Type"},
+			record:  []string{"2025-01-20", "8", "DEBIT"},
+			amount:  "8",
+		},
+		{
+			name:    "credit type is skipped",
+			mapping: ImportMapping{Date: "Date", Amount: "Amount", Sign: SignType, Type: "Type"},
+			header:  []string{"Date", "Amount", "Type"},
+			record:  []string{"2025-01-20", "8", "credit"},
+			err:     errNotExpense,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := tt.mapping.withDefaults()
+			cols, err := resolveColumns(tt.header, m)
+			require.NoError(t, err)
+			row, err := parseImportRow(tt.record, cols, m)
+			if tt.err != nil {
+				assert.ErrorIs(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.amount, row.Amount.String())
+		})
+	}
+
+	_, err := resolveColumns([]string{"Date", "Amount"}, ImportMapping{Date: "Date", Sign: SignSplit, Outflow: "Outflow"})
+	assert.Error(t, err, "the outflow column is required")
+}
+
+func TestImportCategoryMapping(t *testing.T) {
+	m := ImportMapping{Date: "Date", Amount: "Amount", Category: "Category",
+		Categories: map[string]string{" Restaurants ": "Food", "Transfer": ""}}.withDefaults()
+	cols, err := resolveColumns([]string{"Date", "Amount", "Category"}, m)
+	require.NoError(t, err)
+
+	row, err := parseImportRow([]string{"2025-01-20", "12", "restaurants"}, cols, m)
+	require.NoError(t, err)
+	assert.Equal(t, "Food", row.Category)
+
+	row, err = parseImportRow([]string{"2025-01-20", "12", "Transfer"}, cols, m)
+	require.NoError(t, err)
+	assert.Empty(t, row.Category)
+
+	row, err = parseImportRow([]string{"2025-01-20", "12", "Books"}, cols, m)
+	require.NoError(t, err)
+	assert.Equal(t, "Books", row.Category, "unmapped categories are kept")
+}
+
+func TestBuiltInProfiles(t *testing.T) {
+	tests := []struct {
+		profile string
+		header  []string
+		record  []string
+		amount  string
+	}{
+		{
+			profile: "ynab",
+			header:  []string{"Account", "Flag", "Date", "Payee", "Category Group/Category", "Category Group", "Category", "Memo", "Outflow", "Inflow", "Cleared"},
+			record:  []string{"Checking", "", "01/20/2025", "Blue Bottle", "Food: Coffee", "Food", "Coffee", "", "$4.50", "$0.00", "Cleared"},
+			amount:  "4.5",
+		},
+		{
+			profile: "mint",
+			header:  []string{"Date", "Description", "Original Description", "Amount", "Transaction Type", "Category", "Account Name", "Labels", "Notes"},
+			record:  []string{"1/20/2025", "Blue Bottle", "BLUE BOTTLE #12", "4.50", "debit", "Coffee Shops", "Visa", "", ""},
+			amount:  "4.5",
+		},
+		{
+			profile: "chase",
+			header:  []string{"Transaction Date", "Post Date", "Description", "Category", "Type", "Amount", "Memo"},
+			record:  []string{"01/20/2025", "01/21/2025", "BLUE BOTTLE", "Food & Drink", "Sale", "-4.50", ""},
+			amount:  "4.5",
+		},
+		{
+			profile: "amex",
+			header:  []string{"Date", "Description", "Amount"},
+			record:  []string{"01/20/2025", "BLUE BOTTLE", "4.50"},
+			amount:  "4.5",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.profile, func(t *testing.T) {
+			var profile ImportProfile
+			for _, p := range builtInProfiles {
+				if p.Name == tt.profile {
+					profile = p
+				}
+			}
+			require.NoError(t, validation.Struct(&profile.Mapping))
+
+			m := profile.Mapping.withDefaults()
+			cols, err := resolveColumns(tt.header, m)
+			require.NoError(t, err)
+			row, err := parseImportRow(tt.record, cols, m)
+			require.NoError(t, err)
+			assert.Equal(t, tt.amount, row.Amount.String())
+			assert.Equal(t, "2025-01-20", row.ExpenseDate.Format("2006-01-02"))
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package personalexpense
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/category"
+	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+	"github.com/yanonymousV2/finance-manager-backend/internal/event"
+	"github.com/yanonymousV2/finance-manager-backend/internal/notification"
+	"github.com/yanonymousV2/finance-manager-backend/internal/requestid"
+)
+
+// ExpenseResponse is a saved expense together with its category's spending
+// against the monthly limit, when the category has one
+type ExpenseResponse struct {
+	PersonalExpense
+	CategoryLimit *category.LimitStatus `json:"category_limit,omitempty"`
+}
+
+// checkCategoryLimit returns the limit status of the expense's category and
+// notifies the user, and records a budget.exceeded event, when this
+// expense took the category over its limit.
+// The expense is already saved, so failures are only logged.
+func checkCategoryLimit(ctx context.Context, db *db.DB, e PersonalExpense) *category.LimitStatus {
+	if e.CategoryID == nil {
+		return nil
+	}
+
+	status, err := category.CheckLimit(ctx, db, *e.CategoryID, e.ExpenseDate, e.Currency)
+	if err != nil {
+		requestid.Logger(ctx).Error("failed to check category limit", "category_id", *e.CategoryID, "error", err)
+		return nil
+	}
+	if status == nil || !status.Exceeded {
+		return status
+	}
+
+	// Only the expense that crosses the limit notifies, not every one after
+	if status.Spent.Sub(e.Amount).GreaterThan(status.MonthlyLimit) {
+		return status
+	}
+	data := map[string]any{
+		"category_id":   status.CategoryID,
+		"category_name": status.CategoryName,
+		"month":         status.Month,
+		"year":          status.Year,
+		"monthly_limit": status.MonthlyLimit,
+		"spent":         status.Spent,
+		"currency":      e.Currency,
+		"expense_id":    e.ID,
+	}
+	err = db.WithTx(ctx, func(tx pgx.Tx) error {
+		if _, err := notification.Create(ctx, tx, e.UserID, nil, notification.TypeCategoryLimit, data); err != nil {
+			return err
+		}
+		return event.Record(ctx, tx, event.Event{Type: event.BudgetExceeded, UserID: e.UserID, Data: data})
+	})
+	if err != nil {
+		requestid.Logger(ctx).Error("failed to notify user of category limit", "user_id", e.UserID, "error", err)
+	}
+	return status
+}
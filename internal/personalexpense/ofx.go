@@ -0,0 +1,415 @@
+package personalexpense
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+	"github.com/yanonymousV2/finance-manager-backend/internal/fxrate"
+	"github.com/yanonymousV2/finance-manager-backend/internal/helpers"
+	"github.com/yanonymousV2/finance-manager-backend/internal/merchant"
+	"github.com/yanonymousV2/finance-manager-backend/internal/middleware"
+)
+
+// maxOFXSize caps an uploaded OFX or QFX file
+const maxOFXSize = 10 << 20
+
+// A transaction is a duplicate when one with the same FITID was already
+// imported into the account, including earlier in the same file
+const importOFXSQL = `INSERT INTO personal_expenses (user_id, account_id, category_id, amount, currency, description, expense_date,
+	                                merchant_id, merchant, original_amount, original_currency, fx_rate, ofx_fitid, updated_at)
+	 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, NOW())
+	 ON CONFLICT (account_id, ofx_fitid) WHERE ofx_fitid IS NOT NULL DO NOTHING
+	 RETURNING id`
+
+type OFXTransactionError struct {
+	FITID string `json:"fitid,omitempty"`
+	Error string `json:"error"`
+}
+
+type OFXImportReport struct {
+	AccountID    uuid.UUID             `json:"account_id"`
+	Transactions int                   `json:"transactions"`
+	Imported     int                   `json:"imported"`
+	Duplicates   int                   `json:"duplicates"`
+	Skipped      int                   `json:"skipped"`
+	Failed       int                   `json:"failed"`
+	Errors       []OFXTransactionError `json:"errors"`
+}
+
+func (r *OFXImportReport) addError(fitID string, err error) {
+	r.Failed++
+	if len(r.Errors) < maxImportErrors {
+		r.Errors = append(r.Errors, OFXTransactionError{FITID: fitID, Error: err.Error()})
+	}
+}
+
+// ofxTransaction is a STMTTRN of a statement, as written in the file.
+// Currency is the statement's CURDEF unless the transaction names its own.
+type ofxTransaction struct {
+	Type     string
+	FITID    string
+	Posted   string
+	Amount   string
+	Name     string
+	Memo     string
+	Currency string
+}
+
+// parseOFX reads the transactions of every bank and credit card statement
+// in an OFX or QFX file. Both OFX 1.x, which is SGML whose elements need no
+// end tags, and OFX 2.x, which is XML, are read by the same scan: an
+// element's value is the text up to the next tag, and aggregates are
+// tracked by their start and end tags.
+func parseOFX(data []byte) ([]ofxTransaction, error) {
+	start := bytes.Index(bytes.ToUpper(data), []byte("<OFX>"))
+	if start < 0 {
+		return nil, errors.New("not an OFX file")
+	}
+	data = data[start:]
+
+	var (
+		transactions []ofxTransaction
+		current      *ofxTransaction
+		statementCur string
+		inCurrency   bool
+	)
+	finish := func() {
+		if current != nil {
+			if current.Currency == "" {
+				current.Currency = statementCur
+			}
+			transactions = append(transactions, *current)
+			current = nil
+		}
+	}
+
+	for len(data) > 0 {
+		open := bytes.IndexByte(data, '<')
+		if open < 0 {
+			break
+		}
+		end := bytes.IndexByte(data[open:], '>')
+		if end < 0 {
+			return nil, errors.New("invalid OFX: unterminated tag")
+		}
+		tag := strings.ToUpper(strings.TrimSpace(string(data[open+1 : open+end])))
+		data = data[open+end+1:]
+
+		if closing, ok := strings.CutPrefix(tag, "/"); ok {
+			switch closing {
+			case "STMTTRN", "BANKTRANLIST":
+				finish()
+			case "CURRENCY", "ORIGCURRENCY":
+				inCurrency = false
+			}
+			continue
+		}
+
+		value := data
+		if next := bytes.IndexByte(data, '<'); next >= 0 {
+			value = data[:next]
+		}
+		text := html.UnescapeString(strings.TrimSpace(string(value)))
+
+		switch tag {
+		case "STMTTRN":
+			// 1.x files seen in the wild don't always end transactions
+			finish()
+			current = &ofxTransaction{}
+			if len(transactions) >= maxImportRows {
+				return nil, fmt.Errorf("file has more than %d transactions", maxImportRows)
+			}
+		case "CURDEF":
+			statementCur = strings.ToUpper(text)
+		case "CURRENCY":
+			// The amount is in CURSYM; ORIGCURRENCY only records the
+			// currency it was converted from, so it's ignored
+			inCurrency = true
+		case "ORIGCURRENCY":
+			inCurrency = false
+		}
+		if current == nil {
+			continue
+		}
+		switch tag {
+		case "TRNTYPE":
+			current.Type = strings.ToUpper(text)
+		case "FITID":
+			current.FITID = text
+		case "DTPOSTED":
+			current.Posted = text
+		case "TRNAMT":
+			current.Amount = text
+		case "NAME":
+			current.Name = text
+		case "MEMO":
+			current.Memo = text
+		case "CURSYM":
+			if inCurrency {
+				current.Currency = strings.ToUpper(text)
+			}
+		}
+	}
+	finish()
+
+	if len(transactions) == 0 && statementCur == "" {
+		return nil, errors.New("file has no bank or credit card statement")
+	}
+	return transactions, nil
+}
+
+// parseOFXDate reads the date of an OFX datetime, e.g.
+// 20250120120000.000[-5:EST]. The date is kept as the bank wrote it,
+// without moving it to another time zone.
+func parseOFXDate(s string) (time.Time, error) {
+	if len(s) < 8 {
+		return time.Time{}, fmt.Errorf("invalid date %q", s)
+	}
+	date, err := time.Parse("20060102", s[:8])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid date %q", s)
+	}
+	return date, nil
+}
+
+// parseOFXAmount reads a TRNAMT, which may use a comma as the decimal
+// separator
+func parseOFXAmount(s string) (decimal.Decimal, error) {
+	amount, err := decimal.NewFromString(strings.TrimPrefix(strings.ReplaceAll(s, ",", "."), "+"))
+	if err != nil {
+		return decimal.Decimal{}, fmt.Errorf("invalid amount %q", s)
+	}
+	return amount, nil
+}
+
+// payee is who a transaction was paid to: its NAME, or its MEMO when the
+// bank leaves NAME empty
+func (t ofxTransaction) payee() string {
+	if t.Name != "" {
+		return t.Name
+	}
+	return t.Memo
+}
+
+// toImportRow turns a statement transaction into an importRow with its
+// amount as spent, or returns ok false for credits, which aren't expenses
+func (t ofxTransaction) toImportRow() (row importRow, ok bool, err error) {
+	if t.FITID == "" {
+		return row, false, errors.New("missing FITID")
+	}
+	if len(t.FITID) > 255 {
+		return row, false, errors.New("FITID is longer than 255 characters")
+	}
+	if row.ExpenseDate, err = parseOFXDate(t.Posted); err != nil {
+		return row, false, err
+	}
+	amount, err := parseOFXAmount(t.Amount)
+	if err != nil {
+		return row, false, err
+	}
+	// Money leaving the account is negative
+	row.Amount = amount.Neg().Round(2)
+	if !row.Amount.IsPositive() {
+		return row, false, nil
+	}
+
+	if payee := t.payee(); payee != "" {
+		if runes := []rune(payee); len(runes) > 255 {
+			payee = string(runes[:255])
+		}
+		row.Description = &payee
+		row.Merchant = payee
+	}
+	return row, true, nil
+}
+
+// ofxRow is a statement transaction ready to be inserted
+type ofxRow struct {
+	importRow
+	FITID      string
+	Conversion conversion
+}
+
+// ImportOFX imports the spending in an OFX or QFX bank or credit card
+// statement into one of the user's accounts. The multipart body holds an
+// "account_id" field followed by a "file" part. Deposits and other credits
+// are skipped, transactions already imported into the account are matched
+// by their FITID, and amounts in another currency are converted at the
+// day's exchange rate.
+func ImportOFX(c *gin.Context, db *db.DB, rates *fxrate.Service) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	reader, err := c.Request.MultipartReader()
+	if err != nil {
+		c.JSON(400, gin.H{"error": "expected a multipart/form-data body"})
+		return
+	}
+
+	var accountID *uuid.UUID
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			c.JSON(400, gin.H{"error": "missing file"})
+			return
+		}
+		if err != nil {
+			c.JSON(400, gin.H{"error": "invalid multipart body"})
+			return
+		}
+
+		switch part.FormName() {
+		case "account_id":
+			value, err := io.ReadAll(io.LimitReader(part, 100))
+			if err != nil {
+				c.JSON(400, gin.H{"error": "invalid multipart body"})
+				return
+			}
+			id, err := uuid.Parse(strings.TrimSpace(string(value)))
+			if err != nil {
+				c.JSON(400, gin.H{"error": "invalid account_id"})
+				return
+			}
+			accountID = &id
+		case "file":
+			if accountID == nil {
+				c.JSON(400, gin.H{"error": "account_id must be sent before file"})
+				return
+			}
+			data, err := io.ReadAll(io.LimitReader(part, maxOFXSize+1))
+			if err != nil {
+				c.JSON(400, gin.H{"error": "failed to read file"})
+				return
+			}
+			if len(data) > maxOFXSize {
+				c.JSON(400, gin.H{"error": fmt.Sprintf("file is larger than %d MB", maxOFXSize>>20)})
+				return
+			}
+			importOFX(c, db, rates, userID, *accountID, data)
+			return
+		}
+	}
+}
+
+func importOFX(c *gin.Context, db *db.DB, rates *fxrate.Service, userID, accountID uuid.UUID, data []byte) {
+	ctx := c.Request.Context()
+
+	var ownerID uuid.UUID
+	var defaultCurrency string
+	err := db.Pool.QueryRow(ctx,
+		`SELECT a.user_id, u.default_currency FROM accounts a JOIN users u ON u.id = $2 WHERE a.id = $1`,
+		accountID, userID).Scan(&ownerID, &defaultCurrency)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid account"})
+		return
+	}
+	if ownerID != userID {
+		c.JSON(403, gin.H{"error": "account does not belong to user"})
+		return
+	}
+
+	transactions, err := parseOFX(data)
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	report := OFXImportReport{AccountID: accountID, Transactions: len(transactions), Errors: []OFXTransactionError{}}
+
+	// Amounts are converted before the transaction, since rates may have
+	// to be fetched
+	rows := make([]ofxRow, 0, len(transactions))
+	for _, t := range transactions {
+		row, ok, err := t.toImportRow()
+		if err != nil {
+			report.addError(t.FITID, err)
+			continue
+		}
+		if !ok {
+			report.Skipped++
+			continue
+		}
+		conv, err := convertOFXAmount(ctx, rates, row.Amount, t.Currency, defaultCurrency, row.ExpenseDate)
+		if err != nil {
+			report.addError(t.FITID, err)
+			continue
+		}
+		rows = append(rows, ofxRow{importRow: row, FITID: t.FITID, Conversion: conv})
+	}
+
+	err = db.WithTx(ctx, func(tx pgx.Tx) error {
+		merchants := map[string]*merchant.Resolved{}
+		for start := 0; start < len(rows); start += importBatchSize {
+			batch := &pgx.Batch{}
+			for _, row := range rows[start:min(start+importBatchSize, len(rows))] {
+				// Normalize each distinct merchant once per file
+				if key := merchant.Key(row.Merchant); key != "" {
+					m, seen := merchants[key]
+					if !seen {
+						var err error
+						if m, err = merchant.Resolve(ctx, tx, userID, row.Merchant); err != nil {
+							return helpers.NewRequestError(500, "failed to resolve merchant")
+						}
+						merchants[key] = m
+					}
+					row.MerchantID, row.MerchantName, row.CategoryID = &m.ID, &m.Name, m.CategoryID
+				}
+
+				conv := row.Conversion
+				batch.Queue(importOFXSQL,
+					userID, accountID, row.CategoryID, conv.Amount, conv.Currency, row.Description, row.ExpenseDate,
+					row.MerchantID, row.MerchantName, conv.OriginalAmount, conv.OriginalCurrency, conv.FXRate, row.FITID,
+				).QueryRow(func(r pgx.Row) error {
+					var id uuid.UUID
+					if err := r.Scan(&id); err != nil {
+						if errors.Is(err, pgx.ErrNoRows) {
+							report.Duplicates++
+							return nil
+						}
+						return err
+					}
+					report.Imported++
+					return nil
+				})
+			}
+			if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		helpers.RespondError(c, err, "failed to import statement")
+		return
+	}
+
+	c.JSON(200, report)
+}
+
+// convertOFXAmount converts a transaction's amount into the user's default
+// currency at the exchange rate of the day it was posted
+func convertOFXAmount(ctx context.Context, rates *fxrate.Service, amount decimal.Decimal, currency, defaultCurrency string, date time.Time) (conversion, error) {
+	if currency != "" && currency != defaultCurrency && rates == nil {
+		return conversion{}, fmt.Errorf("no exchange rate available for %s", currency)
+	}
+	fxRate, err := lookupRate(ctx, rates, currency, defaultCurrency, date, nil)
+	if err != nil {
+		return conversion{}, fmt.Errorf("no exchange rate available for %s on %s", currency, date.Format(time.DateOnly))
+	}
+	return convertAmount(amount, currency, defaultCurrency, fxRate)
+}
@@ -0,0 +1,109 @@
+package personalexpense
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const ofxSGML = `OFXHEADER:100
+DATA:OFXSGML
+VERSION:102
+ENCODING:USASCII
+CHARSET:1252
+
+<OFX>
+<SIGNONMSGSRSV1><SONRS><STATUS><CODE>0<SEVERITY>INFO</STATUS><DTSERVER>20250131</SONRS></SIGNONMSGSRSV1>
+<BANKMSGSRSV1><STMTTRNRS><TRNUID>1<STMTRS>
+<CURDEF>USD
+<BANKACCTFROM><BANKID>121000248<ACCTID>1234<ACCTTYPE>CHECKING</BANKACCTFROM>
+<BANKTRANLIST><DTSTART>20250101<DTEND>20250131
+<STMTTRN><TRNTYPE>DEBIT<DTPOSTED>20250120120000.000[-5:EST]<TRNAMT>-12.50<FITID>2025012001<NAME>BLUE BOTTLE COFFEE<MEMO>POS PURCHASE</STMTTRN>
+<STMTTRN><TRNTYPE>CREDIT<DTPOSTED>20250125<TRNAMT>2500.00<FITID>2025012501<NAME>PAYROLL</STMTTRN>
+<STMTTRN><TRNTYPE>POS<DTPOSTED>20250128<TRNAMT>-40,00<FITID>2025012801<MEMO>AT&amp;T WIRELESS
+<CURRENCY><CURRATE>1.04<CURSYM>EUR</CURRENCY>
+</STMTTRN>
+</BANKTRANLIST>
+</STMTRS></STMTTRNRS></BANKMSGSRSV1>
+</OFX>`
+
+const ofxXML = `<?xml version="1.0" encoding="UTF-8" standalone="no"?>
+<?OFX OFXHEADER="200" VERSION="220" SECURITY="NONE" OLDFILEUID="NONE" NEWFILEUID="NONE"?>
+<OFX>
+  <CREDITCARDMSGSRSV1>
+    <CCSTMTTRNRS>
+      <CCSTMTRS>
+        <CURDEF>GBP</CURDEF>
+        <BANKTRANLIST>
+          <STMTTRN>
+            <TRNTYPE>DEBIT</TRNTYPE>
+            <DTPOSTED>20250203</DTPOSTED>
+            <TRNAMT>-8.99</TRNAMT>
+            <FITID>tx-1</FITID>
+            <NAME>Netflix</NAME>
+          </STMTTRN>
+        </BANKTRANLIST>
+      </CCSTMTRS>
+    </CCSTMTTRNRS>
+  </CREDITCARDMSGSRSV1>
+</OFX>`
+
+func TestParseOFX(t *testing.T) {
+	transactions, err := parseOFX([]byte(ofxSGML))
+	require.NoError(t, err)
+	assert.Equal(t, []ofxTransaction{
+		{Type: "DEBIT", FITID: "2025012001", Posted: "20250120120000.000[-5:EST]", Amount: "-12.50", Name: "BLUE BOTTLE COFFEE", Memo: "POS PURCHASE", Currency: "USD"},
+		{Type: "CREDIT", FITID: "2025012501", Posted: "20250125", Amount: "2500.00", Name: "PAYROLL", Currency: "USD"},
+		{Type: "POS", FITID: "2025012801", Posted: "20250128", Amount: "-40,00", Memo: "AT&T WIRELESS", Currency: "EUR"},
+	}, transactions)
+
+	transactions, err = parseOFX([]byte(ofxXML))
+	require.NoError(t, err)
+	assert.Equal(t, []ofxTransaction{
+		{Type: "DEBIT", FITID: "tx-1", Posted: "20250203", Amount: "-8.99", Name: "Netflix", Currency: "GBP"},
+	}, transactions)
+
+	_, err = parseOFX([]byte("Date,Amount\n2025-01-20,12.50\n"))
+	assert.Error(t, err)
+	_, err = parseOFX([]byte("<OFX><SIGNONMSGSRSV1></SIGNONMSGSRSV1></OFX>"))
+	assert.Error(t, err, "no statement")
+}
+
+func TestOFXTransactionToImportRow(t *testing.T) {
+	transactions, err := parseOFX([]byte(ofxSGML))
+	require.NoError(t, err)
+
+	row, ok, err := transactions[0].toImportRow()
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "12.5", row.Amount.String())
+	assert.Equal(t, "2025-01-20", row.ExpenseDate.Format("2006-01-02"))
+	assert.Equal(t, "BLUE BOTTLE COFFEE", *row.Description)
+	assert.Equal(t, "BLUE BOTTLE COFFEE", row.Merchant)
+
+	_, ok, err = transactions[1].toImportRow()
+	require.NoError(t, err)
+	assert.False(t, ok, "a deposit is skipped")
+
+	row, ok, err = transactions[2].toImportRow()
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, "40", row.Amount.String())
+	assert.Equal(t, "AT&T WIRELESS", *row.Description)
+
+	tests := []struct {
+		name string
+		tx   ofxTransaction
+	}{
+		{name: "missing FITID", tx: ofxTransaction{Posted: "20250120", Amount: "-1"}},
+		{name: "invalid date", tx: ofxTransaction{FITID: "1", Posted: "2025-01", Amount: "-1"}},
+		{name: "invalid amount", tx: ofxTransaction{FITID: "1", Posted: "20250120", Amount: "twelve"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := tt.tx.toImportRow()
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,311 @@
+package personalexpense
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+	"github.com/yanonymousV2/finance-manager-backend/internal/middleware"
+	"github.com/yanonymousV2/finance-manager-backend/internal/validation"
+)
+
+// maxImportProfiles is how many profiles one user can save
+const maxImportProfiles = 50
+
+// ImportProfile is a named ImportMapping. Built-in profiles cover the CSV
+// exports of common tools and have no ID; a saved profile with the same
+// name replaces one for its user.
+type ImportProfile struct {
+	ID        *uuid.UUID    `json:"id,omitempty"`
+	Name      string        `json:"name"`
+	BuiltIn   bool          `json:"built_in"`
+	Mapping   ImportMapping `json:"mapping"`
+	CreatedAt *time.Time    `json:"created_at,omitempty"`
+	UpdatedAt *time.Time    `json:"updated_at,omitempty"`
+}
+
+type CreateImportProfileRequest struct {
+	Name    string        `json:"name" validate:"required,max=100"`
+	Mapping ImportMapping `json:"mapping"`
+}
+
+// UpdateImportProfileRequest changes the fields that are set. A mapping
+// replaces the whole current one.
+type UpdateImportProfileRequest struct {
+	Name    *string        `json:"name,omitempty" validate:"omitempty,min=1,max=100"`
+	Mapping *ImportMapping `json:"mapping,omitempty"`
+}
+
+// starterCategories maps the categories of bank and budgeting exports to
+// the starter set new users get
+var starterCategories = map[string]string{
+	"Restaurants":            "Food",
+	"Fast Food":              "Food",
+	"Coffee Shops":           "Food",
+	"Food & Dining":          "Food",
+	"Food & Drink":           "Food",
+	"Groceries":              "Groceries",
+	"Auto & Transport":       "Transport",
+	"Gas & Fuel":             "Transport",
+	"Gas":                    "Transport",
+	"Public Transportation":  "Transport",
+	"Rental Car & Taxi":      "Transport",
+	"Parking":                "Transport",
+	"Mortgage & Rent":        "Rent",
+	"Bills & Utilities":      "Utilities",
+	"Utilities":              "Utilities",
+	"Mobile Phone":           "Utilities",
+	"Internet":               "Utilities",
+	"Entertainment":          "Entertainment",
+	"Movies & DVDs":          "Entertainment",
+	"Music":                  "Entertainment",
+	"Health & Fitness":       "Health",
+	"Health & Wellness":      "Health",
+	"Doctor":                 "Health",
+	"Pharmacy":               "Health",
+	"Shopping":               "Shopping",
+	"Clothing":               "Shopping",
+	"Electronics & Software": "Shopping",
+	"Travel":                 "Travel",
+	"Air Travel":             "Travel",
+	"Hotel":                  "Travel",
+}
+
+// builtInProfiles are listed in this order
+var builtInProfiles = []ImportProfile{
+	{Name: "ynab", BuiltIn: true, Mapping: ImportMapping{
+		Date: "Date", DateFormat: "01/02/2006", Sign: SignSplit, Outflow: "Outflow", Inflow: "Inflow",
+		Description: "Memo", Merchant: "Payee", Category: "Category",
+	}},
+	{Name: "mint", BuiltIn: true, Mapping: ImportMapping{
+		Date: "Date", DateFormat: "1/02/2006", Amount: "Amount", Sign: SignType, Type: "Transaction Type",
+		DebitTypes: []string{"debit"}, Description: "Description", Merchant: "Description", Category: "Category",
+		Categories: starterCategories,
+	}},
+	{Name: "chase", BuiltIn: true, Mapping: ImportMapping{
+		Date: "Transaction Date", DateFormat: "01/02/2006", Amount: "Amount", Sign: SignNegative,
+		Description: "Description", Merchant: "Description", Category: "Category", Categories: starterCategories,
+	}},
+	{Name: "amex", BuiltIn: true, Mapping: ImportMapping{
+		Date: "Date", DateFormat: "01/02/2006", Amount: "Amount", Sign: SignPositive,
+		Description: "Description", Merchant: "Description",
+	}},
+}
+
+// errUnknownProfile is returned for a name that's neither saved nor built in
+var errUnknownProfile = errors.New("unknown import profile")
+
+const importProfileColumns = "id, name, mapping, created_at, updated_at"
+
+func scanImportProfile(row interface{ Scan(...any) error }) (ImportProfile, error) {
+	var p ImportProfile
+	var id uuid.UUID
+	var createdAt, updatedAt time.Time
+	if err := row.Scan(&id, &p.Name, &p.Mapping, &createdAt, &updatedAt); err != nil {
+		return p, err
+	}
+	p.ID, p.CreatedAt, p.UpdatedAt = &id, &createdAt, &updatedAt
+	return p, nil
+}
+
+// findImportProfile looks up a profile by name, case-insensitively: the
+// user's saved ones first, then the built-in ones
+func findImportProfile(ctx context.Context, db *db.DB, userID uuid.UUID, name string) (ImportProfile, error) {
+	p, err := scanImportProfile(db.Pool.QueryRow(ctx,
+		`SELECT `+importProfileColumns+` FROM import_profiles WHERE user_id = $1 AND LOWER(name) = LOWER($2)`,
+		userID, name))
+	if err == nil || !errors.Is(err, pgx.ErrNoRows) {
+		return p, err
+	}
+	for _, p := range builtInProfiles {
+		if strings.EqualFold(p.Name, name) {
+			return p, nil
+		}
+	}
+	return ImportProfile{}, errUnknownProfile
+}
+
+// ListImportProfiles returns the user's saved profiles followed by the
+// built-in ones they haven't replaced
+func ListImportProfiles(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	rows, err := db.Pool.Query(c.Request.Context(),
+		`SELECT `+importProfileColumns+` FROM import_profiles WHERE user_id = $1 ORDER BY LOWER(name)`, userID)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to retrieve import profiles"})
+		return
+	}
+	defer rows.Close()
+
+	profiles := []ImportProfile{}
+	saved := map[string]bool{}
+	for rows.Next() {
+		p, err := scanImportProfile(rows)
+		if err != nil {
+			c.JSON(500, gin.H{"error": "failed to scan import profile"})
+			return
+		}
+		profiles = append(profiles, p)
+		saved[strings.ToLower(p.Name)] = true
+	}
+	for _, p := range builtInProfiles {
+		if !saved[p.Name] {
+			profiles = append(profiles, p)
+		}
+	}
+
+	c.JSON(200, profiles)
+}
+
+// CreateImportProfile saves a mapping under a name for later imports
+func CreateImportProfile(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req CreateImportProfileRequest
+	if !validation.Bind(c, &req) {
+		return
+	}
+
+	var count int
+	err := db.Pool.QueryRow(c.Request.Context(),
+		"SELECT COUNT(*) FROM import_profiles WHERE user_id = $1", userID).Scan(&count)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to check import profiles"})
+		return
+	}
+	if count >= maxImportProfiles {
+		c.JSON(409, gin.H{"error": fmt.Sprintf("at most %d import profiles are allowed", maxImportProfiles)})
+		return
+	}
+
+	profile, err := scanImportProfile(db.Pool.QueryRow(c.Request.Context(),
+		`INSERT INTO import_profiles (user_id, name, mapping) VALUES ($1, $2, $3) RETURNING `+importProfileColumns,
+		userID, strings.TrimSpace(req.Name), req.Mapping))
+	if err != nil {
+		respondProfileError(c, err, "failed to create import profile")
+		return
+	}
+
+	c.JSON(201, profile)
+}
+
+// updateImportProfileSQL changes the fields given; a NULL argument leaves its
+// column as it is
+const updateImportProfileSQL = `UPDATE import_profiles SET
+	name = COALESCE(@name, name),
+	mapping = COALESCE(@mapping, mapping),
+	updated_at = NOW()
+WHERE id = @id
+RETURNING ` + importProfileColumns
+
+// UpdateImportProfile renames a saved profile or replaces its mapping
+func UpdateImportProfile(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	profileID, ok := ownImportProfile(c, db, userID)
+	if !ok {
+		return
+	}
+
+	var req UpdateImportProfileRequest
+	if !validation.Bind(c, &req) {
+		return
+	}
+
+	if req.Name == nil && req.Mapping == nil {
+		c.JSON(400, gin.H{"error": "no fields to update"})
+		return
+	}
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		req.Name = &name
+	}
+
+	profile, err := scanImportProfile(db.Pool.QueryRow(c.Request.Context(), updateImportProfileSQL, pgx.NamedArgs{
+		"id":      profileID,
+		"name":    req.Name,
+		"mapping": req.Mapping,
+	}))
+	if err != nil {
+		respondProfileError(c, err, "failed to update import profile")
+		return
+	}
+
+	c.JSON(200, profile)
+}
+
+// DeleteImportProfile removes a saved profile. A built-in profile it
+// replaced is available again.
+func DeleteImportProfile(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	profileID, ok := ownImportProfile(c, db, userID)
+	if !ok {
+		return
+	}
+
+	if _, err := db.Pool.Exec(c.Request.Context(), "DELETE FROM import_profiles WHERE id = $1", profileID); err != nil {
+		c.JSON(500, gin.H{"error": "failed to delete import profile"})
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "import profile deleted successfully"})
+}
+
+// respondProfileError reports a taken name as a conflict
+func respondProfileError(c *gin.Context, err error, msg string) {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "23505" { // unique_violation
+		c.JSON(409, gin.H{"error": "an import profile with this name already exists"})
+		return
+	}
+	c.JSON(500, gin.H{"error": msg})
+}
+
+// ownImportProfile parses the profile ID in the path and checks userID owns
+// it. On failure it has already responded.
+func ownImportProfile(c *gin.Context, db *db.DB, userID uuid.UUID) (uuid.UUID, bool) {
+	profileID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid import profile id"})
+		return uuid.Nil, false
+	}
+
+	var ownerID uuid.UUID
+	err = db.Pool.QueryRow(c.Request.Context(),
+		"SELECT user_id FROM import_profiles WHERE id = $1", profileID).Scan(&ownerID)
+	if err != nil {
+		c.JSON(404, gin.H{"error": "import profile not found"})
+		return uuid.Nil, false
+	}
+	if ownerID != userID {
+		c.JSON(403, gin.H{"error": "not authorized to access this import profile"})
+		return uuid.Nil, false
+	}
+	return profileID, true
+}
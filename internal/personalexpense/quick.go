@@ -0,0 +1,180 @@
+package personalexpense
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+	"github.com/yanonymousV2/finance-manager-backend/internal/helpers"
+	"github.com/yanonymousV2/finance-manager-backend/internal/middleware"
+	"github.com/yanonymousV2/finance-manager-backend/internal/validation"
+)
+
+type QuickEntryRequest struct {
+	Text string `json:"text" validate:"required,max=255"`
+	// Create saves the parsed expense instead of only returning the draft
+	Create bool `json:"create,omitempty"`
+}
+
+// QuickDraft is an expense parsed from a quick entry, for the client to
+// confirm. CategoryID is nil when the #category matches none of the user's
+// active categories.
+type QuickDraft struct {
+	Amount      decimal.Decimal `json:"amount"`
+	ExpenseDate time.Time       `json:"expense_date"`
+	Description *string         `json:"description,omitempty"`
+	Category    *string         `json:"category,omitempty"`
+	CategoryID  *uuid.UUID      `json:"category_id,omitempty"`
+}
+
+var weekdays = map[string]time.Weekday{
+	"sunday": time.Sunday, "monday": time.Monday, "tuesday": time.Tuesday, "wednesday": time.Wednesday,
+	"thursday": time.Thursday, "friday": time.Friday, "saturday": time.Saturday,
+}
+
+// parseQuickAmount parses a token such as "12.50", "$12.50" or "1,200"
+func parseQuickAmount(token string) (decimal.Decimal, bool) {
+	amount, err := decimal.NewFromString(strings.TrimLeft(strings.ReplaceAll(token, ",", ""), "$€£"))
+	if err != nil || !amount.IsPositive() {
+		return decimal.Zero, false
+	}
+	return amount.Round(2), true
+}
+
+// parseQuickDate parses "today", "yesterday", a weekday name (its latest
+// occurrence, today included) or a YYYY-MM-DD date
+func parseQuickDate(token string, today time.Time) (time.Time, bool) {
+	switch token = strings.ToLower(token); token {
+	case "today":
+		return today, true
+	case "yesterday":
+		return today.AddDate(0, 0, -1), true
+	}
+	if wd, ok := weekdays[token]; ok {
+		return today.AddDate(0, 0, -((int(today.Weekday()) - int(wd) + 7) % 7)), true
+	}
+	if date, err := time.Parse("2006-01-02", token); err == nil {
+		return date, true
+	}
+	return time.Time{}, false
+}
+
+// parseQuickEntry turns text like "12.50 lunch yesterday #food" into a draft.
+// The amount is the first token with a currency symbol or decimal point, or
+// else the first number; the first date word sets the date (default today);
+// the first #tag names the category. Remaining words form the description.
+func parseQuickEntry(text string, now time.Time) (QuickDraft, error) {
+	draft := QuickDraft{ExpenseDate: time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)}
+	tokens := strings.Fields(text)
+
+	amountAt := -1
+	for i, token := range tokens {
+		if _, ok := parseQuickAmount(token); !ok {
+			continue
+		}
+		if strings.ContainsAny(token, ".$€£") {
+			amountAt = i
+			break
+		}
+		if amountAt < 0 {
+			amountAt = i
+		}
+	}
+	if amountAt < 0 {
+		return draft, errors.New("no amount found")
+	}
+	draft.Amount, _ = parseQuickAmount(tokens[amountAt])
+
+	var words []string
+	dateSet := false
+	for i, token := range tokens {
+		if i == amountAt {
+			continue
+		}
+		if strings.HasPrefix(token, "#") {
+			if name := strings.TrimPrefix(token, "#"); name != "" && draft.Category == nil {
+				draft.Category = &name
+			}
+			continue
+		}
+		if !dateSet {
+			if date, ok := parseQuickDate(token, draft.ExpenseDate); ok {
+				draft.ExpenseDate, dateSet = date, true
+				continue
+			}
+		}
+		words = append(words, token)
+	}
+
+	if len(words) > 0 {
+		description := strings.Join(words, " ")
+		draft.Description = &description
+	}
+	if draft.Category != nil && len(*draft.Category) > 100 {
+		return draft, errors.New("category is longer than 100 characters")
+	}
+	return draft, nil
+}
+
+// QuickEntry parses a one-line expense and returns the draft, or creates the
+// expense when asked to
+func QuickEntry(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req QuickEntryRequest
+	if !validation.Bind(c, &req) {
+		return
+	}
+
+	draft, err := parseQuickEntry(req.Text, time.Now())
+	if err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	// Categories are only matched, never created, so a typo cannot add one
+	if draft.Category != nil {
+		var categoryID uuid.UUID
+		err := db.Pool.QueryRow(c.Request.Context(),
+			`SELECT id FROM expense_categories WHERE user_id = $1 AND LOWER(name) = LOWER($2) AND NOT archived AND deleted_at IS NULL`,
+			userID, *draft.Category).Scan(&categoryID)
+		if err == nil {
+			draft.CategoryID = &categoryID
+		}
+	}
+
+	if !req.Create {
+		c.JSON(200, draft)
+		return
+	}
+
+	currency, err := helpers.GetUserCurrency(c.Request.Context(), db, userID)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to get default currency"})
+		return
+	}
+
+	expense, err := scanExpense(db.Pool.QueryRow(c.Request.Context(),
+		`INSERT INTO personal_expenses (user_id, category_id, amount, currency, description, expense_date, updated_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, NOW())
+		 RETURNING `+expenseColumns,
+		userID, draft.CategoryID, draft.Amount, currency, draft.Description, draft.ExpenseDate))
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to create expense"})
+		return
+	}
+
+	c.JSON(201, ExpenseResponse{
+		PersonalExpense: expense,
+		CategoryLimit:   checkCategoryLimit(c.Request.Context(), db, expense),
+	})
+}
@@ -0,0 +1,60 @@
+package personalexpense
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseQuickEntry(t *testing.T) {
+	// A Thursday
+	now := time.Date(2026, 10, 15, 18, 30, 0, 0, time.UTC)
+	day := func(d int) time.Time { return time.Date(2026, 10, d, 0, 0, 0, 0, time.UTC) }
+
+	tests := []struct {
+		name        string
+		text        string
+		amount      string
+		date        time.Time
+		description string
+		category    string
+		wantErr     bool
+	}{
+		{name: "full", text: "12.50 lunch yesterday #food", amount: "12.5", date: day(14), description: "lunch", category: "food"},
+		{name: "defaults to today", text: "coffee 3", amount: "3", date: day(15), description: "coffee"},
+		{name: "decimal beats plain number", text: "2 coffees 7.50 #Cafe", amount: "7.5", date: day(15), description: "2 coffees", category: "Cafe"},
+		{name: "currency symbol", text: "$1,200 rent 2026-10-01", amount: "1200", date: day(1), description: "rent"},
+		{name: "weekday", text: "taxi 18 monday", amount: "18", date: day(12), description: "taxi"},
+		{name: "weekday is today", text: "taxi 18 thursday", amount: "18", date: day(15), description: "taxi"},
+		{name: "only first date word", text: "9.99 today today", amount: "9.99", date: day(15), description: "today"},
+		{name: "no amount", text: "lunch yesterday", wantErr: true},
+		{name: "zero amount", text: "0 lunch", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			draft, err := parseQuickEntry(tt.text, now)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.amount, draft.Amount.String())
+			assert.Equal(t, tt.date, draft.ExpenseDate)
+			if tt.description == "" {
+				assert.Nil(t, draft.Description)
+			} else {
+				require.NotNil(t, draft.Description)
+				assert.Equal(t, tt.description, *draft.Description)
+			}
+			if tt.category == "" {
+				assert.Nil(t, draft.Category)
+			} else {
+				require.NotNil(t, draft.Category)
+				assert.Equal(t, tt.category, *draft.Category)
+			}
+		})
+	}
+}
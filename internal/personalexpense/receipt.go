@@ -0,0 +1,318 @@
+package personalexpense
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+	"github.com/yanonymousV2/finance-manager-backend/internal/middleware"
+	"github.com/yanonymousV2/finance-manager-backend/internal/ocr"
+	"github.com/yanonymousV2/finance-manager-backend/internal/requestid"
+	"github.com/yanonymousV2/finance-manager-backend/internal/storage"
+)
+
+const (
+	// maxReceiptSize is the largest receipt that can be uploaded
+	maxReceiptSize = 10 << 20
+	// receiptURLTTL is how long a receipt download URL stays valid
+	receiptURLTTL = 15 * time.Minute
+)
+
+// receiptTypes maps the accepted content types to file extensions
+var receiptTypes = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"image/webp":      ".webp",
+	"application/pdf": ".pdf",
+}
+
+type Receipt struct {
+	ID          uuid.UUID `json:"id" db:"id"`
+	ExpenseID   uuid.UUID `json:"expense_id" db:"expense_id"`
+	Filename    string    `json:"filename" db:"filename"`
+	ContentType string    `json:"content_type" db:"content_type"`
+	SizeBytes   int64     `json:"size_bytes" db:"size_bytes"`
+	URL         string    `json:"url"`
+	URLExpires  time.Time `json:"url_expires_at"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	// ExtractionStatus is how reading the receipt with OCR is going, or nil
+	// when it isn't read
+	ExtractionStatus *string `json:"extraction_status,omitempty"`
+	storageKey       string
+}
+
+func (r *Receipt) sign(store storage.Storage) error {
+	url, err := store.SignedURL(r.storageKey, receiptURLTTL)
+	if err != nil {
+		return err
+	}
+	r.URL = url
+	r.URLExpires = time.Now().Add(receiptURLTTL)
+	return nil
+}
+
+// ownExpense checks the expense belongs to the user and reports whether it
+// is mirrored from a group expense
+func ownExpense(ctx context.Context, db *db.DB, expenseID, userID uuid.UUID) (found, mirrored bool) {
+	var groupExpenseID *uuid.UUID
+	err := db.Pool.QueryRow(ctx,
+		`SELECT group_expense_id FROM personal_expenses WHERE id = $1 AND user_id = $2`,
+		expenseID, userID).Scan(&groupExpenseID)
+	if err != nil {
+		return false, false
+	}
+	return true, groupExpenseID != nil
+}
+
+// DeleteReceiptFiles removes stored receipt files. Failures are logged since
+// the rows referencing them are already gone.
+func DeleteReceiptFiles(ctx context.Context, store storage.Storage, keys []string) {
+	for _, key := range keys {
+		if err := store.Delete(ctx, key); err != nil {
+			requestid.Logger(ctx).Error("failed to delete receipt", "key", key, "error", err)
+		}
+	}
+}
+
+// UploadReceipt attaches a receipt image or PDF to a personal expense, and
+// queues it to be read when an OCR provider is configured
+func UploadReceipt(c *gin.Context, db *db.DB, store storage.Storage, provider ocr.Provider) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	expenseID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid expense id"})
+		return
+	}
+
+	found, mirrored := ownExpense(c.Request.Context(), db, expenseID, userID)
+	if !found {
+		c.JSON(404, gin.H{"error": "expense not found"})
+		return
+	}
+	// Mirrored expenses come and go with the group expense
+	if mirrored {
+		c.JSON(400, gin.H{"error": "receipts cannot be attached to mirrored group expenses"})
+		return
+	}
+
+	// Leave room for the multipart envelope around the file
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxReceiptSize+1<<20)
+	header, err := c.FormFile("file")
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			c.JSON(413, gin.H{"error": "receipt must be at most 10 MB"})
+			return
+		}
+		c.JSON(400, gin.H{"error": "missing file"})
+		return
+	}
+	if header.Size > maxReceiptSize {
+		c.JSON(413, gin.H{"error": "receipt must be at most 10 MB"})
+		return
+	}
+	if header.Size == 0 {
+		c.JSON(400, gin.H{"error": "file is empty"})
+		return
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		c.JSON(400, gin.H{"error": "failed to read file"})
+		return
+	}
+	defer file.Close()
+
+	// Trust the content, not the declared type
+	sniff := make([]byte, 512)
+	n, err := io.ReadFull(file, sniff)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		c.JSON(400, gin.H{"error": "failed to read file"})
+		return
+	}
+	contentType := http.DetectContentType(sniff[:n])
+	ext, allowed := receiptTypes[contentType]
+	if !allowed {
+		c.JSON(415, gin.H{"error": "receipt must be a JPEG, PNG, WebP or PDF file"})
+		return
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		c.JSON(500, gin.H{"error": "failed to read file"})
+		return
+	}
+
+	receiptID := uuid.New()
+	key := "receipts/" + userID.String() + "/" + receiptID.String() + ext
+	if err := store.Put(c.Request.Context(), key, file, header.Size, contentType); err != nil {
+		c.JSON(500, gin.H{"error": "failed to store receipt"})
+		return
+	}
+
+	filename := filepath.Base(header.Filename)
+	if len(filename) > 255 {
+		filename = filename[len(filename)-255:]
+	}
+
+	receipt := Receipt{ID: receiptID, ExpenseID: expenseID, Filename: filename, ContentType: contentType,
+		SizeBytes: header.Size, storageKey: key}
+	err = db.Pool.QueryRow(c.Request.Context(),
+		`INSERT INTO receipts (id, expense_id, user_id, storage_key, filename, content_type, size_bytes)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7)
+		 RETURNING created_at`,
+		receiptID, expenseID, userID, key, filename, contentType, header.Size).Scan(&receipt.CreatedAt)
+	if err != nil {
+		// The expense may have been deleted meanwhile
+		DeleteReceiptFiles(c.Request.Context(), store, []string{key})
+		c.JSON(500, gin.H{"error": "failed to save receipt"})
+		return
+	}
+
+	// The receipt is kept even when it can't be queued
+	if provider != nil {
+		if err := ocr.Enqueue(c.Request.Context(), db, receiptID, provider); err != nil {
+			requestid.Logger(c.Request.Context()).Error("failed to queue receipt extraction", "receipt_id", receiptID, "error", err)
+		} else {
+			status := ocr.StatusPending
+			receipt.ExtractionStatus = &status
+		}
+	}
+
+	if err := receipt.sign(store); err != nil {
+		c.JSON(500, gin.H{"error": "failed to sign receipt url"})
+		return
+	}
+
+	c.JSON(201, receipt)
+}
+
+// ListReceipts returns the receipts of a personal expense with download URLs
+func ListReceipts(c *gin.Context, db *db.DB, store storage.Storage) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	expenseID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid expense id"})
+		return
+	}
+
+	if found, _ := ownExpense(c.Request.Context(), db, expenseID, userID); !found {
+		c.JSON(404, gin.H{"error": "expense not found"})
+		return
+	}
+
+	rows, err := db.Pool.Query(c.Request.Context(),
+		`SELECT r.id, r.expense_id, r.filename, r.content_type, r.size_bytes, r.created_at, r.storage_key, x.status
+		 FROM receipts r LEFT JOIN receipt_extractions x ON x.receipt_id = r.id
+		 WHERE r.expense_id = $1
+		 ORDER BY r.created_at, r.id`,
+		expenseID)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to get receipts"})
+		return
+	}
+	defer rows.Close()
+
+	receipts := []Receipt{}
+	for rows.Next() {
+		var r Receipt
+		if err := rows.Scan(&r.ID, &r.ExpenseID, &r.Filename, &r.ContentType, &r.SizeBytes, &r.CreatedAt, &r.storageKey, &r.ExtractionStatus); err != nil {
+			c.JSON(500, gin.H{"error": "failed to scan receipt"})
+			return
+		}
+		if err := r.sign(store); err != nil {
+			c.JSON(500, gin.H{"error": "failed to sign receipt url"})
+			return
+		}
+		receipts = append(receipts, r)
+	}
+
+	c.JSON(200, receipts)
+}
+
+// DownloadReceipt redirects to a short-lived signed URL for a receipt
+func DownloadReceipt(c *gin.Context, db *db.DB, store storage.Storage) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	expenseID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid expense id"})
+		return
+	}
+
+	receiptID, err := uuid.Parse(c.Param("receiptId"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid receipt id"})
+		return
+	}
+
+	var r Receipt
+	err = db.Pool.QueryRow(c.Request.Context(),
+		`SELECT storage_key FROM receipts WHERE id = $1 AND expense_id = $2 AND user_id = $3`,
+		receiptID, expenseID, userID).Scan(&r.storageKey)
+	if err != nil {
+		c.JSON(404, gin.H{"error": "receipt not found"})
+		return
+	}
+
+	if err := r.sign(store); err != nil {
+		c.JSON(500, gin.H{"error": "failed to sign receipt url"})
+		return
+	}
+
+	c.Redirect(http.StatusFound, r.URL)
+}
+
+// DeleteReceipt removes a receipt and its stored file
+func DeleteReceipt(c *gin.Context, db *db.DB, store storage.Storage) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	expenseID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid expense id"})
+		return
+	}
+
+	receiptID, err := uuid.Parse(c.Param("receiptId"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid receipt id"})
+		return
+	}
+
+	var key string
+	err = db.Pool.QueryRow(c.Request.Context(),
+		`DELETE FROM receipts WHERE id = $1 AND expense_id = $2 AND user_id = $3
+		 RETURNING storage_key`,
+		receiptID, expenseID, userID).Scan(&key)
+	if err != nil {
+		c.JSON(404, gin.H{"error": "receipt not found"})
+		return
+	}
+
+	DeleteReceiptFiles(c.Request.Context(), store, []string{key})
+
+	c.JSON(200, gin.H{"message": "receipt deleted successfully"})
+}
@@ -0,0 +1,118 @@
+package personalexpense
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+	"github.com/yanonymousV2/finance-manager-backend/internal/helpers"
+	"github.com/yanonymousV2/finance-manager-backend/internal/middleware"
+)
+
+// SearchResult is a matching expense with its rank and the matched text
+// wrapped in <mark> tags
+type SearchResult struct {
+	PersonalExpense
+	Rank                 float64 `json:"rank"`
+	DescriptionHighlight *string `json:"description_highlight,omitempty"`
+	NotesHighlight       *string `json:"notes_highlight,omitempty"`
+}
+
+// An expense matches when the query matches its words (with stemming) or
+// appears as a substring, which catches partial words like "cabl". Word
+// matches rank above substring-only ones.
+const searchMatchSQL = `e.user_id = $1 AND (
+	    e.search_vector @@ websearch_to_tsquery('english', $2)
+	    OR e.merchant ILIKE $3 OR e.description ILIKE $3 OR e.notes ILIKE $3)`
+
+// SearchExpenses searches the authenticated user's personal expenses by
+// merchant, description and notes, best matches first
+func SearchExpenses(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	q := strings.TrimSpace(c.Query("q"))
+	if q == "" {
+		c.JSON(400, gin.H{"error": "q is required"})
+		return
+	}
+	if len(q) > 200 {
+		c.JSON(400, gin.H{"error": "q must be at most 200 characters"})
+		return
+	}
+
+	limit := 20
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
+			limit = l
+		}
+	}
+
+	offset := 0
+	if offsetStr := c.Query("offset"); offsetStr != "" {
+		if o, err := strconv.Atoi(offsetStr); err == nil && o >= 0 {
+			offset = o
+		}
+	}
+
+	pattern := "%" + helpers.EscapeLike(q) + "%"
+
+	var totalCount int
+	err := db.Pool.QueryRow(c.Request.Context(),
+		`SELECT COUNT(*) FROM personal_expenses e WHERE `+searchMatchSQL,
+		userID, q, pattern).Scan(&totalCount)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to get total count"})
+		return
+	}
+
+	rows, err := db.Pool.Query(c.Request.Context(),
+		`SELECT e.id, e.user_id, e.category_id, e.account_id, e.merchant_id, e.merchant, e.amount, e.currency, e.description, e.notes, e.expense_date,
+		        e.group_expense_id, e.created_at, e.updated_at, e.original_amount, e.original_currency, e.fx_rate,
+		        (ts_rank(e.search_vector, websearch_to_tsquery('english', $2)) +
+		         GREATEST(similarity(COALESCE(e.merchant, ''), $2), similarity(COALESCE(e.description, ''), $2),
+		                  similarity(COALESCE(e.notes, ''), $2)) / 2)::float8 AS rank,
+		        CASE WHEN e.description IS NOT NULL THEN
+		            ts_headline('english', e.description, websearch_to_tsquery('english', $2),
+		                        'StartSel=<mark>, StopSel=</mark>, HighlightAll=true') END,
+		        CASE WHEN e.notes IS NOT NULL THEN
+		            ts_headline('english', e.notes, websearch_to_tsquery('english', $2),
+		                        'StartSel=<mark>, StopSel=</mark>, MaxFragments=2') END
+		 FROM personal_expenses e
+		 WHERE `+searchMatchSQL+`
+		 ORDER BY rank DESC, e.expense_date DESC, e.id
+		 LIMIT $4 OFFSET $5`,
+		userID, q, pattern, limit, offset)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to search expenses"})
+		return
+	}
+	defer rows.Close()
+
+	results := []SearchResult{}
+	for rows.Next() {
+		var r SearchResult
+		if err := rows.Scan(&r.ID, &r.UserID, &r.CategoryID, &r.AccountID, &r.MerchantID, &r.Merchant, &r.Amount, &r.Currency, &r.Description, &r.Notes,
+			&r.ExpenseDate, &r.GroupExpenseID, &r.CreatedAt, &r.UpdatedAt, &r.OriginalAmount, &r.OriginalCurrency, &r.FXRate,
+			&r.Rank, &r.DescriptionHighlight, &r.NotesHighlight); err != nil {
+			c.JSON(500, gin.H{"error": "failed to scan expense"})
+			return
+		}
+		results = append(results, r)
+	}
+
+	c.JSON(200, gin.H{
+		"query":   q,
+		"results": results,
+		"pagination": gin.H{
+			"limit":  limit,
+			"offset": offset,
+			"total":  totalCount,
+		},
+	})
+}
@@ -0,0 +1,31 @@
+package personalexpense
+
+import (
+	"errors"
+	"strings"
+)
+
+// sortColumns are the columns the sort query parameter can order by
+var sortColumns = map[string]bool{
+	"amount":       true,
+	"expense_date": true,
+	"created_at":   true,
+}
+
+// parseSort checks the sort and order query parameters and returns the
+// column to order by and whether the order is descending. Ties are broken
+// by creation time and id in ListPersonalExpenses, so pages stay stable.
+func parseSort(sort, order string) (column string, descending bool, err error) {
+	if !sortColumns[sort] {
+		return "", false, errors.New("sort must be one of amount, expense_date, created_at")
+	}
+
+	switch strings.ToLower(order) {
+	case "asc":
+		return sort, false, nil
+	case "desc":
+		return sort, true, nil
+	default:
+		return "", false, errors.New("order must be asc or desc")
+	}
+}
@@ -0,0 +1,37 @@
+package personalexpense
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseSort(t *testing.T) {
+	tests := []struct {
+		sort           string
+		order          string
+		wantColumn     string
+		wantDescending bool
+		wantErr        bool
+	}{
+		{sort: "expense_date", order: "desc", wantColumn: "expense_date", wantDescending: true},
+		{sort: "amount", order: "ASC", wantColumn: "amount"},
+		{sort: "created_at", order: "asc", wantColumn: "created_at"},
+		{sort: "description", order: "asc", wantErr: true},
+		{sort: "amount; DROP TABLE users", order: "asc", wantErr: true},
+		{sort: "amount", order: "sideways", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.sort+" "+tt.order, func(t *testing.T) {
+			column, descending, err := parseSort(tt.sort, tt.order)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.wantColumn, column)
+			assert.Equal(t, tt.wantDescending, descending)
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package personalexpense
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+	"github.com/yanonymousV2/finance-manager-backend/internal/db/sqlc"
+	"github.com/yanonymousV2/finance-manager-backend/internal/helpers"
+	"github.com/yanonymousV2/finance-manager-backend/internal/middleware"
+)
+
+// StatsBucket is the spending within one period
+type StatsBucket struct {
+	PeriodStart  time.Time       `json:"period_start"`
+	TotalAmount  decimal.Decimal `json:"total_amount"`
+	ExpenseCount int             `json:"expense_count"`
+}
+
+var validStatsGroupings = map[string]bool{
+	"day":   true,
+	"week":  true,
+	"month": true,
+}
+
+// GetExpenseStats returns the authenticated user's spending summed per day,
+// week or month. Only periods with expenses are returned.
+func GetExpenseStats(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	groupBy := c.DefaultQuery("group_by", "month")
+	if !validStatsGroupings[groupBy] {
+		c.JSON(400, gin.H{"error": "group_by must be one of day, week, month"})
+		return
+	}
+
+	// Totals only add up expenses in the user's default currency, like the
+	// monthly dashboard
+	currency, err := helpers.GetUserCurrency(c.Request.Context(), db, userID)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to get default currency"})
+		return
+	}
+
+	params := sqlc.PersonalExpenseStatsParams{UserID: userID, GroupBy: groupBy, Currency: currency}
+	if startDateStr := c.Query("start_date"); startDateStr != "" {
+		startDate, err := time.Parse("2006-01-02", startDateStr)
+		if err != nil {
+			c.JSON(400, gin.H{"error": "invalid start_date, expected YYYY-MM-DD"})
+			return
+		}
+		params.StartDate = &startDate
+	}
+
+	if endDateStr := c.Query("end_date"); endDateStr != "" {
+		endDate, err := time.Parse("2006-01-02", endDateStr)
+		if err != nil {
+			c.JSON(400, gin.H{"error": "invalid end_date, expected YYYY-MM-DD"})
+			return
+		}
+		endDate = endDate.Add(24 * time.Hour)
+		params.EndDate = &endDate
+	}
+
+	rows, err := sqlc.New(db.Pool).PersonalExpenseStats(c.Request.Context(), params)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to get expense stats"})
+		return
+	}
+
+	buckets := make([]StatsBucket, len(rows))
+	total := decimal.Zero
+	count := 0
+	for i, row := range rows {
+		buckets[i] = StatsBucket{PeriodStart: row.Bucket, TotalAmount: row.TotalAmount, ExpenseCount: int(row.ExpenseCount)}
+		total = total.Add(row.TotalAmount)
+		count += buckets[i].ExpenseCount
+	}
+
+	c.JSON(200, gin.H{
+		"group_by":      groupBy,
+		"currency":      currency,
+		"total_amount":  total,
+		"expense_count": count,
+		"buckets":       buckets,
+	})
+}
@@ -0,0 +1,191 @@
+package personalexpense
+
+import (
+	"context"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+	"github.com/yanonymousV2/finance-manager-backend/internal/helpers"
+	"github.com/yanonymousV2/finance-manager-backend/internal/middleware"
+	"github.com/yanonymousV2/finance-manager-backend/internal/validation"
+)
+
+// A share is mirrored while the expense is live and approved and the member
+// has opted in for its group. Mirrors keep the group's currency.
+const (
+	removeStaleMirrorsSQL = `DELETE FROM personal_expenses pe
+		 WHERE pe.group_expense_id = $1
+		   AND NOT EXISTS (
+		       SELECT 1 FROM expenses e
+		       JOIN expense_splits es ON es.expense_id = e.id
+		       JOIN group_members gm ON gm.group_id = e.group_id AND gm.user_id = es.user_id
+		       WHERE e.id = $1 AND es.user_id = pe.user_id AND es.amount > 0
+		         AND e.deleted_at IS NULL AND e.status = 'approved' AND gm.mirror_to_personal)`
+	upsertMirrorsSQL = `INSERT INTO personal_expenses (user_id, category_id, amount, currency, description, expense_date, group_expense_id, updated_at)
+		 SELECT es.user_id, gm.mirror_category_id, es.amount, e.currency, e.description, e.expense_date, e.id, NOW()
+		 FROM expenses e
+		 JOIN expense_splits es ON es.expense_id = e.id
+		 JOIN group_members gm ON gm.group_id = e.group_id AND gm.user_id = es.user_id
+		 WHERE e.id = $1 AND es.amount > 0
+		   AND e.deleted_at IS NULL AND e.status = 'approved' AND gm.mirror_to_personal
+		 ON CONFLICT (user_id, group_expense_id)
+		 DO UPDATE SET amount = EXCLUDED.amount, currency = EXCLUDED.currency, description = EXCLUDED.description,
+		               expense_date = EXCLUDED.expense_date, updated_at = NOW()`
+)
+
+// QueueGroupExpenseSync queues the statements bringing the mirrored personal
+// expenses of a group expense up to date on batch
+func QueueGroupExpenseSync(batch *pgx.Batch, expenseID uuid.UUID) {
+	batch.Queue(removeStaleMirrorsSQL, expenseID)
+	batch.Queue(upsertMirrorsSQL, expenseID)
+}
+
+// SyncGroupExpense brings the mirrored personal expenses of a group expense
+// up to date inside tx. Call it after any change to the expense.
+func SyncGroupExpense(ctx context.Context, tx pgx.Tx, expenseID uuid.UUID) error {
+	batch := &pgx.Batch{}
+	QueueGroupExpenseSync(batch, expenseID)
+	return tx.SendBatch(ctx, batch).Close()
+}
+
+// SyncMemberExpenses resyncs every expense of a group the user has a share
+// in inside tx
+func SyncMemberExpenses(ctx context.Context, tx pgx.Tx, groupID, userID uuid.UUID) error {
+	rows, err := tx.Query(ctx,
+		`SELECT e.id FROM expenses e JOIN expense_splits es ON es.expense_id = e.id
+		 WHERE e.group_id = $1 AND es.user_id = $2`,
+		groupID, userID)
+	if err != nil {
+		return err
+	}
+	expenseIDs, err := pgx.CollectRows(rows, pgx.RowTo[uuid.UUID])
+	if err != nil {
+		return err
+	}
+
+	batch := &pgx.Batch{}
+	for _, id := range expenseIDs {
+		QueueGroupExpenseSync(batch, id)
+	}
+	return tx.SendBatch(ctx, batch).Close()
+}
+
+type GroupSyncSettings struct {
+	GroupID    uuid.UUID  `json:"group_id"`
+	Enabled    bool       `json:"enabled"`
+	CategoryID *uuid.UUID `json:"category_id,omitempty"`
+}
+
+type UpdateGroupSyncRequest struct {
+	Enabled    bool       `json:"enabled"`
+	CategoryID *uuid.UUID `json:"category_id,omitempty"`
+}
+
+// GetGroupSync returns whether the user mirrors their shares in a group
+func GetGroupSync(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	groupID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid group id"})
+		return
+	}
+
+	settings := GroupSyncSettings{GroupID: groupID}
+	err = db.Pool.QueryRow(c.Request.Context(),
+		"SELECT mirror_to_personal, mirror_category_id FROM group_members WHERE group_id = $1 AND user_id = $2",
+		groupID, userID).Scan(&settings.Enabled, &settings.CategoryID)
+	if err != nil {
+		c.JSON(403, gin.H{"error": "not a member of the group"})
+		return
+	}
+
+	c.JSON(200, settings)
+}
+
+// UpdateGroupSync turns mirroring of the user's shares in a group on or off.
+// Enabling copies existing shares over; disabling removes mirrored expenses.
+// A new category applies to existing mirrors still in the previous one.
+func UpdateGroupSync(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	groupID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid group id"})
+		return
+	}
+
+	isMember, err := helpers.IsGroupMember(c.Request.Context(), db, groupID, userID)
+	if err != nil || !isMember {
+		c.JSON(403, gin.H{"error": "not a member of the group"})
+		return
+	}
+
+	var req UpdateGroupSyncRequest
+	if !validation.Bind(c, &req) {
+		return
+	}
+
+	if req.CategoryID != nil {
+		ownerID, _, err := helpers.GetCategoryOwner(c.Request.Context(), db.Pool, *req.CategoryID)
+		if err != nil {
+			c.JSON(400, gin.H{"error": "invalid category"})
+			return
+		}
+		if ownerID != userID {
+			c.JSON(403, gin.H{"error": "category does not belong to user"})
+			return
+		}
+	}
+
+	err = db.WithTx(c.Request.Context(), func(tx pgx.Tx) error {
+		var previous *uuid.UUID
+		err := tx.QueryRow(c.Request.Context(),
+			"SELECT mirror_category_id FROM group_members WHERE group_id = $1 AND user_id = $2 FOR UPDATE",
+			groupID, userID).Scan(&previous)
+		if err != nil {
+			return helpers.NewRequestError(500, "failed to update sync settings")
+		}
+
+		_, err = tx.Exec(c.Request.Context(),
+			"UPDATE group_members SET mirror_to_personal = $1, mirror_category_id = $2 WHERE group_id = $3 AND user_id = $4",
+			req.Enabled, req.CategoryID, groupID, userID)
+		if err != nil {
+			return helpers.NewRequestError(500, "failed to update sync settings")
+		}
+
+		// Mirrors still in the previous mirror category move to the new one;
+		// ones the user recategorized keep their category
+		_, err = tx.Exec(c.Request.Context(),
+			`UPDATE personal_expenses pe SET category_id = $1, updated_at = NOW()
+			 FROM expenses e
+			 WHERE pe.group_expense_id = e.id AND e.group_id = $2 AND pe.user_id = $3
+			   AND pe.category_id IS NOT DISTINCT FROM $4 AND $4 IS DISTINCT FROM $1`,
+			req.CategoryID, groupID, userID, previous)
+		if err != nil {
+			return helpers.NewRequestError(500, "failed to update sync settings")
+		}
+
+		if err := SyncMemberExpenses(c.Request.Context(), tx, groupID, userID); err != nil {
+			return helpers.NewRequestError(500, "failed to sync expenses")
+		}
+		return nil
+	})
+	if err != nil {
+		helpers.RespondError(c, err, "failed to update sync settings")
+		return
+	}
+
+	c.JSON(200, GroupSyncSettings{GroupID: groupID, Enabled: req.Enabled, CategoryID: req.CategoryID})
+}
@@ -0,0 +1,267 @@
+// Package plaid connects users' bank accounts through Plaid and turns their
+// card and bank transactions into personal expenses. Users link a bank in
+// Plaid Link with a link token from the API; the items and accounts they
+// link are stored, and a background job syncs new transactions whenever
+// Plaid's webhook says there are some.
+package plaid
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/config"
+	"github.com/yanonymousV2/finance-manager-backend/internal/secret"
+)
+
+var envURLs = map[string]string{
+	"sandbox":    "https://sandbox.plaid.com",
+	"production": "https://production.plaid.com",
+}
+
+// Error is an error response from the Plaid API
+type Error struct {
+	Type    string `json:"error_type"`
+	Code    string `json:"error_code"`
+	Message string `json:"error_message"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("plaid: %s (%s)", e.Message, e.Code)
+}
+
+// errorCode returns the Plaid error code of err, or "" for other errors
+func errorCode(err error) string {
+	var plaidErr *Error
+	if errors.As(err, &plaidErr) {
+		return plaidErr.Code
+	}
+	return ""
+}
+
+// Client calls the Plaid API. Items' access tokens are stored sealed by box
+// and only opened for the call that needs one.
+type Client struct {
+	url          string
+	clientID     string
+	secret       string
+	countryCodes []string
+	// webhookURL is where Plaid sends an item's webhooks
+	webhookURL string
+	client     *http.Client
+	box        *secret.Box
+
+	// keys caches the keys webhooks are signed with, by key ID
+	mu   sync.Mutex
+	keys map[string]*ecdsa.PublicKey
+}
+
+func NewClient(clientID, secret, env string, countryCodes []string, webhookURL string, box *secret.Box) *Client {
+	return &Client{
+		url:          envURLs[env],
+		clientID:     clientID,
+		secret:       secret,
+		countryCodes: countryCodes,
+		webhookURL:   webhookURL,
+		client:       &http.Client{Timeout: 30 * time.Second},
+		box:          box,
+		keys:         make(map[string]*ecdsa.PublicKey),
+	}
+}
+
+// New returns a client for the keys in cfg, or nil when Plaid isn't set up
+func New(cfg *config.Config) *Client {
+	if cfg.PlaidClientID == "" {
+		return nil
+	}
+	box, err := secret.New(cfg.TokenEncryptionKey)
+	if err != nil {
+		panic(err) // config.Load checked the key
+	}
+	return NewClient(cfg.PlaidClientID, cfg.PlaidSecret, cfg.PlaidEnv, cfg.PlaidCountryCodes, cfg.PublicURL+"/webhooks/plaid", box)
+}
+
+// open returns the access token sealed in accessToken. Tokens stored before
+// they were encrypted are used as they are until sealLegacyTokens seals them.
+func (c *Client) open(accessToken []byte) (string, error) {
+	if !secret.IsSealed(accessToken) {
+		return string(accessToken), nil
+	}
+	return c.box.Open(accessToken)
+}
+
+// call posts req to a Plaid endpoint and decodes the response into resp
+func (c *Client) call(ctx context.Context, path string, req, resp any) error {
+	body, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.url+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("PLAID-CLIENT-ID", c.clientID)
+	httpReq.Header.Set("PLAID-SECRET", c.secret)
+
+	httpResp, err := c.client.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer httpResp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(httpResp.Body, 10<<20))
+	if err != nil {
+		return err
+	}
+	if httpResp.StatusCode != http.StatusOK {
+		plaidErr := &Error{}
+		if err := json.Unmarshal(data, plaidErr); err != nil || plaidErr.Code == "" {
+			return fmt.Errorf("plaid returned %s", httpResp.Status)
+		}
+		return plaidErr
+	}
+	return json.Unmarshal(data, resp)
+}
+
+// LinkToken starts Plaid Link in the app
+type LinkToken struct {
+	LinkToken  string    `json:"link_token"`
+	Expiration time.Time `json:"expiration"`
+}
+
+// createLinkToken returns a link token for linking a new item, or with an
+// item's sealed accessToken, for logging in to it again
+func (c *Client) createLinkToken(ctx context.Context, clientUserID string, accessToken []byte) (LinkToken, error) {
+	req := map[string]any{
+		"client_name":   "Finance Manager",
+		"language":      "en",
+		"country_codes": c.countryCodes,
+		"user":          map[string]string{"client_user_id": clientUserID},
+		"webhook":       c.webhookURL,
+	}
+	if accessToken != nil {
+		token, err := c.open(accessToken)
+		if err != nil {
+			return LinkToken{}, err
+		}
+		req["access_token"] = token
+	} else {
+		req["products"] = []string{"transactions"}
+	}
+	var resp LinkToken
+	err := c.call(ctx, "/link/token/create", req, &resp)
+	return resp, err
+}
+
+// exchangePublicToken turns the public token Link gives the app into an
+// item's access token, sealed for storing
+func (c *Client) exchangePublicToken(ctx context.Context, publicToken string) (accessToken []byte, itemID string, err error) {
+	var resp struct {
+		AccessToken string `json:"access_token"`
+		ItemID      string `json:"item_id"`
+	}
+	if err := c.call(ctx, "/item/public_token/exchange", map[string]string{"public_token": publicToken}, &resp); err != nil {
+		return nil, "", err
+	}
+	return c.box.Seal(resp.AccessToken), resp.ItemID, nil
+}
+
+// plaidAccount is a bank account of an item
+type plaidAccount struct {
+	AccountID string  `json:"account_id"`
+	Name      string  `json:"name"`
+	Mask      *string `json:"mask"`
+	Type      string  `json:"type"`
+	Subtype   *string `json:"subtype"`
+	Balances  struct {
+		ISOCurrencyCode *string `json:"iso_currency_code"`
+	} `json:"balances"`
+}
+
+func (c *Client) accounts(ctx context.Context, accessToken []byte) ([]plaidAccount, error) {
+	token, err := c.open(accessToken)
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		Accounts []plaidAccount `json:"accounts"`
+	}
+	err = c.call(ctx, "/accounts/get", map[string]string{"access_token": token}, &resp)
+	return resp.Accounts, err
+}
+
+// transaction is a Plaid transaction. A positive amount is money leaving
+// the account.
+type transaction struct {
+	TransactionID   string          `json:"transaction_id"`
+	AccountID       string          `json:"account_id"`
+	Amount          decimal.Decimal `json:"amount"`
+	ISOCurrencyCode *string         `json:"iso_currency_code"`
+	Date            string          `json:"date"`
+	Name            string          `json:"name"`
+	MerchantName    *string         `json:"merchant_name"`
+	Pending         bool            `json:"pending"`
+	Category        *struct {
+		Primary  string `json:"primary"`
+		Detailed string `json:"detailed"`
+	} `json:"personal_finance_category"`
+}
+
+// syncPage is one page of changes since a cursor
+type syncPage struct {
+	Added    []transaction `json:"added"`
+	Modified []transaction `json:"modified"`
+	Removed  []struct {
+		TransactionID string `json:"transaction_id"`
+	} `json:"removed"`
+	NextCursor string `json:"next_cursor"`
+	HasMore    bool   `json:"has_more"`
+}
+
+func (c *Client) syncTransactions(ctx context.Context, accessToken []byte, cursor string) (syncPage, error) {
+	token, err := c.open(accessToken)
+	if err != nil {
+		return syncPage{}, err
+	}
+	req := map[string]any{"access_token": token, "count": 500}
+	if cursor != "" {
+		req["cursor"] = cursor
+	}
+	var resp syncPage
+	err = c.call(ctx, "/transactions/sync", req, &resp)
+	return resp, err
+}
+
+func (c *Client) removeItem(ctx context.Context, accessToken []byte) error {
+	token, err := c.open(accessToken)
+	if err != nil {
+		return err
+	}
+	return c.call(ctx, "/item/remove", map[string]string{"access_token": token}, &struct{}{})
+}
+
+// verificationKey is the public key a webhook was signed with, as a JWK
+type verificationKey struct {
+	Kty       string `json:"kty"`
+	Crv       string `json:"crv"`
+	X         string `json:"x"`
+	Y         string `json:"y"`
+	ExpiredAt *int64 `json:"expired_at"`
+}
+
+func (c *Client) verificationKey(ctx context.Context, keyID string) (verificationKey, error) {
+	var resp struct {
+		Key verificationKey `json:"key"`
+	}
+	err := c.call(ctx, "/webhook_verification_key/get", map[string]string{"key_id": keyID}, &resp)
+	return resp.Key, err
+}
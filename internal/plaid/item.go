@@ -0,0 +1,322 @@
+package plaid
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+	"github.com/yanonymousV2/finance-manager-backend/internal/helpers"
+	"github.com/yanonymousV2/finance-manager-backend/internal/middleware"
+	"github.com/yanonymousV2/finance-manager-backend/internal/validation"
+)
+
+// Item statuses. Items that aren't active aren't synced until the user
+// logs in again through Link, or links them again.
+const (
+	StatusActive        = "active"
+	StatusLoginRequired = "login_required"
+	StatusRevoked       = "revoked"
+	StatusError         = "error"
+)
+
+// Item is a linked login at a bank
+type Item struct {
+	ID              uuid.UUID  `json:"id"`
+	InstitutionName *string    `json:"institution_name,omitempty"`
+	Status          string     `json:"status"`
+	ErrorCode       *string    `json:"error_code,omitempty"`
+	LastSyncedAt    *time.Time `json:"last_synced_at,omitempty"`
+	CreatedAt       time.Time  `json:"created_at"`
+	Accounts        []Account  `json:"accounts"`
+}
+
+// Account is a bank account of an item. AccountID is the account its
+// transactions are recorded against.
+type Account struct {
+	ID        uuid.UUID  `json:"id"`
+	AccountID *uuid.UUID `json:"account_id,omitempty"`
+	Name      string     `json:"name"`
+	Mask      *string    `json:"mask,omitempty"`
+	Type      string     `json:"type"`
+	Subtype   *string    `json:"subtype,omitempty"`
+}
+
+// LinkItemRequest completes linking with the public token Link gave the app
+type LinkItemRequest struct {
+	PublicToken     string  `json:"public_token" validate:"required,max=200"`
+	InstitutionName *string `json:"institution_name,omitempty" validate:"omitempty,max=100"`
+}
+
+// unavailable responds when Plaid isn't set up
+func unavailable(c *gin.Context) {
+	c.JSON(503, gin.H{"error": "bank connections are unavailable"})
+}
+
+// CreateLinkToken returns a link token for linking a bank in Plaid Link
+func CreateLinkToken(c *gin.Context, client *Client) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+	if client == nil {
+		unavailable(c)
+		return
+	}
+
+	token, err := client.createLinkToken(c.Request.Context(), userID.String(), nil)
+	if err != nil {
+		slog.Error("failed to create Plaid link token", "error", err)
+		c.JSON(502, gin.H{"error": "failed to create link token"})
+		return
+	}
+	c.JSON(201, token)
+}
+
+// CreateRelinkToken returns a link token for logging in to an item again,
+// when its status is login_required
+func CreateRelinkToken(c *gin.Context, db *db.DB, client *Client) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+	if client == nil {
+		unavailable(c)
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid item id"})
+		return
+	}
+	accessToken, err := itemAccessToken(c.Request.Context(), db, id, userID)
+	if err != nil {
+		c.JSON(404, gin.H{"error": "item not found"})
+		return
+	}
+
+	token, err := client.createLinkToken(c.Request.Context(), userID.String(), accessToken)
+	if err != nil {
+		slog.Error("failed to create Plaid link token", "error", err)
+		c.JSON(502, gin.H{"error": "failed to create link token"})
+		return
+	}
+	c.JSON(201, token)
+}
+
+// itemAccessToken returns the sealed access token of one of the user's items
+func itemAccessToken(ctx context.Context, db *db.DB, id, userID uuid.UUID) ([]byte, error) {
+	var token []byte
+	err := db.Pool.QueryRow(ctx,
+		"SELECT access_token FROM plaid_items WHERE id = $1 AND user_id = $2", id, userID).Scan(&token)
+	return token, err
+}
+
+// accountType maps a Plaid account type to an account's
+func accountType(plaidType string) string {
+	if plaidType == "credit" {
+		return "card"
+	}
+	return "bank"
+}
+
+// accountName names the account a bank account's transactions are
+// recorded against, e.g. "Chase Checking ••1234"
+func accountName(institution *string, a plaidAccount) string {
+	name := a.Name
+	if institution != nil && *institution != "" {
+		name = *institution + " " + name
+	}
+	if a.Mask != nil && *a.Mask != "" {
+		name += " ••" + *a.Mask
+	}
+	if runes := []rune(name); len(runes) > 100 {
+		name = string(runes[:100])
+	}
+	return name
+}
+
+// LinkItem stores the item Link connected, with an account for each of its
+// bank accounts, and queues its first sync
+func LinkItem(c *gin.Context, db *db.DB, client *Client) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+	if client == nil {
+		unavailable(c)
+		return
+	}
+
+	var req LinkItemRequest
+	if !validation.Bind(c, &req) {
+		return
+	}
+
+	ctx := c.Request.Context()
+	accessToken, plaidItemID, err := client.exchangePublicToken(ctx, req.PublicToken)
+	if errorCode(err) == "INVALID_PUBLIC_TOKEN" {
+		c.JSON(400, gin.H{"error": "invalid public token"})
+		return
+	}
+	if err != nil {
+		slog.Error("failed to exchange Plaid public token", "error", err)
+		c.JSON(502, gin.H{"error": "failed to link item"})
+		return
+	}
+	plaidAccounts, err := client.accounts(ctx, accessToken)
+	if err != nil {
+		slog.Error("failed to get Plaid accounts", "error", err)
+		c.JSON(502, gin.H{"error": "failed to link item"})
+		return
+	}
+
+	var item Item
+	err = db.WithTx(ctx, func(tx pgx.Tx) error {
+		err := tx.QueryRow(ctx,
+			`INSERT INTO plaid_items (user_id, item_id, access_token, institution_name, sync_requested_at)
+			 VALUES ($1, $2, $3, $4, NOW())
+			 RETURNING id, institution_name, status, error_code, last_synced_at, created_at`,
+			userID, plaidItemID, accessToken, req.InstitutionName).
+			Scan(&item.ID, &item.InstitutionName, &item.Status, &item.ErrorCode, &item.LastSyncedAt, &item.CreatedAt)
+		if err != nil {
+			return helpers.NewRequestError(500, "failed to link item")
+		}
+
+		item.Accounts = []Account{}
+		for _, pa := range plaidAccounts {
+			// An account of the same name is reused, e.g. after linking
+			// the bank again
+			var accountID uuid.UUID
+			err := tx.QueryRow(ctx,
+				`INSERT INTO accounts (user_id, name, type, currency)
+				 VALUES ($1, $2, $3, COALESCE($4, (SELECT default_currency FROM users WHERE id = $1)))
+				 ON CONFLICT (user_id, name) DO UPDATE SET name = EXCLUDED.name
+				 RETURNING id`,
+				userID, accountName(req.InstitutionName, pa), accountType(pa.Type), pa.Balances.ISOCurrencyCode).Scan(&accountID)
+			if err != nil {
+				return helpers.NewRequestError(500, "failed to create account")
+			}
+
+			a := Account{AccountID: &accountID, Name: pa.Name, Mask: pa.Mask, Type: pa.Type, Subtype: pa.Subtype}
+			err = tx.QueryRow(ctx,
+				`INSERT INTO plaid_accounts (item_id, plaid_account_id, account_id, name, mask, type, subtype)
+				 VALUES ($1, $2, $3, $4, $5, $6, $7)
+				 ON CONFLICT (plaid_account_id) DO UPDATE SET item_id = EXCLUDED.item_id, account_id = EXCLUDED.account_id
+				 RETURNING id`,
+				item.ID, pa.AccountID, accountID, pa.Name, pa.Mask, pa.Type, pa.Subtype).Scan(&a.ID)
+			if err != nil {
+				return helpers.NewRequestError(500, "failed to link account")
+			}
+			item.Accounts = append(item.Accounts, a)
+		}
+		return nil
+	})
+	if err != nil {
+		helpers.RespondError(c, err, "failed to link item")
+		return
+	}
+
+	c.JSON(201, item)
+}
+
+// ListItems returns the user's linked items with their accounts
+func ListItems(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	rows, err := db.Pool.Query(ctx,
+		`SELECT id, institution_name, status, error_code, last_synced_at, created_at
+		 FROM plaid_items WHERE user_id = $1 ORDER BY created_at, id`, userID)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to retrieve items"})
+		return
+	}
+	items := []Item{}
+	byID := map[uuid.UUID]int{}
+	for rows.Next() {
+		item := Item{Accounts: []Account{}}
+		if err := rows.Scan(&item.ID, &item.InstitutionName, &item.Status, &item.ErrorCode, &item.LastSyncedAt, &item.CreatedAt); err != nil {
+			rows.Close()
+			c.JSON(500, gin.H{"error": "failed to scan item"})
+			return
+		}
+		byID[item.ID] = len(items)
+		items = append(items, item)
+	}
+	rows.Close()
+
+	rows, err = db.Pool.Query(ctx,
+		`SELECT pa.item_id, pa.id, pa.account_id, pa.name, pa.mask, pa.type, pa.subtype
+		 FROM plaid_accounts pa JOIN plaid_items pi ON pi.id = pa.item_id
+		 WHERE pi.user_id = $1 ORDER BY pa.name, pa.id`, userID)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to retrieve accounts"})
+		return
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var itemID uuid.UUID
+		var a Account
+		if err := rows.Scan(&itemID, &a.ID, &a.AccountID, &a.Name, &a.Mask, &a.Type, &a.Subtype); err != nil {
+			c.JSON(500, gin.H{"error": "failed to scan account"})
+			return
+		}
+		if i, ok := byID[itemID]; ok {
+			items[i].Accounts = append(items[i].Accounts, a)
+		}
+	}
+
+	c.JSON(200, items)
+}
+
+// DeleteItem unlinks an item at Plaid and stops syncing it. Expenses and
+// accounts already created from it are kept.
+func DeleteItem(c *gin.Context, db *db.DB, client *Client) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+	if client == nil {
+		unavailable(c)
+		return
+	}
+
+	id, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid item id"})
+		return
+	}
+	token, err := itemAccessToken(c.Request.Context(), db, id, userID)
+	if err != nil {
+		c.JSON(404, gin.H{"error": "item not found"})
+		return
+	}
+
+	// An item Plaid no longer knows is already unlinked
+	err = client.removeItem(c.Request.Context(), token)
+	if code := errorCode(err); err != nil && code != "ITEM_NOT_FOUND" && code != "INVALID_ACCESS_TOKEN" {
+		slog.Error("failed to remove Plaid item", "item_id", id, "error", err)
+		c.JSON(502, gin.H{"error": "failed to unlink item"})
+		return
+	}
+
+	if _, err := db.Pool.Exec(c.Request.Context(), "DELETE FROM plaid_items WHERE id = $1", id); err != nil {
+		c.JSON(500, gin.H{"error": "failed to delete item"})
+		return
+	}
+	c.JSON(200, gin.H{"message": "item deleted successfully"})
+}
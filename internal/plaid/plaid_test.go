@@ -0,0 +1,168 @@
+package plaid
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/secret"
+)
+
+func testBox(t *testing.T) *secret.Box {
+	box, err := secret.New(make([]byte, secret.KeySize))
+	require.NoError(t, err)
+	return box
+}
+
+func TestTransaction(t *testing.T) {
+	var tx transaction
+	require.NoError(t, json.Unmarshal([]byte(`{
+		"transaction_id": "tx-1",
+		"account_id": "acc-1",
+		"amount": 12.5,
+		"iso_currency_code": "USD",
+		"date": "2024-03-01",
+		"name": "SQ *BLUE BOTTLE COFFEE",
+		"merchant_name": "Blue Bottle Coffee",
+		"pending": false,
+		"personal_finance_category": {"primary": "FOOD_AND_DRINK", "detailed": "FOOD_AND_DRINK_COFFEE"}
+	}`), &tx))
+	assert.True(t, tx.Amount.Equal(decimal.RequireFromString("12.5")))
+	assert.True(t, tx.isSpending())
+	assert.Equal(t, "Food", tx.categoryName())
+	assert.Equal(t, "Blue Bottle Coffee", tx.payee())
+	assert.Equal(t, "SQ *BLUE BOTTLE COFFEE", *tx.description())
+
+	tx.Category.Detailed = "FOOD_AND_DRINK_GROCERIES"
+	assert.Equal(t, "Groceries", tx.categoryName())
+	tx.Category = nil
+	assert.Equal(t, "", tx.categoryName())
+	tx.MerchantName = nil
+	assert.Equal(t, "SQ *BLUE BOTTLE COFFEE", tx.payee())
+
+	pending := tx
+	pending.Pending = true
+	assert.False(t, pending.isSpending())
+	refund := tx
+	refund.Amount = decimal.RequireFromString("-12.5")
+	assert.False(t, refund.isSpending())
+	cardPayment := tx
+	cardPayment.Category = &struct {
+		Primary  string `json:"primary"`
+		Detailed string `json:"detailed"`
+	}{Primary: "LOAN_PAYMENTS", Detailed: "LOAN_PAYMENTS_CREDIT_CARD_PAYMENT"}
+	assert.False(t, cardPayment.isSpending())
+}
+
+func TestAccountName(t *testing.T) {
+	institution, mask := "Chase", "1234"
+	assert.Equal(t, "Chase Checking ••1234", accountName(&institution, plaidAccount{Name: "Checking", Mask: &mask}))
+	assert.Equal(t, "Checking", accountName(nil, plaidAccount{Name: "Checking"}))
+	assert.Equal(t, "card", accountType("credit"))
+	assert.Equal(t, "bank", accountType("depository"))
+}
+
+func TestClientError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "client", r.Header.Get("PLAID-CLIENT-ID"))
+		assert.Equal(t, "secret", r.Header.Get("PLAID-SECRET"))
+		var req map[string]any
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+		assert.Equal(t, "access-sandbox-1", req["access_token"], "the sealed token is opened for the call")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error_type":"ITEM_ERROR","error_code":"ITEM_LOGIN_REQUIRED","error_message":"the login details of this item have changed"}`))
+	}))
+	defer srv.Close()
+
+	box := testBox(t)
+	client := NewClient("client", "secret", "sandbox", []string{"US"}, "", box)
+	client.url = srv.URL
+	_, err := client.syncTransactions(t.Context(), box.Seal("access-sandbox-1"), "")
+	assert.Equal(t, "ITEM_LOGIN_REQUIRED", errorCode(err))
+	assert.EqualError(t, err, "plaid: the login details of this item have changed (ITEM_LOGIN_REQUIRED)")
+}
+
+func TestVerifyWebhook(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	keyRequests := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/webhook_verification_key/get", r.URL.Path)
+		keyRequests++
+		json.NewEncoder(w).Encode(map[string]any{"key": map[string]any{
+			"kty": "EC", "crv": "P-256", "alg": "ES256", "kid": "key-1",
+			"x":          base64.RawURLEncoding.EncodeToString(key.X.FillBytes(make([]byte, 32))),
+			"y":          base64.RawURLEncoding.EncodeToString(key.Y.FillBytes(make([]byte, 32))),
+			"expired_at": nil,
+		}})
+	}))
+	defer srv.Close()
+
+	client := NewClient("client", "secret", "sandbox", []string{"US"}, "", testBox(t))
+	client.url = srv.URL
+
+	now := time.Now()
+	body := []byte(`{"webhook_type":"TRANSACTIONS","webhook_code":"SYNC_UPDATES_AVAILABLE","item_id":"item-1"}`)
+	sign := func(body []byte, issued time.Time) string {
+		sum := sha256.Sum256(body)
+		token := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+			"iat":                 issued.Unix(),
+			"request_body_sha256": hex.EncodeToString(sum[:]),
+		})
+		token.Header["kid"] = "key-1"
+		signed, err := token.SignedString(key)
+		require.NoError(t, err)
+		return signed
+	}
+
+	assert.NoError(t, client.verifyWebhook(t.Context(), sign(body, now), body, now))
+	assert.Error(t, client.verifyWebhook(t.Context(), sign(body, now), []byte(`{"item_id":"item-2"}`), now), "a changed body")
+	assert.Error(t, client.verifyWebhook(t.Context(), sign(body, now.Add(-10*time.Minute)), body, now), "an old signature")
+	assert.Error(t, client.verifyWebhook(t.Context(), "", body, now))
+	assert.Equal(t, 1, keyRequests, "the key is cached")
+
+	other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	forged := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{"iat": now.Unix()})
+	forged.Header["kid"] = "key-1"
+	signed, err := forged.SignedString(other)
+	require.NoError(t, err)
+	assert.Error(t, client.verifyWebhook(t.Context(), signed, body, now), "signed with another key")
+}
+
+func TestExchangePublicTokenSeals(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"access_token":"access-sandbox-1","item_id":"item-1"}`))
+	}))
+	defer srv.Close()
+
+	box := testBox(t)
+	client := NewClient("client", "secret", "sandbox", []string{"US"}, "", box)
+	client.url = srv.URL
+	sealed, itemID, err := client.exchangePublicToken(t.Context(), "public-sandbox-1")
+	require.NoError(t, err)
+	assert.Equal(t, "item-1", itemID)
+	assert.True(t, secret.IsSealed(sealed))
+	token, err := box.Open(sealed)
+	require.NoError(t, err)
+	assert.Equal(t, "access-sandbox-1", token)
+
+	// Tokens stored before they were encrypted still work until sealed
+	token, err = client.open([]byte("access-sandbox-1"))
+	require.NoError(t, err)
+	assert.Equal(t, "access-sandbox-1", token)
+}
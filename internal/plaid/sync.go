@@ -0,0 +1,421 @@
+package plaid
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+	"github.com/yanonymousV2/finance-manager-backend/internal/fxrate"
+	"github.com/yanonymousV2/finance-manager-backend/internal/merchant"
+	"github.com/yanonymousV2/finance-manager-backend/internal/secret"
+)
+
+const (
+	// staleAfter is how long an active item goes without a sync before it's
+	// synced anyway, in case a webhook was missed
+	staleAfter = 12 * time.Hour
+	// maxSyncRestarts is how often a sync starts over when the transactions
+	// change while it pages through them
+	maxSyncRestarts = 3
+)
+
+// skippedCategories are Plaid categories of money moving between the
+// user's own accounts, such as paying off a card, which aren't spending
+var skippedCategories = map[string]bool{
+	"TRANSFER_OUT":  true,
+	"LOAN_PAYMENTS": true,
+}
+
+// categoryNames maps Plaid categories, detailed ones first, to the names
+// of the built-in categories. A transaction gets the user's category of
+// that name when they have one.
+var categoryNames = map[string]string{
+	"FOOD_AND_DRINK_GROCERIES": "Groceries",
+	"RENT_AND_UTILITIES_RENT":  "Rent",
+	"FOOD_AND_DRINK":           "Food",
+	"TRANSPORTATION":           "Transport",
+	"RENT_AND_UTILITIES":       "Utilities",
+	"ENTERTAINMENT":            "Entertainment",
+	"MEDICAL":                  "Health",
+	"GENERAL_MERCHANDISE":      "Shopping",
+	"TRAVEL":                   "Travel",
+}
+
+// isSpending reports whether a transaction becomes an expense: money that
+// left the account for something other than a transfer, once it's posted
+func (t transaction) isSpending() bool {
+	if t.Pending || !t.Amount.IsPositive() {
+		return false
+	}
+	return t.Category == nil || !skippedCategories[t.Category.Primary]
+}
+
+// categoryName is the name of the category a transaction belongs in, or ""
+func (t transaction) categoryName() string {
+	if t.Category == nil {
+		return ""
+	}
+	if name, ok := categoryNames[t.Category.Detailed]; ok {
+		return name
+	}
+	return categoryNames[t.Category.Primary]
+}
+
+// payee is the merchant Plaid recognized, or the raw transaction name
+func (t transaction) payee() string {
+	if t.MerchantName != nil && *t.MerchantName != "" {
+		return *t.MerchantName
+	}
+	return t.Name
+}
+
+// description is the transaction's name as a description
+func (t transaction) description() *string {
+	name := strings.TrimSpace(t.Name)
+	if name == "" {
+		return nil
+	}
+	if runes := []rune(name); len(runes) > 255 {
+		name = string(runes[:255])
+	}
+	return &name
+}
+
+// item is an item to sync
+type item struct {
+	ID          uuid.UUID
+	UserID      uuid.UUID
+	ItemID      string
+	AccessToken []byte
+	Cursor      *string
+}
+
+// SyncItems syncs the transactions of every active item that Plaid reported
+// new ones for, or that hasn't been synced for staleAfter. Items Plaid
+// can't reach until the user logs in again are marked so and skipped from
+// then on.
+func SyncItems(ctx context.Context, db *db.DB, client *Client, rates *fxrate.Service) error {
+	if err := sealLegacyTokens(ctx, db, client); err != nil {
+		return fmt.Errorf("failed to encrypt access tokens: %w", err)
+	}
+
+	rows, err := db.Pool.Query(ctx,
+		`SELECT id, user_id, item_id, access_token, cursor FROM plaid_items
+		 WHERE status = $1 AND (sync_requested_at IS NOT NULL OR last_synced_at IS NULL OR last_synced_at < $2)
+		 ORDER BY sync_requested_at NULLS LAST, id`,
+		StatusActive, time.Now().Add(-staleAfter))
+	if err != nil {
+		return err
+	}
+	items, err := pgx.CollectRows(rows, pgx.RowToStructByPos[item])
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for _, it := range items {
+		err := syncItem(ctx, db, client, rates, it)
+		if err == nil {
+			continue
+		}
+		var plaidErr *Error
+		if errors.As(err, &plaidErr) && plaidErr.Type == "ITEM_ERROR" {
+			status := StatusError
+			if plaidErr.Code == "ITEM_LOGIN_REQUIRED" {
+				status = StatusLoginRequired
+			}
+			slog.Warn("Plaid item needs attention", "item_id", it.ID, "code", plaidErr.Code)
+			_, err = db.Pool.Exec(ctx, "UPDATE plaid_items SET status = $2, error_code = $3 WHERE id = $1",
+				it.ID, status, plaidErr.Code)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("item %s: %w", it.ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// syncItem fetches the changes since the item's cursor and applies them,
+// saving the new cursor with them
+func syncItem(ctx context.Context, db *db.DB, client *Client, rates *fxrate.Service, it item) error {
+	started := time.Now()
+	var changes syncPage
+	var err error
+	for range maxSyncRestarts {
+		changes, err = fetchChanges(ctx, client, it)
+		if errorCode(err) != "TRANSACTIONS_SYNC_MUTATION_DURING_PAGINATION" {
+			break
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	return db.WithTx(ctx, func(tx pgx.Tx) error {
+		s, err := newSyncer(ctx, tx, rates, it)
+		if err != nil {
+			return err
+		}
+		for _, r := range changes.Removed {
+			if _, err := tx.Exec(ctx,
+				"DELETE FROM personal_expenses WHERE user_id = $1 AND plaid_transaction_id = $2",
+				it.UserID, r.TransactionID); err != nil {
+				return err
+			}
+		}
+		for _, t := range changes.Modified {
+			if err := s.modify(ctx, tx, t); err != nil {
+				return err
+			}
+		}
+		for _, t := range changes.Added {
+			if err := s.add(ctx, tx, t); err != nil {
+				return err
+			}
+		}
+
+		// A sync requested while this one ran still needs to happen
+		_, err = tx.Exec(ctx,
+			`UPDATE plaid_items SET cursor = $2, last_synced_at = NOW(),
+			     sync_requested_at = CASE WHEN sync_requested_at <= $3 THEN NULL ELSE sync_requested_at END
+			 WHERE id = $1`,
+			it.ID, changes.NextCursor, started)
+		return err
+	})
+}
+
+// fetchChanges pages through every change since the item's cursor
+func fetchChanges(ctx context.Context, client *Client, it item) (syncPage, error) {
+	var all syncPage
+	cursor := ""
+	if it.Cursor != nil {
+		cursor = *it.Cursor
+	}
+	for {
+		page, err := client.syncTransactions(ctx, it.AccessToken, cursor)
+		if err != nil {
+			return syncPage{}, err
+		}
+		all.Added = append(all.Added, page.Added...)
+		all.Modified = append(all.Modified, page.Modified...)
+		all.Removed = append(all.Removed, page.Removed...)
+		all.NextCursor = page.NextCursor
+		if !page.HasMore {
+			return all, nil
+		}
+		cursor = page.NextCursor
+	}
+}
+
+// syncer turns an item's transactions into its user's expenses
+type syncer struct {
+	userID          uuid.UUID
+	defaultCurrency string
+	rates           *fxrate.Service
+	// accounts maps Plaid account IDs to the accounts they're recorded
+	// against
+	accounts map[string]*uuid.UUID
+	// categories maps lowercase names to the user's categories
+	categories map[string]uuid.UUID
+	merchants  map[string]*merchant.Resolved
+}
+
+func newSyncer(ctx context.Context, tx pgx.Tx, rates *fxrate.Service, it item) (*syncer, error) {
+	s := &syncer{
+		userID:     it.UserID,
+		rates:      rates,
+		accounts:   map[string]*uuid.UUID{},
+		categories: map[string]uuid.UUID{},
+		merchants:  map[string]*merchant.Resolved{},
+	}
+	err := tx.QueryRow(ctx, "SELECT default_currency FROM users WHERE id = $1", it.UserID).Scan(&s.defaultCurrency)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := tx.Query(ctx, "SELECT plaid_account_id, account_id FROM plaid_accounts WHERE item_id = $1", it.ID)
+	if err != nil {
+		return nil, err
+	}
+	for rows.Next() {
+		var plaidAccountID string
+		var accountID *uuid.UUID
+		if err := rows.Scan(&plaidAccountID, &accountID); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		s.accounts[plaidAccountID] = accountID
+	}
+	rows.Close()
+
+	rows, err = tx.Query(ctx,
+		"SELECT id, name FROM expense_categories WHERE user_id = $1 AND deleted_at IS NULL AND NOT archived", it.UserID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id uuid.UUID
+		var name string
+		if err := rows.Scan(&id, &name); err != nil {
+			return nil, err
+		}
+		s.categories[strings.ToLower(name)] = id
+	}
+	return s, rows.Err()
+}
+
+// amounts is a transaction's amount in the user's default currency, with
+// the original when it was in another one
+type amounts struct {
+	Amount           decimal.Decimal
+	OriginalAmount   *decimal.Decimal
+	OriginalCurrency *string
+	FXRate           *decimal.Decimal
+}
+
+func (s *syncer) convert(ctx context.Context, t transaction, date time.Time) (amounts, error) {
+	amount := t.Amount.Round(2)
+	if t.ISOCurrencyCode == nil || *t.ISOCurrencyCode == s.defaultCurrency {
+		return amounts{Amount: amount}, nil
+	}
+	if s.rates == nil {
+		return amounts{}, fmt.Errorf("no exchange rates to convert %s", *t.ISOCurrencyCode)
+	}
+	rate, err := s.rates.Rate(ctx, *t.ISOCurrencyCode, s.defaultCurrency, date)
+	if err != nil {
+		return amounts{}, err
+	}
+	return amounts{
+		Amount:           decimal.Max(amount.Mul(rate).Round(2), decimal.New(1, -2)),
+		OriginalAmount:   &amount,
+		OriginalCurrency: t.ISOCurrencyCode,
+		FXRate:           &rate,
+	}, nil
+}
+
+// add records a new transaction. One that matches an expense the user
+// entered by hand, with the same amount and dated up to three days apart
+// since cards often post later, is linked to it instead.
+func (s *syncer) add(ctx context.Context, tx pgx.Tx, t transaction) error {
+	if !t.isSpending() {
+		return nil
+	}
+	date, err := time.Parse(time.DateOnly, t.Date)
+	if err != nil {
+		return fmt.Errorf("invalid date %q of transaction %s", t.Date, t.TransactionID)
+	}
+	a, err := s.convert(ctx, t, date)
+	if err != nil {
+		return err
+	}
+	accountID := s.accounts[t.AccountID]
+
+	tag, err := tx.Exec(ctx,
+		`UPDATE personal_expenses SET plaid_transaction_id = $1, account_id = COALESCE(account_id, $2), updated_at = NOW()
+		 WHERE id = (
+		     SELECT id FROM personal_expenses
+		     WHERE user_id = $3 AND plaid_transaction_id IS NULL AND group_expense_id IS NULL
+		       AND amount = $4 AND expense_date BETWEEN $5::timestamptz - INTERVAL '3 days' AND $5::timestamptz + INTERVAL '3 days'
+		     ORDER BY ABS(EXTRACT(EPOCH FROM expense_date - $5::timestamptz)), id
+		     LIMIT 1)`,
+		t.TransactionID, accountID, s.userID, a.Amount, date)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() > 0 {
+		return nil
+	}
+
+	var merchantID, categoryID *uuid.UUID
+	var merchantName *string
+	if key := merchant.Key(t.payee()); key != "" {
+		m, seen := s.merchants[key]
+		if !seen {
+			if m, err = merchant.Resolve(ctx, tx, s.userID, t.payee()); err != nil {
+				return err
+			}
+			s.merchants[key] = m
+		}
+		if m != nil {
+			merchantID, merchantName, categoryID = &m.ID, &m.Name, m.CategoryID
+		}
+	}
+	if categoryID == nil {
+		if id, ok := s.categories[strings.ToLower(t.categoryName())]; ok {
+			categoryID = &id
+		}
+	}
+
+	_, err = tx.Exec(ctx,
+		`INSERT INTO personal_expenses (user_id, account_id, category_id, amount, currency, description, expense_date,
+		                                merchant_id, merchant, original_amount, original_currency, fx_rate, plaid_transaction_id, updated_at)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, NOW())
+		 ON CONFLICT (plaid_transaction_id) WHERE plaid_transaction_id IS NOT NULL DO NOTHING`,
+		s.userID, accountID, categoryID, a.Amount, s.defaultCurrency, t.description(), date,
+		merchantID, merchantName, a.OriginalAmount, a.OriginalCurrency, a.FXRate, t.TransactionID)
+	return err
+}
+
+// modify follows a change to a transaction's amount or date; the user's own
+// edits, such as the category, are kept. A transaction that wasn't recorded
+// is added.
+func (s *syncer) modify(ctx context.Context, tx pgx.Tx, t transaction) error {
+	if !t.isSpending() {
+		return nil
+	}
+	date, err := time.Parse(time.DateOnly, t.Date)
+	if err != nil {
+		return fmt.Errorf("invalid date %q of transaction %s", t.Date, t.TransactionID)
+	}
+	a, err := s.convert(ctx, t, date)
+	if err != nil {
+		return err
+	}
+	tag, err := tx.Exec(ctx,
+		`UPDATE personal_expenses SET amount = $3, expense_date = $4, original_amount = $5, original_currency = $6,
+		     fx_rate = $7, updated_at = NOW()
+		 WHERE user_id = $1 AND plaid_transaction_id = $2`,
+		s.userID, t.TransactionID, a.Amount, date, a.OriginalAmount, a.OriginalCurrency, a.FXRate)
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return s.add(ctx, tx, t)
+	}
+	return nil
+}
+
+// sealLegacyTokens encrypts the access tokens stored before tokens were
+// encrypted
+func sealLegacyTokens(ctx context.Context, db *db.DB, client *Client) error {
+	rows, err := db.Pool.Query(ctx,
+		"SELECT id, access_token FROM plaid_items WHERE get_byte(access_token, 0) <> $1", int(secret.Version))
+	if err != nil {
+		return err
+	}
+	type legacy struct {
+		ID          uuid.UUID
+		AccessToken []byte
+	}
+	items, err := pgx.CollectRows(rows, pgx.RowToStructByPos[legacy])
+	if err != nil {
+		return err
+	}
+	for _, it := range items {
+		_, err := db.Pool.Exec(ctx, "UPDATE plaid_items SET access_token = $2 WHERE id = $1 AND access_token = $3",
+			it.ID, client.box.Seal(string(it.AccessToken)), it.AccessToken)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
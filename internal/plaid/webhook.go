@@ -0,0 +1,158 @@
+package plaid
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/big"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+)
+
+const (
+	// maxWebhookAge is how old a webhook's signature can be, so a captured
+	// webhook can't be replayed later
+	maxWebhookAge = 5 * time.Minute
+	// maxWebhookBody caps a webhook body
+	maxWebhookBody = 1 << 20
+)
+
+// webhook is the part of a Plaid webhook body that's used
+type webhook struct {
+	Type   string `json:"webhook_type"`
+	Code   string `json:"webhook_code"`
+	ItemID string `json:"item_id"`
+	Error  *Error `json:"error"`
+}
+
+// publicKey returns the key webhooks signed with keyID are verified with
+func (c *Client) publicKey(ctx context.Context, keyID string) (*ecdsa.PublicKey, error) {
+	c.mu.Lock()
+	key, ok := c.keys[keyID]
+	c.mu.Unlock()
+	if ok {
+		return key, nil
+	}
+
+	jwk, err := c.verificationKey(ctx, keyID)
+	if err != nil {
+		return nil, err
+	}
+	if jwk.ExpiredAt != nil {
+		return nil, errors.New("verification key has expired")
+	}
+	if jwk.Kty != "EC" || jwk.Crv != "P-256" {
+		return nil, fmt.Errorf("unsupported verification key %s %s", jwk.Kty, jwk.Crv)
+	}
+	x, errX := base64.RawURLEncoding.DecodeString(jwk.X)
+	y, errY := base64.RawURLEncoding.DecodeString(jwk.Y)
+	if errX != nil || errY != nil {
+		return nil, errors.New("invalid verification key")
+	}
+	key = &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+
+	c.mu.Lock()
+	c.keys[keyID] = key
+	c.mu.Unlock()
+	return key, nil
+}
+
+// verifyWebhook checks the Plaid-Verification header of a webhook: a JWT
+// signed by Plaid, issued in the last maxWebhookAge, with the SHA-256 of the
+// body
+func (c *Client) verifyWebhook(ctx context.Context, header string, body []byte, now time.Time) error {
+	claims := jwt.MapClaims{}
+	_, err := jwt.ParseWithClaims(header, claims, func(t *jwt.Token) (any, error) {
+		keyID, _ := t.Header["kid"].(string)
+		if keyID == "" {
+			return nil, errors.New("missing key ID")
+		}
+		return c.publicKey(ctx, keyID)
+	}, jwt.WithValidMethods([]string{"ES256"}), jwt.WithIssuedAt(), jwt.WithTimeFunc(func() time.Time { return now }))
+	if err != nil {
+		return err
+	}
+
+	issuedAt, err := claims.GetIssuedAt()
+	if err != nil || issuedAt == nil || now.Sub(issuedAt.Time) > maxWebhookAge {
+		return errors.New("webhook is too old")
+	}
+	want, _ := claims["request_body_sha256"].(string)
+	got := sha256.Sum256(body)
+	if subtle.ConstantTimeCompare([]byte(want), []byte(hex.EncodeToString(got[:]))) != 1 {
+		return errors.New("webhook body doesn't match its signature")
+	}
+	return nil
+}
+
+// HandleWebhook receives Plaid's webhooks: new transactions queue a sync of
+// the item, and item errors and repairs update its status
+func HandleWebhook(c *gin.Context, db *db.DB, client *Client) {
+	if client == nil {
+		unavailable(c)
+		return
+	}
+
+	body, err := io.ReadAll(io.LimitReader(c.Request.Body, maxWebhookBody))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "failed to read body"})
+		return
+	}
+	if err := client.verifyWebhook(c.Request.Context(), c.GetHeader("Plaid-Verification"), body, time.Now()); err != nil {
+		slog.Warn("rejected Plaid webhook", "error", err)
+		c.JSON(401, gin.H{"error": "invalid webhook signature"})
+		return
+	}
+
+	var w webhook
+	if err := json.Unmarshal(body, &w); err != nil {
+		c.JSON(400, gin.H{"error": "invalid webhook body"})
+		return
+	}
+
+	if err := applyWebhook(c.Request.Context(), db, w); err != nil {
+		slog.Error("failed to handle Plaid webhook", "type", w.Type, "code", w.Code, "error", err)
+		c.JSON(500, gin.H{"error": "failed to handle webhook"})
+		return
+	}
+	c.JSON(200, gin.H{"message": "webhook received"})
+}
+
+// applyWebhook updates the webhook's item. Webhooks for unknown items, e.g.
+// ones deleted meanwhile, and of other kinds are ignored.
+func applyWebhook(ctx context.Context, db *db.DB, w webhook) error {
+	var err error
+	switch {
+	case w.Type == "TRANSACTIONS" && w.Code == "SYNC_UPDATES_AVAILABLE":
+		_, err = db.Pool.Exec(ctx,
+			"UPDATE plaid_items SET sync_requested_at = NOW() WHERE item_id = $1", w.ItemID)
+	case w.Type == "ITEM" && w.Code == "ERROR" && w.Error != nil:
+		status := StatusError
+		if w.Error.Code == "ITEM_LOGIN_REQUIRED" {
+			status = StatusLoginRequired
+		}
+		_, err = db.Pool.Exec(ctx,
+			"UPDATE plaid_items SET status = $2, error_code = $3 WHERE item_id = $1", w.ItemID, status, w.Error.Code)
+	case w.Type == "ITEM" && (w.Code == "USER_PERMISSION_REVOKED" || w.Code == "USER_ACCOUNT_REVOKED"):
+		_, err = db.Pool.Exec(ctx,
+			"UPDATE plaid_items SET status = $2, error_code = NULL WHERE item_id = $1", w.ItemID, StatusRevoked)
+	case w.Type == "ITEM" && w.Code == "LOGIN_REPAIRED":
+		_, err = db.Pool.Exec(ctx,
+			"UPDATE plaid_items SET status = $2, error_code = NULL, sync_requested_at = NOW() WHERE item_id = $1",
+			w.ItemID, StatusActive)
+	}
+	return err
+}
@@ -0,0 +1,146 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	apnsURL        = "https://api.push.apple.com"
+	apnsSandboxURL = "https://api.sandbox.push.apple.com"
+	// apnsTokenLifetime is how long a provider token is reused. Apple
+	// rejects tokens older than an hour and ones refreshed more often than
+	// every 20 minutes.
+	apnsTokenLifetime = 50 * time.Minute
+)
+
+// APNs sends through the Apple Push Notification service over HTTP/2,
+// authenticating with a token signed by a .p8 key
+type APNs struct {
+	url    string
+	keyID  string
+	teamID string
+	topic  string
+	key    *ecdsa.PrivateKey
+	client *http.Client
+
+	mu     sync.Mutex
+	token  string
+	issued time.Time
+}
+
+// NewAPNs returns an APNs client. topic is the app's bundle ID; sandbox
+// sends to development builds.
+func NewAPNs(key []byte, keyID, teamID, topic string, sandbox bool) (*APNs, error) {
+	signingKey, err := jwt.ParseECPrivateKeyFromPEM(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid APNs key: %w", err)
+	}
+	endpoint := apnsURL
+	if sandbox {
+		endpoint = apnsSandboxURL
+	}
+	// The default transport negotiates HTTP/2, which APNs requires
+	return &APNs{
+		url:    endpoint,
+		keyID:  keyID,
+		teamID: teamID,
+		topic:  topic,
+		key:    signingKey,
+		client: &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+// apnsInvalidReasons are the rejection reasons that mean a token is dead
+var apnsInvalidReasons = map[string]bool{
+	"BadDeviceToken":         true,
+	"DeviceTokenNotForTopic": true,
+	"Unregistered":           true,
+}
+
+func (a *APNs) Send(ctx context.Context, token string, msg Message) error {
+	// Data goes beside the aps dictionary, where the app reads custom keys
+	body := map[string]any{
+		"aps": map[string]any{
+			"alert": map[string]string{"title": msg.Title, "body": msg.Body},
+			"sound": "default",
+		},
+	}
+	for k, v := range msg.Data {
+		if k != "aps" {
+			body[k] = v
+		}
+	}
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	providerToken, err := a.providerToken()
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.url+"/3/device/"+token, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "bearer "+providerToken)
+	req.Header.Set("apns-topic", a.topic)
+	req.Header.Set("apns-push-type", "alert")
+	req.Header.Set("apns-priority", "10")
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+	detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	var e struct {
+		Reason string `json:"reason"`
+	}
+	_ = json.Unmarshal(detail, &e)
+	if resp.StatusCode == http.StatusGone || apnsInvalidReasons[e.Reason] {
+		return fmt.Errorf("apns: %s: %w", e.Reason, ErrInvalidToken)
+	}
+	if e.Reason == "ExpiredProviderToken" {
+		a.mu.Lock()
+		a.token = ""
+		a.mu.Unlock()
+	}
+	return fmt.Errorf("apns: %s: %s", resp.Status, detail)
+}
+
+// providerToken returns the signed token requests are authenticated with,
+// making a new one when it's apnsTokenLifetime old
+func (a *APNs) providerToken() (string, error) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	now := time.Now()
+	if a.token != "" && now.Sub(a.issued) < apnsTokenLifetime {
+		return a.token, nil
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, jwt.MapClaims{
+		"iss": a.teamID,
+		"iat": now.Unix(),
+	})
+	token.Header["kid"] = a.keyID
+	signed, err := token.SignedString(a.key)
+	if err != nil {
+		return "", fmt.Errorf("apns: failed to sign provider token: %w", err)
+	}
+	a.token, a.issued = signed, now
+	return signed, nil
+}
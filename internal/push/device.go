@@ -0,0 +1,155 @@
+package push
+
+import (
+	"encoding/hex"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+	"github.com/yanonymousV2/finance-manager-backend/internal/middleware"
+	"github.com/yanonymousV2/finance-manager-backend/internal/validation"
+)
+
+// maxDevices is how many devices one user can register. Registering
+// another removes the one seen least recently.
+const maxDevices = 20
+
+// Device is a device registered for push notifications. The token isn't
+// returned; the app already has it.
+type Device struct {
+	ID         uuid.UUID `json:"id" db:"id"`
+	Provider   string    `json:"provider" db:"provider"`
+	Name       *string   `json:"name,omitempty" db:"name"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	LastSeenAt time.Time `json:"last_seen_at" db:"last_seen_at"`
+}
+
+// RegisterDeviceRequest registers the token an app got from FCM or APNs.
+// Apps should register on every start, which keeps the device from being
+// removed as stale.
+type RegisterDeviceRequest struct {
+	Provider string  `json:"provider" validate:"required,oneof=fcm apns"`
+	Token    string  `json:"token" validate:"required,max=512"`
+	Name     *string `json:"name,omitempty" validate:"omitempty,max=100"`
+}
+
+const deviceColumns = "id, provider, name, created_at, last_seen_at"
+
+func scanDevice(row interface{ Scan(...any) error }) (Device, error) {
+	var d Device
+	err := row.Scan(&d.ID, &d.Provider, &d.Name, &d.CreatedAt, &d.LastSeenAt)
+	return d, err
+}
+
+// RegisterDevice adds a device for the authenticated user, or refreshes it
+// if the token is already registered. A token registered to another user is
+// moved to this one.
+func RegisterDevice(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req RegisterDeviceRequest
+	if !validation.Bind(c, &req) {
+		return
+	}
+	// APNs tokens are hex and go in the request path
+	if _, err := hex.DecodeString(req.Token); req.Provider == ProviderAPNs && err != nil {
+		c.JSON(400, gin.H{"error": "invalid APNs device token"})
+		return
+	}
+
+	var device Device
+	err := db.WithTx(c.Request.Context(), func(tx pgx.Tx) error {
+		var err error
+		device, err = scanDevice(tx.QueryRow(c.Request.Context(),
+			`INSERT INTO device_tokens (user_id, provider, token, name)
+			 VALUES ($1, $2, $3, $4)
+			 ON CONFLICT (token) DO UPDATE SET
+			     user_id = EXCLUDED.user_id,
+			     provider = EXCLUDED.provider,
+			     name = COALESCE(EXCLUDED.name, device_tokens.name),
+			     last_seen_at = NOW()
+			 RETURNING `+deviceColumns,
+			userID, req.Provider, req.Token, req.Name))
+		if err != nil {
+			return err
+		}
+		_, err = tx.Exec(c.Request.Context(),
+			`DELETE FROM device_tokens WHERE user_id = $1 AND id NOT IN (
+			     SELECT id FROM device_tokens WHERE user_id = $1
+			     ORDER BY last_seen_at DESC LIMIT $2)`,
+			userID, maxDevices)
+		return err
+	})
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to register device"})
+		return
+	}
+
+	c.JSON(201, device)
+}
+
+// ListDevices returns the authenticated user's devices, most recently seen
+// first
+func ListDevices(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	rows, err := db.Pool.Query(c.Request.Context(),
+		`SELECT `+deviceColumns+` FROM device_tokens WHERE user_id = $1 ORDER BY last_seen_at DESC, id`, userID)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to retrieve devices"})
+		return
+	}
+	defer rows.Close()
+
+	devices := []Device{}
+	for rows.Next() {
+		device, err := scanDevice(rows)
+		if err != nil {
+			c.JSON(500, gin.H{"error": "failed to scan device"})
+			return
+		}
+		devices = append(devices, device)
+	}
+
+	c.JSON(200, devices)
+}
+
+// DeleteDevice stops push notifications to a device, e.g. when the user
+// signs out of the app on it
+func DeleteDevice(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	deviceID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid device id"})
+		return
+	}
+
+	tag, err := db.Pool.Exec(c.Request.Context(),
+		"DELETE FROM device_tokens WHERE id = $1 AND user_id = $2", deviceID, userID)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to delete device"})
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		c.JSON(404, gin.H{"error": "device not found"})
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "device deleted successfully"})
+}
@@ -0,0 +1,207 @@
+package push
+
+import (
+	"bytes"
+	"context"
+	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	fcmURL   = "https://fcm.googleapis.com"
+	fcmScope = "https://www.googleapis.com/auth/firebase.messaging"
+)
+
+// FCM sends through the Firebase Cloud Messaging HTTP v1 API, with OAuth
+// access tokens it gets for a service account
+type FCM struct {
+	url         string
+	projectID   string
+	clientEmail string
+	tokenURL    string
+	key         *rsa.PrivateKey
+	client      *http.Client
+
+	mu          sync.Mutex
+	accessToken string
+	expires     time.Time
+}
+
+// serviceAccount is the part of a Firebase service account key file FCM
+// needs
+type serviceAccount struct {
+	Type        string `json:"type"`
+	ProjectID   string `json:"project_id"`
+	PrivateKey  string `json:"private_key"`
+	ClientEmail string `json:"client_email"`
+	TokenURI    string `json:"token_uri"`
+}
+
+// NewFCM returns an FCM client for the service account key file's project
+func NewFCM(credentials []byte) (*FCM, error) {
+	var account serviceAccount
+	if err := json.Unmarshal(credentials, &account); err != nil {
+		return nil, fmt.Errorf("invalid FCM credentials: %w", err)
+	}
+	if account.Type != "service_account" || account.ProjectID == "" || account.ClientEmail == "" {
+		return nil, errors.New("invalid FCM credentials: not a service account key")
+	}
+	key, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(account.PrivateKey))
+	if err != nil {
+		return nil, fmt.Errorf("invalid FCM credentials: %w", err)
+	}
+	if account.TokenURI == "" {
+		account.TokenURI = "https://oauth2.googleapis.com/token"
+	}
+	return &FCM{
+		url:         fcmURL,
+		projectID:   account.ProjectID,
+		clientEmail: account.ClientEmail,
+		tokenURL:    account.TokenURI,
+		key:         key,
+		client:      &http.Client{Timeout: 30 * time.Second},
+	}, nil
+}
+
+type fcmRequest struct {
+	Message struct {
+		Token        string            `json:"token"`
+		Notification fcmNotification   `json:"notification"`
+		Data         map[string]string `json:"data,omitempty"`
+	} `json:"message"`
+}
+
+type fcmNotification struct {
+	Title string `json:"title"`
+	Body  string `json:"body"`
+}
+
+// fcmError is the error body of a failed send
+type fcmError struct {
+	Error struct {
+		Status  string `json:"status"`
+		Message string `json:"message"`
+		Details []struct {
+			ErrorCode string `json:"errorCode"`
+		} `json:"details"`
+	} `json:"error"`
+}
+
+// invalidToken reports whether the error means the token is dead: the app
+// was uninstalled (UNREGISTERED), the token is malformed (INVALID_ARGUMENT;
+// the rest of the message is always valid) or it's another project's
+// (SENDER_ID_MISMATCH)
+func (e fcmError) invalidToken() bool {
+	for _, d := range e.Error.Details {
+		switch d.ErrorCode {
+		case "UNREGISTERED", "INVALID_ARGUMENT", "SENDER_ID_MISMATCH":
+			return true
+		}
+	}
+	return false
+}
+
+func (f *FCM) Send(ctx context.Context, token string, msg Message) error {
+	var body fcmRequest
+	body.Message.Token = token
+	body.Message.Notification = fcmNotification{Title: msg.Title, Body: msg.Body}
+	body.Message.Data = msg.Data
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+
+	accessToken, err := f.token(ctx)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost,
+		f.url+"/v1/projects/"+url.PathEscape(f.projectID)+"/messages:send", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+accessToken)
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		return nil
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		// Get a new access token next time
+		f.mu.Lock()
+		f.accessToken = ""
+		f.mu.Unlock()
+	}
+	detail, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	var e fcmError
+	if json.Unmarshal(detail, &e) == nil && e.invalidToken() {
+		return fmt.Errorf("fcm: %s: %w", e.Error.Message, ErrInvalidToken)
+	}
+	return fmt.Errorf("fcm: %s: %s", resp.Status, detail)
+}
+
+// token returns an access token, getting a new one shortly before the
+// current one expires
+func (f *FCM) token(ctx context.Context) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.accessToken != "" && time.Now().Before(f.expires) {
+		return f.accessToken, nil
+	}
+
+	now := time.Now()
+	assertion, err := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+		"iss":   f.clientEmail,
+		"scope": fcmScope,
+		"aud":   f.tokenURL,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	}).SignedString(f.key)
+	if err != nil {
+		return "", fmt.Errorf("fcm: failed to sign token request: %w", err)
+	}
+	form := url.Values{
+		"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+		"assertion":  {assertion},
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, f.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := f.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("fcm: failed to get access token: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return "", fmt.Errorf("fcm: failed to get access token: %s: %s", resp.Status, detail)
+	}
+	var result struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("fcm: failed to get access token: %w", err)
+	}
+	f.accessToken = result.AccessToken
+	f.expires = now.Add(time.Duration(result.ExpiresIn)*time.Second - time.Minute)
+	return f.accessToken, nil
+}
@@ -0,0 +1,147 @@
+// Package push sends push notifications to users' devices through Firebase
+// Cloud Messaging and the Apple Push Notification service, and lets users
+// register the devices' tokens.
+package push
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/config"
+	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+	"github.com/yanonymousV2/finance-manager-backend/internal/requestid"
+)
+
+// Providers
+const (
+	ProviderFCM  = "fcm"
+	ProviderAPNs = "apns"
+)
+
+// Providers lists every provider, for validating registrations
+var Providers = []string{ProviderFCM, ProviderAPNs}
+
+// Message is a push notification. Data is passed to the app along with it,
+// e.g. the notification's id and type for opening the right screen.
+type Message struct {
+	Title string
+	Body  string
+	Data  map[string]string
+}
+
+// ErrInvalidToken is returned by a Client when the provider says a device
+// token will never work again, e.g. because the app was uninstalled
+var ErrInvalidToken = errors.New("device token is no longer valid")
+
+// Client sends a message to one device through a provider
+type Client interface {
+	Send(ctx context.Context, token string, msg Message) error
+}
+
+// Sender pushes messages to every device a user has registered, using the
+// client for each device's provider. Devices of a provider without a
+// client get the message written to the server log instead.
+type Sender struct {
+	db      *db.DB
+	clients map[string]Client
+}
+
+func NewSender(db *db.DB, clients map[string]Client) *Sender {
+	return &Sender{db: db, clients: clients}
+}
+
+// New returns a sender with a client for each provider cfg sets up
+func New(cfg *config.Config, db *db.DB) (*Sender, error) {
+	clients := make(map[string]Client)
+	if cfg.FCMCredentialsFile != "" {
+		credentials, err := os.ReadFile(cfg.FCMCredentialsFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read FCM_CREDENTIALS_FILE: %w", err)
+		}
+		fcm, err := NewFCM(credentials)
+		if err != nil {
+			return nil, err
+		}
+		clients[ProviderFCM] = fcm
+	}
+	if cfg.APNsKeyFile != "" {
+		key, err := os.ReadFile(cfg.APNsKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read APNS_KEY_FILE: %w", err)
+		}
+		apns, err := NewAPNs(key, cfg.APNsKeyID, cfg.APNsTeamID, cfg.APNsTopic, cfg.APNsSandbox)
+		if err != nil {
+			return nil, err
+		}
+		clients[ProviderAPNs] = apns
+	}
+	return NewSender(db, clients), nil
+}
+
+// Enabled lists the providers with a client, for the startup log
+func (s *Sender) Enabled() []string {
+	var enabled []string
+	for _, provider := range Providers {
+		if s.clients[provider] != nil {
+			enabled = append(enabled, provider)
+		}
+	}
+	return enabled
+}
+
+type device struct {
+	ID       uuid.UUID
+	Provider string
+	Token    string
+}
+
+// Push sends msg to each of the user's devices. Tokens the provider
+// rejects as invalid are deleted. It fails only when no device got the
+// message and at least one failed for another reason, so a retry doesn't
+// push it twice to the devices that did.
+func (s *Sender) Push(ctx context.Context, userID uuid.UUID, msg Message) error {
+	rows, err := s.db.Pool.Query(ctx,
+		"SELECT id, provider, token FROM device_tokens WHERE user_id = $1", userID)
+	if err != nil {
+		return fmt.Errorf("failed to find devices: %w", err)
+	}
+	devices, err := pgx.CollectRows(rows, pgx.RowToStructByPos[device])
+	if err != nil {
+		return fmt.Errorf("failed to find devices: %w", err)
+	}
+
+	logger := requestid.Logger(ctx)
+	var delivered int
+	var failed []error
+	for _, d := range devices {
+		client := s.clients[d.Provider]
+		if client == nil {
+			logger.Info("push notification", "user_id", userID, "device_id", d.ID, "provider", d.Provider,
+				"title", msg.Title, "body", msg.Body)
+			delivered++
+			continue
+		}
+		err := client.Send(ctx, d.Token, msg)
+		switch {
+		case errors.Is(err, ErrInvalidToken):
+			logger.Info("removing invalid device token", "user_id", userID, "device_id", d.ID, "provider", d.Provider)
+			if _, err := s.db.Pool.Exec(ctx, "DELETE FROM device_tokens WHERE id = $1", d.ID); err != nil {
+				return fmt.Errorf("failed to remove device %s: %w", d.ID, err)
+			}
+		case err != nil:
+			logger.Warn("failed to push to device", "user_id", userID, "device_id", d.ID, "provider", d.Provider, "error", err)
+			failed = append(failed, fmt.Errorf("device %s: %w", d.ID, err))
+		default:
+			delivered++
+		}
+	}
+	if delivered == 0 && len(failed) > 0 {
+		return errors.Join(failed...)
+	}
+	return nil
+}
@@ -0,0 +1,147 @@
+package push
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func pemKey(t *testing.T, key any) []byte {
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})
+}
+
+func TestFCM(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+
+	tokenRequests := 0
+	var got fcmRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			tokenRequests++
+			require.NoError(t, r.ParseForm())
+			claims := jwt.MapClaims{}
+			_, err := jwt.ParseWithClaims(r.PostForm.Get("assertion"), claims, func(*jwt.Token) (any, error) { return &key.PublicKey, nil })
+			assert.NoError(t, err)
+			assert.Equal(t, "push@finance-test.iam.gserviceaccount.com", claims["iss"])
+			assert.Equal(t, fcmScope, claims["scope"])
+			w.Write([]byte(`{"access_token":"ya29.token","expires_in":3600}`))
+		case "/v1/projects/finance-test/messages:send":
+			assert.Equal(t, "Bearer ya29.token", r.Header.Get("Authorization"))
+			body, _ := io.ReadAll(r.Body)
+			assert.NoError(t, json.Unmarshal(body, &got))
+			if got.Message.Token == "uninstalled" {
+				w.WriteHeader(http.StatusNotFound)
+				w.Write([]byte(`{"error":{"code":404,"message":"Requested entity was not found.","status":"NOT_FOUND",
+					"details":[{"@type":"type.googleapis.com/google.firebase.fcm.v1.FcmError","errorCode":"UNREGISTERED"}]}}`))
+				return
+			}
+			if got.Message.Token == "busy" {
+				w.WriteHeader(http.StatusServiceUnavailable)
+				w.Write([]byte(`{"error":{"code":503,"status":"UNAVAILABLE","details":[{"errorCode":"UNAVAILABLE"}]}}`))
+				return
+			}
+			w.Write([]byte(`{"name":"projects/finance-test/messages/1"}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	credentials, _ := json.Marshal(serviceAccount{
+		Type:        "service_account",
+		ProjectID:   "finance-test",
+		PrivateKey:  string(pemKey(t, key)),
+		ClientEmail: "push@finance-test.iam.gserviceaccount.com",
+		TokenURI:    srv.URL + "/token",
+	})
+	fcm, err := NewFCM(credentials)
+	require.NoError(t, err)
+	fcm.url = srv.URL
+
+	msg := Message{Title: "Payment reminder", Body: "You owe 12.50 USD", Data: map[string]string{"type": "settlement_reminder"}}
+	require.NoError(t, fcm.Send(t.Context(), "device-1", msg))
+	assert.Equal(t, "device-1", got.Message.Token)
+	assert.Equal(t, fcmNotification{Title: "Payment reminder", Body: "You owe 12.50 USD"}, got.Message.Notification)
+	assert.Equal(t, msg.Data, got.Message.Data)
+
+	assert.ErrorIs(t, fcm.Send(t.Context(), "uninstalled", msg), ErrInvalidToken)
+	err = fcm.Send(t.Context(), "busy", msg)
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrInvalidToken)
+	assert.Equal(t, 1, tokenRequests, "the access token is reused")
+
+	_, err = NewFCM([]byte(`{"type":"authorized_user"}`))
+	assert.Error(t, err)
+}
+
+func TestAPNs(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+
+	var got map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth := strings.TrimPrefix(r.Header.Get("Authorization"), "bearer ")
+		token, err := jwt.Parse(auth, func(*jwt.Token) (any, error) { return &key.PublicKey, nil })
+		if assert.NoError(t, err) {
+			assert.Equal(t, "KEY123", token.Header["kid"])
+			issuer, _ := token.Claims.GetIssuer()
+			assert.Equal(t, "TEAM123", issuer)
+		}
+		assert.Equal(t, "com.example.finance", r.Header.Get("apns-topic"))
+		assert.Equal(t, "alert", r.Header.Get("apns-push-type"))
+
+		switch r.URL.Path {
+		case "/3/device/aa11":
+			body, _ := io.ReadAll(r.Body)
+			assert.NoError(t, json.Unmarshal(body, &got))
+		case "/3/device/bb22":
+			w.WriteHeader(http.StatusGone)
+			w.Write([]byte(`{"reason":"Unregistered","timestamp":1700000000000}`))
+		case "/3/device/cc33":
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"reason":"BadDeviceToken"}`))
+		default:
+			w.WriteHeader(http.StatusTooManyRequests)
+			w.Write([]byte(`{"reason":"TooManyRequests"}`))
+		}
+	}))
+	defer srv.Close()
+
+	apns, err := NewAPNs(pemKey(t, key), "KEY123", "TEAM123", "com.example.finance", true)
+	require.NoError(t, err)
+	assert.Equal(t, apnsSandboxURL, apns.url)
+	apns.url = srv.URL
+
+	msg := Message{Title: "New expense: Dinner", Body: "60.00 EUR was added", Data: map[string]string{"type": "expense_added"}}
+	require.NoError(t, apns.Send(t.Context(), "aa11", msg))
+	assert.Equal(t, map[string]any{
+		"aps":  map[string]any{"alert": map[string]any{"title": "New expense: Dinner", "body": "60.00 EUR was added"}, "sound": "default"},
+		"type": "expense_added",
+	}, got)
+
+	assert.ErrorIs(t, apns.Send(t.Context(), "bb22", msg), ErrInvalidToken)
+	assert.ErrorIs(t, apns.Send(t.Context(), "cc33", msg), ErrInvalidToken)
+	err = apns.Send(t.Context(), "dd44", msg)
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, ErrInvalidToken)
+
+	_, err = NewAPNs([]byte("not a key"), "KEY123", "TEAM123", "com.example.finance", false)
+	assert.Error(t, err)
+}
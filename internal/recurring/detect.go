@@ -0,0 +1,280 @@
+package recurring
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+	"github.com/yanonymousV2/finance-manager-backend/internal/helpers"
+	"github.com/yanonymousV2/finance-manager-backend/internal/middleware"
+)
+
+// detectionDays is how far back charges are looked at, enough to see a
+// yearly subscription charged twice
+const detectionDays = 400
+
+// amountTolerance is how far a charge may differ from the latest one and
+// still count as the same subscription
+const amountTolerance = 0.10
+
+// cadence describes a subscription frequency: the days between charges, how
+// many charges in a row it takes to detect it, and how many days past the
+// expected charge it is still considered active
+type cadence struct {
+	frequency  string
+	minDays    int
+	maxDays    int
+	minCharges int
+	graceDays  int
+}
+
+var cadences = []cadence{
+	{frequency: FrequencyWeekly, minDays: 6, maxDays: 8, minCharges: 4, graceDays: 3},
+	{frequency: FrequencyMonthly, minDays: 27, maxDays: 34, minCharges: 3, graceDays: 10},
+	{frequency: FrequencyYearly, minDays: 355, maxDays: 375, minCharges: 2, graceDays: 30},
+}
+
+// charge is a personal expense at a merchant. MerchantKey identifies the
+// merchant: its id, or the raw payee text.
+type charge struct {
+	ID           uuid.UUID
+	Amount       decimal.Decimal
+	Date         time.Time
+	CategoryID   *uuid.UUID
+	CategoryName *string
+	MerchantID   *uuid.UUID
+	Merchant     string
+	MerchantKey  string
+}
+
+// Subscription is a detected run of regular, similar charges at one
+// merchant. ID is derived from the merchant, so it stays the same between
+// requests.
+type Subscription struct {
+	ID           uuid.UUID       `json:"id"`
+	MerchantID   *uuid.UUID      `json:"merchant_id"`
+	Merchant     string          `json:"merchant"`
+	CategoryID   *uuid.UUID      `json:"category_id"`
+	CategoryName *string         `json:"category_name"`
+	Frequency    string          `json:"frequency"`
+	Amount       decimal.Decimal `json:"amount"`
+	MonthlyCost  decimal.Decimal `json:"monthly_cost"`
+	Since        time.Time       `json:"since"`
+	LastCharged  time.Time       `json:"last_charged"`
+	NextExpected time.Time       `json:"next_expected"`
+	ChargeCount  int             `json:"charge_count"`
+}
+
+// DetectedSubscriptions is the user's detected subscriptions and what they
+// cost per month together
+type DetectedSubscriptions struct {
+	Currency      string          `json:"currency"`
+	MonthlyTotal  decimal.Decimal `json:"monthly_total"`
+	Subscriptions []Subscription  `json:"subscriptions"`
+}
+
+// monthlyCost converts a charge at the given frequency to a monthly amount
+func monthlyCost(frequency string, amount decimal.Decimal) decimal.Decimal {
+	switch frequency {
+	case FrequencyWeekly:
+		return amount.Mul(decimal.NewFromInt(52)).Div(decimal.NewFromInt(12)).Round(2)
+	case FrequencyYearly:
+		return amount.Div(decimal.NewFromInt(12)).Round(2)
+	default:
+		return amount
+	}
+}
+
+func days(from, to time.Time) int {
+	return int(to.Sub(from).Hours()/24 + 0.5)
+}
+
+// match returns the latest run of charges (sorted oldest first) that repeat
+// at cadence cd with amounts close to the latest one
+func match(charges []charge, cd cadence) []charge {
+	last := charges[len(charges)-1]
+	tolerance := last.Amount.Mul(decimal.NewFromFloat(amountTolerance))
+	start := len(charges) - 1
+	for start > 0 {
+		prev := charges[start-1]
+		gap := days(prev.Date, charges[start].Date)
+		if gap < cd.minDays || gap > cd.maxDays || prev.Amount.Sub(last.Amount).Abs().GreaterThan(tolerance) {
+			break
+		}
+		start--
+	}
+	return charges[start:]
+}
+
+// detectSubscriptions finds merchants charging a similar amount weekly,
+// monthly or yearly, and still doing so as of now
+func detectSubscriptions(charges []charge, now time.Time) []Subscription {
+	byMerchant := map[string][]charge{}
+	for _, ch := range charges {
+		if ch.MerchantKey != "" {
+			byMerchant[ch.MerchantKey] = append(byMerchant[ch.MerchantKey], ch)
+		}
+	}
+
+	subscriptions := []Subscription{}
+	for key, merchantCharges := range byMerchant {
+		sort.Slice(merchantCharges, func(i, j int) bool { return merchantCharges[i].Date.Before(merchantCharges[j].Date) })
+
+		for _, cd := range cadences {
+			run := match(merchantCharges, cd)
+			if len(run) < cd.minCharges {
+				continue
+			}
+			first, last := run[0], run[len(run)-1]
+			if days(last.Date, now) > cd.maxDays+cd.graceDays {
+				// Lapsed
+				break
+			}
+
+			subscriptions = append(subscriptions, Subscription{
+				ID:           uuid.NewSHA1(uuid.NameSpaceOID, []byte("subscription:"+key)),
+				MerchantID:   last.MerchantID,
+				Merchant:     last.Merchant,
+				CategoryID:   last.CategoryID,
+				CategoryName: last.CategoryName,
+				Frequency:    cd.frequency,
+				Amount:       last.Amount,
+				MonthlyCost:  monthlyCost(cd.frequency, last.Amount),
+				Since:        first.Date,
+				LastCharged:  last.Date,
+				NextExpected: NextDue(cd.frequency, last.Date, last.Date.AddDate(0, 0, 1)),
+				ChargeCount:  len(run),
+			})
+			break
+		}
+	}
+
+	// Most expensive first
+	sort.Slice(subscriptions, func(i, j int) bool {
+		if !subscriptions[i].MonthlyCost.Equal(subscriptions[j].MonthlyCost) {
+			return subscriptions[i].MonthlyCost.GreaterThan(subscriptions[j].MonthlyCost)
+		}
+		return subscriptions[i].Merchant < subscriptions[j].Merchant
+	})
+	return subscriptions
+}
+
+// Detect returns the user's subscriptions found in their personal expenses,
+// leaving out merchants already tracked as recurring expenses
+func Detect(ctx context.Context, db *db.DB, userID uuid.UUID, now time.Time) (DetectedSubscriptions, error) {
+	result := DetectedSubscriptions{MonthlyTotal: decimal.Zero, Subscriptions: []Subscription{}}
+
+	currency, err := helpers.GetUserCurrency(ctx, db, userID)
+	if err != nil {
+		return result, fmt.Errorf("failed to get default currency: %w", err)
+	}
+	result.Currency = currency
+
+	rows, err := db.Pool.Query(ctx,
+		`SELECT pe.id, pe.amount, pe.expense_date, pe.category_id, ec.name, pe.merchant_id,
+		        COALESCE(m.name, pe.merchant), COALESCE(pe.merchant_id::text, LOWER(TRIM(pe.merchant)))
+		 FROM personal_expenses pe
+		 LEFT JOIN expense_categories ec ON pe.category_id = ec.id
+		 LEFT JOIN merchants m ON pe.merchant_id = m.id
+		 WHERE pe.user_id = $1 AND pe.currency = $2 AND pe.expense_date >= $3
+		   AND pe.group_expense_id IS NULL
+		   AND (pe.merchant_id IS NOT NULL OR TRIM(pe.merchant) <> '')
+		   AND COALESCE(pe.merchant_id::text, LOWER(TRIM(pe.merchant))) NOT IN (
+		       SELECT COALESCE(merchant_id::text, LOWER(TRIM(merchant))) FROM recurring_expenses
+		       WHERE user_id = $1 AND (merchant_id IS NOT NULL OR merchant IS NOT NULL))`,
+		userID, currency, now.AddDate(0, 0, -detectionDays))
+	if err != nil {
+		return result, fmt.Errorf("failed to get charges: %w", err)
+	}
+	defer rows.Close()
+
+	var charges []charge
+	for rows.Next() {
+		var ch charge
+		if err := rows.Scan(&ch.ID, &ch.Amount, &ch.Date, &ch.CategoryID, &ch.CategoryName, &ch.MerchantID,
+			&ch.Merchant, &ch.MerchantKey); err != nil {
+			return result, fmt.Errorf("failed to scan charge: %w", err)
+		}
+		charges = append(charges, ch)
+	}
+	if err := rows.Err(); err != nil {
+		return result, fmt.Errorf("failed to get charges: %w", err)
+	}
+
+	result.Subscriptions = detectSubscriptions(charges, now)
+	for _, s := range result.Subscriptions {
+		result.MonthlyTotal = result.MonthlyTotal.Add(s.MonthlyCost)
+	}
+	return result, nil
+}
+
+// GetDetectedSubscriptions returns subscriptions found in the authenticated
+// user's expense history that aren't tracked as recurring expenses yet
+func GetDetectedSubscriptions(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	detected, err := Detect(c.Request.Context(), db, userID, time.Now())
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to detect subscriptions"})
+		return
+	}
+
+	c.JSON(200, detected)
+}
+
+// ConfirmSubscription turns a detected subscription into a recurring expense.
+// Its schedule counts from the last charge, so it is next due when the
+// subscription is next expected to charge.
+func ConfirmSubscription(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	subscriptionID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid subscription id"})
+		return
+	}
+
+	detected, err := Detect(c.Request.Context(), db, userID, time.Now())
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to detect subscriptions"})
+		return
+	}
+	var found *Subscription
+	for i := range detected.Subscriptions {
+		if detected.Subscriptions[i].ID == subscriptionID {
+			found = &detected.Subscriptions[i]
+		}
+	}
+	if found == nil {
+		c.JSON(404, gin.H{"error": "subscription not found"})
+		return
+	}
+
+	r, err := scanRecurring(db.Pool.QueryRow(c.Request.Context(),
+		`INSERT INTO recurring_expenses (user_id, category_id, merchant_id, merchant, amount, currency, description,
+		                                 frequency, start_date, next_due_date)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		 RETURNING `+recurringColumns,
+		userID, found.CategoryID, found.MerchantID, found.Merchant, found.Amount, detected.Currency, found.Merchant,
+		found.Frequency, found.LastCharged, found.NextExpected))
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to create recurring expense"})
+		return
+	}
+
+	c.JSON(201, r)
+}
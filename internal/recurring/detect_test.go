@@ -0,0 +1,75 @@
+package recurring
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDetectSubscriptions(t *testing.T) {
+	d := decimal.RequireFromString
+	now := ymd(2024, 6, 20)
+	at := func(key, amount string, date time.Time) charge {
+		return charge{Amount: d(amount), Date: date.Add(9 * time.Hour), Merchant: key, MerchantKey: key}
+	}
+
+	charges := []charge{
+		// Monthly, with a small price rise; the one-off charge a year earlier
+		// isn't part of the run
+		at("Streamly", "30", ymd(2023, 6, 1)),
+		at("Streamly", "15.99", ymd(2024, 2, 3)),
+		at("Streamly", "15.99", ymd(2024, 3, 3)),
+		at("Streamly", "15.99", ymd(2024, 4, 3)),
+		at("Streamly", "16.99", ymd(2024, 5, 3)),
+		at("Streamly", "16.99", ymd(2024, 6, 3)),
+		// Weekly
+		at("Veg Box", "25", ymd(2024, 5, 28)),
+		at("Veg Box", "25", ymd(2024, 6, 4)),
+		at("Veg Box", "25", ymd(2024, 6, 11)),
+		at("Veg Box", "25", ymd(2024, 6, 18)),
+		// Yearly
+		at("Cloud Drive", "120", ymd(2023, 6, 10)),
+		at("Cloud Drive", "120", ymd(2024, 6, 8)),
+		// Stopped in March
+		at("Gym", "40", ymd(2024, 1, 10)),
+		at("Gym", "40", ymd(2024, 2, 10)),
+		at("Gym", "40", ymd(2024, 3, 10)),
+		// Regular, but the amounts vary too much
+		at("Grocer", "80", ymd(2024, 4, 1)),
+		at("Grocer", "20", ymd(2024, 5, 1)),
+		at("Grocer", "55", ymd(2024, 6, 1)),
+		// No merchant
+		{Amount: d("10"), Date: ymd(2024, 6, 1)},
+	}
+
+	subscriptions := detectSubscriptions(charges, now)
+	require.Len(t, subscriptions, 3)
+
+	veg := subscriptions[0]
+	assert.Equal(t, "Veg Box", veg.Merchant)
+	assert.Equal(t, FrequencyWeekly, veg.Frequency)
+	assert.Equal(t, "108.33", veg.MonthlyCost.String())
+	assert.Equal(t, 4, veg.ChargeCount)
+	assert.Equal(t, ymd(2024, 6, 25), veg.NextExpected)
+
+	streamly := subscriptions[1]
+	assert.Equal(t, "Streamly", streamly.Merchant)
+	assert.Equal(t, FrequencyMonthly, streamly.Frequency)
+	assert.Equal(t, "16.99", streamly.MonthlyCost.String())
+	assert.Equal(t, 5, streamly.ChargeCount)
+	assert.Equal(t, ymd(2024, 2, 3), streamly.Since.Truncate(24*time.Hour))
+	assert.Equal(t, ymd(2024, 7, 3), streamly.NextExpected)
+
+	cloud := subscriptions[2]
+	assert.Equal(t, "Cloud Drive", cloud.Merchant)
+	assert.Equal(t, FrequencyYearly, cloud.Frequency)
+	assert.Equal(t, "10", cloud.MonthlyCost.String())
+	assert.Equal(t, ymd(2023, 6, 10), cloud.Since.Truncate(24*time.Hour))
+	assert.Equal(t, ymd(2025, 6, 8), cloud.NextExpected)
+
+	// The same subscription keeps its id
+	assert.Equal(t, streamly.ID, detectSubscriptions(charges, now.AddDate(0, 0, 5))[1].ID)
+}
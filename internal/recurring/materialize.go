@@ -0,0 +1,90 @@
+package recurring
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+)
+
+// Materialize records a personal expense for every due date up to today of
+// each active recurring expense with AutoRecord on, and moves its next due
+// date past today. It returns how many expenses were recorded. Each
+// recurring expense is advanced and recorded in one transaction, guarded on
+// its old due date, so a charge is never recorded twice.
+func Materialize(ctx context.Context, db *db.DB, today time.Time) (int, error) {
+	today = time.Date(today.Year(), today.Month(), today.Day(), 0, 0, 0, 0, time.UTC)
+	rows, err := db.Pool.Query(ctx,
+		`SELECT `+recurringColumns+` FROM recurring_expenses
+		 WHERE active AND auto_record AND next_due_date <= $1
+		 ORDER BY next_due_date`, today)
+	if err != nil {
+		return 0, fmt.Errorf("failed to find due recurring expenses: %w", err)
+	}
+	due, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (RecurringExpense, error) {
+		return scanRecurring(row)
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to find due recurring expenses: %w", err)
+	}
+
+	recorded := 0
+	for _, r := range due {
+		// Stop between recurring expenses on shutdown, never half way through one
+		if ctx.Err() != nil {
+			return recorded, ctx.Err()
+		}
+		n, err := materializeOne(ctx, db, r, today)
+		if err != nil {
+			return recorded, err
+		}
+		recorded += n
+	}
+	if recorded > 0 {
+		slog.Info("recorded recurring expenses", "count", recorded)
+	}
+	return recorded, nil
+}
+
+func materializeOne(ctx context.Context, db *db.DB, r RecurringExpense, today time.Time) (int, error) {
+	dates := DueDates(r.Frequency, r.StartDate, r.NextDueDate, today)
+	recorded := 0
+	err := db.WithTx(ctx, func(tx pgx.Tx) error {
+		tag, err := tx.Exec(ctx,
+			`UPDATE recurring_expenses SET next_due_date = $3, updated_at = NOW()
+			 WHERE id = $1 AND next_due_date = $2 AND active AND auto_record`,
+			r.ID, r.NextDueDate, NextDue(r.Frequency, r.StartDate, today.AddDate(0, 0, 1)))
+		if err != nil {
+			return err
+		}
+		// Changed or recorded by another server since it was read
+		if tag.RowsAffected() == 0 {
+			return nil
+		}
+
+		description := r.Description
+		if description == nil {
+			description = r.Merchant
+		}
+		batch := &pgx.Batch{}
+		for _, date := range dates {
+			batch.Queue(
+				`INSERT INTO personal_expenses (user_id, category_id, merchant_id, merchant, amount, currency, description, expense_date, updated_at)
+				 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, NOW())`,
+				r.UserID, r.CategoryID, r.MerchantID, r.Merchant, r.Amount, r.Currency, description, date)
+		}
+		if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+			return err
+		}
+		recorded = len(dates)
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to record recurring expense %s: %w", r.ID, err)
+	}
+	return recorded, nil
+}
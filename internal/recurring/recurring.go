@@ -0,0 +1,289 @@
+package recurring
+
+import (
+	"context"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+	"github.com/yanonymousV2/finance-manager-backend/internal/db/sqlc"
+	"github.com/yanonymousV2/finance-manager-backend/internal/helpers"
+	"github.com/yanonymousV2/finance-manager-backend/internal/middleware"
+	"github.com/yanonymousV2/finance-manager-backend/internal/validation"
+)
+
+// Recurring expense frequencies
+const (
+	FrequencyWeekly  = "weekly"
+	FrequencyMonthly = "monthly"
+	FrequencyYearly  = "yearly"
+)
+
+// RecurringExpense is a bill or subscription paid on a schedule. Due dates
+// are counted from StartDate; NextDueDate is the next one not yet paid.
+// With AutoRecord on, a personal expense is recorded on each due date.
+type RecurringExpense struct {
+	ID          uuid.UUID       `json:"id" db:"id"`
+	UserID      uuid.UUID       `json:"user_id" db:"user_id"`
+	CategoryID  *uuid.UUID      `json:"category_id,omitempty" db:"category_id"`
+	MerchantID  *uuid.UUID      `json:"merchant_id,omitempty" db:"merchant_id"`
+	Merchant    *string         `json:"merchant,omitempty" db:"merchant"`
+	Amount      decimal.Decimal `json:"amount" db:"amount"`
+	Currency    string          `json:"currency" db:"currency"`
+	Description *string         `json:"description,omitempty" db:"description"`
+	Frequency   string          `json:"frequency" db:"frequency"`
+	StartDate   time.Time       `json:"start_date" db:"start_date"`
+	NextDueDate time.Time       `json:"next_due_date" db:"next_due_date"`
+	Active      bool            `json:"active" db:"active"`
+	AutoRecord  bool            `json:"auto_record" db:"auto_record"`
+	CreatedAt   time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at" db:"updated_at"`
+}
+
+// recurringColumns is the column list scanRecurring expects
+const recurringColumns = "id, user_id, category_id, merchant_id, merchant, amount, currency, description, frequency, " +
+	"start_date, next_due_date, active, auto_record, created_at, updated_at"
+
+func scanRecurring(row pgx.Row) (RecurringExpense, error) {
+	var r RecurringExpense
+	err := row.Scan(&r.ID, &r.UserID, &r.CategoryID, &r.MerchantID, &r.Merchant, &r.Amount, &r.Currency,
+		&r.Description, &r.Frequency, &r.StartDate, &r.NextDueDate, &r.Active, &r.AutoRecord, &r.CreatedAt, &r.UpdatedAt)
+	return r, err
+}
+
+type UpdateRecurringRequest struct {
+	CategoryID  *uuid.UUID `json:"category_id,omitempty"`
+	Amount      *string    `json:"amount,omitempty" validate:"omitempty,numeric"`
+	Description *string    `json:"description,omitempty" validate:"omitempty,max=255"`
+	// Active pauses (false) or resumes (true) the schedule
+	Active *bool `json:"active,omitempty"`
+	// AutoRecord turns recording a personal expense on each due date on or off
+	AutoRecord *bool `json:"auto_record,omitempty"`
+}
+
+// occurrence returns the nth due date counted from start. Monthly and yearly
+// dates past the end of a shorter month fall on its last day.
+func occurrence(frequency string, start time.Time, n int) time.Time {
+	if frequency == FrequencyWeekly {
+		return start.AddDate(0, 0, 7*n)
+	}
+	months := n
+	if frequency == FrequencyYearly {
+		months = 12 * n
+	}
+	first := time.Date(start.Year(), start.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, months, 0)
+	last := first.AddDate(0, 1, -1).Day()
+	day := start.Day()
+	if day > last {
+		day = last
+	}
+	return time.Date(first.Year(), first.Month(), day, 0, 0, 0, 0, time.UTC)
+}
+
+// NextDue returns the first due date on or after from
+func NextDue(frequency string, start, from time.Time) time.Time {
+	start = time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.UTC)
+	from = time.Date(from.Year(), from.Month(), from.Day(), 0, 0, 0, 0, time.UTC)
+
+	// Estimate how many periods have passed, then step to the exact one
+	n := 0
+	if from.After(start) {
+		days := int(from.Sub(start).Hours() / 24)
+		switch frequency {
+		case FrequencyWeekly:
+			n = days / 7
+		case FrequencyMonthly:
+			n = days / 31
+		case FrequencyYearly:
+			n = days / 366
+		}
+	}
+	for occurrence(frequency, start, n).Before(from) {
+		n++
+	}
+	return occurrence(frequency, start, n)
+}
+
+// DueDates returns the due dates from from to to (both inclusive)
+func DueDates(frequency string, start, from, to time.Time) []time.Time {
+	var dates []time.Time
+	for due := NextDue(frequency, start, from); !due.After(to); due = NextDue(frequency, start, due.AddDate(0, 0, 1)) {
+		dates = append(dates, due)
+	}
+	return dates
+}
+
+// List returns the user's recurring expenses, active ones first and then
+// soonest due
+func List(ctx context.Context, db *db.DB, userID uuid.UUID) ([]RecurringExpense, error) {
+	rows, err := db.Pool.Query(ctx,
+		`SELECT `+recurringColumns+` FROM recurring_expenses
+		 WHERE user_id = $1
+		 ORDER BY active DESC, next_due_date, created_at`,
+		userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	expenses := []RecurringExpense{}
+	for rows.Next() {
+		r, err := scanRecurring(rows)
+		if err != nil {
+			return nil, err
+		}
+		expenses = append(expenses, r)
+	}
+	return expenses, rows.Err()
+}
+
+// ListRecurringExpenses returns the authenticated user's recurring expenses,
+// soonest due first
+func ListRecurringExpenses(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	expenses, err := List(c.Request.Context(), db, userID)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to get recurring expenses"})
+		return
+	}
+
+	var lastUpdated *time.Time
+	for i := range expenses {
+		if lastUpdated == nil || expenses[i].UpdatedAt.After(*lastUpdated) {
+			lastUpdated = &expenses[i].UpdatedAt
+		}
+	}
+	if helpers.NotModified(c, helpers.ETag(len(expenses), lastUpdated)) {
+		return
+	}
+
+	c.JSON(200, expenses)
+}
+
+// UpdateRecurringExpense changes a recurring expense's amount, description
+// or category, pauses and resumes it, or turns auto recording on or off. Resuming moves the next due date
+// up to today if it has passed.
+func UpdateRecurringExpense(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	recurringID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid recurring expense id"})
+		return
+	}
+
+	var req UpdateRecurringRequest
+	if !validation.Bind(c, &req) {
+		return
+	}
+
+	existing, err := scanRecurring(db.Pool.QueryRow(c.Request.Context(),
+		`SELECT `+recurringColumns+` FROM recurring_expenses WHERE id = $1 AND user_id = $2`,
+		recurringID, userID))
+	if err != nil {
+		c.JSON(404, gin.H{"error": "recurring expense not found"})
+		return
+	}
+
+	if req.CategoryID == nil && req.Amount == nil && req.Description == nil && req.Active == nil && req.AutoRecord == nil {
+		c.JSON(400, gin.H{"error": "no fields to update"})
+		return
+	}
+
+	params := sqlc.UpdateRecurringExpenseParams{
+		ID:          recurringID,
+		CategoryID:  req.CategoryID,
+		Description: req.Description,
+		Active:      req.Active,
+		AutoRecord:  req.AutoRecord,
+	}
+	if req.CategoryID != nil {
+		categoryOwnerID, _, err := helpers.GetCategoryOwner(c.Request.Context(), db.Pool, *req.CategoryID)
+		if err != nil {
+			c.JSON(400, gin.H{"error": "invalid category"})
+			return
+		}
+		if categoryOwnerID != userID {
+			c.JSON(403, gin.H{"error": "category does not belong to user"})
+			return
+		}
+	}
+	if req.Amount != nil {
+		amount, err := decimal.NewFromString(*req.Amount)
+		if err != nil {
+			c.JSON(400, gin.H{"error": "invalid amount format"})
+			return
+		}
+		if amount.LessThanOrEqual(decimal.Zero) {
+			c.JSON(400, gin.H{"error": "amount must be greater than 0"})
+			return
+		}
+		params.Amount = &amount
+	}
+	// Don't catch up on charges missed while paused, or record ones due
+	// before auto recording was turned on
+	skipMissed := req.Active != nil && *req.Active && !existing.Active
+	skipMissed = skipMissed || req.AutoRecord != nil && *req.AutoRecord && !existing.AutoRecord
+	if skipMissed {
+		next := NextDue(existing.Frequency, existing.StartDate, maxTime(existing.NextDueDate, time.Now()))
+		params.NextDueDate = &next
+	}
+
+	row, err := sqlc.New(db.Pool).UpdateRecurringExpense(c.Request.Context(), params)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to update recurring expense"})
+		return
+	}
+	updated := RecurringExpense(row)
+
+	c.JSON(200, updated)
+}
+
+func maxTime(a, b time.Time) time.Time {
+	if a.After(b) {
+		return a
+	}
+	return b
+}
+
+// DeleteRecurringExpense stops tracking a recurring expense. Expenses
+// already recorded for it are kept.
+func DeleteRecurringExpense(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	recurringID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid recurring expense id"})
+		return
+	}
+
+	tag, err := db.Pool.Exec(c.Request.Context(),
+		`DELETE FROM recurring_expenses WHERE id = $1 AND user_id = $2`, recurringID, userID)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to delete recurring expense"})
+		return
+	}
+	if tag.RowsAffected() == 0 {
+		c.JSON(404, gin.H{"error": "recurring expense not found"})
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "recurring expense deleted successfully"})
+}
@@ -0,0 +1,37 @@
+package recurring
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func ymd(y int, m time.Month, d int) time.Time {
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+func TestNextDue(t *testing.T) {
+	tests := []struct {
+		name      string
+		frequency string
+		start     time.Time
+		from      time.Time
+		want      time.Time
+	}{
+		{name: "before start", frequency: FrequencyMonthly, start: ymd(2024, 3, 15), from: ymd(2024, 1, 1), want: ymd(2024, 3, 15)},
+		{name: "on a due date", frequency: FrequencyMonthly, start: ymd(2024, 1, 15), from: ymd(2024, 4, 15), want: ymd(2024, 4, 15)},
+		{name: "between due dates", frequency: FrequencyMonthly, start: ymd(2024, 1, 15), from: ymd(2024, 4, 16), want: ymd(2024, 5, 15)},
+		{name: "end of month in February", frequency: FrequencyMonthly, start: ymd(2024, 1, 31), from: ymd(2024, 2, 1), want: ymd(2024, 2, 29)},
+		{name: "end of month doesn't drift", frequency: FrequencyMonthly, start: ymd(2024, 1, 31), from: ymd(2024, 3, 1), want: ymd(2024, 3, 31)},
+		{name: "weekly", frequency: FrequencyWeekly, start: ymd(2024, 1, 1), from: ymd(2024, 1, 10), want: ymd(2024, 1, 15)},
+		{name: "yearly on leap day", frequency: FrequencyYearly, start: ymd(2024, 2, 29), from: ymd(2024, 3, 1), want: ymd(2025, 2, 28)},
+		{name: "time of day ignored", frequency: FrequencyWeekly, start: ymd(2024, 1, 1).Add(15 * time.Hour), from: ymd(2024, 1, 8).Add(20 * time.Hour), want: ymd(2024, 1, 8)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, NextDue(tt.frequency, tt.start, tt.from))
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package requestid
+
+import (
+	"context"
+	"log/slog"
+)
+
+// Header carries the request ID in both directions
+const Header = "X-Request-ID"
+
+type contextKey struct{}
+
+// Valid accepts short IDs of printable ASCII so a caller can't inject
+// anything odd into logs
+func Valid(id string) bool {
+	if id == "" || len(id) > 128 {
+		return false
+	}
+	for _, r := range id {
+		if r <= ' ' || r > '~' {
+			return false
+		}
+	}
+	return true
+}
+
+// With returns ctx carrying id
+func With(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// From returns the request ID in ctx, or "" outside a request
+func From(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
+
+// Logger returns the default logger with the request ID in ctx attached,
+// or the default logger itself outside a request
+func Logger(ctx context.Context) *slog.Logger {
+	id := From(ctx)
+	if id == "" {
+		return slog.Default()
+	}
+	return slog.Default().With("request_id", id)
+}
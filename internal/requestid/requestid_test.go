@@ -0,0 +1,33 @@
+package requestid
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestContext(t *testing.T) {
+	assert.Equal(t, "", From(context.Background()))
+	assert.Equal(t, "abc", From(With(context.Background(), "abc")))
+}
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	defaultLogger := slog.Default()
+	slog.SetDefault(slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return a
+		},
+	})))
+	defer slog.SetDefault(defaultLogger)
+
+	Logger(With(context.Background(), "abc")).Error("failed")
+	Logger(context.Background()).Info("plain")
+	assert.Equal(t, "level=ERROR msg=failed request_id=abc\nlevel=INFO msg=plain\n", buf.String())
+}
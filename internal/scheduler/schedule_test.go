@@ -0,0 +1,61 @@
+package scheduler
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseInvalid(t *testing.T) {
+	for _, spec := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"5-1 * * * *",
+		"a * * * *",
+		"0 0 30 2 *",
+		"@every 500ms",
+		"@every soon",
+		"@fortnightly",
+	} {
+		_, err := Parse(spec)
+		assert.Error(t, err, spec)
+	}
+}
+
+func TestNext(t *testing.T) {
+	at := func(s string) time.Time {
+		v, err := time.Parse(time.RFC3339, s)
+		require.NoError(t, err)
+		return v
+	}
+	for _, tc := range []struct {
+		spec, from, want string
+	}{
+		{"@hourly", "2024-03-10T14:00:00Z", "2024-03-10T15:00:00Z"},
+		{"@hourly", "2024-03-10T14:59:59Z", "2024-03-10T15:00:00Z"},
+		{"@daily", "2024-12-31T23:30:00Z", "2025-01-01T00:00:00Z"},
+		{"5 0 * * *", "2024-03-10T00:05:00Z", "2024-03-11T00:05:00Z"},
+		{"*/15 9-17 * * 1-5", "2024-03-08T17:50:00Z", "2024-03-11T09:00:00Z"},
+		{"0 3 * * 7", "2024-03-10T04:00:00Z", "2024-03-17T03:00:00Z"},
+		{"0 0 29 2 *", "2024-03-01T00:00:00Z", "2028-02-29T00:00:00Z"},
+		{"0 0 31 * *", "2024-04-01T00:00:00Z", "2024-05-31T00:00:00Z"},
+		// Either day field may match when both are restricted
+		{"0 0 1 * 1", "2024-03-01T00:00:00Z", "2024-03-04T00:00:00Z"},
+		{"10,40 * * * *", "2024-03-10T14:10:00Z", "2024-03-10T14:40:00Z"},
+		{"@every 15m", "2024-03-10T14:07:00Z", "2024-03-10T14:15:00Z"},
+		// Evaluated in UTC
+		{"0 12 * * *", "2024-03-10T13:00:00+02:00", "2024-03-10T12:00:00Z"},
+	} {
+		s, err := Parse(tc.spec)
+		require.NoError(t, err, tc.spec)
+		assert.True(t, at(tc.want).Equal(s.Next(at(tc.from))), "%s from %s: got %s", tc.spec, tc.from, s.Next(at(tc.from)))
+	}
+}
@@ -0,0 +1,263 @@
+// Package scheduler runs periodic jobs, such as sending digests or purging
+// deleted rows, on cron-like schedules. Every server runs a Scheduler, and
+// the scheduled_jobs table makes sure each run happens on only one of them.
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+	"github.com/yanonymousV2/finance-manager-backend/internal/health"
+)
+
+// Job is a task run on a schedule. Timeout bounds one run: another server
+// may take the job over once it has passed.
+type Job struct {
+	Name     string
+	Spec     string
+	Schedule Schedule
+	Timeout  time.Duration
+	Run      func(ctx context.Context) error
+}
+
+// Scheduler runs jobs when they're due
+type Scheduler struct {
+	db    *db.DB
+	owner string
+	jobs  []Job
+}
+
+// New returns a Scheduler with no jobs. It identifies itself in
+// scheduled_jobs.locked_by by host name and a random suffix.
+func New(db *db.DB) *Scheduler {
+	host, _ := os.Hostname()
+	return &Scheduler{db: db, owner: host + "-" + uuid.NewString()[:8]}
+}
+
+// Add registers a job with a schedule in the form Parse reads
+func (s *Scheduler) Add(name, spec string, timeout time.Duration, run func(ctx context.Context) error) error {
+	schedule, err := Parse(spec)
+	if err != nil {
+		return fmt.Errorf("job %s: %w", name, err)
+	}
+	for _, j := range s.jobs {
+		if j.Name == name {
+			return fmt.Errorf("job %s added twice", name)
+		}
+	}
+	s.jobs = append(s.jobs, Job{Name: name, Spec: spec, Schedule: schedule, Timeout: timeout, Run: run})
+	return nil
+}
+
+// Jobs returns the registered jobs
+func (s *Scheduler) Jobs() []Job {
+	return s.jobs
+}
+
+// Run checks for due jobs every poll until ctx is cancelled, running each in
+// its own goroutine and beating heartbeat after each check. On cancellation
+// it waits for running jobs, which get the cancelled ctx, to return.
+func (s *Scheduler) Run(ctx context.Context, poll time.Duration, heartbeat *health.Heartbeat) {
+	var running sync.WaitGroup
+	defer running.Wait()
+
+	err := s.register(ctx)
+	if err != nil {
+		slog.Error("failed to register scheduled jobs", "error", err)
+	}
+	registered := err == nil
+
+	ticker := time.NewTicker(poll)
+	defer ticker.Stop()
+	busy := make(map[string]bool)
+	var mu sync.Mutex
+	for {
+		if !registered {
+			err = s.register(ctx)
+			registered = err == nil
+		}
+		if registered {
+			for _, job := range s.jobs {
+				mu.Lock()
+				skip := busy[job.Name]
+				mu.Unlock()
+				if skip {
+					continue
+				}
+				claimed, claimErr := s.claim(ctx, job)
+				if claimErr != nil {
+					err = claimErr
+					continue
+				}
+				if !claimed {
+					continue
+				}
+				mu.Lock()
+				busy[job.Name] = true
+				mu.Unlock()
+				running.Add(1)
+				go func() {
+					defer running.Done()
+					s.execute(ctx, job)
+					mu.Lock()
+					delete(busy, job.Name)
+					mu.Unlock()
+				}()
+			}
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			slog.Error("failed to check scheduled jobs", "error", err)
+		}
+		heartbeat.Beat(err)
+		err = nil
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// register adds a row for each job, scheduling its first run. A job whose
+// schedule changed is rescheduled.
+func (s *Scheduler) register(ctx context.Context) error {
+	now := time.Now()
+	for _, job := range s.jobs {
+		_, err := s.db.Pool.Exec(ctx,
+			`INSERT INTO scheduled_jobs (name, schedule, next_run_at) VALUES ($1, $2, $3)
+			 ON CONFLICT (name) DO UPDATE SET schedule = EXCLUDED.schedule, next_run_at = EXCLUDED.next_run_at
+			 WHERE scheduled_jobs.schedule <> EXCLUDED.schedule`,
+			job.Name, job.Spec, job.Schedule.Next(now))
+		if err != nil {
+			return fmt.Errorf("failed to register job %s: %w", job.Name, err)
+		}
+	}
+	return nil
+}
+
+// claim takes a due job for this server until its timeout has passed. It
+// reports false when the job isn't due or another server has it.
+func (s *Scheduler) claim(ctx context.Context, job Job) (bool, error) {
+	tag, err := s.db.Pool.Exec(ctx,
+		`UPDATE scheduled_jobs
+		 SET locked_until = NOW() + $2::interval, locked_by = $3, last_started_at = NOW()
+		 WHERE name = $1 AND next_run_at <= NOW() AND (locked_until IS NULL OR locked_until < NOW())`,
+		job.Name, job.Timeout, s.owner)
+	if err != nil {
+		return false, fmt.Errorf("failed to claim job %s: %w", job.Name, err)
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// execute runs a claimed job, then schedules its next run and releases it
+func (s *Scheduler) execute(ctx context.Context, job Job) {
+	runCtx, cancel := context.WithTimeout(ctx, job.Timeout)
+	start := time.Now()
+	slog.Debug("running scheduled job", "job", job.Name)
+	err := run(runCtx, job)
+	cancel()
+	if err != nil {
+		slog.Error("scheduled job failed", "job", job.Name, "duration", time.Since(start), "error", err)
+	} else {
+		slog.Info("scheduled job finished", "job", job.Name, "duration", time.Since(start))
+	}
+
+	var lastError *string
+	if err != nil {
+		message := err.Error()
+		lastError = &message
+	}
+	// Record the run even when shutting down, so it isn't repeated
+	_, dbErr := s.db.Pool.Exec(context.WithoutCancel(ctx),
+		`UPDATE scheduled_jobs
+		 SET next_run_at = $2, locked_until = NULL, locked_by = NULL, last_finished_at = NOW(), last_error = $3
+		 WHERE name = $1 AND locked_by = $4`,
+		job.Name, job.Schedule.Next(time.Now()), lastError, s.owner)
+	if dbErr != nil {
+		slog.Error("failed to release scheduled job", "job", job.Name, "error", dbErr)
+	}
+}
+
+// run calls job.Run, turning a panic into an error so one bad job doesn't
+// take the server down
+func run(ctx context.Context, job Job) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("panic: %v", p)
+		}
+	}()
+	return job.Run(ctx)
+}
+
+// RunNow runs a registered job straight away on this server, whether or not
+// it's due, as long as no other server is running it. Its schedule is
+// unchanged.
+func (s *Scheduler) RunNow(ctx context.Context, name string) error {
+	for _, job := range s.jobs {
+		if job.Name != name {
+			continue
+		}
+		if err := s.register(ctx); err != nil {
+			return err
+		}
+		tag, err := s.db.Pool.Exec(ctx,
+			`UPDATE scheduled_jobs
+			 SET locked_until = NOW() + $2::interval, locked_by = $3, last_started_at = NOW()
+			 WHERE name = $1 AND (locked_until IS NULL OR locked_until < NOW())`,
+			job.Name, job.Timeout, s.owner)
+		if err != nil {
+			return fmt.Errorf("failed to claim job %s: %w", name, err)
+		}
+		if tag.RowsAffected() == 0 {
+			return fmt.Errorf("job %s is running on another server", name)
+		}
+		defer func() {
+			_, err := s.db.Pool.Exec(context.WithoutCancel(ctx),
+				`UPDATE scheduled_jobs SET locked_until = NULL, locked_by = NULL, last_finished_at = NOW()
+				 WHERE name = $1 AND locked_by = $2`, job.Name, s.owner)
+			if err != nil {
+				slog.Error("failed to release scheduled job", "job", job.Name, "error", err)
+			}
+		}()
+		runCtx, cancel := context.WithTimeout(ctx, job.Timeout)
+		defer cancel()
+		return run(runCtx, job)
+	}
+	return fmt.Errorf("unknown job %q", name)
+}
+
+// Status is a job's row in scheduled_jobs
+type Status struct {
+	Name           string     `json:"name"`
+	Schedule       string     `json:"schedule"`
+	NextRunAt      time.Time  `json:"next_run_at"`
+	LockedBy       *string    `json:"locked_by,omitempty"`
+	LastStartedAt  *time.Time `json:"last_started_at,omitempty"`
+	LastFinishedAt *time.Time `json:"last_finished_at,omitempty"`
+	LastError      *string    `json:"last_error,omitempty"`
+}
+
+// Statuses returns every job's state, by name. A job is running while
+// LockedBy is set.
+func Statuses(ctx context.Context, db *db.DB) ([]Status, error) {
+	rows, err := db.Pool.Query(ctx,
+		`SELECT name, schedule, next_run_at,
+		        CASE WHEN locked_until > NOW() THEN locked_by END,
+		        last_started_at, last_finished_at, last_error
+		 FROM scheduled_jobs ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, pgx.RowToStructByPos[Status])
+}
@@ -0,0 +1,69 @@
+// Package secret encrypts credentials kept in the database, such as bank
+// and Google tokens, so a leaked backup or replica doesn't leak them. Values
+// are sealed with AES-256-GCM under TOKEN_ENCRYPTION_KEY; a sealed value
+// holds a version byte, the nonce and the ciphertext, in one column.
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+)
+
+// KeySize is the length of an encryption key
+const KeySize = 32
+
+// Version is the first byte of sealed values. Plaintext tokens stored
+// before they were encrypted never start with it.
+const Version byte = 1
+
+var errInvalid = errors.New("secret: sealed value is invalid or was sealed with another key")
+
+// Box seals and opens values with one key
+type Box struct {
+	aead cipher.AEAD
+}
+
+func New(key []byte) (*Box, error) {
+	if len(key) != KeySize {
+		return nil, fmt.Errorf("secret: key must be %d bytes, got %d", KeySize, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &Box{aead: aead}, nil
+}
+
+// Seal encrypts plaintext with a random nonce
+func (b *Box) Seal(plaintext string) []byte {
+	out := make([]byte, 1+b.aead.NonceSize(), 1+b.aead.NonceSize()+len(plaintext)+b.aead.Overhead())
+	out[0] = Version
+	rand.Read(out[1:])
+	return b.aead.Seal(out, out[1:], []byte(plaintext), nil)
+}
+
+// Open decrypts a value from Seal
+func (b *Box) Open(sealed []byte) (string, error) {
+	if !IsSealed(sealed) || len(sealed) < 1+b.aead.NonceSize() {
+		return "", errInvalid
+	}
+	nonce, ciphertext := sealed[1:1+b.aead.NonceSize()], sealed[1+b.aead.NonceSize():]
+	plaintext, err := b.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return "", errInvalid
+	}
+	return string(plaintext), nil
+}
+
+// IsSealed reports whether v came from Seal rather than being a plaintext
+// value stored before it was encrypted
+func IsSealed(v []byte) bool {
+	return len(v) > 0 && v[0] == Version
+}
@@ -0,0 +1,48 @@
+package secret
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSealOpen(t *testing.T) {
+	box, err := New(bytes.Repeat([]byte{7}, KeySize))
+	require.NoError(t, err)
+
+	sealed := box.Seal("access-sandbox-123")
+	assert.True(t, IsSealed(sealed))
+	assert.NotContains(t, string(sealed), "access-sandbox-123")
+	assert.NotEqual(t, sealed, box.Seal("access-sandbox-123"), "every seal has its own nonce")
+
+	plaintext, err := box.Open(sealed)
+	require.NoError(t, err)
+	assert.Equal(t, "access-sandbox-123", plaintext)
+
+	sealed[len(sealed)-1] ^= 1
+	_, err = box.Open(sealed)
+	assert.Error(t, err, "tampered values don't open")
+}
+
+func TestOpenOtherKey(t *testing.T) {
+	a, err := New(bytes.Repeat([]byte{1}, KeySize))
+	require.NoError(t, err)
+	b, err := New(bytes.Repeat([]byte{2}, KeySize))
+	require.NoError(t, err)
+
+	_, err = b.Open(a.Seal("token"))
+	assert.Error(t, err)
+}
+
+func TestIsSealed(t *testing.T) {
+	assert.False(t, IsSealed(nil))
+	assert.False(t, IsSealed([]byte("access-sandbox-123")))
+	assert.False(t, IsSealed([]byte("1//0gLegacyRefreshToken")))
+}
+
+func TestNewKeySize(t *testing.T) {
+	_, err := New([]byte("short"))
+	assert.EqualError(t, err, "secret: key must be 32 bytes, got 5")
+}
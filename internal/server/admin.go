@@ -0,0 +1,59 @@
+package server
+
+import (
+	"encoding/json"
+	"expvar"
+	"log/slog"
+	"net/http"
+	"net/http/pprof"
+
+	"github.com/google/uuid"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+	"github.com/yanonymousV2/finance-manager-backend/internal/group"
+)
+
+// NewAdmin builds the admin API: runtime profiles under /debug/pprof/,
+// counters such as gRPC calls under /debug/vars and maintenance tasks under
+// /admin/. It has no authentication, so it's meant
+// for its own port that only operators can reach, never the public one.
+func NewAdmin(database *db.DB) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	mux.Handle("/debug/vars", expvar.Handler())
+	mux.HandleFunc("POST /admin/balances/rebuild", rebuildBalances(database))
+	return mux
+}
+
+// rebuildBalances recomputes stored group balances from their history, for
+// the group given by ?group_id= or every group
+func rebuildBalances(database *db.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+
+		var groupID *uuid.UUID
+		if s := r.URL.Query().Get("group_id"); s != "" {
+			id, err := uuid.Parse(s)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				json.NewEncoder(w).Encode(map[string]string{"error": "invalid group_id"})
+				return
+			}
+			groupID = &id
+		}
+
+		corrected, err := group.RebuildBalances(r.Context(), database, groupID)
+		if err != nil {
+			slog.Error("failed to rebuild balances", "error", err)
+			w.WriteHeader(http.StatusInternalServerError)
+			json.NewEncoder(w).Encode(map[string]string{"error": "failed to rebuild balances"})
+			return
+		}
+		slog.Info("rebuilt group balances", "group_id", groupID, "corrected", corrected)
+		json.NewEncoder(w).Encode(map[string]int{"corrected": corrected})
+	}
+}
@@ -0,0 +1,49 @@
+package server
+
+import (
+	"encoding/json"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/openapi"
+)
+
+// swaggerUI loads Swagger UI from a CDN and points it at /openapi.json
+const swaggerUI = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <title>Finance Manager API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@5/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@5/swagger-ui-bundle.js"></script>
+  <script>
+    window.ui = SwaggerUIBundle({ url: "/openapi.json", dom_id: "#swagger-ui" });
+  </script>
+</body>
+</html>
+`
+
+// specJSON is the OpenAPI document for operations
+func specJSON() ([]byte, error) {
+	return json.Marshal(openapi.Build("Finance Manager API", "1.0.0", operations))
+}
+
+// registerDocs serves the OpenAPI document at /openapi.json and Swagger UI at
+// /docs
+func registerDocs(r *gin.Engine) {
+	spec, err := specJSON()
+	if err != nil {
+		// The document is built from static types; this is a programming error
+		panic("build OpenAPI document: " + err.Error())
+	}
+
+	r.GET("/openapi.json", func(c *gin.Context) {
+		c.Data(200, "application/json; charset=utf-8", spec)
+	})
+	r.GET("/docs", func(c *gin.Context) {
+		c.Data(200, "text/html; charset=utf-8", []byte(swaggerUI))
+	})
+}
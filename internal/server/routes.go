@@ -0,0 +1,199 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/account"
+	"github.com/yanonymousV2/finance-manager-backend/internal/auth"
+	"github.com/yanonymousV2/finance-manager-backend/internal/budget"
+	"github.com/yanonymousV2/finance-manager-backend/internal/calendar"
+	"github.com/yanonymousV2/finance-manager-backend/internal/category"
+	"github.com/yanonymousV2/finance-manager-backend/internal/dashboard"
+	"github.com/yanonymousV2/finance-manager-backend/internal/digest"
+	"github.com/yanonymousV2/finance-manager-backend/internal/expense"
+	"github.com/yanonymousV2/finance-manager-backend/internal/fxrate"
+	"github.com/yanonymousV2/finance-manager-backend/internal/graphql"
+	"github.com/yanonymousV2/finance-manager-backend/internal/group"
+	"github.com/yanonymousV2/finance-manager-backend/internal/insight"
+	"github.com/yanonymousV2/finance-manager-backend/internal/merchant"
+	"github.com/yanonymousV2/finance-manager-backend/internal/notification"
+	"github.com/yanonymousV2/finance-manager-backend/internal/ocr"
+	"github.com/yanonymousV2/finance-manager-backend/internal/openapi"
+	"github.com/yanonymousV2/finance-manager-backend/internal/personalexpense"
+	"github.com/yanonymousV2/finance-manager-backend/internal/plaid"
+	"github.com/yanonymousV2/finance-manager-backend/internal/push"
+	"github.com/yanonymousV2/finance-manager-backend/internal/recurring"
+	"github.com/yanonymousV2/finance-manager-backend/internal/settlement"
+	"github.com/yanonymousV2/finance-manager-backend/internal/sheets"
+	"github.com/yanonymousV2/finance-manager-backend/internal/webhook"
+)
+
+// operations documents every route New registers, in the same order. A test
+// checks the two agree, so a new route needs an entry here too.
+var operations = []openapi.Operation{
+	{Method: "GET", Path: "/health", Tag: "system", Summary: "Check the server and database are up", Public: true},
+	{Method: "GET", Path: "/livez", Tag: "system", Summary: "Liveness probe: the process is serving requests", Public: true},
+	{Method: "GET", Path: "/readyz", Tag: "system", Summary: "Readiness probe: the database, schema and background jobs are healthy", Public: true},
+	{Method: "GET", Path: "/openapi.json", Tag: "system", Summary: "This OpenAPI document", Public: true},
+	{Method: "GET", Path: "/docs", Tag: "system", Summary: "Swagger UI for this API", Public: true, ContentType: "text/html"},
+	{Method: "GET", Path: "/digest/unsubscribe", Tag: "digest", Summary: "Page confirming unsubscribing from email digests with the link's token", Public: true, ContentType: "text/html"},
+	{Method: "POST", Path: "/digest/unsubscribe", Tag: "digest", Summary: "One-click unsubscribe from email digests", Public: true},
+	{Method: "POST", Path: "/webhooks/plaid", Tag: "plaid", Summary: "Plaid webhook, verified by its Plaid-Verification signature", Public: true},
+	{Method: "GET", Path: "/integrations/google-sheets/callback", Tag: "integrations", Summary: "Where Google redirects after the user grants access to their sheets", Public: true},
+	{Method: "GET", Path: "/files/*key", Tag: "files", Summary: "Download a file with a signed link (local storage only)", Public: true, ContentType: "application/octet-stream"},
+
+	{Method: "POST", Path: "/auth/signup", Tag: "auth", Summary: "Sign up", Public: true, Request: auth.SignupRequest{}, Response: auth.AuthResponse{}, Status: http.StatusCreated},
+	{Method: "POST", Path: "/auth/login", Tag: "auth", Summary: "Log in", Public: true, Request: auth.LoginRequest{}, Response: auth.AuthResponse{}},
+
+	{Method: "POST", Path: "/groups", Tag: "groups", Summary: "Create a group", Request: group.CreateGroupRequest{}, Response: group.Group{}, Status: http.StatusCreated},
+	{Method: "POST", Path: "/groups/import/splitwise", Tag: "groups", Summary: "Create a group from a Splitwise CSV or JSON export (multipart/form-data)", Response: group.SplitwiseImportReport{}, Status: http.StatusCreated},
+	{Method: "POST", Path: "/groups/:id/add-member", Tag: "groups", Summary: "Add a member", Request: group.AddMemberRequest{}},
+	{Method: "POST", Path: "/groups/:id/placeholders", Tag: "groups", Summary: "Add a placeholder member", Request: group.AddPlaceholderRequest{}, Response: group.Placeholder{}, Status: http.StatusCreated},
+	{Method: "POST", Path: "/groups/:id/placeholders/:placeholderId/claim-token", Tag: "groups", Summary: "Issue a token for claiming a placeholder (group creator only)", Status: http.StatusCreated},
+	{Method: "POST", Path: "/groups/:id/placeholders/:placeholderId/claim", Tag: "groups", Summary: "Claim a placeholder's history with its claim token", Request: group.ClaimPlaceholderRequest{}},
+	{Method: "GET", Path: "/groups/:id/balances", Tag: "groups", Summary: "Members' net balances", Response: []group.Balance{}},
+	{Method: "GET", Path: "/groups/:id/stream", Tag: "groups", Summary: "Live group events (Server-Sent Events)", ContentType: "text/event-stream"},
+	{Method: "GET", Path: "/groups/:id/balances/pairwise", Tag: "groups", Summary: "Who owes whom", Response: []group.PairwiseBalance{}},
+	{Method: "GET", Path: "/groups/:id/balances/history", Tag: "groups", Summary: "A member's balance over time"},
+	{Method: "POST", Path: "/groups/:id/balances/:userId/remind", Tag: "groups", Summary: "Remind a member what they owe", Response: notification.Notification{}, Status: http.StatusCreated},
+	{Method: "GET", Path: "/groups/:id/settle-suggestions", Tag: "groups", Summary: "Fewest transfers to settle up", Response: []group.Transfer{}},
+	{Method: "POST", Path: "/groups/:id/settle-all", Tag: "groups", Summary: "Record the suggested transfers as settlements", Request: group.SettleAllRequest{}, Status: http.StatusCreated},
+	{Method: "GET", Path: "/groups/:id/dashboard", Tag: "groups", Summary: "Group spending dashboard", Response: dashboard.GroupDashboard{}},
+	{Method: "GET", Path: "/groups/:id/members/:userId/stats", Tag: "groups", Summary: "A member's spending stats", Response: group.MemberStats{}},
+	{Method: "GET", Path: "/groups/:id/settings", Tag: "groups", Summary: "Group settings", Response: group.Settings{}},
+	{Method: "PUT", Path: "/groups/:id/settings", Tag: "groups", Summary: "Update group settings", Request: group.UpdateSettingsRequest{}, Response: group.Settings{}},
+	{Method: "GET", Path: "/groups/:id/personal-sync", Tag: "groups", Summary: "Mirroring of group shares into personal expenses", Response: personalexpense.GroupSyncSettings{}},
+	{Method: "PUT", Path: "/groups/:id/personal-sync", Tag: "groups", Summary: "Turn mirroring of group shares on or off", Request: personalexpense.UpdateGroupSyncRequest{}, Response: personalexpense.GroupSyncSettings{}},
+	{Method: "DELETE", Path: "/groups/:id", Tag: "groups", Summary: "Delete a group (creator only)"},
+	{Method: "POST", Path: "/groups/:id/restore", Tag: "groups", Summary: "Restore a deleted group (creator only)"},
+
+	{Method: "POST", Path: "/expenses", Tag: "expenses", Summary: "Create a group expense", Request: expense.CreateExpenseRequest{}, Response: expense.Expense{}, Status: http.StatusCreated},
+	{Method: "POST", Path: "/expenses/bulk", Tag: "expenses", Summary: "Create several group expenses at once", Request: expense.BulkCreateExpenseRequest{}, Status: http.StatusCreated},
+	{Method: "GET", Path: "/expenses/:id", Tag: "expenses", Summary: "Get a group expense", Response: expense.Expense{}},
+	{Method: "PUT", Path: "/expenses/:id", Tag: "expenses", Summary: "Update a group expense", Request: expense.UpdateExpenseRequest{}, Response: expense.Expense{}},
+	{Method: "DELETE", Path: "/expenses/:id", Tag: "expenses", Summary: "Delete a group expense"},
+	{Method: "POST", Path: "/expenses/:id/restore", Tag: "expenses", Summary: "Restore a deleted group expense"},
+	{Method: "POST", Path: "/expenses/:id/approve", Tag: "expenses", Summary: "Approve a pending group expense"},
+	{Method: "POST", Path: "/expenses/:id/reject", Tag: "expenses", Summary: "Reject a pending group expense"},
+	{Method: "POST", Path: "/expenses/:id/comments", Tag: "expenses", Summary: "Comment on a group expense", Request: expense.CreateCommentRequest{}, Response: expense.Comment{}, Status: http.StatusCreated},
+	{Method: "GET", Path: "/expenses/:id/comments", Tag: "expenses", Summary: "A group expense's comments"},
+	{Method: "GET", Path: "/expenses/:id/history", Tag: "expenses", Summary: "A group expense's edit history", Response: []expense.Revision{}},
+	{Method: "GET", Path: "/groups/:id/expenses", Tag: "expenses", Summary: "List a group's expenses"},
+
+	{Method: "POST", Path: "/settlements", Tag: "settlements", Summary: "Record a settlement", Request: settlement.CreateSettlementRequest{}, Response: settlement.Settlement{}, Status: http.StatusCreated},
+	{Method: "POST", Path: "/settlements/:id/confirm", Tag: "settlements", Summary: "Confirm a settlement you received", Response: settlement.Settlement{}},
+	{Method: "DELETE", Path: "/settlements/:id", Tag: "settlements", Summary: "Delete a settlement"},
+	{Method: "POST", Path: "/settlements/:id/restore", Tag: "settlements", Summary: "Restore a deleted settlement"},
+	{Method: "GET", Path: "/groups/:id/settlements", Tag: "settlements", Summary: "List a group's settlements"},
+	{Method: "GET", Path: "/groups/:id/members/:userId/unpaid-expenses", Tag: "settlements", Summary: "Expenses a member still owes for"},
+
+	{Method: "GET", Path: "/notifications", Tag: "notifications", Summary: "List notifications"},
+	{Method: "POST", Path: "/notifications/:id/read", Tag: "notifications", Summary: "Mark a notification read"},
+	{Method: "GET", Path: "/notifications/preferences", Tag: "notifications", Summary: "Notification channel preferences", Response: []notification.Preference{}},
+	{Method: "PUT", Path: "/notifications/preferences/:type", Tag: "notifications", Summary: "Set the channels for a notification type", Request: notification.UpdatePreferenceRequest{}, Response: notification.Preference{}},
+	{Method: "POST", Path: "/devices", Tag: "notifications", Summary: "Register a device for push notifications", Request: push.RegisterDeviceRequest{}, Response: push.Device{}, Status: http.StatusCreated},
+	{Method: "GET", Path: "/devices", Tag: "notifications", Summary: "List devices registered for push notifications", Response: []push.Device{}},
+	{Method: "DELETE", Path: "/devices/:id", Tag: "notifications", Summary: "Stop push notifications to a device"},
+
+	{Method: "GET", Path: "/rates", Tag: "rates", Summary: "Exchange rates from a currency on a date", Response: fxrate.RatesResponse{}},
+
+	{Method: "POST", Path: "/plaid/link-token", Tag: "plaid", Summary: "Create a link token for linking a bank in Plaid Link", Response: plaid.LinkToken{}, Status: http.StatusCreated},
+	{Method: "POST", Path: "/plaid/items", Tag: "plaid", Summary: "Link the bank connected in Plaid Link", Request: plaid.LinkItemRequest{}, Response: plaid.Item{}, Status: http.StatusCreated},
+	{Method: "GET", Path: "/plaid/items", Tag: "plaid", Summary: "List linked banks and their accounts", Response: []plaid.Item{}},
+	{Method: "POST", Path: "/plaid/items/:id/link-token", Tag: "plaid", Summary: "Create a link token for logging in to a linked bank again", Response: plaid.LinkToken{}, Status: http.StatusCreated},
+	{Method: "DELETE", Path: "/plaid/items/:id", Tag: "plaid", Summary: "Unlink a bank"},
+
+	{Method: "GET", Path: "/integrations/google-sheets", Tag: "integrations", Summary: "The Google Sheets export and its status", Response: sheets.Integration{}},
+	{Method: "PUT", Path: "/integrations/google-sheets", Tag: "integrations", Summary: "Choose the spreadsheet, what to export and how often", Request: sheets.UpdateIntegrationRequest{}, Response: sheets.Integration{}},
+	{Method: "DELETE", Path: "/integrations/google-sheets", Tag: "integrations", Summary: "Disconnect Google Sheets"},
+	{Method: "POST", Path: "/integrations/google-sheets/connect", Tag: "integrations", Summary: "Start connecting a Google account", Response: sheets.ConnectResponse{}},
+	{Method: "POST", Path: "/integrations/google-sheets/export", Tag: "integrations", Summary: "Export the expenses added since the last export now", Response: sheets.ExportResult{}},
+
+	{Method: "GET", Path: "/digest/preferences", Tag: "digest", Summary: "Email digest preferences", Response: digest.Preferences{}},
+	{Method: "PUT", Path: "/digest/preferences", Tag: "digest", Summary: "Update email digest preferences", Request: digest.UpdatePreferencesRequest{}, Response: digest.Preferences{}},
+	{Method: "GET", Path: "/digest/preview", Tag: "digest", Summary: "Preview the next email digest", Response: digest.Digest{}},
+
+	{Method: "POST", Path: "/webhooks", Tag: "webhooks", Summary: "Register a webhook endpoint", Request: webhook.CreateEndpointRequest{}, Response: webhook.Endpoint{}, Status: http.StatusCreated},
+	{Method: "GET", Path: "/webhooks", Tag: "webhooks", Summary: "List webhook endpoints", Response: []webhook.Endpoint{}},
+	{Method: "PUT", Path: "/webhooks/:id", Tag: "webhooks", Summary: "Update a webhook endpoint", Request: webhook.UpdateEndpointRequest{}, Response: webhook.Endpoint{}},
+	{Method: "DELETE", Path: "/webhooks/:id", Tag: "webhooks", Summary: "Delete a webhook endpoint"},
+	{Method: "GET", Path: "/webhooks/:id/deliveries", Tag: "webhooks", Summary: "A webhook endpoint's delivery log"},
+	{Method: "POST", Path: "/webhooks/:id/deliveries/:deliveryId/retry", Tag: "webhooks", Summary: "Retry a failed delivery"},
+
+	{Method: "POST", Path: "/budget", Tag: "budget", Summary: "Set a budget", Request: budget.SetBudgetRequest{}, Response: budget.Budget{}},
+	{Method: "GET", Path: "/budget", Tag: "budget", Summary: "Get a budget", Response: budget.Budget{}},
+	{Method: "DELETE", Path: "/budget", Tag: "budget", Summary: "Delete a budget"},
+	{Method: "GET", Path: "/budgets", Tag: "budget", Summary: "List budgets", Response: []budget.Budget{}},
+	{Method: "GET", Path: "/budget/suggestions", Tag: "budget", Summary: "Suggest a budget from past spending", Response: budget.Suggestion{}},
+	{Method: "POST", Path: "/budget/suggestions/accept", Tag: "budget", Summary: "Set the suggested budget"},
+
+	{Method: "POST", Path: "/categories", Tag: "categories", Summary: "Create a category", Request: category.CreateCategoryRequest{}, Response: category.ExpenseCategory{}, Status: http.StatusCreated},
+	{Method: "GET", Path: "/categories", Tag: "categories", Summary: "List categories", Response: []category.ExpenseCategory{}},
+	{Method: "POST", Path: "/categories/defaults", Tag: "categories", Summary: "Create the default categories"},
+	{Method: "PUT", Path: "/categories/reorder", Tag: "categories", Summary: "Reorder and pin categories", Request: category.ReorderCategoriesRequest{}},
+	{Method: "PUT", Path: "/categories/:id", Tag: "categories", Summary: "Update a category", Request: category.UpdateCategoryRequest{}, Response: category.ExpenseCategory{}},
+	{Method: "DELETE", Path: "/categories/:id", Tag: "categories", Summary: "Delete a category"},
+	{Method: "POST", Path: "/categories/:id/restore", Tag: "categories", Summary: "Restore a deleted category", Response: category.ExpenseCategory{}},
+	{Method: "POST", Path: "/categories/:id/archive", Tag: "categories", Summary: "Archive a category", Response: category.ExpenseCategory{}},
+	{Method: "POST", Path: "/categories/:id/unarchive", Tag: "categories", Summary: "Unarchive a category", Response: category.ExpenseCategory{}},
+	{Method: "GET", Path: "/categories/:id/trends", Tag: "categories", Summary: "A category's monthly spending", Response: dashboard.CategoryDetail{}},
+
+	{Method: "POST", Path: "/accounts", Tag: "accounts", Summary: "Create an account", Request: account.CreateAccountRequest{}, Response: account.Account{}, Status: http.StatusCreated},
+	{Method: "GET", Path: "/accounts", Tag: "accounts", Summary: "List accounts", Response: []account.Account{}},
+	{Method: "GET", Path: "/accounts/:id", Tag: "accounts", Summary: "Get an account", Response: account.Account{}},
+	{Method: "PUT", Path: "/accounts/:id", Tag: "accounts", Summary: "Update an account", Request: account.UpdateAccountRequest{}, Response: account.Account{}},
+	{Method: "DELETE", Path: "/accounts/:id", Tag: "accounts", Summary: "Delete an account"},
+	{Method: "GET", Path: "/accounts/:id/ledger", Tag: "accounts", Summary: "An account's running balance"},
+
+	{Method: "GET", Path: "/merchants", Tag: "merchants", Summary: "List merchants", Response: []merchant.Merchant{}},
+	{Method: "PUT", Path: "/merchants/:id", Tag: "merchants", Summary: "Rename or merge a merchant", Request: merchant.UpdateMerchantRequest{}, Response: merchant.Merchant{}},
+	{Method: "POST", Path: "/merchants/:id/aliases", Tag: "merchants", Summary: "Add a merchant alias", Request: merchant.CreateAliasRequest{}, Response: merchant.Alias{}, Status: http.StatusCreated},
+	{Method: "DELETE", Path: "/merchants/:id/aliases/:aliasId", Tag: "merchants", Summary: "Delete a merchant alias"},
+
+	{Method: "POST", Path: "/personal-expenses", Tag: "personal-expenses", Summary: "Create a personal expense", Request: personalexpense.CreateExpenseRequest{}, Response: personalexpense.ExpenseResponse{}, Status: http.StatusCreated},
+	{Method: "POST", Path: "/personal-expenses/import", Tag: "personal-expenses", Summary: "Import personal expenses from a CSV upload (multipart/form-data)", Response: personalexpense.ImportReport{}},
+	{Method: "POST", Path: "/personal-expenses/import/ofx", Tag: "personal-expenses", Summary: "Import an OFX or QFX bank statement into an account (multipart/form-data)", Response: personalexpense.OFXImportReport{}},
+	{Method: "GET", Path: "/personal-expenses/import/profiles", Tag: "personal-expenses", Summary: "List saved and built-in CSV import profiles", Response: []personalexpense.ImportProfile{}},
+	{Method: "POST", Path: "/personal-expenses/import/profiles", Tag: "personal-expenses", Summary: "Save a CSV import profile", Request: personalexpense.CreateImportProfileRequest{}, Response: personalexpense.ImportProfile{}, Status: http.StatusCreated},
+	{Method: "PUT", Path: "/personal-expenses/import/profiles/:id", Tag: "personal-expenses", Summary: "Update a saved CSV import profile", Request: personalexpense.UpdateImportProfileRequest{}, Response: personalexpense.ImportProfile{}},
+	{Method: "DELETE", Path: "/personal-expenses/import/profiles/:id", Tag: "personal-expenses", Summary: "Delete a saved CSV import profile"},
+	{Method: "POST", Path: "/personal-expenses/quick", Tag: "personal-expenses", Summary: "Parse a one-line expense into a draft", Request: personalexpense.QuickEntryRequest{}, Response: personalexpense.QuickDraft{}},
+	{Method: "GET", Path: "/personal-expenses", Tag: "personal-expenses", Summary: "List personal expenses"},
+	{Method: "GET", Path: "/personal-expenses/search", Tag: "personal-expenses", Summary: "Search personal expenses"},
+	{Method: "GET", Path: "/personal-expenses/stats", Tag: "personal-expenses", Summary: "Personal expense statistics"},
+	{Method: "GET", Path: "/personal-expenses/export", Tag: "personal-expenses", Summary: "Export personal expenses as CSV or JSON", ContentType: "text/csv"},
+	{Method: "GET", Path: "/personal-expenses/:id", Tag: "personal-expenses", Summary: "Get a personal expense", Response: personalexpense.PersonalExpense{}},
+	{Method: "PUT", Path: "/personal-expenses/:id", Tag: "personal-expenses", Summary: "Update a personal expense", Request: personalexpense.UpdateExpenseRequest{}, Response: personalexpense.ExpenseResponse{}},
+	{Method: "DELETE", Path: "/personal-expenses/:id", Tag: "personal-expenses", Summary: "Delete a personal expense"},
+	{Method: "POST", Path: "/personal-expenses/:id/receipts", Tag: "personal-expenses", Summary: "Upload a receipt (multipart/form-data)", Response: personalexpense.Receipt{}, Status: http.StatusCreated},
+	{Method: "GET", Path: "/personal-expenses/:id/receipts", Tag: "personal-expenses", Summary: "List an expense's receipts", Response: []personalexpense.Receipt{}},
+	{Method: "GET", Path: "/personal-expenses/:id/receipts/:receiptId", Tag: "personal-expenses", Summary: "Redirect to a receipt's download link", Status: http.StatusFound},
+	{Method: "DELETE", Path: "/personal-expenses/:id/receipts/:receiptId", Tag: "personal-expenses", Summary: "Delete a receipt"},
+	{Method: "GET", Path: "/attachments/:id/extraction", Tag: "personal-expenses", Summary: "What OCR read off a receipt, with a draft of its expense", Response: ocr.ExtractionResponse{}},
+
+	{Method: "GET", Path: "/dashboard/monthly", Tag: "dashboard", Summary: "Monthly spending against the budget", Response: dashboard.MonthlyDashboard{}},
+	{Method: "GET", Path: "/dashboard/period", Tag: "dashboard", Summary: "Spending in a budget period", Response: dashboard.PeriodDashboard{}},
+	{Method: "GET", Path: "/dashboard/compare", Tag: "dashboard", Summary: "A month against the month before", Response: dashboard.MonthComparison{}},
+	{Method: "GET", Path: "/dashboard/trends", Tag: "dashboard", Summary: "Spending per day, week or month", Response: dashboard.Trends{}},
+	{Method: "GET", Path: "/dashboard/merchants", Tag: "dashboard", Summary: "Top merchants in a month"},
+	{Method: "GET", Path: "/dashboard/heatmap", Tag: "dashboard", Summary: "Spending per day of a year"},
+	{Method: "GET", Path: "/dashboard/forecast", Tag: "dashboard", Summary: "Forecast a month's spending", Response: dashboard.Forecast{}},
+	{Method: "GET", Path: "/dashboard/weekly", Tag: "dashboard", Summary: "A week's spending against last week", Response: dashboard.WeeklyDashboard{}},
+	{Method: "GET", Path: "/dashboard/score", Tag: "dashboard", Summary: "Budget adherence score", Response: dashboard.BudgetScore{}},
+
+	{Method: "GET", Path: "/recurring-expenses", Tag: "recurring", Summary: "List recurring expenses", Response: []recurring.RecurringExpense{}},
+	{Method: "PUT", Path: "/recurring-expenses/:id", Tag: "recurring", Summary: "Update, pause or resume a recurring expense", Request: recurring.UpdateRecurringRequest{}, Response: recurring.RecurringExpense{}},
+	{Method: "DELETE", Path: "/recurring-expenses/:id", Tag: "recurring", Summary: "Delete a recurring expense"},
+	{Method: "GET", Path: "/subscriptions/detected", Tag: "recurring", Summary: "Subscriptions detected in spending", Response: recurring.DetectedSubscriptions{}},
+	{Method: "POST", Path: "/subscriptions/detected/:id/confirm", Tag: "recurring", Summary: "Track a detected subscription as a recurring expense", Response: recurring.RecurringExpense{}, Status: http.StatusCreated},
+
+	{Method: "GET", Path: "/calendar/upcoming", Tag: "calendar", Summary: "Upcoming bills and budget periods", Response: calendar.Upcoming{}},
+
+	{Method: "GET", Path: "/insights", Tag: "insights", Summary: "Unusual spending"},
+	{Method: "POST", Path: "/insights/:id/dismiss", Tag: "insights", Summary: "Dismiss an insight", Response: insight.Insight{}},
+	{Method: "POST", Path: "/insights/:id/acknowledge", Tag: "insights", Summary: "Acknowledge an insight", Response: insight.Insight{}},
+
+	{Method: "GET", Path: "/reports/monthly.pdf", Tag: "reports", Summary: "Monthly statement as a PDF", ContentType: "application/pdf"},
+
+	{Method: "POST", Path: "/graphql", Tag: "graphql", Summary: "Run a GraphQL query against internal/graphql/schema.graphqls", Request: graphql.Request{}},
+}
@@ -0,0 +1,315 @@
+package server
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/account"
+	"github.com/yanonymousV2/finance-manager-backend/internal/auth"
+	"github.com/yanonymousV2/finance-manager-backend/internal/budget"
+	"github.com/yanonymousV2/finance-manager-backend/internal/calendar"
+	"github.com/yanonymousV2/finance-manager-backend/internal/category"
+	"github.com/yanonymousV2/finance-manager-backend/internal/dashboard"
+	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+	"github.com/yanonymousV2/finance-manager-backend/internal/digest"
+	"github.com/yanonymousV2/finance-manager-backend/internal/expense"
+	"github.com/yanonymousV2/finance-manager-backend/internal/fxrate"
+	"github.com/yanonymousV2/finance-manager-backend/internal/graphql"
+	"github.com/yanonymousV2/finance-manager-backend/internal/group"
+	"github.com/yanonymousV2/finance-manager-backend/internal/health"
+	"github.com/yanonymousV2/finance-manager-backend/internal/insight"
+	"github.com/yanonymousV2/finance-manager-backend/internal/merchant"
+	"github.com/yanonymousV2/finance-manager-backend/internal/middleware"
+	"github.com/yanonymousV2/finance-manager-backend/internal/notification"
+	"github.com/yanonymousV2/finance-manager-backend/internal/ocr"
+	"github.com/yanonymousV2/finance-manager-backend/internal/personalexpense"
+	"github.com/yanonymousV2/finance-manager-backend/internal/plaid"
+	"github.com/yanonymousV2/finance-manager-backend/internal/push"
+	"github.com/yanonymousV2/finance-manager-backend/internal/recurring"
+	"github.com/yanonymousV2/finance-manager-backend/internal/settlement"
+	"github.com/yanonymousV2/finance-manager-backend/internal/sheets"
+	"github.com/yanonymousV2/finance-manager-backend/internal/storage"
+	"github.com/yanonymousV2/finance-manager-backend/internal/stream"
+	"github.com/yanonymousV2/finance-manager-backend/internal/webhook"
+)
+
+// Deps is everything the API's handlers need
+type Deps struct {
+	DB    *db.DB
+	Store storage.Storage
+	Auth  *auth.AuthService
+	// Groups defaults to a Service over DB
+	Groups *group.Service
+	// Expenses defaults to a Service over DB
+	Expenses          *expense.Service
+	DefaultCategories []category.Default
+	JWTSecret         string
+	// Streams sends group events to /groups/:id/stream; without it the
+	// endpoint is unavailable
+	Streams *stream.Hub
+	// Rates looks up exchange rates for /rates and for amounts sent in a
+	// foreign currency without fx_rate; without it fx_rate is required
+	Rates *fxrate.Service
+	// Plaid links bank accounts; without it those endpoints are unavailable
+	Plaid *plaid.Client
+	// Sheets exports expenses to Google Sheets; without it those endpoints
+	// are unavailable
+	Sheets *sheets.Client
+	// OCR reads uploaded receipts; without it they aren't read
+	OCR ocr.Provider
+	// Ready holds the readiness checks behind /readyz; with none it's
+	// always ready
+	Ready *health.Checker
+}
+
+// New builds the API with every route registered
+func New(deps Deps) http.Handler {
+	if deps.Groups == nil {
+		deps.Groups = group.NewService(group.NewRepository(deps.DB))
+	}
+	if deps.Expenses == nil {
+		deps.Expenses = expense.NewService(expense.NewRepository(deps.DB))
+	}
+	if deps.Ready == nil {
+		deps.Ready = health.NewChecker(time.Second)
+	}
+
+	// gin.Default would add gin's own access log next to RequestLogger
+	r := gin.New()
+	r.Use(gin.Recovery())
+	// Let handlers that pass the gin.Context as a context reach the
+	// request's values, such as its ID
+	r.ContextWithFallback = true
+	r.Use(middleware.RequestID())
+	r.Use(middleware.RequestLogger())
+	r.Use(middleware.CORS())
+
+	// Health check endpoint
+	r.GET("/health", func(c *gin.Context) {
+		// Check database connectivity
+		if err := deps.DB.Pool.Ping(c.Request.Context()); err != nil {
+			c.JSON(503, gin.H{"status": "unhealthy", "database": "disconnected"})
+			return
+		}
+		c.JSON(200, gin.H{"status": "healthy", "database": "connected"})
+	})
+
+	// Liveness only says the process is serving requests, so orchestrators
+	// don't restart it over a dependency outage; readiness checks those
+	r.GET("/livez", func(c *gin.Context) {
+		c.JSON(200, gin.H{"status": health.StatusOK})
+	})
+	r.GET("/readyz", func(c *gin.Context) {
+		ready, checks := deps.Ready.Run(c.Request.Context())
+		if !ready {
+			c.JSON(503, gin.H{"status": health.StatusFailing, "checks": checks})
+			return
+		}
+		c.JSON(200, gin.H{"status": health.StatusOK, "checks": checks})
+	})
+
+	registerDocs(r)
+
+	// Unsubscribe links in digest emails work without logging in
+	r.GET("/digest/unsubscribe", digest.ConfirmUnsubscribe)
+	r.POST("/digest/unsubscribe", func(c *gin.Context) { digest.Unsubscribe(c, deps.DB) })
+
+	// Plaid calls this with its own signature instead of a token
+	r.POST("/webhooks/plaid", func(c *gin.Context) { plaid.HandleWebhook(c, deps.DB, deps.Plaid) })
+
+	// Google redirects here after the user grants access, without our token
+	r.GET("/integrations/google-sheets/callback", func(c *gin.Context) { sheets.Callback(c, deps.DB, deps.Sheets) })
+
+	// Signed file downloads for local storage
+	if local, ok := deps.Store.(*storage.Local); ok {
+		r.GET("/files/*key", local.ServeFile)
+	}
+
+	// Auth routes with rate limiting
+	authLimited := r.Group("/auth")
+	authLimited.Use(middleware.RateLimiter())
+	{
+		authLimited.POST("/signup", func(c *gin.Context) { auth.Signup(c, deps.Auth) })
+		authLimited.POST("/login", func(c *gin.Context) { auth.Login(c, deps.Auth) })
+	}
+
+	// Protected routes
+	protected := r.Group("/")
+	protected.Use(middleware.JWTAuth(deps.JWTSecret))
+	{
+		// Groups
+		protected.POST("/groups", func(c *gin.Context) { group.CreateGroup(c, deps.Groups) })
+		protected.POST("/groups/import/splitwise", func(c *gin.Context) { group.ImportSplitwise(c, deps.DB) })
+		protected.POST("/groups/:id/add-member", func(c *gin.Context) { group.AddMember(c, deps.Groups) })
+		protected.POST("/groups/:id/placeholders", func(c *gin.Context) { group.AddPlaceholderMember(c, deps.DB) })
+		protected.POST("/groups/:id/placeholders/:placeholderId/claim-token", func(c *gin.Context) { group.IssueClaimToken(c, deps.DB) })
+		protected.POST("/groups/:id/placeholders/:placeholderId/claim", func(c *gin.Context) { group.ClaimPlaceholder(c, deps.DB) })
+		protected.GET("/groups/:id/balances", func(c *gin.Context) { group.GetBalances(c, deps.Groups) })
+		protected.GET("/groups/:id/stream", func(c *gin.Context) { stream.GroupStream(c, deps.DB, deps.Streams) })
+		protected.GET("/groups/:id/balances/pairwise", func(c *gin.Context) { group.GetPairwiseBalances(c, deps.Groups) })
+		protected.GET("/groups/:id/balances/history", func(c *gin.Context) { group.GetBalanceHistory(c, deps.DB) })
+		protected.POST("/groups/:id/balances/:userId/remind", func(c *gin.Context) { group.RemindDebtor(c, deps.DB) })
+		protected.GET("/groups/:id/settle-suggestions", func(c *gin.Context) { group.GetSettleSuggestions(c, deps.DB) })
+		protected.POST("/groups/:id/settle-all", func(c *gin.Context) { group.SettleAll(c, deps.DB) })
+		protected.GET("/groups/:id/dashboard", func(c *gin.Context) { dashboard.GetGroupDashboard(c, deps.DB.Reader()) })
+		protected.GET("/groups/:id/members/:userId/stats", func(c *gin.Context) { group.GetMemberStats(c, deps.DB) })
+		protected.GET("/groups/:id/settings", func(c *gin.Context) { group.GetGroupSettings(c, deps.DB) })
+		protected.PUT("/groups/:id/settings", func(c *gin.Context) { group.UpdateGroupSettings(c, deps.DB) })
+		protected.GET("/groups/:id/personal-sync", func(c *gin.Context) { personalexpense.GetGroupSync(c, deps.DB) })
+		protected.PUT("/groups/:id/personal-sync", func(c *gin.Context) { personalexpense.UpdateGroupSync(c, deps.DB) })
+		protected.DELETE("/groups/:id", func(c *gin.Context) { group.DeleteGroup(c, deps.DB) })
+		protected.POST("/groups/:id/restore", func(c *gin.Context) { group.RestoreGroup(c, deps.DB) })
+
+		// Group Expenses
+		protected.POST("/expenses", func(c *gin.Context) { expense.CreateExpense(c, deps.Expenses) })
+		protected.POST("/expenses/bulk", func(c *gin.Context) { expense.BulkCreateExpenses(c, deps.Expenses) })
+		protected.GET("/expenses/:id", func(c *gin.Context) { expense.GetExpense(c, deps.DB) })
+		protected.PUT("/expenses/:id", func(c *gin.Context) { expense.UpdateExpense(c, deps.DB) })
+		protected.DELETE("/expenses/:id", func(c *gin.Context) { expense.DeleteExpense(c, deps.DB) })
+		protected.POST("/expenses/:id/restore", func(c *gin.Context) { expense.RestoreExpense(c, deps.DB) })
+		protected.POST("/expenses/:id/approve", func(c *gin.Context) { expense.ApproveExpense(c, deps.DB) })
+		protected.POST("/expenses/:id/reject", func(c *gin.Context) { expense.RejectExpense(c, deps.DB) })
+		protected.POST("/expenses/:id/comments", func(c *gin.Context) { expense.CreateComment(c, deps.DB) })
+		protected.GET("/expenses/:id/comments", func(c *gin.Context) { expense.GetComments(c, deps.DB) })
+		protected.GET("/expenses/:id/history", func(c *gin.Context) { expense.GetExpenseHistory(c, deps.DB) })
+		protected.GET("/groups/:id/expenses", func(c *gin.Context) { expense.GetGroupExpenses(c, deps.DB) })
+
+		// Settlements
+		protected.POST("/settlements", func(c *gin.Context) { settlement.CreateSettlement(c, deps.DB, deps.Rates) })
+		protected.POST("/settlements/:id/confirm", func(c *gin.Context) { settlement.ConfirmSettlement(c, deps.DB) })
+		protected.DELETE("/settlements/:id", func(c *gin.Context) { settlement.DeleteSettlement(c, deps.DB) })
+		protected.POST("/settlements/:id/restore", func(c *gin.Context) { settlement.RestoreSettlement(c, deps.DB) })
+		protected.GET("/groups/:id/settlements", func(c *gin.Context) { settlement.ListSettlements(c, deps.DB) })
+		protected.GET("/groups/:id/members/:userId/unpaid-expenses", func(c *gin.Context) { settlement.GetUnpaidExpenses(c, deps.DB) })
+
+		// Notifications
+		protected.GET("/notifications", func(c *gin.Context) { notification.ListNotifications(c, deps.DB) })
+		protected.POST("/notifications/:id/read", func(c *gin.Context) { notification.MarkRead(c, deps.DB) })
+		protected.GET("/notifications/preferences", func(c *gin.Context) { notification.GetPreferences(c, deps.DB) })
+		protected.PUT("/notifications/preferences/:type", func(c *gin.Context) { notification.UpdatePreference(c, deps.DB) })
+		protected.POST("/devices", func(c *gin.Context) { push.RegisterDevice(c, deps.DB) })
+		protected.GET("/devices", func(c *gin.Context) { push.ListDevices(c, deps.DB) })
+		protected.DELETE("/devices/:id", func(c *gin.Context) { push.DeleteDevice(c, deps.DB) })
+
+		// Exchange rates
+		protected.GET("/rates", func(c *gin.Context) { fxrate.GetRates(c, deps.DB, deps.Rates) })
+
+		// Bank connections
+		protected.POST("/plaid/link-token", func(c *gin.Context) { plaid.CreateLinkToken(c, deps.Plaid) })
+		protected.POST("/plaid/items", func(c *gin.Context) { plaid.LinkItem(c, deps.DB, deps.Plaid) })
+		protected.GET("/plaid/items", func(c *gin.Context) { plaid.ListItems(c, deps.DB) })
+		protected.POST("/plaid/items/:id/link-token", func(c *gin.Context) { plaid.CreateRelinkToken(c, deps.DB, deps.Plaid) })
+		protected.DELETE("/plaid/items/:id", func(c *gin.Context) { plaid.DeleteItem(c, deps.DB, deps.Plaid) })
+
+		protected.GET("/integrations/google-sheets", func(c *gin.Context) { sheets.GetIntegration(c, deps.DB) })
+		protected.PUT("/integrations/google-sheets", func(c *gin.Context) { sheets.UpdateIntegration(c, deps.DB, deps.Sheets) })
+		protected.DELETE("/integrations/google-sheets", func(c *gin.Context) { sheets.Disconnect(c, deps.DB, deps.Sheets) })
+		protected.POST("/integrations/google-sheets/connect", func(c *gin.Context) { sheets.Connect(c, deps.DB, deps.Sheets) })
+		protected.POST("/integrations/google-sheets/export", func(c *gin.Context) { sheets.ExportNow(c, deps.DB, deps.Sheets) })
+
+		// Email digests
+		protected.GET("/digest/preferences", func(c *gin.Context) { digest.GetPreferences(c, deps.DB) })
+		protected.PUT("/digest/preferences", func(c *gin.Context) { digest.UpdatePreferences(c, deps.DB) })
+		protected.GET("/digest/preview", func(c *gin.Context) { digest.PreviewDigest(c, deps.DB) })
+
+		// Webhooks
+		protected.POST("/webhooks", func(c *gin.Context) { webhook.CreateEndpoint(c, deps.DB) })
+		protected.GET("/webhooks", func(c *gin.Context) { webhook.ListEndpoints(c, deps.DB) })
+		protected.PUT("/webhooks/:id", func(c *gin.Context) { webhook.UpdateEndpoint(c, deps.DB) })
+		protected.DELETE("/webhooks/:id", func(c *gin.Context) { webhook.DeleteEndpoint(c, deps.DB) })
+		protected.GET("/webhooks/:id/deliveries", func(c *gin.Context) { webhook.ListDeliveries(c, deps.DB) })
+		protected.POST("/webhooks/:id/deliveries/:deliveryId/retry", func(c *gin.Context) { webhook.RetryDelivery(c, deps.DB) })
+
+		// Personal Finance - Budget
+		protected.POST("/budget", func(c *gin.Context) { budget.SetBudget(c, deps.DB) })
+		protected.GET("/budget", func(c *gin.Context) { budget.GetBudget(c, deps.DB) })
+		protected.DELETE("/budget", func(c *gin.Context) { budget.DeleteBudget(c, deps.DB) })
+		protected.GET("/budgets", func(c *gin.Context) { budget.ListBudgets(c, deps.DB) })
+		protected.GET("/budget/suggestions", func(c *gin.Context) { budget.GetBudgetSuggestion(c, deps.DB) })
+		protected.POST("/budget/suggestions/accept", func(c *gin.Context) { budget.AcceptBudgetSuggestion(c, deps.DB) })
+
+		// Personal Finance - Categories
+		protected.POST("/categories", func(c *gin.Context) { category.CreateCategory(c, deps.DB) })
+		protected.GET("/categories", func(c *gin.Context) { category.ListCategories(c, deps.DB) })
+		protected.POST("/categories/defaults", func(c *gin.Context) { category.SeedDefaultCategories(c, deps.DB, deps.DefaultCategories) })
+		protected.PUT("/categories/reorder", func(c *gin.Context) { category.ReorderCategories(c, deps.DB) })
+		protected.PUT("/categories/:id", func(c *gin.Context) { category.UpdateCategory(c, deps.DB) })
+		protected.DELETE("/categories/:id", func(c *gin.Context) { category.DeleteCategory(c, deps.DB) })
+		protected.POST("/categories/:id/restore", func(c *gin.Context) { category.RestoreCategory(c, deps.DB) })
+		protected.POST("/categories/:id/archive", func(c *gin.Context) { category.ArchiveCategory(c, deps.DB) })
+		protected.POST("/categories/:id/unarchive", func(c *gin.Context) { category.UnarchiveCategory(c, deps.DB) })
+		protected.GET("/categories/:id/trends", func(c *gin.Context) { dashboard.GetCategoryTrends(c, deps.DB.Reader()) })
+
+		// Personal Finance - Accounts
+		protected.POST("/accounts", func(c *gin.Context) { account.CreateAccount(c, deps.DB) })
+		protected.GET("/accounts", func(c *gin.Context) { account.ListAccounts(c, deps.DB) })
+		protected.GET("/accounts/:id", func(c *gin.Context) { account.GetAccount(c, deps.DB) })
+		protected.PUT("/accounts/:id", func(c *gin.Context) { account.UpdateAccount(c, deps.DB) })
+		protected.DELETE("/accounts/:id", func(c *gin.Context) { account.DeleteAccount(c, deps.DB) })
+		protected.GET("/accounts/:id/ledger", func(c *gin.Context) { account.GetLedger(c, deps.DB) })
+
+		// Personal Finance - Merchants
+		protected.GET("/merchants", func(c *gin.Context) { merchant.ListMerchants(c, deps.DB) })
+		protected.PUT("/merchants/:id", func(c *gin.Context) { merchant.UpdateMerchant(c, deps.DB) })
+		protected.POST("/merchants/:id/aliases", func(c *gin.Context) { merchant.CreateAlias(c, deps.DB) })
+		protected.DELETE("/merchants/:id/aliases/:aliasId", func(c *gin.Context) { merchant.DeleteAlias(c, deps.DB) })
+
+		// Personal Finance - Expenses
+		protected.POST("/personal-expenses", func(c *gin.Context) { personalexpense.CreateExpense(c, deps.DB, deps.Rates) })
+		protected.POST("/personal-expenses/import", func(c *gin.Context) { personalexpense.ImportExpenses(c, deps.DB) })
+		protected.POST("/personal-expenses/import/ofx", func(c *gin.Context) { personalexpense.ImportOFX(c, deps.DB, deps.Rates) })
+		protected.GET("/personal-expenses/import/profiles", func(c *gin.Context) { personalexpense.ListImportProfiles(c, deps.DB) })
+		protected.POST("/personal-expenses/import/profiles", func(c *gin.Context) { personalexpense.CreateImportProfile(c, deps.DB) })
+		protected.PUT("/personal-expenses/import/profiles/:id", func(c *gin.Context) { personalexpense.UpdateImportProfile(c, deps.DB) })
+		protected.DELETE("/personal-expenses/import/profiles/:id", func(c *gin.Context) { personalexpense.DeleteImportProfile(c, deps.DB) })
+		protected.POST("/personal-expenses/quick", func(c *gin.Context) { personalexpense.QuickEntry(c, deps.DB) })
+		protected.GET("/personal-expenses", func(c *gin.Context) { personalexpense.ListExpenses(c, deps.DB) })
+		protected.GET("/personal-expenses/search", func(c *gin.Context) { personalexpense.SearchExpenses(c, deps.DB) })
+		protected.GET("/personal-expenses/stats", func(c *gin.Context) { personalexpense.GetExpenseStats(c, deps.DB) })
+		protected.GET("/personal-expenses/export", func(c *gin.Context) { personalexpense.ExportExpenses(c, deps.DB.Reader()) })
+		protected.GET("/personal-expenses/:id", func(c *gin.Context) { personalexpense.GetExpense(c, deps.DB) })
+		protected.PUT("/personal-expenses/:id", func(c *gin.Context) { personalexpense.UpdateExpense(c, deps.DB, deps.Rates) })
+		protected.DELETE("/personal-expenses/:id", func(c *gin.Context) { personalexpense.DeleteExpense(c, deps.DB, deps.Store) })
+		protected.POST("/personal-expenses/:id/receipts", func(c *gin.Context) { personalexpense.UploadReceipt(c, deps.DB, deps.Store, deps.OCR) })
+		protected.GET("/personal-expenses/:id/receipts", func(c *gin.Context) { personalexpense.ListReceipts(c, deps.DB, deps.Store) })
+		protected.GET("/personal-expenses/:id/receipts/:receiptId", func(c *gin.Context) { personalexpense.DownloadReceipt(c, deps.DB, deps.Store) })
+		protected.DELETE("/personal-expenses/:id/receipts/:receiptId", func(c *gin.Context) { personalexpense.DeleteReceipt(c, deps.DB, deps.Store) })
+		protected.GET("/attachments/:id/extraction", func(c *gin.Context) { ocr.GetExtraction(c, deps.DB) })
+
+		// Personal Finance - Dashboard
+		protected.GET("/dashboard/monthly", func(c *gin.Context) { dashboard.GetMonthlyDashboard(c, deps.DB.Reader()) })
+		protected.GET("/dashboard/period", func(c *gin.Context) { dashboard.GetPeriodDashboard(c, deps.DB.Reader()) })
+		protected.GET("/dashboard/compare", func(c *gin.Context) { dashboard.GetMonthComparison(c, deps.DB.Reader()) })
+		protected.GET("/dashboard/trends", func(c *gin.Context) { dashboard.GetSpendingTrends(c, deps.DB.Reader()) })
+		protected.GET("/dashboard/merchants", func(c *gin.Context) { dashboard.GetTopMerchants(c, deps.DB.Reader()) })
+		protected.GET("/dashboard/heatmap", func(c *gin.Context) { dashboard.GetSpendingHeatmap(c, deps.DB.Reader()) })
+		protected.GET("/dashboard/forecast", func(c *gin.Context) { dashboard.GetSpendingForecast(c, deps.DB.Reader()) })
+		protected.GET("/dashboard/weekly", func(c *gin.Context) { dashboard.GetWeeklyDashboard(c, deps.DB.Reader()) })
+		protected.GET("/dashboard/score", func(c *gin.Context) { dashboard.GetBudgetScore(c, deps.DB.Reader()) })
+
+		// Personal Finance - Recurring Expenses
+		protected.GET("/recurring-expenses", func(c *gin.Context) { recurring.ListRecurringExpenses(c, deps.DB) })
+		protected.PUT("/recurring-expenses/:id", func(c *gin.Context) { recurring.UpdateRecurringExpense(c, deps.DB) })
+		protected.DELETE("/recurring-expenses/:id", func(c *gin.Context) { recurring.DeleteRecurringExpense(c, deps.DB) })
+		protected.GET("/subscriptions/detected", func(c *gin.Context) { recurring.GetDetectedSubscriptions(c, deps.DB) })
+		protected.POST("/subscriptions/detected/:id/confirm", func(c *gin.Context) { recurring.ConfirmSubscription(c, deps.DB) })
+
+		// Personal Finance - Calendar
+		protected.GET("/calendar/upcoming", func(c *gin.Context) { calendar.GetUpcoming(c, deps.DB) })
+
+		// Personal Finance - Insights
+		protected.GET("/insights", func(c *gin.Context) { insight.ListInsights(c, deps.DB) })
+		protected.POST("/insights/:id/dismiss", func(c *gin.Context) { insight.DismissInsight(c, deps.DB) })
+		protected.POST("/insights/:id/acknowledge", func(c *gin.Context) { insight.AcknowledgeInsight(c, deps.DB) })
+
+		// Personal Finance - Reports
+		protected.GET("/reports/monthly.pdf", func(c *gin.Context) { dashboard.GetMonthlyReportPDF(c, deps.DB.Reader()) })
+
+		// GraphQL, for screens that would need several of the above
+		protected.POST("/graphql", graphql.Handler(deps.DB, deps.Groups))
+	}
+
+	return r
+}
@@ -0,0 +1,237 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/auth"
+	"github.com/yanonymousV2/finance-manager-backend/internal/group"
+	"github.com/yanonymousV2/finance-manager-backend/internal/health"
+	"github.com/yanonymousV2/finance-manager-backend/internal/storage"
+)
+
+const testSecret = "test-secret"
+
+// groupRepository keeps groups in memory
+type groupRepository struct {
+	groups  map[uuid.UUID]group.Group
+	members map[uuid.UUID][]uuid.UUID
+}
+
+func (r *groupRepository) Create(_ context.Context, name, currency string, createdBy uuid.UUID) (group.Group, error) {
+	g := group.Group{ID: uuid.New(), Name: name, Currency: currency, CreatedBy: createdBy, CreatedAt: time.Now()}
+	r.groups[g.ID] = g
+	r.members[g.ID] = []uuid.UUID{createdBy}
+	return g, nil
+}
+
+func (r *groupRepository) AddMember(_ context.Context, groupID, userID, _ uuid.UUID) error {
+	r.members[groupID] = append(r.members[groupID], userID)
+	return nil
+}
+
+func (r *groupRepository) IsMember(_ context.Context, groupID, userID uuid.UUID) (bool, error) {
+	for _, id := range r.members[groupID] {
+		if id == userID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *groupRepository) UserExists(context.Context, uuid.UUID) (bool, error) {
+	return true, nil
+}
+
+func (r *groupRepository) IsPlaceholder(context.Context, uuid.UUID) (bool, error) {
+	return false, nil
+}
+
+func (r *groupRepository) Currency(_ context.Context, groupID uuid.UUID) (string, error) {
+	return r.groups[groupID].Currency, nil
+}
+
+func (r *groupRepository) Balances(_ context.Context, groupID uuid.UUID) (map[uuid.UUID]decimal.Decimal, error) {
+	balances := map[uuid.UUID]decimal.Decimal{}
+	for _, id := range r.members[groupID] {
+		balances[id] = decimal.Zero
+	}
+	return balances, nil
+}
+
+func (r *groupRepository) Debts(context.Context, uuid.UUID) ([]group.PairwiseBalance, error) {
+	return nil, nil
+}
+
+func token(t *testing.T, userID uuid.UUID) string {
+	signed, err := jwt.NewWithClaims(jwt.SigningMethodHS256, auth.Claims{
+		UserID: userID,
+		Email:  "test@example.com",
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+	}).SignedString([]byte(testSecret))
+	require.NoError(t, err)
+	return signed
+}
+
+func TestServer(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &groupRepository{groups: map[uuid.UUID]group.Group{}, members: map[uuid.UUID][]uuid.UUID{}}
+	srv := httptest.NewServer(New(Deps{
+		Groups:    group.NewService(repo),
+		JWTSecret: testSecret,
+	}))
+	defer srv.Close()
+
+	do := func(method, path, body string, userID *uuid.UUID) *http.Response {
+		req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+		require.NoError(t, err)
+		req.Header.Set("Content-Type", "application/json")
+		if userID != nil {
+			req.Header.Set("Authorization", "Bearer "+token(t, *userID))
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+
+	owner, friend, outsider := uuid.New(), uuid.New(), uuid.New()
+
+	resp := do("POST", "/groups", `{"name": "Trip"}`, nil)
+	assert.Equal(t, 401, resp.StatusCode)
+
+	resp = do("POST", "/groups", `{"name": "Trip", "currency": "EUR"}`, &owner)
+	require.Equal(t, 201, resp.StatusCode)
+	var g group.Group
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&g))
+	assert.Equal(t, "Trip", g.Name)
+	assert.Equal(t, "EUR", g.Currency)
+
+	resp = do("POST", "/groups/"+g.ID.String()+"/add-member", `{"user_id": "`+friend.String()+`"}`, &owner)
+	assert.Equal(t, 200, resp.StatusCode)
+
+	resp = do("GET", "/groups/"+g.ID.String()+"/balances", "", &friend)
+	require.Equal(t, 200, resp.StatusCode)
+	var balances []group.Balance
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&balances))
+	assert.Len(t, balances, 2)
+
+	resp = do("GET", "/groups/"+g.ID.String()+"/balances", "", &outsider)
+	assert.Equal(t, 403, resp.StatusCode)
+}
+
+func TestOperationsMatchRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	local, err := storage.NewLocal(t.TempDir(), "http://localhost", "secret")
+	require.NoError(t, err)
+	repo := &groupRepository{groups: map[uuid.UUID]group.Group{}, members: map[uuid.UUID][]uuid.UUID{}}
+	engine, ok := New(Deps{Store: local, Groups: group.NewService(repo), JWTSecret: testSecret}).(*gin.Engine)
+	require.True(t, ok)
+
+	var registered, documented []string
+	for _, route := range engine.Routes() {
+		registered = append(registered, route.Method+" "+route.Path)
+	}
+	for _, op := range operations {
+		documented = append(documented, op.Method+" "+op.Path)
+	}
+	assert.ElementsMatch(t, registered, documented)
+}
+
+func TestOpenAPIDocument(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &groupRepository{groups: map[uuid.UUID]group.Group{}, members: map[uuid.UUID][]uuid.UUID{}}
+	handler := New(Deps{Groups: group.NewService(repo), JWTSecret: testSecret})
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/openapi.json", nil))
+	require.Equal(t, 200, w.Code)
+
+	var spec struct {
+		OpenAPI string                    `json:"openapi"`
+		Paths   map[string]map[string]any `json:"paths"`
+	}
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &spec))
+	assert.Equal(t, "3.0.3", spec.OpenAPI)
+	assert.Contains(t, spec.Paths["/groups/{id}/balances"], "get")
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "/docs", nil))
+	assert.Equal(t, 200, w.Code)
+	assert.Contains(t, w.Body.String(), "/openapi.json")
+}
+
+func TestAdmin(t *testing.T) {
+	admin := NewAdmin(nil)
+	w := httptest.NewRecorder()
+	admin.ServeHTTP(w, httptest.NewRequest("GET", "/debug/pprof/heap?debug=1", nil))
+	assert.Equal(t, 200, w.Code)
+	assert.Contains(t, w.Body.String(), "heap profile")
+
+	w = httptest.NewRecorder()
+	admin.ServeHTTP(w, httptest.NewRequest("GET", "/debug/vars", nil))
+	assert.Equal(t, 200, w.Code)
+	assert.Contains(t, w.Body.String(), `"memstats"`)
+
+	w = httptest.NewRecorder()
+	admin.ServeHTTP(w, httptest.NewRequest("GET", "/admin/balances/rebuild", nil))
+	assert.Equal(t, 405, w.Code)
+	w = httptest.NewRecorder()
+	admin.ServeHTTP(w, httptest.NewRequest("POST", "/admin/balances/rebuild?group_id=nope", nil))
+	assert.Equal(t, 400, w.Code)
+	assert.JSONEq(t, `{"error": "invalid group_id"}`, w.Body.String())
+
+	// Profiles are never served on the public API
+	gin.SetMode(gin.TestMode)
+	repo := &groupRepository{groups: map[uuid.UUID]group.Group{}, members: map[uuid.UUID][]uuid.UUID{}}
+	w = httptest.NewRecorder()
+	New(Deps{Groups: group.NewService(repo), JWTSecret: testSecret}).ServeHTTP(w, httptest.NewRequest("GET", "/debug/pprof/", nil))
+	assert.Equal(t, 404, w.Code)
+}
+
+func TestProbes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	repo := &groupRepository{groups: map[uuid.UUID]group.Group{}, members: map[uuid.UUID][]uuid.UUID{}}
+	ready := health.NewChecker(time.Second)
+	ready.Add("database", func(context.Context) (any, error) { return nil, nil })
+	handler := New(Deps{Groups: group.NewService(repo), JWTSecret: testSecret, Ready: ready})
+
+	get := func(path string) (int, map[string]any) {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		var body map[string]any
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+		return w.Code, body
+	}
+
+	code, body := get("/readyz")
+	assert.Equal(t, 200, code)
+	assert.Equal(t, "ok", body["status"])
+
+	// A failing dependency takes the server out of rotation but it stays live
+	ready.Add("database", func(context.Context) (any, error) { return nil, errors.New("connection refused") })
+	code, body = get("/readyz")
+	assert.Equal(t, 503, code)
+	database := body["checks"].(map[string]any)["database"].(map[string]any)
+	assert.Equal(t, "failing", database["status"])
+	assert.Equal(t, "connection refused", database["error"])
+
+	code, body = get("/livez")
+	assert.Equal(t, 200, code)
+	assert.Equal(t, "ok", body["status"])
+}
@@ -0,0 +1,87 @@
+package server
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// TLSOptions turns on built-in HTTPS, for deployments without a reverse
+// proxy. Give either a certificate and key, or the domains to get
+// certificates for from Let's Encrypt.
+type TLSOptions struct {
+	CertFile string
+	KeyFile  string
+
+	Domains []string
+	// Email is given to Let's Encrypt for expiry notices
+	Email string
+	// CacheDir keeps issued certificates across restarts
+	CacheDir string
+
+	// HTTPSPort is where plain HTTP requests are redirected, when not 443
+	HTTPSPort string
+}
+
+// TLS returns the TLS config for the API, or nil when opts leave TLS off,
+// and the handler for a plain HTTP listener. That handler answers Let's
+// Encrypt's challenges in autocert mode and redirects everything else to
+// HTTPS.
+func TLS(opts TLSOptions) (*tls.Config, http.Handler, error) {
+	redirect := redirectHandler(opts.HTTPSPort)
+	files := opts.CertFile != "" || opts.KeyFile != ""
+
+	switch {
+	case files && len(opts.Domains) > 0:
+		return nil, nil, errors.New("use either a certificate and key or autocert domains, not both")
+	case files:
+		if opts.CertFile == "" || opts.KeyFile == "" {
+			return nil, nil, errors.New("both a certificate and a key file are needed")
+		}
+		cert, err := tls.LoadX509KeyPair(opts.CertFile, opts.KeyFile)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load certificate: %w", err)
+		}
+		return &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			MinVersion:   tls.VersionTLS12,
+		}, redirect, nil
+	case len(opts.Domains) > 0:
+		m := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			HostPolicy: autocert.HostWhitelist(opts.Domains...),
+			Cache:      autocert.DirCache(opts.CacheDir),
+			Email:      opts.Email,
+		}
+		config := m.TLSConfig()
+		config.MinVersion = tls.VersionTLS12
+		return config, m.HTTPHandler(redirect), nil
+	default:
+		return nil, nil, nil
+	}
+}
+
+// redirectHandler sends requests to the same host and path over HTTPS.
+// GET and HEAD are moved permanently; other methods get a 308 so clients
+// repeat them with their body rather than switching to GET.
+func redirectHandler(httpsPort string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if httpsPort != "" && httpsPort != "443" {
+			host = net.JoinHostPort(host, httpsPort)
+		}
+
+		code := http.StatusMovedPermanently
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			code = http.StatusPermanentRedirect
+		}
+		http.Redirect(w, r, "https://"+host+r.URL.RequestURI(), code)
+	})
+}
@@ -0,0 +1,87 @@
+package server
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// selfSigned writes a certificate and key for localhost to dir
+func selfSigned(t *testing.T, dir string) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		NotBefore:    time.Now(),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+	return certFile, keyFile
+}
+
+func TestTLS(t *testing.T) {
+	config, handler, err := TLS(TLSOptions{})
+	require.NoError(t, err)
+	assert.Nil(t, config)
+	assert.Nil(t, handler)
+
+	certFile, keyFile := selfSigned(t, t.TempDir())
+	config, handler, err = TLS(TLSOptions{CertFile: certFile, KeyFile: keyFile})
+	require.NoError(t, err)
+	assert.Len(t, config.Certificates, 1)
+	assert.NotNil(t, handler)
+
+	config, handler, err = TLS(TLSOptions{Domains: []string{"api.example.com"}, CacheDir: t.TempDir()})
+	require.NoError(t, err)
+	assert.NotNil(t, config.GetCertificate)
+	assert.Contains(t, config.NextProtos, "acme-tls/1")
+
+	// Challenges for unknown tokens are answered, not redirected
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest("GET", "http://api.example.com/.well-known/acme-challenge/abc", nil))
+	assert.Equal(t, 404, w.Code)
+
+	_, _, err = TLS(TLSOptions{CertFile: certFile})
+	assert.Error(t, err)
+	_, _, err = TLS(TLSOptions{CertFile: certFile, KeyFile: keyFile, Domains: []string{"api.example.com"}})
+	assert.Error(t, err)
+	_, _, err = TLS(TLSOptions{CertFile: certFile, KeyFile: certFile})
+	assert.Error(t, err)
+}
+
+func TestRedirect(t *testing.T) {
+	redirect := func(port, method, url string) (int, string) {
+		w := httptest.NewRecorder()
+		redirectHandler(port).ServeHTTP(w, httptest.NewRequest(method, url, nil))
+		return w.Code, w.Header().Get("Location")
+	}
+
+	code, location := redirect("", "GET", "http://api.example.com/personal-expenses?limit=10")
+	assert.Equal(t, 301, code)
+	assert.Equal(t, "https://api.example.com/personal-expenses?limit=10", location)
+
+	code, location = redirect("8443", "POST", "http://api.example.com:8080/auth/login")
+	assert.Equal(t, 308, code)
+	assert.Equal(t, "https://api.example.com:8443/auth/login", location)
+}
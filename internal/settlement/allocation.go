@@ -0,0 +1,202 @@
+package settlement
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+	"github.com/yanonymousV2/finance-manager-backend/internal/helpers"
+	"github.com/yanonymousV2/finance-manager-backend/internal/middleware"
+)
+
+type Allocation struct {
+	ExpenseID uuid.UUID       `json:"expense_id" db:"expense_id"`
+	Amount    decimal.Decimal `json:"amount" db:"amount"`
+}
+
+type AllocationRequest struct {
+	ExpenseID uuid.UUID       `json:"expense_id" validate:"required"`
+	Amount    decimal.Decimal `json:"amount" validate:"required"`
+}
+
+// owedSharesSQL returns, for each approved expense of a group, what a member
+// owes for it (their split less what they paid themselves) and how much of
+// that is already allocated by their confirmed and pending settlements.
+// Deleted settlements don't count.
+const owedSharesSQL = `SELECT e.id, e.description, e.expense_date,
+	        GREATEST(es.amount - COALESCE(ep.amount, 0), 0) AS share,
+	        COALESCE(SUM(sa.amount) FILTER (WHERE s.status = 'confirmed'), 0) AS paid,
+	        COALESCE(SUM(sa.amount) FILTER (WHERE s.status = 'pending'), 0) AS pending
+	 FROM expenses e
+	 JOIN expense_splits es ON es.expense_id = e.id AND es.user_id = $2
+	 LEFT JOIN expense_payers ep ON ep.expense_id = e.id AND ep.user_id = $2
+	 LEFT JOIN settlement_allocations sa ON sa.expense_id = e.id
+	 LEFT JOIN settlements s ON s.id = sa.settlement_id AND s.from_user = $2 AND s.deleted_at IS NULL
+	 WHERE e.group_id = $1 AND e.deleted_at IS NULL AND e.status = 'approved'`
+
+// validateAllocations checks the allocations of a new settlement against the
+// amount settled and what from_user still owes for each expense. It runs in
+// tx after locking the expenses so concurrent settlements cannot over-allocate.
+func validateAllocations(ctx context.Context, tx pgx.Tx, groupID, fromUser uuid.UUID, amount decimal.Decimal, allocations []AllocationRequest) error {
+	total := decimal.Zero
+	seen := make(map[uuid.UUID]bool, len(allocations))
+	expenseIDs := make([]uuid.UUID, 0, len(allocations))
+	for _, a := range allocations {
+		if !a.Amount.IsPositive() {
+			return errors.New("allocation amount must be greater than 0")
+		}
+		if seen[a.ExpenseID] {
+			return fmt.Errorf("expense %s is allocated more than once", a.ExpenseID)
+		}
+		seen[a.ExpenseID] = true
+		expenseIDs = append(expenseIDs, a.ExpenseID)
+		total = total.Add(a.Amount)
+	}
+	if total.GreaterThan(amount) {
+		return errors.New("allocations exceed the settlement amount")
+	}
+
+	if _, err := tx.Exec(ctx,
+		"SELECT id FROM expenses WHERE id = ANY($1) ORDER BY id FOR UPDATE", expenseIDs); err != nil {
+		return err
+	}
+
+	rows, err := tx.Query(ctx,
+		owedSharesSQL+` AND e.id = ANY($3)
+		 GROUP BY e.id, es.amount, ep.amount`,
+		groupID, fromUser, expenseIDs)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	remaining := make(map[uuid.UUID]decimal.Decimal, len(allocations))
+	for rows.Next() {
+		var id uuid.UUID
+		var description string
+		var expenseDate time.Time
+		var share, paid, pending decimal.Decimal
+		if err := rows.Scan(&id, &description, &expenseDate, &share, &paid, &pending); err != nil {
+			return err
+		}
+		remaining[id] = share.Sub(paid).Sub(pending)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, a := range allocations {
+		left, ok := remaining[a.ExpenseID]
+		if !ok {
+			return fmt.Errorf("expense %s is not an approved group expense shared by from_user", a.ExpenseID)
+		}
+		if a.Amount.GreaterThan(left) {
+			return fmt.Errorf("allocation for expense %s exceeds the %s still owed", a.ExpenseID, left.StringFixed(2))
+		}
+	}
+	return nil
+}
+
+// insertAllocations stores the allocations of a settlement in tx
+func insertAllocations(ctx context.Context, tx pgx.Tx, settlementID uuid.UUID, allocations []AllocationRequest) ([]Allocation, error) {
+	batch := &pgx.Batch{}
+	stored := make([]Allocation, len(allocations))
+	for i, a := range allocations {
+		batch.Queue(
+			"INSERT INTO settlement_allocations (settlement_id, expense_id, amount) VALUES ($1, $2, $3)",
+			settlementID, a.ExpenseID, a.Amount)
+		stored[i] = Allocation{ExpenseID: a.ExpenseID, Amount: a.Amount}
+	}
+	if err := tx.SendBatch(ctx, batch).Close(); err != nil {
+		return nil, err
+	}
+	return stored, nil
+}
+
+type UnpaidExpense struct {
+	ExpenseID   uuid.UUID       `json:"expense_id"`
+	Description string          `json:"description"`
+	ExpenseDate time.Time       `json:"expense_date"`
+	Share       decimal.Decimal `json:"share"`
+	Paid        decimal.Decimal `json:"paid"`
+	Pending     decimal.Decimal `json:"pending"`
+	Outstanding decimal.Decimal `json:"outstanding"`
+}
+
+// GetUnpaidExpenses lists the expenses a member still owes for, based on the
+// allocations of their confirmed settlements. Settlements recorded without
+// allocations are not attributed to any expense.
+func GetUnpaidExpenses(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	groupID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid group id"})
+		return
+	}
+
+	memberID, err := uuid.Parse(c.Param("userId"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid user id"})
+		return
+	}
+
+	isMember, err := helpers.IsGroupMember(c.Request.Context(), db, groupID, userID)
+	if err != nil || !isMember {
+		c.JSON(403, gin.H{"error": "not a member of the group"})
+		return
+	}
+
+	isMember, err = helpers.IsGroupMember(c.Request.Context(), db, groupID, memberID)
+	if err != nil || !isMember {
+		c.JSON(404, gin.H{"error": "user is not a member of the group"})
+		return
+	}
+
+	rows, err := db.Pool.Query(c.Request.Context(),
+		owedSharesSQL+`
+		 GROUP BY e.id, es.amount, ep.amount
+		 HAVING GREATEST(es.amount - COALESCE(ep.amount, 0), 0) >
+		        COALESCE(SUM(sa.amount) FILTER (WHERE s.status = 'confirmed'), 0)
+		 ORDER BY e.expense_date, e.id`,
+		groupID, memberID)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to get unpaid expenses"})
+		return
+	}
+	defer rows.Close()
+
+	expenses := []UnpaidExpense{}
+	total := decimal.Zero
+	for rows.Next() {
+		var u UnpaidExpense
+		if err := rows.Scan(&u.ExpenseID, &u.Description, &u.ExpenseDate, &u.Share, &u.Paid, &u.Pending); err != nil {
+			c.JSON(500, gin.H{"error": "failed to scan unpaid expense"})
+			return
+		}
+		u.Outstanding = u.Share.Sub(u.Paid)
+		total = total.Add(u.Outstanding)
+		expenses = append(expenses, u)
+	}
+	if err := rows.Err(); err != nil {
+		c.JSON(500, gin.H{"error": "failed to get unpaid expenses"})
+		return
+	}
+
+	c.JSON(200, gin.H{
+		"user_id":           memberID,
+		"expenses":          expenses,
+		"total_outstanding": total,
+	})
+}
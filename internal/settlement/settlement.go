@@ -1,35 +1,109 @@
 package settlement
 
 import (
+	"errors"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/go-playground/validator/v10"
 	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
 	"github.com/shopspring/decimal"
 
 	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+	"github.com/yanonymousV2/finance-manager-backend/internal/event"
+	"github.com/yanonymousV2/finance-manager-backend/internal/fxrate"
 	"github.com/yanonymousV2/finance-manager-backend/internal/helpers"
 	"github.com/yanonymousV2/finance-manager-backend/internal/middleware"
+	"github.com/yanonymousV2/finance-manager-backend/internal/softdelete"
+	"github.com/yanonymousV2/finance-manager-backend/internal/validation"
 )
 
 type Settlement struct {
-	ID        uuid.UUID       `json:"id" db:"id"`
-	GroupID   uuid.UUID       `json:"group_id" db:"group_id"`
-	FromUser  uuid.UUID       `json:"from_user" db:"from_user"`
-	ToUser    uuid.UUID       `json:"to_user" db:"to_user"`
-	Amount    decimal.Decimal `json:"amount" db:"amount"`
-	CreatedAt time.Time       `json:"created_at" db:"created_at"`
+	ID          uuid.UUID       `json:"id" db:"id"`
+	GroupID     uuid.UUID       `json:"group_id" db:"group_id"`
+	FromUser    uuid.UUID       `json:"from_user" db:"from_user"`
+	ToUser      uuid.UUID       `json:"to_user" db:"to_user"`
+	Amount      decimal.Decimal `json:"amount" db:"amount"`
+	Currency    string          `json:"currency" db:"currency"`
+	Method      *string         `json:"method,omitempty" db:"method"`
+	Note        *string         `json:"note,omitempty" db:"note"`
+	ExternalRef *string         `json:"external_ref,omitempty" db:"external_ref"`
+	Status      string          `json:"status" db:"status"`
+	ConfirmedAt *time.Time      `json:"confirmed_at,omitempty" db:"confirmed_at"`
+	// Set when the payment was made in a currency other than the group's
+	OriginalAmount   *decimal.Decimal `json:"original_amount,omitempty" db:"original_amount"`
+	OriginalCurrency *string          `json:"original_currency,omitempty" db:"original_currency"`
+	FXRate           *decimal.Decimal `json:"fx_rate,omitempty" db:"fx_rate"`
+	CreatedAt        time.Time        `json:"created_at" db:"created_at"`
+	Allocations      []Allocation     `json:"allocations,omitempty"`
+}
+
+// Settlement statuses; only confirmed settlements count toward balances
+const (
+	StatusPending   = "pending"
+	StatusConfirmed = "confirmed"
+)
+
+// Columns lists the settlement columns read by Scan, in order
+const Columns = "id, group_id, from_user, to_user, amount, currency, method, note, external_ref, status, confirmed_at, " +
+	"original_amount, original_currency, fx_rate, created_at"
+
+// InitialStatus returns the status a new settlement starts in. When the
+// group requires confirmation, only settlements recorded by their recipient
+// are confirmed right away.
+func InitialStatus(requireConfirmation bool, recordedBy, toUser uuid.UUID) string {
+	if requireConfirmation && recordedBy != toUser {
+		return StatusPending
+	}
+	return StatusConfirmed
+}
+
+// InsertSQL inserts a settlement and returns Columns. Confirmed settlements
+// are stamped with the confirmation time.
+const InsertSQL = `INSERT INTO settlements (group_id, from_user, to_user, amount, currency, method, note, external_ref, status, confirmed_at,
+	                          original_amount, original_currency, fx_rate)
+	 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9::varchar, CASE WHEN $9::varchar = 'confirmed' THEN NOW() END, $10, $11, $12)
+	 RETURNING ` + Columns
+
+// convert turns an amount in a foreign currency into the group's currency,
+// rounded to cents
+func convert(amount, fxRate decimal.Decimal) (decimal.Decimal, error) {
+	converted := amount.Mul(fxRate).Round(2)
+	if !converted.IsPositive() {
+		return decimal.Zero, errors.New("converted amount must be greater than 0")
+	}
+	return converted, nil
+}
+
+// Scan reads a settlement selected with Columns
+func Scan(row pgx.Row) (Settlement, error) {
+	var s Settlement
+	err := row.Scan(&s.ID, &s.GroupID, &s.FromUser, &s.ToUser, &s.Amount, &s.Currency,
+		&s.Method, &s.Note, &s.ExternalRef, &s.Status, &s.ConfirmedAt,
+		&s.OriginalAmount, &s.OriginalCurrency, &s.FXRate, &s.CreatedAt)
+	return s, err
 }
 
 type CreateSettlementRequest struct {
-	GroupID  uuid.UUID       `json:"group_id" validate:"required"`
-	FromUser uuid.UUID       `json:"from_user" validate:"required"`
-	ToUser   uuid.UUID       `json:"to_user" validate:"required"`
-	Amount   decimal.Decimal `json:"amount" validate:"required,gt=0"`
+	GroupID     uuid.UUID       `json:"group_id" validate:"required"`
+	FromUser    uuid.UUID       `json:"from_user" validate:"required"`
+	ToUser      uuid.UUID       `json:"to_user" validate:"required"`
+	Amount      decimal.Decimal `json:"amount" validate:"required"`
+	Currency    string          `json:"currency,omitempty" validate:"omitempty,iso4217"`
+	Method      *string         `json:"method,omitempty" validate:"omitempty,oneof=cash bank_transfer venmo other"`
+	Note        *string         `json:"note,omitempty" validate:"omitempty,max=500"`
+	ExternalRef *string         `json:"external_ref,omitempty" validate:"omitempty,max=100"`
+	// FXRate converts one unit of Currency into the group's currency. When
+	// Currency differs from it and FXRate is left out, today's exchange rate
+	// is used.
+	FXRate *decimal.Decimal `json:"fx_rate,omitempty"`
+	// Allocations optionally say which of from_user's expenses the
+	// settlement pays off, in the group's currency
+	Allocations []AllocationRequest `json:"allocations,omitempty" validate:"omitempty,max=100,dive"`
 }
 
-func CreateSettlement(c *gin.Context, db *db.DB) {
+func CreateSettlement(c *gin.Context, db *db.DB, rates *fxrate.Service) {
 	userID, ok := middleware.GetUserID(c)
 	if !ok {
 		c.JSON(401, gin.H{"error": "unauthorized"})
@@ -37,14 +111,11 @@ func CreateSettlement(c *gin.Context, db *db.DB) {
 	}
 
 	var req CreateSettlementRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
+	if !validation.Bind(c, &req) {
 		return
 	}
-
-	validate := validator.New()
-	if err := validate.Struct(req); err != nil {
-		c.JSON(400, gin.H{"error": err.Error()})
+	if !req.Amount.IsPositive() {
+		c.JSON(400, gin.H{"error": "amount must be greater than 0"})
 		return
 	}
 
@@ -74,15 +145,341 @@ func CreateSettlement(c *gin.Context, db *db.DB) {
 		return
 	}
 
+	// Balances are kept in the group's currency; payments in another
+	// currency are converted at the given rate and the original is kept
+	currency, err := helpers.GetGroupCurrency(c.Request.Context(), db, groupID)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to get group currency"})
+		return
+	}
+	amount := req.Amount
+	var originalAmount *decimal.Decimal
+	var originalCurrency *string
+	var fxRate *decimal.Decimal
+	if req.Currency != "" && req.Currency != currency {
+		if req.FXRate == nil && rates != nil {
+			rate, err := rates.Rate(c.Request.Context(), req.Currency, currency, time.Now().UTC())
+			if err != nil {
+				fxrate.RespondRateError(c, err)
+				return
+			}
+			req.FXRate = &rate
+		}
+		if req.FXRate == nil || !req.FXRate.IsPositive() {
+			c.JSON(400, gin.H{"error": "fx_rate is required when currency differs from group currency"})
+			return
+		}
+		amount, err = convert(req.Amount, *req.FXRate)
+		if err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+		originalAmount, originalCurrency, fxRate = &req.Amount, &req.Currency, req.FXRate
+	} else if req.FXRate != nil {
+		c.JSON(400, gin.H{"error": "fx_rate is only allowed for a foreign currency"})
+		return
+	}
+
 	// Insert settlement
+	requireConfirmation, err := helpers.RequiresSettlementConfirmation(c.Request.Context(), db, groupID)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to get group settings"})
+		return
+	}
+
 	var s Settlement
-	err = db.Pool.QueryRow(c.Request.Context(),
-		"INSERT INTO settlements (group_id, from_user, to_user, amount) VALUES ($1, $2, $3, $4) RETURNING id, group_id, from_user, to_user, amount, created_at",
-		groupID, req.FromUser, req.ToUser, req.Amount).Scan(&s.ID, &s.GroupID, &s.FromUser, &s.ToUser, &s.Amount, &s.CreatedAt)
+	err = db.WithTx(c.Request.Context(), func(tx pgx.Tx) error {
+		// Settle-all locks the group, so it never misses a pending
+		// settlement being recorded at the same time
+		if _, err := tx.Exec(c.Request.Context(), "SELECT id FROM groups WHERE id = $1 FOR SHARE", groupID); err != nil {
+			return helpers.NewRequestError(500, "failed to create settlement")
+		}
+
+		if len(req.Allocations) > 0 {
+			if err := validateAllocations(c.Request.Context(), tx, groupID, req.FromUser, amount, req.Allocations); err != nil {
+				return helpers.NewRequestError(400, err.Error())
+			}
+		}
+
+		var err error
+		s, err = Scan(tx.QueryRow(c.Request.Context(), InsertSQL,
+			groupID, req.FromUser, req.ToUser, amount, currency, req.Method, req.Note, req.ExternalRef,
+			InitialStatus(requireConfirmation, userID, req.ToUser), originalAmount, originalCurrency, fxRate))
+		if err != nil {
+			return helpers.NewRequestError(500, "failed to create settlement")
+		}
+
+		if len(req.Allocations) > 0 {
+			s.Allocations, err = insertAllocations(c.Request.Context(), tx, s.ID, req.Allocations)
+			if err != nil {
+				return helpers.NewRequestError(500, "failed to allocate settlement")
+			}
+		}
+		return event.Record(c.Request.Context(), tx, event.Event{Type: event.SettlementCreated, UserID: userID, GroupID: &groupID, Data: s})
+	})
 	if err != nil {
-		c.JSON(500, gin.H{"error": "failed to create settlement"})
+		helpers.RespondError(c, err, "failed to create settlement")
 		return
 	}
 
 	c.JSON(201, s)
 }
+
+// ListSettlements returns the settlements of a group, newest first
+func ListSettlements(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	groupID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid group id"})
+		return
+	}
+
+	isMember, err := helpers.IsGroupMember(c.Request.Context(), db, groupID, userID)
+	if err != nil || !isMember {
+		c.JSON(403, gin.H{"error": "not a member of the group"})
+		return
+	}
+
+	// Parse pagination parameters
+	limit := 50 // default
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
+			limit = l
+		}
+	}
+
+	offset := 0
+	if offsetStr := c.Query("offset"); offsetStr != "" {
+		if o, err := strconv.Atoi(offsetStr); err == nil && o >= 0 {
+			offset = o
+		}
+	}
+
+	var totalCount int
+	err = db.Pool.QueryRow(c.Request.Context(),
+		"SELECT COUNT(*) FROM settlements WHERE group_id = $1 AND deleted_at IS NULL", groupID).Scan(&totalCount)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to get total count"})
+		return
+	}
+
+	rows, err := db.Pool.Query(c.Request.Context(),
+		`SELECT `+Columns+`
+		 FROM settlements
+		 WHERE group_id = $1 AND deleted_at IS NULL
+		 ORDER BY created_at DESC, id
+		 LIMIT $2 OFFSET $3`,
+		groupID, limit, offset)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to get settlements"})
+		return
+	}
+	defer rows.Close()
+
+	settlements := []Settlement{}
+	for rows.Next() {
+		s, err := Scan(rows)
+		if err != nil {
+			c.JSON(500, gin.H{"error": "failed to scan settlement"})
+			return
+		}
+		settlements = append(settlements, s)
+	}
+
+	c.JSON(200, gin.H{
+		"settlements": settlements,
+		"pagination": gin.H{
+			"limit":  limit,
+			"offset": offset,
+			"total":  totalCount,
+		},
+	})
+}
+
+// ConfirmSettlement lets the recipient confirm a pending settlement so it
+// counts toward balances
+func ConfirmSettlement(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	settlementID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid settlement id"})
+		return
+	}
+
+	s, err := Scan(db.Pool.QueryRow(c.Request.Context(),
+		"SELECT "+Columns+" FROM settlements WHERE id = $1 AND deleted_at IS NULL", settlementID))
+	if err != nil {
+		c.JSON(404, gin.H{"error": "settlement not found"})
+		return
+	}
+
+	if s.ToUser != userID {
+		c.JSON(403, gin.H{"error": "only the recipient can confirm a settlement"})
+		return
+	}
+
+	if s.Status != StatusPending {
+		c.JSON(400, gin.H{"error": "settlement is not pending"})
+		return
+	}
+
+	err = db.WithTx(c.Request.Context(), func(tx pgx.Tx) error {
+		var err error
+		s, err = Scan(tx.QueryRow(c.Request.Context(),
+			`UPDATE settlements SET status = 'confirmed', confirmed_at = NOW()
+			 WHERE id = $1 AND status = 'pending' AND deleted_at IS NULL
+			 RETURNING `+Columns,
+			settlementID))
+		if errors.Is(err, pgx.ErrNoRows) {
+			return helpers.NewRequestError(409, "settlement was already confirmed")
+		}
+		if err != nil {
+			return helpers.NewRequestError(500, "failed to confirm settlement")
+		}
+		return event.Record(c.Request.Context(), tx, event.Event{Type: event.SettlementConfirmed, UserID: userID, GroupID: &s.GroupID, Data: s})
+	})
+	if err != nil {
+		helpers.RespondError(c, err, "failed to confirm settlement")
+		return
+	}
+
+	c.JSON(200, s)
+}
+
+// DeleteSettlement deletes a settlement recorded by mistake, taking it out
+// of balances. Either party can delete it.
+func DeleteSettlement(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	settlementID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid settlement id"})
+		return
+	}
+
+	s, deletedAt, ok := authorizeSettlement(c, db, settlementID, userID)
+	if !ok {
+		return
+	}
+	if deletedAt != nil {
+		c.JSON(404, gin.H{"error": "settlement not found"})
+		return
+	}
+
+	err = db.WithTx(c.Request.Context(), func(tx pgx.Tx) error {
+		if _, err := softdelete.Delete(c.Request.Context(), tx, softdelete.Settlements, s.ID, userID); err != nil {
+			return err
+		}
+		deleted := event.Deleted{ID: s.ID, GroupID: s.GroupID}
+		return event.Record(c.Request.Context(), tx, event.Event{Type: event.SettlementDeleted, UserID: userID, GroupID: &s.GroupID, Data: deleted})
+	})
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to delete settlement"})
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "settlement deleted successfully"})
+}
+
+// RestoreSettlement undoes DeleteSettlement. Its allocations must still fit
+// what the payer owes, since other settlements may have covered it since.
+func RestoreSettlement(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	settlementID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid settlement id"})
+		return
+	}
+
+	s, deletedAt, ok := authorizeSettlement(c, db, settlementID, userID)
+	if !ok {
+		return
+	}
+	if deletedAt == nil {
+		c.JSON(400, gin.H{"error": "settlement is not deleted"})
+		return
+	}
+
+	err = db.WithTx(c.Request.Context(), func(tx pgx.Tx) error {
+		rows, err := tx.Query(c.Request.Context(),
+			"SELECT expense_id, amount FROM settlement_allocations WHERE settlement_id = $1", s.ID)
+		if err != nil {
+			return helpers.NewRequestError(500, "failed to load allocations")
+		}
+		allocations, err := pgx.CollectRows(rows, pgx.RowToStructByPos[AllocationRequest])
+		if err != nil {
+			return helpers.NewRequestError(500, "failed to load allocations")
+		}
+		if len(allocations) > 0 {
+			if err := validateAllocations(c.Request.Context(), tx, s.GroupID, s.FromUser, s.Amount, allocations); err != nil {
+				return helpers.NewRequestError(409, err.Error())
+			}
+		}
+
+		if _, err := softdelete.Restore(c.Request.Context(), tx, softdelete.Settlements, s.ID); err != nil {
+			return helpers.NewRequestError(500, "failed to restore settlement")
+		}
+
+		restored, err := Scan(tx.QueryRow(c.Request.Context(),
+			"SELECT "+Columns+" FROM settlements WHERE id = $1", s.ID))
+		if err != nil {
+			return helpers.NewRequestError(500, "failed to load settlement")
+		}
+		restored.Allocations = make([]Allocation, len(allocations))
+		for i, a := range allocations {
+			restored.Allocations[i] = Allocation{ExpenseID: a.ExpenseID, Amount: a.Amount}
+		}
+		return event.Record(c.Request.Context(), tx, event.Event{Type: event.SettlementRestored, UserID: userID, GroupID: &s.GroupID, Data: restored})
+	})
+	if err != nil {
+		helpers.RespondError(c, err, "failed to restore settlement")
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "settlement restored successfully"})
+}
+
+// authorizeSettlement loads a settlement, deleted or not, and checks the
+// user is one of its parties and still in its group. On failure it has
+// already responded.
+func authorizeSettlement(c *gin.Context, db *db.DB, settlementID, userID uuid.UUID) (Settlement, *time.Time, bool) {
+	var deletedAt *time.Time
+	var s Settlement
+	err := db.Pool.QueryRow(c.Request.Context(),
+		"SELECT id, group_id, from_user, to_user, amount, deleted_at FROM settlements WHERE id = $1",
+		settlementID).Scan(&s.ID, &s.GroupID, &s.FromUser, &s.ToUser, &s.Amount, &deletedAt)
+	if err != nil {
+		c.JSON(404, gin.H{"error": "settlement not found"})
+		return Settlement{}, nil, false
+	}
+
+	isMember, err := helpers.IsGroupMember(c.Request.Context(), db, s.GroupID, userID)
+	if err != nil || !isMember {
+		c.JSON(403, gin.H{"error": "not a member of the group"})
+		return Settlement{}, nil, false
+	}
+	if userID != s.FromUser && userID != s.ToUser {
+		c.JSON(403, gin.H{"error": "only the payer or recipient can change a settlement"})
+		return Settlement{}, nil, false
+	}
+	return s, deletedAt, true
+}
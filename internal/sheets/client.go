@@ -0,0 +1,315 @@
+// Package sheets exports expenses to a Google Sheet. Users connect their
+// Google account through OAuth, pick a spreadsheet and choose whether to
+// export their personal expenses or a group's. Each export appends the
+// expenses created since the last one, and a background job exports on the
+// schedule the user picked.
+package sheets
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/config"
+	"github.com/yanonymousV2/finance-manager-backend/internal/secret"
+)
+
+const (
+	googleAuthURL   = "https://accounts.google.com/o/oauth2/v2/auth"
+	googleTokenURL  = "https://oauth2.googleapis.com/token"
+	googleRevokeURL = "https://oauth2.googleapis.com/revoke"
+	sheetsURL       = "https://sheets.googleapis.com/v4/spreadsheets"
+	// scope lets the app read and write the user's spreadsheets
+	scope = "https://www.googleapis.com/auth/spreadsheets"
+)
+
+// Error is an error response from Google. Code is the OAuth error, like
+// invalid_grant, or the API status, like NOT_FOUND.
+type Error struct {
+	HTTPStatus int
+	Code       string
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("google: %s (%s)", e.Message, e.Code)
+}
+
+// isRevoked reports whether err means the user's grant is gone, so they
+// have to connect again
+func isRevoked(err error) bool {
+	var googleErr *Error
+	return errors.As(err, &googleErr) && googleErr.Code == "invalid_grant"
+}
+
+// Client calls Google's OAuth and Sheets APIs. Tokens are stored sealed by
+// box and only opened for the call that needs one.
+type Client struct {
+	clientID     string
+	clientSecret string
+	redirectURL  string
+	authURL      string
+	tokenURL     string
+	revokeURL    string
+	sheetsURL    string
+	client       *http.Client
+	box          *secret.Box
+}
+
+func NewClient(clientID, clientSecret, redirectURL string, box *secret.Box) *Client {
+	return &Client{
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		redirectURL:  redirectURL,
+		authURL:      googleAuthURL,
+		tokenURL:     googleTokenURL,
+		revokeURL:    googleRevokeURL,
+		sheetsURL:    sheetsURL,
+		client:       &http.Client{Timeout: 30 * time.Second},
+		box:          box,
+	}
+}
+
+// New returns a client for the OAuth client in cfg, or nil when Google
+// Sheets isn't set up
+func New(cfg *config.Config) *Client {
+	if cfg.GoogleClientID == "" {
+		return nil
+	}
+	box, err := secret.New(cfg.TokenEncryptionKey)
+	if err != nil {
+		panic(err) // config.Load checked the key
+	}
+	return NewClient(cfg.GoogleClientID, cfg.GoogleClientSecret, cfg.PublicURL+"/integrations/google-sheets/callback", box)
+}
+
+// open returns the token sealed in t. Refresh tokens stored before they
+// were encrypted are used as they are until sealLegacyTokens seals them.
+func (c *Client) open(t []byte) (string, error) {
+	if !secret.IsSealed(t) {
+		return string(t), nil
+	}
+	return c.box.Open(t)
+}
+
+// authCodeURL is where the user grants access. Offline access with the
+// consent prompt makes Google return a refresh token every time.
+func (c *Client) authCodeURL(state string) string {
+	return c.authURL + "?" + url.Values{
+		"client_id":     {c.clientID},
+		"redirect_uri":  {c.redirectURL},
+		"response_type": {"code"},
+		"scope":         {scope},
+		"access_type":   {"offline"},
+		"prompt":        {"consent"},
+		"state":         {state},
+	}.Encode()
+}
+
+// do sends req and decodes a successful response into resp, which may be
+// nil
+func (c *Client) do(req *http.Request, resp any) error {
+	httpResp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer httpResp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(httpResp.Body, 10<<20))
+	if err != nil {
+		return err
+	}
+	if httpResp.StatusCode >= 300 {
+		// OAuth errors are {"error": "invalid_grant", "error_description": ...};
+		// API errors are {"error": {"code": 404, "message": ..., "status": ...}}
+		var oauthErr struct {
+			Error       string `json:"error"`
+			Description string `json:"error_description"`
+		}
+		if json.Unmarshal(data, &oauthErr) == nil && oauthErr.Error != "" {
+			return &Error{HTTPStatus: httpResp.StatusCode, Code: oauthErr.Error, Message: oauthErr.Description}
+		}
+		var apiErr struct {
+			Error struct {
+				Message string `json:"message"`
+				Status  string `json:"status"`
+			} `json:"error"`
+		}
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Error.Status != "" {
+			return &Error{HTTPStatus: httpResp.StatusCode, Code: apiErr.Error.Status, Message: apiErr.Error.Message}
+		}
+		return fmt.Errorf("google returned %s", httpResp.Status)
+	}
+	if resp == nil {
+		return nil
+	}
+	return json.Unmarshal(data, resp)
+}
+
+// grant is the tokens of an OAuth token response, sealed for storing.
+// RefreshToken is only set when exchanging a code.
+type grant struct {
+	AccessToken  []byte
+	RefreshToken []byte
+	ExpiresAt    time.Time
+}
+
+func (c *Client) postToken(ctx context.Context, form url.Values) (grant, error) {
+	form.Set("client_id", c.clientID)
+	form.Set("client_secret", c.clientSecret)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return grant{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	var resp struct {
+		AccessToken  string `json:"access_token"`
+		RefreshToken string `json:"refresh_token"`
+		ExpiresIn    int    `json:"expires_in"`
+	}
+	if err := c.do(req, &resp); err != nil {
+		return grant{}, err
+	}
+	g := grant{
+		AccessToken: c.box.Seal(resp.AccessToken),
+		ExpiresAt:   time.Now().Add(time.Duration(resp.ExpiresIn) * time.Second),
+	}
+	if resp.RefreshToken != "" {
+		g.RefreshToken = c.box.Seal(resp.RefreshToken)
+	}
+	return g, nil
+}
+
+// exchange trades the code Google redirected back with for tokens
+func (c *Client) exchange(ctx context.Context, code string) (grant, error) {
+	return c.postToken(ctx, url.Values{
+		"grant_type":   {"authorization_code"},
+		"code":         {code},
+		"redirect_uri": {c.redirectURL},
+	})
+}
+
+// refresh gets a new access token with a sealed refresh token
+func (c *Client) refresh(ctx context.Context, refreshToken []byte) (grant, error) {
+	token, err := c.open(refreshToken)
+	if err != nil {
+		return grant{}, err
+	}
+	return c.postToken(ctx, url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {token},
+	})
+}
+
+// revoke withdraws the grant a sealed refresh token belongs to
+func (c *Client) revoke(ctx context.Context, refreshToken []byte) error {
+	token, err := c.open(refreshToken)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.revokeURL,
+		strings.NewReader(url.Values{"token": {token}}.Encode()))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return c.do(req, nil)
+}
+
+// api calls the Sheets API at path under the spreadsheets URL with a
+// sealed access token
+func (c *Client) api(ctx context.Context, accessToken []byte, method, path string, body, resp any) error {
+	token, err := c.open(accessToken)
+	if err != nil {
+		return err
+	}
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.sheetsURL+path, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	return c.do(req, resp)
+}
+
+// spreadsheet is a spreadsheet's title and the titles of its sheets
+type spreadsheet struct {
+	Title  string
+	Sheets []string
+}
+
+func (c *Client) spreadsheet(ctx context.Context, accessToken []byte, id string) (spreadsheet, error) {
+	var resp struct {
+		Properties struct {
+			Title string `json:"title"`
+		} `json:"properties"`
+		Sheets []struct {
+			Properties struct {
+				Title string `json:"title"`
+			} `json:"properties"`
+		} `json:"sheets"`
+	}
+	err := c.api(ctx, accessToken, http.MethodGet,
+		"/"+url.PathEscape(id)+"?fields="+url.QueryEscape("properties.title,sheets.properties.title"), nil, &resp)
+	if err != nil {
+		return spreadsheet{}, err
+	}
+	s := spreadsheet{Title: resp.Properties.Title}
+	for _, sheet := range resp.Sheets {
+		s.Sheets = append(s.Sheets, sheet.Properties.Title)
+	}
+	return s, nil
+}
+
+// addSheet adds a sheet titled title to a spreadsheet
+func (c *Client) addSheet(ctx context.Context, accessToken []byte, id, title string) error {
+	body := map[string]any{"requests": []any{
+		map[string]any{"addSheet": map[string]any{"properties": map[string]any{"title": title}}},
+	}}
+	return c.api(ctx, accessToken, http.MethodPost, "/"+url.PathEscape(id)+":batchUpdate", body, nil)
+}
+
+// appendRows adds rows after the last one of a sheet. Values are written
+// as is, so text that looks like a formula stays text.
+func (c *Client) appendRows(ctx context.Context, accessToken []byte, id, sheet string, rows [][]any) error {
+	path := "/" + url.PathEscape(id) + "/values/" + url.PathEscape(sheetRange(sheet)) +
+		":append?valueInputOption=RAW&insertDataOption=INSERT_ROWS"
+	return c.api(ctx, accessToken, http.MethodPost, path, map[string]any{"values": rows}, nil)
+}
+
+// sheetRange is the A1 range of a whole sheet, quoted for any title
+func sheetRange(sheet string) string {
+	return "'" + strings.ReplaceAll(sheet, "'", "''") + "'"
+}
+
+var spreadsheetURLPattern = regexp.MustCompile(`/spreadsheets/d/([a-zA-Z0-9_-]+)`)
+var spreadsheetIDPattern = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// spreadsheetID takes a spreadsheet's ID or the URL it's opened at
+func spreadsheetID(s string) (string, error) {
+	s = strings.TrimSpace(s)
+	if m := spreadsheetURLPattern.FindStringSubmatch(s); m != nil {
+		return m[1], nil
+	}
+	if spreadsheetIDPattern.MatchString(s) {
+		return s, nil
+	}
+	return "", errors.New("spreadsheet must be a Google Sheets URL or ID")
+}
@@ -0,0 +1,337 @@
+package sheets
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/shopspring/decimal"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+	"github.com/yanonymousV2/finance-manager-backend/internal/helpers"
+	"github.com/yanonymousV2/finance-manager-backend/internal/secret"
+)
+
+const (
+	// pageSize is how many expenses are appended per request
+	pageSize = 1000
+	// maxRowsPerExport caps one export; the rest follow in the next one
+	maxRowsPerExport = 20000
+	// exportTimeout bounds one integration's export in the job
+	exportTimeout = 5 * time.Minute
+)
+
+var (
+	errNoSpreadsheet = errors.New("no spreadsheet chosen")
+	errNotMember     = errors.New("you're no longer a member of the group")
+)
+
+// personalHeader and groupHeader are the first row of a sheet
+var (
+	personalHeader = []any{"Date", "Amount", "Currency", "Category", "Merchant", "Account", "Description", "Notes"}
+	groupHeader    = []any{"Date", "Description", "Category", "Amount", "Currency", "Paid by", "Your share", "Status"}
+)
+
+// nextExportAt is when an export on schedule runs after now, or nil when
+// exports aren't scheduled
+func nextExportAt(schedule string, now time.Time) *time.Time {
+	var next time.Time
+	switch schedule {
+	case ScheduleHourly:
+		next = now.Add(time.Hour)
+	case ScheduleDaily:
+		next = now.AddDate(0, 0, 1)
+	case ScheduleWeekly:
+		next = now.AddDate(0, 0, 7)
+	default:
+		return nil
+	}
+	return &next
+}
+
+// number writes an amount as a number rather than text
+func number(d decimal.Decimal) json.Number {
+	return json.Number(d.StringFixed(2))
+}
+
+// text writes a nullable string, empty when null
+func text(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
+}
+
+// accessToken returns a current sealed access token, refreshing and saving
+// it when the stored one is about to expire
+func accessToken(ctx context.Context, db *db.DB, client *Client, in *Integration) ([]byte, error) {
+	if in.accessToken != nil && in.accessTokenExpiresAt != nil && time.Until(*in.accessTokenExpiresAt) > time.Minute {
+		return in.accessToken, nil
+	}
+	if in.refreshToken == nil {
+		return nil, errors.New("google account isn't connected")
+	}
+	g, err := client.refresh(ctx, in.refreshToken)
+	if err != nil {
+		return nil, err
+	}
+	_, err = db.Pool.Exec(ctx,
+		`UPDATE google_sheets_integrations SET access_token = $2, access_token_expires_at = $3 WHERE user_id = $1`,
+		in.UserID, g.AccessToken, g.ExpiresAt)
+	if err != nil {
+		return nil, err
+	}
+	in.accessToken, in.accessTokenExpiresAt = g.AccessToken, &g.ExpiresAt
+	return g.AccessToken, nil
+}
+
+// cursor is the created_at and ID of the last expense exported
+type cursor struct {
+	CreatedAt time.Time
+	ID        uuid.UUID
+}
+
+// page reads the next expenses after cur as sheet rows
+func page(ctx context.Context, db *db.DB, in *Integration, cur cursor, limit int) ([][]any, cursor, error) {
+	var rows pgx.Rows
+	var err error
+	if in.Source == SourceGroup {
+		rows, err = db.Pool.Query(ctx,
+			`SELECT e.id, e.created_at, e.expense_date, e.description, e.category, e.total_amount, e.currency, e.status,
+			        (SELECT string_agg(COALESCE(u.display_name, u.email), ', ' ORDER BY COALESCE(u.display_name, u.email))
+			         FROM expense_payers p JOIN users u ON u.id = p.user_id WHERE p.expense_id = e.id),
+			        (SELECT s.amount FROM expense_splits s WHERE s.expense_id = e.id AND s.user_id = $2)
+			 FROM expenses e
+			 WHERE e.group_id = $1 AND e.deleted_at IS NULL AND (e.created_at, e.id) > ($3, $4)
+			 ORDER BY e.created_at, e.id
+			 LIMIT $5`,
+			in.GroupID, in.UserID, cur.CreatedAt, cur.ID, limit)
+	} else {
+		rows, err = db.Pool.Query(ctx,
+			`SELECT pe.id, pe.created_at, pe.expense_date, pe.amount, pe.currency, ec.name, pe.merchant, a.name,
+			        pe.description, pe.notes
+			 FROM personal_expenses pe
+			 LEFT JOIN expense_categories ec ON pe.category_id = ec.id
+			 LEFT JOIN accounts a ON pe.account_id = a.id
+			 WHERE pe.user_id = $1 AND (pe.created_at, pe.id) > ($2, $3)
+			 ORDER BY pe.created_at, pe.id
+			 LIMIT $4`,
+			in.UserID, cur.CreatedAt, cur.ID, limit)
+	}
+	if err != nil {
+		return nil, cur, err
+	}
+	defer rows.Close()
+
+	var values [][]any
+	for rows.Next() {
+		var date time.Time
+		var amount decimal.Decimal
+		var currency string
+		if in.Source == SourceGroup {
+			var description, status string
+			var category, paidBy *string
+			var share *decimal.Decimal
+			if err := rows.Scan(&cur.ID, &cur.CreatedAt, &date, &description, &category, &amount, &currency, &status,
+				&paidBy, &share); err != nil {
+				return nil, cur, err
+			}
+			row := []any{date.Format("2006-01-02"), description, text(category), number(amount), currency, text(paidBy), "", status}
+			if share != nil {
+				row[6] = number(*share)
+			}
+			values = append(values, row)
+		} else {
+			var category, merchant, account, description, notes *string
+			if err := rows.Scan(&cur.ID, &cur.CreatedAt, &date, &amount, &currency, &category, &merchant, &account,
+				&description, &notes); err != nil {
+				return nil, cur, err
+			}
+			values = append(values, []any{date.Format("2006-01-02"), number(amount), currency, text(category),
+				text(merchant), text(account), text(description), text(notes)})
+		}
+	}
+	return values, cur, rows.Err()
+}
+
+// export appends the expenses created since the last export to the sheet,
+// returning how many it appended. The first export of a sheet adds it to
+// the spreadsheet when missing and writes the header. The cursor is saved
+// after each page, so a failed export picks up where it stopped.
+func export(ctx context.Context, db *db.DB, client *Client, in *Integration) (int, error) {
+	if in.SpreadsheetID == nil {
+		return 0, errNoSpreadsheet
+	}
+	if in.Source == SourceGroup {
+		if in.GroupID == nil {
+			return 0, errNotMember
+		}
+		isMember, err := helpers.IsGroupMember(ctx, db, *in.GroupID, in.UserID)
+		if err != nil {
+			return 0, err
+		}
+		if !isMember {
+			return 0, errNotMember
+		}
+	}
+
+	token, err := accessToken(ctx, db, client, in)
+	if err != nil {
+		return 0, err
+	}
+
+	// A sheet that has its header has a cursor, at the zero time until
+	// expenses are exported
+	var cur cursor
+	if in.cursorCreatedAt == nil {
+		s, err := client.spreadsheet(ctx, token, *in.SpreadsheetID)
+		if err != nil {
+			return 0, err
+		}
+		if !slices.Contains(s.Sheets, in.SheetName) {
+			if err := client.addSheet(ctx, token, *in.SpreadsheetID, in.SheetName); err != nil {
+				return 0, err
+			}
+		}
+		header := personalHeader
+		if in.Source == SourceGroup {
+			header = groupHeader
+		}
+		if err := client.appendRows(ctx, token, *in.SpreadsheetID, in.SheetName, [][]any{header}); err != nil {
+			return 0, err
+		}
+		if err := saveCursor(ctx, db, in.UserID, cur, 0); err != nil {
+			return 0, err
+		}
+	} else {
+		cur = cursor{CreatedAt: *in.cursorCreatedAt, ID: *in.cursorID}
+	}
+
+	n := 0
+	for n < maxRowsPerExport {
+		values, next, err := page(ctx, db, in, cur, min(pageSize, maxRowsPerExport-n))
+		if err != nil {
+			return n, fmt.Errorf("failed to read expenses: %w", err)
+		}
+		if len(values) == 0 {
+			break
+		}
+		if err := client.appendRows(ctx, token, *in.SpreadsheetID, in.SheetName, values); err != nil {
+			return n, err
+		}
+		if err := saveCursor(ctx, db, in.UserID, next, len(values)); err != nil {
+			return n, err
+		}
+		cur = next
+		n += len(values)
+		if len(values) < pageSize {
+			break
+		}
+	}
+	return n, nil
+}
+
+func saveCursor(ctx context.Context, db *db.DB, userID uuid.UUID, cur cursor, rows int) error {
+	_, err := db.Pool.Exec(ctx,
+		`UPDATE google_sheets_integrations
+		 SET cursor_created_at = $2, cursor_id = $3, rows_exported = rows_exported + $4
+		 WHERE user_id = $1`,
+		userID, cur.CreatedAt, cur.ID, rows)
+	return err
+}
+
+// record stores how an export went and schedules the next one. A revoked
+// grant needs the user to connect again.
+func record(ctx context.Context, db *db.DB, in *Integration, exportErr error) error {
+	if exportErr == nil {
+		_, err := db.Pool.Exec(ctx,
+			`UPDATE google_sheets_integrations
+			 SET last_exported_at = NOW(), last_error = NULL, next_export_at = $2, updated_at = NOW()
+			 WHERE user_id = $1`,
+			in.UserID, nextExportAt(in.Schedule, time.Now()))
+		return err
+	}
+
+	status := StatusActive
+	if isRevoked(exportErr) {
+		status = StatusReauthRequired
+	}
+	_, err := db.Pool.Exec(ctx,
+		`UPDATE google_sheets_integrations
+		 SET status = $2, last_error = $3, next_export_at = $4, updated_at = NOW()
+		 WHERE user_id = $1`,
+		in.UserID, status, exportErr.Error(), nextExportAt(in.Schedule, time.Now()))
+	return err
+}
+
+// sealLegacyTokens encrypts the refresh tokens stored before tokens were
+// encrypted
+func sealLegacyTokens(ctx context.Context, db *db.DB, client *Client) error {
+	rows, err := db.Pool.Query(ctx,
+		"SELECT user_id, refresh_token FROM google_sheets_integrations WHERE get_byte(refresh_token, 0) <> $1",
+		int(secret.Version))
+	if err != nil {
+		return err
+	}
+	type legacy struct {
+		UserID       uuid.UUID
+		RefreshToken []byte
+	}
+	integrations, err := pgx.CollectRows(rows, pgx.RowToStructByPos[legacy])
+	if err != nil {
+		return err
+	}
+	for _, in := range integrations {
+		_, err := db.Pool.Exec(ctx,
+			"UPDATE google_sheets_integrations SET refresh_token = $2 WHERE user_id = $1 AND refresh_token = $3",
+			in.UserID, client.box.Seal(string(in.RefreshToken)), in.RefreshToken)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ExportDue runs the scheduled exports that are due
+func ExportDue(ctx context.Context, db *db.DB, client *Client) error {
+	if err := sealLegacyTokens(ctx, db, client); err != nil {
+		return fmt.Errorf("failed to encrypt refresh tokens: %w", err)
+	}
+
+	rows, err := db.Pool.Query(ctx,
+		`SELECT `+integrationColumns+` FROM google_sheets_integrations
+		 WHERE status = 'active' AND schedule <> 'off' AND spreadsheet_id IS NOT NULL AND next_export_at <= NOW()
+		 ORDER BY next_export_at`)
+	if err != nil {
+		return fmt.Errorf("failed to load due exports: %w", err)
+	}
+	due, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (Integration, error) {
+		return scanIntegration(row)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to load due exports: %w", err)
+	}
+
+	for _, in := range due {
+		// Stop between exports on shutdown
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		exportCtx, cancel := context.WithTimeout(ctx, exportTimeout)
+		n, err := export(exportCtx, db, client, &in)
+		cancel()
+		if err != nil {
+			slog.Warn("google sheets export failed", "user_id", in.UserID, "rows", n, "error", err)
+		}
+		if err := record(context.WithoutCancel(ctx), db, &in, err); err != nil {
+			slog.Error("failed to record google sheets export", "user_id", in.UserID, "error", err)
+		}
+	}
+	return nil
+}
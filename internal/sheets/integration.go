@@ -0,0 +1,405 @@
+package sheets
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+	"github.com/yanonymousV2/finance-manager-backend/internal/helpers"
+	"github.com/yanonymousV2/finance-manager-backend/internal/middleware"
+	"github.com/yanonymousV2/finance-manager-backend/internal/requestid"
+	"github.com/yanonymousV2/finance-manager-backend/internal/validation"
+)
+
+// Integration statuses
+const (
+	StatusPending        = "pending"
+	StatusActive         = "active"
+	StatusReauthRequired = "reauth_required"
+)
+
+// What an integration exports
+const (
+	SourcePersonal = "personal"
+	SourceGroup    = "group"
+)
+
+// Export schedules
+const (
+	ScheduleOff    = "off"
+	ScheduleHourly = "hourly"
+	ScheduleDaily  = "daily"
+	ScheduleWeekly = "weekly"
+)
+
+// stateTTL is how long the user has to grant access after connecting
+const stateTTL = 10 * time.Minute
+
+// Integration is a user's Google Sheets export. It's pending until they
+// have granted access, and needs them to connect again when the grant is
+// revoked. Its tokens are never returned.
+type Integration struct {
+	UserID         uuid.UUID  `json:"user_id"`
+	Status         string     `json:"status"`
+	SpreadsheetID  *string    `json:"spreadsheet_id,omitempty"`
+	SheetName      string     `json:"sheet_name"`
+	Source         string     `json:"source"`
+	GroupID        *uuid.UUID `json:"group_id,omitempty"`
+	Schedule       string     `json:"schedule"`
+	RowsExported   int        `json:"rows_exported"`
+	LastExportedAt *time.Time `json:"last_exported_at,omitempty"`
+	NextExportAt   *time.Time `json:"next_export_at,omitempty"`
+	LastError      *string    `json:"last_error,omitempty"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+
+	refreshToken         []byte
+	accessToken          []byte
+	accessTokenExpiresAt *time.Time
+	cursorCreatedAt      *time.Time
+	cursorID             *uuid.UUID
+}
+
+// ConnectResponse is where to send the user to grant access
+type ConnectResponse struct {
+	AuthURL string `json:"auth_url"`
+}
+
+// UpdateIntegrationRequest changes the fields that are set. Spreadsheet is
+// the spreadsheet's URL or ID. Exporting somewhere else, or something else,
+// starts over with every expense.
+type UpdateIntegrationRequest struct {
+	Spreadsheet *string    `json:"spreadsheet,omitempty" validate:"omitempty,max=500"`
+	SheetName   *string    `json:"sheet_name,omitempty" validate:"omitempty,min=1,max=100"`
+	Source      *string    `json:"source,omitempty" validate:"omitempty,oneof=personal group"`
+	GroupID     *uuid.UUID `json:"group_id,omitempty"`
+	Schedule    *string    `json:"schedule,omitempty" validate:"omitempty,oneof=off hourly daily weekly"`
+}
+
+// ExportResult is how many expenses an export appended
+type ExportResult struct {
+	Rows        int         `json:"rows"`
+	Integration Integration `json:"integration"`
+}
+
+const integrationColumns = `user_id, status, spreadsheet_id, sheet_name, source, group_id, schedule, rows_exported,
+	last_exported_at, next_export_at, last_error, created_at, updated_at,
+	refresh_token, access_token, access_token_expires_at, cursor_created_at, cursor_id`
+
+func scanIntegration(row interface{ Scan(...any) error }) (Integration, error) {
+	var in Integration
+	err := row.Scan(&in.UserID, &in.Status, &in.SpreadsheetID, &in.SheetName, &in.Source, &in.GroupID, &in.Schedule,
+		&in.RowsExported, &in.LastExportedAt, &in.NextExportAt, &in.LastError, &in.CreatedAt, &in.UpdatedAt,
+		&in.refreshToken, &in.accessToken, &in.accessTokenExpiresAt, &in.cursorCreatedAt, &in.cursorID)
+	return in, err
+}
+
+// unavailable responds when Google Sheets isn't set up
+func unavailable(c *gin.Context) {
+	c.JSON(503, gin.H{"error": "google sheets export is unavailable"})
+}
+
+// loadIntegration returns the user's integration. On failure it has
+// already responded.
+func loadIntegration(c *gin.Context, db *db.DB, userID uuid.UUID) (Integration, bool) {
+	in, err := scanIntegration(db.Pool.QueryRow(c.Request.Context(),
+		`SELECT `+integrationColumns+` FROM google_sheets_integrations WHERE user_id = $1`, userID))
+	if errors.Is(err, pgx.ErrNoRows) {
+		c.JSON(404, gin.H{"error": "google sheets is not connected"})
+		return in, false
+	}
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to get integration"})
+		return in, false
+	}
+	return in, true
+}
+
+// respondGoogleError reports a spreadsheet the account can't open as the
+// user's mistake, and a revoked grant as needing to connect again
+func respondGoogleError(c *gin.Context, err error, msg string) {
+	var googleErr *Error
+	switch {
+	case isRevoked(err):
+		c.JSON(409, gin.H{"error": "google access was revoked; connect again"})
+	case errors.Is(err, errNoSpreadsheet):
+		c.JSON(400, gin.H{"error": "choose a spreadsheet first"})
+	case errors.Is(err, errNotMember):
+		c.JSON(403, gin.H{"error": err.Error()})
+	case errors.As(err, &googleErr) && (googleErr.HTTPStatus == http.StatusNotFound || googleErr.HTTPStatus == http.StatusForbidden):
+		c.JSON(400, gin.H{"error": "spreadsheet not found, or the connected Google account can't edit it"})
+	default:
+		requestid.Logger(c.Request.Context()).Error(msg, "error", err)
+		c.JSON(502, gin.H{"error": msg})
+	}
+}
+
+// Connect starts connecting a Google account, returning the URL to send the
+// user to. Google redirects back to Callback. Connecting again keeps the
+// current settings.
+func Connect(c *gin.Context, db *db.DB, client *Client) {
+	if client == nil {
+		unavailable(c)
+		return
+	}
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	state := uuid.New()
+	_, err := db.Pool.Exec(c.Request.Context(),
+		`INSERT INTO google_sheets_integrations (user_id, oauth_state, oauth_state_expires_at)
+		 VALUES ($1, $2, NOW() + $3::interval)
+		 ON CONFLICT (user_id) DO UPDATE
+		 SET oauth_state = EXCLUDED.oauth_state, oauth_state_expires_at = EXCLUDED.oauth_state_expires_at, updated_at = NOW()`,
+		userID, state, stateTTL)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to start connecting"})
+		return
+	}
+
+	c.JSON(200, ConnectResponse{AuthURL: client.authCodeURL(state.String())})
+}
+
+// Callback is where Google sends the user back after they've granted, or
+// declined, access. It needs no login: the state identifies the user.
+func Callback(c *gin.Context, db *db.DB, client *Client) {
+	if client == nil {
+		unavailable(c)
+		return
+	}
+	if c.Query("error") != "" {
+		c.JSON(400, gin.H{"error": "google access was not granted"})
+		return
+	}
+	state, err := uuid.Parse(c.Query("state"))
+	if err != nil || c.Query("code") == "" {
+		c.JSON(400, gin.H{"error": "invalid callback"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	var userID uuid.UUID
+	err = db.Pool.QueryRow(ctx,
+		`SELECT user_id FROM google_sheets_integrations WHERE oauth_state = $1 AND oauth_state_expires_at > NOW()`,
+		state).Scan(&userID)
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid or expired state; connect again"})
+		return
+	}
+
+	g, err := client.exchange(ctx, c.Query("code"))
+	if err != nil {
+		requestid.Logger(ctx).Error("failed to exchange google code", "user_id", userID, "error", err)
+		c.JSON(502, gin.H{"error": "failed to connect google account"})
+		return
+	}
+	if g.RefreshToken == nil {
+		c.JSON(502, gin.H{"error": "google returned no refresh token"})
+		return
+	}
+
+	_, err = db.Pool.Exec(ctx,
+		`UPDATE google_sheets_integrations
+		 SET status = 'active', refresh_token = $2, access_token = $3, access_token_expires_at = $4,
+		     oauth_state = NULL, oauth_state_expires_at = NULL, last_error = NULL,
+		     next_export_at = CASE WHEN schedule <> 'off' THEN NOW() END, updated_at = NOW()
+		 WHERE user_id = $1`,
+		userID, g.RefreshToken, g.AccessToken, g.ExpiresAt)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to save google account"})
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "google sheets connected"})
+}
+
+// GetIntegration returns the user's Google Sheets export
+func GetIntegration(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	in, ok := loadIntegration(c, db, userID)
+	if !ok {
+		return
+	}
+	c.JSON(200, in)
+}
+
+// UpdateIntegration picks the spreadsheet, sheet, what's exported and the
+// schedule. A new spreadsheet is checked to be one the connected account
+// can open.
+func UpdateIntegration(c *gin.Context, db *db.DB, client *Client) {
+	if client == nil {
+		unavailable(c)
+		return
+	}
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req UpdateIntegrationRequest
+	if !validation.Bind(c, &req) {
+		return
+	}
+
+	in, ok := loadIntegration(c, db, userID)
+	if !ok {
+		return
+	}
+	if in.Status == StatusPending {
+		c.JSON(409, gin.H{"error": "finish connecting google first"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	restart := false
+	if req.Spreadsheet != nil {
+		id, err := spreadsheetID(*req.Spreadsheet)
+		if err != nil {
+			c.JSON(400, gin.H{"error": err.Error()})
+			return
+		}
+		if in.SpreadsheetID == nil || *in.SpreadsheetID != id {
+			token, err := accessToken(ctx, db, client, &in)
+			if err == nil {
+				_, err = client.spreadsheet(ctx, token, id)
+			}
+			if err != nil {
+				respondGoogleError(c, err, "failed to open spreadsheet")
+				return
+			}
+			in.SpreadsheetID, restart = &id, true
+		}
+	}
+	if req.SheetName != nil && *req.SheetName != in.SheetName {
+		in.SheetName, restart = *req.SheetName, true
+	}
+	if req.Source != nil && *req.Source != in.Source {
+		in.Source, restart = *req.Source, true
+	}
+	if req.GroupID != nil && (in.GroupID == nil || *in.GroupID != *req.GroupID) {
+		in.GroupID, restart = req.GroupID, true
+	}
+	if in.Source == SourcePersonal {
+		in.GroupID = nil
+	} else {
+		if in.GroupID == nil {
+			c.JSON(400, gin.H{"error": "group_id is required to export a group"})
+			return
+		}
+		isMember, err := helpers.IsGroupMember(ctx, db, *in.GroupID, userID)
+		if err != nil {
+			c.JSON(500, gin.H{"error": "failed to check group membership"})
+			return
+		}
+		if !isMember {
+			c.JSON(403, gin.H{"error": "not a member of this group"})
+			return
+		}
+	}
+	if req.Schedule != nil && *req.Schedule != in.Schedule {
+		in.Schedule, in.NextExportAt = *req.Schedule, nil
+		if in.Schedule != ScheduleOff {
+			// Export on the new schedule right away
+			now := time.Now()
+			in.NextExportAt = &now
+		}
+	}
+
+	query := `UPDATE google_sheets_integrations
+		 SET spreadsheet_id = $2, sheet_name = $3, source = $4, group_id = $5, schedule = $6, next_export_at = $7,
+		     updated_at = NOW()`
+	if restart {
+		query += `, cursor_created_at = NULL, cursor_id = NULL, rows_exported = 0, last_exported_at = NULL, last_error = NULL`
+	}
+	in, err := scanIntegration(db.Pool.QueryRow(ctx, query+` WHERE user_id = $1 RETURNING `+integrationColumns,
+		userID, in.SpreadsheetID, in.SheetName, in.Source, in.GroupID, in.Schedule, in.NextExportAt))
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to update integration"})
+		return
+	}
+
+	c.JSON(200, in)
+}
+
+// ExportNow appends the expenses created since the last export right away
+func ExportNow(c *gin.Context, db *db.DB, client *Client) {
+	if client == nil {
+		unavailable(c)
+		return
+	}
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	in, ok := loadIntegration(c, db, userID)
+	if !ok {
+		return
+	}
+	if in.Status != StatusActive {
+		c.JSON(409, gin.H{"error": "connect google first"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	n, exportErr := export(ctx, db, client, &in)
+	if err := record(ctx, db, &in, exportErr); err != nil {
+		requestid.Logger(ctx).Error("failed to record google sheets export", "error", err)
+	}
+	if exportErr != nil {
+		respondGoogleError(c, exportErr, "failed to export to google sheets")
+		return
+	}
+
+	in, ok = loadIntegration(c, db, userID)
+	if !ok {
+		return
+	}
+	c.JSON(200, ExportResult{Rows: n, Integration: in})
+}
+
+// Disconnect revokes Google's grant and removes the integration. The sheet
+// keeps what was exported.
+func Disconnect(c *gin.Context, db *db.DB, client *Client) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	in, ok := loadIntegration(c, db, userID)
+	if !ok {
+		return
+	}
+
+	// The integration is removed even when Google can't be reached; the
+	// user can still revoke access from their Google account
+	if client != nil && in.refreshToken != nil {
+		if err := client.revoke(c.Request.Context(), in.refreshToken); err != nil {
+			requestid.Logger(c.Request.Context()).Warn("failed to revoke google token", "error", err)
+		}
+	}
+
+	if _, err := db.Pool.Exec(c.Request.Context(),
+		"DELETE FROM google_sheets_integrations WHERE user_id = $1", userID); err != nil {
+		c.JSON(500, gin.H{"error": "failed to disconnect google sheets"})
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "google sheets disconnected"})
+}
@@ -0,0 +1,140 @@
+package sheets
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/secret"
+)
+
+var testBox, _ = secret.New(make([]byte, secret.KeySize))
+
+// testClient points a client at srv for every Google endpoint
+func testClient(srv *httptest.Server) *Client {
+	c := NewClient("client-id", "client-secret", "https://api.example.com/integrations/google-sheets/callback", testBox)
+	c.tokenURL = srv.URL + "/token"
+	c.revokeURL = srv.URL + "/revoke"
+	c.sheetsURL = srv.URL + "/v4/spreadsheets"
+	return c
+}
+
+func TestAuthCodeURL(t *testing.T) {
+	c := NewClient("client-id", "client-secret", "https://api.example.com/integrations/google-sheets/callback", testBox)
+	u, err := url.Parse(c.authCodeURL("state-1"))
+	require.NoError(t, err)
+	q := u.Query()
+	assert.Equal(t, "client-id", q.Get("client_id"))
+	assert.Equal(t, "https://api.example.com/integrations/google-sheets/callback", q.Get("redirect_uri"))
+	assert.Equal(t, "offline", q.Get("access_type"), "a refresh token is needed for scheduled exports")
+	assert.Equal(t, "state-1", q.Get("state"))
+	assert.Equal(t, scope, q.Get("scope"))
+}
+
+func TestExchange(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "authorization_code", r.Form.Get("grant_type"))
+		assert.Equal(t, "code-1", r.Form.Get("code"))
+		assert.Equal(t, "client-secret", r.Form.Get("client_secret"))
+		w.Write([]byte(`{"access_token": "access", "refresh_token": "refresh", "expires_in": 3599}`))
+	}))
+	defer srv.Close()
+
+	g, err := testClient(srv).exchange(t.Context(), "code-1")
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().Add(3599*time.Second), g.ExpiresAt, time.Second)
+
+	// Tokens are only stored sealed
+	access, err := testBox.Open(g.AccessToken)
+	require.NoError(t, err)
+	assert.Equal(t, "access", access)
+	refresh, err := testBox.Open(g.RefreshToken)
+	require.NoError(t, err)
+	assert.Equal(t, "refresh", refresh)
+}
+
+func TestRefreshRevoked(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.NoError(t, r.ParseForm())
+		assert.Equal(t, "refresh", r.Form.Get("refresh_token"), "the sealed token is opened for the call")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error": "invalid_grant", "error_description": "Token has been expired or revoked."}`))
+	}))
+	defer srv.Close()
+
+	_, err := testClient(srv).refresh(t.Context(), testBox.Seal("refresh"))
+	assert.EqualError(t, err, "google: Token has been expired or revoked. (invalid_grant)")
+	assert.True(t, isRevoked(err))
+}
+
+func TestSpreadsheetNotFound(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"error": {"code": 404, "message": "Requested entity was not found.", "status": "NOT_FOUND"}}`))
+	}))
+	defer srv.Close()
+
+	_, err := testClient(srv).spreadsheet(t.Context(), testBox.Seal("access"), "abc")
+	var googleErr *Error
+	require.ErrorAs(t, err, &googleErr)
+	assert.Equal(t, http.StatusNotFound, googleErr.HTTPStatus)
+	assert.False(t, isRevoked(err))
+}
+
+func TestAppendRows(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer access", r.Header.Get("Authorization"))
+		assert.Equal(t, "/v4/spreadsheets/abc/values/'Bob''s expenses':append", r.URL.Path)
+		assert.Equal(t, "RAW", r.URL.Query().Get("valueInputOption"), "text that looks like a formula stays text")
+
+		var body struct {
+			Values [][]any `json:"values"`
+		}
+		data, _ := io.ReadAll(r.Body)
+		require.NoError(t, json.Unmarshal(data, &body))
+		assert.Equal(t, [][]any{{"2025-01-20", 12.5, "=HYPERLINK(\"x\")"}}, body.Values)
+		w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	err := testClient(srv).appendRows(t.Context(), testBox.Seal("access"), "abc", "Bob's expenses",
+		[][]any{{"2025-01-20", number(decimal.RequireFromString("12.5")), "=HYPERLINK(\"x\")"}})
+	require.NoError(t, err)
+}
+
+func TestSpreadsheetID(t *testing.T) {
+	id, err := spreadsheetID("https://docs.google.com/spreadsheets/d/1AbC-d_E/edit#gid=0")
+	require.NoError(t, err)
+	assert.Equal(t, "1AbC-d_E", id)
+
+	id, err = spreadsheetID(" 1AbC-d_E ")
+	require.NoError(t, err)
+	assert.Equal(t, "1AbC-d_E", id)
+
+	_, err = spreadsheetID("https://example.com/sheet")
+	assert.Error(t, err)
+}
+
+func TestNextExportAt(t *testing.T) {
+	now := time.Date(2025, 1, 20, 12, 0, 0, 0, time.UTC)
+	assert.Nil(t, nextExportAt(ScheduleOff, now))
+	assert.Equal(t, now.Add(time.Hour), *nextExportAt(ScheduleHourly, now))
+	assert.Equal(t, now.AddDate(0, 0, 1), *nextExportAt(ScheduleDaily, now))
+	assert.Equal(t, now.AddDate(0, 0, 7), *nextExportAt(ScheduleWeekly, now))
+}
+
+func TestLegacyRefreshToken(t *testing.T) {
+	c := NewClient("client-id", "client-secret", "https://api.example.com/integrations/google-sheets/callback", testBox)
+	token, err := c.open([]byte("1//legacy"))
+	require.NoError(t, err)
+	assert.Equal(t, "1//legacy", token, "tokens stored before they were encrypted work until sealed")
+}
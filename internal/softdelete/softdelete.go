@@ -0,0 +1,111 @@
+// Package softdelete implements deleting rows by setting deleted_at and
+// deleted_by, restoring them, and purging them for good once they have been
+// deleted long enough. Queries on these tables filter with "deleted_at IS
+// NULL" so deleted rows behave as if they were gone.
+package softdelete
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+)
+
+// Table is a table whose rows are soft deleted
+type Table string
+
+const (
+	Expenses    Table = "expenses"
+	Settlements Table = "settlements"
+	Categories  Table = "expense_categories"
+	Groups      Table = "groups"
+)
+
+// Tables lists every soft deleted table, in the order Purge empties them:
+// a group's expenses and settlements before the group
+var Tables = []Table{Expenses, Settlements, Categories, Groups}
+
+// Execer is satisfied by both the pool and a transaction
+type Execer interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+// Delete marks the row id of table deleted by userID. It reports false if
+// there is no such row or it's already deleted.
+func Delete(ctx context.Context, q Execer, table Table, id, userID uuid.UUID) (bool, error) {
+	tag, err := q.Exec(ctx,
+		"UPDATE "+string(table)+" SET deleted_at = NOW(), deleted_by = $2 WHERE id = $1 AND deleted_at IS NULL",
+		id, userID)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// Restore undoes Delete. It reports false if there is no such row or it
+// isn't deleted.
+func Restore(ctx context.Context, q Execer, table Table, id uuid.UUID) (bool, error) {
+	tag, err := q.Exec(ctx,
+		"UPDATE "+string(table)+" SET deleted_at = NULL, deleted_by = NULL WHERE id = $1 AND deleted_at IS NOT NULL",
+		id)
+	if err != nil {
+		return false, err
+	}
+	return tag.RowsAffected() > 0, nil
+}
+
+// Result is how many rows of a table were, or would be, purged
+type Result struct {
+	Table Table
+	Rows  int64
+}
+
+// Count returns how many rows of each table Purge would remove
+func Count(ctx context.Context, db *db.DB, cutoff time.Time) ([]Result, error) {
+	results := make([]Result, len(Tables))
+	for i, table := range Tables {
+		results[i].Table = table
+		err := db.Pool.QueryRow(ctx,
+			"SELECT COUNT(*) FROM "+string(table)+" WHERE deleted_at < $1", cutoff).Scan(&results[i].Rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count deleted %s: %w", table, err)
+		}
+	}
+	return results, nil
+}
+
+// Purge permanently removes rows deleted before cutoff, with everything
+// that belongs to them: an expense's payers, splits, comments and
+// revisions, or a group's expenses and settlements. They can no longer be
+// restored.
+func Purge(ctx context.Context, db *db.DB, cutoff time.Time) ([]Result, error) {
+	results := make([]Result, len(Tables))
+	for i, table := range Tables {
+		tag, err := db.Pool.Exec(ctx, "DELETE FROM "+string(table)+" WHERE deleted_at < $1", cutoff)
+		if err != nil {
+			return nil, fmt.Errorf("failed to purge deleted %s: %w", table, err)
+		}
+		results[i] = Result{Table: table, Rows: tag.RowsAffected()}
+	}
+	return results, nil
+}
+
+// PurgeExpired purges rows deleted more than retention ago and logs what
+// it removed. It's run by the scheduler.
+func PurgeExpired(ctx context.Context, db *db.DB, retention time.Duration) error {
+	results, err := Purge(ctx, db, time.Now().Add(-retention))
+	if err != nil {
+		return err
+	}
+	for _, r := range results {
+		if r.Rows > 0 {
+			slog.Info("purged deleted rows", "table", r.Table, "rows", r.Rows)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,113 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/sigv4"
+)
+
+// S3 stores files in an S3-compatible bucket using path-style addressing, so
+// it also works against MinIO and similar services. Every request is a
+// presigned URL (AWS Signature Version 4).
+type S3 struct {
+	endpoint  *url.URL
+	region    string
+	bucket    string
+	accessKey string
+	secretKey string
+	client    *http.Client
+}
+
+// NewS3 returns an S3 backend. An empty endpoint means AWS S3 in region.
+func NewS3(endpoint, region, bucket, accessKey, secretKey string) *S3 {
+	if region == "" {
+		region = "us-east-1"
+	}
+	if endpoint == "" {
+		endpoint = "https://s3." + region + ".amazonaws.com"
+	}
+	u, err := url.Parse(strings.TrimRight(endpoint, "/"))
+	if err != nil {
+		u = &url.URL{Scheme: "https", Host: endpoint}
+	}
+	return &S3{
+		endpoint:  u,
+		region:    region,
+		bucket:    bucket,
+		accessKey: accessKey,
+		secretKey: secretKey,
+		client:    &http.Client{Timeout: 60 * time.Second},
+	}
+}
+
+func (s *S3) presignedURL(method, key string, ttl time.Duration) string {
+	path := s.endpoint.Path + "/" + sigv4.Escape(s.bucket, false) + "/" + sigv4.Escape(key, true)
+	query := sigv4.Presign(method, s.endpoint.Host, path, s.accessKey, s.secretKey, s.region, "s3", time.Now(), ttl)
+	return s.endpoint.Scheme + "://" + s.endpoint.Host + path + "?" + query
+}
+
+func (s *S3) do(req *http.Request, okStatus ...int) error {
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	for _, status := range okStatus {
+		if resp.StatusCode == status {
+			return nil
+		}
+	}
+	if resp.StatusCode == http.StatusNotFound {
+		return ErrNotFound
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return fmt.Errorf("s3 %s: %s: %s", req.Method, resp.Status, body)
+}
+
+func (s *S3) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, s.presignedURL(http.MethodPut, key, 15*time.Minute), r)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	req.Header.Set("Content-Type", contentType)
+	return s.do(req, http.StatusOK)
+}
+
+func (s *S3) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, s.presignedURL(http.MethodGet, key, 15*time.Minute), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusOK {
+		return resp.Body, nil
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return nil, ErrNotFound
+	}
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+	return nil, fmt.Errorf("s3 GET: %s: %s", resp.Status, body)
+}
+
+func (s *S3) Delete(ctx context.Context, key string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodDelete, s.presignedURL(http.MethodDelete, key, 15*time.Minute), nil)
+	if err != nil {
+		return err
+	}
+	return s.do(req, http.StatusOK, http.StatusNoContent)
+}
+
+func (s *S3) SignedURL(key string, ttl time.Duration) (string, error) {
+	return s.presignedURL(http.MethodGet, key, ttl), nil
+}
@@ -0,0 +1,44 @@
+package storage
+
+import (
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalSignedURL(t *testing.T) {
+	l, err := NewLocal(t.TempDir(), "http://localhost:8080", "secret")
+	require.NoError(t, err)
+
+	expires := time.Now().Add(time.Minute).Unix()
+	expiresStr := strconv.FormatInt(expires, 10)
+	sig := l.sign("receipts/a.pdf", expires)
+
+	assert.True(t, l.verify("receipts/a.pdf", expiresStr, sig, time.Now()))
+	assert.False(t, l.verify("receipts/b.pdf", expiresStr, sig, time.Now()))
+	assert.False(t, l.verify("receipts/a.pdf", expiresStr, sig, time.Now().Add(2*time.Minute)))
+
+	_, err = l.SignedURL("../etc/passwd", time.Minute)
+	assert.Error(t, err)
+}
+
+func TestLocalGet(t *testing.T) {
+	l, err := NewLocal(t.TempDir(), "http://localhost:8080", "secret")
+	require.NoError(t, err)
+
+	require.NoError(t, l.Put(t.Context(), "receipts/a.txt", strings.NewReader("receipt"), 7, "text/plain"))
+	f, err := l.Get(t.Context(), "receipts/a.txt")
+	require.NoError(t, err)
+	data, err := io.ReadAll(f)
+	f.Close()
+	require.NoError(t, err)
+	assert.Equal(t, "receipt", string(data))
+
+	_, err = l.Get(t.Context(), "receipts/b.txt")
+	assert.ErrorIs(t, err, ErrNotFound)
+}
@@ -0,0 +1,221 @@
+// Package stream sends group events to connected clients as Server-Sent
+// Events, so group screens update without polling. Events come from the
+// events outbox: a trigger announces each new group event with
+// NOTIFY, and every server's Hub listens and passes it to that server's
+// clients.
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+)
+
+const (
+	// channel is the NOTIFY channel new group events are announced on
+	channel = "events"
+	// bufferSize is how many events a client can fall behind by before
+	// it's disconnected; it catches up when it reconnects
+	bufferSize = 64
+	// reconnectDelay is how long to wait before listening again after the
+	// listening connection fails
+	reconnectDelay = 5 * time.Second
+)
+
+// ErrClosed is returned by Subscribe once the hub has been closed for
+// shutdown
+var ErrClosed = errors.New("stream hub is closed")
+
+// Event is a group event as clients receive it; the same shape as a webhook
+// delivery's body
+type Event struct {
+	ID        uuid.UUID       `json:"id"`
+	Type      string          `json:"type"`
+	UserID    uuid.UUID       `json:"user_id"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+// Subscription receives one group's events. Events is closed when the hub
+// drops it: the client fell too far behind, the hub lost its listening
+// connection and may have missed events, or the server is shutting down.
+type Subscription struct {
+	hub     *Hub
+	groupID uuid.UUID
+	events  chan Event
+}
+
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Close stops the subscription
+func (s *Subscription) Close() {
+	s.hub.remove(s)
+}
+
+// Hub passes new group events to the subscriptions for their group
+type Hub struct {
+	db *db.DB
+
+	mu     sync.Mutex
+	groups map[uuid.UUID]map[*Subscription]struct{}
+	closed bool
+}
+
+func NewHub(db *db.DB) *Hub {
+	return &Hub{db: db, groups: make(map[uuid.UUID]map[*Subscription]struct{})}
+}
+
+// Subscribe starts receiving a group's events
+func (h *Hub) Subscribe(groupID uuid.UUID) (*Subscription, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		return nil, ErrClosed
+	}
+	s := &Subscription{hub: h, groupID: groupID, events: make(chan Event, bufferSize)}
+	if h.groups[groupID] == nil {
+		h.groups[groupID] = make(map[*Subscription]struct{})
+	}
+	h.groups[groupID][s] = struct{}{}
+	return s, nil
+}
+
+func (h *Hub) remove(s *Subscription) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.drop(s)
+}
+
+// drop removes s and closes its channel, if it hasn't been already. The
+// caller holds h.mu.
+func (h *Hub) drop(s *Subscription) {
+	subs := h.groups[s.groupID]
+	if _, ok := subs[s]; !ok {
+		return
+	}
+	delete(subs, s)
+	if len(subs) == 0 {
+		delete(h.groups, s.groupID)
+	}
+	close(s.events)
+}
+
+// dropAll drops every subscription
+func (h *Hub) dropAll() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, subs := range h.groups {
+		for s := range subs {
+			h.drop(s)
+		}
+	}
+}
+
+// Close drops every subscription and refuses new ones, ending the open
+// streams so the server can shut down
+func (h *Hub) Close() {
+	h.mu.Lock()
+	h.closed = true
+	h.mu.Unlock()
+	h.dropAll()
+}
+
+// watching reports whether anyone is subscribed to a group
+func (h *Hub) watching(groupID uuid.UUID) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return len(h.groups[groupID]) > 0
+}
+
+// publish passes e to the group's subscriptions, dropping any that are too
+// far behind to take it
+func (h *Hub) publish(groupID uuid.UUID, e Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for s := range h.groups[groupID] {
+		select {
+		case s.events <- e:
+		default:
+			h.drop(s)
+		}
+	}
+}
+
+// Run listens for new group events until ctx is cancelled. When the
+// listening connection fails every subscription is dropped, since events
+// may have been missed, and it listens again after reconnectDelay.
+func (h *Hub) Run(ctx context.Context) {
+	for {
+		err := h.listen(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		slog.Error("stopped listening for group events", "error", err)
+		h.dropAll()
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(reconnectDelay):
+		}
+	}
+}
+
+// listen holds a connection of its own, taken out of the pool, for as long
+// as it listens
+func (h *Hub) listen(ctx context.Context) error {
+	pooled, err := h.db.Pool.Acquire(ctx)
+	if err != nil {
+		return err
+	}
+	conn := pooled.Hijack()
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+channel); err != nil {
+		return err
+	}
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		var announced struct {
+			ID      uuid.UUID `json:"id"`
+			GroupID uuid.UUID `json:"group_id"`
+		}
+		if err := json.Unmarshal([]byte(n.Payload), &announced); err != nil {
+			slog.Warn("invalid event notification", "payload", n.Payload, "error", err)
+			continue
+		}
+		if !h.watching(announced.GroupID) {
+			continue
+		}
+		e, err := h.load(ctx, announced.ID)
+		if errors.Is(err, pgx.ErrNoRows) {
+			// Already purged
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to load event %s: %w", announced.ID, err)
+		}
+		h.publish(announced.GroupID, e)
+	}
+}
+
+func (h *Hub) load(ctx context.Context, id uuid.UUID) (Event, error) {
+	var e Event
+	err := h.db.Pool.QueryRow(ctx,
+		"SELECT id, type, user_id, created_at, data FROM events WHERE id = $1", id).
+		Scan(&e.ID, &e.Type, &e.UserID, &e.CreatedAt, &e.Data)
+	return e, err
+}
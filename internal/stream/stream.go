@@ -0,0 +1,186 @@
+package stream
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+	"github.com/yanonymousV2/finance-manager-backend/internal/helpers"
+	"github.com/yanonymousV2/finance-manager-backend/internal/middleware"
+)
+
+const (
+	// keepAlive is how often a comment is sent on an idle stream, so
+	// proxies don't close it
+	keepAlive = 25 * time.Second
+	// retryAfter is how long clients wait before reconnecting
+	retryAfter = 5 * time.Second
+	// replayLimit is how many missed events are sent on reconnecting; a
+	// client that missed more gets a reset event
+	replayLimit = 500
+)
+
+// writeEvent writes e as a Server-Sent Event named after its type, with its
+// ID for Last-Event-ID
+func writeEvent(w io.Writer, e Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data)
+	return err
+}
+
+// writeReset tells the client it missed events that can't be replayed, so
+// it should reload the group
+func writeReset(w io.Writer) error {
+	_, err := io.WriteString(w, "event: reset\ndata: {}\n\n")
+	return err
+}
+
+// GroupStream streams a group's events to a member until they disconnect
+// or stop being a member, which is checked with every keep-alive. A client
+// reconnecting with Last-Event-ID first gets the events it missed.
+func GroupStream(c *gin.Context, db *db.DB, hub *Hub) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	if hub == nil {
+		c.JSON(503, gin.H{"error": "live updates are unavailable"})
+		return
+	}
+
+	groupID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid group id"})
+		return
+	}
+
+	isMember, err := helpers.IsGroupMember(c.Request.Context(), db, groupID, userID)
+	if err != nil || !isMember {
+		c.JSON(403, gin.H{"error": "not a member of the group"})
+		return
+	}
+
+	var lastEventID *uuid.UUID
+	if header := c.GetHeader("Last-Event-ID"); header != "" {
+		id, err := uuid.Parse(header)
+		if err != nil {
+			c.JSON(400, gin.H{"error": "invalid Last-Event-ID"})
+			return
+		}
+		lastEventID = &id
+	}
+
+	// Subscribe before replaying so nothing falls between the two
+	sub, err := hub.Subscribe(groupID)
+	if err != nil {
+		c.JSON(503, gin.H{"error": "live updates are unavailable"})
+		return
+	}
+	defer sub.Close()
+
+	var missed []Event
+	reset := false
+	if lastEventID != nil {
+		missed, reset, err = replay(c, db, groupID, *lastEventID)
+		if err != nil {
+			c.JSON(500, gin.H{"error": "failed to load missed events"})
+			return
+		}
+	}
+
+	// The stream outlives the server's write timeout
+	_ = http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{})
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(200)
+
+	w := c.Writer
+	fmt.Fprintf(w, "retry: %d\n\n", retryAfter.Milliseconds())
+	if reset {
+		writeReset(w)
+	}
+	sent := make(map[uuid.UUID]bool, len(missed))
+	for _, e := range missed {
+		if writeEvent(w, e) != nil {
+			return
+		}
+		sent[e.ID] = true
+	}
+	w.Flush()
+
+	ticker := time.NewTicker(keepAlive)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case e, ok := <-sub.Events():
+			if !ok {
+				// Dropped; the client reconnects and catches up
+				return
+			}
+			if sent[e.ID] {
+				continue
+			}
+			if writeEvent(w, e) != nil {
+				return
+			}
+		case <-ticker.C:
+			// Members removed since, or of a group deleted since, stop
+			// getting its events
+			isMember, err := helpers.IsGroupMember(c.Request.Context(), db, groupID, userID)
+			if err != nil || !isMember {
+				return
+			}
+			if _, err := io.WriteString(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		}
+		w.Flush()
+	}
+}
+
+// replay returns the group's events after lastEventID, oldest first. reset
+// is true when they can't all be replayed: the event is gone, or too many
+// came after it.
+func replay(c *gin.Context, db *db.DB, groupID, lastEventID uuid.UUID) (events []Event, reset bool, err error) {
+	var known bool
+	err = db.Pool.QueryRow(c.Request.Context(),
+		"SELECT EXISTS(SELECT 1 FROM events WHERE id = $1 AND group_id = $2)", lastEventID, groupID).Scan(&known)
+	if err != nil {
+		return nil, false, err
+	}
+	if !known {
+		return nil, true, nil
+	}
+
+	rows, err := db.Pool.Query(c.Request.Context(),
+		`SELECT id, type, user_id, created_at, data FROM events
+		 WHERE group_id = $1 AND (created_at, id) > (SELECT created_at, id FROM events WHERE id = $2)
+		 ORDER BY created_at, id LIMIT $3`,
+		groupID, lastEventID, replayLimit+1)
+	if err != nil {
+		return nil, false, err
+	}
+	events, err = pgx.CollectRows(rows, pgx.RowToStructByPos[Event])
+	if err != nil {
+		return nil, false, err
+	}
+	if len(events) > replayLimit {
+		return nil, true, nil
+	}
+	return events, false, nil
+}
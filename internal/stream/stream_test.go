@@ -0,0 +1,84 @@
+package stream
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHub(t *testing.T) {
+	hub := NewHub(nil)
+	groupID, otherGroup := uuid.New(), uuid.New()
+
+	sub, err := hub.Subscribe(groupID)
+	require.NoError(t, err)
+	assert.True(t, hub.watching(groupID))
+	assert.False(t, hub.watching(otherGroup))
+
+	e := Event{ID: uuid.New(), Type: "expense.created"}
+	hub.publish(groupID, e)
+	hub.publish(otherGroup, Event{ID: uuid.New()})
+	assert.Equal(t, e, <-sub.Events())
+	assert.Empty(t, sub.Events())
+
+	sub.Close()
+	assert.False(t, hub.watching(groupID))
+	_, open := <-sub.Events()
+	assert.False(t, open)
+	sub.Close() // twice is fine
+}
+
+func TestHubDropsSlowSubscriptions(t *testing.T) {
+	hub := NewHub(nil)
+	groupID := uuid.New()
+	slow, err := hub.Subscribe(groupID)
+	require.NoError(t, err)
+
+	for range bufferSize + 1 {
+		hub.publish(groupID, Event{ID: uuid.New()})
+	}
+	received := 0
+	for range slow.Events() {
+		received++
+	}
+	assert.Equal(t, bufferSize, received, "the buffered events, then closed")
+	assert.False(t, hub.watching(groupID))
+}
+
+func TestHubClose(t *testing.T) {
+	hub := NewHub(nil)
+	sub, err := hub.Subscribe(uuid.New())
+	require.NoError(t, err)
+
+	hub.Close()
+	select {
+	case _, open := <-sub.Events():
+		assert.False(t, open)
+	case <-time.After(time.Second):
+		t.Fatal("subscription not closed")
+	}
+	_, err = hub.Subscribe(uuid.New())
+	assert.ErrorIs(t, err, ErrClosed)
+}
+
+func TestWriteEvent(t *testing.T) {
+	e := Event{
+		ID:        uuid.MustParse("5b0f6c2e-4a7c-4f3e-9a51-0c1d2e3f4a5b"),
+		Type:      "settlement.created",
+		UserID:    uuid.MustParse("0e6f1a2b-3c4d-4e5f-8a9b-0c1d2e3f4a5b"),
+		CreatedAt: time.Date(2024, 3, 1, 9, 0, 0, 0, time.UTC),
+		Data:      json.RawMessage(`{"amount":"25.00"}`),
+	}
+	var b strings.Builder
+	require.NoError(t, writeEvent(&b, e))
+	assert.Equal(t, "id: 5b0f6c2e-4a7c-4f3e-9a51-0c1d2e3f4a5b\n"+
+		"event: settlement.created\n"+
+		`data: {"id":"5b0f6c2e-4a7c-4f3e-9a51-0c1d2e3f4a5b","type":"settlement.created",`+
+		`"user_id":"0e6f1a2b-3c4d-4e5f-8a9b-0c1d2e3f4a5b","created_at":"2024-03-01T09:00:00Z","data":{"amount":"25.00"}}`+"\n\n",
+		b.String())
+}
@@ -0,0 +1,28 @@
+package user
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+	"github.com/yanonymousV2/finance-manager-backend/internal/helpers"
+)
+
+// List returns the users who can sign in, oldest first. A non-empty search
+// keeps those whose email contains it.
+func List(ctx context.Context, db *db.DB, search string) ([]User, error) {
+	rows, err := db.Pool.Query(ctx,
+		`SELECT id, email, default_currency, created_at FROM users
+		 WHERE NOT is_placeholder AND email ILIKE $1
+		 ORDER BY created_at`,
+		"%"+helpers.EscapeLike(search)+"%")
+	if err != nil {
+		return nil, err
+	}
+	return pgx.CollectRows(rows, func(row pgx.CollectableRow) (User, error) {
+		var u User
+		err := row.Scan(&u.ID, &u.Email, &u.DefaultCurrency, &u.CreatedAt)
+		return u, err
+	})
+}
@@ -10,5 +10,10 @@ type User struct {
 	ID           uuid.UUID `json:"id" db:"id"`
 	Email        string    `json:"email" db:"email"`
 	PasswordHash string    `json:"-" db:"password_hash"`
-	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+	// DefaultCurrency is the currency personal expenses are reported in
+	DefaultCurrency string    `json:"default_currency" db:"default_currency"`
+	CreatedAt       time.Time `json:"created_at" db:"created_at"`
 }
+
+// DefaultCurrency is used when a user signs up without an explicit currency
+const DefaultCurrency = "USD"
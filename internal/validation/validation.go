@@ -0,0 +1,186 @@
+package validation
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+)
+
+// FieldError is one problem with one field of a request body. Field is the
+// JSON path, such as "splits[1].amount".
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Param   string `json:"param,omitempty"`
+	Message string `json:"message"`
+}
+
+// validate is shared; validator.Validate caches struct metadata and is safe
+// for concurrent use
+var validate = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New()
+	// Report fields by their JSON names
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return f.Name
+		}
+		return name
+	})
+	return v
+}
+
+// Struct validates v against its validate tags
+func Struct(v any) error {
+	return validate.Struct(v)
+}
+
+// message describes a failed rule in words
+func message(fe validator.FieldError) string {
+	kind := fe.Kind()
+	if kind == reflect.Pointer {
+		kind = fe.Type().Elem().Kind()
+	}
+	unit := ""
+	switch kind {
+	case reflect.String:
+		unit = " characters"
+	case reflect.Slice, reflect.Array, reflect.Map:
+		unit = " items"
+	}
+
+	switch fe.Tag() {
+	case "required", "required_if", "required_unless":
+		return "is required"
+	case "min":
+		return "must be at least " + fe.Param() + unit
+	case "max":
+		return "must be at most " + fe.Param() + unit
+	case "len":
+		return "must be exactly " + fe.Param() + unit
+	case "gt":
+		return "must be greater than " + fe.Param()
+	case "gte":
+		return "must be at least " + fe.Param()
+	case "lt":
+		return "must be less than " + fe.Param()
+	case "lte":
+		return "must be at most " + fe.Param()
+	case "oneof":
+		return "must be one of " + strings.Join(strings.Fields(fe.Param()), ", ")
+	case "email":
+		return "must be a valid email address"
+	case "numeric":
+		return "must be a number"
+	case "iso4217":
+		return "must be a valid currency code"
+	case "uuid", "uuid4":
+		return "must be a valid UUID"
+	case "url":
+		return "must be a valid URL"
+	case "http_url":
+		return "must be an http or https URL"
+	case "hexcolor":
+		return "must be a hex color"
+	default:
+		return "failed the " + fe.Tag() + " rule"
+	}
+}
+
+// field turns a validator namespace like "CreateExpenseRequest.splits[1].amount"
+// into a JSON path by dropping the struct name
+func field(namespace string) string {
+	_, path, found := strings.Cut(namespace, ".")
+	if !found {
+		return namespace
+	}
+	return path
+}
+
+// Fields lists the field-level problems in err, an error from Struct or from
+// decoding JSON. It's empty for other errors.
+func Fields(err error) []FieldError {
+	var validationErrors validator.ValidationErrors
+	if errors.As(err, &validationErrors) {
+		fields := make([]FieldError, len(validationErrors))
+		for i, fe := range validationErrors {
+			fields[i] = FieldError{
+				Field:   field(fe.Namespace()),
+				Rule:    fe.Tag(),
+				Param:   fe.Param(),
+				Message: message(fe),
+			}
+		}
+		return fields
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return []FieldError{{
+			Field:   typeErr.Field,
+			Rule:    "type",
+			Param:   typeErr.Type.String(),
+			Message: fmt.Sprintf("must be a %s, not a %s", jsonType(typeErr.Type), typeErr.Value),
+		}}
+	}
+	return nil
+}
+
+// jsonType names the JSON type a Go type is decoded from
+func jsonType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return "number"
+	case reflect.Slice, reflect.Array:
+		return "array"
+	case reflect.Map, reflect.Struct:
+		return "object"
+	default:
+		return "string"
+	}
+}
+
+// Respond reports err, from Struct or from decoding the body, as a 400 with
+// its field-level problems when there are any
+func Respond(c *gin.Context, err error) {
+	var syntaxErr *json.SyntaxError
+	switch fields := Fields(err); {
+	case len(fields) > 0:
+		c.JSON(400, gin.H{"error": "validation failed", "fields": fields})
+	case errors.Is(err, io.EOF):
+		c.JSON(400, gin.H{"error": "request body is required"})
+	case errors.As(err, &syntaxErr), errors.Is(err, io.ErrUnexpectedEOF):
+		c.JSON(400, gin.H{"error": "invalid JSON body"})
+	default:
+		c.JSON(400, gin.H{"error": err.Error()})
+	}
+}
+
+// Bind decodes the JSON body into req and validates it. On failure it
+// responds with a 400 and returns false.
+func Bind(c *gin.Context, req any) bool {
+	if err := c.ShouldBindJSON(req); err != nil {
+		Respond(c, err)
+		return false
+	}
+	if err := Struct(req); err != nil {
+		Respond(c, err)
+		return false
+	}
+	return true
+}
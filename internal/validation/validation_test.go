@@ -0,0 +1,84 @@
+package validation
+
+import (
+	"encoding/json"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type split struct {
+	Amount string `json:"amount" validate:"required,numeric"`
+}
+
+type request struct {
+	Name     string   `json:"name" validate:"required,min=2"`
+	Currency string   `json:"currency,omitempty" validate:"omitempty,iso4217"`
+	Period   string   `json:"period" validate:"omitempty,oneof=weekly monthly"`
+	Count    int      `json:"count" validate:"gte=0"`
+	Tags     []string `json:"tags" validate:"max=2"`
+	Splits   []split  `json:"splits" validate:"dive"`
+}
+
+func bind(t *testing.T, body string) (bool, int, map[string]any) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest("POST", "/", strings.NewReader(body))
+	c.Request.Header.Set("Content-Type", "application/json")
+
+	var req request
+	ok := Bind(c, &req)
+	var resp map[string]any
+	if w.Body.Len() > 0 {
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &resp))
+	}
+	return ok, w.Code, resp
+}
+
+func TestBind(t *testing.T) {
+	ok, _, _ := bind(t, `{"name": "ok", "splits": [{"amount": "1.5"}]}`)
+	assert.True(t, ok)
+
+	ok, code, resp := bind(t, `{"name": "x", "currency": "XXY", "period": "daily", "count": -1,
+		"tags": ["a", "b", "c"], "splits": [{"amount": "1"}, {"amount": "abc"}]}`)
+	assert.False(t, ok)
+	assert.Equal(t, 400, code)
+	assert.Equal(t, "validation failed", resp["error"])
+
+	messages := map[string]string{}
+	for _, f := range resp["fields"].([]any) {
+		fe := f.(map[string]any)
+		messages[fe["field"].(string)] = fe["message"].(string)
+	}
+	assert.Equal(t, map[string]string{
+		"name":             "must be at least 2 characters",
+		"currency":         "must be a valid currency code",
+		"period":           "must be one of weekly, monthly",
+		"count":            "must be at least 0",
+		"tags":             "must be at most 2 items",
+		"splits[1].amount": "must be a number",
+	}, messages)
+}
+
+func TestBindDecodeErrors(t *testing.T) {
+	_, code, resp := bind(t, `{"name": "ok", "count": "three"}`)
+	assert.Equal(t, 400, code)
+	require.Len(t, resp["fields"], 1)
+	field := resp["fields"].([]any)[0].(map[string]any)
+	assert.Equal(t, "count", field["field"])
+	assert.Equal(t, "must be a number, not a string", field["message"])
+
+	_, _, resp = bind(t, `{"name": `)
+	assert.Equal(t, "invalid JSON body", resp["error"])
+
+	_, _, resp = bind(t, ``)
+	assert.Equal(t, "request body is required", resp["error"])
+
+	_, _, resp = bind(t, `{}`)
+	assert.Equal(t, []any{map[string]any{"field": "name", "rule": "required", "message": "is required"}}, resp["fields"])
+}
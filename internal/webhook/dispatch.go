@@ -0,0 +1,323 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+	"github.com/yanonymousV2/finance-manager-backend/internal/health"
+)
+
+const (
+	// maxAttempts is how many times a delivery is tried before it's failed
+	maxAttempts = 8
+	// batchSize is how many events are fanned out, or deliveries sent, at a time
+	batchSize = 100
+	// workers is how many deliveries are sent at once
+	workers = 8
+	// requestTimeout bounds one attempt. A claimed delivery isn't picked up
+	// by another server for twice as long.
+	requestTimeout = 10 * time.Second
+	// eventRetention is how long events and their delivery log are kept once
+	// every delivery has finished
+	eventRetention = 30 * 24 * time.Hour
+)
+
+// backoff is how long to wait after the given failed attempt: a minute,
+// doubling each time up to six hours, so eight attempts span about two
+// hours
+func backoff(attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	if attempt > 10 {
+		return 6 * time.Hour
+	}
+	return min(time.Minute<<(attempt-1), 6*time.Hour)
+}
+
+// NewClient returns the HTTP client deliveries are sent with. Unless
+// allowPrivate is set it refuses to connect to loopback, private and
+// link-local addresses, so endpoints can't be used to reach internal
+// services. Redirects aren't followed.
+func NewClient(allowPrivate bool) *http.Client {
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+	if !allowPrivate {
+		dialer.Control = func(_, address string, _ syscall.RawConn) error {
+			return checkAddress(address)
+		}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = dialer.DialContext
+	transport.Proxy = nil
+	return &http.Client{
+		Transport: transport,
+		Timeout:   requestTimeout,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// checkAddress rejects the host:port being dialled if it isn't a public
+// address. It runs after DNS resolution, so a hostname can't dodge it.
+func checkAddress(address string) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return fmt.Errorf("invalid address %q", host)
+	}
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsUnspecified() || ip.IsMulticast() {
+		return fmt.Errorf("address %s is not public", ip)
+	}
+	return nil
+}
+
+// Run dispatches events every interval until ctx is cancelled, beating
+// heartbeat after each run. On cancellation deliveries in flight are
+// finished before Run returns.
+func Run(ctx context.Context, db *db.DB, client *http.Client, interval time.Duration, heartbeat *health.Heartbeat) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		err := RunOnce(ctx, db, client)
+		if ctx.Err() != nil {
+			return
+		}
+		if err != nil {
+			slog.Error("failed to dispatch webhooks", "error", err)
+		}
+		heartbeat.Beat(err)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RunOnce turns new events into deliveries, sends the deliveries that are
+// due and removes old events, once they have also been turned into
+// notifications
+func RunOnce(ctx context.Context, db *db.DB, client *http.Client) error {
+	if err := fanOut(ctx, db); err != nil {
+		return err
+	}
+	if err := deliverDue(ctx, db, client); err != nil {
+		return err
+	}
+	_, err := db.Pool.Exec(ctx,
+		`DELETE FROM events e
+		 WHERE e.dispatched_at < $1 AND e.notified_at IS NOT NULL
+		   AND NOT EXISTS (SELECT 1 FROM webhook_deliveries d WHERE d.event_id = e.id AND d.status = 'pending')`,
+		time.Now().Add(-eventRetention))
+	if err != nil {
+		return fmt.Errorf("failed to remove old events: %w", err)
+	}
+	return nil
+}
+
+// fanOutSQL creates a delivery for every active endpoint subscribed to each
+// of a batch of undispatched events, and marks the batch dispatched. An
+// event goes to the endpoints of whoever caused it and, for group events,
+// of every group member. SKIP LOCKED lets several servers run it at once.
+const fanOutSQL = `
+	WITH batch AS (
+		SELECT id FROM events WHERE dispatched_at IS NULL
+		ORDER BY created_at LIMIT $1
+		FOR UPDATE SKIP LOCKED
+	), deliveries AS (
+		INSERT INTO webhook_deliveries (endpoint_id, event_id)
+		SELECT w.id, e.id
+		FROM events e
+		JOIN batch b ON b.id = e.id
+		JOIN webhook_endpoints w ON w.active
+			AND (cardinality(w.event_types) = 0 OR e.type = ANY(w.event_types))
+			AND (w.user_id = e.user_id
+				OR w.user_id IN (SELECT gm.user_id FROM group_members gm WHERE gm.group_id = e.group_id))
+		ON CONFLICT (endpoint_id, event_id) DO NOTHING
+	)
+	UPDATE events SET dispatched_at = NOW() WHERE id IN (SELECT id FROM batch)`
+
+func fanOut(ctx context.Context, db *db.DB) error {
+	for {
+		tag, err := db.Pool.Exec(ctx, fanOutSQL, batchSize)
+		if err != nil {
+			return fmt.Errorf("failed to fan out events: %w", err)
+		}
+		if tag.RowsAffected() < batchSize {
+			return nil
+		}
+	}
+}
+
+// claimSQL takes a batch of due deliveries, counting the attempt and
+// pushing next_attempt_at past the request timeout so no other server
+// sends them meanwhile. Deliveries to inactive endpoints wait.
+const claimSQL = `
+	UPDATE webhook_deliveries d
+	SET attempts = d.attempts + 1, last_attempt_at = NOW(), next_attempt_at = NOW() + $2::interval
+	FROM webhook_endpoints w, events e
+	WHERE d.id IN (
+		SELECT d2.id FROM webhook_deliveries d2
+		JOIN webhook_endpoints w2 ON w2.id = d2.endpoint_id AND w2.active
+		WHERE d2.status = 'pending' AND d2.next_attempt_at <= NOW()
+		ORDER BY d2.next_attempt_at LIMIT $1
+		FOR UPDATE OF d2 SKIP LOCKED
+	) AND w.id = d.endpoint_id AND e.id = d.event_id
+	RETURNING d.id, d.attempts, w.url, w.secret, e.id, e.type, e.data, e.created_at`
+
+// attempt is a claimed delivery with what's needed to send it
+type attempt struct {
+	deliveryID uuid.UUID
+	number     int
+	url        string
+	secret     string
+	eventID    uuid.UUID
+	eventType  string
+	data       json.RawMessage
+	createdAt  time.Time
+}
+
+// payload is the body of every delivery
+type payload struct {
+	ID        uuid.UUID       `json:"id"`
+	Type      string          `json:"type"`
+	CreatedAt time.Time       `json:"created_at"`
+	Data      json.RawMessage `json:"data"`
+}
+
+func deliverDue(ctx context.Context, db *db.DB, client *http.Client) error {
+	for {
+		// Stop between batches on shutdown, never half way through one
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		rows, err := db.Pool.Query(ctx, claimSQL, batchSize, 2*requestTimeout)
+		if err != nil {
+			return fmt.Errorf("failed to claim deliveries: %w", err)
+		}
+		var due []attempt
+		for rows.Next() {
+			var a attempt
+			if err := rows.Scan(&a.deliveryID, &a.number, &a.url, &a.secret,
+				&a.eventID, &a.eventType, &a.data, &a.createdAt); err != nil {
+				rows.Close()
+				return fmt.Errorf("failed to scan delivery: %w", err)
+			}
+			due = append(due, a)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("failed to claim deliveries: %w", err)
+		}
+
+		sendAll(context.WithoutCancel(ctx), db, client, due)
+		if len(due) < batchSize {
+			return nil
+		}
+	}
+}
+
+// sendAll sends a batch of deliveries, workers at a time, and records how
+// each went
+func sendAll(ctx context.Context, db *db.DB, client *http.Client, due []attempt) {
+	queue := make(chan attempt)
+	var wg sync.WaitGroup
+	for range min(workers, len(due)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for a := range queue {
+				status, err := send(ctx, client, a)
+				if err := record(ctx, db, a, status, err); err != nil {
+					slog.Error("failed to record webhook delivery", "delivery_id", a.deliveryID, "error", err)
+				}
+			}
+		}()
+	}
+	for _, a := range due {
+		queue <- a
+	}
+	close(queue)
+	wg.Wait()
+}
+
+// send posts a delivery and returns the response status. Anything but a
+// 2xx response is an error.
+func send(ctx context.Context, client *http.Client, a attempt) (int, error) {
+	body, err := json.Marshal(payload{ID: a.eventID, Type: a.eventType, CreatedAt: a.createdAt, Data: a.data})
+	if err != nil {
+		return 0, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, a.url, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "finance-manager-webhooks")
+	req.Header.Set(SignatureHeader, Sign(a.secret, time.Now(), body))
+	req.Header.Set(EventTypeHeader, a.eventType)
+	req.Header.Set(EventIDHeader, a.eventID.String())
+	req.Header.Set(DeliveryHeader, a.deliveryID.String())
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	// Drain a little so the connection can be reused
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, errors.New(resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// record stores the outcome of an attempt: succeeded, failed for good after
+// maxAttempts, or pending again after a backoff
+func record(ctx context.Context, db *db.DB, a attempt, status int, sendErr error) error {
+	var responseStatus *int
+	if status != 0 {
+		responseStatus = &status
+	}
+	if sendErr == nil {
+		_, err := db.Pool.Exec(ctx,
+			`UPDATE webhook_deliveries SET status = 'succeeded', response_status = $2, last_error = NULL
+			 WHERE id = $1`, a.deliveryID, responseStatus)
+		return err
+	}
+
+	message := sendErr.Error()
+	if len(message) > 500 {
+		message = message[:500]
+	}
+	if a.number >= maxAttempts {
+		slog.Warn("webhook delivery failed", "delivery_id", a.deliveryID, "url", a.url, "attempts", a.number, "error", message)
+		_, err := db.Pool.Exec(ctx,
+			`UPDATE webhook_deliveries SET status = 'failed', response_status = $2, last_error = $3
+			 WHERE id = $1`, a.deliveryID, responseStatus, message)
+		return err
+	}
+	_, err := db.Pool.Exec(ctx,
+		`UPDATE webhook_deliveries SET response_status = $2, last_error = $3, next_attempt_at = NOW() + $4::interval
+		 WHERE id = $1`, a.deliveryID, responseStatus, message, backoff(a.number))
+	return err
+}
@@ -0,0 +1,398 @@
+// Package webhook lets users register URLs that events are posted to, and
+// runs the dispatcher that delivers them from the events outbox.
+package webhook
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+
+	"github.com/yanonymousV2/finance-manager-backend/internal/db"
+	"github.com/yanonymousV2/finance-manager-backend/internal/event"
+	"github.com/yanonymousV2/finance-manager-backend/internal/middleware"
+	"github.com/yanonymousV2/finance-manager-backend/internal/validation"
+)
+
+// maxEndpoints is how many endpoints one user can register
+const maxEndpoints = 10
+
+// Endpoint is a URL events are posted to. An empty EventTypes subscribes
+// to every type. Secret is only returned when the endpoint is created.
+type Endpoint struct {
+	ID          uuid.UUID `json:"id" db:"id"`
+	UserID      uuid.UUID `json:"user_id" db:"user_id"`
+	URL         string    `json:"url" db:"url"`
+	Description *string   `json:"description,omitempty" db:"description"`
+	EventTypes  []string  `json:"event_types" db:"event_types"`
+	Active      bool      `json:"active" db:"active"`
+	Secret      string    `json:"secret,omitempty" db:"secret"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+}
+
+type CreateEndpointRequest struct {
+	URL         string   `json:"url" validate:"required,http_url,max=2048"`
+	Description *string  `json:"description,omitempty" validate:"omitempty,max=255"`
+	EventTypes  []string `json:"event_types,omitempty"`
+}
+
+// UpdateEndpointRequest changes the fields that are set. "event_types": []
+// subscribes to every type; leaving it out keeps the current ones.
+type UpdateEndpointRequest struct {
+	URL         *string  `json:"url,omitempty" validate:"omitempty,http_url,max=2048"`
+	Description *string  `json:"description,omitempty" validate:"omitempty,max=255"`
+	EventTypes  []string `json:"event_types,omitempty"`
+	Active      *bool    `json:"active,omitempty"`
+}
+
+// Delivery is one event posted, or to be posted, to an endpoint, with the
+// outcome of its latest attempt
+type Delivery struct {
+	ID             uuid.UUID  `json:"id" db:"id"`
+	EventID        uuid.UUID  `json:"event_id" db:"event_id"`
+	EventType      string     `json:"event_type" db:"event_type"`
+	Status         string     `json:"status" db:"status"`
+	Attempts       int        `json:"attempts" db:"attempts"`
+	NextAttemptAt  *time.Time `json:"next_attempt_at,omitempty" db:"next_attempt_at"`
+	LastAttemptAt  *time.Time `json:"last_attempt_at,omitempty" db:"last_attempt_at"`
+	ResponseStatus *int       `json:"response_status,omitempty" db:"response_status"`
+	LastError      *string    `json:"last_error,omitempty" db:"last_error"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+}
+
+// Delivery statuses
+const (
+	StatusPending   = "pending"
+	StatusSucceeded = "succeeded"
+	StatusFailed    = "failed"
+)
+
+const endpointColumns = "id, user_id, url, description, event_types, active, created_at, updated_at"
+
+func scanEndpoint(row interface{ Scan(...any) error }) (Endpoint, error) {
+	var e Endpoint
+	err := row.Scan(&e.ID, &e.UserID, &e.URL, &e.Description, &e.EventTypes, &e.Active, &e.CreatedAt, &e.UpdatedAt)
+	return e, err
+}
+
+// checkEventTypes reports the first type that isn't an event type
+func checkEventTypes(types []string) error {
+	for _, t := range types {
+		if !slices.Contains(event.Types, t) {
+			return fmt.Errorf("unknown event type %q", t)
+		}
+	}
+	return nil
+}
+
+func newSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return "whsec_" + hex.EncodeToString(buf), nil
+}
+
+// CreateEndpoint registers a webhook endpoint. The response includes the
+// secret deliveries are signed with; it isn't shown again.
+func CreateEndpoint(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	var req CreateEndpointRequest
+	if !validation.Bind(c, &req) {
+		return
+	}
+	if err := checkEventTypes(req.EventTypes); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+	if req.EventTypes == nil {
+		req.EventTypes = []string{}
+	}
+
+	var count int
+	err := db.Pool.QueryRow(c.Request.Context(),
+		"SELECT COUNT(*) FROM webhook_endpoints WHERE user_id = $1", userID).Scan(&count)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to check endpoints"})
+		return
+	}
+	if count >= maxEndpoints {
+		c.JSON(409, gin.H{"error": fmt.Sprintf("at most %d webhook endpoints are allowed", maxEndpoints)})
+		return
+	}
+
+	secret, err := newSecret()
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to create secret"})
+		return
+	}
+
+	endpoint, err := scanEndpoint(db.Pool.QueryRow(c.Request.Context(),
+		`INSERT INTO webhook_endpoints (user_id, url, description, secret, event_types)
+		 VALUES ($1, $2, $3, $4, $5)
+		 RETURNING `+endpointColumns,
+		userID, req.URL, req.Description, secret, req.EventTypes))
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to create webhook endpoint"})
+		return
+	}
+	endpoint.Secret = secret
+
+	c.JSON(201, endpoint)
+}
+
+// ListEndpoints returns the user's webhook endpoints, oldest first
+func ListEndpoints(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	rows, err := db.Pool.Query(c.Request.Context(),
+		`SELECT `+endpointColumns+` FROM webhook_endpoints WHERE user_id = $1 ORDER BY created_at, id`, userID)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to retrieve webhook endpoints"})
+		return
+	}
+	defer rows.Close()
+
+	endpoints := []Endpoint{}
+	for rows.Next() {
+		endpoint, err := scanEndpoint(rows)
+		if err != nil {
+			c.JSON(500, gin.H{"error": "failed to scan webhook endpoint"})
+			return
+		}
+		endpoints = append(endpoints, endpoint)
+	}
+
+	c.JSON(200, endpoints)
+}
+
+// updateEndpointSQL changes the fields given; a NULL argument leaves its
+// column as it is and an empty description clears it
+const updateEndpointSQL = `UPDATE webhook_endpoints SET
+	url = COALESCE(@url, url),
+	description = CASE WHEN @description::text IS NULL THEN description ELSE NULLIF(@description, '') END,
+	event_types = CASE WHEN @set_event_types THEN @event_types ELSE event_types END,
+	active = COALESCE(@active, active),
+	updated_at = NOW()
+WHERE id = @id
+RETURNING ` + endpointColumns
+
+// UpdateEndpoint changes an endpoint's URL, description, event types or
+// whether it's active. Deliveries to an inactive endpoint wait until it's
+// active again.
+func UpdateEndpoint(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	endpointID, ok := ownEndpoint(c, db, userID)
+	if !ok {
+		return
+	}
+
+	var req UpdateEndpointRequest
+	if !validation.Bind(c, &req) {
+		return
+	}
+	if err := checkEventTypes(req.EventTypes); err != nil {
+		c.JSON(400, gin.H{"error": err.Error()})
+		return
+	}
+
+	if req.URL == nil && req.Description == nil && req.EventTypes == nil && req.Active == nil {
+		c.JSON(400, gin.H{"error": "no fields to update"})
+		return
+	}
+
+	endpoint, err := scanEndpoint(db.Pool.QueryRow(c.Request.Context(), updateEndpointSQL, pgx.NamedArgs{
+		"id":              endpointID,
+		"url":             req.URL,
+		"description":     req.Description,
+		"set_event_types": req.EventTypes != nil,
+		"event_types":     req.EventTypes,
+		"active":          req.Active,
+	}))
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to update webhook endpoint"})
+		return
+	}
+
+	c.JSON(200, endpoint)
+}
+
+// DeleteEndpoint removes an endpoint along with its delivery log
+func DeleteEndpoint(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	endpointID, ok := ownEndpoint(c, db, userID)
+	if !ok {
+		return
+	}
+
+	if _, err := db.Pool.Exec(c.Request.Context(), "DELETE FROM webhook_endpoints WHERE id = $1", endpointID); err != nil {
+		c.JSON(500, gin.H{"error": "failed to delete webhook endpoint"})
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "webhook endpoint deleted successfully"})
+}
+
+// ListDeliveries returns an endpoint's delivery log, newest first. Pass
+// status to see only pending, succeeded or failed deliveries.
+func ListDeliveries(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	endpointID, ok := ownEndpoint(c, db, userID)
+	if !ok {
+		return
+	}
+
+	// Parse pagination parameters
+	limit := 50 // default
+	if limitStr := c.Query("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 && l <= 100 {
+			limit = l
+		}
+	}
+
+	offset := 0
+	if offsetStr := c.Query("offset"); offsetStr != "" {
+		if o, err := strconv.Atoi(offsetStr); err == nil && o >= 0 {
+			offset = o
+		}
+	}
+
+	status := c.Query("status")
+	if status != "" && status != StatusPending && status != StatusSucceeded && status != StatusFailed {
+		c.JSON(400, gin.H{"error": "status must be pending, succeeded or failed"})
+		return
+	}
+
+	rows, err := db.Pool.Query(c.Request.Context(),
+		`SELECT d.id, d.event_id, e.type, d.status, d.attempts,
+		        CASE WHEN d.status = 'pending' THEN d.next_attempt_at END,
+		        d.last_attempt_at, d.response_status, d.last_error, d.created_at
+		 FROM webhook_deliveries d
+		 JOIN events e ON e.id = d.event_id
+		 WHERE d.endpoint_id = $1 AND ($2 = '' OR d.status = $2)
+		 ORDER BY d.created_at DESC, d.id
+		 LIMIT $3 OFFSET $4`,
+		endpointID, status, limit, offset)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to retrieve deliveries"})
+		return
+	}
+	defer rows.Close()
+
+	deliveries := []Delivery{}
+	for rows.Next() {
+		var d Delivery
+		if err := rows.Scan(&d.ID, &d.EventID, &d.EventType, &d.Status, &d.Attempts,
+			&d.NextAttemptAt, &d.LastAttemptAt, &d.ResponseStatus, &d.LastError, &d.CreatedAt); err != nil {
+			c.JSON(500, gin.H{"error": "failed to scan delivery"})
+			return
+		}
+		deliveries = append(deliveries, d)
+	}
+
+	c.JSON(200, gin.H{
+		"deliveries": deliveries,
+		"pagination": gin.H{
+			"limit":  limit,
+			"offset": offset,
+		},
+	})
+}
+
+// RetryDelivery queues a failed delivery to be sent again straight away,
+// with a fresh set of attempts
+func RetryDelivery(c *gin.Context, db *db.DB) {
+	userID, ok := middleware.GetUserID(c)
+	if !ok {
+		c.JSON(401, gin.H{"error": "unauthorized"})
+		return
+	}
+
+	endpointID, ok := ownEndpoint(c, db, userID)
+	if !ok {
+		return
+	}
+
+	deliveryID, err := uuid.Parse(c.Param("deliveryId"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid delivery id"})
+		return
+	}
+
+	var status string
+	err = db.Pool.QueryRow(c.Request.Context(),
+		"SELECT status FROM webhook_deliveries WHERE id = $1 AND endpoint_id = $2",
+		deliveryID, endpointID).Scan(&status)
+	if err != nil {
+		c.JSON(404, gin.H{"error": "delivery not found"})
+		return
+	}
+	if status != StatusFailed {
+		c.JSON(409, gin.H{"error": "only failed deliveries can be retried"})
+		return
+	}
+
+	_, err = db.Pool.Exec(c.Request.Context(),
+		`UPDATE webhook_deliveries SET status = 'pending', attempts = 0, next_attempt_at = NOW()
+		 WHERE id = $1 AND status = 'failed'`, deliveryID)
+	if err != nil {
+		c.JSON(500, gin.H{"error": "failed to retry delivery"})
+		return
+	}
+
+	c.JSON(200, gin.H{"message": "delivery queued for retry"})
+}
+
+// ownEndpoint parses the endpoint ID in the path and checks userID owns it.
+// On failure it has already responded.
+func ownEndpoint(c *gin.Context, db *db.DB, userID uuid.UUID) (uuid.UUID, bool) {
+	endpointID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(400, gin.H{"error": "invalid webhook endpoint id"})
+		return uuid.Nil, false
+	}
+
+	var ownerID uuid.UUID
+	err = db.Pool.QueryRow(c.Request.Context(),
+		"SELECT user_id FROM webhook_endpoints WHERE id = $1", endpointID).Scan(&ownerID)
+	if err != nil {
+		c.JSON(404, gin.H{"error": "webhook endpoint not found"})
+		return uuid.Nil, false
+	}
+	if ownerID != userID {
+		c.JSON(403, gin.H{"error": "not authorized to access this webhook endpoint"})
+		return uuid.Nil, false
+	}
+	return endpointID, true
+}
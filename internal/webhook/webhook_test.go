@@ -0,0 +1,85 @@
+package webhook
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignVerify(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	body := []byte(`{"id":"1"}`)
+	header := Sign("whsec_test", now, body)
+	assert.Regexp(t, `^t=1700000000,v1=[0-9a-f]{64}$`, header)
+
+	assert.NoError(t, Verify("whsec_test", header, body, now.Add(time.Minute), 5*time.Minute))
+	assert.Error(t, Verify("whsec_other", header, body, now, 5*time.Minute))
+	assert.Error(t, Verify("whsec_test", header, []byte(`{"id":"2"}`), now, 5*time.Minute))
+	assert.Error(t, Verify("whsec_test", header, body, now.Add(10*time.Minute), 5*time.Minute))
+	assert.Error(t, Verify("whsec_test", "v1=abc", body, now, 5*time.Minute))
+}
+
+func TestBackoff(t *testing.T) {
+	assert.Equal(t, time.Minute, backoff(1))
+	assert.Equal(t, 2*time.Minute, backoff(2))
+	assert.Equal(t, 64*time.Minute, backoff(7))
+	assert.Equal(t, 6*time.Hour, backoff(12))
+	assert.Equal(t, 6*time.Hour, backoff(100))
+}
+
+func TestCheckAddress(t *testing.T) {
+	for _, address := range []string{"127.0.0.1:80", "[::1]:443", "10.1.2.3:80", "192.168.0.10:8080", "169.254.169.254:80", "0.0.0.0:80"} {
+		assert.Error(t, checkAddress(address), address)
+	}
+	for _, address := range []string{"93.184.216.34:443", "[2606:2800:220:1::]:443"} {
+		assert.NoError(t, checkAddress(address), address)
+	}
+}
+
+func TestSend(t *testing.T) {
+	a := attempt{
+		deliveryID: uuid.New(),
+		number:     1,
+		secret:     "whsec_test",
+		eventID:    uuid.New(),
+		eventType:  "expense.created",
+		data:       json.RawMessage(`{"description":"Dinner"}`),
+		createdAt:  time.Now().UTC(),
+	}
+
+	var got payload
+	status := http.StatusNoContent
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		assert.NoError(t, Verify(a.secret, r.Header.Get(SignatureHeader), body, time.Now(), time.Minute))
+		assert.Equal(t, a.eventType, r.Header.Get(EventTypeHeader))
+		assert.Equal(t, a.eventID.String(), r.Header.Get(EventIDHeader))
+		assert.Equal(t, a.deliveryID.String(), r.Header.Get(DeliveryHeader))
+		assert.NoError(t, json.Unmarshal(body, &got))
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+	a.url = srv.URL
+
+	code, err := send(t.Context(), NewClient(true), a)
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusNoContent, code)
+	assert.Equal(t, a.eventID, got.ID)
+	assert.JSONEq(t, `{"description":"Dinner"}`, string(got.Data))
+
+	status = http.StatusInternalServerError
+	code, err = send(t.Context(), NewClient(true), a)
+	assert.Error(t, err)
+	assert.Equal(t, http.StatusInternalServerError, code)
+
+	// The test server is on loopback
+	_, err = send(t.Context(), NewClient(false), a)
+	assert.ErrorContains(t, err, "not public")
+}